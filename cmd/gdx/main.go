@@ -132,6 +132,11 @@ var (
 		utils.EVMInterpreterFlag,
 		configFileFlag,
 		utils.StorageRoleFlag,
+		utils.StorageClientPassphraseFileFlag,
+		utils.StorageClientGatewayFlag,
+		utils.StorageClientS3GatewayFlag,
+		utils.StorageCompressionFlag,
+		utils.StorageCompressionThresholdFlag,
 	}
 
 	rpcFlags = []cli.Flag{
@@ -144,6 +149,8 @@ var (
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCAuthNamespacesFlag,
+		utils.RPCAuthTokensFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/DxChainNetwork/godx/cmd/utils"
 	"github.com/DxChainNetwork/godx/common"
@@ -12,6 +13,7 @@ import (
 	"github.com/DxChainNetwork/godx/rpc"
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/storageclient"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractmanager"
 	"github.com/DxChainNetwork/godx/storage/storageclient/storagehostmanager"
 	"github.com/olekukonko/tablewriter"
 
@@ -64,6 +66,16 @@ var (
 		Usage: "Absolute path of the file that is going to ge uploaded/downloaded to (destination)",
 	}
 
+	verifyRateFlag = cli.Float64Flag{
+		Name:  "verifyrate",
+		Usage: "Rate of the downloaded sectors verified with the Merkle proofs, between 0 and 1 (default every sector)",
+	}
+
+	overdriveFlag = cli.IntFlag{
+		Name:  "overdrive",
+		Usage: "Number of extra sector fetches beyond the minimum issued for every downloaded segment",
+	}
+
 	filePathFlag = cli.StringFlag{
 		Name:  "filepath",
 		Usage: "Absolute path of the file",
@@ -78,6 +90,48 @@ var (
 		Name:  "newpath",
 		Usage: "New absolute file path",
 	}
+
+	forecastPeriodsFlag = cli.IntFlag{
+		Name:  "periods",
+		Usage: "Number of periods to be forecast",
+		Value: 3,
+	}
+
+	mountPointFlag = cli.StringFlag{
+		Name:  "mountpoint",
+		Usage: "Local directory where the dxfiles are mounted",
+	}
+
+	storageClassFlag = cli.StringFlag{
+		Name:  "class",
+		Usage: "Storage class the file is transitioned to",
+	}
+
+	backupPathFlag = cli.StringFlag{
+		Name:  "backup",
+		Usage: "Path of the storage client backup file",
+	}
+
+	descriptorPathFlag = cli.StringFlag{
+		Name:  "descriptor",
+		Usage: "Path of the file descriptor used to recover the deleted file",
+	}
+
+	snapshotPathFlag = cli.StringFlag{
+		Name:  "snapshot",
+		Usage: "Path of the bootstrap snapshot of the well known storage hosts",
+	}
+
+	snapshotSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "Address of the account signing the bootstrap snapshot",
+	}
+
+	snapshotHostsFlag = cli.IntFlag{
+		Name:  "hosts",
+		Usage: "Number of the storage hosts exported to the bootstrap snapshot",
+		Value: 50,
+	}
 )
 
 var storageClientCommand = cli.Command{
@@ -129,6 +183,22 @@ will display detailed host information based on the provided hostID, such as dep
 allowed storage time, and etc.`,
 		},
 
+		{
+			Name:      "explainhost",
+			Usage:     "Explain why the storage host is or is not chosen to sign contracts with",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(explainHost),
+			Flags: []cli.Flag{
+				storageHostIDFlag,
+			},
+			Description: `
+			gdx sclient explainhost [--hostid arg]
+
+will display the evaluation break down, filter status, ip violation status, recent scan
+results, and the selection weight of the storage host, along with the reasons that stop
+the host from being chosen`,
+		},
+
 		{
 			Name:      "hostrank",
 			Usage:     "Retrieve host's ranking status for each storage host learnt by the client",
@@ -218,9 +288,10 @@ flag must be used along with this flag to specify the account address`,
 				contractHostFlag,
 				contractRenewFlag,
 				contractFundFlag,
+				overdriveFlag,
 			},
 			Description: `
-			gdx sclient setConfig [--period arg] [--host arg] [--renew arg] [--fund arg]
+			gdx sclient setConfig [--period arg] [--host arg] [--renew arg] [--fund arg] [--overdrive arg]
 		
 will configure the client settings used for contract creation, file upload, download, and etc. There are
 multiple flags can be used along with this command to specify the setting:
@@ -228,6 +299,7 @@ multiple flags can be used along with this command to specify the setting:
 2. host: specifies the number of storage hosts that the client want to sign contracts with
 3. renew: specifies the time that the contract will automatically be renewed.
 4. fund: specifies the amount of money the client wants to be used for the storage service
+5. overdrive: specifies the number of extra sector fetches issued for every downloaded segment
 
 units:
 currency: [camel, gcamel, dx]
@@ -261,13 +333,19 @@ that the file is going to be uploaded to. Note: the src must be absolute path: /
 			Flags: []cli.Flag{
 				fileSourceFlag,
 				fileDestinationFlag,
+				verifyRateFlag,
+				overdriveFlag,
 			},
 			Description: `
-			gdx sclient download [--src arg] [--dst arg]
+			gdx sclient download [--src arg] [--dst arg] [--verifyrate arg] [--overdrive arg]
 
 will download the file specified by the client to the local machine. This command must be used along
 with two flags to specify the source of the file that is going to be downloaded, and the destination
-that the file is going to be downloaded from. Note, the download destination must be absolute path.`,
+that the file is going to be downloaded from. Note, the download destination must be absolute path.
+For big files, the --verifyrate flag can be used to verify only a random sample of the sectors with
+the Merkle proofs, which trades the verification cost for the download throughput. The --overdrive
+flag sets how many sectors beyond the minimum are fetched for every segment, trading the download
+cost for the latency.`,
 		},
 
 		{
@@ -328,6 +406,192 @@ will retrieve and display the cost that storage client needs to pay within one p
 The cost includes cost for all contracts. In addition, it also provides the contract fund left,
 fund unspent, and fund withhold, along with the withhold fund release block height`,
 		},
+		{
+			Name:      "forecast",
+			Usage:     "Project the storage spending over the next periods",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(forecastSpending),
+			Flags: []cli.Flag{
+				forecastPeriodsFlag,
+			},
+			Description: `
+			gdx sclient forecast [--periods arg]
+
+will project the storage spending of each of the next periods based on the data currently stored,
+the upload and download trend within the current period, and the median prices of the active
+storage hosts, including the contract renewal fees. The shortfall shows how much the cost exceeds
+the fund set in the client config, so that the fund could be planned ahead`,
+		},
+		{
+			Name:      "mount",
+			Usage:     "Mount the dxfiles as a local filesystem",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(mountFiles),
+			Flags: []cli.Flag{
+				mountPointFlag,
+			},
+			Description: `
+			gdx sclient mount --mountpoint arg
+
+will mount the dxfiles at the local directory with FUSE, so that the files could be read and written
+with the ordinary file tools. The files opened are downloaded into a local cache, and the files written
+are uploaded in background once closed. Without the mountpoint flag, the current mount points are listed`,
+		},
+		{
+			Name:      "unmount",
+			Usage:     "Unmount the dxfiles mounted",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(unmountFiles),
+			Flags: []cli.Flag{
+				mountPointFlag,
+			},
+			Description: `
+			gdx sclient unmount --mountpoint arg
+
+will unmount the dxfiles mounted at the local directory. The command returns after the files written
+are handed over to the storage client for uploading`,
+		},
+		{
+			Name:      "transition",
+			Usage:     "Transition the file to another storage class",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(transitionStorageClass),
+			Flags: []cli.Flag{
+				filePathFlag,
+				storageClassFlag,
+			},
+			Description: `
+			gdx sclient transition --filepath arg --class arg
+
+will transition the uploaded file to the storage class, e.g. from the standard class to the archive
+class stored with less redundancy on the cheap hosts. The sectors of the new storage class are placed
+by the repair loop, and the file is replaced once they are all uploaded. Without the flags, the
+storage classes and the transitions in progress are listed`,
+		},
+		{
+			Name:      "backup",
+			Usage:     "Backup the storage client into an encrypted file",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(backupClient),
+			Flags: []cli.Flag{
+				backupPathFlag,
+				utils.PasswordFileFlag,
+			},
+			Description: `
+			gdx sclient backup --backup arg
+
+will save the metadata of the uploaded files, the contracts, the storage host information and the
+client settings into one file encrypted with the passphrase. The contracts are locked briefly, so
+that the files and the contracts in the backup are consistent`,
+		},
+		{
+			Name:      "restore",
+			Usage:     "Restore the storage client from the encrypted backup file",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(restoreClient),
+			Flags: []cli.Flag{
+				backupPathFlag,
+				utils.PasswordFileFlag,
+			},
+			Description: `
+			gdx sclient restore --backup arg
+
+will restore the storage client from the backup file. The backup can only be restored on a fresh
+node which has neither contracts nor uploaded files`,
+		},
+		{
+			Name:      "describe",
+			Usage:     "Save the descriptor of the uploaded file, which is needed to recover the file",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(describeFile),
+			Flags: []cli.Flag{
+				filePathFlag,
+				descriptorPathFlag,
+			},
+			Description: `
+			gdx sclient describe --filepath arg --descriptor arg
+
+will save the erasure code, the cipher key and the sector merkle roots of the uploaded file to the
+descriptor. The descriptor contains the key of the file, and shall be kept safe`,
+		},
+		{
+			Name:      "recover",
+			Usage:     "Recover the deleted file from the contracts with the file descriptor",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(recoverFile),
+			Flags: []cli.Flag{
+				descriptorPathFlag,
+			},
+			Description: `
+			gdx sclient recover --descriptor arg
+
+will recreate the file deleted locally from the descriptor, if its sectors are still stored under
+the active contracts. The segments with enough sectors found are recovered, and the missing sectors
+are uploaded again by the repair loop`,
+		},
+		{
+			Name:      "verify",
+			Usage:     "Verify the downloaded file against the sector roots covered by the contracts",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(verifyFile),
+			Flags: []cli.Flag{
+				filePathFlag,
+				fileDestinationFlag,
+			},
+			Description: `
+			gdx sclient verify --filepath arg --dst arg
+
+will verify the file downloaded to --dst. Enough sectors of each segment are fetched from the hosts
+to recompute their merkle roots and recover the segment, which is compared with the downloaded file.
+Each sector root is checked to be covered by the latest revision signed by the client and the host.
+The sectors fetched are paid with the download bandwidth of the contracts`,
+		},
+		{
+			Name:      "verifycontracts",
+			Usage:     "Verify the consistency of the contracts in the contract set",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(verifyContracts),
+			Description: `
+			gdx sclient verifycontracts
+
+will check the number of merkle roots stored for each contract against the file size of the latest
+revision, the merkle roots against the file merkle root, and the signatures of the revision against
+the client and the host of the contract. The inconsistent contracts are reported along with the
+suggested remediation, resyncing with the host or marking the contract as unusable`,
+		},
+		{
+			Name:      "bootstrap",
+			Usage:     "Load the signed bootstrap snapshot of the well known storage hosts",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(loadBootstrapSnapshot),
+			Flags: []cli.Flag{
+				snapshotPathFlag,
+				snapshotSignerFlag,
+			},
+			Description: `
+			gdx sclient bootstrap --snapshot arg --signer arg
+
+will load the storage hosts in the bootstrap snapshot signed by the trusted signer, so that the first
+contracts could be formed before the block chain is synced and the initial scan of the storage hosts
+finished. The storage hosts are evaluated conservatively, and their settings are overridden by the
+scans in the background`,
+		},
+		{
+			Name:      "exportbootstrap",
+			Usage:     "Export the signed bootstrap snapshot of the best storage hosts",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(exportBootstrapSnapshot),
+			Flags: []cli.Flag{
+				snapshotPathFlag,
+				snapshotSignerFlag,
+				snapshotHostsFlag,
+			},
+			Description: `
+			gdx sclient exportbootstrap --snapshot arg --signer arg [--hosts arg]
+
+will save the active storage hosts with the highest evaluations into the bootstrap snapshot, signed
+by the account of the signer, which must be unlocked`,
+		},
 	},
 }
 
@@ -354,10 +618,12 @@ func getConfig(ctx *cli.Context) error {
 	ExpecedDownload:                %s
 	Max Upload Speed:               %s
 	Max Download Speed:             %s
+	Download Overdrive:             %s
 	IP Violation Check Status:      %s
 `, config.RentPayment.Fund, config.RentPayment.Period, config.RentPayment.StorageHosts, config.RentPayment.RenewWindow,
 		config.RentPayment.ExpectedRedundancy, config.RentPayment.ExpectedStorage, config.RentPayment.ExpectedUpload,
-		config.RentPayment.ExpectedDownload, config.MaxUploadSpeed, config.MaxDownloadSpeed, config.EnableIPViolation)
+		config.RentPayment.ExpectedDownload, config.MaxUploadSpeed, config.MaxDownloadSpeed, config.DownloadOverdrive,
+		config.EnableIPViolation)
 
 	return nil
 }
@@ -433,6 +699,64 @@ func getHostInfo(ctx *cli.Context) error {
 	return nil
 }
 
+func explainHost(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	if !ctx.IsSet(storageHostIDFlag.Name) {
+		utils.Fatalf("the --hostid flag must be used to specify which storage host to be explained")
+	}
+	id := ctx.String(storageHostIDFlag.Name)
+
+	var exp storagehostmanager.HostExplanation
+	if err = client.Call(&exp, "sclient_explainHost", id); err != nil {
+		utils.Fatalf("failed to explain the storage host: %s", err.Error())
+	}
+
+	ev, scores := exp.Evaluation, exp.Evaluation.Scores
+	fmt.Printf(`Host Explanation:
+	HostID:                        %s
+	Total Evaluation:              %v
+	Score:                         %.6f
+	AgeFactor:                     %v (score %.6f)
+	DepositFactor:                 %v (score %.6f)
+	InteractionFactor:             %v (score %.6f)
+	PriceFactor:                   %v (score %.6f)
+	RemainingStorageFactor:        %v (score %.6f)
+	UptimeFactor:                  %v (score %.6f)
+	QoSFactor:                     %v (score %.6f)
+	FilterMode:                    %s
+	InFilteredList:                %t
+	Selectable:                    %t
+	IPViolationCheck:              %t
+	IPViolation:                   %t
+	AcceptingStorageContracts:     %t
+	SelectionWeight:               %v
+
+`, exp.EnodeID, ev.Evaluation, ev.Score, ev.PresenceFactor, scores.Presence, ev.DepositFactor, scores.Deposit,
+		ev.InteractionFactor, scores.Interaction, ev.ContractPriceFactor, scores.ContractPrice,
+		ev.StorageRemainingFactor, scores.StorageRemaining, ev.UptimeFactor, scores.Uptime, ev.QoSFactor, scores.QoS,
+		exp.FilterMode, exp.InFilteredList, exp.Selectable, exp.IPViolationCheck,
+		exp.IPViolation, exp.AcceptingContracts, exp.SelectionWeight)
+
+	fmt.Println("Recent Scans:")
+	for _, scan := range exp.RecentScans {
+		fmt.Printf("\t%v\tsuccess: %t\n", scan.Timestamp.Format(time.RFC3339), scan.Success)
+	}
+
+	if len(exp.Reasons) == 0 {
+		fmt.Println("\nThe storage host is eligible to be chosen")
+		return nil
+	}
+	fmt.Println("\nReasons the storage host cannot be chosen:")
+	for _, reason := range exp.Reasons {
+		fmt.Printf("\t%s\n", reason)
+	}
+	return nil
+}
+
 func getRanking(ctx *cli.Context) error {
 	client, err := gdxAttach(ctx)
 	if err != nil {
@@ -644,6 +968,10 @@ func setClientConfig(ctx *cli.Context) error {
 		settings["renew"] = ctx.String(contractRenewFlag.Name)
 	}
 
+	if ctx.IsSet(overdriveFlag.Name) {
+		settings["overdrive"] = strconv.Itoa(ctx.Int(overdriveFlag.Name))
+	}
+
 	var resp string
 	if err = client.Call(&resp, "sclient_setConfig", settings); err != nil {
 		utils.Fatalf("%s", err.Error())
@@ -702,8 +1030,16 @@ func fileDownload(ctx *cli.Context) error {
 		destination = ctx.String(fileDestinationFlag.Name)
 	}
 
+	args := []interface{}{source, destination, nil, nil}
+	if ctx.IsSet(verifyRateFlag.Name) {
+		args[2] = ctx.Float64(verifyRateFlag.Name)
+	}
+	if ctx.IsSet(overdriveFlag.Name) {
+		args[3] = ctx.Int(overdriveFlag.Name)
+	}
+
 	var result string
-	err = client.Call(&result, "sclient_downloadSync", source, destination)
+	err = client.Call(&result, "sclient_downloadSync", args...)
 	if err != nil {
 		utils.Fatalf("failed to download the file: %s", err.Error())
 	}
@@ -785,12 +1121,23 @@ func fileDelete(ctx *cli.Context) error {
 		filePath = ctx.String(filePathFlag.Name)
 	}
 
-	var resp string
-	if err = client.Call(&resp, "clientfiles_delete", filePath); err != nil {
+	var report storageclient.FileDeletionReport
+	if err = client.Call(&report, "sclient_deleteFile", filePath); err != nil {
 		utils.Fatalf("%s", err.Error())
 	}
-
-	fmt.Println(resp)
+	fmt.Printf(`File Deletion:
+	DxPath:               %s
+	Sectors:              %v/%v removed
+	ReclaimedStorage:     %v bytes
+	ReleasedDeposit:      %v camel
+`, report.DxPath, report.RemovedSectors, report.Sectors, report.ReclaimedStorage, report.ReleasedDeposit)
+	for _, hr := range report.Hosts {
+		if hr.Err != "" {
+			fmt.Printf("\tHost %s: %v sectors not removed: %s\n", hr.HostID, hr.Sectors, hr.Err)
+			continue
+		}
+		fmt.Printf("\tHost %s: %v/%v sectors removed\n", hr.HostID, hr.RemovedSectors, hr.Sectors)
+	}
 	return nil
 }
 
@@ -824,6 +1171,97 @@ func periodCost(ctx *cli.Context) error {
 	return nil
 }
 
+func forecastSpending(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remove gdx, please start the gdx first: %s", err.Error())
+	}
+
+	var forecast contractmanager.SpendingForecast
+	if err = client.Call(&forecast, "sclient_forecastSpending", ctx.Int(forecastPeriodsFlag.Name)); err != nil {
+		utils.Fatalf("failed to forecast the storage spending: %s", err.Error())
+	}
+
+	fmt.Printf(`Spending Forecast:
+	BlockHeight:                  %v
+	StoredBytes:                  %v
+	UploadPerBlock:               %v bytes
+	DownloadPerBlock:             %v bytes
+	TotalCost:                    %v camel
+
+`, forecast.BlockHeight, forecast.StoredBytes, forecast.UploadPerBlock, forecast.DownloadPerBlock, forecast.TotalCost)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Blocks", "StoredBytes", "StorageCost", "UploadCost", "DownloadCost", "RenewalFees", "TotalCost", "Shortfall"})
+	for _, pf := range forecast.Periods {
+		table.Append([]string{
+			fmt.Sprintf("%v - %v", pf.StartHeight, pf.EndHeight),
+			fmt.Sprintf("%v", pf.StoredBytes),
+			pf.StorageCost.String(),
+			pf.UploadCost.String(),
+			pf.DownloadCost.String(),
+			pf.RenewalFees.String(),
+			pf.TotalCost.String(),
+			pf.Shortfall.String(),
+		})
+	}
+	table.Render()
+	return nil
+}
+
+func mountFiles(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remove gdx, please start the gdx first: %s", err.Error())
+	}
+
+	if !ctx.IsSet(mountPointFlag.Name) {
+		var mountPoints []string
+		if err = client.Call(&mountPoints, "sclient_mounts"); err != nil {
+			utils.Fatalf("failed to retrieve the mount points: %s", err.Error())
+		}
+		if len(mountPoints) == 0 {
+			fmt.Println("No dxfiles mounted")
+		}
+		for _, mountPoint := range mountPoints {
+			fmt.Println(mountPoint)
+		}
+		return nil
+	}
+
+	mountPoint, err := filepath.Abs(ctx.String(mountPointFlag.Name))
+	if err != nil {
+		utils.Fatalf("invalid mount point: %s", err.Error())
+	}
+	var resp string
+	if err = client.Call(&resp, "sclient_mount", mountPoint); err != nil {
+		utils.Fatalf("failed to mount the dxfiles: %s", err.Error())
+	}
+	fmt.Println(resp)
+	return nil
+}
+
+func unmountFiles(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remove gdx, please start the gdx first: %s", err.Error())
+	}
+
+	if !ctx.IsSet(mountPointFlag.Name) {
+		utils.Fatalf("must specify the mount point to be unmounted")
+	}
+	mountPoint, err := filepath.Abs(ctx.String(mountPointFlag.Name))
+	if err != nil {
+		utils.Fatalf("invalid mount point: %s", err.Error())
+	}
+	var resp string
+	if err = client.Call(&resp, "sclient_unmount", mountPoint); err != nil {
+		utils.Fatalf("failed to unmount the dxfiles: %s", err.Error())
+	}
+	fmt.Println(resp)
+	return nil
+}
+
 func gdxAttach(ctx *cli.Context) (*rpc.Client, error) {
 	path := node.DefaultDataDir()
 	if ctx.GlobalIsSet(utils.DataDirFlag.Name) {
@@ -867,14 +1305,18 @@ func hostRankingTable(rankings []storagehostmanager.StorageHostRank) *tablewrite
 	var formattedData [][]string
 
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"ID", "Total Evaluation", "AgeFactor", "DepositFactor",
-		"InteractionFactor", "PriceFactor", "RemainingStorageFactor", "UptimeFactor"})
+	table.SetHeader([]string{"ID", "Total Evaluation", "Score", "AgeScore", "DepositScore",
+		"InteractionScore", "PriceScore", "RemainingStorageScore", "UptimeScore", "QoSScore", "PriceDiscrepancies",
+		"PriceBlacklisted"})
 
+	// the normalized scores are displayed, which are comparable across the client versions
 	for _, rank := range rankings {
-		dataEntry := []string{rank.EnodeID, rank.Evaluation.String(), floatToString(rank.PresenceFactor),
-			floatToString(rank.DepositFactor),
-			floatToString(rank.InteractionFactor), floatToString(rank.ContractPriceFactor),
-			floatToString(rank.StorageRemainingFactor), floatToString(rank.UptimeFactor)}
+		scores := rank.Scores
+		dataEntry := []string{rank.EnodeID, rank.Evaluation.String(), floatToString(rank.Score),
+			floatToString(scores.Presence), floatToString(scores.Deposit),
+			floatToString(scores.Interaction), floatToString(scores.ContractPrice),
+			floatToString(scores.StorageRemaining), floatToString(scores.Uptime), floatToString(scores.QoS),
+			fmt.Sprintf("%v", rank.PriceDiscrepancies), boolToString(rank.PriceBlacklisted)}
 
 		formattedData = append(formattedData, dataEntry)
 	}
@@ -897,3 +1339,273 @@ func boolToString(val bool) string {
 	}
 	return "false"
 }
+
+func transitionStorageClass(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	if !ctx.IsSet(filePathFlag.Name) && !ctx.IsSet(storageClassFlag.Name) {
+		var classes []storageclient.StorageClass
+		if err = client.Call(&classes, "sclient_storageClasses"); err != nil {
+			utils.Fatalf("failed to retrieve the storage classes: %s", err.Error())
+		}
+		fmt.Println("Storage Classes:")
+		for _, class := range classes {
+			fmt.Printf("\t%-12s %d of %d sectors, %s hosts\n", class.Name, class.MinSectors, class.NumSectors, class.HostPreference)
+		}
+
+		var transitions []storageclient.StorageClassTransition
+		if err = client.Call(&transitions, "sclient_storageClassTransitions"); err != nil {
+			utils.Fatalf("failed to retrieve the storage class transitions: %s", err.Error())
+		}
+		if len(transitions) == 0 {
+			fmt.Println("No storage class transition in progress")
+			return nil
+		}
+		fmt.Println("Transitions In Progress:")
+		for _, t := range transitions {
+			fmt.Printf("\t%s -> %s, %.2f%% uploaded, started at %s\n", t.DxPath.Path, t.Class, t.Progress, t.StartTime.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	if !ctx.IsSet(filePathFlag.Name) || !ctx.IsSet(storageClassFlag.Name) {
+		utils.Fatalf("both the filepath and the class must be specified")
+	}
+	var resp string
+	if err = client.Call(&resp, "sclient_transitionStorageClass", ctx.String(filePathFlag.Name), ctx.String(storageClassFlag.Name)); err != nil {
+		utils.Fatalf("failed to transition the storage class: %s", err.Error())
+	}
+	fmt.Println(resp)
+	return nil
+}
+
+func backupClient(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	path, err := backupPath(ctx)
+	if err != nil {
+		utils.Fatalf("invalid backup path: %s", err.Error())
+	}
+	passphrase := getPassPhrase("The backup is encrypted with a passphrase. Do not forget this passphrase.", true, 0, utils.MakePasswordList(ctx))
+
+	var resp string
+	if err = client.Call(&resp, "sclient_backup", path, passphrase); err != nil {
+		utils.Fatalf("%s", err.Error())
+	}
+	fmt.Println(resp)
+	return nil
+}
+
+func restoreClient(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	path, err := backupPath(ctx)
+	if err != nil {
+		utils.Fatalf("invalid backup path: %s", err.Error())
+	}
+	passphrase := getPassPhrase("Please give the passphrase of the backup.", false, 0, utils.MakePasswordList(ctx))
+
+	var resp string
+	if err = client.Call(&resp, "sclient_restore", path, passphrase); err != nil {
+		utils.Fatalf("%s", err.Error())
+	}
+	fmt.Println(resp)
+	return nil
+}
+
+func verifyContracts(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	var report storageclient.ContractSetReport
+	if err = client.Call(&report, "sclient_verifyContracts"); err != nil {
+		utils.Fatalf("failed to verify the contracts: %s", err.Error())
+	}
+
+	fmt.Printf("%v contracts consistent, %v contracts inconsistent\n", report.Consistent, report.Inconsistent)
+	for _, c := range report.Contracts {
+		if c.Consistent {
+			continue
+		}
+		fmt.Printf(`Contract %s:
+	Host:                   %s
+	Revision Number:        %v
+	Chain Revision Number:  %v
+	Merkle Roots:           %v stored, %v expected
+	Remediation:            %s
+`, c.ContractID, c.HostID, c.RevisionNumber, c.ChainRevisionNumber, c.NumRoots, c.ExpectedRoots, c.Remediation)
+		for _, problem := range c.Inconsistencies {
+			fmt.Printf("\t  - %s\n", problem)
+		}
+	}
+	return nil
+}
+
+func loadBootstrapSnapshot(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	path, err := snapshotPath(ctx)
+	if err != nil {
+		utils.Fatalf("invalid snapshot path: %s", err.Error())
+	}
+	var resp string
+	if err = client.Call(&resp, "sclient_loadBootstrapSnapshot", path, ctx.String(snapshotSignerFlag.Name)); err != nil {
+		utils.Fatalf("%s", err.Error())
+	}
+	fmt.Println(resp)
+	return nil
+}
+
+func exportBootstrapSnapshot(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	path, err := snapshotPath(ctx)
+	if err != nil {
+		utils.Fatalf("invalid snapshot path: %s", err.Error())
+	}
+	var resp string
+	if err = client.Call(&resp, "sclient_exportBootstrapSnapshot", path, ctx.String(snapshotSignerFlag.Name), ctx.Int(snapshotHostsFlag.Name)); err != nil {
+		utils.Fatalf("%s", err.Error())
+	}
+	fmt.Println(resp)
+	return nil
+}
+
+// snapshotPath returns the absolute path of the bootstrap snapshot, as the path is resolved by the gdx node
+func snapshotPath(ctx *cli.Context) (string, error) {
+	if !ctx.IsSet(snapshotPathFlag.Name) {
+		return "", fmt.Errorf("the --%s flag is required", snapshotPathFlag.Name)
+	}
+	return filepath.Abs(ctx.String(snapshotPathFlag.Name))
+}
+
+// backupPath returns the absolute path of the backup file, as the path is resolved by the gdx node
+func backupPath(ctx *cli.Context) (string, error) {
+	if !ctx.IsSet(backupPathFlag.Name) {
+		return "", fmt.Errorf("the --%s flag is required", backupPathFlag.Name)
+	}
+	return filepath.Abs(ctx.String(backupPathFlag.Name))
+}
+
+func describeFile(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	if !ctx.IsSet(filePathFlag.Name) {
+		utils.Fatalf("must specify the file path used for uploading in order to describe the file")
+	}
+	path, err := descriptorPath(ctx)
+	if err != nil {
+		utils.Fatalf("invalid descriptor path: %s", err.Error())
+	}
+
+	var resp string
+	if err = client.Call(&resp, "sclient_describeFile", ctx.String(filePathFlag.Name), path); err != nil {
+		utils.Fatalf("%s", err.Error())
+	}
+	fmt.Println(resp)
+	return nil
+}
+
+func recoverFile(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	path, err := descriptorPath(ctx)
+	if err != nil {
+		utils.Fatalf("invalid descriptor path: %s", err.Error())
+	}
+
+	var report storageclient.FileRecoveryReport
+	if err = client.Call(&report, "sclient_recoverFile", path); err != nil {
+		utils.Fatalf("%s", err.Error())
+	}
+	fmt.Printf(`File Recovery:
+	DxPath:               %s
+	ContractsSearched:    %v
+	Segments:             %v/%v recovered
+	Sectors:              %v/%v found
+`, report.DxPath, report.ContractsSearched, report.RecoverableSegments, report.Segments,
+		report.SectorsFound, report.Sectors)
+	if len(report.UnrecoverableSegments) != 0 {
+		fmt.Printf("\tUnrecoverable segments: %v\n", report.UnrecoverableSegments)
+	}
+	return nil
+}
+
+func verifyFile(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	if !ctx.IsSet(filePathFlag.Name) || !ctx.IsSet(fileDestinationFlag.Name) {
+		utils.Fatalf("must specify the file path used for uploading and the path of the downloaded file")
+	}
+	localPath, err := filepath.Abs(ctx.String(fileDestinationFlag.Name))
+	if err != nil {
+		utils.Fatalf("invalid downloaded file path: %s", err.Error())
+	}
+
+	var report storageclient.FileIntegrityReport
+	if err = client.Call(&report, "sclient_verifyFile", ctx.String(filePathFlag.Name), localPath); err != nil {
+		utils.Fatalf("%s", err.Error())
+	}
+	fmt.Printf(`File Integrity:
+	DxPath:               %s
+	LocalPath:            %s
+	Verified:             %v
+	Segments:             %v/%v verified
+	Sectors Fetched:      %v
+	Sectors Covered:      %v
+	Sectors Uncovered:    %v
+`, report.DxPath, report.LocalPath, report.Verified, report.SegmentsVerified, report.Segments,
+		report.SectorsFetched, report.SectorsCovered, report.SectorsUncovered)
+	for _, f := range report.Failures {
+		fmt.Printf("\tSegment %v failed: %s\n", f.Index, f.Reason)
+	}
+	for _, c := range report.Contracts {
+		fmt.Printf(`Contract %s:
+	HostID:               %s
+	Revision:             %v (on chain: %v)
+	FileMerkleRoot:       %s
+	Roots:                %v
+	RootsMatch:           %v
+	FileSizeMatch:        %v
+	SignaturesValid:      %v
+	SectorsCovered:       %v
+	Valid:                %v
+`, c.ContractID, c.HostID, c.RevisionNumber, c.ChainRevisionNumber, c.FileMerkleRoot.String(), c.NumRoots,
+			c.RootsMatch, c.FileSizeMatch, c.SignaturesValid, c.SectorsCovered, c.Valid)
+	}
+	return nil
+}
+
+// descriptorPath returns the absolute path of the file descriptor
+func descriptorPath(ctx *cli.Context) (string, error) {
+	if !ctx.IsSet(descriptorPathFlag.Name) {
+		return "", fmt.Errorf("the --%s flag is required", descriptorPathFlag.Name)
+	}
+	return filepath.Abs(ctx.String(descriptorPathFlag.Name))
+}
@@ -6,6 +6,8 @@ package main
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/DxChainNetwork/godx/cmd/utils"
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/storagehost"
@@ -73,6 +75,31 @@ var (
 		Name:  "folderPath",
 		Usage: "Path of the folder",
 	}
+
+	newFolderPathFlag = cli.StringFlag{
+		Name:  "newFolderPath",
+		Usage: "New path of the folder",
+	}
+
+	priceNameFlag = cli.StringFlag{
+		Name:  "priceName",
+		Usage: "Name of the price to be scheduled, for example storagePrice",
+	}
+
+	scheduledPriceFlag = cli.StringFlag{
+		Name:  "price",
+		Usage: "CURRENCY - the price taking effect at the effective height",
+	}
+
+	effectiveHeightFlag = cli.Uint64Flag{
+		Name:  "height",
+		Usage: "Block height at which the scheduled price takes effect",
+	}
+
+	clientIDFlag = cli.StringFlag{
+		Name:  "clientID",
+		Usage: "Node ID of the storage client",
+	}
 )
 
 var storageHostCommand = cli.Command{
@@ -125,6 +152,124 @@ The values are associated with units.
 	CURRENCY:   {"camel", "gcamel", "dx"}
 	DURATION:   {"h", "b", "d", "w", "m", "y"}`,
 		},
+		{
+			Name:      "validateConfig",
+			Usage:     "Validate the storage host configurations without applying",
+			ArgsUsage: "",
+			Flags: []cli.Flag{
+				acceptingContractsFlag,
+				storageDurationFlag,
+				depositPriceFlag,
+				contractPriceFlag,
+				downloadPriceFlag,
+				uploadPriceFlag,
+				storagePriceFlag,
+				budgetPriceFlag,
+				maxDepositFlag,
+				folderPathFlag,
+				folderSizeFlag,
+			},
+
+			Action: utils.MigrateFlags(validateHostConfig),
+			Description: `
+			gdx shost validateConfig [--acceptingContracts arg] [--storagePrice arg] ... [--folderPath arg --size arg]
+
+validate the storage host configuration with the same flags as setConfig, along with the
+storage folder to be added or resized. The configuration is checked against the protocol
+limits, the balance of the payment address and the disk space, and the errors and warnings
+are reported. Nothing is applied to the storage host.`,
+		},
+		{
+			Name:      "schedulePrice",
+			Usage:     "Schedule a price change at a future block height",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(scheduleHostPrice),
+			Flags: []cli.Flag{
+				priceNameFlag,
+				scheduledPriceFlag,
+				effectiveHeightFlag,
+			},
+			Description: `
+			gdx shost schedulePrice [--priceName arg] [--price arg] [--height arg]
+
+will pre-announce the price change to the storage clients. The price specified by --priceName
+will be changed to --price once the block height reaches --height. The price names that can be
+scheduled are baseRPCPrice, contractPrice, downloadBandwidthPrice, sectorAccessPrice, storagePrice,
+and uploadBandwidthPrice.`,
+		},
+
+		{
+			Name:      "cancelPrice",
+			Usage:     "Cancel the price change scheduled at a future block height",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(cancelHostPrice),
+			Flags: []cli.Flag{
+				priceNameFlag,
+				effectiveHeightFlag,
+			},
+			Description: `
+			gdx shost cancelPrice [--priceName arg] [--height arg]
+
+will cancel the price change of --priceName scheduled at block --height.`,
+		},
+
+		{
+			Name:      "reputation",
+			Usage:     "Retrieve the negotiation abuse reports of the storage clients",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(getClientReputation),
+			Description: `
+			gdx shost reputation
+
+will display the storage clients which abused the negotiation with the host, including malformed
+messages, timeouts after the data was transferred, and commit failures. The score decays over time.
+The requests of the clients with a high score are deprioritized, and the clients are temporarily
+banned once the score reaches the limit.`,
+		},
+
+		{
+			Name:      "negotiations",
+			Usage:     "Retrieve the memory and the bandwidth used by the negotiations in progress",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(getNegotiationUsage),
+			Description: `
+			gdx shost negotiations
+
+will display the bytes buffered and the bandwidth consumed by each negotiation in progress, along
+with the totals and the limits. The negotiations exceeding the per negotiation limits are rejected,
+and the negotiations exceeding the global limits wait for the others to finish.`,
+		},
+
+		{
+			Name:      "resetReputation",
+			Usage:     "Remove the negotiation abuse record of a storage client",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(resetClientReputation),
+			Flags: []cli.Flag{
+				clientIDFlag,
+			},
+			Description: `
+			gdx shost resetReputation [--clientID arg]
+
+will remove the negotiation abuse record of the storage client, which lifts the ban as well.`,
+		},
+
+		{
+			Name:      "resubmitProof",
+			Usage:     "Construct and send the storage proof of a contract again",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(resubmitProof),
+			Flags: []cli.Flag{
+				contractIDFlag,
+			},
+			Description: `
+			gdx shost resubmitProof [--contractid arg]
+
+will construct and send the storage proof of the contract again, when the previous submission
+failed. The proof can only be resubmitted after the proof window is open and the proof has been
+submitted automatically, and not too close to the proof deadline.`,
+		},
+
 		{
 			Name:      "setPaymentAddr",
 			Usage:     "Register the account address to be used for the storage services",
@@ -165,6 +310,18 @@ will display the detailed financial metrics for hosting the storage service, inc
 potential revenue.`,
 		},
 
+		{
+			Name:      "margins",
+			Usage:     "Retrieve the net margin of each storage contract after the transaction fees",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(getContractMargins),
+			Description: `
+			gdx shost margins
+
+will display the revenue, the gas spent on the storage proof, the net margin and the storage deposit
+at risk of each storage contract. The contracts with the lowest margins are listed first.`,
+		},
+
 		{
 			Name:      "announce",
 			Usage:     "Announce the node as a storage host node",
@@ -179,6 +336,42 @@ If the host node has higher evaluation, client will automatically create contrac
 		`,
 		},
 
+		{
+			Name:      "retire",
+			Usage:     "Retire the node from hosting the storage service",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(retireHost),
+			Description: `
+			gdx shost retire
+
+will put the host into the retirement mode. The host stops accepting new contracts and uploads, but keeps
+serving downloads and submitting storage proofs until all contracts are resolved. Use the retirement command
+to check the estimated block height when the retirement completes.`,
+		},
+
+		{
+			Name:      "cancelRetire",
+			Usage:     "Cancel the retirement, and accept new contracts and uploads again",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(cancelHostRetirement),
+			Description: `
+			gdx shost cancelRetire
+
+will bring the retiring host back to accept new contracts and uploads.`,
+		},
+
+		{
+			Name:      "retirement",
+			Usage:     "Retrieve the retirement status of the storage host",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(getHostRetirement),
+			Description: `
+			gdx shost retirement
+
+will display the retirement status of the host, including the number of unresolved contracts and the
+estimated block height when all of them are resolved.`,
+		},
+
 		{
 			Name:      "addFolder",
 			Usage:     "Allocate disk space for saving data uploaded by the storage client",
@@ -230,6 +423,23 @@ will free up the disk space used for saving data uploaded by the storage client.
 specified using --folderPath.`,
 		},
 
+		{
+			Name:      "remapFolder",
+			Usage:     "Update the folder path after the folder data file is moved to a new location",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(remapFolder),
+			Flags: []cli.Flag{
+				folderPathFlag,
+				newFolderPathFlag,
+			},
+			Description: `
+			gdx shost remapFolder [--folderPath arg] [--newFolderPath arg]
+
+will update the path of the folder after the data file in the folder is moved to a new location, for
+example a new mount point. The content of the data file must not be changed, so that all data saved
+in the folder are kept. Both --folderPath and --newFolderPath must be specified.`,
+		},
+
 		{
 			Name:      "paymentAddr",
 			Usage:     "Retrieve the account address used for storage service revenue",
@@ -260,7 +470,9 @@ func getHostConfig(ctx *cli.Context) error {
 	MaxDownloadBatchSize:          %v
 	MaxDuration:                   %v
 	MaxReviseBatchSize:            %v
+	MaxUploadBatchSectors:         %v
 	WindowSize:                    %v
+	DeletionRetention:             %v
 	PaymentAddress:                %s 
 	Deposit:                       %v
 	DepositBudget:                 %v
@@ -272,11 +484,18 @@ func getHostConfig(ctx *cli.Context) error {
 	StoragePrice:                  %v
 	UploadBandwidthPrice:          %v
 `, config.AcceptingContracts, config.MaxDownloadBatchSize, config.MaxDuration,
-		config.MaxReviseBatchSize, config.WindowSize, config.PaymentAddress,
+		config.MaxReviseBatchSize, config.MaxUploadBatchSectors, config.WindowSize, config.DeletionRetention, config.PaymentAddress,
 		config.Deposit, config.DepositBudget, config.MaxDeposit, config.BaseRPCPrice,
 		config.ContractPrice, config.DownloadBandwidthPrice, config.SectorAccessPrice,
 		config.StoragePrice, config.UploadBandwidthPrice)
 
+	if len(config.ScheduledPrices) != 0 {
+		fmt.Println("Scheduled Prices:")
+		for _, sp := range config.ScheduledPrices {
+			fmt.Printf("\t%s\n", sp)
+		}
+	}
+
 	return nil
 }
 
@@ -298,6 +517,39 @@ func setHostConfig(ctx *cli.Context) error {
 	return nil
 }
 
+// validateHostConfig validates the storage host settings without applying
+func validateHostConfig(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+	config := hostConfigFromFlags(ctx)
+	folders := make(map[string]string)
+	if ctx.IsSet(folderPathFlag.Name) {
+		if !ctx.IsSet(folderSizeFlag.Name) {
+			utils.Fatalf("the --size flag must be used along with the --folderPath flag")
+		}
+		folders[ctx.String(folderPathFlag.Name)] = ctx.String(folderSizeFlag.Name)
+	}
+
+	var report storagehost.HostConfigValidation
+	if err = client.Call(&report, "shost_validateConfig", config, folders); err != nil {
+		utils.Fatalf("failed to validate config: %v", err)
+	}
+	for _, e := range report.Errors {
+		fmt.Printf("ERROR:   %v\n", e)
+	}
+	for _, w := range report.Warnings {
+		fmt.Printf("WARNING: %v\n", w)
+	}
+	if report.Valid {
+		fmt.Println("The host configuration is valid")
+	} else {
+		fmt.Println("The host configuration is invalid")
+	}
+	return nil
+}
+
 // hostConfigFromFlags gets the user set flag values from the command line arguments
 func hostConfigFromFlags(ctx *cli.Context) map[string]string {
 	config := make(map[string]string)
@@ -423,13 +675,16 @@ func getFinance(ctx *cli.Context) error {
 	RiskedStorageDeposit:                   %v 
 	StorageRevenue:                         %v 
 	TransactionFeeExpenses:                 %v 
+	AnnouncementFeeExpenses:                %v 
+	NetRevenue:                             %v 
 	DownloadBandwidthRevenue:               %v 
 	PotentialDownloadBandwidthRevenue:      %v 
 	PotentialUploadBandwidthRevenue:        %v 
 	UploadBandwidthRevenue:                 %v 
 `, finance.ContractCount, finance.ContractCompensation, finance.PotentialContractCompensation,
 		finance.LockedStorageDeposit, finance.LostRevenue, finance.LostStorageDeposit, finance.PotentialStorageRevenue,
-		finance.RiskedStorageDeposit, finance.StorageRevenue, finance.TransactionFeeExpenses, finance.DownloadBandwidthRevenue,
+		finance.RiskedStorageDeposit, finance.StorageRevenue, finance.TransactionFeeExpenses, finance.AnnouncementFeeExpenses,
+		finance.NetRevenue, finance.DownloadBandwidthRevenue,
 		finance.PotentialDownloadBandwidthRevenue, finance.PotentialUploadBandwidthRevenue, finance.UploadBandwidthRevenue)
 
 	return nil
@@ -450,6 +705,61 @@ func makeAnnounce(ctx *cli.Context) error {
 	return nil
 }
 
+func retireHost(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	var resp string
+	if err = client.Call(&resp, "shost_retire"); err != nil {
+		utils.Fatalf("failed to retire the storage host: %s", err.Error())
+	}
+
+	fmt.Println(resp)
+	return nil
+}
+
+func cancelHostRetirement(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	var resp string
+	if err = client.Call(&resp, "shost_cancelRetirement"); err != nil {
+		utils.Fatalf("failed to cancel the host retirement: %s", err.Error())
+	}
+
+	fmt.Println(resp)
+	return nil
+}
+
+func getHostRetirement(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	var status storagehost.HostRetirement
+	if err = client.Call(&status, "shost_retirementStatus"); err != nil {
+		utils.Fatalf("failed to get the host retirement status: %s", err.Error())
+	}
+
+	fmt.Printf(`Host Retirement:
+	Retiring:                       %v
+	Start Height:                   %v
+	Block Height:                   %v
+	Unresolved Contracts:           %v
+	Estimated Complete At:          %v
+	Remaining Blocks:               %v
+	Completed:                      %v
+`, status.Retiring, status.StartHeight, status.BlockHeight, status.UnresolvedContracts,
+		status.EstimatedCompleteAt, status.RemainingBlocks, status.Completed)
+
+	return nil
+}
+
 func addFolder(ctx *cli.Context) error {
 	client, err := gdxAttach(ctx)
 	if err != nil {
@@ -528,6 +838,210 @@ func deleteFolder(ctx *cli.Context) error {
 	return nil
 }
 
+func remapFolder(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	var oldPath, newPath string
+	if !ctx.IsSet(folderPathFlag.Name) {
+		utils.Fatalf("the --folderpath flag must be used to specify the folder to be remapped")
+	} else {
+		oldPath = ctx.String(folderPathFlag.Name)
+	}
+
+	if !ctx.IsSet(newFolderPathFlag.Name) {
+		utils.Fatalf("the --newFolderPath flag must be used to specify the new location of the folder")
+	} else {
+		newPath = ctx.String(newFolderPathFlag.Name)
+	}
+
+	var resp string
+	if err = client.Call(&resp, "shost_remapFolder", oldPath, newPath); err != nil {
+		utils.Fatalf("failed to remap the folder: %s", err.Error())
+	}
+
+	fmt.Printf("%s \n\n", resp)
+	return nil
+}
+
+func scheduleHostPrice(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	if !ctx.IsSet(priceNameFlag.Name) || !ctx.IsSet(scheduledPriceFlag.Name) || !ctx.IsSet(effectiveHeightFlag.Name) {
+		utils.Fatalf("the --priceName, --price, and --height flags must be used to specify the scheduled price")
+	}
+
+	var resp string
+	if err = client.Call(&resp, "shost_schedulePrice", ctx.String(priceNameFlag.Name),
+		ctx.String(scheduledPriceFlag.Name), ctx.Uint64(effectiveHeightFlag.Name)); err != nil {
+		utils.Fatalf("failed to schedule the price: %s", err.Error())
+	}
+
+	fmt.Printf("%s \n\n", resp)
+	return nil
+}
+
+func cancelHostPrice(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	if !ctx.IsSet(priceNameFlag.Name) || !ctx.IsSet(effectiveHeightFlag.Name) {
+		utils.Fatalf("the --priceName and --height flags must be used to specify the scheduled price")
+	}
+
+	var resp string
+	if err = client.Call(&resp, "shost_cancelScheduledPrice", ctx.String(priceNameFlag.Name),
+		ctx.Uint64(effectiveHeightFlag.Name)); err != nil {
+		utils.Fatalf("failed to cancel the scheduled price: %s", err.Error())
+	}
+
+	fmt.Printf("%s \n\n", resp)
+	return nil
+}
+
+func getContractMargins(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	var margins []storagehost.ContractMargin
+	if err = client.Call(&margins, "shost_contractMargins"); err != nil {
+		utils.Fatalf("failed to get the contract margins: %s", err.Error())
+	}
+
+	if len(margins) == 0 {
+		fmt.Println("No storage contract found")
+		return nil
+	}
+
+	for _, m := range margins {
+		fmt.Printf(`Contract %s:
+	Status:           %s
+	Expiration:       %v
+	Revenue:          %s
+	Transaction Fees: %s
+	Net Margin:       %s
+	Risked Deposit:   %s
+`, m.ContractID, m.Status, m.Expiration, m.Revenue, m.TransactionFees, m.NetMargin, m.RiskedDeposit)
+	}
+	return nil
+}
+
+func getClientReputation(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	var reports []storagehost.ClientReputation
+	if err = client.Call(&reports, "shost_clientReputation"); err != nil {
+		utils.Fatalf("failed to get the client reputation: %s", err.Error())
+	}
+
+	if len(reports) == 0 {
+		fmt.Println("No storage client abused the negotiation")
+		return nil
+	}
+
+	for _, r := range reports {
+		status := "normal"
+		if !r.BannedUntil.IsZero() {
+			status = fmt.Sprintf("banned until %v", r.BannedUntil.Format(time.RFC3339))
+		} else if r.Deprioritized {
+			status = "deprioritized"
+		}
+		fmt.Printf(`Client %s:
+	Score:              %.2f
+	Status:             %s
+	Malformed Messages: %v
+	Timeouts:           %v
+	Commit Failures:    %v
+	Bans:               %v
+`, r.ClientID, r.Score, status, r.MalformedMsgs, r.Timeouts, r.CommitFailures, r.Bans)
+	}
+	return nil
+}
+
+func getNegotiationUsage(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	var usage storagehost.NegotiationUsage
+	if err = client.Call(&usage, "shost_negotiationUsage"); err != nil {
+		utils.Fatalf("failed to get the negotiation usage: %s", err.Error())
+	}
+
+	fmt.Printf(`Negotiations:
+	Active:                      %v
+	Waiting:                     %v
+	Buffered:                    %v / %v bytes
+	Buffered Per Negotiation:    %v bytes at most
+	Bandwidth Consumed:          %v bytes
+	Bandwidth Rate:              %v bytes per second
+	Bandwidth Per Negotiation:   %v bytes at most
+	Throttled:                   %v
+	Rejected:                    %v
+`, usage.Active, usage.Waiting, usage.Buffered, usage.MemoryLimit, usage.NegotiationMemoryLimit,
+		usage.Bandwidth, usage.BandwidthRate, usage.NegotiationBandwidthLimit, usage.Throttled, usage.Rejected)
+
+	for _, n := range usage.Negotiations {
+		fmt.Printf(`Negotiation %s with client %s:
+	Started:    %v
+	Buffered:   %v bytes
+	Bandwidth:  %v bytes
+`, n.Kind, n.ClientID, n.Started.Format(time.RFC3339), n.Buffered, n.Bandwidth)
+	}
+	return nil
+}
+
+func resetClientReputation(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	if !ctx.IsSet(clientIDFlag.Name) {
+		utils.Fatalf("the --clientID flag must be used to specify the storage client")
+	}
+
+	var resp string
+	if err = client.Call(&resp, "shost_resetClientReputation", ctx.String(clientIDFlag.Name)); err != nil {
+		utils.Fatalf("failed to reset the client reputation: %s", err.Error())
+	}
+
+	fmt.Printf("%s \n\n", resp)
+	return nil
+}
+
+func resubmitProof(ctx *cli.Context) error {
+	client, err := gdxAttach(ctx)
+	if err != nil {
+		utils.Fatalf("unable to connect to remote gdx, please start the gdx first: %s", err.Error())
+	}
+
+	if !ctx.IsSet(contractIDFlag.Name) {
+		utils.Fatalf("the --contractid flag must be used to specify the contract")
+	}
+
+	var resp string
+	if err = client.Call(&resp, "shost_resubmitProof", ctx.String(contractIDFlag.Name)); err != nil {
+		utils.Fatalf("failed to resubmit the storage proof: %s", err.Error())
+	}
+
+	fmt.Printf("%s \n\n", resp)
+	return nil
+}
+
 func getHostPaymentAddress(ctx *cli.Context) error {
 	client, err := gdxAttach(ctx)
 	if err != nil {
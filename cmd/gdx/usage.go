@@ -157,6 +157,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.RPCAuthNamespacesFlag,
+			utils.RPCAuthTokensFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
@@ -236,6 +238,11 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "STORAGE",
 		Flags: []cli.Flag{
 			utils.StorageRoleFlag,
+			utils.StorageClientPassphraseFileFlag,
+			utils.StorageClientGatewayFlag,
+			utils.StorageClientS3GatewayFlag,
+			utils.StorageCompressionFlag,
+			utils.StorageCompressionThresholdFlag,
 		},
 	},
 	{
@@ -55,6 +55,7 @@ import (
 	"github.com/DxChainNetwork/godx/p2p/nat"
 	"github.com/DxChainNetwork/godx/p2p/netutil"
 	"github.com/DxChainNetwork/godx/params"
+	"github.com/DxChainNetwork/godx/storage"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -427,6 +428,16 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCAuthNamespacesFlag = cli.StringFlag{
+		Name:  "rpcauth.namespaces",
+		Usage: "Comma separated list of API namespaces requiring an authentication token over the HTTP-RPC and WS-RPC interfaces",
+		Value: "",
+	}
+	RPCAuthTokensFlag = cli.StringFlag{
+		Name:  "rpcauth.tokens",
+		Usage: "Comma separated list of authentication tokens with their granted namespaces, e.g. \"token1=sclient+shost,token2=*\"",
+		Value: "",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -599,6 +610,39 @@ var (
 		Name:  "role",
 		Usage: "Chooses which role a node can be. There are four options: all, host, client, and none",
 	}
+
+	// StorageClientPassphraseFileFlag specifies the file containing the passphrase
+	// used to encrypt the storage contracts at rest
+	StorageClientPassphraseFileFlag = cli.StringFlag{
+		Name:  "sclient.passphrase",
+		Usage: "Passphrase file used to encrypt the storage client contracts at rest",
+	}
+
+	// StorageClientGatewayFlag specifies the listening address of the storage client gateway
+	StorageClientGatewayFlag = cli.StringFlag{
+		Name:  "sclient.gateway",
+		Usage: "Listening address of the storage client JSON/HTTP gateway, e.g. localhost:8585 (disabled if empty). Requests must carry the bearer token in gateway.token under the storage client directory",
+	}
+
+	// StorageClientS3GatewayFlag specifies the listening address of the storage client S3 gateway
+	StorageClientS3GatewayFlag = cli.StringFlag{
+		Name:  "sclient.s3gateway",
+		Usage: "Listening address of the storage client S3 compatible object gateway, e.g. localhost:8586 (disabled if empty). Requests must be signed (AWS signature v4) with the access key in s3gateway.credentials under the storage client directory",
+	}
+
+	// StorageCompressionFlag specifies the codec used to compress the storage messages
+	StorageCompressionFlag = cli.StringFlag{
+		Name:  "storage.compression",
+		Usage: "Codec used to compress the storage messages (none, snappy, deflate)",
+		Value: eth.DefaultConfig.StorageCompression.Codec,
+	}
+
+	// StorageCompressionThresholdFlag specifies the minimum size of the storage messages to be compressed
+	StorageCompressionThresholdFlag = cli.Uint64Flag{
+		Name:  "storage.compressionthreshold",
+		Usage: "Minimum payload size in bytes of the storage messages to be compressed",
+		Value: eth.DefaultConfig.StorageCompression.Threshold,
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -782,6 +826,41 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCAuth applies the authentication of the HTTP and websocket RPC interfaces from
+// the command line flags
+func setRPCAuth(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCAuthNamespacesFlag.Name) {
+		cfg.RPCAuthNamespaces = splitAndTrim(ctx.GlobalString(RPCAuthNamespacesFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCAuthTokensFlag.Name) {
+		tokens, err := parseRPCAuthTokens(ctx.GlobalString(RPCAuthTokensFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", RPCAuthTokensFlag.Name, err)
+		}
+		cfg.RPCAuthTokens = tokens
+	}
+}
+
+// parseRPCAuthTokens parses the tokens in the format of token=namespace+namespace,...
+func parseRPCAuthTokens(input string) (map[string][]string, error) {
+	tokens := make(map[string][]string)
+	for _, entry := range splitAndTrim(input) {
+		if entry == "" {
+			continue
+		}
+		index := strings.LastIndex(entry, "=")
+		if index <= 0 || index == len(entry)-1 {
+			return nil, fmt.Errorf("invalid token entry %q, expect token=namespace+namespace", entry)
+		}
+		for _, scope := range strings.Split(entry[index+1:], "+") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				tokens[entry[:index]] = append(tokens[entry[:index]], scope)
+			}
+		}
+	}
+	return tokens, nil
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -944,6 +1023,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCAuth(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	setDataDir(ctx, cfg)
@@ -1214,6 +1294,34 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		}
 	}
 
+	if ctx.GlobalIsSet(StorageClientPassphraseFileFlag.Name) {
+		text, err := ioutil.ReadFile(ctx.GlobalString(StorageClientPassphraseFileFlag.Name))
+		if err != nil {
+			Fatalf("Failed to read storage client passphrase file: %v", err)
+		}
+		cfg.StorageClientPassphrase = strings.TrimRight(strings.Split(string(text), "\n")[0], "\r")
+	}
+
+	if ctx.GlobalIsSet(StorageClientGatewayFlag.Name) {
+		cfg.StorageClientGateway = ctx.GlobalString(StorageClientGatewayFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(StorageClientS3GatewayFlag.Name) {
+		cfg.StorageClientS3Gateway = ctx.GlobalString(StorageClientS3GatewayFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(StorageCompressionFlag.Name) {
+		codec := ctx.GlobalString(StorageCompressionFlag.Name)
+		if err := storage.ValidateCompressionCodec(codec); err != nil {
+			Fatalf("Invalid storage compression: %v", err)
+		}
+		cfg.StorageCompression.Codec = codec
+	}
+
+	if ctx.GlobalIsSet(StorageCompressionThresholdFlag.Name) {
+		cfg.StorageCompression.Threshold = ctx.GlobalUint64(StorageCompressionThresholdFlag.Name)
+	}
+
 	// If datadir is set, change ethash directory
 	if ctx.GlobalIsSet(DataDirFlag.Name) {
 		cfg.Ethash.DatasetDir = filepath.Join(ctx.GlobalString(DataDirFlag.Name), "Ethash")
@@ -0,0 +1,207 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// Package alert provides the node-wide registry of the alerts raised by the
+// storage modules. Alerts raised repeatedly with the same module and message
+// are merged into a single entry, until the alert is dismissed.
+package alert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DxChainNetwork/godx/log"
+)
+
+// Severity is the severity of an alert
+type Severity int
+
+const (
+	// SeverityInfo is the severity of the alert for information only
+	SeverityInfo Severity = iota
+
+	// SeverityWarning is the severity of the alert which may need attention
+	SeverityWarning
+
+	// SeverityError is the severity of the alert for a failed operation
+	SeverityError
+
+	// SeverityCritical is the severity of the alert which may cause loss of fund or data
+	SeverityCritical
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the severity as its name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the severity from its name
+func (s *Severity) UnmarshalText(text []byte) error {
+	for severity := SeverityInfo; severity <= SeverityCritical; severity++ {
+		if severity.String() == string(text) {
+			*s = severity
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown alert severity %s", text)
+}
+
+// Alert is an alert raised by a module of the node
+type Alert struct {
+	ID        string    `json:"id"`
+	Severity  Severity  `json:"severity"`
+	Module    string    `json:"module"`
+	Message   string    `json:"message"`
+	Detail    string    `json:"detail"`
+	FirstSeen time.Time `json:"firstseen"`
+	LastSeen  time.Time `json:"lastseen"`
+	Count     uint64    `json:"count"`
+}
+
+// Registry records the alerts raised
+type Registry struct {
+	alerts map[string]*Alert
+	lock   sync.RWMutex
+}
+
+// DefaultRegistry is the alert registry shared by the whole node
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty alert registry
+func NewRegistry() *Registry {
+	return &Registry{
+		alerts: make(map[string]*Alert),
+	}
+}
+
+// Raise records the alert into the registry and writes it to the logger of the module
+// raising it. The context is formatted as key value pairs the same way as the log, and
+// kept as the detail of the latest occurrence. The id of the alert is returned
+func (r *Registry) Raise(logger log.Logger, severity Severity, module, message string, ctx ...interface{}) string {
+	id := alertID(module, message)
+	now := time.Now()
+
+	r.lock.Lock()
+	a, exists := r.alerts[id]
+	if !exists {
+		a = &Alert{
+			ID:        id,
+			Module:    module,
+			Message:   message,
+			FirstSeen: now,
+		}
+		r.alerts[id] = a
+	}
+	// the severity of a recurring alert is the highest one raised
+	if severity > a.Severity {
+		a.Severity = severity
+	}
+	a.Detail = formatContext(ctx)
+	a.LastSeen = now
+	a.Count++
+	r.lock.Unlock()
+
+	logAlert(logger, severity, message, ctx)
+	return id
+}
+
+// Dismiss removes the alert from the registry. If raised again, the alert will be
+// recorded as a new one
+func (r *Registry) Dismiss(id string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, exists := r.alerts[id]; !exists {
+		return fmt.Errorf("alert %s does not exist", id)
+	}
+	delete(r.alerts, id)
+	return nil
+}
+
+// Alerts returns all alerts in the registry, ordered by the severity from the
+// highest, and then the last seen time from the latest
+func (r *Registry) Alerts() []Alert {
+	r.lock.RLock()
+	alerts := make([]Alert, 0, len(r.alerts))
+	for _, a := range r.alerts {
+		alerts = append(alerts, *a)
+	}
+	r.lock.RUnlock()
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Severity != alerts[j].Severity {
+			return alerts[i].Severity > alerts[j].Severity
+		}
+		return alerts[i].LastSeen.After(alerts[j].LastSeen)
+	})
+	return alerts
+}
+
+// Raise records the alert into the DefaultRegistry
+func Raise(logger log.Logger, severity Severity, module, message string, ctx ...interface{}) string {
+	return DefaultRegistry.Raise(logger, severity, module, message, ctx...)
+}
+
+// Dismiss removes the alert from the DefaultRegistry
+func Dismiss(id string) error {
+	return DefaultRegistry.Dismiss(id)
+}
+
+// Alerts returns all alerts in the DefaultRegistry
+func Alerts() []Alert {
+	return DefaultRegistry.Alerts()
+}
+
+// alertID derives the id of the alert from the module and the message, so that
+// the recurring alerts share the same id
+func alertID(module, message string) string {
+	h := sha256.Sum256([]byte(module + "/" + message))
+	return hex.EncodeToString(h[:8])
+}
+
+// formatContext formats the context key value pairs into a string
+func formatContext(ctx []interface{}) string {
+	var pairs []string
+	for i := 0; i < len(ctx); i += 2 {
+		if i+1 < len(ctx) {
+			pairs = append(pairs, fmt.Sprintf("%v=%v", ctx[i], ctx[i+1]))
+		} else {
+			pairs = append(pairs, fmt.Sprintf("%v", ctx[i]))
+		}
+	}
+	return strings.Join(pairs, " ")
+}
+
+// logAlert writes the alert to the logger with the level matching the severity
+func logAlert(logger log.Logger, severity Severity, message string, ctx []interface{}) {
+	switch severity {
+	case SeverityInfo:
+		logger.Info(message, ctx...)
+	case SeverityWarning:
+		logger.Warn(message, ctx...)
+	default:
+		logger.Error(message, ctx...)
+	}
+}
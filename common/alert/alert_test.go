@@ -0,0 +1,66 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package alert
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/log"
+)
+
+func TestRegistry_Raise(t *testing.T) {
+	r := NewRegistry()
+
+	id := r.Raise(log.Root(), SeverityWarning, "storagehost", "failed to sync", "err", "disk full")
+	if again := r.Raise(log.Root(), SeverityError, "storagehost", "failed to sync", "err", "permission denied"); again != id {
+		t.Fatalf("recurring alert should share the same id: %v != %v", again, id)
+	}
+	r.Raise(log.Root(), SeverityInfo, "storageclient", "contract renewed")
+
+	alerts := r.Alerts()
+	if len(alerts) != 2 {
+		t.Fatalf("number of alerts expect 2, got %v", len(alerts))
+	}
+	a := alerts[0]
+	if a.ID != id || a.Count != 2 || a.Severity != SeverityError || a.Detail != "err=permission denied" {
+		t.Errorf("recurring alert not expected: %+v", a)
+	}
+	if a.LastSeen.Before(a.FirstSeen) {
+		t.Errorf("last seen %v before first seen %v", a.LastSeen, a.FirstSeen)
+	}
+	if alerts[1].Module != "storageclient" {
+		t.Errorf("alerts should be ordered by severity: %+v", alerts)
+	}
+
+	if err := r.Dismiss(id); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Dismiss(id); err == nil {
+		t.Errorf("dismissing a dismissed alert should fail")
+	}
+	r.Raise(log.Root(), SeverityWarning, "storagehost", "failed to sync")
+	for _, a := range r.Alerts() {
+		if a.ID == id && (a.Count != 1 || a.Severity != SeverityWarning) {
+			t.Errorf("alert raised after dismissed should be a new one: %+v", a)
+		}
+	}
+}
+
+func TestSeverity_JSON(t *testing.T) {
+	for severity := SeverityInfo; severity <= SeverityCritical; severity++ {
+		b, err := json.Marshal(severity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Severity
+		if err = json.Unmarshal(b, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded != severity {
+			t.Errorf("severity %v decoded as %v", severity, decoded)
+		}
+	}
+}
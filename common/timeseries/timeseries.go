@@ -0,0 +1,256 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// Package timeseries provides a lightweight embedded store of the hourly aggregates
+// of a fixed set of metrics. The aggregates are kept in a ring file with one slot
+// for each hour, so that the file size is constant and the oldest hour is
+// overwritten when the ring wraps around.
+package timeseries
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+)
+
+const (
+	// fileMagic is the magic at the beginning of the ring file
+	fileMagic = "DXTS"
+
+	// fileVersion is the version of the ring file layout
+	fileVersion uint32 = 1
+
+	// valueSize is the size of a single aggregate value, a 256 bits big endian integer
+	valueSize = 32
+
+	// hourSize is the size of the hour field at the beginning of each slot
+	hourSize = 8
+
+	// headerSize is the size of the file header: magic, version, number of slots
+	// and the hash of the metric names
+	headerSize = len(fileMagic) + 4 + 8 + sha256.Size
+)
+
+var (
+	// errUnknownMetric is the error that the metric is not registered in the store
+	errUnknownMetric = errors.New("unknown metric")
+
+	// errNegativeValue is the error that a negative value is added to the aggregate
+	errNegativeValue = errors.New("the value to add must not be negative")
+
+	// errValueOverflow is the error that the aggregate exceeds 256 bits
+	errValueOverflow = errors.New("the aggregate value overflows 256 bits")
+)
+
+type (
+	// Store is the time series store backed by the ring file
+	Store struct {
+		file     *os.File
+		metrics  []string
+		index    map[string]int
+		numSlots uint64
+
+		// now is the time source, which could be replaced in tests
+		now func() time.Time
+
+		lock sync.Mutex
+	}
+
+	// Point is the aggregates of the metrics within an hour
+	Point struct {
+		Time   time.Time                `json:"time"`
+		Values map[string]common.BigInt `json:"values"`
+	}
+)
+
+// Open opens the ring file at path keeping numSlots hours of the metrics. If the file
+// does not exist, or the file is created with different metrics or number of slots,
+// the file is initialized and the previous aggregates are discarded
+func Open(path string, metrics []string, numSlots uint64) (*Store, error) {
+	if numSlots == 0 {
+		return nil, errors.New("number of slots must be positive")
+	}
+	s := &Store{
+		metrics:  metrics,
+		index:    make(map[string]int),
+		numSlots: numSlots,
+		now:      time.Now,
+	}
+	for i, metric := range metrics {
+		if _, exist := s.index[metric]; exist {
+			return nil, fmt.Errorf("duplicate metric %v", metric)
+		}
+		s.index[metric] = i
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+
+	header := s.header()
+	b := make([]byte, len(header))
+	if _, err = file.ReadAt(b, 0); err == nil && bytes.Equal(b, header) {
+		return s, nil
+	}
+	// the file is newly created or not compatible, initialize the file
+	if err = s.initFile(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add adds the value to the aggregate of the metric in the current hour. Add on a nil
+// store is a no-op, so that the modules constructed without the store could still record
+func (s *Store) Add(metric string, value common.BigInt) error {
+	if s == nil {
+		return nil
+	}
+	i, exist := s.index[metric]
+	if !exist {
+		return errUnknownMetric
+	}
+	if value.Sign() < 0 {
+		return errNegativeValue
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hour := uint64(s.now().Unix()) / 3600
+	values, err := s.readSlot(hour)
+	if err != nil {
+		return err
+	}
+	sum := values[i].Add(value)
+	if sum.BigIntPtr().BitLen() > valueSize*8 {
+		return errValueOverflow
+	}
+	values[i] = sum
+	return s.writeSlot(hour, values)
+}
+
+// Query returns the points from the hour of since to the current hour. The hours without
+// any aggregate, or already overwritten in the ring file, are not included
+func (s *Store) Query(since time.Time) ([]Point, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	end := uint64(s.now().Unix()) / 3600
+	start := uint64(0)
+	if since.Unix() > 0 {
+		start = uint64(since.Unix()) / 3600
+	}
+	if end >= s.numSlots && start <= end-s.numSlots {
+		start = end - s.numSlots + 1
+	}
+	var points []Point
+	for hour := start; hour <= end; hour++ {
+		values, err := s.readSlot(hour)
+		if err != nil {
+			return nil, err
+		}
+		point := Point{
+			Time:   time.Unix(int64(hour*3600), 0),
+			Values: make(map[string]common.BigInt),
+		}
+		for i, metric := range s.metrics {
+			if values[i].Sign() != 0 {
+				point.Values[metric] = values[i]
+			}
+		}
+		if len(point.Values) != 0 {
+			points = append(points, point)
+		}
+	}
+	return points, nil
+}
+
+// Close closes the ring file
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.file.Close()
+}
+
+// header returns the header of the ring file. The metric names are included as
+// a hash, so that the file is reinitialized when the metrics are changed
+func (s *Store) header() []byte {
+	b := make([]byte, 0, headerSize)
+	b = append(b, fileMagic...)
+	b = append(b, make([]byte, 12)...)
+	binary.BigEndian.PutUint32(b[len(fileMagic):], fileVersion)
+	binary.BigEndian.PutUint64(b[len(fileMagic)+4:], s.numSlots)
+	hash := sha256.Sum256([]byte(strings.Join(s.metrics, "\x00")))
+	return append(b, hash[:]...)
+}
+
+// initFile truncates the file to the size of all slots and writes the header
+func (s *Store) initFile(header []byte) error {
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+	if err := s.file.Truncate(int64(headerSize) + int64(s.numSlots)*s.slotSize()); err != nil {
+		return err
+	}
+	if _, err := s.file.WriteAt(header, 0); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// slotSize returns the size of a slot in the ring file
+func (s *Store) slotSize() int64 {
+	return int64(hourSize + valueSize*len(s.metrics))
+}
+
+// slotOffset returns the offset of the slot for the hour in the ring file
+func (s *Store) slotOffset(hour uint64) int64 {
+	return int64(headerSize) + int64(hour%s.numSlots)*s.slotSize()
+}
+
+// readSlot reads the aggregates of the hour. If the slot is holding another hour,
+// the aggregates are all zero
+func (s *Store) readSlot(hour uint64) ([]common.BigInt, error) {
+	b := make([]byte, s.slotSize())
+	if _, err := s.file.ReadAt(b, s.slotOffset(hour)); err != nil {
+		return nil, err
+	}
+	values := make([]common.BigInt, len(s.metrics))
+	stored := binary.BigEndian.Uint64(b[:hourSize])
+	for i := range values {
+		if stored != hour {
+			values[i] = common.BigInt0
+			continue
+		}
+		start := hourSize + i*valueSize
+		values[i] = common.PtrBigInt(new(big.Int).SetBytes(b[start : start+valueSize]))
+	}
+	return values, nil
+}
+
+// writeSlot writes the aggregates of the hour to the slot
+func (s *Store) writeSlot(hour uint64, values []common.BigInt) error {
+	b := make([]byte, s.slotSize())
+	binary.BigEndian.PutUint64(b[:hourSize], hour)
+	for i, value := range values {
+		raw := value.BigIntPtr().Bytes()
+		end := hourSize + (i+1)*valueSize
+		copy(b[end-len(raw):end], raw)
+	}
+	_, err := s.file.WriteAt(b, s.slotOffset(hour))
+	return err
+}
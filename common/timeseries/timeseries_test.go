@@ -0,0 +1,115 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package timeseries
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+)
+
+var testMetrics = []string{"revenue", "bytes"}
+
+func newTestStore(t *testing.T, numSlots uint64) (*Store, string) {
+	dir, err := ioutil.TempDir("", "timeseries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "metrics.dat")
+	s, err := Open(path, testMetrics, numSlots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+// TestStore_AddQuery test adding values in different hours and query the aggregates
+func TestStore_AddQuery(t *testing.T) {
+	s, path := newTestStore(t, 4)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	start := time.Unix(1000*3600, 0)
+	now := start
+	s.now = func() time.Time { return now }
+	for hour := 0; hour != 6; hour++ {
+		now = start.Add(time.Duration(hour) * time.Hour)
+		for i := 0; i <= hour; i++ {
+			if err := s.Add("revenue", common.NewBigInt(10)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Add("bytes", common.NewBigInt(int64(hour))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Add("unknown", common.NewBigInt(1)); err != errUnknownMetric {
+		t.Errorf("expect error %v, got %v", errUnknownMetric, err)
+	}
+	if err := s.Add("revenue", common.NewBigInt(-1)); err != errNegativeValue {
+		t.Errorf("expect error %v, got %v", errNegativeValue, err)
+	}
+
+	// Only the last 4 hours are kept in the ring file
+	points, err := s.Query(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 4 {
+		t.Fatalf("expect 4 points, got %v", len(points))
+	}
+	for i, point := range points {
+		hour := i + 2
+		if !point.Time.Equal(start.Add(time.Duration(hour) * time.Hour)) {
+			t.Errorf("point %v: unexpected time %v", i, point.Time)
+		}
+		if point.Values["revenue"].Cmp(common.NewBigInt(int64(10*(hour+1)))) != 0 {
+			t.Errorf("point %v: unexpected revenue %v", i, point.Values["revenue"])
+		}
+		if point.Values["bytes"].Cmp(common.NewBigInt(int64(hour))) != 0 {
+			t.Errorf("point %v: unexpected bytes %v", i, point.Values["bytes"])
+		}
+	}
+
+	// The aggregates are kept after reopened
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = Open(path, testMetrics, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return now }
+	reopened, err := s.Query(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened) != 2 || reopened[1].Values["revenue"].Cmp(points[3].Values["revenue"]) != 0 {
+		t.Errorf("unexpected points after reopen: %v", reopened)
+	}
+	s.Close()
+
+	// The file is reinitialized if the metrics are changed
+	s, err = Open(path, []string{"revenue"}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.now = func() time.Time { return now }
+	if points, err = s.Query(start); err != nil || len(points) != 0 {
+		t.Errorf("expect no points after metrics changed, got %v, %v", points, err)
+	}
+}
+
+// TestStore_Nil test adding to a nil store is a no-op
+func TestStore_Nil(t *testing.T) {
+	var s *Store
+	if err := s.Add("revenue", common.NewBigInt(1)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -6,6 +6,7 @@ package merkle
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"hash"
 	"io"
@@ -109,6 +110,7 @@ func NewSubtreeRootReader(r io.Reader, leafNumber int, h hash.Hash) *SubtreeRoot
 type CachedSubtreeRoot struct {
 	leafRoots [][]byte
 	h         hash.Hash
+	ctx       context.Context
 }
 
 // GetSubtreeRoot implements SubtreeRoot.
@@ -118,6 +120,11 @@ func (csh *CachedSubtreeRoot) GetSubtreeRoot(leafIndex int) ([]byte, error) {
 	}
 	tree := NewTree(csh.h)
 	for i := 0; i < leafIndex && len(csh.leafRoots) > 0; i++ {
+		if i%cancelCheckInterval == 0 {
+			if err := csh.ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
 		if err := tree.PushSubTree(0, csh.leafRoots[0]); err != nil {
 			return nil, err
 		}
@@ -137,9 +144,16 @@ func (csh *CachedSubtreeRoot) Skip(n int) error {
 
 // NewCachedSubtreeRoot return cachedSubtreeRoot
 func NewCachedSubtreeRoot(roots [][]byte, h hash.Hash) *CachedSubtreeRoot {
+	return newCachedSubtreeRootContext(context.Background(), roots, h)
+}
+
+// newCachedSubtreeRootContext return cachedSubtreeRoot which stops computing the subtree
+// roots once the context is cancelled
+func newCachedSubtreeRootContext(ctx context.Context, roots [][]byte, h hash.Hash) *CachedSubtreeRoot {
 	return &CachedSubtreeRoot{
 		leafRoots: roots,
 		h:         h,
+		ctx:       ctx,
 	}
 }
 
@@ -6,6 +6,7 @@ package merkle
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -22,6 +23,10 @@ const (
 	LeafSize   = 64
 )
 
+// cancelCheckInterval is the number of roots processed between two checks of the context
+// cancellation, which keeps the overhead of the check small for the large trees
+const cancelCheckInterval = 1024
+
 // Sha256MerkleTree serves as a wrapper of the merkle tree, provide a convenient way
 // to access methods associated with the merkle tree
 type Sha256MerkleTree struct {
@@ -70,6 +75,20 @@ func (ct *Sha256CachedTree) PushSubTree(height int, h common.Hash) (err error) {
 	return ct.CachedTree.PushSubTree(height, h[:])
 }
 
+// PushRoots will push the roots into the merkle tree. The context is checked periodically, and
+// the context error is returned once it is cancelled
+func (ct *Sha256CachedTree) PushRoots(ctx context.Context, roots []common.Hash) (err error) {
+	for i, r := range roots {
+		if i%cancelCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+		}
+		ct.Push(r)
+	}
+	return
+}
+
 // Root will return the merkle root of the Sha256CachedTree
 func (ct *Sha256CachedTree) Root() (h common.Hash) {
 	copy(h[:], ct.CachedTree.Root())
@@ -110,12 +129,19 @@ func Sha256MerkleTreeRoot(b []byte) (h common.Hash) {
 
 // Sha256CachedTreeRoot will return the root of the cached tree
 func Sha256CachedTreeRoot(roots []common.Hash, height uint64) (root common.Hash) {
+	root, _ = Sha256CachedTreeRootContext(context.Background(), roots, height)
+	return
+}
+
+// Sha256CachedTreeRootContext is the same as Sha256CachedTreeRoot, except that the computation
+// is aborted with the context error once the context is cancelled
+func Sha256CachedTreeRootContext(ctx context.Context, roots []common.Hash, height uint64) (root common.Hash, err error) {
 	cmt := NewSha256CachedTree(height)
-	for _, r := range roots {
-		cmt.Push(r)
+	if err = cmt.PushRoots(ctx, roots); err != nil {
+		return
 	}
 
-	return cmt.Root()
+	return cmt.Root(), nil
 }
 
 //Sha256CachedTreeRoot2 will return the root of the cached tree
@@ -276,14 +302,23 @@ func Sha256VerifySectorRangeProof(rootsVerify []common.Hash, hashProofSet []comm
 // Sha256DiffProof is similar to Sha256SectorRangeProof, the only difference is that this function
 // can provide multiple ranges
 func Sha256DiffProof(roots []common.Hash, rangeSet []SubTreeLimit, leavesCount uint64) (hashProofSet []common.Hash, err error) {
+	return Sha256DiffProofContext(context.Background(), roots, rangeSet, leavesCount)
+}
+
+// Sha256DiffProofContext is the same as Sha256DiffProof, except that the proof construction
+// is aborted with the context error once the context is cancelled
+func Sha256DiffProofContext(ctx context.Context, roots []common.Hash, rangeSet []SubTreeLimit, leavesCount uint64) (hashProofSet []common.Hash, err error) {
 	// range set validation
 	if err = rangeSetVerification(rangeSet); err != nil {
 		return
 	}
 
 	byteSectorRoots := hashSliceToByteSlices(roots)
-	hasher := NewCachedSubtreeRoot(byteSectorRoots, sha256.New())
+	hasher := newCachedSubtreeRootContext(ctx, byteSectorRoots, sha256.New())
 	proofSet, err := GetDiffStorageProof(rangeSet, hasher, leavesCount)
+	if err != nil {
+		return nil, err
+	}
 
 	// conversion
 	for _, proof := range proofSet {
@@ -6,6 +6,7 @@ package merkle
 
 import (
 	"bytes"
+	"context"
 	"math/rand"
 	"testing"
 	"time"
@@ -176,6 +177,34 @@ func TestMerkleDiffProofVerification(t *testing.T) {
 
 }
 
+func TestMerkleContextCancellation(t *testing.T) {
+	roots := randomHashSliceGenerator(3 * cancelCheckInterval)
+	rangeSet := []SubTreeLimit{{Left: 1, Right: 2}}
+
+	// the results are the same as the non-context versions if the context is not cancelled
+	root, err := Sha256CachedTreeRootContext(context.Background(), roots, sectorHeight)
+	if err != nil || root != Sha256CachedTreeRoot(roots, sectorHeight) {
+		t.Fatalf("the merkle root not expected: %v, %v", root, err)
+	}
+	proofSet, err := Sha256DiffProofContext(context.Background(), roots, rangeSet, uint64(len(roots)))
+	if err != nil {
+		t.Fatalf("failed to create the diff proof: %s", err.Error())
+	}
+	if err = Sha256VerifyDiffProof(rangeSet, uint64(len(roots)), proofSet, roots[1:2], root); err != nil {
+		t.Fatalf("failed to verify the diff proof: %s", err.Error())
+	}
+
+	// the computations are aborted once the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = Sha256CachedTreeRootContext(ctx, roots, sectorHeight); err != context.Canceled {
+		t.Errorf("merkle root with cancelled context: expect %v, got %v", context.Canceled, err)
+	}
+	if _, err = Sha256DiffProofContext(ctx, roots, rangeSet, uint64(len(roots))); err != context.Canceled {
+		t.Errorf("diff proof with cancelled context: expect %v, got %v", context.Canceled, err)
+	}
+}
+
 /*
  _____  _____  _______      __  _______ ______      ______ _    _ _   _  _____ _______ _____ ____  _   _
 |  __ \|  __ \|_   _\ \    / /\|__   __|  ____|    |  ____| |  | | \ | |/ ____|__   __|_   _/ __ \| \ | |
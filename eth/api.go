@@ -38,6 +38,7 @@ import (
 	"github.com/DxChainNetwork/godx/params"
 	"github.com/DxChainNetwork/godx/rlp"
 	"github.com/DxChainNetwork/godx/rpc"
+	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/trie"
 )
 
@@ -265,6 +266,12 @@ func NewPublicDebugAPI(eth *Ethereum) *PublicDebugAPI {
 	return &PublicDebugAPI{eth: eth}
 }
 
+// StorageCompression returns the realized compression ratios of the storage messages sent,
+// keyed by the message code
+func (api *PublicDebugAPI) StorageCompression() map[string]storage.MessageCompressionStats {
+	return api.eth.protocolManager.compressionStats.Snapshot()
+}
+
 // DumpBlock retrieves the entire state of the database at a given block.
 func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error) {
 	if blockNr == rpc.PendingBlockNumber {
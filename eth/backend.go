@@ -56,6 +56,7 @@ import (
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/storageclient"
 	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
+	"github.com/DxChainNetwork/godx/storage/storageclient/storagehostmanager"
 	"github.com/DxChainNetwork/godx/storage/storagehost"
 )
 
@@ -209,7 +210,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	// Initialize StorageClient based on the configuration
 	if config.StorageClient {
 		clientPath := ctx.ResolvePath(config.StorageClientDir)
-		eth.storageClient, err = storageclient.New(clientPath)
+		eth.storageClient, err = storageclient.New(clientPath, config.StorageClientPassphrase)
 		if err != nil {
 			return nil, err
 		}
@@ -364,6 +365,11 @@ func (s *Ethereum) APIs() []rpc.API {
 					Version:   "1.0",
 					Service:   filesystem.NewPublicFileSystemAPI(s.storageClient.GetFileSystem()),
 					Public:    true,
+				}, {
+					Namespace: "storagehostmanager",
+					Version:   "1.0",
+					Service:   storagehostmanager.NewPublicStorageHostManagerAPI(s.storageClient.GetStorageHostManager()),
+					Public:    true,
 				},
 			}
 			s.registeredAPIs = append(s.registeredAPIs, storageClientAPIs...)
@@ -603,6 +609,16 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 		if err != nil {
 			return err
 		}
+		if s.config.StorageClientGateway != "" {
+			if err = s.storageClient.StartGateway(s.config.StorageClientGateway); err != nil {
+				return err
+			}
+		}
+		if s.config.StorageClientS3Gateway != "" {
+			if err = s.storageClient.StartS3Gateway(s.config.StorageClientS3Gateway); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Start Storage Host
@@ -33,6 +33,7 @@ import (
 	"github.com/DxChainNetwork/godx/eth/downloader"
 	"github.com/DxChainNetwork/godx/eth/gasprice"
 	"github.com/DxChainNetwork/godx/params"
+	"github.com/DxChainNetwork/godx/storage"
 )
 
 // DefaultConfig contains default settings for use on the Ethereum main net.
@@ -64,6 +65,10 @@ var DefaultConfig = Config{
 	StorageClientDir: storageclient.PersistDirectory,
 	StorageClient:    true,
 	StorageHost:      true,
+	StorageCompression: storage.CompressionConfig{
+		Codec:     storage.CompressionNone,
+		Threshold: storage.DefaultCompressionThreshold,
+	},
 }
 
 func init() {
@@ -145,6 +150,21 @@ type Config struct {
 	// StorageClient Persist Directory
 	StorageClientDir string
 
+	// StorageClientPassphrase is used to encrypt the storage contracts at rest
+	StorageClientPassphrase string `toml:"-"`
+
+	// StorageClientGateway is the listening address of the storage client JSON/HTTP
+	// gateway, empty means the gateway is disabled
+	StorageClientGateway string
+
+	// StorageClientS3Gateway is the listening address of the storage client S3 compatible
+	// object gateway, empty means the gateway is disabled
+	StorageClientS3Gateway string
+
+	// StorageCompression is the storage message compression config advertised in the
+	// handshake, the compression is used only if both peers support the same codec
+	StorageCompression storage.CompressionConfig
+
 	// Role, can only be one of the two roles
 	StorageClient bool
 	StorageHost   bool
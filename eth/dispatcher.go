@@ -33,6 +33,14 @@ func (pm *ProtocolManager) msgDispatch(msg p2p.Msg, p *peer) error {
 		// hostMsgSchedule
 		return pm.hostMsgSchedule(msg, p)
 
+	case msg.Code == storage.CompressedMsg:
+		// unwrap the compressed storage message, and dispatch the original one
+		original, err := p.decompressStorageMsg(msg)
+		if err != nil {
+			return err
+		}
+		return pm.msgDispatch(original, p)
+
 	default:
 		// message code exceed the range
 		return errors.New("invalid message code")
@@ -40,6 +40,7 @@ import (
 	"github.com/DxChainNetwork/godx/p2p/enode"
 	"github.com/DxChainNetwork/godx/params"
 	"github.com/DxChainNetwork/godx/rlp"
+	"github.com/DxChainNetwork/godx/storage"
 )
 
 const (
@@ -90,6 +91,10 @@ type ProtocolManager struct {
 
 	whitelist map[uint64]common.Hash
 
+	// storage message compression config and the realized compression statistics
+	compression      storage.CompressionConfig
+	compressionStats *storage.CompressionStats
+
 	// channels for fetcher, syncer, txsyncLoop
 	newPeerCh   chan *peer
 	txsyncCh    chan *txsync
@@ -118,6 +123,11 @@ func NewProtocolManager(eth *Ethereum, config *params.ChainConfig, mode download
 		noMorePeers: make(chan struct{}),
 		txsyncCh:    make(chan *txsync),
 		quitSync:    make(chan struct{}),
+
+		compressionStats: storage.NewCompressionStats(),
+	}
+	if eth != nil {
+		manager.compression = eth.config.StorageCompression
 	}
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
@@ -270,7 +280,8 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		number  = head.Number.Uint64()
 		td      = pm.blockchain.GetTd(hash, number)
 	)
-	if err := p.Handshake(pm.networkID, td, hash, genesis.Hash()); err != nil {
+	p.compressionStats = pm.compressionStats
+	if err := p.Handshake(pm.networkID, td, hash, genesis.Hash(), pm.compression.Capability()); err != nil {
 		p.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
@@ -326,6 +337,11 @@ func (pm *ProtocolManager) handle(p *peer) error {
 
 	// Handle incoming messages until the connection is torn down
 	for {
+		// the storage client waits for the memory of the host before its request is read
+		if err := pm.waitStorageRead(p); err != nil {
+			return err
+		}
+
 		// keep reading the message util an error occur
 		msg, err := p.rw.ReadMsg()
 		if err != nil {
@@ -27,6 +27,7 @@ import (
 	"github.com/DxChainNetwork/godx/core/types"
 	"github.com/DxChainNetwork/godx/p2p"
 	"github.com/DxChainNetwork/godx/rlp"
+	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/coinchargemaintenance"
 	mapset "github.com/deckarep/golang-set"
 )
@@ -74,6 +75,10 @@ type propEvent struct {
 }
 
 type peer struct {
+	// lastStorageSend is the unix nano time the last storage message is sent. It is
+	// accessed atomically, and placed first to be 64-bit aligned
+	lastStorageSend int64
+
 	id string
 
 	*p2p.Peer
@@ -111,6 +116,18 @@ type peer struct {
 	// error channel
 	errMsg chan error
 
+	// storage message compression negotiated in the handshake
+	compressionCodec     string
+	compressionThreshold uint64
+	compressionStats     *storage.CompressionStats
+
+	// qos records the quality of the storage negotiations
+	qos *storage.PeerQoS
+
+	// storageClient is set once the peer sent a contract request to the host. It is only
+	// accessed by the message reading loop
+	storageClient bool
+
 	checkPeerStopHook func(*peer) error
 }
 
@@ -135,6 +152,8 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		errMsg:                     make(chan error, 1),
 		contractRevisingOrRenewing: make(chan struct{}, 1),
 		hostConfigRequesting:       make(chan struct{}, 1),
+		compressionCodec:           storage.CompressionNone,
+		qos:                        storage.NewPeerQoS(),
 		checkPeerStopHook:          checkPeerStop,
 	}
 }
@@ -382,13 +401,25 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 }
 
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
+// network IDs, difficulties, head and genesis blocks, and the storage message compression.
+func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, compression []storage.CompressionCapability) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
-	var status statusData // safe to read after two values have been received from errc
+	var status statusData65 // safe to read after two values have been received from errc
 
 	go func() {
+		// the compression capability is carried in the status message since eth/65
+		if p.version >= eth65 {
+			errc <- p2p.Send(p.rw, StatusMsg, &statusData65{
+				ProtocolVersion: uint32(p.version),
+				NetworkId:       network,
+				TD:              td,
+				CurrentBlock:    head,
+				GenesisBlock:    genesis,
+				Compression:     compression,
+			})
+			return
+		}
 		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
 			ProtocolVersion: uint32(p.version),
 			NetworkId:       network,
@@ -413,10 +444,12 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 		}
 	}
 	p.td, p.head = status.TD, status.CurrentBlock
+	// the compression is disabled with the peer before eth/65
+	p.compressionCodec, p.compressionThreshold = storage.NegotiateCompression(compression, status.Compression)
 	return nil
 }
 
-func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+func (p *peer) readStatus(network uint64, status *statusData65, genesis common.Hash) (err error) {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
@@ -428,8 +461,22 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
 	// Decode the handshake and make sure everything matches
-	if err := msg.Decode(&status); err != nil {
-		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	if p.version >= eth65 {
+		if err := msg.Decode(status); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+	} else {
+		var legacy statusData
+		if err := msg.Decode(&legacy); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		*status = statusData65{
+			ProtocolVersion: legacy.ProtocolVersion,
+			NetworkId:       legacy.NetworkId,
+			TD:              legacy.TD,
+			CurrentBlock:    legacy.CurrentBlock,
+			GenesisBlock:    legacy.GenesisBlock,
+		}
 	}
 	if status.GenesisBlock != genesis {
 		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", status.GenesisBlock[:8], genesis[:8])
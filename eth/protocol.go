@@ -26,6 +26,7 @@ import (
 	"github.com/DxChainNetwork/godx/core/types"
 	"github.com/DxChainNetwork/godx/event"
 	"github.com/DxChainNetwork/godx/rlp"
+	"github.com/DxChainNetwork/godx/storage"
 )
 
 // Constants to match up protocol versions and messages
@@ -33,16 +34,17 @@ const (
 	eth62 = 62
 	eth63 = 63
 	eth64 = 64
+	eth65 = 65 // the status message carries the storage message compression capability
 )
 
 // ProtocolName is the official short name of the protocol used during capability negotiation.
 var ProtocolName = "eth"
 
 // ProtocolVersions are the supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{eth64, eth63, eth62}
+var ProtocolVersions = []uint{eth65, eth64, eth63, eth62}
 
 // ProtocolLengths are the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{100, 17, 8}
+var ProtocolLengths = []uint64{100, 100, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -118,6 +120,20 @@ type statusData struct {
 	GenesisBlock    common.Hash
 }
 
+// statusData65 is the network packet for the status message since eth/65, which carries
+// the storage message compression capability as well
+type statusData65 struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	TD              *big.Int
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+
+	// Compression is the storage message compression capability, which is empty if the
+	// compression is disabled
+	Compression []storage.CompressionCapability
+}
+
 // newBlockHashesData is the network packet for the block announcements.
 type newBlockHashesData []struct {
 	Hash   common.Hash // Hash of one particular block being announced
@@ -2,6 +2,8 @@ package eth
 
 import (
 	"errors"
+	"time"
+
 	"github.com/DxChainNetwork/godx/log"
 	"github.com/DxChainNetwork/godx/p2p"
 	"github.com/DxChainNetwork/godx/storage"
@@ -44,6 +46,18 @@ func (pm *ProtocolManager) contractMsgHandler(p *peer, msg p2p.Msg) error {
 }
 
 func (pm *ProtocolManager) contractReqHandler(handler func(h *storagehost.StorageHost, sp storage.Peer, msg p2p.Msg), p *peer, msg p2p.Msg) error {
+	// the following requests of the client are read after the host has the memory
+	// to buffer them, see waitStorageRead
+	p.storageClient = true
+
+	// the requests from the banned clients are rejected, and the requests from
+	// the deprioritized clients are handled after the delay
+	delay, err := pm.eth.storageHost.AdmitClient(p.ID())
+	if err != nil {
+		_ = p.SendHostBusyHandleRequestErr()
+		return err
+	}
+
 	// avoid continuously contract related requests attack
 	// generate too many go routines and used all resources
 	if err := p.HostContractProcessing(); err != nil {
@@ -59,6 +73,13 @@ func (pm *ProtocolManager) contractReqHandler(handler func(h *storagehost.Storag
 		pm.wg.Add(1)
 		defer pm.wg.Done()
 		defer p.HostContractProcessingDone()
+		if delay != 0 {
+			select {
+			case <-time.After(delay):
+			case <-pm.quitSync:
+				return
+			}
+		}
 		handler(pm.eth.storageHost, p, msg)
 	}()
 
@@ -99,3 +120,17 @@ func (pm *ProtocolManager) waitEthStartIndicator(p *peer) error {
 		return errors.New("protocol manager sync quit")
 	}
 }
+
+// waitStorageRead holds reading the next message from the storage client till the host has
+// the memory to buffer the request, which pushes the backpressure to the client instead of
+// disconnecting it. The client in a negotiation is not held, since the negotiation waits for
+// its responses, and the next request is rejected till the negotiation is done
+func (pm *ProtocolManager) waitStorageRead(p *peer) error {
+	if !p.storageClient || pm.eth == nil || pm.eth.storageHost == nil || p.hostContractInProgress() {
+		return nil
+	}
+	if !pm.eth.storageHost.WaitNegotiationRead(pm.quitSync) {
+		return p2p.DiscQuitting
+	}
+	return nil
+}
@@ -5,11 +5,15 @@
 package eth
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/DxChainNetwork/godx/p2p"
 	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/rlp"
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/coinchargemaintenance"
 )
@@ -28,7 +32,7 @@ func (p *peer) TriggerError(err error) {
 func (p *peer) SendStorageHostConfig(config storage.HostExtConfig) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.HostConfigRespMsg, config)
+		return p.sendStorageMsg(storage.HostConfigRespMsg, config)
 	}
 	return err
 }
@@ -38,7 +42,7 @@ func (p *peer) SendStorageHostConfig(config storage.HostExtConfig) error {
 func (p *peer) RequestStorageHostConfig() error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.HostConfigReqMsg, struct{}{})
+		return p.sendStorageMsg(storage.HostConfigReqMsg, struct{}{})
 	}
 	return err
 }
@@ -49,7 +53,7 @@ func (p *peer) RequestStorageHostConfig() error {
 func (p *peer) RequestContractCreation(req storage.ContractCreateRequest) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ContractCreateReqMsg, req)
+		return p.sendStorageMsg(storage.ContractCreateReqMsg, req)
 	}
 	return err
 }
@@ -59,7 +63,7 @@ func (p *peer) RequestContractCreation(req storage.ContractCreateRequest) error
 func (p *peer) SendContractCreateClientRevisionSign(revisionSign []byte) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ContractCreateClientRevisionSign, revisionSign)
+		return p.sendStorageMsg(storage.ContractCreateClientRevisionSign, revisionSign)
 	}
 	return err
 }
@@ -70,7 +74,7 @@ func (p *peer) SendContractCreateClientRevisionSign(revisionSign []byte) error {
 func (p *peer) SendContractCreationHostSign(contractSign []byte) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ContractCreateHostSign, contractSign)
+		return p.sendStorageMsg(storage.ContractCreateHostSign, contractSign)
 	}
 	return err
 }
@@ -80,7 +84,7 @@ func (p *peer) SendContractCreationHostSign(contractSign []byte) error {
 func (p *peer) SendContractCreationHostRevisionSign(revisionSign []byte) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ContractCreateRevisionSign, revisionSign)
+		return p.sendStorageMsg(storage.ContractCreateRevisionSign, revisionSign)
 	}
 	return err
 }
@@ -91,7 +95,7 @@ func (p *peer) SendContractCreationHostRevisionSign(revisionSign []byte) error {
 func (p *peer) RequestContractUpload(req storage.UploadRequest) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ContractUploadReqMsg, req)
+		return p.sendStorageMsg(storage.ContractUploadReqMsg, req)
 	}
 	return err
 }
@@ -101,7 +105,7 @@ func (p *peer) RequestContractUpload(req storage.UploadRequest) error {
 func (p *peer) SendContractUploadClientRevisionSign(revisionSign []byte) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ContractUploadClientRevisionSign, revisionSign)
+		return p.sendStorageMsg(storage.ContractUploadClientRevisionSign, revisionSign)
 	}
 	return err
 }
@@ -111,7 +115,7 @@ func (p *peer) SendContractUploadClientRevisionSign(revisionSign []byte) error {
 func (p *peer) SendUploadMerkleProof(merkleProof storage.UploadMerkleProof) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ContractUploadMerkleProofMsg, merkleProof)
+		return p.sendStorageMsg(storage.ContractUploadMerkleProofMsg, merkleProof)
 	}
 	return err
 }
@@ -122,7 +126,7 @@ func (p *peer) SendUploadMerkleProof(merkleProof storage.UploadMerkleProof) erro
 func (p *peer) SendUploadHostRevisionSign(revisionSign []byte) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ContractUploadRevisionSign, revisionSign)
+		return p.sendStorageMsg(storage.ContractUploadRevisionSign, revisionSign)
 	}
 	return err
 }
@@ -132,7 +136,7 @@ func (p *peer) SendUploadHostRevisionSign(revisionSign []byte) error {
 func (p *peer) RequestContractDownload(req storage.DownloadRequest) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ContractDownloadReqMsg, req)
+		return p.sendStorageMsg(storage.ContractDownloadReqMsg, req)
 	}
 	return err
 }
@@ -142,7 +146,7 @@ func (p *peer) RequestContractDownload(req storage.DownloadRequest) error {
 func (p *peer) SendContractDownloadData(resp storage.DownloadResponse) error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ContractDownloadDataMsg, resp)
+		return p.sendStorageMsg(storage.ContractDownloadDataMsg, resp)
 	}
 	return err
 }
@@ -152,7 +156,7 @@ func (p *peer) SendContractDownloadData(resp storage.DownloadResponse) error {
 func (p *peer) SendHostBusyHandleRequestErr() error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.HostBusyHandleReqMsg, "error handling")
+		return p.sendStorageMsg(storage.HostBusyHandleReqMsg, "error handling")
 	}
 	return err
 }
@@ -161,7 +165,7 @@ func (p *peer) SendHostBusyHandleRequestErr() error {
 func (p *peer) SendClientNegotiateErrorMsg() error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ClientNegotiateErrorMsg, storage.ErrClientNegotiate.Error())
+		return p.sendStorageMsg(storage.ClientNegotiateErrorMsg, storage.ErrClientNegotiate.Error())
 	}
 	return err
 }
@@ -171,7 +175,7 @@ func (p *peer) SendClientNegotiateErrorMsg() error {
 func (p *peer) SendClientCommitFailedMsg() error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ClientCommitFailedMsg, storage.ErrClientCommit.Error())
+		return p.sendStorageMsg(storage.ClientCommitFailedMsg, storage.ErrClientCommit.Error())
 	}
 	return err
 }
@@ -180,7 +184,7 @@ func (p *peer) SendClientCommitFailedMsg() error {
 func (p *peer) SendClientCommitSuccessMsg() error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ClientCommitSuccessMsg, "commit success")
+		return p.sendStorageMsg(storage.ClientCommitSuccessMsg, "commit success")
 	}
 	return err
 }
@@ -189,7 +193,7 @@ func (p *peer) SendClientCommitSuccessMsg() error {
 func (p *peer) SendHostCommitFailedMsg() error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.HostCommitFailedMsg, storage.ErrHostCommit.Error())
+		return p.sendStorageMsg(storage.HostCommitFailedMsg, storage.ErrHostCommit.Error())
 	}
 	return err
 }
@@ -197,7 +201,7 @@ func (p *peer) SendHostCommitFailedMsg() error {
 func (p *peer) SendClientAckMsg() error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.ClientAckMsg, "client ack")
+		return p.sendStorageMsg(storage.ClientAckMsg, "client ack")
 	}
 	return err
 }
@@ -206,7 +210,7 @@ func (p *peer) SendClientAckMsg() error {
 func (p *peer) SendHostAckMsg() error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.HostAckMsg, "host ack")
+		return p.sendStorageMsg(storage.HostAckMsg, "host ack")
 	}
 	return err
 }
@@ -215,7 +219,7 @@ func (p *peer) SendHostAckMsg() error {
 func (p *peer) SendHostNegotiateErrorMsg() error {
 	var err error
 	if err = p.checkPeerStopHook(p); err == nil {
-		return p2p.Send(p.rw, storage.HostNegotiateErrorMsg, storage.ErrHostNegotiate.Error())
+		return p.sendStorageMsg(storage.HostNegotiateErrorMsg, storage.ErrHostNegotiate.Error())
 	}
 	return err
 }
@@ -226,8 +230,10 @@ func (p *peer) WaitConfigResp() (msg p2p.Msg, err error) {
 	timeout := time.After(1 * time.Minute)
 	select {
 	case msg = <-p.clientConfigMsg:
+		p.recordStorageResponse(msg)
 		return
 	case <-timeout:
+		p.qos.RecordTimeout()
 		err = errors.New("timeout -> client waits too long for config response from the host")
 		return
 	case <-p.StopChan():
@@ -242,8 +248,10 @@ func (p *peer) ClientWaitContractResp() (msg p2p.Msg, err error) {
 	timeout := time.After(1 * time.Minute)
 	select {
 	case msg = <-p.clientContractMsg:
+		p.recordStorageResponse(msg)
 		return
 	case <-timeout:
+		p.qos.RecordTimeout()
 		err = errors.New("timeout -> client waits too long for contract response from the host")
 		return
 	case <-p.StopChan():
@@ -314,6 +322,12 @@ func (p *peer) HostContractProcessingDone() {
 	}
 }
 
+// hostContractInProgress checks if the host is processing the contract related request
+// sent from the storage client
+func (p *peer) hostContractInProgress() bool {
+	return len(p.hostContractProcessing) != 0
+}
+
 // TryToRenewOrRevise will try to renew or revise the contract, if failed
 // the renew process and revision process will be interrupted immediately
 func (p *peer) TryToRenewOrRevise() bool {
@@ -363,3 +377,89 @@ func (p *peer) IsStaticConn() bool {
 func (p *peer) PeerNode() *enode.Node {
 	return p.Peer.Node()
 }
+
+// sendStorageMsg encodes and sends the storage message. If the compression is negotiated
+// with the peer and the payload reaches the threshold, the message is compressed and sent
+// as CompressedMsg, unless the compression does not reduce the size
+func (p *peer) sendStorageMsg(code uint64, data interface{}) error {
+	payload, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		return err
+	}
+	sent, compressed := payload, false
+	if p.compressionCodec != storage.CompressionNone && uint64(len(payload)) >= p.compressionThreshold {
+		var wrapped []byte
+		if wrapped, err = p.compressStorageMsg(code, payload); err != nil {
+			return err
+		}
+		if len(wrapped) < len(payload) {
+			sent, compressed = wrapped, true
+		}
+	}
+	if p.compressionStats != nil {
+		p.compressionStats.Record(code, len(payload), len(sent), compressed)
+	}
+
+	msgCode := code
+	if compressed {
+		msgCode = storage.CompressedMsg
+	}
+	if err = p.rw.WriteMsg(p2p.Msg{Code: msgCode, Size: uint32(len(sent)), Payload: bytes.NewReader(sent)}); err != nil {
+		p.qos.RecordSendFailure()
+		return err
+	}
+	atomic.StoreInt64(&p.lastStorageSend, time.Now().UnixNano())
+	return nil
+}
+
+// recordStorageResponse records the round trip time of the storage response received by the
+// client, which is measured from the last storage message sent
+func (p *peer) recordStorageResponse(msg p2p.Msg) {
+	sent := atomic.LoadInt64(&p.lastStorageSend)
+	if sent == 0 || msg.ReceivedAt.IsZero() {
+		return
+	}
+	if rtt := msg.ReceivedAt.Sub(time.Unix(0, sent)); rtt >= 0 {
+		p.qos.RecordResponse(rtt, msg.Size)
+	}
+}
+
+// TakeQoSSample returns the quality of the storage negotiations with the peer recorded
+// since the last sample
+func (p *peer) TakeQoSSample() storage.PeerQoSSample {
+	return p.qos.TakeSample()
+}
+
+// compressStorageMsg compresses the payload with the negotiated codec, and wraps it
+// into the payload of CompressedMsg
+func (p *peer) compressStorageMsg(code uint64, payload []byte) ([]byte, error) {
+	data, err := storage.Compress(p.compressionCodec, payload)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(storage.CompressedMessage{Code: code, Data: data})
+}
+
+// decompressStorageMsg unwraps the CompressedMsg into the original storage message
+func (p *peer) decompressStorageMsg(msg p2p.Msg) (p2p.Msg, error) {
+	if p.compressionCodec == storage.CompressionNone {
+		return p2p.Msg{}, errors.New("compressed storage message received without compression negotiated")
+	}
+	var cm storage.CompressedMessage
+	if err := msg.Decode(&cm); err != nil {
+		return p2p.Msg{}, err
+	}
+	if cm.Code < storage.HostConfigRespMsg || cm.Code >= storage.CompressedMsg {
+		return p2p.Msg{}, fmt.Errorf("invalid compressed storage message code %x", cm.Code)
+	}
+	data, err := storage.Decompress(p.compressionCodec, cm.Data, ProtocolMaxMsgSize)
+	if err != nil {
+		return p2p.Msg{}, err
+	}
+	return p2p.Msg{
+		Code:       cm.Code,
+		Size:       uint32(len(data)),
+		Payload:    bytes.NewReader(data),
+		ReceivedAt: msg.ReceivedAt,
+	}, nil
+}
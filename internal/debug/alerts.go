@@ -0,0 +1,21 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package debug
+
+import "github.com/DxChainNetwork/godx/common/alert"
+
+// Alerts returns the alerts raised by the modules of the node, with the most
+// severe and the most recent ones first
+func (*HandlerT) Alerts() []alert.Alert {
+	return alert.Alerts()
+}
+
+// DismissAlert removes the alert with the given id from the alert registry
+func (*HandlerT) DismissAlert(id string) (string, error) {
+	if err := alert.Dismiss(id); err != nil {
+		return "", err
+	}
+	return "successfully dismissed the alert", nil
+}
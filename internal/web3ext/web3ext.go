@@ -282,6 +282,21 @@ web3._extend({
 			params: 0,
 			outputFormatter: console.log
 		}),
+		new web3._extend.Method({
+			name: 'alerts',
+			call: 'debug_alerts',
+			params: 0,
+		}),
+		new web3._extend.Method({
+			name: 'dismissAlert',
+			call: 'debug_dismissAlert',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'storageCompression',
+			call: 'debug_storageCompression',
+			params: 0,
+		}),
 		new web3._extend.Method({
 			name: 'freeOSMemory',
 			call: 'debug_freeOSMemory',
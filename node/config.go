@@ -152,6 +152,16 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// RPCAuthNamespaces is the list of API namespaces requiring an authentication token
+	// on the HTTP and websocket RPC interfaces, e.g. the storage client file operations
+	// and the storage host config mutations. The namespaces not listed stay open.
+	RPCAuthNamespaces []string `toml:",omitempty"`
+
+	// RPCAuthTokens maps the authentication tokens to the namespaces they are granted.
+	// The scope "*" grants the token all namespaces. The token is sent by the client as
+	// the bearer token or the basic authentication password.
+	RPCAuthTokens map[string][]string `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -160,6 +170,15 @@ type Config struct {
 	oldGethResourceWarning bool
 }
 
+// RPCAuthenticator returns the authenticator guarding the namespaces of the HTTP and websocket
+// RPC interfaces, or nil if no namespace is protected
+func (c *Config) RPCAuthenticator() *rpc.Authenticator {
+	if len(c.RPCAuthNamespaces) == 0 {
+		return nil
+	}
+	return rpc.NewAuthenticator(c.RPCAuthNamespaces, c.RPCAuthTokens)
+}
+
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
 // account the set data folders as well as the designated platform we're currently
 // running on.
@@ -38,12 +38,15 @@ var (
 )
 
 const (
-	baseProtocolVersion    = 5
+	baseProtocolVersion    = 6
 	baseProtocolLength     = uint64(16)
 	baseProtocolMaxMsgSize = 2 * 1024
 
 	snappyProtocolVersion = 5
 
+	// rekeyProtocolVersion is the version since which the session keys are rotated
+	rekeyProtocolVersion = 6
+
 	pingInterval = 15 * time.Second
 )
 
@@ -53,6 +56,7 @@ const (
 	discMsg      = 0x01
 	pingMsg      = 0x02
 	pongMsg      = 0x03
+	rekeyMsg     = 0x04 // handled by the transport, never delivered to the peer
 )
 
 // protoHandshake is the RLP structure of the protocol handshake.
@@ -173,6 +177,17 @@ func (p *Peer) LocalAddr() net.Addr {
 	return p.rw.fd.LocalAddr()
 }
 
+// RotateSessionKey rotates the session keys of the connection in both directions. The
+// frames already written are not affected, so the messages in flight are not interrupted.
+// It returns an error if the remote node does not support the key rotation
+func (p *Peer) RotateSessionKey() error {
+	r, ok := p.rw.transport.(keyRotator)
+	if !ok {
+		return errRekeyNotSupported
+	}
+	return r.rotateKeys()
+}
+
 // Disconnect terminates the peer connection with the given reason.
 // It returns immediately and does not wait until the connection is closed.
 // disconnect with a peer
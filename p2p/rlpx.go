@@ -33,6 +33,7 @@ import (
 	mrand "math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/DxChainNetwork/godx/common/bitutil"
@@ -68,11 +69,38 @@ const (
 	// This is shorter than the usual timeout because we don't want
 	// to wait if the connection is known to be bad anyway.
 	discWriteTimeout = 1 * time.Second
+
+	// The egress session keys are rotated after the interval or
+	// after the amount of data is written with the keys, whichever
+	// comes first.
+	keyRotationInterval = 1 * time.Hour
+	keyRotationBytes    = 1 << 30
+)
+
+var (
+	// errPlainMessageTooLarge is returned if a decompressed message length exceeds
+	// the allowed 24 bits (i.e. length >= 16MB).
+	errPlainMessageTooLarge = errors.New("message length >= 16MB")
+
+	// errRekeyNotSupported is returned if the session keys are rotated on
+	// a connection with a remote node not supporting the key rotation.
+	errRekeyNotSupported = errors.New("session key rotation not supported by the remote node")
 )
 
-// errPlainMessageTooLarge is returned if a decompressed message length exceeds
-// the allowed 24 bits (i.e. length >= 16MB).
-var errPlainMessageTooLarge = errors.New("message length >= 16MB")
+// keyRotator is implemented by the transports able to rotate the session keys.
+type keyRotator interface {
+	rotateKeys() error
+}
+
+// rekeyPacket is the payload of rekeyMsg. The sender rotates its egress keys
+// right after the packet, using the shared secret of the ephemeral key and
+// the static key of the receiver, so that the new keys cannot be derived from
+// the old session keys alone.
+type rekeyPacket struct {
+	Pubkey [pubLen]byte
+	// Request asks the receiver to rotate its egress keys as well.
+	Request bool
+}
 
 // rlpx is the transport protocol used by actual (non-test) connections.
 // It wraps the frame encoder with locks and read/write deadlines.
@@ -81,6 +109,15 @@ type rlpx struct {
 
 	rmu, wmu sync.Mutex
 	rw       *rlpxFrameRW
+
+	// key rotation state. prv and remote are the static keys of
+	// both sides. lastRekey and egressBytes are guarded by wmu.
+	rekey        bool
+	prv          *ecies.PrivateKey
+	remote       *ecies.PublicKey
+	lastRekey    time.Time
+	egressBytes  uint64
+	rekeyPending int32 // the remote side requested a rotation, accessed atomically
 }
 
 func newRLPX(fd net.Conn) transport {
@@ -91,17 +128,88 @@ func newRLPX(fd net.Conn) transport {
 func (t *rlpx) ReadMsg() (Msg, error) {
 	t.rmu.Lock()
 	defer t.rmu.Unlock()
-	t.fd.SetReadDeadline(time.Now().Add(frameReadTimeout))
-	return t.rw.ReadMsg()
+	for {
+		t.fd.SetReadDeadline(time.Now().Add(frameReadTimeout))
+		msg, err := t.rw.ReadMsg()
+		if err != nil || !t.rekey || msg.Code != rekeyMsg {
+			return msg, err
+		}
+		// the frames after the rekey packet are encrypted with the new keys
+		if err := t.handleRekey(msg); err != nil {
+			return Msg{}, err
+		}
+	}
 }
 
 func (t *rlpx) WriteMsg(msg Msg) error {
 	t.wmu.Lock()
 	defer t.wmu.Unlock()
 	t.fd.SetWriteDeadline(time.Now().Add(frameWriteTimeout))
+	if t.rekey && (atomic.LoadInt32(&t.rekeyPending) == 1 ||
+		time.Since(t.lastRekey) >= keyRotationInterval || t.egressBytes >= keyRotationBytes) {
+		if err := t.rotateEgress(false); err != nil {
+			return err
+		}
+	}
+	t.egressBytes += uint64(msg.Size)
 	return t.rw.WriteMsg(msg)
 }
 
+// rotateKeys rotates the egress keys, and requests the remote side
+// to rotate its egress keys with the next message it sends.
+func (t *rlpx) rotateKeys() error {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	if t.rw == nil || !t.rekey {
+		return errRekeyNotSupported
+	}
+	t.fd.SetWriteDeadline(time.Now().Add(frameWriteTimeout))
+	return t.rotateEgress(true)
+}
+
+// rotateEgress sends the rekey packet with a new ephemeral key, and
+// switches the egress keys after the packet. wmu must be held.
+func (t *rlpx) rotateEgress(request bool) error {
+	ephemeral, err := ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+	if err != nil {
+		return err
+	}
+	shared, err := ephemeral.GenerateShared(t.remote, sskLen, sskLen)
+	if err != nil {
+		return err
+	}
+	packet := rekeyPacket{Request: request}
+	copy(packet.Pubkey[:], exportPubkey(&ephemeral.PublicKey))
+	if err := Send(t.rw, rekeyMsg, &packet); err != nil {
+		return err
+	}
+	t.rw.rotateEgress(shared)
+	t.lastRekey, t.egressBytes = time.Now(), 0
+	atomic.StoreInt32(&t.rekeyPending, 0)
+	return nil
+}
+
+// handleRekey switches the ingress keys with the rekey packet. rmu must be held.
+func (t *rlpx) handleRekey(msg Msg) error {
+	var packet rekeyPacket
+	if err := msg.Decode(&packet); err != nil {
+		return fmt.Errorf("invalid rekey packet: %v", err)
+	}
+	pub, err := importPublicKey(packet.Pubkey[:])
+	if err != nil {
+		return err
+	}
+	shared, err := t.prv.GenerateShared(pub, sskLen, sskLen)
+	if err != nil {
+		return err
+	}
+	t.rw.rotateIngress(shared)
+	if packet.Request {
+		atomic.StoreInt32(&t.rekeyPending, 1)
+	}
+	return nil
+}
+
 func (t *rlpx) close(err error) {
 	t.wmu.Lock()
 	defer t.wmu.Unlock()
@@ -146,6 +254,12 @@ func (t *rlpx) doProtoHandshake(our *protoHandshake) (their *protoHandshake, err
 	// check if the protocol support snappy
 	t.rw.snappy = their.Version >= snappyProtocolVersion
 
+	// the session keys are rotated if both sides support
+	t.wmu.Lock()
+	t.rekey = our.Version >= rekeyProtocolVersion && their.Version >= rekeyProtocolVersion
+	t.lastRekey = time.Now()
+	t.wmu.Unlock()
+
 	return their, nil
 }
 
@@ -207,6 +321,7 @@ func (t *rlpx) doEncHandshake(prv *ecdsa.PrivateKey, dial *ecdsa.PublicKey) (*ec
 	}
 	t.wmu.Lock()
 	t.rw = newRLPXFrameRW(t.fd, sec)
+	t.prv, t.remote = ecies.ImportECDSA(prv), sec.Remote
 	t.wmu.Unlock()
 	return sec.Remote.ExportECDSA(), nil
 }
@@ -643,9 +758,14 @@ type rlpxFrameRW struct {
 	enc  cipher.Stream
 	dec  cipher.Stream
 
-	macCipher  cipher.Block
-	egressMAC  hash.Hash
-	ingressMAC hash.Hash
+	egressMACCipher  cipher.Block
+	ingressMACCipher cipher.Block
+	egressMAC        hash.Hash
+	ingressMAC       hash.Hash
+
+	// the AES secrets of both directions, from which
+	// the rotated keys are derived
+	egressAES, ingressAES []byte
 
 	snappy bool
 }
@@ -663,15 +783,46 @@ func newRLPXFrameRW(conn io.ReadWriter, s secrets) *rlpxFrameRW {
 	// for encryption is ephemeral.
 	iv := make([]byte, encc.BlockSize())
 	return &rlpxFrameRW{
-		conn:       conn,
-		enc:        cipher.NewCTR(encc, iv),
-		dec:        cipher.NewCTR(encc, iv),
-		macCipher:  macc,
-		egressMAC:  s.EgressMAC,
-		ingressMAC: s.IngressMAC,
+		conn:             conn,
+		enc:              cipher.NewCTR(encc, iv),
+		dec:              cipher.NewCTR(encc, iv),
+		egressMACCipher:  macc,
+		ingressMACCipher: macc,
+		egressMAC:        s.EgressMAC,
+		ingressMAC:       s.IngressMAC,
+		egressAES:        s.AES,
+		ingressAES:       s.AES,
 	}
 }
 
+// rotateEgress switches the egress keys to the ones derived from
+// the shared secret of the rekey packet and the current egress keys.
+func (rw *rlpxFrameRW) rotateEgress(shared []byte) {
+	rw.egressAES, rw.enc, rw.egressMACCipher = rotateSecrets(shared, rw.egressAES)
+}
+
+// rotateIngress switches the ingress keys the same as the remote
+// side switched its egress keys.
+func (rw *rlpxFrameRW) rotateIngress(shared []byte) {
+	rw.ingressAES, rw.dec, rw.ingressMACCipher = rotateSecrets(shared, rw.ingressAES)
+}
+
+// rotateSecrets derives the new AES and MAC secrets, the same way
+// the secrets are derived in the encryption handshake.
+func rotateSecrets(shared, aesSecret []byte) ([]byte, cipher.Stream, cipher.Block) {
+	aesSecret = crypto.Keccak256(shared, aesSecret)
+	encc, err := aes.NewCipher(aesSecret)
+	if err != nil {
+		panic("invalid AES secret: " + err.Error())
+	}
+	macc, err := aes.NewCipher(crypto.Keccak256(shared, aesSecret))
+	if err != nil {
+		panic("invalid MAC secret: " + err.Error())
+	}
+	iv := make([]byte, encc.BlockSize())
+	return aesSecret, cipher.NewCTR(encc, iv), macc
+}
+
 func (rw *rlpxFrameRW) WriteMsg(msg Msg) error {
 	// check the message code
 	ptype, _ := rlp.EncodeToBytes(msg.Code)
@@ -709,7 +860,7 @@ func (rw *rlpxFrameRW) WriteMsg(msg Msg) error {
 	// write header MAC to to the last 16 bites
 	// and send the headbuf through the TCP connection
 	// Get the MAC based on the first 16 bits data from headbuf
-	copy(headbuf[16:], updateMAC(rw.egressMAC, rw.macCipher, headbuf[:16]))
+	copy(headbuf[16:], updateMAC(rw.egressMAC, rw.egressMACCipher, headbuf[:16]))
 	if _, err := rw.conn.Write(headbuf); err != nil {
 		return err
 	}
@@ -732,7 +883,7 @@ func (rw *rlpxFrameRW) WriteMsg(msg Msg) error {
 	// write frame MAC. egress MAC hash is up to date because
 	// frame content was written to it as well.
 	fmacseed := rw.egressMAC.Sum(nil)
-	mac := updateMAC(rw.egressMAC, rw.macCipher, fmacseed)
+	mac := updateMAC(rw.egressMAC, rw.egressMACCipher, fmacseed)
 	_, err := rw.conn.Write(mac)
 	return err
 }
@@ -744,7 +895,7 @@ func (rw *rlpxFrameRW) ReadMsg() (msg Msg, err error) {
 		return msg, err
 	}
 	// verify header mac
-	shouldMAC := updateMAC(rw.ingressMAC, rw.macCipher, headbuf[:16])
+	shouldMAC := updateMAC(rw.ingressMAC, rw.ingressMACCipher, headbuf[:16])
 	if !hmac.Equal(shouldMAC, headbuf[16:]) {
 		return msg, errors.New("bad header MAC")
 	}
@@ -770,7 +921,7 @@ func (rw *rlpxFrameRW) ReadMsg() (msg Msg, err error) {
 	if _, err := io.ReadFull(rw.conn, headbuf[:16]); err != nil {
 		return msg, err
 	}
-	shouldMAC = updateMAC(rw.ingressMAC, rw.macCipher, fmacseed)
+	shouldMAC = updateMAC(rw.ingressMAC, rw.ingressMACCipher, fmacseed)
 	if !hmac.Equal(shouldMAC, headbuf[:16]) {
 		return msg, errors.New("bad frame MAC")
 	}
@@ -0,0 +1,196 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/crypto"
+)
+
+// testMsgCode is the code of the messages sent in the tests, outside of the base protocol
+const testMsgCode = baseProtocolLength
+
+// newTestRLPXPair connects two rlpx transports over a pipe, and runs the encryption and the
+// protocol handshakes with the protocol versions of the initiator and the receiver
+func newTestRLPXPair(t *testing.T, initVersion, recvVersion uint64) (*rlpx, *rlpx) {
+	initKey, _ := crypto.GenerateKey()
+	recvKey, _ := crypto.GenerateKey()
+	fd0, fd1 := net.Pipe()
+	initiator, receiver := newRLPX(fd0).(*rlpx), newRLPX(fd1).(*rlpx)
+
+	errc := make(chan error, 2)
+	go func() {
+		_, err := initiator.doEncHandshake(initKey, &recvKey.PublicKey)
+		errc <- err
+	}()
+	go func() {
+		_, err := receiver.doEncHandshake(recvKey, nil)
+		errc <- err
+	}()
+	for i := 0; i != 2; i++ {
+		if err := <-errc; err != nil {
+			t.Fatalf("encryption handshake failed: %v", err)
+		}
+	}
+
+	go func() {
+		_, err := initiator.doProtoHandshake(&protoHandshake{Version: initVersion, ID: crypto.FromECDSAPub(&initKey.PublicKey)[1:]})
+		errc <- err
+	}()
+	go func() {
+		_, err := receiver.doProtoHandshake(&protoHandshake{Version: recvVersion, ID: crypto.FromECDSAPub(&recvKey.PublicKey)[1:]})
+		errc <- err
+	}()
+	for i := 0; i != 2; i++ {
+		if err := <-errc; err != nil {
+			t.Fatalf("protocol handshake failed: %v", err)
+		}
+	}
+	return initiator, receiver
+}
+
+// sendTestMsgs writes the messages numbered from start to end, and rotates the keys before
+// the messages listed in rotateAt
+func sendTestMsgs(t *rlpx, start, end int, rotateAt ...int) error {
+	for i := start; i != end; i++ {
+		for _, at := range rotateAt {
+			if at == i {
+				if err := t.rotateKeys(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := Send(t, testMsgCode, fmt.Sprintf("msg %d", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTestMsgs reads the messages numbered from start to end, and checks the content
+func readTestMsgs(t *rlpx, start, end int) error {
+	for i := start; i != end; i++ {
+		msg, err := t.ReadMsg()
+		if err != nil {
+			return err
+		}
+		var content string
+		if err = msg.Decode(&content); err != nil {
+			return err
+		}
+		if expect := fmt.Sprintf("msg %d", i); msg.Code != testMsgCode || content != expect {
+			return fmt.Errorf("expect %v, got code %v content %v", expect, msg.Code, content)
+		}
+	}
+	return nil
+}
+
+// exchangeTestMsgs writes the messages from both sides concurrently, and checks the messages
+// read by the other side
+func exchangeTestMsgs(t *testing.T, a, b *rlpx, start, end int, aRotateAt, bRotateAt []int) {
+	errc := make(chan error, 4)
+	go func() { errc <- sendTestMsgs(a, start, end, aRotateAt...) }()
+	go func() { errc <- sendTestMsgs(b, start, end, bRotateAt...) }()
+	go func() { errc <- readTestMsgs(a, start, end) }()
+	go func() { errc <- readTestMsgs(b, start, end) }()
+	for i := 0; i != 4; i++ {
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRLPX_RotateKeysPerDirection(t *testing.T) {
+	a, b := newTestRLPXPair(t, baseProtocolVersion, baseProtocolVersion)
+	defer a.fd.Close()
+	defer b.fd.Close()
+	initial := append([]byte{}, a.rw.egressAES...)
+	initialMAC := a.rw.egressMACCipher
+
+	// rotate the egress keys of a only, without requesting b to rotate
+	errc := make(chan error, 1)
+	go func() {
+		a.wmu.Lock()
+		err := a.rotateEgress(false)
+		a.wmu.Unlock()
+		if err == nil {
+			err = sendTestMsgs(a, 0, 1)
+		}
+		errc <- err
+	}()
+	if err := readTestMsgs(b, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a.rw.egressAES, initial) || a.rw.egressMACCipher == initialMAC {
+		t.Fatalf("the egress keys of a are not rotated")
+	}
+	if !bytes.Equal(a.rw.egressAES, b.rw.ingressAES) {
+		t.Fatalf("the ingress keys of b do not follow the egress keys of a")
+	}
+	if !bytes.Equal(a.rw.ingressAES, initial) || !bytes.Equal(b.rw.egressAES, initial) || a.rw.ingressMACCipher != initialMAC {
+		t.Fatalf("the keys from b to a should not be rotated")
+	}
+
+	// the messages still flow in both directions
+	exchangeTestMsgs(t, a, b, 1, 5, nil, nil)
+
+	// the rotation requested by a rotates the egress keys of b with its next message
+	exchangeTestMsgs(t, a, b, 5, 10, []int{5}, nil)
+	exchangeTestMsgs(t, a, b, 10, 11, nil, nil)
+	if bytes.Equal(b.rw.egressAES, initial) || !bytes.Equal(b.rw.egressAES, a.rw.ingressAES) {
+		t.Fatalf("the egress keys of b are not rotated as requested")
+	}
+	if bytes.Equal(a.rw.egressAES, b.rw.egressAES) {
+		t.Fatalf("the keys of both directions should be different")
+	}
+}
+
+func TestRLPX_RotateKeysInFlight(t *testing.T) {
+	a, b := newTestRLPXPair(t, baseProtocolVersion, baseProtocolVersion)
+	defer a.fd.Close()
+	defer b.fd.Close()
+
+	// both sides rotate the keys repeatedly while the frames of the other side are in flight
+	exchangeTestMsgs(t, a, b, 0, 50, []int{3, 10, 11, 30}, []int{0, 10, 25, 49})
+	exchangeTestMsgs(t, a, b, 50, 51, nil, nil)
+	if !bytes.Equal(a.rw.egressAES, b.rw.ingressAES) || !bytes.Equal(b.rw.egressAES, a.rw.ingressAES) {
+		t.Fatalf("the keys of both sides are not in sync")
+	}
+}
+
+func TestRLPX_RotateKeysVersion(t *testing.T) {
+	tests := []struct {
+		initVersion, recvVersion uint64
+		rekey                    bool
+	}{
+		{rekeyProtocolVersion - 1, rekeyProtocolVersion, false},
+		{rekeyProtocolVersion, rekeyProtocolVersion - 1, false},
+		{rekeyProtocolVersion - 1, rekeyProtocolVersion - 1, false},
+		{rekeyProtocolVersion, rekeyProtocolVersion, true},
+	}
+	for _, test := range tests {
+		a, b := newTestRLPXPair(t, test.initVersion, test.recvVersion)
+		if a.rekey != test.rekey || b.rekey != test.rekey {
+			t.Errorf("v%v and v%v: expect rekey %v, got %v and %v", test.initVersion, test.recvVersion, test.rekey, a.rekey, b.rekey)
+		}
+		if !test.rekey {
+			if err := a.rotateKeys(); err != errRekeyNotSupported {
+				t.Errorf("v%v and v%v: expect rotation not supported, got %v", test.initVersion, test.recvVersion, err)
+			}
+			exchangeTestMsgs(t, a, b, 0, 5, nil, nil)
+		} else {
+			exchangeTestMsgs(t, a, b, 0, 5, []int{2}, []int{3})
+		}
+		a.fd.Close()
+		b.fd.Close()
+	}
+}
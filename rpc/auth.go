@@ -0,0 +1,102 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AuthScopeAll is the scope granting the token access to all namespaces
+const AuthScopeAll = "*"
+
+// authTokenKey is the context key of the authentication token carried by the request
+type authTokenKey struct{}
+
+// Authenticator guards the namespaces of the server with tokens. The request calling a
+// method in a protected namespace is only served if it carries a token granted the
+// namespace. The namespaces not protected are open to all requests.
+type Authenticator struct {
+	protected map[string]struct{}
+	tokens    map[string]map[string]struct{}
+}
+
+// NewAuthenticator creates an authenticator protecting the namespaces. tokens maps each
+// token to the namespaces it is granted, where AuthScopeAll grants all namespaces
+func NewAuthenticator(protected []string, tokens map[string][]string) *Authenticator {
+	a := &Authenticator{
+		protected: make(map[string]struct{}),
+		tokens:    make(map[string]map[string]struct{}),
+	}
+	for _, namespace := range protected {
+		a.protected[namespace] = struct{}{}
+	}
+	for token, scopes := range tokens {
+		if token == "" {
+			continue
+		}
+		a.tokens[token] = make(map[string]struct{})
+		for _, scope := range scopes {
+			a.tokens[token][scope] = struct{}{}
+		}
+	}
+	return a
+}
+
+// authorize checks whether the token is allowed to call the methods in the namespace
+func (a *Authenticator) authorize(token, namespace string) bool {
+	if _, protected := a.protected[namespace]; !protected {
+		return true
+	}
+	// compare all tokens in constant time, so that the token could not be guessed
+	// from the response time
+	var scopes map[string]struct{}
+	for t, s := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			scopes = s
+		}
+	}
+	if scopes == nil {
+		return false
+	}
+	if _, all := scopes[AuthScopeAll]; all {
+		return true
+	}
+	_, granted := scopes[namespace]
+	return granted
+}
+
+// SetAuthenticator sets the authenticator guarding the namespaces of the server. It shall
+// be called before the server starts serving
+func (s *Server) SetAuthenticator(a *Authenticator) {
+	s.auth = a
+}
+
+// authTokenFromRequest returns the token in the authorization header of the http request.
+// Both the bearer token and the password of the basic authentication are accepted, the
+// latter is what the client sends for the credentials in the endpoint url
+func authTokenFromRequest(r *http.Request) string {
+	if _, password, ok := r.BasicAuth(); ok {
+		return password
+	}
+	header := r.Header.Get("Authorization")
+	if prefix := "Bearer "; len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+	return ""
+}
+
+// withAuthToken stores the authentication token of the http request in the context
+func withAuthToken(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, authTokenKey{}, authTokenFromRequest(r))
+}
+
+// authTokenFromContext returns the authentication token stored in the context
+func authTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(authTokenKey{}).(string)
+	return token
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthenticatorAuthorize(t *testing.T) {
+	a := NewAuthenticator([]string{"sclient", "shost"}, map[string][]string{
+		"client": {"sclient"},
+		"admin":  {AuthScopeAll},
+	})
+	tests := []struct {
+		token     string
+		namespace string
+		expect    bool
+	}{
+		{"", "eth", true},
+		{"", "sclient", false},
+		{"wrong", "sclient", false},
+		{"client", "sclient", true},
+		{"client", "shost", false},
+		{"admin", "shost", true},
+		{"admin", "eth", true},
+	}
+	for _, test := range tests {
+		if res := a.authorize(test.token, test.namespace); res != test.expect {
+			t.Errorf("token %q namespace %v: expect %v, got %v", test.token, test.namespace, test.expect, res)
+		}
+	}
+}
+
+func TestServerAuthenticationHTTP(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("open", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("guarded", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuthenticator(NewAuthenticator([]string{"guarded"}, map[string][]string{"token": {"guarded"}}))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	tests := []struct {
+		endpoint string
+		method   string
+		allowed  bool
+	}{
+		{httpServer.URL, "open_rets", true},
+		{httpServer.URL, "guarded_rets", false},
+		{strings.Replace(httpServer.URL, "http://", "http://:wrong@", 1), "guarded_rets", false},
+		{strings.Replace(httpServer.URL, "http://", "http://:token@", 1), "guarded_rets", true},
+	}
+	for _, test := range tests {
+		client, err := DialHTTP(test.endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res string
+		err = client.Call(&res, test.method)
+		client.Close()
+		if test.allowed && err != nil {
+			t.Errorf("%v %v: unexpected error %v", test.endpoint, test.method, err)
+		}
+		if !test.allowed && (err == nil || err.Error() != (&unauthorizedError{"guarded"}).Error()) {
+			t.Errorf("%v %v: expect unauthorized, got %v", test.endpoint, test.method, err)
+		}
+	}
+}
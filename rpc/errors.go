@@ -58,6 +58,15 @@ func (e *callbackError) ErrorCode() int { return -32000 }
 
 func (e *callbackError) Error() string { return e.message }
 
+// request is for a protected namespace without a token granted the namespace
+type unauthorizedError struct{ service string }
+
+func (e *unauthorizedError) ErrorCode() int { return -32001 }
+
+func (e *unauthorizedError) Error() string {
+	return fmt.Sprintf("unauthorized to access the %s namespace", e.service)
+}
+
 // issued when a request is received after the server is issued to stop.
 type shutdownError struct{}
 
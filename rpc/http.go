@@ -320,6 +320,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if origin := r.Header.Get("Origin"); origin != "" {
 		ctx = context.WithValue(ctx, "Origin", origin)
 	}
+	ctx = withAuthToken(ctx, r)
 
 	// set up reading limit be 512 bytes because the max request content length
 	// allowed to be passed into a single request is 512 bytes
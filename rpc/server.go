@@ -356,6 +356,11 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
 
+	// the namespace guarded by the authenticator requires the token granted the namespace
+	if s.auth != nil && !s.auth.authorize(authTokenFromContext(ctx), req.svcname) {
+		return codec.CreateErrorResponse(&req.id, &unauthorizedError{req.svcname}), nil
+	}
+
 	// if the request is for canceling subscription
 	if req.isUnsubscribe { // cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
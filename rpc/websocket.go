@@ -97,7 +97,10 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			decoder := func(v interface{}) error {
 				return websocketJSONCodec.Receive(conn, v)
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+			// the token in the handshake request authenticates all requests of the connection
+			codec := NewCodec(conn, encoder, decoder)
+			defer codec.Close()
+			srv.serveRequest(withAuthToken(context.Background(), conn.Request()), codec, false, OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
@@ -0,0 +1,239 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+// CompressedMsg is the message code of the compressed storage message, which wraps
+// the original storage message compressed by the negotiated codec
+const CompressedMsg = 0x40
+
+// Storage message compression codecs
+const (
+	CompressionNone    = "none"
+	CompressionSnappy  = "snappy"
+	CompressionDeflate = "deflate"
+)
+
+// DefaultCompressionThreshold is the default minimum payload size in bytes of the
+// storage messages to be compressed
+const DefaultCompressionThreshold = 4096
+
+// SupportedCompressionCodecs is the list of codecs supported by the node, in the
+// order of preference
+var SupportedCompressionCodecs = []string{CompressionSnappy, CompressionDeflate}
+
+// errDecompressedTooLarge is returned if the decompressed message exceeds the size limit
+var errDecompressedTooLarge = errors.New("decompressed storage message too large")
+
+type (
+	// CompressionConfig is the storage message compression configuration of the node
+	CompressionConfig struct {
+		// Codec is the codec preferred to compress the storage messages, compression
+		// is disabled if it is none or empty
+		Codec string
+
+		// Threshold is the minimum payload size in bytes of the messages to be compressed
+		Threshold uint64
+	}
+
+	// CompressionCapability is the storage message compression capability advertised
+	// in the handshake
+	CompressionCapability struct {
+		Codecs    []string
+		Threshold uint64
+	}
+
+	// CompressedMessage is the payload of CompressedMsg
+	CompressedMessage struct {
+		Code uint64
+		Data []byte
+	}
+)
+
+// ValidateCompressionCodec checks whether the codec is supported
+func ValidateCompressionCodec(codec string) error {
+	if codec == "" || codec == CompressionNone {
+		return nil
+	}
+	for _, supported := range SupportedCompressionCodecs {
+		if codec == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported storage message compression codec %s", codec)
+}
+
+// Capability returns the compression capability advertised in the handshake. The configured
+// codec is advertised first as the preferred one, followed by the other supported codecs.
+// Nothing is advertised if the compression is disabled
+func (config CompressionConfig) Capability() []CompressionCapability {
+	if config.Codec == "" || config.Codec == CompressionNone {
+		return nil
+	}
+	codecs := []string{config.Codec}
+	for _, codec := range SupportedCompressionCodecs {
+		if codec != config.Codec {
+			codecs = append(codecs, codec)
+		}
+	}
+	return []CompressionCapability{{Codecs: codecs, Threshold: config.Threshold}}
+}
+
+// NegotiateCompression negotiates the compression used with the peer based on the
+// capabilities advertised by both sides. Both sides must pick the same codec regardless of
+// which side is local: the codec preferred by both sides is used if they agree, otherwise
+// the first codec in SupportedCompressionCodecs advertised by both sides is used. The
+// larger threshold of both sides applies. If either side does not advertise the capability,
+// the compression is disabled
+func NegotiateCompression(local, remote []CompressionCapability) (codec string, threshold uint64) {
+	if len(local) == 0 || len(remote) == 0 || len(local[0].Codecs) == 0 || len(remote[0].Codecs) == 0 {
+		return CompressionNone, 0
+	}
+	threshold = local[0].Threshold
+	if remote[0].Threshold > threshold {
+		threshold = remote[0].Threshold
+	}
+	if preferred := local[0].Codecs[0]; preferred == remote[0].Codecs[0] && ValidateCompressionCodec(preferred) == nil {
+		return preferred, threshold
+	}
+	for _, c := range SupportedCompressionCodecs {
+		if containsCodec(local[0].Codecs, c) && containsCodec(remote[0].Codecs, c) {
+			return c, threshold
+		}
+	}
+	return CompressionNone, 0
+}
+
+// containsCodec checks whether the codec is in the list of codecs
+func containsCodec(codecs []string, codec string) bool {
+	for _, c := range codecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
+
+// Compress compresses the data with the codec
+func Compress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	case CompressionDeflate:
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.BestSpeed)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(data); err != nil {
+			return nil, err
+		}
+		if err = w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage message compression codec %s", codec)
+	}
+}
+
+// Decompress decompresses the data with the codec. The decompressed data is not allowed
+// to exceed the maxSize
+func Decompress(codec string, data []byte, maxSize uint64) ([]byte, error) {
+	switch codec {
+	case CompressionSnappy:
+		size, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(size) > maxSize {
+			return nil, errDecompressedTooLarge
+		}
+		return snappy.Decode(nil, data)
+	case CompressionDeflate:
+		r := flate.NewReader(bytes.NewReader(data))
+		defer r.Close()
+		decompressed, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(decompressed)) > maxSize {
+			return nil, errDecompressedTooLarge
+		}
+		return decompressed, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage message compression codec %s", codec)
+	}
+}
+
+// MessageCompressionStats is the compression statistics of a storage message type
+type MessageCompressionStats struct {
+	Messages        uint64  `json:"messages"`
+	Compressed      uint64  `json:"compressed"`
+	OriginalBytes   uint64  `json:"originalbytes"`
+	CompressedBytes uint64  `json:"compressedbytes"`
+	Ratio           float64 `json:"ratio"`
+}
+
+// CompressionStats records the realized compression ratios of the storage messages sent,
+// grouped by the message code
+type CompressionStats struct {
+	messages map[uint64]*MessageCompressionStats
+	lock     sync.Mutex
+}
+
+// NewCompressionStats creates an empty CompressionStats
+func NewCompressionStats() *CompressionStats {
+	return &CompressionStats{
+		messages: make(map[uint64]*MessageCompressionStats),
+	}
+}
+
+// Record records a storage message sent, where size is the payload size before the compression,
+// and sent is the size actually sent. The message is not compressed if compressed is false
+func (cs *CompressionStats) Record(code uint64, size, sent int, compressed bool) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	stats, exists := cs.messages[code]
+	if !exists {
+		stats = &MessageCompressionStats{}
+		cs.messages[code] = stats
+	}
+	stats.Messages++
+	if compressed {
+		stats.Compressed++
+	}
+	stats.OriginalBytes += uint64(size)
+	stats.CompressedBytes += uint64(sent)
+}
+
+// Snapshot returns the compression statistics keyed by the hex message code. The ratio is
+// the size sent over the original size
+func (cs *CompressionStats) Snapshot() map[string]MessageCompressionStats {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	snapshot := make(map[string]MessageCompressionStats)
+	for code, stats := range cs.messages {
+		s := *stats
+		if s.OriginalBytes != 0 {
+			s.Ratio = float64(s.CompressedBytes) / float64(s.OriginalBytes)
+		}
+		snapshot[fmt.Sprintf("0x%x", code)] = s
+	}
+	return snapshot
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestCompressDecompress test the round trip of the compression with all supported codecs
+func TestCompressDecompress(t *testing.T) {
+	data := bytes.Repeat([]byte("storage message payload "), 1000)
+	for _, codec := range SupportedCompressionCodecs {
+		compressed, err := Compress(codec, data)
+		if err != nil {
+			t.Fatalf("codec %s: %v", codec, err)
+		}
+		if len(compressed) >= len(data) {
+			t.Errorf("codec %s: compressed size %d not smaller than %d", codec, len(compressed), len(data))
+		}
+		decompressed, err := Decompress(codec, compressed, uint64(len(data)))
+		if err != nil {
+			t.Fatalf("codec %s: %v", codec, err)
+		}
+		if !bytes.Equal(decompressed, data) {
+			t.Errorf("codec %s: decompressed data not equal to the original", codec)
+		}
+		if _, err = Decompress(codec, compressed, uint64(len(data)-1)); err != errDecompressedTooLarge {
+			t.Errorf("codec %s: expect error %v, got %v", codec, errDecompressedTooLarge, err)
+		}
+	}
+	if _, err := Compress(CompressionNone, data); err == nil {
+		t.Error("compress with codec none should fail")
+	}
+}
+
+func TestNegotiateCompression(t *testing.T) {
+	snappyFirst := CompressionConfig{Codec: CompressionSnappy, Threshold: 1024}.Capability()
+	deflateFirst := CompressionConfig{Codec: CompressionDeflate, Threshold: 4096}.Capability()
+	deflateOnly := []CompressionCapability{{Codecs: []string{CompressionDeflate}, Threshold: 512}}
+	unknown := []CompressionCapability{{Codecs: []string{"lz4"}, Threshold: 512}}
+	disabled := CompressionConfig{Codec: CompressionNone, Threshold: 1024}.Capability()
+
+	tests := []struct {
+		local, remote []CompressionCapability
+		codec         string
+		threshold     uint64
+	}{
+		{snappyFirst, deflateFirst, CompressionSnappy, 4096},
+		{deflateFirst, snappyFirst, CompressionSnappy, 4096},
+		{deflateFirst, deflateFirst, CompressionDeflate, 4096},
+		{snappyFirst, deflateOnly, CompressionDeflate, 1024},
+		{deflateOnly, snappyFirst, CompressionDeflate, 1024},
+		{snappyFirst, unknown, CompressionNone, 0},
+		{snappyFirst, disabled, CompressionNone, 0},
+		{disabled, snappyFirst, CompressionNone, 0},
+	}
+	for i, test := range tests {
+		codec, threshold := NegotiateCompression(test.local, test.remote)
+		if codec != test.codec || threshold != test.threshold {
+			t.Errorf("test %d: expect %s/%d, got %s/%d", i, test.codec, test.threshold, codec, threshold)
+		}
+		// both sides of the connection must negotiate the same compression
+		if remoteCodec, remoteThreshold := NegotiateCompression(test.remote, test.local); remoteCodec != codec || remoteThreshold != threshold {
+			t.Errorf("test %d: the remote negotiates %s/%d, the local negotiates %s/%d", i, remoteCodec, remoteThreshold, codec, threshold)
+		}
+	}
+}
+
+func TestCompressionStats(t *testing.T) {
+	stats := NewCompressionStats()
+	stats.Record(ContractUploadReqMsg, 1000, 250, true)
+	stats.Record(ContractUploadReqMsg, 1000, 250, true)
+	stats.Record(HostConfigReqMsg, 100, 100, false)
+
+	snapshot := stats.Snapshot()
+	upload := snapshot["0x33"]
+	if upload.Messages != 2 || upload.Compressed != 2 || upload.OriginalBytes != 2000 || upload.CompressedBytes != 500 {
+		t.Errorf("unexpected upload stats %+v", upload)
+	}
+	if upload.Ratio != 0.25 {
+		t.Errorf("expect ratio 0.25, got %v", upload.Ratio)
+	}
+	config := snapshot["0x30"]
+	if config.Messages != 1 || config.Compressed != 0 || config.Ratio != 1 {
+		t.Errorf("unexpected config stats %+v", config)
+	}
+}
@@ -84,3 +84,17 @@ var (
 		ExpectedRedundancy: 2.0,
 	}
 )
+
+// The metrics aggregated hourly by the storage host and the storage client. The bytes
+// uploaded and downloaded are counted in the direction of the client
+const (
+	MetricRevenue         = "revenue"
+	MetricSpend           = "spend"
+	MetricUploadBytes     = "uploadbytes"
+	MetricDownloadBytes   = "downloadbytes"
+	MetricContracts       = "contracts"
+	MetricStorageProofs   = "storageproofs"
+	MetricsFileName       = "metrics.dat"
+	MetricsRetentionDays  = 30
+	MetricsRetentionHours = MetricsRetentionDays * 24
+)
@@ -0,0 +1,116 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"io"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/rlp"
+)
+
+// hostExtConfigRLP is the RLP layout of HostExtConfig. The fields added to the config after
+// Version are encoded in the tail in the order added, so that the config advertised by the
+// hosts not knowing the fields can still be decoded, and the unknown fields appended by the
+// newer hosts are ignored
+type hostExtConfigRLP struct {
+	AcceptingContracts   bool
+	MaxDownloadBatchSize uint64
+	MaxDuration          uint64
+	MaxReviseBatchSize   uint64
+	PaymentAddress       common.Address
+	RemainingStorage     uint64
+	SectorSize           uint64
+	TotalStorage         uint64
+
+	WindowSize uint64
+
+	Deposit    common.BigInt
+	MaxDeposit common.BigInt
+
+	BaseRPCPrice           common.BigInt
+	ContractPrice          common.BigInt
+	DownloadBandwidthPrice common.BigInt
+	SectorAccessPrice      common.BigInt
+	StoragePrice           common.BigInt
+	UploadBandwidthPrice   common.BigInt
+
+	Version string
+
+	Tail []rlp.RawValue `rlp:"tail"`
+}
+
+// EncodeRLP encodes the host config, the fields added after Version are encoded in the tail.
+// HostInfo embedding the config is not expected to be RLP encoded, since the method is promoted
+func (config HostExtConfig) EncodeRLP(w io.Writer) error {
+	maxUploadBatchSectors, err := rlp.EncodeToBytes(config.MaxUploadBatchSectors)
+	if err != nil {
+		return err
+	}
+	scheduledPrices, err := rlp.EncodeToBytes(config.ScheduledPrices)
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, hostExtConfigRLP{
+		AcceptingContracts:     config.AcceptingContracts,
+		MaxDownloadBatchSize:   config.MaxDownloadBatchSize,
+		MaxDuration:            config.MaxDuration,
+		MaxReviseBatchSize:     config.MaxReviseBatchSize,
+		PaymentAddress:         config.PaymentAddress,
+		RemainingStorage:       config.RemainingStorage,
+		SectorSize:             config.SectorSize,
+		TotalStorage:           config.TotalStorage,
+		WindowSize:             config.WindowSize,
+		Deposit:                config.Deposit,
+		MaxDeposit:             config.MaxDeposit,
+		BaseRPCPrice:           config.BaseRPCPrice,
+		ContractPrice:          config.ContractPrice,
+		DownloadBandwidthPrice: config.DownloadBandwidthPrice,
+		SectorAccessPrice:      config.SectorAccessPrice,
+		StoragePrice:           config.StoragePrice,
+		UploadBandwidthPrice:   config.UploadBandwidthPrice,
+		Version:                config.Version,
+		Tail:                   []rlp.RawValue{maxUploadBatchSectors, scheduledPrices},
+	})
+}
+
+// DecodeRLP decodes the host config, the fields missing in the tail are left as zero
+func (config *HostExtConfig) DecodeRLP(s *rlp.Stream) error {
+	var dec hostExtConfigRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	*config = HostExtConfig{
+		AcceptingContracts:     dec.AcceptingContracts,
+		MaxDownloadBatchSize:   dec.MaxDownloadBatchSize,
+		MaxDuration:            dec.MaxDuration,
+		MaxReviseBatchSize:     dec.MaxReviseBatchSize,
+		PaymentAddress:         dec.PaymentAddress,
+		RemainingStorage:       dec.RemainingStorage,
+		SectorSize:             dec.SectorSize,
+		TotalStorage:           dec.TotalStorage,
+		WindowSize:             dec.WindowSize,
+		Deposit:                dec.Deposit,
+		MaxDeposit:             dec.MaxDeposit,
+		BaseRPCPrice:           dec.BaseRPCPrice,
+		ContractPrice:          dec.ContractPrice,
+		DownloadBandwidthPrice: dec.DownloadBandwidthPrice,
+		SectorAccessPrice:      dec.SectorAccessPrice,
+		StoragePrice:           dec.StoragePrice,
+		UploadBandwidthPrice:   dec.UploadBandwidthPrice,
+		Version:                dec.Version,
+	}
+	if len(dec.Tail) > 0 {
+		if err := rlp.DecodeBytes(dec.Tail[0], &config.MaxUploadBatchSectors); err != nil {
+			return err
+		}
+	}
+	if len(dec.Tail) > 1 {
+		if err := rlp.DecodeBytes(dec.Tail[1], &config.ScheduledPrices); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/rlp"
+)
+
+// legacyHostExtConfig is the layout of the host config advertised before the tail fields
+type legacyHostExtConfig struct {
+	AcceptingContracts     bool
+	MaxDownloadBatchSize   uint64
+	MaxDuration            uint64
+	MaxReviseBatchSize     uint64
+	PaymentAddress         common.Address
+	RemainingStorage       uint64
+	SectorSize             uint64
+	TotalStorage           uint64
+	WindowSize             uint64
+	Deposit                common.BigInt
+	MaxDeposit             common.BigInt
+	BaseRPCPrice           common.BigInt
+	ContractPrice          common.BigInt
+	DownloadBandwidthPrice common.BigInt
+	SectorAccessPrice      common.BigInt
+	StoragePrice           common.BigInt
+	UploadBandwidthPrice   common.BigInt
+	Version                string
+}
+
+func TestHostExtConfig_RLP(t *testing.T) {
+	config := HostExtConfig{
+		AcceptingContracts:    true,
+		MaxReviseBatchSize:    1 << 20,
+		PaymentAddress:        common.Address{1},
+		SectorSize:            SectorSize,
+		Deposit:               common.NewBigIntUint64(3),
+		StoragePrice:          common.NewBigIntUint64(5),
+		ScheduledPrices:       []HostScheduledPrice{{Name: "storagePrice", Price: common.NewBigIntUint64(6), EffectiveHeight: 100}},
+		Version:               ConfigVersion,
+		MaxUploadBatchSectors: 16,
+	}
+	b, err := rlp.EncodeToBytes(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded HostExtConfig
+	if err = rlp.DecodeBytes(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, config) {
+		t.Errorf("decoded config not expected:\n%+v\n%+v", decoded, config)
+	}
+
+	// the config advertised by the host not knowing the tail fields
+	legacy := legacyHostExtConfig{
+		MaxReviseBatchSize: config.MaxReviseBatchSize,
+		Deposit:            config.Deposit,
+		Version:            "1.0.0",
+	}
+	if b, err = rlp.EncodeToBytes(legacy); err != nil {
+		t.Fatal(err)
+	}
+	decoded = HostExtConfig{}
+	if err = rlp.DecodeBytes(b, &decoded); err != nil {
+		t.Fatalf("the legacy config cannot be decoded: %v", err)
+	}
+	if decoded.MaxReviseBatchSize != legacy.MaxReviseBatchSize || decoded.Version != legacy.Version ||
+		decoded.MaxUploadBatchSectors != 0 || len(decoded.ScheduledPrices) != 0 {
+		t.Errorf("decoded legacy config not expected: %+v", decoded)
+	}
+
+	// the unknown fields appended by the newer hosts are ignored
+	newer := hostExtConfigRLP{Version: ConfigVersion, Tail: []rlp.RawValue{{0x10}, {0xc0}, {0x82, 0x01, 0x02}}}
+	if b, err = rlp.EncodeToBytes(newer); err != nil {
+		t.Fatal(err)
+	}
+	decoded = HostExtConfig{}
+	if err = rlp.DecodeBytes(b, &decoded); err != nil {
+		t.Fatalf("the config with unknown fields cannot be decoded: %v", err)
+	}
+	if decoded.MaxUploadBatchSectors != 16 {
+		t.Errorf("max upload batch sectors expect 16, got %v", decoded.MaxUploadBatchSectors)
+	}
+}
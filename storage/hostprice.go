@@ -0,0 +1,118 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DxChainNetwork/godx/common"
+)
+
+// HostScheduledPrice is a price change pre-announced by the storage host. The price
+// specified by Name will be changed to Price once the block height reaches EffectiveHeight
+type HostScheduledPrice struct {
+	Name            string        `json:"name"`
+	Price           common.BigInt `json:"price"`
+	EffectiveHeight uint64        `json:"effectiveHeight"`
+}
+
+// HostPriceNames are the names of the host prices that could be scheduled
+var HostPriceNames = []string{
+	"baseRPCPrice",
+	"contractPrice",
+	"downloadBandwidthPrice",
+	"sectorAccessPrice",
+	"storagePrice",
+	"uploadBandwidthPrice",
+}
+
+// ValidateScheduledPrice checks whether the scheduled price could be applied
+func ValidateScheduledPrice(sp HostScheduledPrice) error {
+	for _, name := range HostPriceNames {
+		if name == sp.Name {
+			return nil
+		}
+	}
+	return fmt.Errorf("price %v cannot be scheduled", sp.Name)
+}
+
+// SortScheduledPrices sort the scheduled prices by the effective height
+func SortScheduledPrices(prices []HostScheduledPrice) {
+	sort.SliceStable(prices, func(i, j int) bool {
+		return prices[i].EffectiveHeight < prices[j].EffectiveHeight
+	})
+}
+
+// ApplyScheduledPrices applies all scheduled prices effective at the block height
+// to the host internal config, and returns the scheduled prices not effective yet
+func (config *HostIntConfig) ApplyScheduledPrices(height uint64) (applied []HostScheduledPrice) {
+	var pending []HostScheduledPrice
+	for _, sp := range config.ScheduledPrices {
+		if sp.EffectiveHeight > height {
+			pending = append(pending, sp)
+			continue
+		}
+		if price := config.price(sp.Name); price != nil {
+			*price = sp.Price
+			applied = append(applied, sp)
+		}
+	}
+	config.ScheduledPrices = pending
+	return
+}
+
+// price return the pointer to the price field specified by name
+func (config *HostIntConfig) price(name string) *common.BigInt {
+	switch name {
+	case "baseRPCPrice":
+		return &config.BaseRPCPrice
+	case "contractPrice":
+		return &config.ContractPrice
+	case "downloadBandwidthPrice":
+		return &config.DownloadBandwidthPrice
+	case "sectorAccessPrice":
+		return &config.SectorAccessPrice
+	case "storagePrice":
+		return &config.StoragePrice
+	case "uploadBandwidthPrice":
+		return &config.UploadBandwidthPrice
+	}
+	return nil
+}
+
+// UpcomingPrices returns the host config with the price increases scheduled before or at
+// the block height applied. Price decreases are ignored, so that the cost estimated with
+// the returned config will not be lower than the cost actually charged by the host
+func (config HostExtConfig) UpcomingPrices(height uint64) HostExtConfig {
+	for _, sp := range config.ScheduledPrices {
+		if sp.EffectiveHeight > height {
+			continue
+		}
+		if price := config.price(sp.Name); price != nil && sp.Price.Cmp(*price) > 0 {
+			*price = sp.Price
+		}
+	}
+	return config
+}
+
+// price return the pointer to the price field specified by name
+func (config *HostExtConfig) price(name string) *common.BigInt {
+	switch name {
+	case "baseRPCPrice":
+		return &config.BaseRPCPrice
+	case "contractPrice":
+		return &config.ContractPrice
+	case "downloadBandwidthPrice":
+		return &config.DownloadBandwidthPrice
+	case "sectorAccessPrice":
+		return &config.SectorAccessPrice
+	case "storagePrice":
+		return &config.StoragePrice
+	case "uploadBandwidthPrice":
+		return &config.UploadBandwidthPrice
+	}
+	return nil
+}
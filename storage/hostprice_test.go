@@ -0,0 +1,45 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+)
+
+func TestHostExtConfig_UpcomingPrices(t *testing.T) {
+	config := HostExtConfig{
+		StoragePrice:  common.NewBigIntUint64(10),
+		ContractPrice: common.NewBigIntUint64(10),
+		ScheduledPrices: []HostScheduledPrice{
+			{Name: "storagePrice", Price: common.NewBigIntUint64(20), EffectiveHeight: 100},
+			{Name: "contractPrice", Price: common.NewBigIntUint64(5), EffectiveHeight: 100},
+			{Name: "storagePrice", Price: common.NewBigIntUint64(30), EffectiveHeight: 200},
+		},
+	}
+
+	tests := []struct {
+		height        uint64
+		storagePrice  uint64
+		contractPrice uint64
+	}{
+		{99, 10, 10},
+		{100, 20, 10},
+		{200, 30, 10},
+	}
+	for _, test := range tests {
+		upcoming := config.UpcomingPrices(test.height)
+		if upcoming.StoragePrice.CmpUint64(test.storagePrice) != 0 {
+			t.Errorf("height %v: storage price expect %v, got %v", test.height, test.storagePrice, upcoming.StoragePrice)
+		}
+		if upcoming.ContractPrice.CmpUint64(test.contractPrice) != 0 {
+			t.Errorf("height %v: contract price expect %v, got %v", test.height, test.contractPrice, upcoming.ContractPrice)
+		}
+	}
+	if config.StoragePrice.CmpUint64(10) != 0 {
+		t.Errorf("the original config should not be modified")
+	}
+}
@@ -50,4 +50,6 @@ type Peer interface {
 	RequestHostConfigDone()
 	PeerNode() *enode.Node
 	IsStaticConn() bool
+	RotateSessionKey() error
+	TakeQoSSample() PeerQoSSample
 }
@@ -0,0 +1,98 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// qosThroughputMinSize is the minimum size of the response counted in the throughput. The
+// smaller responses are dominated by the round trip time
+const qosThroughputMinSize = 64 * 1024
+
+type (
+	// PeerQoS records the quality of the storage negotiations experienced with a peer, which
+	// includes the round trip time of the responses, the responses timed out, the messages
+	// failed to be sent, and the throughput of the large responses
+	PeerQoS struct {
+		sample PeerQoSSample
+		lock   sync.Mutex
+	}
+
+	// PeerQoSSample is the quality of the storage negotiations recorded since the last sample
+	PeerQoSSample struct {
+		Responses    uint64        `json:"responses"`
+		Timeouts     uint64        `json:"timeouts"`
+		SendFailures uint64        `json:"sendfailures"`
+		TotalRTT     time.Duration `json:"totalrtt"`
+
+		// TransferredBytes and TransferTime are the total size and the time of the
+		// responses counted in the throughput
+		TransferredBytes uint64        `json:"transferredbytes"`
+		TransferTime     time.Duration `json:"transfertime"`
+	}
+)
+
+// NewPeerQoS creates an empty PeerQoS
+func NewPeerQoS() *PeerQoS {
+	return &PeerQoS{}
+}
+
+// RecordResponse records a response of size received rtt after the request is sent
+func (q *PeerQoS) RecordResponse(rtt time.Duration, size uint32) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.sample.Responses++
+	q.sample.TotalRTT += rtt
+	if size >= qosThroughputMinSize {
+		q.sample.TransferredBytes += uint64(size)
+		q.sample.TransferTime += rtt
+	}
+}
+
+// RecordTimeout records a response not received before the timeout
+func (q *PeerQoS) RecordTimeout() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.sample.Timeouts++
+}
+
+// RecordSendFailure records a message failed to be sent to the peer
+func (q *PeerQoS) RecordSendFailure() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.sample.SendFailures++
+}
+
+// TakeSample returns the quality recorded since the last sample, and resets the records
+func (q *PeerQoS) TakeSample() (sample PeerQoSSample) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	sample, q.sample = q.sample, PeerQoSSample{}
+	return
+}
+
+// Empty returns whether nothing is recorded in the sample
+func (s PeerQoSSample) Empty() bool {
+	return s.Responses == 0 && s.Timeouts == 0 && s.SendFailures == 0
+}
+
+// AverageRTT returns the average round trip time of the responses in the sample
+func (s PeerQoSSample) AverageRTT() time.Duration {
+	if s.Responses == 0 {
+		return 0
+	}
+	return s.TotalRTT / time.Duration(s.Responses)
+}
+
+// Throughput returns the throughput of the large responses in the sample in bytes per second
+func (s PeerQoSSample) Throughput() float64 {
+	if s.TransferTime <= 0 {
+		return 0
+	}
+	return float64(s.TransferredBytes) / s.TransferTime.Seconds()
+}
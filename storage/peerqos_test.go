@@ -0,0 +1,33 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerQoS_TakeSample(t *testing.T) {
+	q := NewPeerQoS()
+	q.RecordResponse(100*time.Millisecond, 128)
+	q.RecordResponse(300*time.Millisecond, 1<<20)
+	q.RecordTimeout()
+	q.RecordSendFailure()
+
+	sample := q.TakeSample()
+	if sample.Responses != 2 || sample.Timeouts != 1 || sample.SendFailures != 1 {
+		t.Fatalf("unexpected sample %+v", sample)
+	}
+	if rtt := sample.AverageRTT(); rtt != 200*time.Millisecond {
+		t.Errorf("expect average rtt %v, got %v", 200*time.Millisecond, rtt)
+	}
+	// only the large response is counted in the throughput
+	if throughput, expect := sample.Throughput(), float64(1<<20)/0.3; throughput < expect-1 || throughput > expect+1 {
+		t.Errorf("expect throughput %v, got %v", expect, throughput)
+	}
+	if sample = q.TakeSample(); !sample.Empty() {
+		t.Errorf("the records should be reset after the sample is taken, got %+v", sample)
+	}
+}
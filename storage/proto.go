@@ -10,9 +10,14 @@ import (
 	"math/big"
 )
 
-// Defines upload mode
+// Defines upload mode. The sectors are removed from the contract by swapping them
+// to the end and trimming them, which could not be combined with Append in a request
 const (
 	UploadActionAppend = "Append"
+	// UploadActionSwap swaps the sector at the index A with the sector at the index B
+	UploadActionSwap = "Swap"
+	// UploadActionTrim removes the last A sectors
+	UploadActionTrim = "Trim"
 )
 
 type (
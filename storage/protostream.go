@@ -0,0 +1,122 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/DxChainNetwork/godx/rlp"
+)
+
+// DecodeUploadRequest decodes the upload request from the reader of the message payload.
+// The actions are passed to handleAction one at a time as they are decoded, and the size
+// of each action data is checked against the remaining maxDataSize before the data is
+// copied, thus the decoded actions never hold more than maxDataSize of data. The returned
+// request contains every field except the actions.
+func DecodeUploadRequest(r io.Reader, size uint64, maxDataSize uint64, handleAction func(UploadAction) error) (req UploadRequest, err error) {
+	s := rlp.NewStream(r, size)
+	if _, err = s.List(); err != nil {
+		return
+	}
+	if err = s.Decode(&req.StorageContractID); err != nil {
+		return
+	}
+
+	// decode the actions one by one
+	if _, err = s.List(); err != nil {
+		return
+	}
+	remaining := maxDataSize
+	for {
+		var action UploadAction
+		action, err = decodeUploadAction(s, remaining)
+		if err == rlp.EOL {
+			break
+		} else if err != nil {
+			return
+		}
+		remaining -= uint64(len(action.Data))
+		if err = handleAction(action); err != nil {
+			return
+		}
+	}
+	if err = s.ListEnd(); err != nil {
+		return
+	}
+
+	if req.NewRevisionNumber, err = s.Uint(); err != nil {
+		return
+	}
+	if err = s.Decode(&req.NewValidProofValues); err != nil {
+		return
+	}
+	if err = s.Decode(&req.NewMissedProofValues); err != nil {
+		return
+	}
+	err = s.ListEnd()
+	return
+}
+
+// decodeUploadAction decodes the next upload action in the stream, the data larger
+// than maxDataSize is rejected before it is copied
+func decodeUploadAction(s *rlp.Stream, maxDataSize uint64) (action UploadAction, err error) {
+	if _, err = s.List(); err != nil {
+		return
+	}
+	var actionType []byte
+	if actionType, err = s.Bytes(); err != nil {
+		return
+	}
+	action.Type = string(actionType)
+	if action.A, err = s.Uint(); err != nil {
+		return
+	}
+	if action.B, err = s.Uint(); err != nil {
+		return
+	}
+
+	_, dataSize, err := s.Kind()
+	if err != nil {
+		return
+	}
+	if dataSize > maxDataSize {
+		err = fmt.Errorf("upload action data size %v exceeds the limit %v", dataSize, maxDataSize)
+		return
+	}
+	if action.Data, err = s.Bytes(); err != nil {
+		return
+	}
+	err = s.ListEnd()
+	return
+}
+
+// DecodeDownloadResponse decodes the download response from the message payload. The
+// response data larger than maxDataSize is rejected before it is copied.
+func DecodeDownloadResponse(r io.Reader, size uint64, maxDataSize uint64) (resp DownloadResponse, err error) {
+	s := rlp.NewStream(r, size)
+	if _, err = s.List(); err != nil {
+		return
+	}
+	if resp.Signature, err = s.Bytes(); err != nil {
+		return
+	}
+	_, dataSize, err := s.Kind()
+	if err != nil {
+		return
+	}
+	if dataSize > maxDataSize {
+		err = fmt.Errorf("download response data size %v exceeds the limit %v", dataSize, maxDataSize)
+		return
+	}
+	if resp.Data, err = s.Bytes(); err != nil {
+		return
+	}
+	if err = s.Decode(&resp.MerkleProof); err != nil {
+		return
+	}
+	err = s.ListEnd()
+	return
+}
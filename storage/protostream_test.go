@@ -0,0 +1,77 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/rlp"
+)
+
+func TestDecodeUploadRequest(t *testing.T) {
+	req := UploadRequest{
+		StorageContractID: common.Hash{1, 2, 3},
+		Actions: []UploadAction{
+			{Type: UploadActionAppend, Data: bytes.Repeat([]byte{1}, 100)},
+			{Type: UploadActionAppend, A: 1, B: 2, Data: bytes.Repeat([]byte{2}, 100)},
+		},
+		NewRevisionNumber:    10,
+		NewValidProofValues:  []*big.Int{big.NewInt(100), big.NewInt(0)},
+		NewMissedProofValues: []*big.Int{big.NewInt(1), big.NewInt(1 << 40)},
+	}
+	b, err := rlp.EncodeToBytes(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actions []UploadAction
+	handle := func(action UploadAction) error {
+		actions = append(actions, action)
+		return nil
+	}
+	decoded, err := DecodeUploadRequest(bytes.NewReader(b), uint64(len(b)), 200, handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded.Actions = actions
+	if !reflect.DeepEqual(decoded, req) {
+		t.Fatalf("decoded request not expected:\n%+v\n%+v", decoded, req)
+	}
+
+	// the action data exceeding the limit is rejected, and the actions after are not read
+	actions = nil
+	if _, err = DecodeUploadRequest(bytes.NewReader(b), uint64(len(b)), 150, handle); err == nil {
+		t.Fatal("the data exceeding the limit should be rejected")
+	}
+	if len(actions) != 1 {
+		t.Errorf("expect 1 action handled before the limit exceeded, got %v", len(actions))
+	}
+}
+
+func TestDecodeDownloadResponse(t *testing.T) {
+	resp := DownloadResponse{
+		Signature:   []byte{1, 2, 3},
+		Data:        bytes.Repeat([]byte{4}, 64),
+		MerkleProof: []common.Hash{{5}, {6}},
+	}
+	b, err := rlp.EncodeToBytes(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeDownloadResponse(bytes.NewReader(b), uint64(len(b)), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, resp) {
+		t.Fatalf("decoded response not expected:\n%+v\n%+v", decoded, resp)
+	}
+	if _, err = DecodeDownloadResponse(bytes.NewReader(b), uint64(len(b)), 63); err == nil {
+		t.Fatal("the data exceeding the limit should be rejected")
+	}
+}
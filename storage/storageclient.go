@@ -63,10 +63,19 @@ type ClientBackend interface {
 	RevisionOrRenewingDone(hostID enode.ID)
 	CheckAndUpdateConnection(peerNode *enode.Node)
 	SelfEnodeURL() string
+	GetStorageContractRevisionNumber(contractID common.Hash) (revisionNumber uint64, exists bool, err error)
 }
 
 // DownloadParameters is the parameters to download from outer request
 type DownloadParameters struct {
 	RemoteFilePath   string
 	WriteToLocalPath string
+
+	// VerifyRate is the rate of the downloaded sectors to be verified with the Merkle
+	// proofs. Zero is the default, with which every sector is verified.
+	VerifyRate float64
+
+	// Overdrive is the number of extra sector fetches issued for every segment, which
+	// overrides the client setting if specified
+	Overdrive *int
 }
@@ -8,11 +8,15 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"time"
 
 	"github.com/DxChainNetwork/godx/accounts"
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/timeseries"
 	"github.com/DxChainNetwork/godx/p2p/enode"
 	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractmanager"
 	"github.com/DxChainNetwork/godx/storage/storageclient/storagehostmanager"
 )
 
@@ -43,6 +47,15 @@ func (api *PublicStorageClientAPI) Config() (setting storage.ClientSettingAPIDis
 	return formatClientSetting(api.sc.RetrieveClientSetting())
 }
 
+// ExportProfile will export the complete storage client configuration, including the client
+// setting and the host filter setting, into a single JSON document
+func (api *PublicStorageClientAPI) ExportProfile() (profile string, err error) {
+	if profile, err = encodeProfile(api.sc.ExportProfile()); err != nil {
+		err = fmt.Errorf("failed to export the client profile: %s", err.Error())
+	}
+	return
+}
+
 // Hosts will retrieve the current storage hosts from the storage host manager
 func (api *PublicStorageClientAPI) Hosts() (hosts []storage.HostInfo) {
 	return api.sc.storageHostManager.AllHosts()
@@ -69,12 +82,45 @@ func (api *PublicStorageClientAPI) Host(id string) (host storage.HostInfo, err e
 	return info, nil
 }
 
+// ExplainHost will explain why a specific storage host is or is not chosen to sign contracts
+// with, including the evaluation break down, filter status, ip violation status, recent scans,
+// and the selection weight
+func (api *PublicStorageClientAPI) ExplainHost(id string) (exp storagehostmanager.HostExplanation, err error) {
+	var enodeid enode.ID
+
+	// convert the hex string back to the enode.ID type
+	idSlice, err := hex.DecodeString(id)
+	if err != nil {
+		return storagehostmanager.HostExplanation{}, errors.New("the hostID provided is not valid")
+	}
+	copy(enodeid[:], idSlice)
+
+	return api.sc.storageHostManager.ExplainHost(enodeid)
+}
+
 // HostRank will retrieve the rankings of the storage hosts. The ranking information also
 // includes detailed evaluation break down
 func (api *PublicStorageClientAPI) HostRank() (evaluation []storagehostmanager.StorageHostRank) {
 	return api.sc.storageHostManager.StorageHostRanks()
 }
 
+// PriceAudits will retrieve the audits of the charges against the prices advertised by the storage
+// hosts when the contracts were negotiated
+func (api *PublicStorageClientAPI) PriceAudits() map[string]storagehostmanager.HostPriceAudit {
+	return api.sc.storageHostManager.PriceAudits()
+}
+
+// OperationAudits will retrieve the audit trail of the upload and download operations of the
+// contract, each linked to the revision number and the amount charged
+func (api *PublicStorageClientAPI) OperationAudits(contractID string) (audits []OperationAudit, err error) {
+	var id storage.ContractID
+	if id, err = storage.StringToContractID(contractID); err != nil {
+		err = fmt.Errorf("the contract id provided is invalid: %s", err.Error())
+		return
+	}
+	return api.sc.OperationAudits(id)
+}
+
 // Contracts will retrieve all active contracts and display their general information
 func (api *PublicStorageClientAPI) Contracts() (activeContracts []ActiveContractsAPIDisplay) {
 	activeContracts = api.sc.ActiveContracts()
@@ -108,15 +154,28 @@ func (api *PublicStorageClientAPI) PaymentAddress() (common.Address, error) {
 	return api.sc.GetPaymentAddress()
 }
 
-// DownloadSync is used to download remote file by sync mode
+// DownloadOverdriveStats will retrieve the statistics of the extra sector fetches issued by the
+// downloads, and how often the extra fetches were actually needed
+func (api *PublicStorageClientAPI) DownloadOverdriveStats() DownloadOverdriveStats {
+	return api.sc.DownloadOverdriveStats()
+}
+
+// DownloadSync is used to download remote file by sync mode. The optional verifyRate is the rate of
+// sectors verified with the Merkle proofs, every sector is verified if not specified. The optional
+// overdrive is the number of extra sector fetches issued for every segment, which overrides the client setting.
 // NOTE: RPC not support async download, because it is stateless, should block until download task done.
-func (api *PublicStorageClientAPI) DownloadSync(remoteFilePath, localPath string) (string, error) {
+func (api *PublicStorageClientAPI) DownloadSync(remoteFilePath, localPath string, verifyRate *float64, overdrive *int) (string, error) {
 	p := storage.DownloadParameters{
 		// where to write the downloaded files
 		WriteToLocalPath: localPath,
 
 		// where to download the remote file
 		RemoteFilePath: remoteFilePath,
+
+		Overdrive: overdrive,
+	}
+	if verifyRate != nil {
+		p.VerifyRate = *verifyRate
 	}
 	err := api.sc.DownloadSync(p)
 	if err != nil {
@@ -142,6 +201,16 @@ func (api *PublicStorageClientAPI) Upload(source string, dxPath string) (string,
 	return "success", nil
 }
 
+// StorageClasses returns the storage classes that the files can be transitioned to
+func (api *PublicStorageClientAPI) StorageClasses() []StorageClass {
+	return api.sc.StorageClasses()
+}
+
+// StorageClassTransitions returns the storage class transitions in progress
+func (api *PublicStorageClientAPI) StorageClassTransitions() []StorageClassTransition {
+	return api.sc.StorageClassTransitions()
+}
+
 // PrivateStorageClientAPI defines the object used to call eligible APIs
 // that are used to configure settings
 type PrivateStorageClientAPI struct {
@@ -178,6 +247,24 @@ func (api *PrivateStorageClientAPI) SetConfig(settings map[string]string) (resp
 	return
 }
 
+// ImportProfile will import the JSON document exported by ExportProfile. The profile will be fully
+// validated before being applied
+func (api *PrivateStorageClientAPI) ImportProfile(doc string) (resp string, err error) {
+	profile, err := decodeProfile(doc)
+	if err != nil {
+		err = fmt.Errorf("failed to decode the client profile: %s", err.Error())
+		return
+	}
+
+	if err = api.sc.ImportProfile(profile); err != nil {
+		err = fmt.Errorf("failed to import the client profile: %s", err.Error())
+		return
+	}
+
+	resp = fmt.Sprintf("Successfully imported the storage client profile")
+	return
+}
+
 // SetPaymentAddress configure the account address used to sign the storage contract, which has and can only be the address of the local wallet.
 func (api *PrivateStorageClientAPI) SetPaymentAddress(addrStr string) bool {
 	paymentAddress := common.HexToAddress(addrStr)
@@ -196,12 +283,199 @@ func (api *PrivateStorageClientAPI) SetPaymentAddress(addrStr string) bool {
 	return true
 }
 
+// SetPriceAuditBlacklist enables or disables blacklisting the storage hosts that repeatedly
+// charge more than the prices advertised when the contracts were negotiated
+func (api *PrivateStorageClientAPI) SetPriceAuditBlacklist(enable bool) string {
+	api.sc.storageHostManager.SetPriceAuditBlacklist(enable)
+	if enable {
+		return "the storage hosts charging more than advertised will be blacklisted"
+	}
+	return "the price audit blacklist is disabled"
+}
+
+// SetIPChangeGracePeriod sets the grace period, such as "24h", given to the storage hosts
+// changed their ip networks before they are checked for ip violation
+func (api *PrivateStorageClientAPI) SetIPChangeGracePeriod(period string) (string, error) {
+	grace, err := time.ParseDuration(period)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the grace period: %s", err.Error())
+	}
+	if grace < 0 {
+		return "", errors.New("the grace period must not be negative")
+	}
+	api.sc.storageHostManager.SetIPChangeGracePeriod(grace)
+	return fmt.Sprintf("the ip change grace period is set to %v", grace), nil
+}
+
 // PeriodCost will get the client's period cost which specifies cost that storage
 // client needs to pay within one period cycle. It includes cost for all contracts
 func (api *PrivateStorageClientAPI) PeriodCost() storage.PeriodCost {
 	return api.sc.contractManager.RetrievePeriodCost()
 }
 
+// ForecastSpending projects the storage spending over the next periods, including the renewal
+// fees, based on the data stored, the recent upload and download trend, and the market prices
+func (api *PrivateStorageClientAPI) ForecastSpending(periods int) (contractmanager.SpendingForecast, error) {
+	return api.sc.contractManager.ForecastSpending(periods)
+}
+
+// Status returns the consolidated status of the storage client, including the allowance,
+// the contracts, the file health, the repair backlog, the workers, and the recent errors
+func (api *PrivateStorageClientAPI) Status() (ClientStatus, error) {
+	return api.sc.Status()
+}
+
+// Conformance runs the protocol conformance scenarios against the storage host with the
+// enode url, and returns the compliance report of the host. Only the config exchange and
+// the rejection of the invalid requests are checked, see NotCovered of the report
+func (api *PrivateStorageClientAPI) Conformance(enodeURL string) (ConformanceReport, error) {
+	return api.sc.RunConformance(enodeURL)
+}
+
+// ContractUtilization reports the funded-vs-used storage of the active contracts, the contracts
+// chronically underutilized or exhausted, and the rent payment adjustments for the next period
+func (api *PrivateStorageClientAPI) ContractUtilization() (contractmanager.UtilizationReport, error) {
+	return api.sc.contractManager.ContractUtilization(uint64(storage.DefaultNumSectors))
+}
+
+// Mount mounts the dxfiles at the mount point as a local filesystem
+func (api *PrivateStorageClientAPI) Mount(mountPoint string) (string, error) {
+	if err := api.sc.Mount(mountPoint); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("dxfiles mounted at %v", mountPoint), nil
+}
+
+// Unmount unmounts the filesystem at the mount point, the files written are uploaded before returning
+func (api *PrivateStorageClientAPI) Unmount(mountPoint string) (string, error) {
+	if err := api.sc.Unmount(mountPoint); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v unmounted", mountPoint), nil
+}
+
+// Mounts returns the mount points of the mounted filesystems
+func (api *PrivateStorageClientAPI) Mounts() []string {
+	return api.sc.Mounts()
+}
+
+// TransitionStorageClass starts to transition the file to the storage class, the file is replaced
+// once the sectors are placed according to the storage class
+func (api *PrivateStorageClientAPI) TransitionStorageClass(dxPath string, class string) (string, error) {
+	path, err := storage.NewDxPath(dxPath)
+	if err != nil {
+		return "", err
+	}
+	if err = api.sc.TransitionStorageClass(path, class); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("transition of %v to storage class %s started", dxPath, class), nil
+}
+
+// MetricsHistory returns the hourly aggregates of the spend, the bytes transferred and the
+// contracts formed by the client in the last days
+func (api *PrivateStorageClientAPI) MetricsHistory(days int) ([]timeseries.Point, error) {
+	return api.sc.MetricsHistory(days)
+}
+
+// Backup writes the encrypted backup of the storage client to the path, which contains the files,
+// the contracts, the storage host manager state and the client settings
+func (api *PrivateStorageClientAPI) Backup(path string, passphrase string) (string, error) {
+	if err := api.sc.Backup(path, passphrase); err != nil {
+		return "", fmt.Errorf("failed to backup the storage client: %s", err.Error())
+	}
+	return fmt.Sprintf("storage client backup saved to %v", path), nil
+}
+
+// Restore restores the storage client from the backup at the path, it can only be used
+// on a fresh node without contracts and files
+func (api *PrivateStorageClientAPI) Restore(path string, passphrase string) (string, error) {
+	if err := api.sc.Restore(path, passphrase); err != nil {
+		return "", fmt.Errorf("failed to restore the storage client: %s", err.Error())
+	}
+	return fmt.Sprintf("storage client restored from %v", path), nil
+}
+
+// LoadBootstrapSnapshot loads the bootstrap snapshot of the well known storage hosts at the
+// path, which must be signed by the trusted signer. The first contracts could be formed with
+// the storage hosts in the snapshot before the initial scan of the host pool finished
+func (api *PrivateStorageClientAPI) LoadBootstrapSnapshot(path string, signer string) (string, error) {
+	if !common.IsHexAddress(signer) {
+		return "", fmt.Errorf("invalid signer address %v", signer)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the bootstrap snapshot: %s", err.Error())
+	}
+	inserted, err := api.sc.storageHostManager.LoadBootstrapSnapshot(data, common.HexToAddress(signer))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v storage hosts loaded from the bootstrap snapshot", inserted), nil
+}
+
+// ExportBootstrapSnapshot writes the bootstrap snapshot of the active storage hosts with the
+// highest evaluations to the path, signed by the account of the signer
+func (api *PrivateStorageClientAPI) ExportBootstrapSnapshot(path string, signer string, num int) (string, error) {
+	if !common.IsHexAddress(signer) {
+		return "", fmt.Errorf("invalid signer address %v", signer)
+	}
+	data, err := api.sc.storageHostManager.ExportBootstrapSnapshot(common.HexToAddress(signer), num)
+	if err != nil {
+		return "", err
+	}
+	if err = ioutil.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write the bootstrap snapshot: %s", err.Error())
+	}
+	return fmt.Sprintf("bootstrap snapshot saved to %v", path), nil
+}
+
+// DescribeFile writes the descriptor of the file to the path. The descriptor is needed to
+// recover the file from the contracts after the file is deleted
+func (api *PrivateStorageClientAPI) DescribeFile(dxPath string, path string) (string, error) {
+	p, err := storage.NewDxPath(dxPath)
+	if err != nil {
+		return "", err
+	}
+	if err = api.sc.ExportFileDescriptor(p, path); err != nil {
+		return "", fmt.Errorf("failed to describe the file: %s", err.Error())
+	}
+	return fmt.Sprintf("descriptor of %v saved to %v", dxPath, path), nil
+}
+
+// RecoverFile recovers the deleted file with the descriptor at the path, from the sectors
+// still stored under the active contracts
+func (api *PrivateStorageClientAPI) RecoverFile(path string) (FileRecoveryReport, error) {
+	return api.sc.RecoverFile(path)
+}
+
+// DeleteFile deletes the file from the file system, and removes the sectors of the file from
+// the contracts with the storage hosts. The storage and the deposit reclaimed are reported
+func (api *PrivateStorageClientAPI) DeleteFile(dxPath string) (FileDeletionReport, error) {
+	p, err := storage.NewDxPath(dxPath)
+	if err != nil {
+		return FileDeletionReport{}, err
+	}
+	return api.sc.DeleteFileAndReclaim(p)
+}
+
+// VerifyFile verifies the file downloaded to the local path against the sectors stored by the
+// hosts, and the sector roots covered by the latest signed revisions of the contracts
+func (api *PrivateStorageClientAPI) VerifyFile(dxPath string, localPath string) (FileIntegrityReport, error) {
+	p, err := storage.NewDxPath(dxPath)
+	if err != nil {
+		return FileIntegrityReport{}, err
+	}
+	return api.sc.VerifyFile(p, localPath)
+}
+
+// VerifyContracts verifies the merkle roots stored for each contract against the latest
+// revision, and the signatures of the revision. The remediation is suggested for each
+// inconsistent contract
+func (api *PrivateStorageClientAPI) VerifyContracts() (ContractSetReport, error) {
+	return api.sc.VerifyContracts()
+}
+
 // CancelAllContracts will cancel all contracts signed with storage client by
 // marking all active contracts as canceled, not good for uploading, and not good
 // for renewing
@@ -0,0 +1,348 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractmanager"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxdir"
+	"golang.org/x/crypto/scrypt"
+)
+
+// names of the entries in the backup archive
+const (
+	backupProfileEntry     = "profile.json"
+	backupTransitionsEntry = "transitions.json"
+	backupContractsEntry   = "contracts.json"
+	backupHostManagerEntry = "hostmanager.json"
+	backupDirsEntry        = "dirs.json"
+	backupFilesDir         = "files/"
+)
+
+var (
+	// errEmptyPassphrase is returned when backup or restore without a passphrase
+	errEmptyPassphrase = errors.New("passphrase is required to encrypt the backup")
+
+	// errNodeNotFresh is returned when restoring the backup on a node with active contracts
+	errNodeNotFresh = errors.New("the backup can only be restored on a node without active contracts or files")
+
+	// errBackupPassphrase is returned when the backup cannot be decrypted with the passphrase
+	errBackupPassphrase = errors.New("failed to decrypt the backup, wrong passphrase or corrupted backup")
+)
+
+// backupFile is the content of the backup file. The archive is encrypted with the
+// key derived from the passphrase with the scrypt parameters
+type backupFile struct {
+	Version string    `json:"version"`
+	Created time.Time `json:"created"`
+	Salt    []byte    `json:"salt"`
+	ScryptN int       `json:"scryptn"`
+	ScryptR int       `json:"scryptr"`
+	ScryptP int       `json:"scryptp"`
+	Archive []byte    `json:"archive"`
+}
+
+// clientBackup is the decoded content of the backup archive
+type clientBackup struct {
+	profile     ClientProfile
+	transitions []StorageClassTransition
+	contracts   []contractmanager.ContractBackup
+	hostManager []byte
+	fileSystem  filesystem.Backup
+}
+
+// Backup writes an encrypted archive to the path, containing the client profile, the storage
+// host manager state, the active contracts, and the DxDirs and DxFiles. All active contracts
+// are acquired while the other components are captured, so that no revision is committed and
+// no sector is added to the files during the backup
+func (client *StorageClient) Backup(path string, passphrase string) error {
+	if passphrase == "" {
+		return errEmptyPassphrase
+	}
+	if err := client.tm.Add(); err != nil {
+		return err
+	}
+	defer client.tm.Done()
+
+	var backup clientBackup
+	err := client.contractManager.BackupContracts(func(contracts []contractmanager.ContractBackup) (err error) {
+		backup.contracts = contracts
+		backup.profile = client.ExportProfile()
+		backup.transitions = client.StorageClassTransitions()
+		if backup.hostManager, err = client.storageHostManager.Backup(); err != nil {
+			return fmt.Errorf("failed to backup the storage host manager: %s", err.Error())
+		}
+		if backup.fileSystem, err = client.fileSystem.Backup(); err != nil {
+			return fmt.Errorf("failed to backup the file system: %s", err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	archive, err := encodeBackupArchive(backup)
+	if err != nil {
+		return err
+	}
+	file := backupFile{
+		Version: BackupVersion,
+		Created: time.Now(),
+		Salt:    make([]byte, backupSaltSize),
+		ScryptN: backupScryptN,
+		ScryptR: backupScryptR,
+		ScryptP: backupScryptP,
+	}
+	if _, err = rand.Read(file.Salt); err != nil {
+		return err
+	}
+	ck, err := backupCipherKey(passphrase, file)
+	if err != nil {
+		return err
+	}
+	if file.Archive, err = ck.Encrypt(archive); err != nil {
+		return fmt.Errorf("failed to encrypt the backup: %s", err.Error())
+	}
+	blob, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, blob, 0600)
+}
+
+// Restore restores the backup written by Backup to the storage client. It can only be
+// used on a fresh node, which does not have any active contract or file
+func (client *StorageClient) Restore(path string, passphrase string) error {
+	if passphrase == "" {
+		return errEmptyPassphrase
+	}
+	if err := client.tm.Add(); err != nil {
+		return err
+	}
+	defer client.tm.Done()
+
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file backupFile
+	if err = json.Unmarshal(blob, &file); err != nil {
+		return fmt.Errorf("failed to decode the backup file: %s", err.Error())
+	}
+	if file.Version != BackupVersion {
+		return fmt.Errorf("backup version %s is not supported, expected %s", file.Version, BackupVersion)
+	}
+	ck, err := backupCipherKey(passphrase, file)
+	if err != nil {
+		return err
+	}
+	archive, err := ck.Decrypt(file.Archive)
+	if err != nil {
+		return errBackupPassphrase
+	}
+	backup, err := decodeBackupArchive(archive)
+	if err != nil {
+		return err
+	}
+
+	// validate every component before anything is restored
+	if _, err = profileValidation(backup.profile); err != nil {
+		return fmt.Errorf("invalid client profile in the backup: %s", err.Error())
+	}
+	if err = contractmanager.ValidateContractBackups(backup.contracts); err != nil {
+		return err
+	}
+	if err = client.storageHostManager.ValidateBackup(backup.hostManager); err != nil {
+		return err
+	}
+	if err = filesystem.ValidateBackup(backup.fileSystem); err != nil {
+		return err
+	}
+
+	// the backup could only be restored on a fresh node. The file system restore fails
+	// if there are files, so the contracts are checked before the files are restored
+	if len(client.contractManager.RetrieveActiveContracts()) != 0 {
+		return errNodeNotFresh
+	}
+	// the file system and the contract manager remove what they restored on failure, and
+	// the restored files and contracts are removed if the following steps fail, so that
+	// the restore could be retried. The storage host information restored is merged into
+	// the current state, which is not reverted
+	if err = client.fileSystem.Restore(backup.fileSystem); err != nil {
+		return fmt.Errorf("failed to restore the file system: %s", err.Error())
+	}
+	if err = client.contractManager.RestoreContracts(backup.contracts); err != nil {
+		client.removeRestored(backup, false)
+		return err
+	}
+	if err = client.storageHostManager.Restore(backup.hostManager); err != nil {
+		client.removeRestored(backup, true)
+		return err
+	}
+	if err = client.ImportProfile(backup.profile); err != nil {
+		client.removeRestored(backup, true)
+		return fmt.Errorf("failed to restore the client profile: %s", err.Error())
+	}
+
+	client.transitionLock.Lock()
+	for i := range backup.transitions {
+		t := backup.transitions[i]
+		client.transitions[t.DxPath.Path] = &t
+	}
+	client.transitionLock.Unlock()
+	return client.saveTransitions()
+}
+
+// removeRestored removes the files and directories restored from the backup, and the
+// contracts if they have been restored
+func (client *StorageClient) removeRestored(backup clientBackup, contracts bool) {
+	if contracts {
+		client.contractManager.RemoveRestoredContracts(backup.contracts)
+	}
+	if err := client.fileSystem.RemoveRestored(backup.fileSystem); err != nil {
+		client.log.Warn("failed to remove the restored files", "err", err)
+	}
+}
+
+// backupCipherKey derives the cipher key used to encrypt the backup archive
+func backupCipherKey(passphrase string, file backupFile) (crypto.CipherKey, error) {
+	key, err := scrypt.Key([]byte(passphrase), file.Salt, file.ScryptN, file.ScryptR, file.ScryptP, backupKeyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the backup key: %s", err.Error())
+	}
+	return crypto.NewCipherKey(crypto.GCMCipherCode, key)
+}
+
+// encodeBackupArchive writes the backup into a gzipped tar archive. The DxFiles are
+// written as separate entries under the files directory
+func encodeBackupArchive(backup clientBackup) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	entries := []struct {
+		name string
+		val  interface{}
+	}{
+		{backupProfileEntry, backup.profile},
+		{backupTransitionsEntry, backup.transitions},
+		{backupContractsEntry, backup.contracts},
+		{backupDirsEntry, backup.fileSystem.Dirs},
+	}
+	for _, entry := range entries {
+		blob, err := json.Marshal(entry.val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %s", entry.name, err.Error())
+		}
+		if err = writeTarEntry(tw, entry.name, blob); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeTarEntry(tw, backupHostManagerEntry, backup.hostManager); err != nil {
+		return nil, err
+	}
+	for _, file := range backup.fileSystem.Files {
+		name := backupFilesDir + file.DxPath.Path + storage.DxFileExt
+		if err := writeTarEntry(tw, name, file.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeBackupArchive reads the backup from the gzipped tar archive
+func decodeBackupArchive(archive []byte) (backup clientBackup, err error) {
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	var dirs []dxdir.Metadata
+	for {
+		var header *tar.Header
+		header, err = tr.Next()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			return
+		}
+		var blob []byte
+		if blob, err = ioutil.ReadAll(tr); err != nil {
+			return
+		}
+
+		switch {
+		case header.Name == backupProfileEntry:
+			err = json.Unmarshal(blob, &backup.profile)
+		case header.Name == backupTransitionsEntry:
+			err = json.Unmarshal(blob, &backup.transitions)
+		case header.Name == backupContractsEntry:
+			err = json.Unmarshal(blob, &backup.contracts)
+		case header.Name == backupDirsEntry:
+			err = json.Unmarshal(blob, &dirs)
+		case header.Name == backupHostManagerEntry:
+			backup.hostManager = blob
+		case strings.HasPrefix(header.Name, backupFilesDir) && path.Ext(header.Name) == storage.DxFileExt:
+			var dxPath storage.DxPath
+			name := strings.TrimSuffix(strings.TrimPrefix(header.Name, backupFilesDir), storage.DxFileExt)
+			if dxPath, err = storage.NewDxPath(name); err == nil {
+				backup.fileSystem.Files = append(backup.fileSystem.Files, filesystem.FileBackup{DxPath: dxPath, Content: blob})
+			}
+		default:
+			err = fmt.Errorf("unknown entry %s", header.Name)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to decode the backup entry %s: %s", header.Name, err.Error())
+			return
+		}
+	}
+	backup.fileSystem.Dirs = dirs
+
+	if backup.hostManager == nil {
+		err = errors.New("storage host manager state not found in the backup")
+	}
+	return
+}
+
+// writeTarEntry writes a regular file entry with the data to the tar archive
+func writeTarEntry(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractmanager"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxdir"
+)
+
+func TestBackupArchive(t *testing.T) {
+	backup := clientBackup{
+		profile: ClientProfile{Version: ProfileVersion, FilterMode: "disable"},
+		transitions: []StorageClassTransition{
+			{DxPath: storage.DxPath{Path: "a/b"}, TargetPath: storage.DxPath{Path: "a/b" + storage.TransitionFileSuffix}, Class: "archive"},
+		},
+		contracts: []contractmanager.ContractBackup{
+			{Header: contractset.ContractHeader{ID: storage.ContractID{1}, StartHeight: 10}},
+		},
+		hostManager: []byte(`{"BlockHeight":1}`),
+		fileSystem: filesystem.Backup{
+			Dirs: []dxdir.Metadata{{DxPath: storage.RootDxPath(), NumFiles: 1}},
+			Files: []filesystem.FileBackup{
+				{DxPath: storage.DxPath{Path: "a/b"}, Content: []byte("dxfile content")},
+			},
+		},
+	}
+	archive, err := encodeBackupArchive(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeBackupArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.profile.Version != ProfileVersion || decoded.profile.FilterMode != "disable" {
+		t.Errorf("unexpected profile %+v", decoded.profile)
+	}
+	if len(decoded.transitions) != 1 || decoded.transitions[0].Class != "archive" {
+		t.Errorf("unexpected transitions %+v", decoded.transitions)
+	}
+	if len(decoded.contracts) != 1 || decoded.contracts[0].Header.ID != backup.contracts[0].Header.ID {
+		t.Errorf("unexpected contracts %+v", decoded.contracts)
+	}
+	if !bytes.Equal(decoded.hostManager, backup.hostManager) {
+		t.Errorf("unexpected host manager state %s", decoded.hostManager)
+	}
+	if len(decoded.fileSystem.Dirs) != 1 || decoded.fileSystem.Dirs[0].NumFiles != 1 {
+		t.Errorf("unexpected dirs %+v", decoded.fileSystem.Dirs)
+	}
+	if len(decoded.fileSystem.Files) != 1 || !decoded.fileSystem.Files[0].DxPath.Equals(backup.fileSystem.Files[0].DxPath) ||
+		!bytes.Equal(decoded.fileSystem.Files[0].Content, backup.fileSystem.Files[0].Content) {
+		t.Errorf("unexpected files %+v", decoded.fileSystem.Files)
+	}
+}
+
+func TestBackupCipherKey(t *testing.T) {
+	file := backupFile{Salt: []byte("salt"), ScryptN: 1 << 4, ScryptR: backupScryptR, ScryptP: backupScryptP}
+	ck, err := backupCipherKey("passphrase", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipherText, err := ck.Encrypt([]byte("archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong, err := backupCipherKey("wrong passphrase", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wrong.Decrypt(cipherText); err == nil {
+		t.Error("decrypt with the wrong passphrase should fail")
+	}
+	plainText, err := ck.Decrypt(cipherText)
+	if err != nil || string(plainText) != "archive" {
+		t.Errorf("decrypt failed: %v", err)
+	}
+}
@@ -120,6 +120,15 @@ func parseClientSetting(settings map[string]string, prevSetting storage.ClientSe
 			}
 			clientSetting.MaxDownloadSpeed = downloadSpeed
 
+		case key == "overdrive":
+			var overdrive uint64
+			overdrive, err = unit.ParseUint64(value, 1, "")
+			if err != nil {
+				err = fmt.Errorf("failed to parse the download overdrive: %s", err.Error())
+				break
+			}
+			clientSetting.DownloadOverdrive = int(overdrive)
+
 		default:
 			err = fmt.Errorf("the key entered: %s is not valid. Here is a list of available keys: %+v",
 				key, keys)
@@ -200,6 +200,10 @@ func randomValue(selectedKeys map[string]string) (settings map[string]string, er
 			value = rand.Int63()
 			granularity = unit.SpeedUnit[rand.Intn(len(unit.SpeedUnit))]
 			break
+		case key == "overdrive":
+			value = rand.Intn(10)
+			granularity = ""
+			break
 		default:
 			err = fmt.Errorf("the key received is not valid: %s", key)
 			return
@@ -245,6 +249,9 @@ func clientSettingValidation(key string, prevSetting storage.ClientSetting, curr
 	case "downloadspeed":
 		valid = currentSetting.MaxDownloadSpeed == prevSetting.MaxDownloadSpeed
 		return
+	case "overdrive":
+		valid = currentSetting.DownloadOverdrive == prevSetting.DownloadOverdrive
+		return
 	default:
 		err = fmt.Errorf("the provided key is invalid: %s", key)
 		return
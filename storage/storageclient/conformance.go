@@ -0,0 +1,260 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/p2p"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+type (
+	// ConformanceReport is the compliance report of a storage host against the scripted
+	// protocol scenarios. The protocol behaviors not covered by the scenarios are listed
+	// in NotCovered, so that passing the scenarios is not taken as a full certification
+	ConformanceReport struct {
+		EnodeURL   string                 `json:"enodeurl"`
+		Start      time.Time              `json:"start"`
+		Duration   time.Duration          `json:"duration"`
+		Passed     int                    `json:"passed"`
+		Failed     int                    `json:"failed"`
+		Results    []ScenarioResult       `json:"results"`
+		NotCovered []string               `json:"notcovered"`
+		Config     *storage.HostExtConfig `json:"config,omitempty"`
+	}
+
+	// ScenarioResult is the result of a single conformance scenario
+	ScenarioResult struct {
+		Name        string        `json:"name"`
+		Description string        `json:"description"`
+		Passed      bool          `json:"passed"`
+		Error       string        `json:"error,omitempty"`
+		Duration    time.Duration `json:"duration"`
+	}
+
+	// conformanceScenario is a scripted protocol exchange with the storage host. The run
+	// returns error if the host does not behave as the protocol requires
+	conformanceScenario struct {
+		name        string
+		description string
+		run         func(sp storage.Peer, report *ConformanceReport) error
+	}
+)
+
+// conformanceScenarios is the battery of the protocol scenarios run against the storage host.
+// The scenarios neither sign contracts nor revisions with the host, thus only the config
+// exchange and the rejection of the invalid requests are checked. The invalid requests are
+// the well encoded ones the host rejects as negotiation errors, so that the host does not
+// score the client as abusive for running the scenarios
+var conformanceScenarios = []conformanceScenario{
+	{
+		name:        "hostconfig",
+		description: "the host responds to the config request with a valid config",
+		run:         checkHostConfig,
+	},
+	{
+		name:        "repeatedhostconfig",
+		description: "the host keeps responding to the consecutive config requests",
+		run:         checkRepeatedHostConfig,
+	},
+	{
+		name:        "uploadunknowncontract",
+		description: "the host rejects the upload request of a contract it does not have",
+		run:         checkUploadUnknownContract,
+	},
+	{
+		name:        "downloadunknowncontract",
+		description: "the host rejects the download request of a contract it does not have",
+		run:         checkDownloadUnknownContract,
+	},
+	{
+		name:        "uploadunknownaction",
+		description: "the host rejects the upload request with an unknown action type",
+		run:         checkUploadUnknownAction,
+	},
+}
+
+// conformanceNotCovered is the protocol behaviors not checked by the conformance scenarios.
+// They require paying the host with a contract, sending the messages the host scores as
+// abuse, or waiting for the negotiation timeouts of the host
+var conformanceNotCovered = []string{
+	"contract create and renew",
+	"upload and download with a contract",
+	"malformed message encodings",
+	"negotiation timeouts",
+}
+
+// RunConformance connects to the storage host with the enode url, and runs the protocol
+// conformance scenarios against it. The host failing a scenario is reported in the
+// returned report, and the error is only returned if the host could not be reached. The
+// scenarios do not spend any fund, and the behaviors not covered are listed in the report
+func (client *StorageClient) RunConformance(enodeURL string) (report ConformanceReport, err error) {
+	if err = client.tm.Add(); err != nil {
+		return
+	}
+	defer client.tm.Done()
+
+	sp, err := client.SetupConnection(enodeURL)
+	if err != nil {
+		return report, fmt.Errorf("failed to connect to the storage host: %s", err.Error())
+	}
+	defer client.CheckAndUpdateConnection(sp.PeerNode())
+
+	report = runConformance(sp, conformanceScenarios)
+	report.EnodeURL = enodeURL
+	return report, nil
+}
+
+// runConformance runs the scenarios in order with the storage peer
+func runConformance(sp storage.Peer, scenarios []conformanceScenario) (report ConformanceReport) {
+	report.Start = time.Now()
+	report.NotCovered = conformanceNotCovered
+	for _, scenario := range scenarios {
+		start := time.Now()
+		err := scenario.run(sp, &report)
+		result := ScenarioResult{
+			Name:        scenario.name,
+			Description: scenario.description,
+			Duration:    time.Since(start),
+		}
+		if err == nil && result.Duration > conformanceResponseTimeout {
+			err = fmt.Errorf("the host took %v to respond, longer than %v", result.Duration, conformanceResponseTimeout)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed++
+		} else {
+			result.Passed = true
+			report.Passed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Duration = time.Since(report.Start)
+	return
+}
+
+// checkHostConfig requests the host config, and validates the config received
+func checkHostConfig(sp storage.Peer, report *ConformanceReport) error {
+	config, err := requestHostConfig(sp)
+	if err != nil {
+		return err
+	}
+	report.Config = &config
+
+	switch {
+	case config.SectorSize != storage.SectorSize:
+		return fmt.Errorf("sector size %v does not match the protocol sector size %v", config.SectorSize, storage.SectorSize)
+	case config.MaxReviseBatchSize == 0:
+		return errors.New("max revise batch size is 0")
+	case config.MaxDownloadBatchSize == 0:
+		return errors.New("max download batch size is 0")
+	case config.RemainingStorage > config.TotalStorage:
+		return fmt.Errorf("remaining storage %v is larger than the total storage %v", config.RemainingStorage, config.TotalStorage)
+	case config.AcceptingContracts && config.MaxDuration == 0:
+		return errors.New("the host accepts contracts with the max duration 0")
+	case config.AcceptingContracts && config.WindowSize == 0:
+		return errors.New("the host accepts contracts with the window size 0")
+	case config.AcceptingContracts && config.PaymentAddress == common.Address{}:
+		return errors.New("the host accepts contracts without the payment address")
+	}
+	return nil
+}
+
+// checkRepeatedHostConfig requests the host config several times in a row
+func checkRepeatedHostConfig(sp storage.Peer, report *ConformanceReport) error {
+	for i := 0; i < conformanceConfigRequests; i++ {
+		if _, err := requestHostConfig(sp); err != nil {
+			return fmt.Errorf("config request %v: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+// checkUploadUnknownContract sends the upload request of a contract not signed with the host.
+// The empty contract id is never the id of a contract
+func checkUploadUnknownContract(sp storage.Peer, report *ConformanceReport) error {
+	return expectNegotiateError(sp, func() error {
+		return sp.RequestContractUpload(storage.UploadRequest{
+			StorageContractID: common.Hash{},
+		})
+	})
+}
+
+// checkDownloadUnknownContract sends the download request of a contract not signed with the host
+func checkDownloadUnknownContract(sp storage.Peer, report *ConformanceReport) error {
+	return expectNegotiateError(sp, func() error {
+		return sp.RequestContractDownload(storage.DownloadRequest{
+			StorageContractID: common.Hash{},
+			Sector: storage.DownloadRequestSector{
+				Length: uint32(storage.SectorSize),
+			},
+		})
+	})
+}
+
+// checkUploadUnknownAction sends the upload request with an action type not defined by the protocol
+func checkUploadUnknownAction(sp storage.Peer, report *ConformanceReport) error {
+	return expectNegotiateError(sp, func() error {
+		return sp.RequestContractUpload(storage.UploadRequest{
+			StorageContractID: common.Hash{},
+			Actions:           []storage.UploadAction{{Type: "Conformance"}},
+		})
+	})
+}
+
+// requestHostConfig requests and decodes the host config
+func requestHostConfig(sp storage.Peer) (config storage.HostExtConfig, err error) {
+	if err = sp.TryRequestHostConfig(); err != nil {
+		return
+	}
+	defer sp.RequestHostConfigDone()
+
+	if err = sp.RequestStorageHostConfig(); err != nil {
+		return config, fmt.Errorf("failed to request the host config: %s", err.Error())
+	}
+	msg, err := sp.WaitConfigResp()
+	if err != nil {
+		return config, fmt.Errorf("failed to wait for the host config: %s", err.Error())
+	}
+	if err = msg.Decode(&config); err != nil {
+		return config, fmt.Errorf("failed to decode the host config: %s", err.Error())
+	}
+	return
+}
+
+// expectNegotiateError sends the request, and expects the host to respond with the
+// negotiation error
+func expectNegotiateError(sp storage.Peer, request func() error) error {
+	if !sp.TryToRenewOrRevise() {
+		return errors.New("the host is in negotiation with the client")
+	}
+	defer sp.RevisionOrRenewingDone()
+
+	if err := request(); err != nil {
+		return fmt.Errorf("failed to send the request: %s", err.Error())
+	}
+	msg, err := sp.ClientWaitContractResp()
+	if err != nil {
+		return fmt.Errorf("failed to wait for the host response: %s", err.Error())
+	}
+	return checkResponseCode(msg, storage.HostNegotiateErrorMsg)
+}
+
+// checkResponseCode checks the code of the message responded by the host
+func checkResponseCode(msg p2p.Msg, expect uint64) error {
+	defer msg.Discard()
+	switch msg.Code {
+	case expect:
+		return nil
+	case storage.HostBusyHandleReqMsg:
+		return storage.ErrHostBusyHandleReq
+	default:
+		return fmt.Errorf("expect response message code %#x, got %#x", expect, msg.Code)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/p2p"
+	"github.com/DxChainNetwork/godx/rlp"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// conformancePeer is the storage peer responding with the config, and the contract
+// response of the code given
+type conformancePeer struct {
+	storage.Peer
+	config       storage.HostExtConfig
+	contractResp uint64
+}
+
+func (p *conformancePeer) TryRequestHostConfig() error     { return nil }
+func (p *conformancePeer) RequestHostConfigDone()          {}
+func (p *conformancePeer) RequestStorageHostConfig() error { return nil }
+func (p *conformancePeer) TryToRenewOrRevise() bool        { return true }
+func (p *conformancePeer) RevisionOrRenewingDone()         {}
+
+func (p *conformancePeer) RequestContractUpload(req storage.UploadRequest) error     { return nil }
+func (p *conformancePeer) RequestContractDownload(req storage.DownloadRequest) error { return nil }
+
+func (p *conformancePeer) WaitConfigResp() (p2p.Msg, error) {
+	data, err := rlp.EncodeToBytes(p.config)
+	if err != nil {
+		return p2p.Msg{}, err
+	}
+	return p2p.Msg{Code: storage.HostConfigRespMsg, Size: uint32(len(data)), Payload: bytes.NewReader(data)}, nil
+}
+
+func (p *conformancePeer) ClientWaitContractResp() (p2p.Msg, error) {
+	return p2p.Msg{Code: p.contractResp, Payload: bytes.NewReader(nil)}, nil
+}
+
+func TestRunConformance(t *testing.T) {
+	sp := &conformancePeer{
+		config: storage.HostExtConfig{
+			AcceptingContracts:   true,
+			MaxDownloadBatchSize: 1 << 20,
+			MaxDuration:          1000,
+			MaxReviseBatchSize:   1 << 20,
+			PaymentAddress:       common.Address{1},
+			SectorSize:           storage.SectorSize,
+			TotalStorage:         1 << 30,
+			WindowSize:           100,
+		},
+		contractResp: storage.HostNegotiateErrorMsg,
+	}
+	report := runConformance(sp, conformanceScenarios)
+	if report.Failed != 0 || report.Passed != len(conformanceScenarios) {
+		t.Fatalf("the conforming host failed the scenarios: %+v", report.Results)
+	}
+	if report.Config == nil || report.Config.WindowSize != sp.config.WindowSize {
+		t.Fatalf("the host config is not reported")
+	}
+	if len(report.NotCovered) != len(conformanceNotCovered) {
+		t.Errorf("the behaviors not covered are not reported: %v", report.NotCovered)
+	}
+
+	// the host accepting contracts without the payment address, and acknowledging the
+	// malformed requests instead of rejecting them
+	sp.config.PaymentAddress = common.Address{}
+	sp.contractResp = storage.HostAckMsg
+	report = runConformance(sp, conformanceScenarios)
+	failed := make(map[string]bool)
+	for _, result := range report.Results {
+		if !result.Passed {
+			failed[result.Name] = true
+		}
+	}
+	expect := []string{"hostconfig", "uploadunknowncontract", "downloadunknowncontract", "uploadunknownaction"}
+	if report.Failed != len(expect) {
+		t.Fatalf("expect %v failed scenarios, got %+v", len(expect), report.Results)
+	}
+	for _, name := range expect {
+		if !failed[name] {
+			t.Errorf("scenario %v is expected to fail", name)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package contractmanager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+)
+
+// errContractsExist is returned when restoring contracts to a contract manager that
+// already has active contracts
+var errContractsExist = errors.New("the contract manager already has active contracts")
+
+// ContractBackup is the backup of an active contract, including the contract header
+// and the merkle roots of the sectors stored in the contract
+type ContractBackup struct {
+	Header contractset.ContractHeader
+	Roots  []common.Hash
+}
+
+// BackupContracts acquires all active contracts in the order of the contract id, and calls
+// fn with the backup of the contracts. As no revision can be committed while a contract is
+// acquired, the contracts are only returned after fn returns, allowing fn to capture the
+// state of other modules at the same point
+func (cm *ContractManager) BackupContracts(fn func(backups []ContractBackup) error) error {
+	ids := cm.activeContracts.IDs()
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+
+	var acquired []*contractset.Contract
+	defer func() {
+		for _, c := range acquired {
+			if err := cm.activeContracts.Return(c); err != nil {
+				cm.log.Warn("failed to return the contract after backup", "err", err.Error())
+			}
+		}
+	}()
+
+	backups := make([]ContractBackup, 0, len(ids))
+	for _, id := range ids {
+		c, exists := cm.activeContracts.Acquire(id)
+		if !exists {
+			// the contract has been deleted after the ids are retrieved
+			continue
+		}
+		acquired = append(acquired, c)
+
+		roots, err := c.MerkleRoots()
+		if err != nil {
+			return fmt.Errorf("failed to get the merkle roots of contract %v: %s", id, err.Error())
+		}
+		backups = append(backups, ContractBackup{
+			Header: c.Header(),
+			Roots:  roots,
+		})
+	}
+
+	return fn(backups)
+}
+
+// ValidateContractBackups checks the contract headers in the backups, and that each
+// contract is included once
+func ValidateContractBackups(backups []ContractBackup) error {
+	ids := make(map[storage.ContractID]struct{})
+	for _, backup := range backups {
+		if err := backup.Header.Validate(); err != nil {
+			return fmt.Errorf("invalid contract %v in the backup: %s", backup.Header.ID, err.Error())
+		}
+		if _, exist := ids[backup.Header.ID]; exist {
+			return fmt.Errorf("duplicate contract %v in the backup", backup.Header.ID)
+		}
+		ids[backup.Header.ID] = struct{}{}
+	}
+	return nil
+}
+
+// RestoreContracts inserts the backup contracts into the contract set, and updates the
+// host to contract mapping. It can only be used when there are no active contracts. The
+// backups are validated before any contract is inserted, and the inserted contracts are
+// removed if any of the contracts cannot be inserted
+func (cm *ContractManager) RestoreContracts(backups []ContractBackup) error {
+	if len(cm.activeContracts.IDs()) != 0 {
+		return errContractsExist
+	}
+	if err := ValidateContractBackups(backups); err != nil {
+		return err
+	}
+
+	// insert the contracts in the order of the start height, so that the host is mapped
+	// to the newest contract if the contract has been renewed
+	sorted := make([]ContractBackup, len(backups))
+	copy(sorted, backups)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Header.StartHeight < sorted[j].Header.StartHeight
+	})
+
+	for i, backup := range sorted {
+		meta, err := cm.activeContracts.InsertContract(backup.Header, backup.Roots)
+		if err != nil {
+			cm.RemoveRestoredContracts(sorted[:i])
+			return fmt.Errorf("failed to restore contract %v: %s", backup.Header.ID, err.Error())
+		}
+		cm.updateHostToContractID(meta)
+	}
+	return nil
+}
+
+// RemoveRestoredContracts removes the contracts restored from the backups, along with their
+// host to contract mapping, so that the contracts could be restored again
+func (cm *ContractManager) RemoveRestoredContracts(backups []ContractBackup) {
+	ids := make([]storage.ContractID, 0, len(backups))
+	for _, backup := range backups {
+		ids = append(ids, backup.Header.ID)
+	}
+	cm.delFromContractSet(ids)
+
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	for _, backup := range backups {
+		if cm.hostToContract[backup.Header.EnodeID] == backup.Header.ID {
+			delete(cm.hostToContract, backup.Header.EnodeID)
+		}
+	}
+}
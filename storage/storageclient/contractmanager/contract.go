@@ -9,6 +9,7 @@ import (
 	"sort"
 
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
 	"github.com/DxChainNetwork/godx/p2p/enode"
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
@@ -145,7 +146,7 @@ func (cm *ContractManager) maintainExpiration() {
 
 	// save the updated data information
 	if err := cm.saveSettings(); err != nil {
-		cm.log.Error("failed to save the expired contracts updates while checking the expired contract", "err", err.Error())
+		alert.Raise(cm.log, alert.SeverityError, alertModule, "failed to save the expired contracts updates while checking the expired contract", "err", err.Error())
 	}
 
 	// delete the expired contract from the contract set
@@ -231,7 +232,7 @@ func (cm *ContractManager) removeDuplications() {
 
 	// save the update data information
 	if err := cm.saveSettings(); err != nil {
-		cm.log.Error("failed to save the expired contracts updates persistently", "err", err.Error())
+		alert.Raise(cm.log, alert.SeverityError, alertModule, "failed to save the expired contracts updates persistently", "err", err.Error())
 	}
 
 	// delete the duplicated contracts from the contract set
@@ -462,8 +463,8 @@ func (cm *ContractManager) calculateMinEvaluation(hosts []storage.HostInfo) (min
 
 // checkContractStatus will validate and return the new contract status based on the following criteria
 // 		1. if the status of the contract is not canceled, then mark the upload and renew ability to be true
-// 		2. if the host that the client signed the contract with cannot be found or the host has been filtered, mark
-//		upload and renew ability to be false
+// 		2. if the host that the client signed the contract with cannot be found, the host has been filtered, or
+//		blacklisted for charging more than advertised, mark upload and renew ability to be false
 // 		3. if the host's evaluation is smaller than the baseline, then mark the current contract as not good
 // 		for uploading and renewing
 // 		4. if the storage host that signed contract with is offline, mark the current contract as
@@ -481,7 +482,7 @@ func (cm *ContractManager) checkContractStatus(contract storage.ContractMetaData
 
 	// check if the host that signed the contract with is valid
 	host, exists := cm.hostManager.RetrieveHostInfo(contract.EnodeID)
-	if !exists || host.Filtered {
+	if !exists || host.Filtered || cm.hostManager.PriceBlacklisted(contract.EnodeID) {
 		stats.UploadAbility = false
 		stats.RenewAbility = false
 		return
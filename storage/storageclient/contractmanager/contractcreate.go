@@ -137,6 +137,10 @@ func (cm *ContractManager) createContract(host storage.HostInfo, contractFund co
 		return
 	}
 
+	// record the prices the contract was negotiated with, which the later revisions are audited against
+	cm.hostManager.RecordAdvertisedPrices(newlyCreatedContract.EnodeID, host.HostExtConfig)
+	cm.recordContractFormed(host.ContractPrice)
+
 	// 4. update the contract manager fields
 	cm.lock.Lock()
 	// check if the storage client have created another contract with the same storage host
@@ -257,6 +261,7 @@ func (cm *ContractManager) ContractCreate(params storage.ContractParams) (md sto
 		if err == nil {
 			cm.hostManager.IncrementSuccessfulInteractions(host.EnodeID)
 		}
+		cm.hostManager.RecordHostQoS(host.EnodeID, sp.TakeQoSSample())
 	}()
 
 	//Sign the hash of the storage contract
@@ -9,6 +9,8 @@ import (
 	"os"
 	"sync"
 
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/timeseries"
 	"github.com/DxChainNetwork/godx/log"
 	"github.com/DxChainNetwork/godx/p2p/enode"
 	"github.com/DxChainNetwork/godx/storage"
@@ -56,6 +58,20 @@ type ContractManager struct {
 	// storage client period cost
 	periodCost storage.PeriodCost
 
+	// samples of the storage used and the fund spent by the active contracts
+	utilization map[storage.ContractID][]UtilizationSample
+
+	// nextStaleCheck is the block height the active contracts are next checked against the
+	// contracts on chain, and staleContractFound signals the contracts are found stale
+	nextStaleCheck     uint64
+	staleContractFound chan struct{}
+
+	// metrics is the hourly metrics of the storage client, which records the contracts formed
+	metrics *timeseries.Store
+
+	// persistLock serializes the settings saved to the disk
+	persistLock sync.Mutex
+
 	// utils
 	log  log.Logger
 	lock sync.RWMutex
@@ -63,26 +79,29 @@ type ContractManager struct {
 	quit chan struct{}
 }
 
-// New will initialize the ContractManager object, which is used for contract maintenance
-func New(persistDir string, hm *storagehostmanager.StorageHostManager) (cm *ContractManager, err error) {
+// New will initialize the ContractManager object, which is used for contract maintenance.
+// The passphrase is used to encrypt the contracts at rest, empty passphrase means no encryption
+func New(persistDir string, hm *storagehostmanager.StorageHostManager, passphrase string) (cm *ContractManager, err error) {
 	// contract manager initialization
 	cm = &ContractManager{
-		persistDir:       persistDir,
-		hostManager:      hm,
-		maintenanceStop:  make(chan struct{}),
-		expiredContracts: make(map[storage.ContractID]storage.ContractMetaData),
-		renewedFrom:      make(map[storage.ContractID]storage.ContractID),
-		renewedTo:        make(map[storage.ContractID]storage.ContractID),
-		failedRenewCount: make(map[storage.ContractID]uint64),
-		hostToContract:   make(map[enode.ID]storage.ContractID),
-		quit:             make(chan struct{}),
+		persistDir:         persistDir,
+		hostManager:        hm,
+		maintenanceStop:    make(chan struct{}),
+		expiredContracts:   make(map[storage.ContractID]storage.ContractMetaData),
+		renewedFrom:        make(map[storage.ContractID]storage.ContractID),
+		renewedTo:          make(map[storage.ContractID]storage.ContractID),
+		failedRenewCount:   make(map[storage.ContractID]uint64),
+		hostToContract:     make(map[enode.ID]storage.ContractID),
+		utilization:        make(map[storage.ContractID][]UtilizationSample),
+		staleContractFound: make(chan struct{}, 1),
+		quit:               make(chan struct{}),
 	}
 
 	// initialize log
 	cm.log = log.New("module", "contract manager")
 
 	// initialize contract set
-	cs, err := contractset.New(persistDir)
+	cs, err := contractset.New(persistDir, passphrase)
 	if err != nil {
 		err = fmt.Errorf("error initialize contract set: %s", err.Error())
 		return
@@ -108,6 +127,14 @@ func (cm *ContractManager) Start(b storage.ClientBackend) (err error) {
 		return
 	}
 
+	// catch up with the blocks applied while the client was offline
+	cm.lock.Lock()
+	cm.syncBlockHeight()
+	cm.lock.Unlock()
+
+	// recover the settings not saved with the contract changes before a crash
+	cm.reconcileSettings()
+
 	// subscribe block chain change event
 	go cm.subscribeChainChangeEvent()
 
@@ -126,6 +153,21 @@ func (cm *ContractManager) Start(b storage.ClientBackend) (err error) {
 	return
 }
 
+// SetMetrics sets the store to record the contracts formed and renewed, and the contract fees
+func (cm *ContractManager) SetMetrics(metrics *timeseries.Store) {
+	cm.metrics = metrics
+}
+
+// recordContractFormed records the contract formed or renewed with the contract price of the host
+func (cm *ContractManager) recordContractFormed(contractPrice common.BigInt) {
+	if err := cm.metrics.Add(storage.MetricContracts, common.BigInt1); err != nil {
+		cm.log.Warn("failed to record the contracts metric", "err", err)
+	}
+	if err := cm.metrics.Add(storage.MetricSpend, contractPrice); err != nil {
+		cm.log.Warn("failed to record the spend metric", "err", err)
+	}
+}
+
 // Stop will send stop signal to threadManager, terminate all
 // running go routines
 func (cm *ContractManager) Stop() {
@@ -166,6 +208,16 @@ func (cm *ContractManager) RetrieveActiveContracts() (cms []storage.ContractMeta
 	return cm.activeContracts.RetrieveAllContractsMetaData()
 }
 
+// RetrieveExpiredContracts will be used to retrieve all the expired contracts
+func (cm *ContractManager) RetrieveExpiredContracts() (cms []storage.ContractMetaData) {
+	cm.lock.RLock()
+	defer cm.lock.RUnlock()
+	for _, contract := range cm.expiredContracts {
+		cms = append(cms, contract)
+	}
+	return
+}
+
 // RetrieveActiveContract will return the contract meta data based on the contract id provided
 func (cm *ContractManager) RetrieveActiveContract(contractID storage.ContractID) (contract storage.ContractMetaData, exists bool) {
 	return cm.activeContracts.RetrieveContractMetaData(contractID)
@@ -87,7 +87,7 @@ func newContractManagerTest(hm *storagehostmanager.StorageHostManager) (cm *Cont
 		quit:             make(chan struct{}),
 		log:              log.New(),
 	}
-	cs, err := contractset.New("test")
+	cs, err := contractset.New("test", "")
 	if err != nil {
 		err = fmt.Errorf("failed to create contract set: %s", err.Error())
 		return
@@ -112,7 +112,11 @@ func newStorageHostManagerTest() (shm *storagehostmanager.StorageHostManager, er
 	return
 }
 
-type storageClientBackendContractManager struct{}
+// storageClientBackendContractManager is the client backend used for the contract manager tests,
+// with the revision numbers of the contracts on chain
+type storageClientBackendContractManager struct {
+	revisions map[common.Hash]uint64
+}
 
 func (st *storageClientBackendContractManager) Online() bool {
 	return true
@@ -191,3 +195,8 @@ func (st *storageClientBackendContractManager) TryToRenewOrRevise(hostID enode.I
 }
 
 func (st *storageClientBackendContractManager) RevisionOrRenewingDone(hostID enode.ID) {}
+
+func (st *storageClientBackendContractManager) GetStorageContractRevisionNumber(contractID common.Hash) (uint64, bool, error) {
+	revisionNumber, exists := st.revisions[contractID]
+	return revisionNumber, exists, nil
+}
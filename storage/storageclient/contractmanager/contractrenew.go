@@ -11,6 +11,7 @@ import (
 
 	"github.com/DxChainNetwork/godx/accounts"
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
 	"github.com/DxChainNetwork/godx/common/math"
 	"github.com/DxChainNetwork/godx/core/types"
 	"github.com/DxChainNetwork/godx/crypto"
@@ -130,7 +131,7 @@ func (cm *ContractManager) prepareContractRenew(renewRecords []contractRenewReco
 		// renew the contract, get the spending for the renew
 		renewCost, err := cm.contractRenewStart(record, currentPeriod, rentPayment, contractEndHeight)
 		if err != nil {
-			cm.log.Error("contract renew failed", "contractID", record.id, "err", err.Error())
+			alert.Raise(cm.log, alert.SeverityError, alertModule, "contract renew failed", "contractID", record.id, "err", err.Error())
 		}
 
 		// update the remaining fund
@@ -325,6 +326,10 @@ func (cm *ContractManager) renew(renewContract *contractset.Contract, rentPaymen
 		return
 	}
 
+	// the revisions of the renewed contract are audited against the prices renewed with
+	cm.hostManager.RecordAdvertisedPrices(renewedContract.EnodeID, host.HostExtConfig)
+	cm.recordContractFormed(host.ContractPrice)
+
 	// 5. update the storage host to contract id mapping
 	cm.lock.Lock()
 	cm.hostToContract[renewedContract.EnodeID] = renewedContract.ID
@@ -344,6 +349,10 @@ func (cm *ContractManager) handleRenewFailed(failedContract *contractset.Contrac
 		cm.lock.Lock()
 		cm.failedRenewCount[failedContract.Metadata().ID]++
 		cm.lock.Unlock()
+
+		if err := cm.saveSettings(); err != nil {
+			cm.log.Warn("failed to save the failed renew count", "err", err.Error())
+		}
 	}
 
 	// get the number of failed renews, to check if the contract needs to be replaced
@@ -463,6 +472,7 @@ func (cm *ContractManager) ContractRenew(oldContract *contractset.Contract, para
 		if err == nil {
 			cm.hostManager.IncrementSuccessfulInteractions(contract.EnodeID)
 		}
+		cm.hostManager.RecordHostQoS(contract.EnodeID, sp.TakeQoSSample())
 	}()
 
 	clientContractSign, err := wallet.SignHash(account, storageContract.RLPHash().Bytes())
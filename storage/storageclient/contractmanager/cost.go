@@ -18,45 +18,16 @@ import (
 //      SUMMARY: totalStorageCost + upload cost within current period + download cost within the current period + storage cost within the current period
 //		+ contract cost specified by the storage host -> raise 33%
 func (cm *ContractManager) renewCostEstimation(host storage.HostInfo, contract storage.ContractMetaData, blockHeight uint64, rent storage.RentPayment) (estimation common.BigInt) {
+	// price increases announced by the host within the renewed period are taken into account
+	upcoming := host.UpcomingPrices(blockHeight + rent.Period)
+
 	// get the cost for current storage
 	amountDataStored := contract.LatestContractRevision.NewFileSize
-	storageCost := host.StoragePrice.MultUint64(rent.Period).MultUint64(amountDataStored)
+	storageCost := upcoming.StoragePrice.MultUint64(rent.Period).MultUint64(amountDataStored)
 
 	// add all upload and download cost regarding to this contract
 	// NOTE: only within the current period
-	currentID := contract.ID
-	prevContractTotalUploadCost := contract.UploadCost
-	prevContractTotalDownloadCost := contract.DownloadCost
-
-	cm.lock.RLock()
-	// prevent loop from running forever
-	for i := 0; i < 10e5; i++ {
-		// get the previous contractID
-		prevContractID, exists := cm.renewedFrom[currentID]
-		if !exists {
-			break
-		}
-
-		// get the previous contract information
-		prevContract, exists := cm.expiredContracts[prevContractID]
-		if !exists {
-			break
-		}
-
-		// verify the start height to check if the start height is within the current period
-		// current period will be changed in two places:
-		// 		1. SetRentPayment
-		//		2. ChainChanges
-		if prevContract.StartHeight < cm.currentPeriod {
-			break
-		}
-
-		// update the upload and download cost. Note: this cost is spent within the current period
-		prevContractTotalUploadCost = prevContractTotalUploadCost.Add(prevContract.UploadCost)
-		prevContractTotalDownloadCost = prevContractTotalDownloadCost.Add(prevContract.DownloadCost)
-		currentID = prevContractID
-	}
-	cm.lock.RUnlock()
+	prevContractTotalUploadCost, prevContractTotalDownloadCost := cm.currentPeriodSpending(contract)
 
 	// amount of data uploaded = total amount of data stored in the contract / uploadBandwidthPrice
 	prevDataUploaded := common.NewBigIntUint64(amountDataStored)
@@ -72,10 +43,10 @@ func (cm *ContractManager) renewCostEstimation(host storage.HostInfo, contract s
 	}
 
 	// upload cost + the storage cost for the newly uploaded data
-	newUploadCost := prevContractTotalUploadCost.Add(prevDataUploaded.MultUint64(rent.Period).Mult(host.StoragePrice))
+	newUploadCost := prevContractTotalUploadCost.Add(prevDataUploaded.MultUint64(rent.Period).Mult(upcoming.StoragePrice))
 
 	// contract price will be stayed the same
-	contractPrice := host.ContractPrice
+	contractPrice := upcoming.ContractPrice
 
 	// TODO (mzhang): Gas fee estimation is ignored currently
 
@@ -95,6 +66,45 @@ func (cm *ContractManager) renewCostEstimation(host storage.HostInfo, contract s
 	return
 }
 
+// currentPeriodSpending returns the upload and download cost spent within the current period
+// on the contract and the contracts it was renewed from
+func (cm *ContractManager) currentPeriodSpending(contract storage.ContractMetaData) (uploadCost, downloadCost common.BigInt) {
+	currentID := contract.ID
+	uploadCost, downloadCost = contract.UploadCost, contract.DownloadCost
+
+	cm.lock.RLock()
+	defer cm.lock.RUnlock()
+
+	// prevent loop from running forever
+	for i := 0; i < 10e5; i++ {
+		// get the previous contractID
+		prevContractID, exists := cm.renewedFrom[currentID]
+		if !exists {
+			break
+		}
+
+		// get the previous contract information
+		prevContract, exists := cm.expiredContracts[prevContractID]
+		if !exists {
+			break
+		}
+
+		// verify the start height to check if the start height is within the current period
+		// current period will be changed in two places:
+		// 		1. SetRentPayment
+		//		2. ChainChanges
+		if prevContract.StartHeight < cm.currentPeriod {
+			break
+		}
+
+		// update the upload and download cost. Note: this cost is spent within the current period
+		uploadCost = uploadCost.Add(prevContract.UploadCost)
+		downloadCost = downloadCost.Add(prevContract.DownloadCost)
+		currentID = prevContractID
+	}
+	return
+}
+
 // CalculatePeriodCost will calculate the storage client's cost for one period (including all contracts)
 func (cm *ContractManager) CalculatePeriodCost(rentPayment storage.RentPayment) (periodCost storage.PeriodCost) {
 	// get all activeContracts
@@ -9,8 +9,12 @@ import (
 	"math/big"
 
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/storage"
 )
 
+// alertModule is the module name of the alerts raised by the contract maintenance
+const alertModule = "contractmanager"
+
 // persistent related constants
 const (
 	PersistContractManagerHeader  = "Storage Contract Manager Settings"
@@ -35,6 +39,32 @@ const (
 
 	// if a contract failed to renew for 12 times, consider to replace the contract
 	consecutiveRenewFailsBeforeReplacement = 12
+
+	// maxForecastPeriods is the maximum number of periods the spending could be forecast
+	maxForecastPeriods = 24
+)
+
+// utilizationSampleInterval is the interval in blocks the utilization of each contract is sampled
+var utilizationSampleInterval = 6 * storage.BlockPerHour
+
+// staleContractCheckInterval is the interval in blocks the revision numbers of the active
+// contracts are compared with the ones on chain
+var staleContractCheckInterval = storage.BlockPerHour
+
+// contract utilization related constants
+const (
+	// maxUtilizationSamples is the number of samples kept for each contract, which is 8 days
+	maxUtilizationSamples = 32
+
+	// a contract is chronically underutilized if the storage used is below the ratio of
+	// the funded storage for a day, and exhausted if the storage used or the fund spent
+	// reaches the ratio
+	underutilizedStorageRatio = 0.2
+	minUnderutilizedSamples   = 4
+	exhaustedRatio            = 0.9
+
+	// utilizationFundMargin is the margin over the projected spending for the suggested fund
+	utilizationFundMargin = 1.2
 )
 
 // variables below are used to calculate the maxHostStoragePrice and maxHostDeposit, which set
@@ -0,0 +1,129 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package contractmanager
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/storage/storageclient/storagehostmanager"
+)
+
+// SpendingForecast is the projection of the storage client's spending over the next periods,
+// which is used to help the user plan the funding
+type SpendingForecast struct {
+	BlockHeight uint64 `json:"blockheight"`
+
+	// data stored in all active contracts, and the data uploaded / downloaded per block
+	// observed within the current period
+	StoredBytes      uint64 `json:"storedbytes"`
+	UploadPerBlock   uint64 `json:"uploadperblock"`
+	DownloadPerBlock uint64 `json:"downloadperblock"`
+
+	Periods   []PeriodForecast `json:"periods"`
+	TotalCost common.BigInt    `json:"totalcost"`
+}
+
+// PeriodForecast is the projected spending within a single period
+type PeriodForecast struct {
+	StartHeight uint64 `json:"startheight"`
+	EndHeight   uint64 `json:"endheight"`
+	StoredBytes uint64 `json:"storedbytes"`
+
+	Market storagehostmanager.MarketStats `json:"market"`
+
+	StorageCost  common.BigInt `json:"storagecost"`
+	UploadCost   common.BigInt `json:"uploadcost"`
+	DownloadCost common.BigInt `json:"downloadcost"`
+	RenewalFees  common.BigInt `json:"renewalfees"`
+	TotalCost    common.BigInt `json:"totalcost"`
+
+	// Shortfall is the amount the total cost exceeds the fund of the rent payment
+	Shortfall common.BigInt `json:"shortfall"`
+}
+
+// ForecastSpending projects the spending of the next periods based on the data currently stored,
+// the upload and download trend observed within the current period, and the market prices
+// aggregated from the active storage hosts, including the renewal fees paid to the hosts
+func (cm *ContractManager) ForecastSpending(periods int) (forecast SpendingForecast, err error) {
+	if periods <= 0 || periods > maxForecastPeriods {
+		err = fmt.Errorf("the number of periods must be within 1 and %v", maxForecastPeriods)
+		return
+	}
+
+	cm.lock.RLock()
+	rent, blockHeight, currentPeriod := cm.rentPayment, cm.blockHeight, cm.currentPeriod
+	cm.lock.RUnlock()
+	if rent.Period == 0 {
+		err = errors.New("the rent payment is not set")
+		return
+	}
+
+	// estimate the amount of data uploaded and downloaded within the current period from the
+	// cost spent, the same way the renew cost is estimated
+	uploaded, downloaded := common.BigInt0, common.BigInt0
+	for _, contract := range cm.activeContracts.RetrieveAllContractsMetaData() {
+		forecast.StoredBytes += contract.LatestContractRevision.NewFileSize
+
+		host, exists := cm.hostManager.RetrieveHostInfo(contract.EnodeID)
+		if !exists {
+			continue
+		}
+		uploadCost, downloadCost := cm.currentPeriodSpending(contract)
+		if host.UploadBandwidthPrice.Sign() > 0 {
+			uploaded = uploaded.Add(uploadCost.Div(host.UploadBandwidthPrice))
+		}
+		if host.DownloadBandwidthPrice.Sign() > 0 {
+			downloaded = downloaded.Add(downloadCost.Div(host.DownloadBandwidthPrice))
+		}
+	}
+	elapsed := uint64(1)
+	if blockHeight > currentPeriod {
+		elapsed = blockHeight - currentPeriod
+	}
+	forecast.BlockHeight = blockHeight
+	forecast.UploadPerBlock = uploaded.DivUint64(elapsed).BigIntPtr().Uint64()
+	forecast.DownloadPerBlock = downloaded.DivUint64(elapsed).BigIntPtr().Uint64()
+
+	// the forecast starts from the next period, the data keeps growing till then
+	start := currentPeriod + rent.Period
+	if start < blockHeight {
+		start = blockHeight
+	}
+	stored := forecast.StoredBytes + forecast.UploadPerBlock*(start-blockHeight)
+
+	forecast.TotalCost = common.BigInt0
+	for i := 0; i < periods; i++ {
+		pf := PeriodForecast{
+			StartHeight: start,
+			EndHeight:   start + rent.Period,
+			StoredBytes: stored + forecast.UploadPerBlock*rent.Period,
+			Shortfall:   common.BigInt0,
+		}
+
+		// the price increases scheduled by the hosts within the period are included
+		pf.Market = cm.hostManager.MarketStats(pf.EndHeight)
+		if pf.Market.Hosts == 0 {
+			err = errors.New("no active storage host is available to estimate the market prices")
+			return
+		}
+
+		// the data stored grows linearly within the period, use the average for the storage cost
+		pf.StorageCost = common.NewBigIntUint64(stored + pf.StoredBytes).MultUint64(rent.Period).Mult(pf.Market.StoragePrice).DivUint64(2)
+		pf.UploadCost = pf.Market.UploadBandwidthPrice.MultUint64(forecast.UploadPerBlock * rent.Period)
+		pf.DownloadCost = pf.Market.DownloadBandwidthPrice.MultUint64(forecast.DownloadPerBlock * rent.Period)
+		pf.RenewalFees = pf.Market.ContractPrice.MultUint64(rent.StorageHosts)
+		pf.TotalCost = pf.StorageCost.Add(pf.UploadCost).Add(pf.DownloadCost).Add(pf.RenewalFees)
+		if pf.TotalCost.Cmp(rent.Fund) > 0 {
+			pf.Shortfall = pf.TotalCost.Sub(rent.Fund)
+		}
+
+		forecast.Periods = append(forecast.Periods, pf)
+		forecast.TotalCost = forecast.TotalCost.Add(pf.TotalCost)
+		start, stored = pf.EndHeight, pf.StoredBytes
+	}
+	return
+}
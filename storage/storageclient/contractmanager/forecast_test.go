@@ -0,0 +1,116 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package contractmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+	"github.com/DxChainNetwork/godx/storage/storageclient/storagehostmanager"
+)
+
+func TestContractManager_ForecastSpending(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forecast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cs, err := contractset.New(dir, "")
+	if err != nil {
+		t.Fatalf("failed to create contract set: %s", err.Error())
+	}
+	defer cs.Close()
+
+	cm := &ContractManager{
+		hostManager:     storagehostmanager.New(dir),
+		activeContracts: cs,
+		renewedFrom:     make(map[storage.ContractID]storage.ContractID),
+		rentPayment: storage.RentPayment{
+			Fund:         common.NewBigIntUint64(1),
+			StorageHosts: 2,
+			Period:       1000,
+		},
+		blockHeight: 100,
+	}
+
+	// no active storage host to estimate the market prices
+	if _, err = cm.ForecastSpending(1); err == nil {
+		t.Fatalf("forecast without active storage hosts should return an error")
+	}
+
+	// all prices of the high evaluation hosts are 1
+	debug := storagehostmanager.NewPublicStorageClientDebugAPI(cm.hostManager)
+	for i := 0; i < 2; i++ {
+		ch := randomContractGenerator(1000)
+		ch.StartHeight = 0
+		ch.LatestContractRevision.NewFileSize = 4000
+		ch.UploadCost = common.NewBigIntUint64(1000)
+		ch.DownloadCost = common.NewBigIntUint64(500)
+		if err = debug.InsertHostInfoHighEval(ch.EnodeID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = cs.InsertContract(ch, randomRootsGenerator(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, periods := range []int{0, maxForecastPeriods + 1} {
+		if _, err = cm.ForecastSpending(periods); err == nil {
+			t.Errorf("forecast %v periods should return an error", periods)
+		}
+	}
+
+	forecast, err := cm.ForecastSpending(2)
+	if err != nil {
+		t.Fatalf("failed to forecast the spending: %s", err.Error())
+	}
+	if forecast.StoredBytes != 8000 || forecast.UploadPerBlock != 20 || forecast.DownloadPerBlock != 10 {
+		t.Fatalf("usage trend not expected: %+v", forecast)
+	}
+	if len(forecast.Periods) != 2 {
+		t.Fatalf("expect 2 periods, got %v", len(forecast.Periods))
+	}
+
+	// data stored grows from 8000 + 20 * 900 to 26000 + 20 * 1000 within the first period
+	first := forecast.Periods[0]
+	if first.StartHeight != 1000 || first.EndHeight != 2000 || first.StoredBytes != 46000 {
+		t.Errorf("first period not expected: %+v", first)
+	}
+	expect := map[string]uint64{
+		"storage":  (26000 + 46000) * 1000 / 2,
+		"upload":   20 * 1000,
+		"download": 10 * 1000,
+		"renewal":  2,
+	}
+	got := map[string]common.BigInt{
+		"storage":  first.StorageCost,
+		"upload":   first.UploadCost,
+		"download": first.DownloadCost,
+		"renewal":  first.RenewalFees,
+	}
+	var total uint64
+	for name, cost := range expect {
+		if got[name].CmpUint64(cost) != 0 {
+			t.Errorf("%v cost not expected. Got %v, expect %v", name, got[name], cost)
+		}
+		total += cost
+	}
+	if first.TotalCost.CmpUint64(total) != 0 || first.Shortfall.CmpUint64(total-1) != 0 {
+		t.Errorf("total cost or shortfall not expected: %v, %v", first.TotalCost, first.Shortfall)
+	}
+
+	second := forecast.Periods[1]
+	if second.StartHeight != first.EndHeight || second.StoredBytes != 66000 {
+		t.Errorf("second period not expected: %+v", second)
+	}
+	if forecast.TotalCost.Cmp(first.TotalCost.Add(second.TotalCost)) != 0 {
+		t.Errorf("total cost of the forecast not expected")
+	}
+}
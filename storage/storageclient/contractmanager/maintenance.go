@@ -6,6 +6,7 @@ package contractmanager
 
 import (
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
 	"github.com/DxChainNetwork/godx/log"
 	"github.com/DxChainNetwork/godx/storage"
 	"reflect"
@@ -26,8 +27,9 @@ type contractRenewRecord struct {
 // 		3. maintainHostToContractIDMapping: update the host to contractID mapping
 // 		4. removeHostWithDuplicateNetworkAddress: for storage host located under same network address, only
 // 		one can be saved
-// 		5. filter out contracts need to be renewed, renew contract
-// 		6. check out how many more contracts need to be created, create the contracts
+// 		5. checkStaleContracts: cancel the contracts whose revision on chain is newer than the local one
+// 		6. filter out contracts need to be renewed, renew contract
+// 		7. check out how many more contracts need to be created, create the contracts
 func (cm *ContractManager) contractMaintenance() {
 	// if the maintenance is running, return directly
 	// otherwise, start the maintaining job
@@ -51,6 +53,7 @@ func (cm *ContractManager) contractMaintenance() {
 	cm.removeDuplications()
 	cm.maintainHostToContractIDMapping()
 	cm.removeHostWithDuplicateNetworkAddress()
+	cm.checkStaleContracts()
 
 	// get the rentPayment, this rentPayment will be used for all future
 	// contract renew and contract create
@@ -65,7 +68,7 @@ func (cm *ContractManager) contractMaintenance() {
 	}
 
 	if err := cm.maintainContractStatus(int(rentPayment.StorageHosts)); err != nil {
-		log.Error("failed to maintain contract status, contractMaintenance terminating", "err", err.Error())
+		alert.Raise(cm.log, alert.SeverityError, alertModule, "failed to maintain contract status, contract maintenance terminating", "err", err.Error())
 		return
 	}
 
@@ -125,7 +128,7 @@ func (cm *ContractManager) contractMaintenance() {
 	// prepare to for forming contract based on the number of extract contracts needed
 	terminated, err := cm.prepareCreateContract(neededContracts, clientRemainingFund, rentPayment)
 	if err != nil {
-		cm.log.Error("failed to create the contract", "err", err.Error())
+		alert.Raise(cm.log, alert.SeverityWarning, alertModule, "failed to create the contracts during maintenance", "err", err.Error())
 		return
 	}
 
@@ -17,21 +17,27 @@ var settingsMetadata = common.Metadata{
 }
 
 type persistence struct {
-	Rent             storage.RentPayment           `json:"rentPayment"`
-	BlockHeight      uint64                        `json:"blockheight"`
-	CurrentPeriod    uint64                        `json:"currentperiod"`
-	ExpiredContracts []storage.ContractMetaData    `json:"expiredcontracts"`
-	RenewedFrom      map[string]storage.ContractID `json:"renewedfrom"`
-	RenewedTo        map[string]storage.ContractID `json:"renewedto"`
+	Rent             storage.RentPayment            `json:"rentPayment"`
+	BlockHeight      uint64                         `json:"blockheight"`
+	CurrentPeriod    uint64                         `json:"currentperiod"`
+	PeriodCost       storage.PeriodCost             `json:"periodcost"`
+	ExpiredContracts []storage.ContractMetaData     `json:"expiredcontracts"`
+	RenewedFrom      map[string]storage.ContractID  `json:"renewedfrom"`
+	RenewedTo        map[string]storage.ContractID  `json:"renewedto"`
+	FailedRenewCount map[string]uint64              `json:"failedrenewcount"`
+	Utilization      map[string][]UtilizationSample `json:"utilization"`
 }
 
 func (cm *ContractManager) persistUpdate() (persist persistence) {
 	persist = persistence{
-		Rent:          cm.rentPayment,
-		BlockHeight:   cm.blockHeight,
-		CurrentPeriod: cm.currentPeriod,
-		RenewedFrom:   make(map[string]storage.ContractID),
-		RenewedTo:     make(map[string]storage.ContractID),
+		Rent:             cm.rentPayment,
+		BlockHeight:      cm.blockHeight,
+		CurrentPeriod:    cm.currentPeriod,
+		PeriodCost:       cm.periodCost,
+		RenewedFrom:      make(map[string]storage.ContractID),
+		RenewedTo:        make(map[string]storage.ContractID),
+		FailedRenewCount: make(map[string]uint64),
+		Utilization:      make(map[string][]UtilizationSample),
 	}
 
 	// update the renewedFrom
@@ -44,6 +50,16 @@ func (cm *ContractManager) persistUpdate() (persist persistence) {
 		persist.RenewedTo[key.String()] = value
 	}
 
+	// update the failedRenewCount
+	for key, value := range cm.failedRenewCount {
+		persist.FailedRenewCount[key.String()] = value
+	}
+
+	// update the utilization samples
+	for key, value := range cm.utilization {
+		persist.Utilization[key.String()] = value
+	}
+
 	// update the expiredContracts
 	for _, ec := range cm.expiredContracts {
 		persist.ExpiredContracts = append(persist.ExpiredContracts, ec)
@@ -52,15 +68,38 @@ func (cm *ContractManager) persistUpdate() (persist persistence) {
 	return
 }
 
-// saveSettings will store all the persistence data into the JSON file
+// saveSettings will store all the persistence data into the JSON file. The saves are serialized,
+// so that the snapshot taken later is always the one left on disk. The settings are not written
+// atomically with the contract set: the contract changes are committed first, and the crash
+// before the settings are saved leaves the renew bookkeeping and the period cost behind the
+// contract set. reconcileSettings closes the window on start
 func (cm *ContractManager) saveSettings() (err error) {
-	cm.lock.Lock()
-	defer cm.lock.Unlock()
+	cm.persistLock.Lock()
+	defer cm.persistLock.Unlock()
 
+	cm.lock.RLock()
 	data := cm.persistUpdate()
+	cm.lock.RUnlock()
 	return common.SaveDxJSON(settingsMetadata, filepath.Join(cm.persistDir, PersistFileName), data)
 }
 
+// reconcileSettings rebuilds the settings that could be left behind the contract set by a crash.
+// The renew bookkeeping and the expired contracts are recovered from the duplicated contracts
+// with the same storage host, and the period cost is recalculated from the contracts. The
+// current period is synced with the block height separately
+func (cm *ContractManager) reconcileSettings() {
+	cm.removeDuplications()
+
+	cm.lock.RLock()
+	rentPayment := cm.rentPayment
+	cm.lock.RUnlock()
+
+	periodCost := cm.CalculatePeriodCost(rentPayment)
+	cm.lock.Lock()
+	cm.periodCost = periodCost
+	cm.lock.Unlock()
+}
+
 // loadSettings will load the storage contract manager settings saved
 func (cm *ContractManager) loadSettings() (err error) {
 	// make directory
@@ -81,6 +120,7 @@ func (cm *ContractManager) loadSettings() (err error) {
 	cm.rentPayment = data.Rent
 	cm.blockHeight = data.BlockHeight
 	cm.currentPeriod = data.CurrentPeriod
+	cm.periodCost = data.PeriodCost
 
 	// update the RenewedFrom
 	for key, value := range data.RenewedFrom {
@@ -108,6 +148,26 @@ func (cm *ContractManager) loadSettings() (err error) {
 		cm.renewedTo[id] = value
 	}
 
+	// update the FailedRenewCount
+	for key, value := range data.FailedRenewCount {
+		id, err := storage.StringToContractID(key)
+		if err != nil {
+			cm.log.Warn("contractmanager loadsettings failedRenewCount", "err", err.Error())
+			continue
+		}
+		cm.failedRenewCount[id] = value
+	}
+
+	// update the utilization samples
+	for key, value := range data.Utilization {
+		id, err := storage.StringToContractID(key)
+		if err != nil {
+			cm.log.Warn("contractmanager loadsettings utilization", "err", err.Error())
+			continue
+		}
+		cm.utilization[id] = value
+	}
+
 	// update expired contract list and hostToContract mapping
 	for _, ec := range data.ExpiredContracts {
 		cm.expiredContracts[ec.ID] = ec
@@ -0,0 +1,119 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package contractmanager
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/log"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+)
+
+// backendAtHeight is the backend with the current block at the height
+type backendAtHeight struct {
+	storageClientBackendContractManager
+	height uint64
+}
+
+func (b *backendAtHeight) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(b.height)})
+}
+
+func newPersistTestContractManager(persistDir string, height uint64) *ContractManager {
+	return &ContractManager{
+		b:                &backendAtHeight{height: height},
+		persistDir:       persistDir,
+		expiredContracts: make(map[storage.ContractID]storage.ContractMetaData),
+		renewedFrom:      make(map[storage.ContractID]storage.ContractID),
+		renewedTo:        make(map[storage.ContractID]storage.ContractID),
+		failedRenewCount: make(map[storage.ContractID]uint64),
+		hostToContract:   make(map[enode.ID]storage.ContractID),
+		log:              log.New(),
+	}
+}
+
+func TestContractManager_PersistPeriod(t *testing.T) {
+	dir, err := ioutil.TempDir("", "contractmanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cm := newPersistTestContractManager(dir, 0)
+	cm.rentPayment = testRentPayment
+	cm.blockHeight = 100
+	cm.currentPeriod = 50
+	cm.periodCost = storage.PeriodCost{ContractFund: common.NewBigInt(1000), UploadCost: common.NewBigInt(10)}
+	renewed, failed := storage.ContractID{1}, storage.ContractID{2}
+	cm.renewedFrom[renewed] = storage.ContractID{3}
+	cm.failedRenewCount[failed] = 2
+	if err = cm.saveSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the blocks applied while offline are caught up from the current block, and
+	// the current period is advanced for every period passed
+	height := cm.currentPeriod + 2*testRentPayment.Period + 1
+	loaded := newPersistTestContractManager(dir, height)
+	if err = loaded.loadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.periodCost, cm.periodCost) || loaded.failedRenewCount[failed] != 2 ||
+		loaded.renewedFrom[renewed] != cm.renewedFrom[renewed] {
+		t.Fatalf("loaded settings not expected: %+v, %v", loaded.periodCost, loaded.failedRenewCount)
+	}
+	if loaded.currentPeriod != cm.currentPeriod {
+		t.Fatalf("current period expect %v, got %v", cm.currentPeriod, loaded.currentPeriod)
+	}
+	loaded.syncBlockHeight()
+	if loaded.blockHeight != height {
+		t.Errorf("block height expect %v, got %v", height, loaded.blockHeight)
+	}
+	if expect := cm.currentPeriod + 2*testRentPayment.Period; loaded.currentPeriod != expect {
+		t.Errorf("current period expect %v, got %v", expect, loaded.currentPeriod)
+	}
+}
+
+func TestContractManager_ReconcileSettings(t *testing.T) {
+	cm, err := createNewContractManager()
+	if err != nil {
+		t.Fatalf("failed to create contract manager: %s", err.Error())
+	}
+	defer os.RemoveAll("test")
+	defer cm.activeContracts.Close()
+	defer cm.activeContracts.EmptyDB()
+
+	// the contract renewed right before a crash, with the settings not saved
+	enodeID := randomEnodeIDGenerator()
+	oldContract := randomDuplicateContractGenerator(200, enodeID)
+	renewedContract := randomDuplicateContractGenerator(600, enodeID)
+	for _, ch := range []contractset.ContractHeader{oldContract, renewedContract} {
+		if _, err := cm.activeContracts.InsertContract(ch, randomRootsGenerator(10)); err != nil {
+			t.Fatalf("failed to insert contract: %s", err.Error())
+		}
+	}
+
+	cm.reconcileSettings()
+	if _, exists := cm.expiredContracts[oldContract.ID]; !exists {
+		t.Errorf("the old contract should be expired")
+	}
+	if _, exists := cm.activeContracts.Acquire(oldContract.ID); exists {
+		t.Errorf("the old contract should be removed from the contract set")
+	}
+	if cm.renewedFrom[renewedContract.ID] != oldContract.ID {
+		t.Errorf("renewed from expect %v, got %v", oldContract.ID, cm.renewedFrom[renewedContract.ID])
+	}
+	if cm.periodCost.ContractFund.Cmp(renewedContract.TotalCost) < 0 {
+		t.Errorf("the period cost should include the renewed contract, got %v", cm.periodCost.ContractFund)
+	}
+}
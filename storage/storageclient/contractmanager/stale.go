@@ -0,0 +1,62 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package contractmanager
+
+import (
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
+)
+
+// StaleContractFoundChan returns the channel signaled when the active contracts are found
+// stale and canceled, so that the data stored with them could be migrated to the other hosts
+func (cm *ContractManager) StaleContractFoundChan() <-chan struct{} {
+	return cm.staleContractFound
+}
+
+// checkStaleContracts compares the revision number of each active contract with the revision
+// number of the contract on chain, once in every staleContractCheckInterval. The contract
+// with a newer revision on chain is stale, which means either the local revision is lost, or
+// the host submitted a revision the client does not have. The stale contracts are canceled,
+// thus no longer good for upload and renew
+func (cm *ContractManager) checkStaleContracts() {
+	cm.lock.Lock()
+	if cm.blockHeight < cm.nextStaleCheck {
+		cm.lock.Unlock()
+		return
+	}
+	cm.nextStaleCheck = cm.blockHeight + staleContractCheckInterval
+	cm.lock.Unlock()
+
+	var found bool
+	for _, contract := range cm.activeContracts.RetrieveAllContractsMetaData() {
+		if contract.Status.Canceled {
+			continue
+		}
+		revisionNumber, exists, err := cm.b.GetStorageContractRevisionNumber(common.Hash(contract.ID))
+		if err != nil {
+			cm.log.Warn("failed to retrieve the contract revision number on chain", "contractID", contract.ID, "err", err)
+			return
+		}
+		localRevisionNumber := contract.LatestContractRevision.NewRevisionNumber
+		if !exists || revisionNumber <= localRevisionNumber {
+			continue
+		}
+
+		alert.Raise(cm.log, alert.SeverityError, alertModule, "the contract revision on chain is newer than the local revision, the contract is canceled",
+			"contractID", contract.ID, "hostID", contract.EnodeID, "localRevision", localRevisionNumber, "chainRevision", revisionNumber)
+		if err = cm.markContractCancel(contract.ID); err != nil {
+			cm.log.Warn("failed to cancel the stale contract", "contractID", contract.ID, "err", err)
+			continue
+		}
+		found = true
+	}
+
+	if found {
+		select {
+		case cm.staleContractFound <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package contractmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/log"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+)
+
+func TestContractManager_CheckStaleContracts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cs, err := contractset.New(dir, "")
+	if err != nil {
+		t.Fatalf("failed to create contract set: %s", err.Error())
+	}
+	defer cs.Close()
+
+	// the first contract is up to date, the second is stale, and the third is not on chain
+	b := &storageClientBackendContractManager{revisions: make(map[common.Hash]uint64)}
+	cm := &ContractManager{
+		b:                  b,
+		activeContracts:    cs,
+		staleContractFound: make(chan struct{}, 1),
+		log:                log.New(),
+	}
+	var ids []storage.ContractID
+	for i, chainRevision := range []uint64{10, 11, 0} {
+		ch := randomContractGenerator(2000)
+		ch.LatestContractRevision.NewRevisionNumber = 10
+		ch.Status = storage.ContractStatus{UploadAbility: true, RenewAbility: true}
+		if _, err = cs.InsertContract(ch, randomRootsGenerator(1)); err != nil {
+			t.Fatal(err)
+		}
+		if i != 2 {
+			b.revisions[common.Hash(ch.ID)] = chainRevision
+		}
+		ids = append(ids, ch.ID)
+	}
+
+	cm.checkStaleContracts()
+	for i, id := range ids {
+		status, exists := cm.retrieveContractStatus(id)
+		if !exists {
+			t.Fatalf("contract %v not found", id)
+		}
+		if stale := i == 1; status.Canceled != stale || status.RenewAbility == stale {
+			t.Errorf("contract %v: unexpected status %+v", i, status)
+		}
+	}
+	select {
+	case <-cm.StaleContractFoundChan():
+	default:
+		t.Fatalf("the stale contract found is not signaled")
+	}
+
+	// the contracts are not checked again within the interval
+	b.revisions[common.Hash(ids[0])] = 12
+	cm.blockHeight = staleContractCheckInterval - 1
+	cm.checkStaleContracts()
+	if status, _ := cm.retrieveContractStatus(ids[0]); status.Canceled {
+		t.Fatalf("the contract is checked within the interval")
+	}
+	cm.blockHeight = staleContractCheckInterval
+	cm.checkStaleContracts()
+	if status, _ := cm.retrieveContractStatus(ids[0]); !status.Canceled {
+		t.Fatalf("the stale contract is not canceled after the interval")
+	}
+}
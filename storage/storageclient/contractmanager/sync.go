@@ -47,11 +47,12 @@ func (cm *ContractManager) analyzeChainEventChange(change core.ChainChangeEvent)
 		cm.blockHeight++
 	}
 
-	if cm.blockHeight >= cm.currentPeriod+cm.rentPayment.Period {
-		cm.currentPeriod += cm.rentPayment.Period
-	}
+	cm.syncBlockHeight()
 	cm.lock.Unlock()
 
+	// sample the utilization of the contracts with the new block height
+	cm.sampleUtilization()
+
 	// save the newest settings (blockHeight) persistently
 	if err := cm.saveSettings(); err != nil {
 		cm.log.Warn("failed to save the current contract manager settings while analyzing the chain change event", "err", err.Error())
@@ -62,3 +63,21 @@ func (cm *ContractManager) analyzeChainEventChange(change core.ChainChangeEvent)
 		go cm.contractMaintenance()
 	}
 }
+
+// syncBlockHeight sets the block height to the height of the current block, and advances the
+// current period for every period passed. Counting the chain change events alone could drift,
+// because the events received before a crash are lost if the settings were not saved yet.
+// Require: lock the contract manager by caller
+func (cm *ContractManager) syncBlockHeight() {
+	if block := cm.b.CurrentBlock(); block != nil {
+		cm.blockHeight = block.NumberU64()
+	}
+
+	period := cm.rentPayment.Period
+	if period == 0 {
+		return
+	}
+	for cm.blockHeight >= cm.currentPeriod+period {
+		cm.currentPeriod += period
+	}
+}
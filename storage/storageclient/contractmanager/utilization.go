@@ -0,0 +1,248 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package contractmanager
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// utilization status of the contracts
+const (
+	UtilizationNormal        = "normal"
+	UtilizationUnderutilized = "underutilized"
+	UtilizationExhausted     = "exhausted"
+)
+
+type (
+	// UtilizationSample is the storage used and the fund spent by a contract at the block height
+	UtilizationSample struct {
+		BlockHeight uint64        `json:"blockheight"`
+		UsedStorage uint64        `json:"usedstorage"`
+		SpentFund   common.BigInt `json:"spentfund"`
+	}
+
+	// ContractUtilization is the funded-vs-used storage of an active contract
+	ContractUtilization struct {
+		ContractID storage.ContractID `json:"contractid"`
+		HostID     enode.ID           `json:"hostid"`
+
+		// FundedStorage is the storage the contract is expected to hold, which is the
+		// expected storage with redundancy shared by all contracts
+		FundedStorage uint64  `json:"fundedstorage"`
+		UsedStorage   uint64  `json:"usedstorage"`
+		StorageRatio  float64 `json:"storageratio"`
+
+		Fund      common.BigInt `json:"fund"`
+		SpentFund common.BigInt `json:"spentfund"`
+		FundRatio float64       `json:"fundratio"`
+
+		Status  string              `json:"status"`
+		Samples []UtilizationSample `json:"samples"`
+	}
+
+	// UtilizationReport is the utilization of all active contracts, with the rent payment
+	// adjustments suggested for the next period
+	UtilizationReport struct {
+		BlockHeight   uint64                `json:"blockheight"`
+		Contracts     []ContractUtilization `json:"contracts"`
+		Underutilized int                   `json:"underutilized"`
+		Exhausted     int                   `json:"exhausted"`
+
+		SuggestedExpectedStorage uint64        `json:"suggestedexpectedstorage"`
+		SuggestedStorageHosts    uint64        `json:"suggestedstoragehosts"`
+		SuggestedContractFund    common.BigInt `json:"suggestedcontractfund"`
+		SuggestedFund            common.BigInt `json:"suggestedfund"`
+	}
+)
+
+// sampleUtilization records the storage used and the fund spent by each active contract,
+// once every utilizationSampleInterval blocks. The samples of the contracts no longer
+// active are removed
+func (cm *ContractManager) sampleUtilization() {
+	contracts := cm.activeContracts.RetrieveAllContractsMetaData()
+
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	active := make(map[storage.ContractID]struct{})
+	for _, contract := range contracts {
+		active[contract.ID] = struct{}{}
+		samples := cm.utilization[contract.ID]
+		if len(samples) != 0 && samples[len(samples)-1].BlockHeight+utilizationSampleInterval > cm.blockHeight {
+			continue
+		}
+		samples = append(samples, UtilizationSample{
+			BlockHeight: cm.blockHeight,
+			UsedStorage: contract.LatestContractRevision.NewFileSize,
+			SpentFund:   spentFund(contract),
+		})
+		if len(samples) > maxUtilizationSamples {
+			samples = samples[len(samples)-maxUtilizationSamples:]
+		}
+		cm.utilization[contract.ID] = samples
+	}
+	for id := range cm.utilization {
+		if _, exist := active[id]; !exist {
+			delete(cm.utilization, id)
+		}
+	}
+}
+
+// ContractUtilization reports the funded-vs-used storage of the active contracts. A contract
+// is chronically underutilized if the storage used stays below underutilizedStorageRatio for
+// at least minUnderutilizedSamples samples, and is exhausted if the storage used or the fund
+// spent reaches exhaustedRatio. Based on the trend of the samples, the expected storage,
+// the number of storage hosts and the fund of each contract for the next period are suggested.
+// The suggested number of storage hosts is at least minStorageHosts, which is the number of
+// sectors of the erasure code of the files uploaded. The contracts younger than utilizationSampleInterval are not
+// sampled enough, and are ignored
+func (cm *ContractManager) ContractUtilization(minStorageHosts uint64) (report UtilizationReport, err error) {
+	contracts := cm.activeContracts.RetrieveAllContractsMetaData()
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].StartHeight < contracts[j].StartHeight })
+
+	cm.lock.RLock()
+	rent, blockHeight := cm.rentPayment, cm.blockHeight
+	samples := make(map[storage.ContractID][]UtilizationSample)
+	for _, contract := range contracts {
+		samples[contract.ID] = append([]UtilizationSample{}, cm.utilization[contract.ID]...)
+	}
+	cm.lock.RUnlock()
+	if rent.StorageHosts == 0 || rent.Period == 0 {
+		err = errors.New("the rent payment is not set")
+		return
+	}
+
+	report.BlockHeight = blockHeight
+	fundedStorage := uint64(float64(rent.ExpectedStorage) * rent.ExpectedRedundancy / float64(rent.StorageHosts))
+	contractFund := rent.Fund.DivUint64(rent.StorageHosts)
+
+	// projected storage in total and the max fund ratio projected for the next period
+	var projectedStorage uint64
+	maxProjectedFundRatio := 0.0
+	for _, contract := range contracts {
+		if contract.StartHeight+utilizationSampleInterval > blockHeight {
+			continue
+		}
+		cu := ContractUtilization{
+			ContractID:    contract.ID,
+			HostID:        contract.EnodeID,
+			FundedStorage: fundedStorage,
+			UsedStorage:   contract.LatestContractRevision.NewFileSize,
+			Fund:          contract.TotalCost,
+			SpentFund:     spentFund(contract),
+			Status:        UtilizationNormal,
+			Samples:       samples[contract.ID],
+		}
+		cu.StorageRatio = ratio(cu.UsedStorage, fundedStorage)
+		if cu.Fund.Sign() > 0 {
+			cu.FundRatio = cu.SpentFund.DivWithFloatResult(cu.Fund)
+		}
+
+		switch {
+		case cu.StorageRatio >= exhaustedRatio || cu.FundRatio >= exhaustedRatio:
+			cu.Status = UtilizationExhausted
+			report.Exhausted++
+		case chronicallyUnderutilized(cu.Samples, fundedStorage):
+			cu.Status = UtilizationUnderutilized
+			report.Underutilized++
+		}
+
+		storageGrowth, spendRate := utilizationTrend(cu.Samples)
+		projectedStorage += cu.UsedStorage + uint64(storageGrowth*float64(rent.Period))
+		if cu.Status != UtilizationUnderutilized && contractFund.Sign() > 0 {
+			projected := spendRate * float64(rent.Period) / contractFund.Float64()
+			maxProjectedFundRatio = math.Max(maxProjectedFundRatio, projected)
+		}
+		report.Contracts = append(report.Contracts, cu)
+	}
+
+	// the expected storage is the data projected to be stored at the end of the next period
+	report.SuggestedExpectedStorage = rent.ExpectedStorage
+	if projectedStorage != 0 {
+		report.SuggestedExpectedStorage = uint64(float64(projectedStorage) / rent.ExpectedRedundancy)
+	}
+
+	// the hosts of the chronically underutilized contracts are not needed, but the data
+	// still needs at least as many hosts as the redundancy, and as the sectors of the
+	// erasure code to place each sector of a segment on a different host
+	report.SuggestedStorageHosts = rent.StorageHosts
+	if report.Underutilized != 0 {
+		minHosts := uint64(math.Ceil(rent.ExpectedRedundancy))
+		report.SuggestedStorageHosts = rent.StorageHosts - uint64(report.Underutilized)
+		if rent.StorageHosts < uint64(report.Underutilized)+minHosts {
+			report.SuggestedStorageHosts = minHosts
+		}
+		if report.SuggestedStorageHosts > rent.StorageHosts {
+			report.SuggestedStorageHosts = rent.StorageHosts
+		}
+	}
+	if report.SuggestedStorageHosts < minStorageHosts {
+		report.SuggestedStorageHosts = minStorageHosts
+	}
+
+	// the contracts exhausted shall be funded with the spending projected for the next period
+	report.SuggestedContractFund = contractFund
+	if report.Exhausted != 0 && maxProjectedFundRatio > 1 {
+		report.SuggestedContractFund = contractFund.MultFloat64(maxProjectedFundRatio * utilizationFundMargin)
+	}
+	report.SuggestedFund = report.SuggestedContractFund.MultUint64(report.SuggestedStorageHosts)
+	return
+}
+
+// spentFund returns the fund spent in the contract, which is the fund of the contract
+// minus the remaining balance of the client
+func spentFund(contract storage.ContractMetaData) common.BigInt {
+	if contract.TotalCost.Cmp(contract.ContractBalance) <= 0 {
+		return common.BigInt0
+	}
+	return contract.TotalCost.Sub(contract.ContractBalance)
+}
+
+// chronicallyUnderutilized checks whether the storage used stays below the underutilized
+// ratio in all of the last minUnderutilizedSamples samples
+func chronicallyUnderutilized(samples []UtilizationSample, fundedStorage uint64) bool {
+	if len(samples) < minUnderutilizedSamples {
+		return false
+	}
+	for _, sample := range samples[len(samples)-minUnderutilizedSamples:] {
+		if ratio(sample.UsedStorage, fundedStorage) >= underutilizedStorageRatio {
+			return false
+		}
+	}
+	return true
+}
+
+// utilizationTrend returns the storage growth and the fund spent per block between the
+// first and the last samples
+func utilizationTrend(samples []UtilizationSample) (storageGrowth, spendRate float64) {
+	if len(samples) < 2 {
+		return
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	if last.BlockHeight <= first.BlockHeight {
+		return
+	}
+	blocks := float64(last.BlockHeight - first.BlockHeight)
+	if last.UsedStorage > first.UsedStorage {
+		storageGrowth = float64(last.UsedStorage-first.UsedStorage) / blocks
+	}
+	if last.SpentFund.Cmp(first.SpentFund) > 0 {
+		spendRate = last.SpentFund.Sub(first.SpentFund).Float64() / blocks
+	}
+	return
+}
+
+// ratio returns used / total, and 0 if total is 0
+func ratio(used, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total)
+}
@@ -0,0 +1,139 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package contractmanager
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+)
+
+func TestContractManager_ContractUtilization(t *testing.T) {
+	dir, err := ioutil.TempDir("", "utilization")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cs, err := contractset.New(dir, "")
+	if err != nil {
+		t.Fatalf("failed to create contract set: %s", err.Error())
+	}
+	defer cs.Close()
+
+	// each contract is funded with 1000 bytes and 1000 of fund
+	cm := &ContractManager{
+		activeContracts: cs,
+		utilization:     make(map[storage.ContractID][]UtilizationSample),
+		rentPayment: storage.RentPayment{
+			Fund:               common.NewBigIntUint64(3000),
+			StorageHosts:       3,
+			Period:             1000,
+			ExpectedStorage:    1500,
+			ExpectedRedundancy: 2,
+		},
+	}
+	if _, err = cm.ContractUtilization(2); err != nil {
+		t.Fatalf("failed to report the utilization without contracts: %s", err.Error())
+	}
+
+	// the contracts are normal, underutilized and exhausted
+	usedStorage := []uint64{500, 100, 950}
+	var ids []storage.ContractID
+	for i, used := range usedStorage {
+		ch := randomContractGenerator(2000)
+		ch.StartHeight = uint64(i)
+		ch.TotalCost = common.NewBigIntUint64(1000)
+		ch.LatestContractRevision.NewFileSize = used
+		ch.LatestContractRevision.NewValidProofOutputs[0].Value = big.NewInt(int64(1000 - used))
+		if _, err = cs.InsertContract(ch, randomRootsGenerator(1)); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, ch.ID)
+	}
+
+	// the contracts are sampled once in each interval
+	for height := uint64(0); height < minUnderutilizedSamples*utilizationSampleInterval; height++ {
+		cm.blockHeight = height
+		cm.sampleUtilization()
+	}
+	for _, id := range ids {
+		if len(cm.utilization[id]) != minUnderutilizedSamples {
+			t.Fatalf("expect %v samples, got %v", minUnderutilizedSamples, len(cm.utilization[id]))
+		}
+	}
+
+	report, err := cm.ContractUtilization(2)
+	if err != nil {
+		t.Fatalf("failed to report the utilization: %s", err.Error())
+	}
+	if len(report.Contracts) != 3 || report.Underutilized != 1 || report.Exhausted != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	expectStatus := []string{UtilizationNormal, UtilizationUnderutilized, UtilizationExhausted}
+	for i, cu := range report.Contracts {
+		if cu.ContractID != ids[i] || cu.Status != expectStatus[i] {
+			t.Errorf("contract %v: expect status %v, got %v", i, expectStatus[i], cu.Status)
+		}
+		if cu.FundedStorage != 1000 || cu.UsedStorage != usedStorage[i] {
+			t.Errorf("contract %v: unexpected storage %v/%v", i, cu.UsedStorage, cu.FundedStorage)
+		}
+	}
+
+	// no growth in the samples, the expected storage is the data stored without redundancy,
+	// and the host of the underutilized contract is not needed
+	if report.SuggestedExpectedStorage != 775 {
+		t.Errorf("expect suggested expected storage 775, got %v", report.SuggestedExpectedStorage)
+	}
+	if report.SuggestedStorageHosts != 2 {
+		t.Errorf("expect suggested storage hosts 2, got %v", report.SuggestedStorageHosts)
+	}
+	if report.SuggestedFund.Cmp(common.NewBigIntUint64(2000)) != 0 {
+		t.Errorf("expect suggested fund 2000, got %v", report.SuggestedFund)
+	}
+
+	// the suggested storage hosts are no less than the sectors of the erasure code
+	if report, err = cm.ContractUtilization(3); err != nil {
+		t.Fatal(err)
+	}
+	if report.SuggestedStorageHosts != 3 || report.SuggestedFund.Cmp(common.NewBigIntUint64(3000)) != 0 {
+		t.Errorf("expect suggested storage hosts 3 and fund 3000, got %v and %v", report.SuggestedStorageHosts, report.SuggestedFund)
+	}
+
+	// the contract younger than a sampling window is ignored, even if exhausted
+	young := randomContractGenerator(2000)
+	young.StartHeight = cm.blockHeight
+	young.TotalCost = common.NewBigIntUint64(1000)
+	young.LatestContractRevision.NewFileSize = 1000
+	young.LatestContractRevision.NewValidProofOutputs[0].Value = big.NewInt(0)
+	if _, err = cs.InsertContract(young, randomRootsGenerator(1)); err != nil {
+		t.Fatal(err)
+	}
+	cm.sampleUtilization()
+	if report, err = cm.ContractUtilization(2); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Contracts) != 3 || report.Exhausted != 1 || report.SuggestedExpectedStorage != 775 {
+		t.Errorf("the young contract should be ignored, got %+v", report)
+	}
+
+	// the samples of the contracts no longer active are removed
+	c, exist := cs.Acquire(ids[0])
+	if !exist {
+		t.Fatalf("contract %v not found", ids[0])
+	}
+	if err = cs.Delete(c); err != nil {
+		t.Fatal(err)
+	}
+	cm.sampleUtilization()
+	if _, exist := cm.utilization[ids[0]]; exist {
+		t.Errorf("the samples of the deleted contract are not removed")
+	}
+}
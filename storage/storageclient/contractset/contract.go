@@ -5,7 +5,6 @@
 package contractset
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
@@ -207,7 +206,7 @@ func (c *Contract) CommitTxns() (err error) {
 			// update contract header
 			case dbContractHeader:
 				var walHeader walContractHeaderEntry
-				if err = json.Unmarshal(op.Data, &walHeader); err != nil {
+				if err = c.db.unmarshal(op.Data, &walHeader); err != nil {
 					return
 				}
 				if err = c.contractHeaderUpdate(walHeader.Header); err != nil {
@@ -215,7 +214,7 @@ func (c *Contract) CommitTxns() (err error) {
 				}
 			case dbMerkleRoot:
 				var walRoot walRootsEntry
-				if err = json.Unmarshal(op.Data, &walRoot); err != nil {
+				if err = c.db.unmarshal(op.Data, &walRoot); err != nil {
 					return
 				}
 				if err = c.merkleRoots.push(walRoot.Root); err != nil {
@@ -239,7 +238,7 @@ func (c *Contract) RollbackUndoWal(t *writeaheadlog.Transaction) (err error) {
 		switch op.Name {
 		case dbContractHeader:
 			var walHeader walContractHeaderEntry
-			if err = json.Unmarshal(op.Data, &walHeader); err != nil {
+			if err = c.db.unmarshal(op.Data, &walHeader); err != nil {
 				return
 			}
 			if err = c.contractHeaderUpdate(walHeader.Header); err != nil {
@@ -332,7 +331,7 @@ func (c *Contract) contractHeaderWalOP(ch ContractHeader) (op writeaheadlog.Oper
 	c.headerLock.Unlock()
 
 	// json encode the data that is going to be saved in the wal
-	data, err := json.Marshal(walContractHeaderEntry{
+	data, err := c.db.marshal(walContractHeaderEntry{
 		ID:     contractID,
 		Header: ch,
 	})
@@ -360,7 +359,7 @@ func (c *Contract) merkleRootWalOP(root common.Hash, rootCount int) (op writeahe
 	c.headerLock.Unlock()
 
 	// json encode the data that is going to be saved in the wal
-	data, err := json.Marshal(walRootsEntry{
+	data, err := c.db.marshal(walRootsEntry{
 		ID:    contractID,
 		Index: uint64(rootCount),
 		Root:  root,
@@ -392,3 +391,10 @@ func (c *Contract) Header() ContractHeader {
 func (c *Contract) MerkleRoots() ([]common.Hash, error) {
 	return c.merkleRoots.roots()
 }
+
+// UpdateMerkleRoots will replace the merkle roots of the contract in both db and memory,
+// after the sectors stored by the storage host are changed by the revision. The contract
+// must be acquired by the caller
+func (c *Contract) UpdateMerkleRoots(roots []common.Hash) (err error) {
+	return c.merkleRoots.replace(roots)
+}
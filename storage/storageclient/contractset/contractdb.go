@@ -6,10 +6,10 @@ package contractset
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/crypto"
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
@@ -19,6 +19,10 @@ import (
 // DB is the database wrapper that is used to store the contract set information
 type DB struct {
 	lvl *leveldb.DB
+
+	// cipherKey is used to encrypt the entries stored. If nil, the entries
+	// will be stored in plain text
+	cipherKey crypto.CipherKey
 }
 
 // OpenDB will create a new level db. If the db already existed,
@@ -108,7 +112,7 @@ func (db *DB) FetchAll() (chs []ContractHeader, allRoots [][]common.Hash, err er
 
 		// get the contract header value
 		if bytes.HasSuffix(iter.Key(), []byte(dbContractHeader)) {
-			if err = json.Unmarshal(iter.Value(), &ch); err != nil {
+			if err = db.unmarshal(iter.Value(), &ch); err != nil {
 				return
 			}
 
@@ -117,7 +121,7 @@ func (db *DB) FetchAll() (chs []ContractHeader, allRoots [][]common.Hash, err er
 		}
 
 		if bytes.HasSuffix(iter.Key(), []byte(dbMerkleRoot)) {
-			if err = json.Unmarshal(iter.Value(), &roots); err != nil {
+			if err = db.unmarshal(iter.Value(), &roots); err != nil {
 				return
 			}
 			allRoots = append(allRoots, roots)
@@ -137,7 +141,7 @@ func (db *DB) FetchAllHeader() (chs []ContractHeader, err error) {
 
 		// has suffix, contract header information
 		var ch ContractHeader
-		if err = json.Unmarshal(iter.Value(), &ch); err != nil {
+		if err = db.unmarshal(iter.Value(), &ch); err != nil {
 			return
 		}
 
@@ -156,7 +160,7 @@ func (db *DB) FetchAllRoots() (allRoots [][]common.Hash, err error) {
 
 		// has suffix, merkle roots information
 		var roots []common.Hash
-		if err = json.Unmarshal(iter.Value(), &roots); err != nil {
+		if err = db.unmarshal(iter.Value(), &roots); err != nil {
 			return
 		}
 
@@ -186,7 +190,7 @@ func (db *DB) StoreContractHeader(ch ContractHeader) (err error) {
 	if err != nil {
 		return
 	}
-	blob, err := json.Marshal(ch)
+	blob, err := db.marshal(ch)
 	if err != nil {
 		return
 	}
@@ -206,7 +210,7 @@ func (db *DB) StoreMerkleRoots(id storage.ContractID, roots []common.Hash) (err
 	if err != nil {
 		return
 	}
-	blob, err := json.Marshal(roots)
+	blob, err := db.marshal(roots)
 	if err != nil {
 		return
 	}
@@ -255,7 +259,7 @@ func (db *DB) FetchContractHeader(id storage.ContractID) (ch ContractHeader, err
 	}
 
 	// json decode the data
-	if err = json.Unmarshal(blob, &ch); err != nil {
+	if err = db.unmarshal(blob, &ch); err != nil {
 		return
 	}
 
@@ -278,7 +282,7 @@ func (db *DB) FetchMerkleRoots(id storage.ContractID) (roots []common.Hash, err
 	}
 
 	// json decode the data
-	if err = json.Unmarshal(blob, &roots); err != nil {
+	if err = db.unmarshal(blob, &roots); err != nil {
 		return
 	}
 	return
@@ -42,7 +42,9 @@ type ContractHeader struct {
 	Status storage.ContractStatus
 }
 
-func (ch *ContractHeader) validation() (err error) {
+// Validate checks whether the contract header contains the latest revision with the
+// proof outputs and the payment addresses
+func (ch *ContractHeader) Validate() (err error) {
 	if ch.LatestContractRevision.NewRevisionNumber > 0 &&
 		len(ch.LatestContractRevision.NewValidProofOutputs) > 0 &&
 		len(ch.LatestContractRevision.UnlockConditions.PaymentAddresses) == 2 {
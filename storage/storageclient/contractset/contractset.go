@@ -30,8 +30,9 @@ type StorageContractSet struct {
 }
 
 // New will initialize the StorageContractSet object, as well as
-// loading the data that already stored in the database
-func New(persistDir string) (scs *StorageContractSet, err error) {
+// loading the data that already stored in the database. If the passphrase is
+// provided, contracts will be encrypted at rest with the key derived from it
+func New(persistDir string, passphrase string) (scs *StorageContractSet, err error) {
 	// initialize the directory
 	if err = os.MkdirAll(persistDir, 0700); err != nil {
 		err = fmt.Errorf("error initializing directory: %s", err.Error())
@@ -45,6 +46,18 @@ func New(persistDir string) (scs *StorageContractSet, err error) {
 		return
 	}
 
+	// derive the encryption key, and encrypt the contracts stored in plain text
+	if db.cipherKey, err = loadCipherKey(persistDir, passphrase); err != nil {
+		db.Close()
+		err = fmt.Errorf("error loading contract set key: %s", err.Error())
+		return
+	}
+	if err = db.encryptAll(); err != nil {
+		db.Close()
+		err = fmt.Errorf("error encrypting contract set: %s", err.Error())
+		return
+	}
+
 	// initialize wal
 	wal, walTxns, err := writeaheadlog.New(filepath.Join(persistDir, persistWalName))
 	if err != nil {
@@ -89,7 +102,7 @@ func (scs *StorageContractSet) EmptyDB() (err error) {
 // allow contract manager further to maintain them
 func (scs *StorageContractSet) InsertContract(ch ContractHeader, roots []common.Hash) (cm storage.ContractMetaData, err error) {
 	// contract header validation
-	if err = ch.validation(); err != nil {
+	if err = ch.Validate(); err != nil {
 		return
 	}
 
@@ -104,6 +117,10 @@ func (scs *StorageContractSet) InsertContract(ch ContractHeader, roots []common.
 	merkleRoots := newMerkleRoots(scs.db, ch.ID)
 	for _, root := range roots {
 		if err = merkleRoots.push(root); err != nil {
+			// remove the contract partially stored
+			if errDelete := scs.db.DeleteHeaderAndRoots(ch.ID); errDelete != nil {
+				err = common.ErrCompose(err, errDelete)
+			}
 			return
 		}
 	}
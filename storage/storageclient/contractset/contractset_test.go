@@ -26,7 +26,7 @@ func TestStorageContractSet_New(t *testing.T) {
 		}
 
 		// initialize storage contract set
-		scs, err := New(persistDir)
+		scs, err := New(persistDir, "")
 		if err != nil {
 			t.Fatalf("failed to initialize storage contract set: %s", err.Error())
 		}
@@ -94,7 +94,7 @@ func TestStorageContractSet_New(t *testing.T) {
 }
 
 func TestStorageContractSet_InsertContract(t *testing.T) {
-	scs, err := New(persistDir)
+	scs, err := New(persistDir, "")
 	if err != nil {
 		t.Fatalf("failed to initialize storage contract set: %s", err.Error())
 	}
@@ -142,7 +142,7 @@ func TestStorageContractSet_InsertContract(t *testing.T) {
 func TestStorageContractSet_InsertContractMultiRoutines(t *testing.T) {
 	var wg sync.WaitGroup
 
-	scs, err := New(persistDir)
+	scs, err := New(persistDir, "")
 
 	if err != nil {
 		t.Fatalf("failed to initialize storage contract set: %s", err.Error())
@@ -211,7 +211,7 @@ func TestStorageContractSet_InsertContractMultiRoutines(t *testing.T) {
 }
 
 func TestStorageContractSet_Acquire(t *testing.T) {
-	scs, err := New(persistDir)
+	scs, err := New(persistDir, "")
 
 	if err != nil {
 		t.Fatalf("failed to initialize storage contract set: %s", err.Error())
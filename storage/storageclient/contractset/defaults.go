@@ -12,6 +12,7 @@ import (
 const (
 	persistDBName  = "contractsetdb"
 	persistWalName = "contractset.wal"
+	persistKeyName = "contractset.key"
 
 	dbContractHeader = ":contractheader"
 	dbMerkleRoot     = ":roots"
@@ -30,6 +31,25 @@ const (
 	remainingFile = -1
 )
 
+// encryption related constants. The scrypt parameters are the same as the ones used
+// by the keystore
+const (
+	keySaltSize = 32
+	keyLength   = 32
+	keyScryptN  = 1 << 18
+	keyScryptR  = 8
+	keyScryptP  = 1
+)
+
+var (
+	// encryptedPrefix is added in front of the encrypted entries, which distinguishes them
+	// from the json encoded plain text entries
+	encryptedPrefix = []byte{0x00, 'd', 'x', 'e'}
+
+	// keyCheckPlainText is encrypted and persisted to verify the passphrase provided
+	keyCheckPlainText = []byte("dxchain contract set")
+)
+
 // sectorHeight is the height of the merkle tree constructed
 // based on the data uploaded. Data uploaded will be divided
 // into data pieces based on the LeafSize
@@ -0,0 +1,172 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package contractset
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/syndtr/goleveldb/leveldb"
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	// errPassphraseRequired is returned when the contract set is encrypted but no passphrase is provided
+	errPassphraseRequired = errors.New("the contract set is encrypted, passphrase is required")
+
+	// errWrongPassphrase is returned when the key derived from the passphrase cannot decrypt the key check
+	errWrongPassphrase = errors.New("could not decrypt the contract set with the passphrase provided")
+
+	// errContractSetLocked is returned when an encrypted entry is read without the cipher key
+	errContractSetLocked = errors.New("the contract set entry is encrypted, passphrase is required")
+)
+
+// keyMetadata is the metadata of the persisted contract set key file
+var keyMetadata = common.Metadata{
+	Header:  "Storage Contract Set Key",
+	Version: "1.0",
+}
+
+// persistKey is the persisted information used to derive and verify the key
+// that encrypts the contract set at rest. The key itself is never persisted
+type persistKey struct {
+	Salt     []byte `json:"salt"`
+	ScryptN  int    `json:"scryptn"`
+	ScryptR  int    `json:"scryptr"`
+	ScryptP  int    `json:"scryptp"`
+	KeyCheck []byte `json:"keycheck"`
+}
+
+// loadCipherKey derives the cipher key used to encrypt the contract set from the passphrase.
+// If the key file does not exist and the passphrase is provided, a new key file will be created,
+// meaning the encryption is enabled. If the key file does not exist and no passphrase is
+// provided, nil cipher key is returned and the contract set will be stored in plain text
+func loadCipherKey(persistDir, passphrase string) (ck crypto.CipherKey, err error) {
+	filename := filepath.Join(persistDir, persistKeyName)
+
+	var pk persistKey
+	err = common.LoadDxJSON(keyMetadata, filename, &pk)
+	if os.IsNotExist(err) {
+		if passphrase == "" {
+			return nil, nil
+		}
+		return newCipherKey(filename, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the contract set key file: %s", err.Error())
+	}
+
+	if passphrase == "" {
+		return nil, errPassphraseRequired
+	}
+	if ck, err = deriveCipherKey(passphrase, pk); err != nil {
+		return nil, err
+	}
+	check, err := ck.Decrypt(pk.KeyCheck)
+	if err != nil || !bytes.Equal(check, keyCheckPlainText) {
+		return nil, errWrongPassphrase
+	}
+	return ck, nil
+}
+
+// newCipherKey generates a random salt, derives the cipher key from the passphrase, and saves
+// the information needed to derive the key again into the key file
+func newCipherKey(filename, passphrase string) (ck crypto.CipherKey, err error) {
+	pk := persistKey{
+		Salt:    make([]byte, keySaltSize),
+		ScryptN: keyScryptN,
+		ScryptR: keyScryptR,
+		ScryptP: keyScryptP,
+	}
+	if _, err = rand.Read(pk.Salt); err != nil {
+		return
+	}
+	if ck, err = deriveCipherKey(passphrase, pk); err != nil {
+		return
+	}
+	if pk.KeyCheck, err = ck.Encrypt(keyCheckPlainText); err != nil {
+		return
+	}
+	if err = common.SaveDxJSON(keyMetadata, filename, pk); err != nil {
+		err = fmt.Errorf("failed to save the contract set key file: %s", err.Error())
+		return
+	}
+	return
+}
+
+// deriveCipherKey derives the twofish-gcm cipher key from the passphrase with scrypt
+func deriveCipherKey(passphrase string, pk persistKey) (crypto.CipherKey, error) {
+	key, err := scrypt.Key([]byte(passphrase), pk.Salt, pk.ScryptN, pk.ScryptR, pk.ScryptP, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the contract set key: %s", err.Error())
+	}
+	return crypto.NewCipherKey(crypto.GCMCipherCode, key)
+}
+
+// marshal json encodes the value, and encrypts it if the cipher key is set
+func (db *DB) marshal(v interface{}) (blob []byte, err error) {
+	if blob, err = json.Marshal(v); err != nil || db.cipherKey == nil {
+		return
+	}
+	return db.encrypt(blob)
+}
+
+// unmarshal decodes the blob stored in the database or the wal. Both the encrypted
+// blob and the plain text blob stored before the encryption was enabled are supported
+func (db *DB) unmarshal(blob []byte, v interface{}) (err error) {
+	if bytes.HasPrefix(blob, encryptedPrefix) {
+		if db.cipherKey == nil {
+			return errContractSetLocked
+		}
+		if blob, err = db.cipherKey.Decrypt(blob[len(encryptedPrefix):]); err != nil {
+			return fmt.Errorf("failed to decrypt the contract set entry: %s", err.Error())
+		}
+	}
+	return json.Unmarshal(blob, v)
+}
+
+// encrypt encrypts the plain text and adds the encrypted prefix to it
+func (db *DB) encrypt(plainText []byte) ([]byte, error) {
+	cipherText, err := db.cipherKey.Encrypt(plainText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt the contract set entry: %s", err.Error())
+	}
+	return append(append([]byte{}, encryptedPrefix...), cipherText...), nil
+}
+
+// encryptAll encrypts all entries that are still stored in plain text. It is used
+// when the encryption is enabled on a contract set that already has contracts
+func (db *DB) encryptAll() (err error) {
+	if db.cipherKey == nil {
+		return
+	}
+
+	batch := new(leveldb.Batch)
+	iter := db.lvl.NewIterator(nil, nil)
+	for iter.Next() {
+		if bytes.HasPrefix(iter.Value(), encryptedPrefix) {
+			continue
+		}
+		var blob []byte
+		if blob, err = db.encrypt(iter.Value()); err != nil {
+			iter.Release()
+			return
+		}
+		batch.Put(append([]byte{}, iter.Key()...), blob)
+	}
+	iter.Release()
+	if err = iter.Error(); err != nil {
+		return
+	}
+
+	return db.lvl.Write(batch, nil)
+}
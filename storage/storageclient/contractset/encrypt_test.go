@@ -0,0 +1,82 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package contractset
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestStorageContractSet_Encryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "contractset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// contracts stored in plain text before the encryption is enabled
+	chs, roots, err := fillDB(dir, 3, 10)
+	if err != nil {
+		t.Fatalf("error inserting the contract into db: %s", err.Error())
+	}
+
+	passphrase := "contract set passphrase"
+	scs, err := New(dir, passphrase)
+	if err != nil {
+		t.Fatalf("failed to initialize storage contract set: %s", err.Error())
+	}
+	if len(scs.contracts) != len(chs) {
+		t.Fatalf("expected number of contracts %v, got %v", len(chs), len(scs.contracts))
+	}
+
+	// all entries should be encrypted after the encryption is enabled
+	iter := scs.db.lvl.NewIterator(nil, nil)
+	for iter.Next() {
+		if !bytes.HasPrefix(iter.Value(), encryptedPrefix) {
+			t.Errorf("entry %x is not encrypted", iter.Key())
+		}
+	}
+	iter.Release()
+	if err = scs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// open without or with the wrong passphrase
+	if _, err = New(dir, ""); err == nil {
+		t.Fatalf("opening encrypted contract set without passphrase should return an error")
+	}
+	if _, err = New(dir, "wrong passphrase"); err == nil {
+		t.Fatalf("opening encrypted contract set with wrong passphrase should return an error")
+	}
+
+	// reopen with the passphrase, the contracts should be the same
+	if scs, err = New(dir, passphrase); err != nil {
+		t.Fatalf("failed to reopen storage contract set: %s", err.Error())
+	}
+	defer scs.Close()
+	for i, ch := range chs {
+		c, exists := scs.contracts[ch.ID]
+		if !exists {
+			t.Fatalf("the contract id %v is not loaded", ch.ID)
+		}
+		if c.header.PrivateKey != ch.PrivateKey {
+			t.Errorf("contract header not match after decryption")
+		}
+		loaded, err := scs.db.FetchMerkleRoots(ch.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(loaded) != len(roots[i]) {
+			t.Fatalf("expected number of roots %v, got %v", len(roots[i]), len(loaded))
+		}
+		for j := range loaded {
+			if loaded[j] != roots[i][j] {
+				t.Errorf("merkle root %v not match after decryption", j)
+			}
+		}
+	}
+}
@@ -89,6 +89,22 @@ func (mr *merkleRoots) push(root common.Hash) (err error) {
 	return
 }
 
+// replace will store the roots passed in into the database in place of the roots
+// stored before, then the merkle roots in memory will be rebuilt
+func (mr *merkleRoots) replace(roots []common.Hash) (err error) {
+	if err = mr.db.StoreMerkleRoots(mr.id, roots); err != nil {
+		return
+	}
+
+	mr.cachedSubTrees = nil
+	mr.uncachedRoots = nil
+	if err = mr.appendRootMemory(roots...); err != nil {
+		return
+	}
+	mr.numMerkleRoots = len(roots)
+	return
+}
+
 // appendRootMemory will store the root in the uncached roots field
 // if the number of uncached roots reached a limit, then those
 // roots will be build up to a cachedSubTree
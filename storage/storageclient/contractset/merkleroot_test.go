@@ -156,6 +156,50 @@ func TestMerkleRoot_newMerkleRootPreview(t *testing.T) {
 	}
 }
 
+func TestMerkleRoot_Replace(t *testing.T) {
+	id := storageContractIDGenerator()
+	mk, err := newTestMerkleRoots(id)
+	if err != nil {
+		t.Fatalf("failed to create and initialize: %s", err.Error())
+	}
+	defer mk.db.Close()
+	defer mk.db.EmptyDB()
+
+	for _, r := range rootsGenerator(300) {
+		if err := mk.push(r); err != nil {
+			t.Fatalf("failed to push the root %v: %s", r, err.Error())
+		}
+	}
+
+	replaced := rootsGenerator(130)
+	if err := mk.replace(replaced); err != nil {
+		t.Fatalf("failed to replace the roots: %s", err.Error())
+	}
+	fetched, err := mk.roots()
+	if err != nil {
+		t.Fatalf("failed to fetch the roots: %s", err.Error())
+	}
+	if len(fetched) != len(replaced) || len(mk.cachedSubTrees) != 1 || len(mk.uncachedRoots) != 2 {
+		t.Fatalf("the roots are not replaced, got %v roots, %v cached sub trees, %v uncached roots",
+			len(fetched), len(mk.cachedSubTrees), len(mk.uncachedRoots))
+	}
+	for i, r := range fetched {
+		if replaced[i] != r {
+			t.Fatalf("the root stored does not match. Expected %v, got %v", replaced[i], r)
+		}
+	}
+
+	// the merkle root preview is calculated from the replaced roots
+	newRoot := randomHashGenerator()
+	mroot, err := mk.newMerkleRootPreview(newRoot)
+	if err != nil {
+		t.Fatalf("failed to preview the new merkle root: %s", err.Error())
+	}
+	if expected := merkle.Sha256CachedTreeRoot2(append(replaced, newRoot)); mroot != expected {
+		t.Fatalf("the merkle root does not match. Expected %v, got %v", expected, mroot)
+	}
+}
+
 /*
  _____  _____  _______      __  _______ ______      ______ _    _ _   _  _____ _______ _____ ____  _   _
 |  __ \|  __ \|_   _\ \    / /\|__   __|  ____|    |  ____| |  | | \ | |/ ____|__   __|_   _/ __ \| \ | |
@@ -6,6 +6,8 @@ package storageclient
 
 import (
 	"time"
+
+	"github.com/DxChainNetwork/godx/storage"
 )
 
 // Files and directories related constant
@@ -14,6 +16,56 @@ const (
 	PersistFilename             = "storageclient.json"
 	PersistStorageClientVersion = "1.0"
 	DxPathRoot                  = "dxfiles"
+	ProfileVersion              = "1.0"
+	BackupVersion               = "1.0"
+
+	// gatewayStagingDir is the directory where the content uploaded through the gateway is staged
+	gatewayStagingDir = "gateway"
+
+	// gatewayTokenFile is the file saving the bearer token required by the gateway
+	gatewayTokenFile = "gateway.token"
+
+	// s3MultipartDir is the directory where the parts of the S3 multipart uploads are saved
+	s3MultipartDir = "s3multipart"
+
+	// s3CredentialsFile is the file saving the access key used to sign the S3 requests
+	s3CredentialsFile = "s3gateway.credentials"
+
+	// mountCacheDir is the directory caching the content of the files opened in the mounted filesystems
+	mountCacheDir = "mount"
+
+	// alertModule is the module name of the alerts raised by the storage client
+	alertModule = "storageclient"
+
+	// operationAuditDBName is the database recording the audit entries of the upload and download operations
+	operationAuditDBName = "operationaudit"
+)
+
+// maxStatusErrors is the maximum number of the recent errors in the client status
+const maxStatusErrors = 10
+
+// contractCreationWindow is the number of blocks after the start height of a contract, within
+// which the contract not found on chain is treated as pending instead of inconsistent
+var contractCreationWindow = storage.BlockPerHour
+
+// protocol conformance related constants
+const (
+	// conformanceResponseTimeout is the longest time allowed for the host to respond in a
+	// conformance scenario
+	conformanceResponseTimeout = 20 * time.Second
+
+	// conformanceConfigRequests is the number of consecutive config requests sent to the host
+	conformanceConfigRequests = 3
+)
+
+// backup encryption related constants, the scrypt parameters are the same as the ones
+// used by the keystore
+const (
+	backupSaltSize  = 32
+	backupKeyLength = 32
+	backupScryptN   = 1 << 18
+	backupScryptR   = 8
+	backupScryptP   = 1
 )
 
 // StorageClient Settings, where 0 means unlimited
@@ -22,6 +74,10 @@ const (
 	DefaultMaxUploadSpeed   = 0
 	DefaultPacketSize       = 4 * 4096
 
+	// DefaultDownloadOverdrive is the number of extra sector fetches issued for every
+	// segment downloaded, if not configured
+	DefaultDownloadOverdrive = 3
+
 	// frequency to check whether storage client is online
 	OnlineCheckFrequency = time.Second * 10
 
@@ -33,12 +89,41 @@ const (
 
 	// how many times a bad host's timeout/cool down can be doubled before a maximum cool down is reached.
 	MaxConsecutivePenalty = 10
+
+	// the amount of time to wait for the gateway requests in progress when shutting down
+	gatewayShutdownTimeout = time.Second * 5
+
+	// gatewayMaxUploadSize is the maximum size in bytes of the content uploaded through the
+	// gateway in a request
+	gatewayMaxUploadSize = int64(16 << 30)
+
+	// frequency to check whether the storage class transitions are completed
+	transitionCheckInterval = time.Minute
+
+	// s3MaxKeys is the maximum number of keys responded in a single list objects request,
+	// and s3MaxParts is the maximum number of parts in a multipart upload
+	s3MaxKeys  = 1000
+	s3MaxParts = 10000
+
+	// s3MaxTimeSkew is the maximum difference between the time the S3 request is signed
+	// and the local time, and s3MaxPresignExpiry is the maximum expiry of the presigned URLs
+	s3MaxTimeSkew      = 15 * time.Minute
+	s3MaxPresignExpiry = 7 * 24 * time.Hour
 )
 
 const (
 	// DefaultMaxMemory available
 	DefaultMaxMemory = uint64(3 * 1 << 28)
 	extraRatio       = 0.02
+
+	// DefaultBufferPoolLimit is the maximum bytes of the idle sector and segment buffers kept for reuse
+	DefaultBufferPoolLimit = uint64(1 << 28)
+)
+
+const (
+	// latencyEWMAWeight is the weight of the latest observation when updating the
+	// host download latency and throughput
+	latencyEWMAWeight = 0.2
 )
 
 // Default params about upload/download process
@@ -72,4 +157,4 @@ var (
 )
 
 var keys = []string{"fund", "hosts", "period", "renew", "storage", "upload", "download",
-	"redundancy", "violation", "uploadspeed", "downloadspeed"}
+	"redundancy", "violation", "uploadspeed", "downloadspeed", "overdrive"}
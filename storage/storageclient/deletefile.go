@@ -0,0 +1,229 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxfile"
+)
+
+var (
+	errRootsNotSynced  = errors.New("the merkle roots of the contract are not in sync with the revision, resync with the host first")
+	errNoSectorsStored = errors.New("no sectors of the file are stored in the contract")
+)
+
+type (
+	// FileDeletionReport is the storage and the deposit reclaimed by removing the sectors of
+	// the deleted file from the contracts with the storage hosts
+	FileDeletionReport struct {
+		DxPath           string               `json:"dxpath"`
+		Sectors          int                  `json:"sectors"`
+		RemovedSectors   uint64               `json:"removedSectors"`
+		ReclaimedStorage uint64               `json:"reclaimedStorage"`
+		ReleasedDeposit  common.BigInt        `json:"releasedDeposit"`
+		Hosts            []HostDeletionReport `json:"hosts"`
+	}
+
+	// HostDeletionReport is the result of removing the sectors of the deleted file from the
+	// contract with a storage host. The storage reclaimed is no longer charged when the
+	// contract is renewed, and the deposit released is no longer risked by the host
+	HostDeletionReport struct {
+		HostID           string        `json:"hostID"`
+		ContractID       string        `json:"contractID"`
+		Sectors          int           `json:"sectors"`
+		RemovedSectors   uint64        `json:"removedSectors"`
+		ReclaimedStorage uint64        `json:"reclaimedStorage"`
+		ReleasedDeposit  common.BigInt `json:"releasedDeposit"`
+		Err              string        `json:"error,omitempty"`
+	}
+)
+
+// DeleteFile will delete the file from the file system, and the file will also be deleted
+// from the disk. The sectors of the file are removed from the contracts in background
+func (client *StorageClient) DeleteFile(path storage.DxPath) error {
+	if err := client.tm.Add(); err != nil {
+		return err
+	}
+	defer client.tm.Done()
+
+	sectors, err := client.deleteFile(path)
+	if err != nil {
+		return err
+	}
+	client.removeFileSectorsInBackground(path, sectors)
+	return nil
+}
+
+// DeleteFileAndReclaim will delete the file from the file system, and remove the sectors of
+// the file from the contracts with the storage hosts. The storage and the deposit reclaimed
+// are reported
+func (client *StorageClient) DeleteFileAndReclaim(path storage.DxPath) (report FileDeletionReport, err error) {
+	if err = client.tm.Add(); err != nil {
+		return
+	}
+	defer client.tm.Done()
+
+	sectors, err := client.deleteFile(path)
+	if err != nil {
+		return
+	}
+	return client.removeFileSectors(path, sectors), nil
+}
+
+// deleteFile deletes the file from the file system, and returns the merkle roots of the
+// sectors of the file grouped by the storage hosts storing them
+func (client *StorageClient) deleteFile(path storage.DxPath) (map[enode.ID][]common.Hash, error) {
+	info := HookInfo{Operation: HookDelete, DxPath: path.Path}
+	if err := client.hooks.runPreHooks(info); err != nil {
+		return nil, err
+	}
+
+	// deleting the file not exist is not an error
+	sectors, err := client.fileSectors(path)
+	if err == dxfile.ErrUnknownFile {
+		sectors, err = nil, nil
+	} else if err == nil {
+		err = client.fileSystem.DeleteDxFile(path)
+	}
+	client.hooks.runPostHooks(info, err)
+	return sectors, err
+}
+
+// fileSectors returns the merkle roots of the sectors of the file grouped by the storage hosts
+func (client *StorageClient) fileSectors(path storage.DxPath) (map[enode.ID][]common.Hash, error) {
+	entry, err := client.fileSystem.OpenDxFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer entry.Close()
+
+	sectors := make(map[enode.ID][]common.Hash)
+	for i := 0; i < entry.NumSegments(); i++ {
+		segmentSectors, err := entry.Sectors(i)
+		if err != nil {
+			return nil, err
+		}
+		for _, sectorList := range segmentSectors {
+			for _, sector := range sectorList {
+				sectors[sector.HostID] = append(sectors[sector.HostID], sector.MerkleRoot)
+			}
+		}
+	}
+	return sectors, nil
+}
+
+// removeFileSectorsInBackground removes the sectors of the deleted file from the contracts
+// in background, and logs the storage and the deposit reclaimed
+func (client *StorageClient) removeFileSectorsInBackground(path storage.DxPath, sectors map[enode.ID][]common.Hash) {
+	if len(sectors) == 0 {
+		return
+	}
+	go func() {
+		if err := client.tm.Add(); err != nil {
+			return
+		}
+		defer client.tm.Done()
+
+		report := client.removeFileSectors(path, sectors)
+		client.log.Info("removed the sectors of the deleted file from the contracts", "path", path.Path, "sectors", report.Sectors,
+			"removed", report.RemovedSectors, "reclaimed", report.ReclaimedStorage, "deposit", report.ReleasedDeposit)
+	}()
+}
+
+// removeFileSectors removes the sectors of the deleted file from the contracts with each
+// storage host, and reports the storage and the deposit reclaimed
+func (client *StorageClient) removeFileSectors(path storage.DxPath, sectors map[enode.ID][]common.Hash) FileDeletionReport {
+	report := FileDeletionReport{DxPath: path.Path}
+
+	hostIDs := make([]enode.ID, 0, len(sectors))
+	for hostID := range sectors {
+		hostIDs = append(hostIDs, hostID)
+	}
+	sort.Slice(hostIDs, func(i, j int) bool {
+		return bytes.Compare(hostIDs[i].Bytes(), hostIDs[j].Bytes()) < 0
+	})
+
+	for _, hostID := range hostIDs {
+		hr := client.removeHostSectors(hostID, sectors[hostID])
+		if hr.Err != "" {
+			client.log.Warn("failed to remove the sectors of the deleted file", "path", path.Path, "host", hostID, "err", hr.Err)
+		}
+		report.Sectors += hr.Sectors
+		report.RemovedSectors += hr.RemovedSectors
+		report.ReclaimedStorage += hr.ReclaimedStorage
+		report.ReleasedDeposit = report.ReleasedDeposit.Add(hr.ReleasedDeposit)
+		report.Hosts = append(report.Hosts, hr)
+	}
+	return report
+}
+
+// removeHostSectors negotiates with the storage host to remove the sectors from the contract.
+// The sectors are swapped to the end of the contract and trimmed
+func (client *StorageClient) removeHostSectors(hostID enode.ID, roots []common.Hash) (hr HostDeletionReport) {
+	hr = HostDeletionReport{HostID: hostID.String(), Sectors: len(roots)}
+	hr.ContractID = client.contractManager.GetStorageContractSet().GetContractIDByHostID(hostID).String()
+
+	remove := make(map[common.Hash]struct{}, len(roots))
+	for _, root := range roots {
+		remove[root] = struct{}{}
+	}
+
+	err := func() error {
+		hostInfo, ok := client.storageHostManager.RetrieveHostInfo(hostID)
+		if !ok {
+			return ErrUnableRetrieveHostInfo
+		}
+		sp, err := client.SetupConnection(hostInfo.EnodeURL)
+		if err != nil {
+			return err
+		}
+		if !sp.TryToRenewOrRevise() {
+			return ErrContractRenewing
+		}
+		defer sp.RevisionOrRenewingDone()
+
+		var lastMissed common.BigInt
+		rev, err := client.write(sp, &hostInfo, func(contract *contractset.Contract) ([]storage.UploadAction, error) {
+			header := contract.Header()
+			stored, err := contract.MerkleRoots()
+			if err != nil {
+				return nil, err
+			}
+			if uint64(len(stored)) != header.LatestContractRevision.NewFileSize/storage.SectorSize {
+				return nil, errRootsNotSynced
+			}
+			lastMissed = common.PtrBigInt(header.LatestContractRevision.NewMissedProofOutputs[1].Value)
+
+			var actions []storage.UploadAction
+			if actions, _, hr.RemovedSectors = removeSectorActions(stored, remove); hr.RemovedSectors == 0 {
+				return nil, errNoSectorsStored
+			}
+			return actions, nil
+		})
+		if err == errNoSectorsStored {
+			return nil
+		}
+		if err != nil {
+			hr.RemovedSectors = 0
+			return err
+		}
+		hr.ReclaimedStorage = hr.RemovedSectors * storage.SectorSize
+		if released := common.PtrBigInt(rev.NewMissedProofOutputs[1].Value).Sub(lastMissed); released.Sign() > 0 {
+			hr.ReleasedDeposit = released
+		}
+		return nil
+	}()
+	if err != nil {
+		hr.Err = err.Error()
+	}
+	return
+}
@@ -8,8 +8,15 @@ import (
 	"errors"
 	"io"
 	"sync"
+
+	"github.com/DxChainNetwork/godx/storage/storageclient/memorymanager"
 )
 
+// sectorBuffers is the pool of the buffers shared by all storage clients, which is used for
+// the logical data read or downloaded for erasure encoding, and the segments recovered by
+// erasure decoding
+var sectorBuffers = memorymanager.NewBufferPool(DefaultBufferPoolLimit)
+
 // writeDestination where to write the downloaded data
 type writeDestination interface {
 	WriteAt(data []byte, offset int64) (int, error)
@@ -33,12 +40,19 @@ func newDownloadBuffer(length, sectorSize uint64) downloadBuffer {
 		sectorSize: sectorSize,
 	}
 	for length > 0 {
-		ddb.buf = append(ddb.buf, make([]byte, sectorSize))
+		ddb.buf = append(ddb.buf, sectorBuffers.Get(int(sectorSize)))
 		length -= sectorSize
 	}
 	return ddb
 }
 
+// release returns the buffers to the pool. The buffer must not be used after released
+func (dw downloadBuffer) release() {
+	for _, b := range dw.buf {
+		sectorBuffers.Put(b)
+	}
+}
+
 // ReadFrom reads data from a io.Reader until the buffer is full.
 func (dw downloadBuffer) ReadFrom(r io.Reader) (int64, error) {
 	var n int64
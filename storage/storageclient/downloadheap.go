@@ -8,7 +8,9 @@ import (
 	"container/heap"
 	"time"
 
-	"github.com/DxChainNetwork/godx/log"
+	"github.com/DxChainNetwork/godx/common/alert"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
 )
 
 // download tasks are added to the downloadSegmentHeap.
@@ -56,7 +58,7 @@ func (dch *downloadSegmentHeap) Pop() interface{} {
 func (client *StorageClient) downloadLoop() {
 	err := client.tm.Add()
 	if err != nil {
-		log.Error("storage client thread manager failed to add in downloadLoop", "error", err)
+		alert.Raise(client.log, alert.SeverityError, alertModule, "storage client thread manager failed to add in downloadLoop", "error", err)
 		return
 	}
 	defer client.tm.Done()
@@ -145,8 +147,13 @@ func (client *StorageClient) distributeDownloadSegmentToWorkers(uds *unfinishedD
 
 	// distribute the segment to workers, marking the number of workers that have received the work.
 	client.lock.Lock()
+	preferred := client.preferredDownloadHosts(uds)
 	uds.mu.Lock()
 	uds.workersRemaining = uint32(len(client.workerPool))
+	uds.preferredPending = make(map[string]struct{})
+	for _, id := range preferred {
+		uds.preferredPending[id.String()] = struct{}{}
+	}
 	uds.mu.Unlock()
 	for _, worker := range client.workerPool {
 		worker.queueDownloadSegment(uds)
@@ -158,6 +165,24 @@ func (client *StorageClient) distributeDownloadSegmentToWorkers(uds *unfinishedD
 	uds.cleanUp()
 }
 
+// preferredDownloadHosts returns the hosts that are expected to fetch the sectors of the segment
+// the fastest. The slower hosts will only be used when the preferred hosts failed, so that
+// they will not be on the critical path of the segment recovery.
+//
+// NOTE: client.lock must be held
+func (client *StorageClient) preferredDownloadHosts(uds *unfinishedDownloadSegment) []enode.ID {
+	var candidates []enode.ID
+	for _, worker := range client.workerPool {
+		if _, exists := uds.segmentMap[worker.hostID.String()]; !exists || worker.onDownloadCooldown() {
+			continue
+		}
+		candidates = append(candidates, worker.hostID)
+	}
+	// the whole sector is fetched from the host every time
+	needed := int(uds.erasureCode.MinSectors() + uds.overdrive)
+	return client.hostLatency.preferredDownloadHosts(candidates, storage.SectorSize, needed)
+}
+
 // Add a segment to the download heap
 func (client *StorageClient) addSegmentToDownloadHeap(uds *unfinishedDownloadSegment) {
 
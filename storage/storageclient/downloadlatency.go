@@ -0,0 +1,110 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package storageclient
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DxChainNetwork/godx/p2p/enode"
+)
+
+// hostLatency is the observed download performance of a storage host. Both
+// the time to first byte and the throughput are exponentially weighted moving
+// averages. Throughput is tracked separately for each fetch length in bytes/second
+type hostLatency struct {
+	ttfb       time.Duration
+	throughput map[uint64]float64
+}
+
+// hostLatencyTracker records the download performance of all storage hosts
+type hostLatencyTracker struct {
+	hosts map[enode.ID]*hostLatency
+	lock  sync.RWMutex
+}
+
+// newHostLatencyTracker initializes the hostLatencyTracker object
+func newHostLatencyTracker() *hostLatencyTracker {
+	return &hostLatencyTracker{
+		hosts: make(map[enode.ID]*hostLatency),
+	}
+}
+
+// record will update the performance of the host based on a finished sector fetch, where
+// ttfb is the time used until the host response arrived, and elapsed is the total time
+// used for fetching length bytes
+func (ht *hostLatencyTracker) record(id enode.ID, length uint64, ttfb, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	throughput := float64(length) / elapsed.Seconds()
+
+	ht.lock.Lock()
+	defer ht.lock.Unlock()
+
+	hl, exists := ht.hosts[id]
+	if !exists {
+		ht.hosts[id] = &hostLatency{
+			ttfb:       ttfb,
+			throughput: map[uint64]float64{length: throughput},
+		}
+		return
+	}
+
+	hl.ttfb = time.Duration(latencyEWMAWeight*float64(ttfb) + (1-latencyEWMAWeight)*float64(hl.ttfb))
+	if prev, exists := hl.throughput[length]; exists {
+		throughput = latencyEWMAWeight*throughput + (1-latencyEWMAWeight)*prev
+	}
+	hl.throughput[length] = throughput
+}
+
+// expectedDuration returns the expected time used to fetch length bytes from the
+// host. If the host has never been used for fetching data with the length, false
+// will be returned
+func (ht *hostLatencyTracker) expectedDuration(id enode.ID, length uint64) (expected time.Duration, known bool) {
+	ht.lock.RLock()
+	defer ht.lock.RUnlock()
+
+	hl, exists := ht.hosts[id]
+	if !exists {
+		return
+	}
+	throughput, exists := hl.throughput[length]
+	if !exists || throughput <= 0 {
+		return
+	}
+	expected = hl.ttfb + time.Duration(float64(length)/throughput*float64(time.Second))
+	return expected, true
+}
+
+// preferredDownloadHosts ranks the candidate hosts by their expected fetch duration,
+// and returns the fastest needed ones. Hosts without any record are placed
+// in front of the recorded hosts, so that their performance can be learned
+func (ht *hostLatencyTracker) preferredDownloadHosts(candidates []enode.ID, length uint64, needed int) (preferred []enode.ID) {
+	type rankedHost struct {
+		id       enode.ID
+		expected time.Duration
+		known    bool
+	}
+
+	ranked := make([]rankedHost, 0, len(candidates))
+	for _, id := range candidates {
+		expected, known := ht.expectedDuration(id, length)
+		ranked = append(ranked, rankedHost{id, expected, known})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].known != ranked[j].known {
+			return !ranked[i].known
+		}
+		return ranked[i].expected < ranked[j].expected
+	})
+
+	for i := 0; i < len(ranked) && i < needed; i++ {
+		preferred = append(preferred, ranked[i].id)
+	}
+	return
+}
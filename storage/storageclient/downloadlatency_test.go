@@ -0,0 +1,64 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package storageclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/p2p/enode"
+)
+
+func TestHostLatencyTracker_ExpectedDuration(t *testing.T) {
+	ht := newHostLatencyTracker()
+	id := enode.ID{1}
+	length := uint64(1 << 20)
+
+	if _, known := ht.expectedDuration(id, length); known {
+		t.Fatalf("host without any record should not be known")
+	}
+
+	// 1 MiB in 1 second with 100 millisecond ttfb
+	ht.record(id, length, 100*time.Millisecond, time.Second)
+	expected, known := ht.expectedDuration(id, length)
+	if !known {
+		t.Fatalf("host should be known after recording")
+	}
+	if expected != 1100*time.Millisecond {
+		t.Errorf("expected duration not match. Got %v, expect %v", expected, 1100*time.Millisecond)
+	}
+
+	// the throughput of other length is not known
+	if _, known := ht.expectedDuration(id, length*2); known {
+		t.Errorf("throughput should be tracked per length")
+	}
+
+	// a faster observation should lower the expected duration
+	ht.record(id, length, 100*time.Millisecond, 500*time.Millisecond)
+	if newExpected, _ := ht.expectedDuration(id, length); newExpected >= expected {
+		t.Errorf("expected duration should decrease. Got %v, previous %v", newExpected, expected)
+	}
+}
+
+func TestHostLatencyTracker_PreferredDownloadHosts(t *testing.T) {
+	ht := newHostLatencyTracker()
+	length := uint64(1 << 20)
+	fast, medium, slow, unknown := enode.ID{1}, enode.ID{2}, enode.ID{3}, enode.ID{4}
+
+	ht.record(fast, length, 10*time.Millisecond, 100*time.Millisecond)
+	ht.record(medium, length, 50*time.Millisecond, 500*time.Millisecond)
+	ht.record(slow, length, 500*time.Millisecond, 5*time.Second)
+
+	preferred := ht.preferredDownloadHosts([]enode.ID{slow, medium, unknown, fast}, length, 3)
+	expect := []enode.ID{unknown, fast, medium}
+	if len(preferred) != len(expect) {
+		t.Fatalf("preferred hosts size not expected. Got %v, expect %v", len(preferred), len(expect))
+	}
+	for i := range expect {
+		if preferred[i] != expect[i] {
+			t.Errorf("preferred host %d not expected. Got %v, expect %v", i, preferred[i], expect[i])
+		}
+	}
+}
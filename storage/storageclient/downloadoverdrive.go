@@ -0,0 +1,96 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DownloadOverdriveStats is the statistics of the extra sector fetches issued by the
+// download racer beyond the minimum sectors needed to recover the segments
+type DownloadOverdriveStats struct {
+	// Segments is the number of segments recovered
+	Segments uint64 `json:"segments"`
+
+	// OverdriveSegments is the number of segments fetched with extra sector fetches issued
+	OverdriveSegments uint64 `json:"overdrivesegments"`
+
+	// ExtraFetches is the total number of extra sector fetches issued
+	ExtraFetches uint64 `json:"extrafetches"`
+
+	// OverdriveNeeded is the number of segments that could only be recovered in time with
+	// the sectors from the extra fetches
+	OverdriveNeeded uint64 `json:"overdriveneeded"`
+}
+
+// overdriveTracker records the download overdrive statistics of the storage client
+type overdriveTracker struct {
+	stats DownloadOverdriveStats
+	lock  sync.Mutex
+}
+
+// record updates the statistics with a recovered segment, where issued is the number of
+// sector fetches issued for the segment, and needed indicates whether the sector from an
+// extra fetch is used for recovery
+func (ot *overdriveTracker) record(minSectors, issued uint32, needed bool) {
+	ot.lock.Lock()
+	defer ot.lock.Unlock()
+
+	ot.stats.Segments++
+	if issued > minSectors {
+		ot.stats.OverdriveSegments++
+		ot.stats.ExtraFetches += uint64(issued - minSectors)
+	}
+	if needed {
+		ot.stats.OverdriveNeeded++
+	}
+}
+
+// snapshot returns a copy of the statistics
+func (ot *overdriveTracker) snapshot() DownloadOverdriveStats {
+	ot.lock.Lock()
+	defer ot.lock.Unlock()
+	return ot.stats
+}
+
+// DownloadOverdriveStats returns the statistics of the extra sector fetches issued
+// for the downloads since the client started
+func (client *StorageClient) DownloadOverdriveStats() DownloadOverdriveStats {
+	return client.overdrive.snapshot()
+}
+
+// downloadOverdrive returns the number of extra sector fetches issued for every segment. The
+// value specified for the download overrides the client setting
+func (client *StorageClient) downloadOverdrive(specified *int) (int, error) {
+	if specified != nil {
+		if *specified < 0 {
+			return 0, fmt.Errorf("download overdrive %v cannot be smaller than 0", *specified)
+		}
+		return *specified, nil
+	}
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	return client.persist.DownloadOverdrive, nil
+}
+
+// registerFetch records the order the sector fetch of the host is issued for the segment.
+// uds.mu must be held when calling the function
+func (uds *unfinishedDownloadSegment) registerFetch(hostID string) {
+	uds.fetchesIssued++
+	if uds.fetchOrder == nil {
+		uds.fetchOrder = make(map[string]uint32)
+	}
+	uds.fetchOrder[hostID] = uds.fetchesIssued
+}
+
+// markFetchUsed marks whether the sector fetched from the host is an extra fetch used for the
+// recovery of the segment. uds.mu must be held when calling the function
+func (uds *unfinishedDownloadSegment) markFetchUsed(hostID string) {
+	if uds.fetchOrder[hostID] > uds.erasureCode.MinSectors() {
+		uds.overdriveUsed = true
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"testing"
+
+	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
+)
+
+func TestOverdriveTracker_Record(t *testing.T) {
+	ec, err := erasurecode.New(erasurecode.ECTypeStandard, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ot overdriveTracker
+
+	// the first two fetches are enough to recover the segment
+	uds := &unfinishedDownloadSegment{erasureCode: ec}
+	for _, host := range []string{"a", "b", "c", "d"} {
+		uds.registerFetch(host)
+	}
+	uds.markFetchUsed("a")
+	uds.markFetchUsed("b")
+	ot.record(ec.MinSectors(), uds.fetchesIssued, uds.overdriveUsed)
+
+	// the sector from the extra fetch is used to recover the segment
+	uds = &unfinishedDownloadSegment{erasureCode: ec}
+	for _, host := range []string{"a", "b", "c"} {
+		uds.registerFetch(host)
+	}
+	uds.markFetchUsed("c")
+	uds.markFetchUsed("a")
+	ot.record(ec.MinSectors(), uds.fetchesIssued, uds.overdriveUsed)
+
+	// no extra fetches issued
+	uds = &unfinishedDownloadSegment{erasureCode: ec}
+	uds.registerFetch("a")
+	uds.registerFetch("b")
+	ot.record(ec.MinSectors(), uds.fetchesIssued, uds.overdriveUsed)
+
+	expected := DownloadOverdriveStats{
+		Segments:          3,
+		OverdriveSegments: 2,
+		ExtraFetches:      3,
+		OverdriveNeeded:   1,
+	}
+	if stats := ot.snapshot(); stats != expected {
+		t.Errorf("overdrive stats expect %+v, got %+v", expected, stats)
+	}
+}
+
+func TestStorageClient_DownloadOverdrive(t *testing.T) {
+	client := &StorageClient{persist: persistence{DownloadOverdrive: DefaultDownloadOverdrive}}
+
+	if overdrive, err := client.downloadOverdrive(nil); err != nil || overdrive != DefaultDownloadOverdrive {
+		t.Errorf("overdrive expect the client setting %v, got %v: %v", DefaultDownloadOverdrive, overdrive, err)
+	}
+	specified := 0
+	if overdrive, err := client.downloadOverdrive(&specified); err != nil || overdrive != specified {
+		t.Errorf("overdrive expect the specified %v, got %v: %v", specified, overdrive, err)
+	}
+	specified = -1
+	if _, err := client.downloadOverdrive(&specified); err == nil {
+		t.Errorf("negative overdrive should be rejected")
+	}
+}
@@ -83,6 +83,17 @@ type unfinishedDownloadSegment struct {
 	// backup workers that can be used to download when other workers fail
 	workersStandby []*worker
 
+	// hosts expected to be the fastest to fetch the segment, which have not yet
+	// picked up the segment. Other workers will be placed on standby while they are pending
+	preferredPending map[string]struct{}
+
+	// the number of sector fetches issued, and the order of the fetch issued to each host
+	fetchesIssued uint32
+	fetchOrder    map[string]uint32
+
+	// whether a sector from the extra fetches is used to recover the segment
+	overdriveUsed bool
+
 	// record how much memory allocated
 	memoryAllocated uint64
 
@@ -101,6 +112,14 @@ func (uds *unfinishedDownloadSegment) removeWorker() {
 	uds.cleanUp()
 }
 
+// releasePreferred removes the host from the pending preferred hosts. It is called when
+// the worker of a preferred host is not able to pick up the segment
+func (uds *unfinishedDownloadSegment) releasePreferred(hostID string) {
+	uds.mu.Lock()
+	delete(uds.preferredPending, hostID)
+	uds.mu.Unlock()
+}
+
 // cleanUp will check if the download has failed, and if not it will add
 // any standby workers which need to be added.
 //
@@ -205,7 +224,9 @@ func (uds *unfinishedDownloadSegment) recoverLogicalData() error {
 
 	// NOTE: for not supporting partial encoding, we directly recover the whole sector
 	// recover the sectors into the logical segment data.
-	recoverWriter := new(bytes.Buffer)
+	recovered := sectorBuffers.Get(int(uds.segmentSize))
+	defer sectorBuffers.Put(recovered)
+	recoverWriter := bytes.NewBuffer(recovered[:0])
 	err := uds.erasureCode.Recover(uds.physicalSegmentData, int(uds.segmentSize), recoverWriter)
 	if err != nil {
 		uds.mu.Lock()
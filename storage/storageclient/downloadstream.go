@@ -0,0 +1,73 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// DownloadStream downloads length bytes of the file starting from offset, and writes the data
+// to w in order as the segments are recovered. The data is never staged on the disk, and the
+// call blocks until the download is finished
+func (client *StorageClient) DownloadStream(dxPath storage.DxPath, w io.Writer, offset, length uint64) (err error) {
+	if err = client.tm.Add(); err != nil {
+		return err
+	}
+	defer client.tm.Done()
+
+	entry, err := client.fileSystem.OpenDxFile(dxPath)
+	if err != nil {
+		return err
+	}
+	defer entry.Close()
+	defer entry.SetTimeAccess(time.Now())
+
+	info := HookInfo{Operation: HookDownload, DxPath: dxPath.Path, FileSize: entry.FileSize()}
+	if err = client.hooks.runPreHooks(info); err != nil {
+		return err
+	}
+	defer func() {
+		client.hooks.runPostHooks(info, err)
+	}()
+
+	overdrive, err := client.downloadOverdrive(nil)
+	if err != nil {
+		return err
+	}
+	snap, err := entry.Snapshot()
+	if err != nil {
+		return err
+	}
+	dw := newDownloadWriter(w)
+	d, err := client.newDownload(downloadParams{
+		destination:       dw,
+		destinationType:   "http stream",
+		destinationString: dxPath.Path,
+		file:              snap,
+		latencyTarget:     25e3 * time.Millisecond,
+		length:            length,
+		needsMemory:       true,
+		offset:            offset,
+		overdrive:         overdrive,
+		priority:          5,
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-d.completeChan:
+		err = d.Err()
+	case <-client.tm.StopChan():
+		err = errors.New("download is shutdown")
+	}
+	// unblock the segments waiting to be written in order once the download failed
+	dw.Close()
+	return err
+}
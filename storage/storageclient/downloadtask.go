@@ -5,6 +5,7 @@
 package storageclient
 
 import (
+	"math/rand"
 	"sync"
 	"time"
 
@@ -67,6 +68,12 @@ type (
 		// higher priority will complete first.
 		priority uint64
 
+		// the rate of sectors verified with the Merkle proofs, and the number of sectors
+		// verified and skipped
+		verifyRate      float64
+		sectorsVerified uint64
+		sectorsSkipped  uint64
+
 		// Utilities.
 		log           log.Logger
 		memoryManager *memorymanager.MemoryManager
@@ -107,6 +114,9 @@ type (
 
 		// higher priority download first
 		priority uint64
+
+		// the rate of sectors verified with the Merkle proofs
+		verifyRate float64
 	}
 
 	// a function type that is called when the download completed.
@@ -186,3 +196,19 @@ func (d *download) onComplete(f downloadCompleteFunc) {
 	}
 	d.downloadCompleteFuncs = append(d.downloadCompleteFuncs, f)
 }
+
+// sampleVerification decides whether the sector to be downloaded is verified with the Merkle
+// proof. With the default rate every sector is verified, otherwise the sectors are randomly
+// sampled at the verify rate, which saves the cost of the proofs for big files
+func (d *download) sampleVerification() bool {
+	verify := d.verifyRate <= 0 || d.verifyRate >= 1 || rand.Float64() < d.verifyRate
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if verify {
+		d.sectorsVerified++
+	} else {
+		d.sectorsSkipped++
+	}
+	return verify
+}
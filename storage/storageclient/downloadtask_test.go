@@ -0,0 +1,33 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"testing"
+)
+
+func TestDownload_SampleVerification(t *testing.T) {
+	tests := []struct {
+		verifyRate float64
+		min, max   uint64
+	}{
+		{0, 1000, 1000},
+		{1, 1000, 1000},
+		{0.1, 50, 150},
+		{0.5, 400, 600},
+	}
+	for _, test := range tests {
+		d := &download{verifyRate: test.verifyRate}
+		for i := 0; i != 1000; i++ {
+			d.sampleVerification()
+		}
+		if d.sectorsVerified < test.min || d.sectorsVerified > test.max {
+			t.Errorf("verify rate %v: expect verified in [%v, %v], got %v", test.verifyRate, test.min, test.max, d.sectorsVerified)
+		}
+		if d.sectorsVerified+d.sectorsSkipped != 1000 {
+			t.Errorf("verify rate %v: expect 1000 sectors sampled, got %v", test.verifyRate, d.sectorsVerified+d.sectorsSkipped)
+		}
+	}
+}
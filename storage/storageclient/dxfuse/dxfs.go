@@ -0,0 +1,777 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// Package dxfuse mounts the dxfile namespace of the storage client as a local filesystem,
+// so that the stored files can be accessed with the ordinary file tools
+package dxfuse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DxChainNetwork/godx/log"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+var (
+	// ErrNotSupported is returned when mounting on the platforms FUSE is not available
+	ErrNotSupported = errors.New("FUSE is not supported on this platform")
+
+	errNotExist    = errors.New("no such file or directory")
+	errExist       = errors.New("file already exists")
+	errIsDir       = errors.New("is a directory")
+	errNotDir      = errors.New("not a directory")
+	errDirNotEmpty = errors.New("directory not empty")
+	errClosed      = errors.New("the filesystem is closed")
+	errReadOnly    = errors.New("the file is opened read only")
+)
+
+const (
+	// cacheFilesDir is the directory in the cache directory holding the content of the
+	// opened files, and snapshotDir holds the snapshots used as the upload source
+	cacheFilesDir = "files"
+	snapshotDir   = "snapshots"
+
+	// the upload failed in background is retried for uploadRetries times, and the interval
+	// starting from uploadRetryInterval is doubled after each failure
+	uploadRetries       = 5
+	uploadRetryInterval = 5 * time.Second
+)
+
+// Backend is the storage client functionality the filesystem is built on
+type Backend interface {
+	// FileList returns the brief information of all dxfiles
+	FileList() []storage.FileBriefInfo
+
+	// FileSize returns the size of the dxfile
+	FileSize(dxPath storage.DxPath) (uint64, error)
+
+	// Download downloads the dxfile to the local path, and blocks till finished
+	Download(dxPath storage.DxPath, localPath string) error
+
+	// DownloadRange downloads length bytes of the dxfile starting from offset to w, and
+	// blocks till finished
+	DownloadRange(dxPath storage.DxPath, w io.Writer, offset, length uint64) error
+
+	// Upload uploads the local file to dxPath, the existing dxfile is replaced
+	Upload(dxPath storage.DxPath, localPath string) error
+
+	// Delete deletes the dxfile
+	Delete(dxPath storage.DxPath) error
+
+	// Rename renames the dxfile
+	Rename(prevPath, newPath storage.DxPath) error
+}
+
+// Entry is a file or a directory in the filesystem
+type Entry struct {
+	Name string
+	Dir  bool
+	Size uint64
+}
+
+// DxFS is the view of the dxfile namespace as a filesystem. The files opened read only are
+// read with the ranged downloads, and the content of the files opened for write is cached
+// in the cache directory. Writes go to the cached file only, and the file is uploaded in
+// background when the last handle is released, or synchronously on sync. The failed
+// background uploads are retried with backoff. Paths used by DxFS are slash separated and
+// relative to the root of the namespace. The changed content failed to upload is kept in
+// the cache till uploaded
+type DxFS struct {
+	backend       Backend
+	cacheDir      string
+	retryInterval time.Duration
+
+	lock  sync.Mutex
+	files map[string]*cachedFile
+
+	// directories created through the filesystem, which do not contain any file yet
+	dirs map[string]struct{}
+
+	uploads sync.WaitGroup
+	quit    chan struct{}
+	closed  bool
+	log     log.Logger
+}
+
+// cachedFile is an opened dxfile, whose content is cached locally once materialized
+type cachedFile struct {
+	lock sync.Mutex
+
+	// filePath is the current path of the file in the filesystem, which is changed on rename
+	filePath  string
+	localPath string
+	size      int64
+
+	// opened is the number of open handles, dirty is set when the content changed after
+	// the last upload, and remote is set when the dxfile exists in the storage client.
+	// The file is read from the storage client till materialized to the local path
+	opened       int
+	dirty        bool
+	remote       bool
+	materialized bool
+}
+
+// Handle is an opened file. The handle refers to the file itself, so it keeps working
+// after the file is renamed
+type Handle struct {
+	dfs      *DxFS
+	file     *cachedFile
+	write    bool
+	released sync.Once
+}
+
+// New creates the DxFS with the content cached in cacheDir
+func New(backend Backend, cacheDir string) (*DxFS, error) {
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the cache directory: %s", err.Error())
+	}
+	return &DxFS{
+		backend:       backend,
+		cacheDir:      cacheDir,
+		retryInterval: uploadRetryInterval,
+		files:         make(map[string]*cachedFile),
+		dirs:          make(map[string]struct{}),
+		quit:          make(chan struct{}),
+		log:           log.New("module", "dxfuse"),
+	}, nil
+}
+
+// Stat returns the entry of the path
+func (dfs *DxFS) Stat(p string) (Entry, error) {
+	p = cleanPath(p)
+	if p == "" {
+		return Entry{Dir: true}, nil
+	}
+
+	dfs.lock.Lock()
+	file, cached := dfs.files[p]
+	dfs.lock.Unlock()
+	if cached {
+		file.lock.Lock()
+		defer file.lock.Unlock()
+		return Entry{Name: path.Base(p), Size: uint64(file.size)}, nil
+	}
+
+	entries, err := dfs.ReadDir(path.Dir(p))
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, entry := range entries {
+		if entry.Name != path.Base(p) {
+			continue
+		}
+		if !entry.Dir {
+			dxPath, err := storage.NewDxPath(p)
+			if err != nil {
+				return Entry{}, err
+			}
+			if entry.Size, err = dfs.backend.FileSize(dxPath); err != nil {
+				return Entry{}, err
+			}
+		}
+		return entry, nil
+	}
+	return Entry{}, errNotExist
+}
+
+// ReadDir returns the entries in the directory, sorted by name. The size of the files
+// not cached is not filled
+func (dfs *DxFS) ReadDir(dir string) ([]Entry, error) {
+	dir = cleanPath(dir)
+	entries := make(map[string]Entry)
+	found := dir == ""
+
+	add := func(p string, isDir bool) {
+		rel := p
+		if dir != "" {
+			if !strings.HasPrefix(p, dir+"/") {
+				if p == dir {
+					found = found || isDir
+				}
+				return
+			}
+			rel = strings.TrimPrefix(p, dir+"/")
+		}
+		found = true
+		if i := strings.Index(rel, "/"); i >= 0 {
+			entries[rel[:i]] = Entry{Name: rel[:i], Dir: true}
+		} else if _, exists := entries[rel]; !exists {
+			entries[rel] = Entry{Name: rel, Dir: isDir}
+		}
+	}
+
+	for _, file := range dfs.backend.FileList() {
+		add(cleanPath(file.Path), false)
+	}
+	dfs.lock.Lock()
+	for p, file := range dfs.files {
+		add(p, false)
+		if e, exists := entries[path.Base(p)]; exists && !e.Dir && path.Dir(p) == orRoot(dir) {
+			file.lock.Lock()
+			e.Size = uint64(file.size)
+			file.lock.Unlock()
+			entries[e.Name] = e
+		}
+	}
+	for p := range dfs.dirs {
+		add(p, true)
+	}
+	dfs.lock.Unlock()
+
+	if !found {
+		return nil, errNotExist
+	}
+	list := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Open opens the file. The file opened read only is read with the ranged downloads, and
+// the file opened for write is downloaded to the cache when not cached yet
+func (dfs *DxFS) Open(p string, write bool) (*Handle, error) {
+	p = cleanPath(p)
+	dfs.lock.Lock()
+	if dfs.closed {
+		dfs.lock.Unlock()
+		return nil, errClosed
+	}
+	file, cached := dfs.files[p]
+	if cached {
+		file.lock.Lock()
+		file.opened++
+		file.lock.Unlock()
+	}
+	dfs.lock.Unlock()
+
+	if !cached {
+		entry, err := dfs.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Dir {
+			return nil, errIsDir
+		}
+
+		dfs.lock.Lock()
+		// the file might be opened concurrently, only the first one is kept
+		if file, cached = dfs.files[p]; !cached {
+			file = &cachedFile{filePath: p, localPath: dfs.cachePath(p), size: int64(entry.Size), remote: true}
+			dfs.files[p] = file
+		}
+		file.lock.Lock()
+		file.opened++
+		file.lock.Unlock()
+		dfs.lock.Unlock()
+	}
+
+	h := &Handle{dfs: dfs, file: file, write: write}
+	if write {
+		if err := dfs.materialize(file); err != nil {
+			h.Release()
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// materialize downloads the content of the file to the cache, which is required before
+// the file is changed
+func (dfs *DxFS) materialize(file *cachedFile) error {
+	file.lock.Lock()
+	defer file.lock.Unlock()
+	if file.materialized {
+		return nil
+	}
+
+	dxPath, err := storage.NewDxPath(file.filePath)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(file.localPath), 0700); err != nil {
+		return err
+	}
+	if err = dfs.backend.Download(dxPath, file.localPath); err != nil {
+		return fmt.Errorf("failed to download %v: %s", file.filePath, err.Error())
+	}
+	info, err := os.Stat(file.localPath)
+	if err != nil {
+		return err
+	}
+	file.size, file.materialized = info.Size(), true
+	return nil
+}
+
+// Create creates an empty file and opens it
+func (dfs *DxFS) Create(p string) (*Handle, error) {
+	p = cleanPath(p)
+	if p == "" {
+		return nil, errExist
+	}
+	if _, err := dfs.Stat(p); err == nil {
+		return nil, errExist
+	}
+	if parent, err := dfs.Stat(path.Dir(p)); err != nil {
+		return nil, err
+	} else if !parent.Dir {
+		return nil, errNotDir
+	}
+	if _, err := storage.NewDxPath(p); err != nil {
+		return nil, err
+	}
+
+	localPath := dfs.cachePath(p)
+	if err := os.MkdirAll(filepath.Dir(localPath), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(localPath, nil, 0600); err != nil {
+		return nil, err
+	}
+
+	dfs.lock.Lock()
+	defer dfs.lock.Unlock()
+	if dfs.closed {
+		return nil, errClosed
+	}
+	file := &cachedFile{filePath: p, localPath: localPath, opened: 1, dirty: true, materialized: true}
+	dfs.files[p] = file
+	return &Handle{dfs: dfs, file: file, write: true}, nil
+}
+
+// Path returns the current path of the opened file
+func (h *Handle) Path() string {
+	h.file.lock.Lock()
+	defer h.file.lock.Unlock()
+	return h.file.filePath
+}
+
+// ReadAt reads the content of the opened file. The range is downloaded from the storage
+// client if the file is not materialized in the cache
+func (h *Handle) ReadAt(b []byte, off int64) (int, error) {
+	file := h.file
+	file.lock.Lock()
+	if !file.materialized {
+		filePath, size := file.filePath, file.size
+		file.lock.Unlock()
+		return h.dfs.readRange(filePath, size, b, off)
+	}
+	defer file.lock.Unlock()
+
+	f, err := os.Open(file.localPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n, err := f.ReadAt(b, off)
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// WriteAt writes to the cached content of the opened file
+func (h *Handle) WriteAt(b []byte, off int64) (int, error) {
+	if !h.write {
+		return 0, errReadOnly
+	}
+	file := h.file
+	file.lock.Lock()
+	defer file.lock.Unlock()
+
+	f, err := os.OpenFile(file.localPath, os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n, err := f.WriteAt(b, off)
+	if n > 0 {
+		file.dirty = true
+		if end := off + int64(n); end > file.size {
+			file.size = end
+		}
+	}
+	return n, err
+}
+
+// Truncate changes the size of the opened file
+func (h *Handle) Truncate(size int64) error {
+	if !h.write {
+		return errReadOnly
+	}
+	file := h.file
+	file.lock.Lock()
+	defer file.lock.Unlock()
+
+	if err := os.Truncate(file.localPath, size); err != nil {
+		return err
+	}
+	file.size, file.dirty = size, true
+	return nil
+}
+
+// Sync uploads the changed content of the opened file, and blocks till the upload is
+// accepted by the storage client
+func (h *Handle) Sync() error {
+	return h.dfs.upload(h.file)
+}
+
+// Release closes the handle. When the last handle of the file is released, the changed
+// content is uploaded in background, and the cached content is evicted once uploaded.
+// The handle is released only once
+func (h *Handle) Release() {
+	h.released.Do(func() {
+		h.dfs.release(h.file)
+	})
+}
+
+// Truncate changes the size of the file
+func (dfs *DxFS) Truncate(p string, size int64) error {
+	h, err := dfs.Open(p, true)
+	if err != nil {
+		return err
+	}
+	defer h.Release()
+	return h.Truncate(size)
+}
+
+// Sync uploads the changed content of the file, and blocks till the upload is accepted
+// by the storage client
+func (dfs *DxFS) Sync(p string) error {
+	dfs.lock.Lock()
+	file, cached := dfs.files[cleanPath(p)]
+	dfs.lock.Unlock()
+	if !cached {
+		// the file not cached has nothing to upload
+		return nil
+	}
+	return dfs.upload(file)
+}
+
+// Remove removes the file or the empty directory
+func (dfs *DxFS) Remove(p string) error {
+	p = cleanPath(p)
+	entry, err := dfs.Stat(p)
+	if err != nil {
+		return err
+	}
+	if entry.Dir {
+		entries, err := dfs.ReadDir(p)
+		if err != nil {
+			return err
+		}
+		if len(entries) != 0 {
+			return errDirNotEmpty
+		}
+		dfs.lock.Lock()
+		delete(dfs.dirs, p)
+		dfs.lock.Unlock()
+		return nil
+	}
+
+	dfs.lock.Lock()
+	file, cached := dfs.files[p]
+	delete(dfs.files, p)
+	dfs.lock.Unlock()
+
+	remote := !cached
+	if cached {
+		file.lock.Lock()
+		remote, file.dirty = file.remote, false
+		os.Remove(file.localPath)
+		file.lock.Unlock()
+	}
+	if !remote {
+		return nil
+	}
+	dxPath, err := storage.NewDxPath(p)
+	if err != nil {
+		return err
+	}
+	return dfs.backend.Delete(dxPath)
+}
+
+// Mkdir creates the directory. The directory exists only in the filesystem till a file
+// is created in it
+func (dfs *DxFS) Mkdir(p string) error {
+	p = cleanPath(p)
+	if _, err := dfs.Stat(p); err == nil {
+		return errExist
+	}
+	if parent, err := dfs.Stat(path.Dir(p)); err != nil {
+		return err
+	} else if !parent.Dir {
+		return errNotDir
+	}
+	if _, err := storage.NewDxPath(p); err != nil {
+		return err
+	}
+
+	dfs.lock.Lock()
+	defer dfs.lock.Unlock()
+	dfs.dirs[p] = struct{}{}
+	return nil
+}
+
+// Rename renames the file. Renaming directories is not supported
+func (dfs *DxFS) Rename(prev, cur string) error {
+	prev, cur = cleanPath(prev), cleanPath(cur)
+	entry, err := dfs.Stat(prev)
+	if err != nil {
+		return err
+	}
+	if entry.Dir {
+		return errIsDir
+	}
+	if _, err = dfs.Stat(cur); err == nil {
+		if err = dfs.Remove(cur); err != nil {
+			return err
+		}
+	}
+	prevDxPath, err := storage.NewDxPath(prev)
+	if err != nil {
+		return err
+	}
+	curDxPath, err := storage.NewDxPath(cur)
+	if err != nil {
+		return err
+	}
+
+	dfs.lock.Lock()
+	file, cached := dfs.files[prev]
+	if cached {
+		delete(dfs.files, prev)
+		dfs.files[cur] = file
+	}
+	dfs.lock.Unlock()
+
+	if !cached {
+		return dfs.backend.Rename(prevDxPath, curDxPath)
+	}
+	file.lock.Lock()
+	defer file.lock.Unlock()
+	file.filePath = cur
+	if file.remote {
+		return dfs.backend.Rename(prevDxPath, curDxPath)
+	}
+	return nil
+}
+
+// Close waits for the background uploads to finish, and removes the cached content. The
+// uploads waiting for the retry are stopped, and the content of the files failed to upload
+// is kept in the cache, which is returned as an error
+func (dfs *DxFS) Close() error {
+	dfs.lock.Lock()
+	if !dfs.closed {
+		dfs.closed = true
+		close(dfs.quit)
+	}
+	dfs.lock.Unlock()
+
+	dfs.uploads.Wait()
+
+	dfs.lock.Lock()
+	defer dfs.lock.Unlock()
+	var notUploaded []string
+	for p, file := range dfs.files {
+		file.lock.Lock()
+		if file.dirty {
+			dfs.log.Warn("the changed content is not uploaded, kept in the cache", "path", p, "cache", file.localPath)
+			notUploaded = append(notUploaded, p)
+		} else if err := os.Remove(file.localPath); err != nil && !os.IsNotExist(err) {
+			dfs.log.Warn("failed to remove the cached content", "path", p, "err", err)
+		}
+		file.lock.Unlock()
+	}
+	if len(notUploaded) != 0 {
+		sort.Strings(notUploaded)
+		return fmt.Errorf("the changed content of %v is not uploaded, kept in the cache %v", notUploaded, dfs.cacheDir)
+	}
+	return nil
+}
+
+// release closes a handle of the file. When the last handle is released, the changed
+// content is uploaded in background, and the cached content is evicted once uploaded
+func (dfs *DxFS) release(file *cachedFile) {
+	dfs.lock.Lock()
+	defer dfs.lock.Unlock()
+	file.lock.Lock()
+	defer file.lock.Unlock()
+	if file.opened > 0 {
+		file.opened--
+	}
+	if file.opened > 0 || dfs.files[file.filePath] != file {
+		return
+	}
+	if !file.dirty {
+		delete(dfs.files, file.filePath)
+		os.Remove(file.localPath)
+		return
+	}
+
+	// the path is read under the lock, it could be changed by rename during the upload
+	filePath := file.filePath
+	dfs.uploads.Add(1)
+	go func() {
+		defer dfs.uploads.Done()
+		if err := dfs.uploadWithRetry(file, filePath); err != nil {
+			dfs.log.Error("failed to upload the file written, kept in the cache", "path", filePath, "cache", file.localPath, "err", err)
+			return
+		}
+		dfs.evict(file)
+	}()
+}
+
+// uploadWithRetry uploads the content of the file, and retries the failed upload with the
+// interval doubled after each failure. The retry is stopped when the filesystem is closed
+func (dfs *DxFS) uploadWithRetry(file *cachedFile, filePath string) (err error) {
+	interval := dfs.retryInterval
+	for retry := 0; ; retry++ {
+		if err = dfs.upload(file); err == nil || retry == uploadRetries {
+			return
+		}
+		dfs.log.Warn("failed to upload the file written, retry later", "path", filePath, "retry", interval, "err", err)
+		select {
+		case <-time.After(interval):
+		case <-dfs.quit:
+			return
+		}
+		interval *= 2
+	}
+}
+
+// readRange reads the range of the file not materialized from the storage client
+func (dfs *DxFS) readRange(filePath string, size int64, b []byte, off int64) (int, error) {
+	if off >= size || len(b) == 0 {
+		return 0, nil
+	}
+	length := int64(len(b))
+	if off+length > size {
+		length = size - off
+	}
+	dxPath, err := storage.NewDxPath(filePath)
+	if err != nil {
+		return 0, err
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, length))
+	if err = dfs.backend.DownloadRange(dxPath, buf, uint64(off), uint64(length)); err != nil {
+		return 0, fmt.Errorf("failed to download %v: %s", filePath, err.Error())
+	}
+	return copy(b, buf.Bytes()), nil
+}
+
+// upload uploads the content of the file if changed. The cached content is copied to a new
+// snapshot, which is used as the local source of the dxfile, so that the following writes
+// change neither the source of an upload in progress nor the source of the dxfile replaced
+func (dfs *DxFS) upload(file *cachedFile) error {
+	file.lock.Lock()
+	defer file.lock.Unlock()
+	if !file.dirty {
+		return nil
+	}
+
+	dxPath, err := storage.NewDxPath(file.filePath)
+	if err != nil {
+		return err
+	}
+	snapshot, err := dfs.snapshot(file.localPath, dxPath)
+	if err != nil {
+		return err
+	}
+	if err = dfs.backend.Upload(dxPath, snapshot); err != nil {
+		os.Remove(snapshot)
+		return err
+	}
+	file.dirty, file.remote = false, true
+	return nil
+}
+
+// snapshot copies the cached content to a new snapshot of the dxfile
+func (dfs *DxFS) snapshot(localPath string, dxPath storage.DxPath) (string, error) {
+	base := string(dxPath.SysPath(storage.SysPath(SnapshotDir(dfs.cacheDir))))
+	if err := os.MkdirAll(filepath.Dir(base), 0700); err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(base), filepath.Base(base)+".")
+	if err != nil {
+		return "", err
+	}
+	snapshot := f.Name()
+	if err = f.Close(); err != nil {
+		os.Remove(snapshot)
+		return "", err
+	}
+	if err = copyFile(localPath, snapshot); err != nil {
+		os.Remove(snapshot)
+		return "", err
+	}
+	return snapshot, nil
+}
+
+// evict removes the cached file if it is neither opened nor changed
+func (dfs *DxFS) evict(file *cachedFile) {
+	dfs.lock.Lock()
+	defer dfs.lock.Unlock()
+	file.lock.Lock()
+	defer file.lock.Unlock()
+	if file.opened > 0 || file.dirty || dfs.files[file.filePath] != file {
+		return
+	}
+	delete(dfs.files, file.filePath)
+	os.Remove(file.localPath)
+}
+
+// cachePath returns the local path caching the content of the file
+func (dfs *DxFS) cachePath(p string) string {
+	return filepath.Join(dfs.cacheDir, cacheFilesDir, filepath.FromSlash(p))
+}
+
+// SnapshotDir returns the directory holding the snapshots used as the upload source in
+// the cache directory
+func SnapshotDir(cacheDir string) string {
+	return filepath.Join(cacheDir, snapshotDir)
+}
+
+// cleanPath returns the path relative to the root, root is returned as an empty string
+func cleanPath(p string) string {
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+// orRoot returns the directory of the files directly in dir as returned by path.Dir
+func orRoot(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
+
+// copyFile copies the content of src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,403 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package dxfuse
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// memBackend is the Backend keeping the dxfiles in memory
+type memBackend struct {
+	lock           sync.Mutex
+	files          map[string][]byte
+	uploads        int
+	downloads      int
+	rangeDownloads int
+	uploadErr      error
+	uploadFailures int
+}
+
+func newMemBackend(files map[string][]byte) *memBackend {
+	return &memBackend{files: files}
+}
+
+func (mb *memBackend) FileList() (list []storage.FileBriefInfo) {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	for p := range mb.files {
+		list = append(list, storage.FileBriefInfo{Path: p})
+	}
+	return
+}
+
+func (mb *memBackend) FileSize(dxPath storage.DxPath) (uint64, error) {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	content, exists := mb.files[dxPath.Path]
+	if !exists {
+		return 0, errNotExist
+	}
+	return uint64(len(content)), nil
+}
+
+func (mb *memBackend) Download(dxPath storage.DxPath, localPath string) error {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	mb.downloads++
+	return ioutil.WriteFile(localPath, mb.files[dxPath.Path], 0600)
+}
+
+func (mb *memBackend) DownloadRange(dxPath storage.DxPath, w io.Writer, offset, length uint64) error {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	mb.rangeDownloads++
+	_, err := w.Write(mb.files[dxPath.Path][offset : offset+length])
+	return err
+}
+
+func (mb *memBackend) Upload(dxPath storage.DxPath, localPath string) error {
+	content, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	if mb.uploadErr != nil {
+		return mb.uploadErr
+	}
+	if mb.uploadFailures > 0 {
+		mb.uploadFailures--
+		return errors.New("upload failed")
+	}
+	mb.uploads++
+	mb.files[dxPath.Path] = content
+	return nil
+}
+
+func (mb *memBackend) Delete(dxPath storage.DxPath) error {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	delete(mb.files, dxPath.Path)
+	return nil
+}
+
+func (mb *memBackend) Rename(prevPath, newPath storage.DxPath) error {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	mb.files[newPath.Path] = mb.files[prevPath.Path]
+	delete(mb.files, prevPath.Path)
+	return nil
+}
+
+func newTestDxFS(t *testing.T, files map[string][]byte) (*DxFS, *memBackend, func()) {
+	dir, err := ioutil.TempDir("", "dxfuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := newMemBackend(files)
+	dfs, err := New(backend, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dfs, backend, func() { os.RemoveAll(dir) }
+}
+
+func TestDxFS_ReadDir(t *testing.T) {
+	dfs, _, clean := newTestDxFS(t, map[string][]byte{
+		"a/b/c": []byte("abc"),
+		"a/d":   []byte("d"),
+		"e":     nil,
+	})
+	defer clean()
+
+	if err := dfs.Mkdir("a/f"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		dir    string
+		expect []Entry
+	}{
+		{"", []Entry{{Name: "a", Dir: true}, {Name: "e"}}},
+		{"/a", []Entry{{Name: "b", Dir: true}, {Name: "d"}, {Name: "f", Dir: true}}},
+		{"a/b/", []Entry{{Name: "c"}}},
+		{"a/f", []Entry{}},
+	}
+	for _, test := range tests {
+		entries, err := dfs.ReadDir(test.dir)
+		if err != nil {
+			t.Fatalf("read dir %v: %v", test.dir, err)
+		}
+		if !reflect.DeepEqual(entries, test.expect) {
+			t.Errorf("read dir %v: expect %v, got %v", test.dir, test.expect, entries)
+		}
+	}
+	if _, err := dfs.ReadDir("a/d"); err != errNotExist {
+		t.Errorf("read dir of a file: expect %v, got %v", errNotExist, err)
+	}
+
+	entry, err := dfs.Stat("a/b/c")
+	if err != nil || entry.Size != 3 || entry.Dir {
+		t.Errorf("stat a/b/c not expected: %+v, %v", entry, err)
+	}
+	if err = dfs.Remove("a"); err != errDirNotEmpty {
+		t.Errorf("remove non-empty directory: expect %v, got %v", errDirNotEmpty, err)
+	}
+	if err = dfs.Remove("a/f"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = dfs.Stat("a/f"); err != errNotExist {
+		t.Errorf("stat removed directory: expect %v, got %v", errNotExist, err)
+	}
+}
+
+func TestDxFS_WriteBack(t *testing.T) {
+	dfs, backend, clean := newTestDxFS(t, map[string][]byte{
+		"dir/old": []byte("old content"),
+	})
+	defer clean()
+
+	// writes are not uploaded till the file is released
+	h, err := dfs.Create("dir/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.WriteAt([]byte("hello"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.WriteAt([]byte(" world"), 5); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := dfs.Stat("dir/new"); err != nil || entry.Size != 11 {
+		t.Fatalf("stat of the file written not expected: %+v, %v", entry, err)
+	}
+	if _, exists := backend.files["dir/new"]; exists {
+		t.Fatalf("the file should not be uploaded before released")
+	}
+	h.Release()
+
+	// the existing file is downloaded when opened for write, and uploaded on sync
+	if h, err = dfs.Open("dir/old", true); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 20)
+	n, err := h.ReadAt(buf, 4)
+	if err != nil || string(buf[:n]) != "content" {
+		t.Fatalf("read not expected: %q, %v", buf[:n], err)
+	}
+	if err = h.Truncate(3); err != nil {
+		t.Fatal(err)
+	}
+	if err = dfs.Sync("dir/old"); err != nil {
+		t.Fatal(err)
+	}
+	h.Release()
+
+	if err = dfs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string][]byte{
+		"dir/new": []byte("hello world"),
+		"dir/old": []byte("old"),
+	}
+	if !reflect.DeepEqual(backend.files, expect) {
+		t.Errorf("files not expected: %q", backend.files)
+	}
+	if backend.uploads != 2 || backend.downloads != 1 {
+		t.Errorf("expect 2 uploads and 1 download, got %v and %v", backend.uploads, backend.downloads)
+	}
+	if len(dfs.files) != 0 {
+		t.Errorf("the released files should be evicted from the cache")
+	}
+	if _, err = dfs.Open("dir/old", false); err != errClosed {
+		t.Errorf("open after closed: expect %v, got %v", errClosed, err)
+	}
+}
+
+func TestDxFS_RenameRemove(t *testing.T) {
+	dfs, backend, clean := newTestDxFS(t, map[string][]byte{
+		"a": []byte("a"),
+		"b": []byte("b"),
+	})
+	defer clean()
+
+	// the file created but not uploaded is only renamed locally, and the handle opened
+	// keeps working after the rename
+	h, err := dfs.Create("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dfs.Rename("c", "d"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dfs.Stat("c"); err != errNotExist {
+		t.Errorf("stat of the renamed file: expect %v, got %v", errNotExist, err)
+	}
+	if _, err := h.WriteAt([]byte("c"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if h.Path() != "d" {
+		t.Errorf("the path of the handle should be renamed, got %v", h.Path())
+	}
+	if err := h.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backend.files["d"], []byte("c")) {
+		t.Errorf("the renamed file should be uploaded to the new path: %q", backend.files)
+	}
+	h.Release()
+	h.Release()
+
+	// renaming to an existing file replaces it
+	if err := dfs.Rename("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dfs.Remove("d"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dfs.Create("b"); err != errExist {
+		t.Errorf("create existing file: expect %v, got %v", errExist, err)
+	}
+	if err := dfs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backend.files) != 1 || !bytes.Equal(backend.files["b"], []byte("a")) {
+		t.Errorf("files not expected: %q", backend.files)
+	}
+}
+
+func TestDxFS_UploadFailed(t *testing.T) {
+	dfs, backend, clean := newTestDxFS(t, map[string][]byte{
+		"a": []byte("old"),
+	})
+	defer clean()
+
+	h, err := dfs.Open("a", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = h.WriteAt([]byte("new content"), 0); err != nil {
+		t.Fatal(err)
+	}
+	backend.uploadErr = errors.New("upload failed")
+	if err = h.Sync(); err != backend.uploadErr {
+		t.Fatalf("expect %v, got %v", backend.uploadErr, err)
+	}
+	h.Release()
+
+	// the existing dxfile is kept, and the changed content is kept in the cache
+	if err = dfs.Close(); err == nil {
+		t.Fatal("the content not uploaded should be reported when closed")
+	}
+	if !bytes.Equal(backend.files["a"], []byte("old")) {
+		t.Errorf("the existing file should be kept: %q", backend.files)
+	}
+	content, err := ioutil.ReadFile(dfs.cachePath("a"))
+	if err != nil || string(content) != "new content" {
+		t.Errorf("the changed content should be kept in the cache: %q, %v", content, err)
+	}
+	snapshots, err := ioutil.ReadDir(SnapshotDir(dfs.cacheDir))
+	if err != nil || len(snapshots) != 0 {
+		t.Errorf("the snapshot of the failed upload should be removed: %v, %v", snapshots, err)
+	}
+}
+
+func TestDxFS_ReadOnly(t *testing.T) {
+	dfs, backend, clean := newTestDxFS(t, map[string][]byte{
+		"a": []byte("hello world"),
+	})
+	defer clean()
+
+	// the file opened read only is read with the ranged downloads
+	h, err := dfs.Open("a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 20)
+	n, err := h.ReadAt(buf, 6)
+	if err != nil || string(buf[:n]) != "world" {
+		t.Fatalf("read not expected: %q, %v", buf[:n], err)
+	}
+	if n, err = h.ReadAt(buf, 11); err != nil || n != 0 {
+		t.Fatalf("read after the end not expected: %v, %v", n, err)
+	}
+	if _, err = h.WriteAt([]byte("a"), 0); err != errReadOnly {
+		t.Errorf("write to the file opened read only: expect %v, got %v", errReadOnly, err)
+	}
+	if backend.downloads != 0 || backend.rangeDownloads != 1 {
+		t.Errorf("expect 0 download and 1 ranged download, got %v and %v", backend.downloads, backend.rangeDownloads)
+	}
+
+	// the file is materialized when opened for write, and read from the cache afterwards
+	w, err := dfs.Open("a", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.WriteAt([]byte("H"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, err = h.ReadAt(buf, 0); err != nil || string(buf[:n]) != "Hello world" {
+		t.Errorf("read after written not expected: %q, %v", buf[:n], err)
+	}
+	if backend.downloads != 1 || backend.rangeDownloads != 1 {
+		t.Errorf("expect 1 download and 1 ranged download, got %v and %v", backend.downloads, backend.rangeDownloads)
+	}
+	h.Release()
+	w.Release()
+	if err = dfs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backend.files["a"], []byte("Hello world")) {
+		t.Errorf("files not expected: %q", backend.files)
+	}
+}
+
+func TestDxFS_UploadRetry(t *testing.T) {
+	dfs, backend, clean := newTestDxFS(t, map[string][]byte{})
+	defer clean()
+	dfs.retryInterval = time.Millisecond
+
+	// the upload failed in background is retried
+	backend.uploadFailures = 2
+	h, err := dfs.Create("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = h.WriteAt([]byte("content"), 0); err != nil {
+		t.Fatal(err)
+	}
+	h.Release()
+	for i := 0; ; i++ {
+		backend.lock.Lock()
+		uploads := backend.uploads
+		backend.lock.Unlock()
+		if uploads != 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("the file is not uploaded after retries")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err = dfs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backend.files["a"], []byte("content")) || backend.uploads != 1 {
+		t.Errorf("the file should be uploaded after retries: %q, %v uploads", backend.files, backend.uploads)
+	}
+	if len(dfs.files) != 0 {
+		t.Errorf("the uploaded file should be evicted from the cache")
+	}
+}
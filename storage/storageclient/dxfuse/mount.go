@@ -0,0 +1,318 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// +build linux darwin freebsd
+
+package dxfuse
+
+import (
+	"context"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+)
+
+// MountedFS is the DxFS mounted at the mount point
+type MountedFS struct {
+	dfs        *DxFS
+	conn       *fuse.Conn
+	mountPoint string
+	served     chan error
+}
+
+// Mount mounts the DxFS at the mount point, the requests are served in background
+// till unmounted
+func Mount(dfs *DxFS, mountPoint string) (*MountedFS, error) {
+	conn, err := fuse.Mount(mountPoint, fuse.FSName("dxfs"), fuse.Subtype("dxfs"))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MountedFS{
+		dfs:        dfs,
+		conn:       conn,
+		mountPoint: mountPoint,
+		served:     make(chan error, 1),
+	}
+	go func() {
+		m.served <- fs.Serve(conn, &root{dfs: dfs, nodes: newNodeSet()})
+	}()
+
+	<-conn.Ready
+	if err = conn.MountError; err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Unmount unmounts the filesystem, and waits for the written files to be uploaded
+func (m *MountedFS) Unmount() error {
+	if err := fuse.Unmount(m.mountPoint); err != nil {
+		return err
+	}
+	err := <-m.served
+	m.conn.Close()
+	if closeErr := m.dfs.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// root is the root of the mounted filesystem
+type root struct {
+	dfs   *DxFS
+	nodes *nodeSet
+}
+
+// Root returns the root directory
+func (r *root) Root() (fs.Node, error) {
+	return &dir{dfs: r.dfs, nodes: r.nodes}, nil
+}
+
+// dir is a directory node
+type dir struct {
+	dfs   *DxFS
+	nodes *nodeSet
+	path  string
+}
+
+// node is a file node. The kernel keeps using the same node after the file is renamed,
+// so the path of the node is updated on rename
+type node struct {
+	dfs   *DxFS
+	nodes *nodeSet
+
+	lock sync.Mutex
+	path string
+}
+
+// handle is an opened file
+type handle struct {
+	h *Handle
+}
+
+// nodeSet is the file nodes known by the kernel, indexed by the path
+type nodeSet struct {
+	lock  sync.Mutex
+	nodes map[string]*node
+}
+
+func newNodeSet() *nodeSet {
+	return &nodeSet{nodes: make(map[string]*node)}
+}
+
+// get returns the node of the path, the same node is returned till forgotten
+func (ns *nodeSet) get(dfs *DxFS, p string) *node {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	if n, exists := ns.nodes[p]; exists {
+		return n
+	}
+	n := &node{dfs: dfs, nodes: ns, path: p}
+	ns.nodes[p] = n
+	return n
+}
+
+// rename moves the node of the renamed file to the new path
+func (ns *nodeSet) rename(prev, cur string) {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	delete(ns.nodes, cur)
+	n, exists := ns.nodes[prev]
+	if !exists {
+		return
+	}
+	delete(ns.nodes, prev)
+	ns.nodes[cur] = n
+	n.lock.Lock()
+	n.path = cur
+	n.lock.Unlock()
+}
+
+// forget removes the node forgotten by the kernel
+func (ns *nodeSet) forget(n *node) {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	p := n.currentPath()
+	if ns.nodes[p] == n {
+		delete(ns.nodes, p)
+	}
+}
+
+// Attr fills the attributes of the directory
+func (d *dir) Attr(ctx context.Context, a *fuse.Attr) error {
+	a.Mode = os.ModeDir | 0700
+	return nil
+}
+
+// Lookup looks up the entry in the directory
+func (d *dir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	p := path.Join(d.path, name)
+	entry, err := d.dfs.Stat(p)
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	if entry.Dir {
+		return &dir{dfs: d.dfs, nodes: d.nodes, path: p}, nil
+	}
+	return d.nodes.get(d.dfs, p), nil
+}
+
+// ReadDirAll lists the entries in the directory
+func (d *dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	entries, err := d.dfs.ReadDir(d.path)
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	dirents := make([]fuse.Dirent, 0, len(entries))
+	for _, entry := range entries {
+		dirent := fuse.Dirent{Name: entry.Name, Type: fuse.DT_File}
+		if entry.Dir {
+			dirent.Type = fuse.DT_Dir
+		}
+		dirents = append(dirents, dirent)
+	}
+	return dirents, nil
+}
+
+// Create creates and opens the file
+func (d *dir) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
+	p := path.Join(d.path, req.Name)
+	h, err := d.dfs.Create(p)
+	if err != nil {
+		return nil, nil, toErrno(err)
+	}
+	return d.nodes.get(d.dfs, p), &handle{h: h}, nil
+}
+
+// Mkdir creates the directory
+func (d *dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error) {
+	p := path.Join(d.path, req.Name)
+	if err := d.dfs.Mkdir(p); err != nil {
+		return nil, toErrno(err)
+	}
+	return &dir{dfs: d.dfs, nodes: d.nodes, path: p}, nil
+}
+
+// Remove removes the file or the directory
+func (d *dir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
+	return toErrno(d.dfs.Remove(path.Join(d.path, req.Name)))
+}
+
+// Rename renames the file to the new directory
+func (d *dir) Rename(ctx context.Context, req *fuse.RenameRequest, newDir fs.Node) error {
+	target, ok := newDir.(*dir)
+	if !ok {
+		return fuse.EIO
+	}
+	prev, cur := path.Join(d.path, req.OldName), path.Join(target.path, req.NewName)
+	if err := d.dfs.Rename(prev, cur); err != nil {
+		return toErrno(err)
+	}
+	d.nodes.rename(prev, cur)
+	return nil
+}
+
+// currentPath returns the current path of the file
+func (n *node) currentPath() string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.path
+}
+
+// Forget removes the node forgotten by the kernel
+func (n *node) Forget() {
+	n.nodes.forget(n)
+}
+
+// Attr fills the attributes of the file
+func (n *node) Attr(ctx context.Context, a *fuse.Attr) error {
+	entry, err := n.dfs.Stat(n.currentPath())
+	if err != nil {
+		return toErrno(err)
+	}
+	a.Mode = 0600
+	a.Size = entry.Size
+	return nil
+}
+
+// Open opens the file. The file opened read only is read with the ranged downloads, and
+// the content of the file opened for write is downloaded when not cached
+func (n *node) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fs.Handle, error) {
+	write := !req.Flags.IsReadOnly() || req.Flags&fuse.OpenTruncate != 0
+	h, err := n.dfs.Open(n.currentPath(), write)
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	if req.Flags&fuse.OpenTruncate != 0 {
+		if err := h.Truncate(0); err != nil {
+			h.Release()
+			return nil, toErrno(err)
+		}
+	}
+	return &handle{h: h}, nil
+}
+
+// Setattr handles the truncate of the file
+func (n *node) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.SetattrResponse) error {
+	if req.Valid.Size() {
+		if err := n.dfs.Truncate(n.currentPath(), int64(req.Size)); err != nil {
+			return toErrno(err)
+		}
+	}
+	return n.Attr(ctx, &resp.Attr)
+}
+
+// Fsync uploads the changed content of the file
+func (n *node) Fsync(ctx context.Context, req *fuse.FsyncRequest) error {
+	return toErrno(n.dfs.Sync(n.currentPath()))
+}
+
+// Read reads the content of the file
+func (h *handle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
+	buf := make([]byte, req.Size)
+	n, err := h.h.ReadAt(buf, req.Offset)
+	resp.Data = buf[:n]
+	return toErrno(err)
+}
+
+// Write writes to the cached content
+func (h *handle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
+	n, err := h.h.WriteAt(req.Data, req.Offset)
+	resp.Size = n
+	return toErrno(err)
+}
+
+// Release closes the handle
+func (h *handle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	h.h.Release()
+	return nil
+}
+
+// toErrno converts the DxFS errors to the errno responded to the kernel
+func toErrno(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case errNotExist:
+		return fuse.ENOENT
+	case errExist:
+		return fuse.EEXIST
+	case errIsDir:
+		return fuse.Errno(syscall.EISDIR)
+	case errNotDir:
+		return fuse.Errno(syscall.ENOTDIR)
+	case errDirNotEmpty:
+		return fuse.Errno(syscall.ENOTEMPTY)
+	case errReadOnly:
+		return fuse.Errno(syscall.EBADF)
+	default:
+		return fuse.EIO
+	}
+}
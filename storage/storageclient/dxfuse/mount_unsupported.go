@@ -0,0 +1,20 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// +build !linux,!darwin,!freebsd
+
+package dxfuse
+
+// MountedFS is the DxFS mounted at the mount point
+type MountedFS struct{}
+
+// Mount is not supported on the platform
+func Mount(dfs *DxFS, mountPoint string) (*MountedFS, error) {
+	return nil, ErrNotSupported
+}
+
+// Unmount is not supported on the platform
+func (m *MountedFS) Unmount() error {
+	return ErrNotSupported
+}
@@ -6,6 +6,7 @@ package filesystem
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/DxChainNetwork/godx/storage"
 )
@@ -72,13 +73,21 @@ func (api *PublicFileSystemAPI) DetailedFileInfo(path string) storage.FileInfo {
 	return fileInfo
 }
 
-// FileList is the API function that returns all uploaded files
+// FileList is the API function that returns all uploaded files. The internal files of the
+// storage class transitions are not listed
 func (api *PublicFileSystemAPI) FileList() []storage.FileBriefInfo {
-	fileList, err := api.fs.fileList()
+	rawFileList, err := api.fs.fileList()
 	if err != nil {
 		api.fs.getLogger().Warn("cannot get the file list", "error", err)
 		return []storage.FileBriefInfo{}
 	}
+	fileList := []storage.FileBriefInfo{}
+	for _, file := range rawFileList {
+		if isInternalFile(file.Path) {
+			continue
+		}
+		fileList = append(fileList, file)
+	}
 	return fileList
 }
 
@@ -91,7 +100,7 @@ func (api *PublicFileSystemAPI) Uploads() []storage.FileBriefInfo {
 	}
 	var fileList []storage.FileBriefInfo
 	for _, file := range rawFileList {
-		if file.UploadProgress >= 100 {
+		if file.UploadProgress >= 100 || isInternalFile(file.Path) {
 			continue
 		}
 		fileList = append(fileList, file)
@@ -99,6 +108,12 @@ func (api *PublicFileSystemAPI) Uploads() []storage.FileBriefInfo {
 	return fileList
 }
 
+// Fsck is the API function that checks the integrity of all files and recovers the
+// corrupted ones. The files found corrupted since the last check are also reported
+func (api *PublicFileSystemAPI) Fsck() (FsckReport, error) {
+	return api.fs.Fsck()
+}
+
 // Rename is the API function that rename a file from prevPath to newPath
 func (api *PublicFileSystemAPI) Rename(prevPath, newPath string) string {
 	prevDxPath, err := storage.NewDxPath(prevPath)
@@ -146,3 +161,14 @@ func (api *PublicFileSystemAPI) Delete(path string) string {
 	}
 	return fmt.Sprintf("File %v deleted", path)
 }
+
+// isInternalFile checks whether the file is kept internally by the storage client while
+// replacing a file, either by a storage class transition or with the new content
+func isInternalFile(dxPath string) bool {
+	for _, suffix := range []string{storage.TransitionFileSuffix, storage.RetiredFileSuffix, storage.ReplacingFileSuffix, storage.ReplacedFileSuffix} {
+		if strings.HasSuffix(dxPath, suffix) {
+			return true
+		}
+	}
+	return false
+}
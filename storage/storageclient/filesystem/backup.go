@@ -0,0 +1,250 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxdir"
+)
+
+// errFileSystemNotEmpty is the error that a backup is restored to a file system which
+// already contains files
+var errFileSystemNotEmpty = errors.New("the file system already contains files")
+
+type (
+	// Backup is the backup of the file system, which contains the metadata of all DxDirs
+	// and the persisted content of all DxFiles
+	Backup struct {
+		Dirs  []dxdir.Metadata
+		Files []FileBackup
+	}
+
+	// FileBackup is the persisted content of a DxFile
+	FileBackup struct {
+		DxPath  storage.DxPath
+		Content []byte
+	}
+)
+
+// Backup walks through the file root directory and backups all DxDirs and DxFiles. Each file
+// is read under its lock, so the file content is always consistent
+func (fs *fileSystem) Backup() (backup Backup, err error) {
+	if err = fs.tm.Add(); err != nil {
+		return
+	}
+	defer fs.tm.Done()
+
+	err = filepath.Walk(string(fs.fileRootDir), func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if filepath.Base(path) == dxdir.DirFileName {
+			dxPath, err := fs.dxPathFromSysPath(filepath.Dir(path), "")
+			if err != nil {
+				return err
+			}
+			dir, err := fs.dirSet.Open(dxPath)
+			if err != nil {
+				return err
+			}
+			backup.Dirs = append(backup.Dirs, dir.Metadata())
+			return dir.Close()
+		}
+		if filepath.Ext(path) != storage.DxFileExt {
+			return nil
+		}
+		dxPath, err := fs.dxPathFromSysPath(path, storage.DxFileExt)
+		if err != nil {
+			return err
+		}
+		file, err := fs.fileSet.Open(dxPath)
+		if err != nil {
+			return err
+		}
+		content, err := file.Backup()
+		if errClose := file.Close(); err == nil {
+			err = errClose
+		}
+		if err != nil {
+			return fmt.Errorf("cannot backup file %v: %v", dxPath.Path, err)
+		}
+		backup.Files = append(backup.Files, FileBackup{DxPath: dxPath, Content: content})
+		return nil
+	})
+	return
+}
+
+// Restore restores the DxDirs and DxFiles from the backup. It can only be used when the
+// file system does not have any file. The backup is validated before anything is written,
+// and the restored DxDirs and DxFiles are removed if any of them cannot be restored. After
+// restored, the metadata of the directories are updated with the health of the restored files
+func (fs *fileSystem) Restore(backup Backup) (err error) {
+	if err = fs.tm.Add(); err != nil {
+		return err
+	}
+	defer fs.tm.Done()
+
+	if err = ValidateBackup(backup); err != nil {
+		return err
+	}
+	files, err := fs.fileList()
+	if err != nil {
+		return err
+	}
+	if len(files) != 0 {
+		return errFileSystemNotEmpty
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if errRemove := fs.removeRestored(backup); errRemove != nil {
+			fs.logger.Warn("cannot remove the restored files", "err", errRemove)
+		}
+	}()
+
+	// restore the directories from the root, so that the parent is created before the children
+	dirs := make([]dxdir.Metadata, len(backup.Dirs))
+	copy(dirs, backup.Dirs)
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i].DxPath.Path) < len(dirs[j].DxPath.Path)
+	})
+	for _, md := range dirs {
+		if !fs.dirSet.Exists(md.DxPath) {
+			dir, err := fs.dirSet.NewDxDir(md.DxPath)
+			if err != nil {
+				return fmt.Errorf("cannot restore directory %v: %v", md.DxPath.Path, err)
+			}
+			if err = dir.Close(); err != nil {
+				return err
+			}
+		}
+		md.RootPath = fs.fileRootDir
+		if err := fs.dirSet.UpdateMetadata(md.DxPath, md); err != nil {
+			return fmt.Errorf("cannot restore directory %v: %v", md.DxPath.Path, err)
+		}
+	}
+
+	// restore the files, and update the metadata of the directories containing the files
+	updateDirs := make(map[storage.DxPath]struct{})
+	for _, file := range backup.Files {
+		if err := fs.fileSet.Restore(file.DxPath, file.Content); err != nil {
+			return err
+		}
+		parent, err := file.DxPath.Parent()
+		if err != nil {
+			return err
+		}
+		updateDirs[parent] = struct{}{}
+	}
+	for dxPath := range updateDirs {
+		if err := fs.InitAndUpdateDirMetadata(dxPath); err != nil {
+			fs.logger.Warn("cannot update the metadata of the restored directory", "path", dxPath.Path, "err", err)
+		}
+	}
+	return nil
+}
+
+// RemoveRestored removes the DxFiles and DxDirs restored from the backup, so that the file
+// system could be restored again. The directories still containing other files or
+// directories are kept
+func (fs *fileSystem) RemoveRestored(backup Backup) error {
+	if err := fs.tm.Add(); err != nil {
+		return err
+	}
+	defer fs.tm.Done()
+	return fs.removeRestored(backup)
+}
+
+// removeRestored removes the DxFiles in the backup, and then the DxDirs in the backup which
+// are left empty, from the deepest one. The metadata of the root directory is updated at last
+func (fs *fileSystem) removeRestored(backup Backup) (err error) {
+	for _, file := range backup.Files {
+		err = common.ErrCompose(err, fs.fileSet.Delete(file.DxPath))
+	}
+
+	dirs := make([]dxdir.Metadata, len(backup.Dirs))
+	copy(dirs, backup.Dirs)
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i].DxPath.Path) > len(dirs[j].DxPath.Path)
+	})
+	for _, md := range dirs {
+		if md.DxPath.IsRoot() {
+			continue
+		}
+		sysDir := string(fs.fileRootDir.Join(md.DxPath))
+		infos, errRead := ioutil.ReadDir(sysDir)
+		if os.IsNotExist(errRead) {
+			continue
+		}
+		if errRead != nil {
+			err = common.ErrCompose(err, errRead)
+			continue
+		}
+		if len(infos) > 1 || (len(infos) == 1 && infos[0].Name() != dxdir.DirFileName) {
+			continue
+		}
+		if fs.dirSet.Exists(md.DxPath) {
+			if errDelete := fs.dirSet.Delete(md.DxPath); errDelete != nil {
+				err = common.ErrCompose(err, errDelete)
+				continue
+			}
+		}
+		err = common.ErrCompose(err, os.Remove(sysDir))
+	}
+	return common.ErrCompose(err, fs.InitAndUpdateDirMetadata(storage.RootDxPath()))
+}
+
+// ValidateBackup checks the paths of the DxDirs and DxFiles in the backup, and that each
+// DxFile is included once
+func ValidateBackup(backup Backup) error {
+	for _, md := range backup.Dirs {
+		if md.DxPath.IsRoot() {
+			continue
+		}
+		if _, err := storage.NewDxPath(md.DxPath.Path); err != nil {
+			return fmt.Errorf("invalid directory %v in the backup: %v", md.DxPath.Path, err)
+		}
+	}
+	files := make(map[string]struct{})
+	for _, file := range backup.Files {
+		if file.DxPath.IsRoot() {
+			return errors.New("invalid file with the root path in the backup")
+		}
+		if _, err := storage.NewDxPath(file.DxPath.Path); err != nil {
+			return fmt.Errorf("invalid file %v in the backup: %v", file.DxPath.Path, err)
+		}
+		if _, exist := files[file.DxPath.Path]; exist {
+			return fmt.Errorf("duplicate file %v in the backup", file.DxPath.Path)
+		}
+		files[file.DxPath.Path] = struct{}{}
+	}
+	return nil
+}
+
+// dxPathFromSysPath returns the DxPath of the system path under the file root directory,
+// with the suffix trimmed
+func (fs *fileSystem) dxPathFromSysPath(path string, suffix string) (storage.DxPath, error) {
+	str := strings.TrimSuffix(strings.TrimPrefix(path, string(fs.fileRootDir)), suffix)
+	if strings.Trim(str, "/") == "" {
+		return storage.RootDxPath(), nil
+	}
+	return storage.NewDxPath(str)
+}
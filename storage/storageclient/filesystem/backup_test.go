@@ -0,0 +1,124 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package filesystem
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestFileSystem_BackupRestore test backup the file system and restore it to an empty file system
+func TestFileSystem_BackupRestore(t *testing.T) {
+	ct := &AlwaysSuccessContractManager{}
+	fs := newEmptyTestFileSystem(t, "source", ct, newStandardDisrupter())
+	if err := fs.createRandomFiles(10, 0.5, 0.5, 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := fs.Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backup.Files) != 10 {
+		t.Fatalf("expect 10 files in backup, got %v", len(backup.Files))
+	}
+
+	restored := newEmptyTestFileSystem(t, "restored", ct, newStandardDisrupter())
+	if err = restored.Restore(backup); err != nil {
+		t.Fatal(err)
+	}
+	if err = restored.waitForUpdatesComplete(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range backup.Files {
+		// the health metadata of the restored file is updated, so the sectors are compared
+		entry, err := restored.OpenDxFile(file.DxPath)
+		if err != nil {
+			t.Fatalf("cannot open restored file %v: %v", file.DxPath.Path, err)
+		}
+		source, err := fs.OpenDxFile(file.DxPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.UID() != source.UID() || entry.NumSegments() != source.NumSegments() {
+			t.Errorf("restored file %v not equal to the source", file.DxPath.Path)
+		}
+		for i := 0; i != source.NumSegments(); i++ {
+			expect, _ := source.Sectors(i)
+			got, _ := entry.Sectors(i)
+			if !reflect.DeepEqual(expect, got) {
+				t.Errorf("restored file %v segment %v not equal to the source", file.DxPath.Path, i)
+			}
+		}
+		entry.Close()
+		source.Close()
+	}
+	for _, md := range backup.Dirs {
+		dir, err := restored.OpenDxDir(md.DxPath)
+		if err != nil {
+			t.Fatalf("cannot open restored dir %v: %v", md.DxPath.Path, err)
+		}
+		if dir.Metadata().RootPath != restored.fileRootDir {
+			t.Errorf("restored dir %v root path not updated", md.DxPath.Path)
+		}
+		dir.Close()
+	}
+
+	// restore to a file system with files should fail
+	if err = restored.Restore(backup); err != errFileSystemNotEmpty {
+		t.Errorf("expect error %v, got %v", errFileSystemNotEmpty, err)
+	}
+}
+
+// TestFileSystem_RestoreFailure test the restored files and dirs are removed if the restore
+// fails, so that the restore could be retried
+func TestFileSystem_RestoreFailure(t *testing.T) {
+	ct := &AlwaysSuccessContractManager{}
+	fs := newEmptyTestFileSystem(t, "source", ct, newStandardDisrupter())
+	if err := fs.createRandomFiles(10, 0.5, 0.5, 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := fs.Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last file is corrupted, which fails the restore after the other files are restored
+	corrupted := Backup{Dirs: backup.Dirs, Files: make([]FileBackup, len(backup.Files))}
+	copy(corrupted.Files, backup.Files)
+	last := &corrupted.Files[len(corrupted.Files)-1]
+	last.Content = []byte("corrupted")
+
+	restored := newEmptyTestFileSystem(t, "restored", ct, newStandardDisrupter())
+	if err = restored.Restore(corrupted); err == nil {
+		t.Fatal("restore with the corrupted file should fail")
+	}
+	files, err := restored.fileList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("the restored files should be removed, got %v files", len(files))
+	}
+	for _, md := range backup.Dirs {
+		if !md.DxPath.IsRoot() && restored.dirSet.Exists(md.DxPath) {
+			t.Errorf("the restored dir %v should be removed", md.DxPath.Path)
+		}
+	}
+
+	// the restore could be retried
+	if err = restored.Restore(backup); err != nil {
+		t.Fatalf("the restore should be retried: %v", err)
+	}
+	if files, err = restored.fileList(); err != nil || len(files) != len(backup.Files) {
+		t.Errorf("expect %v files restored, got %v: %v", len(backup.Files), len(files), err)
+	}
+
+	// the backup with duplicate files is rejected before anything is restored
+	duplicate := Backup{Files: []FileBackup{backup.Files[0], backup.Files[0]}}
+	if err = ValidateBackup(duplicate); err == nil {
+		t.Error("the backup with duplicate files should be rejected")
+	}
+}
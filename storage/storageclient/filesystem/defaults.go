@@ -35,6 +35,13 @@ const (
 
 	// updateWalName is the fileName for the updateWal
 	updateWalName = "update.wal"
+
+	// defaultMaxOpenDxFiles is the maximum number of DxFiles opened at the same time by
+	// the directory-wide operations
+	defaultMaxOpenDxFiles = 64
+
+	// alertModule is the module name of the alerts raised by the file system
+	alertModule = "filesystem"
 )
 
 const (
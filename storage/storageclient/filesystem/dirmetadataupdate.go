@@ -424,7 +424,10 @@ func (fs *fileSystem) loopDirAndCalculateDirMetadata(update *dirMetadataUpdate)
 		var md *metadataForUpdate
 		if ext == storage.DxFileExt {
 			// File type DxFile
-			md, err = fs.calculateDxFileMetadata(update.dxPath, file.Name())
+			md, err = fs.calculateDxFileMetadata(update, file.Name())
+			if err == errInterrupted || err == errStopped {
+				return nil, err
+			}
 			if err != nil {
 				fs.logger.Warn("cannot calculate the file metadata", "path", update.dxPath.Path, "err", err)
 				continue
@@ -449,15 +452,19 @@ func (fs *fileSystem) loopDirAndCalculateDirMetadata(update *dirMetadataUpdate)
 }
 
 // calculateDxFileMetadata update, calculate and apply the health related field of a dxfile.
-func (fs *fileSystem) calculateDxFileMetadata(path storage.DxPath, filename string) (*metadataForUpdate, error) {
-	// Deal with the file names. Input path is the DxPath of the target directory.
+func (fs *fileSystem) calculateDxFileMetadata(update *dirMetadataUpdate, filename string) (*metadataForUpdate, error) {
+	// Deal with the file names. Input update is the update of the target directory.
 	// filename is the system filename of the dxfile.
 	filenameNoSuffix := strings.TrimSuffix(filename, storage.DxFileExt)
-	fileDxPath, err := path.Join(filenameNoSuffix)
+	fileDxPath, err := update.dxPath.Join(filenameNoSuffix)
 	if err != nil {
 		return nil, err
 	}
-	// Open the DxPath
+	// Wait until the number of DxFiles opened is within the limit, and open the DxPath
+	if err = fs.openFiles.acquire(update.stop, fs.tm.StopChan()); err != nil {
+		return nil, err
+	}
+	defer fs.openFiles.release()
 	file, err := fs.fileSet.Open(fileDxPath)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open DxPath %v: %v", fileDxPath.Path, err)
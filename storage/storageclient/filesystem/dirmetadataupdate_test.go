@@ -617,7 +617,12 @@ func TestFileSystem_CorruptedFiles(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// corrupt the files
+	// corrupt the files. The shadow copy is removed and the file set is created again without
+	// the recent transactions, so that the corrupted dxfile cannot be recovered
+	if err = os.Remove(string(corruptedDxFile.FilePath()) + ".shadow"); err != nil {
+		t.Fatal(err)
+	}
+	fs.fileSet = dxfile.NewFileSet(fs.fileRootDir, fs.fileWal)
 	file, err := os.OpenFile(string(corruptedDxFile.FilePath()), os.O_RDWR, 0600)
 	if err != nil {
 		t.Fatal(err)
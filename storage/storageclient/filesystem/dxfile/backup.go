@@ -0,0 +1,53 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package dxfile
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// Backup returns the persisted content of the DxFile. The read lock is held while
+// reading the file, so no update could be applied during the backup
+func (df *DxFile) Backup() ([]byte, error) {
+	df.lock.RLock()
+	defer df.lock.RUnlock()
+	if df.deleted {
+		return nil, errors.New("cannot backup the file: file already deleted")
+	}
+	return ioutil.ReadFile(string(df.filePath))
+}
+
+// Restore writes the content returned by DxFile.Backup to the file specified by dxPath.
+// The content is validated by loading the DxFile, and the DxPath in the metadata must
+// match the dxPath. The file must not exist in the file set
+func (fs *FileSet) Restore(dxPath storage.DxPath, content []byte) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	if fs.exists(dxPath) {
+		return ErrFileExist
+	}
+	filePath := fs.filepath(dxPath)
+	if err := os.MkdirAll(filepath.Dir(string(filePath)), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(string(filePath), content, 0600); err != nil {
+		return err
+	}
+	df, err := readDxFile(filePath, fs.wal)
+	if err == nil && !df.metadata.DxPath.Equals(dxPath) {
+		err = fmt.Errorf("dxPath %v not match the metadata %v", dxPath.Path, df.metadata.DxPath.Path)
+	}
+	if err != nil {
+		os.Remove(string(filePath))
+		return fmt.Errorf("cannot restore the file %v: %v", dxPath.Path, err)
+	}
+	return nil
+}
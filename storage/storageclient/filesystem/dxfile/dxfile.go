@@ -53,6 +53,13 @@ type (
 		// filePath is full file path
 		filePath storage.SysPath
 
+		// journal records the updates applied to the file for recovery. It is shared
+		// among all DxFiles in the FileSet
+		journal *fileJournal
+
+		// hostTableChecksum is the checksum of the persisted host table
+		hostTableChecksum common.Hash
+
 		//cached field
 		erasureCode erasurecode.ErasureCoder
 		cipherKey   crypto.CipherKey
@@ -67,6 +74,9 @@ type (
 		Index   uint64
 		Stuck   bool
 		offset  uint64
+
+		// checksum is the checksum of the persisted segment
+		checksum common.Hash
 	}
 
 	// Sector is the Data for a single Sector, which has Data of merkle root and related host address
@@ -15,6 +15,7 @@ import (
 
 	"github.com/DxChainNetwork/godx/common/writeaheadlog"
 	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/log"
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
 )
@@ -39,6 +40,12 @@ type (
 		// filesMap is the mapping from dxPath to contents
 		filesMap map[storage.DxPath]*fileSetEntry
 
+		// journal records the recent updates of the DxFiles for recovery
+		journal *fileJournal
+
+		// recoveries is the records of the corrupted DxFiles found
+		recoveries map[storage.DxPath]RecoveryRecord
+
 		lock sync.Mutex
 		wal  *writeaheadlog.Wal
 	}
@@ -71,9 +78,11 @@ type (
 // NewFileSet create a new DxFileSet with provided rootDir and wal.
 func NewFileSet(rootDir storage.SysPath, wal *writeaheadlog.Wal) *FileSet {
 	return &FileSet{
-		rootDir:  rootDir,
-		filesMap: make(map[storage.DxPath]*fileSetEntry),
-		wal:      wal,
+		rootDir:    rootDir,
+		filesMap:   make(map[storage.DxPath]*fileSetEntry),
+		journal:    newFileJournal(),
+		recoveries: make(map[storage.DxPath]RecoveryRecord),
+		wal:        wal,
 	}
 }
 
@@ -91,6 +100,10 @@ func (fs *FileSet) NewDxFile(dxPath storage.DxPath, sourcePath storage.SysPath,
 	if err != nil {
 		return nil, err
 	}
+	df.journal = fs.journal
+	if err = df.saveShadow(); err != nil {
+		log.Warn("cannot save the shadow copy of DxFile", "path", dxPath.Path, "err", err)
+	}
 	// Assign a threadID to the new DxFile. Register the threadID to the entry.
 	entry := fs.newFileSetEntry(df)
 	threadID := randomThreadID()
@@ -128,8 +141,9 @@ func (fs *FileSet) Open(dxPath storage.DxPath) (*FileSetEntryWithID, error) {
 func (fs *FileSet) open(dxPath storage.DxPath) (*FileSetEntryWithID, error) {
 	entry, exist := fs.filesMap[dxPath]
 	if !exist {
-		// file not loaded or not exist. Try to read DxFile from disk.
-		df, err := readDxFile(fs.filepath(dxPath), fs.wal)
+		// file not loaded or not exist. Try to read DxFile from disk. Corrupted file is
+		// recovered if possible.
+		df, _, err := fs.load(dxPath)
 		if os.IsNotExist(err) {
 			return nil, ErrUnknownFile
 		}
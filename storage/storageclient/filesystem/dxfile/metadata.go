@@ -9,6 +9,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/crypto"
 	"github.com/DxChainNetwork/godx/log"
 	"github.com/DxChainNetwork/godx/storage"
@@ -61,6 +62,17 @@ type (
 
 		// Version control for fork
 		Version string
+
+		// the fields below are encoded in the RLP tail
+
+		// ContentChecksum is the checksum of the persisted host table and segments. It is
+		// updated every time the metadata is saved, and is empty for the files saved before
+		// the checksum is introduced
+		ContentChecksum common.Hash
+
+		// SourceChecksum is the checksum of the local file content when uploaded. It is
+		// calculated in background after the upload starts, and is empty until calculated
+		SourceChecksum common.Hash
 	}
 
 	// UpdateMetaData is the Metadata to be updated
@@ -100,6 +112,22 @@ func (df *DxFile) SetLocalPath(path storage.SysPath) error {
 	return df.saveMetadata()
 }
 
+// SourceChecksum return the checksum of the local file recorded when uploaded
+func (df *DxFile) SourceChecksum() common.Hash {
+	df.lock.RLock()
+	defer df.lock.RUnlock()
+	return df.metadata.SourceChecksum
+}
+
+// SetSourceChecksum change the value of source checksum and save to disk
+func (df *DxFile) SetSourceChecksum(checksum common.Hash) error {
+	df.lock.Lock()
+	defer df.lock.Unlock()
+
+	df.metadata.SourceChecksum = checksum
+	return df.saveMetadata()
+}
+
 // DxPath return dxfile.metadata.DxPath
 func (df *DxFile) DxPath() storage.DxPath {
 	df.lock.RLock()
@@ -5,15 +5,20 @@
 package dxfile
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"sort"
 
 	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/crypto"
 	"github.com/DxChainNetwork/godx/log"
 	"github.com/DxChainNetwork/godx/p2p/enode"
 	"github.com/DxChainNetwork/godx/rlp"
+	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
 )
 
@@ -28,6 +33,10 @@ const (
 	segmentPersistOverhead = 32
 )
 
+// errChecksumMismatch is the error that the persisted content does not match the checksum
+// in metadata
+var errChecksumMismatch = errors.New("content checksum mismatch")
+
 type (
 	// persistHostTable is unmarshaled form of hostTable. Instead of a map, it is marshaled as slice
 	persistHostTable []*persistHostAddress
@@ -45,6 +54,38 @@ type (
 		Stuck   bool        // Stuck indicates whether the Segment is Stuck or not
 	}
 
+	// persistMetadata is the persist Data structure of Metadata. The fields added to the
+	// metadata after Version are encoded in the tail in the order added, so that the dxfiles
+	// saved before the fields are introduced can still be decoded
+	persistMetadata struct {
+		ID                  FileID
+		HostTableOffset     uint64
+		SegmentOffset       uint64
+		FileSize            uint64
+		SectorSize          uint64
+		LocalPath           storage.SysPath
+		DxPath              storage.DxPath
+		CipherKeyCode       uint8
+		CipherKey           []byte
+		TimeModify          uint64
+		TimeUpdate          uint64
+		TimeAccess          uint64
+		TimeCreate          uint64
+		Health              uint32
+		StuckHealth         uint32
+		TimeLastHealthCheck uint64
+		NumStuckSegments    uint32
+		TimeRecentRepair    uint64
+		LastRedundancy      uint32
+		FileMode            os.FileMode
+		ErasureCodeType     uint8
+		MinSectors          uint32
+		NumSectors          uint32
+		ECExtra             []byte
+		Version             string
+		Tail                []rlp.RawValue `rlp:"tail"`
+	}
+
 	// persistSector is the smallest unit of storage. It the erasure code encoded persistSegment
 	persistSector struct {
 		MerkleRoot common.Hash
@@ -62,6 +103,10 @@ func (ht hostTable) EncodeRLP(w io.Writer) error {
 		}
 		pht = append(pht, pha)
 	}
+	// sort the slice so that the same host table always has the same encoding
+	sort.Slice(pht, func(i, j int) bool {
+		return bytes.Compare(pht[i].Address[:], pht[j].Address[:]) < 0
+	})
 	return rlp.Encode(w, pht)
 }
 
@@ -121,6 +166,94 @@ func (s *Segment) DecodeRLP(st *rlp.Stream) error {
 	return nil
 }
 
+// EncodeRLP of Metadata implements rlp encode rule, the fields added after Version are
+// encoded in the tail
+func (md Metadata) EncodeRLP(w io.Writer) error {
+	contentChecksum, err := rlp.EncodeToBytes(md.ContentChecksum)
+	if err != nil {
+		return err
+	}
+	sourceChecksum, err := rlp.EncodeToBytes(md.SourceChecksum)
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, persistMetadata{
+		ID:                  md.ID,
+		HostTableOffset:     md.HostTableOffset,
+		SegmentOffset:       md.SegmentOffset,
+		FileSize:            md.FileSize,
+		SectorSize:          md.SectorSize,
+		LocalPath:           md.LocalPath,
+		DxPath:              md.DxPath,
+		CipherKeyCode:       md.CipherKeyCode,
+		CipherKey:           md.CipherKey,
+		TimeModify:          md.TimeModify,
+		TimeUpdate:          md.TimeUpdate,
+		TimeAccess:          md.TimeAccess,
+		TimeCreate:          md.TimeCreate,
+		Health:              md.Health,
+		StuckHealth:         md.StuckHealth,
+		TimeLastHealthCheck: md.TimeLastHealthCheck,
+		NumStuckSegments:    md.NumStuckSegments,
+		TimeRecentRepair:    md.TimeRecentRepair,
+		LastRedundancy:      md.LastRedundancy,
+		FileMode:            md.FileMode,
+		ErasureCodeType:     md.ErasureCodeType,
+		MinSectors:          md.MinSectors,
+		NumSectors:          md.NumSectors,
+		ECExtra:             md.ECExtra,
+		Version:             md.Version,
+		Tail:                []rlp.RawValue{contentChecksum, sourceChecksum},
+	})
+}
+
+// DecodeRLP of Metadata implements rlp decode rule, the fields missing in the tail are left
+// as zero
+func (md *Metadata) DecodeRLP(st *rlp.Stream) error {
+	var pm persistMetadata
+	if err := st.Decode(&pm); err != nil {
+		return err
+	}
+	*md = Metadata{
+		ID:                  pm.ID,
+		HostTableOffset:     pm.HostTableOffset,
+		SegmentOffset:       pm.SegmentOffset,
+		FileSize:            pm.FileSize,
+		SectorSize:          pm.SectorSize,
+		LocalPath:           pm.LocalPath,
+		DxPath:              pm.DxPath,
+		CipherKeyCode:       pm.CipherKeyCode,
+		CipherKey:           pm.CipherKey,
+		TimeModify:          pm.TimeModify,
+		TimeUpdate:          pm.TimeUpdate,
+		TimeAccess:          pm.TimeAccess,
+		TimeCreate:          pm.TimeCreate,
+		Health:              pm.Health,
+		StuckHealth:         pm.StuckHealth,
+		TimeLastHealthCheck: pm.TimeLastHealthCheck,
+		NumStuckSegments:    pm.NumStuckSegments,
+		TimeRecentRepair:    pm.TimeRecentRepair,
+		LastRedundancy:      pm.LastRedundancy,
+		FileMode:            pm.FileMode,
+		ErasureCodeType:     pm.ErasureCodeType,
+		MinSectors:          pm.MinSectors,
+		NumSectors:          pm.NumSectors,
+		ECExtra:             pm.ECExtra,
+		Version:             pm.Version,
+	}
+	if len(pm.Tail) > 0 {
+		if err := rlp.DecodeBytes(pm.Tail[0], &md.ContentChecksum); err != nil {
+			return err
+		}
+	}
+	if len(pm.Tail) > 1 {
+		if err := rlp.DecodeBytes(pm.Tail[1], &md.SourceChecksum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // segmentPersistSize is the helper function to calculate the number of pages to be used for
 // the persist of a Segment
 func segmentPersistNumPages(numSectors uint32) uint64 {
@@ -207,3 +340,31 @@ func (md Metadata) numSegments() uint64 {
 	}
 	return num
 }
+
+// contentChecksum returns the checksum of the persisted host table and segments, which is
+// calculated from the checksums of each persisted part
+func (df *DxFile) contentChecksum() common.Hash {
+	data := make([]byte, 0, common.HashLength*(len(df.segments)+1))
+	data = append(data, df.hostTableChecksum[:]...)
+	for _, seg := range df.segments {
+		var checksum common.Hash
+		if seg != nil {
+			checksum = seg.checksum
+		}
+		data = append(data, checksum[:]...)
+	}
+	return crypto.Keccak256Hash(data)
+}
+
+// verifyChecksum checks the loaded content against the checksum in metadata. The empty
+// checksum of the files saved before the checksum is introduced is not computed, and is not
+// verified
+func (df *DxFile) verifyChecksum() error {
+	if df.metadata.ContentChecksum == (common.Hash{}) {
+		return nil
+	}
+	if checksum := df.contentChecksum(); checksum != df.metadata.ContentChecksum {
+		return fmt.Errorf("%v: %x != %x", errChecksumMismatch, checksum, df.metadata.ContentChecksum)
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/crypto"
 	"github.com/DxChainNetwork/godx/crypto/twofishgcm"
 	"github.com/DxChainNetwork/godx/rlp"
@@ -111,6 +112,8 @@ func TestMetadata_EncodeRLP_DecodeRLP(t *testing.T) {
 		NumSectors:          30,
 		ECExtra:             []byte{},
 		Version:             "1.0.0",
+		ContentChecksum:     randomHash(),
+		SourceChecksum:      randomHash(),
 	}
 	b, err := rlp.EncodeToBytes(meta)
 	if err != nil {
@@ -125,3 +128,32 @@ func TestMetadata_EncodeRLP_DecodeRLP(t *testing.T) {
 		t.Errorf("not Equal\n\texpect %+v\n\tgot %+v", meta, md)
 	}
 }
+
+// TestMetadata_DecodeRLPLegacy test decoding the metadata saved before the fields in the tail
+// are introduced
+func TestMetadata_DecodeRLPLegacy(t *testing.T) {
+	legacy := persistMetadata{
+		HostTableOffset: PageSize,
+		SegmentOffset:   2 * PageSize,
+		FileSize:        randomUint64(),
+		CipherKey:       randomBytes(twofishgcm.GCMCipherKeyLength),
+		MinSectors:      10,
+		NumSectors:      30,
+		ECExtra:         []byte{},
+		Version:         "1.0.0",
+	}
+	b, err := rlp.EncodeToBytes(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var md Metadata
+	if err = rlp.DecodeBytes(b, &md); err != nil {
+		t.Fatal(err)
+	}
+	if md.FileSize != legacy.FileSize || !reflect.DeepEqual(md.CipherKey, legacy.CipherKey) || md.Version != legacy.Version {
+		t.Errorf("legacy metadata not decoded: %+v", md)
+	}
+	if md.ContentChecksum != (common.Hash{}) {
+		t.Errorf("the content checksum missing in the legacy metadata should be empty, got %x", md.ContentChecksum)
+	}
+}
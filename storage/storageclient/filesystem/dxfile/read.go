@@ -9,7 +9,9 @@ import (
 	"io"
 	"os"
 
+	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/common/writeaheadlog"
+	"github.com/DxChainNetwork/godx/crypto"
 	"github.com/DxChainNetwork/godx/rlp"
 	"github.com/DxChainNetwork/godx/storage"
 )
@@ -39,6 +41,9 @@ func readDxFile(filepath storage.SysPath, wal *writeaheadlog.Wal) (*DxFile, erro
 	if err = df.loadSegments(f); err != nil {
 		return nil, fmt.Errorf("cannot load segments: %v", err)
 	}
+	if err = df.verifyChecksum(); err != nil {
+		return nil, err
+	}
 	// New erasure code
 	if df.erasureCode, err = df.metadata.newErasureCode(); err != nil {
 		return nil, fmt.Errorf("cannot new erasureCode: %v", err)
@@ -47,6 +52,12 @@ func readDxFile(filepath storage.SysPath, wal *writeaheadlog.Wal) (*DxFile, erro
 	if df.cipherKey, err = df.metadata.newCipherKey(); err != nil {
 		return nil, fmt.Errorf("cannot new cipherKey: %v", err)
 	}
+	// backfill the content checksum of the file saved before the checksum is introduced
+	if df.metadata.ContentChecksum == (common.Hash{}) && wal != nil {
+		if err = df.saveMetadata(); err != nil {
+			return nil, fmt.Errorf("cannot backfill the content checksum: %v", err)
+		}
+	}
 	return df, nil
 }
 
@@ -82,6 +93,12 @@ func (df *DxFile) loadHostAddresses(f io.ReadSeeker) error {
 	if err != nil {
 		return err
 	}
+	// the host table is encoded again since the persisted order might differ
+	hostTableBytes, err := rlp.EncodeToBytes(df.hostTable)
+	if err != nil {
+		return err
+	}
+	df.hostTableChecksum = crypto.Keccak256Hash(hostTableBytes)
 	return nil
 }
 
@@ -102,6 +119,9 @@ func (df *DxFile) loadSegments(f io.ReadSeeker) error {
 			return fmt.Errorf("failed to load Segment at %d: %v", offset, err)
 		}
 		seg.offset = offset
+		if seg.Index >= uint64(len(df.segments)) {
+			return fmt.Errorf("unexpected Segment index %d at %d", seg.Index, seg.offset)
+		}
 		if df.segments[seg.Index] != nil {
 			return fmt.Errorf("duplicate Segment %d at %d", seg.Index, seg.offset)
 		}
@@ -128,5 +148,10 @@ func (df *DxFile) readSegment(f io.ReadSeeker, offset uint64) (*Segment, error)
 	if len(seg.Sectors) != int(df.metadata.NumSectors) {
 		return nil, fmt.Errorf("segment does not have expected numSectors")
 	}
+	segBytes, err := rlp.EncodeToBytes(seg)
+	if err != nil {
+		return nil, err
+	}
+	seg.checksum = crypto.Keccak256Hash(segBytes)
 	return seg, nil
 }
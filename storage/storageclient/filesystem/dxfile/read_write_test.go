@@ -8,12 +8,14 @@ import (
 	"bytes"
 	"fmt"
 	"math/rand"
+	"os"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/rlp"
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
 )
@@ -48,6 +50,63 @@ func TestPersist(t *testing.T) {
 	}
 }
 
+// TestPersist_BackfillChecksum test reading the dxfile saved before the content checksum is
+// introduced. The checksum is not verified, and is backfilled
+func TestPersist_BackfillChecksum(t *testing.T) {
+	df, err := newTestDxFileWithSegments(t, SectorSize<<6, 10, 30, erasurecode.ECTypeStandard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = df.saveAll(); err != nil {
+		t.Fatal(err)
+	}
+	// overwrite the metadata with the legacy layout without the content checksum
+	legacy := persistMetadata{
+		ID:              df.metadata.ID,
+		HostTableOffset: df.metadata.HostTableOffset,
+		SegmentOffset:   df.metadata.SegmentOffset,
+		FileSize:        df.metadata.FileSize,
+		SectorSize:      df.metadata.SectorSize,
+		LocalPath:       df.metadata.LocalPath,
+		DxPath:          df.metadata.DxPath,
+		CipherKeyCode:   df.metadata.CipherKeyCode,
+		CipherKey:       df.metadata.CipherKey,
+		FileMode:        df.metadata.FileMode,
+		ErasureCodeType: df.metadata.ErasureCodeType,
+		MinSectors:      df.metadata.MinSectors,
+		NumSectors:      df.metadata.NumSectors,
+		ECExtra:         df.metadata.ECExtra,
+		Version:         df.metadata.Version,
+	}
+	b, err := rlp.EncodeToBytes(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(string(df.filePath), os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteAt(b, 0)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newDF, err := readDxFile(df.filePath, df.wal)
+	if err != nil {
+		t.Fatalf("the legacy dxfile should be read: %v", err)
+	}
+	if newDF.metadata.ContentChecksum != df.metadata.ContentChecksum {
+		t.Errorf("the content checksum not backfilled: %x != %x", newDF.metadata.ContentChecksum, df.metadata.ContentChecksum)
+	}
+	if newDF, err = readDxFile(df.filePath, nil); err != nil {
+		t.Fatal(err)
+	}
+	if newDF.metadata.ContentChecksum != df.metadata.ContentChecksum {
+		t.Errorf("the backfilled content checksum not saved: %x != %x", newDF.metadata.ContentChecksum, df.metadata.ContentChecksum)
+	}
+}
+
 // TestDxFile_SaveHostTableUpdate test the shift functionality of persistence
 func TestDxFile_SaveHostTableUpdate(t *testing.T) {
 	//Two scenarios has to be tested:
@@ -208,6 +267,9 @@ func checkMetadataEqual(md1, md2 *Metadata) error {
 	if md1.LocalPath != md2.LocalPath {
 		return fmt.Errorf("md.LocalPath not equal:\n\t%+v\n\t%+v", md1.LocalPath, md2.LocalPath)
 	}
+	if md1.SourceChecksum != md2.SourceChecksum {
+		return fmt.Errorf("md.SourceChecksum not equal:\n\t%+v\n\t%+v", md1.SourceChecksum, md2.SourceChecksum)
+	}
 	if md1.DxPath != md2.DxPath {
 		return fmt.Errorf("md.DxPath not equal:\n\t%+v\n\t%+v", md1.DxPath, md2.DxPath)
 	}
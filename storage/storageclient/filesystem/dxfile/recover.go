@@ -0,0 +1,395 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package dxfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common/alert"
+	"github.com/DxChainNetwork/godx/common/writeaheadlog"
+	"github.com/DxChainNetwork/godx/log"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+const (
+	// alertModule is the module name of the alerts raised by the DxFiles, as a part of the
+	// file system
+	alertModule = "filesystem"
+
+	// shadowExt is the extension appended to the DxFile path for its shadow copy
+	shadowExt = ".shadow"
+
+	// recoverExt is the extension appended to the DxFile path for the file under reconstruction
+	recoverExt = ".recover"
+
+	// shadowInterval is the minimum interval between two shadow copies of a DxFile
+	shadowInterval = 10 * time.Minute
+
+	// maxJournalTxns is the maximum number of transactions kept in journal for a DxFile.
+	// When reached, a new shadow copy is saved regardless of the shadowInterval
+	maxJournalTxns = 256
+)
+
+const (
+	// RecoverySourceWal is the recovery source that the recent wal transactions are
+	// replayed on the corrupted file
+	RecoverySourceWal = "wal"
+
+	// RecoverySourceShadowWal is the recovery source that the recent wal transactions are
+	// replayed on the shadow copy
+	RecoverySourceShadowWal = "shadow+wal"
+
+	// RecoverySourceShadow is the recovery source that the shadow copy is used as it is.
+	// The updates after the shadow copy is taken are lost
+	RecoverySourceShadow = "shadow"
+
+	// RecoverySourceMemory is the recovery source that the file is loaded in memory and
+	// is saved again
+	RecoverySourceMemory = "memory"
+)
+
+type (
+	// RecoveryRecord is the record of recovering a corrupted DxFile. Source is empty if
+	// the file cannot be recovered
+	RecoveryRecord struct {
+		DxPath       string    `json:"dxpath"`
+		Cause        string    `json:"cause"`
+		Source       string    `json:"source,omitempty"`
+		Transactions int       `json:"transactions"`
+		Error        string    `json:"error,omitempty"`
+		Time         time.Time `json:"time"`
+	}
+
+	// fileJournal keeps the updates of the recent wal transactions applied to each DxFile
+	// since its last shadow copy. The updates are kept in memory, and the transactions
+	// recovered from wal at startup are also recorded.
+	fileJournal struct {
+		txns       map[storage.SysPath][][]storage.FileUpdate
+		lastShadow map[storage.SysPath]time.Time
+		lock       sync.Mutex
+	}
+
+	// recoveryAttempt is a way to reconstruct a DxFile: replay txns on the content of base
+	recoveryAttempt struct {
+		source string
+		base   storage.SysPath
+		txns   [][]storage.FileUpdate
+	}
+)
+
+// Recovered returns whether the file is recovered
+func (record RecoveryRecord) Recovered() bool {
+	return record.Source != ""
+}
+
+// newFileJournal creates an empty fileJournal
+func newFileJournal() *fileJournal {
+	return &fileJournal{
+		txns:       make(map[storage.SysPath][][]storage.FileUpdate),
+		lastShadow: make(map[storage.SysPath]time.Time),
+	}
+}
+
+// record records the updates of a transaction. The updates are grouped by the file, and a
+// deletion update drops the records of the file.
+func (j *fileJournal) record(updates []storage.FileUpdate) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	grouped := make(map[storage.SysPath][]storage.FileUpdate)
+	var order []storage.SysPath
+	for _, up := range updates {
+		switch up := up.(type) {
+		case *storage.InsertUpdate:
+			path := storage.SysPath(up.FileName)
+			if _, exist := grouped[path]; !exist {
+				order = append(order, path)
+			}
+			grouped[path] = append(grouped[path], up)
+		case *storage.DeleteUpdate:
+			path := storage.SysPath(up.FileName)
+			delete(grouped, path)
+			delete(j.txns, path)
+			delete(j.lastShadow, path)
+		}
+	}
+	for _, path := range order {
+		if ups, exist := grouped[path]; exist {
+			j.txns[path] = append(j.txns[path], ups)
+		}
+	}
+}
+
+// shadowDue returns whether a new shadow copy of the file shall be saved
+func (j *fileJournal) shadowDue(path storage.SysPath) bool {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	last, exist := j.lastShadow[path]
+	return !exist || time.Since(last) >= shadowInterval || len(j.txns[path]) >= maxJournalTxns
+}
+
+// shadowed drops the records of the file covered by the new shadow copy
+func (j *fileJournal) shadowed(path storage.SysPath) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	delete(j.txns, path)
+	j.lastShadow[path] = time.Now()
+}
+
+// transactions returns the recorded transactions of the file
+func (j *fileJournal) transactions(path storage.SysPath) [][]storage.FileUpdate {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	txns := make([][]storage.FileUpdate, len(j.txns[path]))
+	copy(txns, j.txns[path])
+	return txns
+}
+
+// applyUpdates applies the updates with wal, and records them in the journal. The updates
+// are recorded even if failed, so that they could be replayed when recovering the file.
+func (df *DxFile) applyUpdates(updates []storage.FileUpdate) error {
+	err := storage.ApplyUpdates(df.wal, updates)
+	if df.journal == nil {
+		return err
+	}
+	df.journal.record(updates)
+	var deleted bool
+	for _, up := range updates {
+		if du, ok := up.(*storage.DeleteUpdate); ok {
+			os.Remove(du.FileName + shadowExt)
+			deleted = deleted || du.FileName == string(df.filePath)
+		}
+	}
+	if err != nil || deleted || !df.journal.shadowDue(df.filePath) {
+		return err
+	}
+	if errShadow := df.saveShadow(); errShadow != nil {
+		log.Warn("cannot save the shadow copy of DxFile", "path", df.filePath, "err", errShadow)
+	}
+	return nil
+}
+
+// saveShadow copies the persisted DxFile to its shadow copy. The content is validated before
+// it atomically replaces the previous shadow copy
+func (df *DxFile) saveShadow() error {
+	content, err := ioutil.ReadFile(string(df.filePath))
+	if err != nil {
+		return err
+	}
+	shadowPath := string(df.filePath) + shadowExt
+	tmpPath := shadowPath + recoverExt
+	if err = ioutil.WriteFile(tmpPath, content, 0600); err != nil {
+		return err
+	}
+	if _, err = readDxFile(storage.SysPath(tmpPath), nil); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("persisted content corrupted: %v", err)
+	}
+	if err = os.Rename(tmpPath, shadowPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	df.journal.shadowed(df.filePath)
+	return nil
+}
+
+// RecordTransactions records the transactions recovered from wal at startup, so that they
+// could be replayed if any DxFile is found corrupted
+func (fs *FileSet) RecordTransactions(txns []*writeaheadlog.Transaction) {
+	for i, txn := range txns {
+		var updates []storage.FileUpdate
+		for _, op := range txn.Operations {
+			up, err := storage.OpToUpdate(op)
+			if err != nil {
+				alert.Raise(log.Root(), alert.SeverityCritical, alertModule, "cannot decode the operation of file transaction, wal might be corrupted",
+					"index", i, "err", err)
+				continue
+			}
+			updates = append(updates, up)
+		}
+		fs.journal.record(updates)
+	}
+}
+
+// TakeRecoveries returns the records of the corrupted DxFiles found since the last call
+func (fs *FileSet) TakeRecoveries() []RecoveryRecord {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	records := make([]RecoveryRecord, 0, len(fs.recoveries))
+	for _, record := range fs.recoveries {
+		records = append(records, record)
+	}
+	fs.recoveries = make(map[storage.DxPath]RecoveryRecord)
+	return records
+}
+
+// Check verifies the persisted DxFile of dxPath, and returns whether the file is healthy.
+// A corrupted file loaded in memory is saved again, and a corrupted file not loaded is
+// recovered as it is opened. The details are recorded and returned by TakeRecoveries.
+func (fs *FileSet) Check(dxPath storage.DxPath) (bool, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	entry, exist := fs.filesMap[dxPath]
+	if !exist {
+		_, record, err := fs.load(dxPath)
+		if os.IsNotExist(err) {
+			return false, ErrUnknownFile
+		}
+		return record == nil, nil
+	}
+	if entry.Deleted() {
+		return false, ErrUnknownFile
+	}
+	_, err := readDxFile(fs.filepath(dxPath), nil)
+	if err == nil {
+		return true, nil
+	}
+	record := RecoveryRecord{
+		DxPath: dxPath.Path,
+		Cause:  err.Error(),
+		Time:   time.Now(),
+	}
+	log.Warn("loaded DxFile corrupted on disk, saving again", "path", dxPath.Path, "err", err)
+	entry.lock.Lock()
+	err = entry.saveAll()
+	entry.lock.Unlock()
+	if err != nil {
+		record.Error = err.Error()
+		log.Error("cannot save the corrupted DxFile", "path", dxPath.Path, "err", err)
+	} else {
+		record.Source = RecoverySourceMemory
+		log.Info("corrupted DxFile recovered", "path", dxPath.Path, "source", record.Source)
+	}
+	fs.recoveries[dxPath] = record
+	return false, nil
+}
+
+// load reads the DxFile of dxPath from disk. If the file is corrupted, it is recovered from
+// the recent wal transactions and the shadow copy. The record is returned if the file is
+// found corrupted.
+func (fs *FileSet) load(dxPath storage.DxPath) (*DxFile, *RecoveryRecord, error) {
+	filePath := fs.filepath(dxPath)
+	df, err := readDxFile(filePath, fs.wal)
+	if err == nil || os.IsNotExist(err) {
+		if df != nil {
+			df.journal = fs.journal
+		}
+		return df, nil, err
+	}
+	record := RecoveryRecord{
+		DxPath: dxPath.Path,
+		Cause:  err.Error(),
+		Time:   time.Now(),
+	}
+	df, err = fs.recoverDxFile(dxPath, &record)
+	if err != nil {
+		record.Error = err.Error()
+		log.Error("DxFile lost", "path", dxPath.Path, "cause", record.Cause, "err", err)
+		err = fmt.Errorf("DxFile %v corrupted and cannot be recovered: %v", dxPath.Path, record.Cause)
+	} else {
+		df.journal = fs.journal
+		log.Info("corrupted DxFile recovered", "path", dxPath.Path, "source", record.Source, "transactions", record.Transactions)
+	}
+	fs.recoveries[dxPath] = record
+	return df, &record, err
+}
+
+// recoverDxFile tries to reconstruct the corrupted DxFile of dxPath. The recent transactions
+// are first replayed on the corrupted file, and then on the shadow copy. At last the shadow
+// copy is used as it is. The first reconstructed file passing the validation replaces the
+// corrupted file.
+func (fs *FileSet) recoverDxFile(dxPath storage.DxPath, record *RecoveryRecord) (*DxFile, error) {
+	filePath := fs.filepath(dxPath)
+	shadowPath := filePath + shadowExt
+	txns := fs.journal.transactions(filePath)
+	log.Warn("DxFile corrupted, trying to recover", "path", dxPath.Path, "transactions", len(txns), "cause", record.Cause)
+
+	var attempts []recoveryAttempt
+	if len(txns) != 0 {
+		attempts = append(attempts, recoveryAttempt{RecoverySourceWal, filePath, txns})
+	}
+	if _, err := os.Stat(string(shadowPath)); err == nil {
+		if len(txns) != 0 {
+			attempts = append(attempts, recoveryAttempt{RecoverySourceShadowWal, shadowPath, txns})
+		}
+		attempts = append(attempts, recoveryAttempt{RecoverySourceShadow, shadowPath, nil})
+	} else {
+		log.Warn("no shadow copy of the corrupted DxFile", "path", dxPath.Path, "err", err)
+	}
+	if len(attempts) == 0 {
+		return nil, fmt.Errorf("no recent transaction or shadow copy")
+	}
+
+	var lastErr error
+	for _, attempt := range attempts {
+		df, err := fs.reconstruct(dxPath, attempt)
+		if err != nil {
+			log.Warn("cannot recover the corrupted DxFile", "path", dxPath.Path, "source", attempt.source, "transactions", len(attempt.txns), "err", err)
+			lastErr = err
+			continue
+		}
+		if attempt.source == RecoverySourceShadow && len(txns) != 0 {
+			log.Warn("recent updates of DxFile lost", "path", dxPath.Path, "transactions", len(txns))
+		}
+		record.Source, record.Transactions = attempt.source, len(attempt.txns)
+		return df, nil
+	}
+	return nil, fmt.Errorf("all %d recovery attempts failed, last error: %v", len(attempts), lastErr)
+}
+
+// reconstruct copies the content of base to a temporary file, replays the transactions on it
+// and validates the result. If valid, the temporary file replaces the DxFile.
+func (fs *FileSet) reconstruct(dxPath storage.DxPath, attempt recoveryAttempt) (df *DxFile, err error) {
+	filePath := fs.filepath(dxPath)
+	tmpPath := filePath + recoverExt
+	defer func() {
+		if err != nil {
+			os.Remove(string(tmpPath))
+		}
+	}()
+	content, err := ioutil.ReadFile(string(attempt.base))
+	if err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(string(tmpPath), content, 0600); err != nil {
+		return nil, err
+	}
+	for i, txn := range attempt.txns {
+		for _, up := range txn {
+			iu, ok := up.(*storage.InsertUpdate)
+			if !ok {
+				continue
+			}
+			replay := &storage.InsertUpdate{
+				FileName: string(tmpPath),
+				Offset:   iu.Offset,
+				Data:     iu.Data,
+			}
+			if err = replay.Apply(); err != nil {
+				return nil, fmt.Errorf("cannot replay transaction %d: %v", i, err)
+			}
+		}
+	}
+	if df, err = readDxFile(tmpPath, fs.wal); err != nil {
+		return nil, err
+	}
+	if !df.metadata.DxPath.Equals(dxPath) {
+		return nil, fmt.Errorf("dxPath %v not match the metadata %v", dxPath.Path, df.metadata.DxPath.Path)
+	}
+	if err = os.Rename(string(tmpPath), string(filePath)); err != nil {
+		return nil, err
+	}
+	df.filePath = filePath
+	return df, nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package dxfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
+)
+
+// newTestRecoverFile creates a FileSet with a DxFile of 4 segments. A sector is added to
+// the first segment after the shadow copy is saved.
+func newTestRecoverFile(t *testing.T) (*FileSet, storage.DxPath, common.Hash) {
+	wal, _ := newWal(t)
+	fs := NewFileSet(testDir, wal)
+	ec, err := erasurecode.New(erasurecode.ECTypeStandard, 10, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ck, err := crypto.GenerateCipherKey(crypto.GCMCipherCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dxPath := randomDxPath()
+	entry, err := fs.NewDxFile(dxPath, "", false, ec, ck, 1<<27, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.NumSegments() != 4 {
+		t.Fatalf("expect 4 segments, got %v", entry.NumSegments())
+	}
+	root := common.BytesToHash([]byte("recover"))
+	if err = entry.AddSector(enode.ID{1}, root, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = entry.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return fs, dxPath, root
+}
+
+// corruptDxFile flips a byte at the offset of the persisted DxFile
+func corruptDxFile(t *testing.T, path storage.SysPath, offset int) {
+	content, err := ioutil.ReadFile(string(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content[offset] ^= 0xff
+	if err = ioutil.WriteFile(string(path), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rootOffset returns the offset of the merkle root in the persisted DxFile
+func rootOffset(t *testing.T, path storage.SysPath, root common.Hash) int {
+	content, err := ioutil.ReadFile(string(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := bytes.Index(content, root[:])
+	if offset < 0 {
+		t.Fatalf("merkle root not found in the file")
+	}
+	return offset
+}
+
+// checkRecovered checks the recovered file and the recovery record
+func checkRecovered(t *testing.T, fs *FileSet, dxPath storage.DxPath, source string, root common.Hash) {
+	entry, err := fs.Open(dxPath)
+	if err != nil {
+		t.Fatalf("cannot open the corrupted file: %v", err)
+	}
+	defer entry.Close()
+	sectors, err := entry.Sectors(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source == RecoverySourceShadow {
+		if len(sectors[0]) != 0 {
+			t.Errorf("the sector added after the shadow copy should be lost")
+		}
+	} else if len(sectors[0]) != 1 || sectors[0][0].MerkleRoot != root {
+		t.Errorf("the sector is not recovered: %v", sectors[0])
+	}
+	records := fs.TakeRecoveries()
+	if len(records) != 1 {
+		t.Fatalf("expect 1 record, got %v", len(records))
+	}
+	if records[0].DxPath != dxPath.Path || records[0].Source != source {
+		t.Errorf("unexpected record %+v, expect source %v", records[0], source)
+	}
+	if _, err = readDxFile(fs.filepath(dxPath), nil); err != nil {
+		t.Errorf("the recovered file is not persisted: %v", err)
+	}
+}
+
+func TestFileSet_RecoverFromWal(t *testing.T) {
+	fs, dxPath, root := newTestRecoverFile(t)
+	path := fs.filepath(dxPath)
+	// the corrupted merkle root could still be decoded, and it is found by the checksum
+	corruptDxFile(t, path, rootOffset(t, path, root))
+	if _, err := readDxFile(path, nil); err == nil {
+		t.Fatalf("checksum mismatch not detected")
+	}
+	checkRecovered(t, fs, dxPath, RecoverySourceWal, root)
+}
+
+func TestFileSet_RecoverFromShadowWal(t *testing.T) {
+	fs, dxPath, root := newTestRecoverFile(t)
+	path := fs.filepath(dxPath)
+	// the last segment is not touched by the recent transactions
+	entry, err := fs.Open(dxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := entry.segments[3].offset
+	entry.Close()
+	corruptDxFile(t, path, int(offset)+1)
+	checkRecovered(t, fs, dxPath, RecoverySourceShadowWal, root)
+}
+
+func TestFileSet_RecoverFromShadow(t *testing.T) {
+	fs, dxPath, root := newTestRecoverFile(t)
+	path := fs.filepath(dxPath)
+	// the recent transactions are lost as if the node is restarted
+	fs.journal = newFileJournal()
+	corruptDxFile(t, path, rootOffset(t, path, root))
+	checkRecovered(t, fs, dxPath, RecoverySourceShadow, root)
+}
+
+func TestFileSet_RecoverLost(t *testing.T) {
+	fs, dxPath, root := newTestRecoverFile(t)
+	path := fs.filepath(dxPath)
+	fs.journal = newFileJournal()
+	if err := os.Remove(string(path) + shadowExt); err != nil {
+		t.Fatal(err)
+	}
+	corruptDxFile(t, path, rootOffset(t, path, root))
+	if _, err := fs.Open(dxPath); err == nil {
+		t.Fatalf("the file without shadow copy should not be recovered")
+	}
+	healthy, err := fs.Check(dxPath)
+	if err != nil || healthy {
+		t.Fatalf("the lost file should be reported corrupted: %v", err)
+	}
+	records := fs.TakeRecoveries()
+	if len(records) != 1 || records[0].Recovered() || records[0].Error == "" {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
+
+func TestFileSet_CheckLoaded(t *testing.T) {
+	fs, dxPath, root := newTestRecoverFile(t)
+	path := fs.filepath(dxPath)
+	entry, err := fs.Open(dxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer entry.Close()
+	if healthy, err := fs.Check(dxPath); err != nil || !healthy {
+		t.Fatalf("the file should be healthy: %v", err)
+	}
+	// the corrupted file loaded in memory is saved again
+	corruptDxFile(t, path, rootOffset(t, path, root))
+	if healthy, err := fs.Check(dxPath); err != nil || healthy {
+		t.Fatalf("the file should be corrupted: %v", err)
+	}
+	records := fs.TakeRecoveries()
+	if len(records) != 1 || records[0].Source != RecoverySourceMemory {
+		t.Errorf("unexpected records %+v", records)
+	}
+	if _, err = readDxFile(path, nil); err != nil {
+		t.Errorf("the file is not saved again: %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/DxChainNetwork/godx/crypto"
 	"github.com/DxChainNetwork/godx/rlp"
 	"github.com/DxChainNetwork/godx/storage"
 )
@@ -54,7 +55,7 @@ func (df *DxFile) saveAll() error {
 	updates = append(updates, up)
 
 	// save all updates
-	return storage.ApplyUpdates(df.wal, updates)
+	return df.applyUpdates(updates)
 }
 
 // rename create a series of transactions to rename the file to a new file
@@ -102,7 +103,7 @@ func (df *DxFile) rename(dxPath storage.DxPath, newFilePath storage.SysPath) err
 	}
 	updates = append(updates, up)
 	// apply updates
-	return storage.ApplyUpdates(df.wal, updates)
+	return df.applyUpdates(updates)
 }
 
 // delete create and apply the deletion update
@@ -114,7 +115,7 @@ func (df *DxFile) delete() error {
 	if err != nil {
 		return fmt.Errorf("cannot create delete update: %v", err)
 	}
-	return storage.ApplyUpdates(df.wal, []storage.FileUpdate{du})
+	return df.applyUpdates([]storage.FileUpdate{du})
 }
 
 // saveSegment save the Segment with the segmentIndex, and write to file
@@ -147,7 +148,7 @@ func (df *DxFile) saveSegments(indexes []int) error {
 	}
 	updates = append(updates, up)
 	// apply the updates
-	return storage.ApplyUpdates(df.wal, updates)
+	return df.applyUpdates(updates)
 }
 
 // saveHostTableUpdate save the host table as well as the metadata
@@ -159,7 +160,7 @@ func (df *DxFile) saveHostTableUpdate() error {
 	if err != nil {
 		return err
 	}
-	return storage.ApplyUpdates(df.wal, updates)
+	return df.applyUpdates(updates)
 }
 
 // saveMetadata only save the metadata
@@ -171,7 +172,7 @@ func (df *DxFile) saveMetadata() error {
 	if err != nil {
 		return err
 	}
-	return storage.ApplyUpdates(df.wal, []storage.FileUpdate{up})
+	return df.applyUpdates([]storage.FileUpdate{up})
 }
 
 // createMetadataHostTableUpdate creates the update for metadata and hostTable
@@ -258,6 +259,7 @@ func (df *DxFile) shiftOffset(targetHostTableSize uint64) (uint64, uint64, uint6
 // createMetadataUpdate create an insert update for metadata
 func (df *DxFile) createMetadataUpdate() (storage.FileUpdate, error) {
 	df.metadata.TimeUpdate = unixNow()
+	df.metadata.ContentChecksum = df.contentChecksum()
 	metaBytes, err := rlp.EncodeToBytes(df.metadata)
 	if err != nil {
 		return nil, err
@@ -281,6 +283,7 @@ func (df *DxFile) createHostTableUpdate() (storage.FileUpdate, uint64, error) {
 	if err != nil {
 		return nil, 0, err
 	}
+	df.hostTableChecksum = crypto.Keccak256Hash(hostTableBytes)
 	return iu, uint64(len(hostTableBytes)), nil
 }
 
@@ -307,6 +310,7 @@ func (df *DxFile) createSegmentUpdate(segmentIndex uint64, offset uint64) (stora
 	if int64(offset) < 0 {
 		return nil, fmt.Errorf("uint64 overflow: %v", int64(offset))
 	}
+	segment.checksum = crypto.Keccak256Hash(segBytes)
 	return df.createInsertUpdate(offset, segBytes)
 }
 
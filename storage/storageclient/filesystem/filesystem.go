@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
 	"github.com/DxChainNetwork/godx/common/threadmanager"
 	"github.com/DxChainNetwork/godx/common/writeaheadlog"
 	"github.com/DxChainNetwork/godx/crypto"
@@ -77,6 +78,9 @@ type fileSystem struct {
 
 	// stuckFound is the channel to signal a stuck segment is found
 	stuckFound chan struct{}
+
+	// openFiles limits the DxFiles opened by the directory-wide operations
+	openFiles *openFileLimiter
 }
 
 // newFileSystem creates a new file system with the standardDisrupter
@@ -92,6 +96,7 @@ func newFileSystem(persistDir string, contractor contractManager, disrupter disr
 		unfinishedUpdates: make(map[storage.DxPath]*dirMetadataUpdate),
 		repairNeeded:      make(chan struct{}, 1),
 		stuckFound:        make(chan struct{}, 1),
+		openFiles:         newOpenFileLimiter(defaultMaxOpenDxFiles),
 	}
 }
 
@@ -99,17 +104,19 @@ func newFileSystem(persistDir string, contractor contractManager, disrupter disr
 // It open the wals, apply all transactions, and start the thread loopRepairUnfinishedDirMetadataUpdate
 func (fs *fileSystem) Start() error {
 	// open the fileWal
-	if err := fs.loadFileWal(); err != nil {
+	unappliedTxns, err := fs.loadFileWal()
+	if err != nil {
 		return fmt.Errorf("cannot start the file system: %v", err)
 	}
 	// load fs.dirSet
-	var err error
 	if fs.dirSet, err = dxdir.NewDirSet(fs.fileRootDir, fs.fileWal); err != nil {
 		return fmt.Errorf("cannot start the file system dirSet: %v", err)
 	}
 	fs.fileSet = dxfile.NewFileSet(fs.fileRootDir, fs.fileWal)
+	// the transactions are recorded to recover the DxFiles corrupted by the unfinished updates
+	fs.fileSet.RecordTransactions(unappliedTxns)
 	// open the updateWal
-	if err := fs.loadUpdateWal(); err != nil {
+	if err = fs.loadUpdateWal(); err != nil {
 		return fmt.Errorf("cannot start the file system: %v", err)
 	}
 	// Start the repair loop
@@ -400,12 +407,13 @@ func (fs *fileSystem) dirsAndFiles(path storage.DxPath) (map[storage.DxPath]stru
 	return dirs, files, nil
 }
 
-// loadFileWal read the fileWal
-func (fs *fileSystem) loadFileWal() error {
+// loadFileWal read the fileWal, and apply the unapplied transactions. The unapplied
+// transactions are returned
+func (fs *fileSystem) loadFileWal() ([]*writeaheadlog.Transaction, error) {
 	fileWalPath := filepath.Join(string(fs.persistDir), fileWalName)
 	fileWal, unappliedTxns, err := writeaheadlog.New(fileWalPath)
 	if err != nil {
-		return fmt.Errorf("cannot start load system fileWal: %v", err)
+		return nil, fmt.Errorf("cannot start load system fileWal: %v", err)
 	}
 	for i, txn := range unappliedTxns {
 		err = storage.ApplyOperations(txn.Operations)
@@ -418,7 +426,7 @@ func (fs *fileSystem) loadFileWal() error {
 		}
 	}
 	fs.fileWal = fileWal
-	return nil
+	return unappliedTxns, nil
 }
 
 // loadUpdateWal load the update Wal from disk, and apply unfinished updates
@@ -432,7 +440,8 @@ func (fs *fileSystem) loadUpdateWal() error {
 		for j, op := range txn.Operations {
 			path, err := decodeWalOp(op)
 			if err != nil {
-				fs.logger.Warn(fmt.Sprintf("cannot decode txn[%d].operation[%d]", i, j), "error", err)
+				alert.Raise(fs.logger, alert.SeverityCritical, alertModule, "cannot decode the operation of update transaction, wal might be corrupted",
+					"txn", i, "operation", j, "error", err)
 				continue
 			}
 			// if error happened: already in progress
@@ -542,6 +551,20 @@ func (fs *fileSystem) fileList() ([]storage.FileBriefInfo, error) {
 	return fileList, err
 }
 
+// HealthDistribution returns the number of files in each health status, which are
+// the same as the status in the file list
+func (fs *fileSystem) HealthDistribution() (map[string]uint64, error) {
+	files, err := fs.fileList()
+	if err != nil {
+		return nil, err
+	}
+	distribution := make(map[string]uint64)
+	for _, file := range files {
+		distribution[file.Status]++
+	}
+	return distribution, nil
+}
+
 // fileDetailedInfo returns detailed information for a file specified by the path
 // If the input table is empty, the code the query the contractManager for health info
 func (fs *fileSystem) fileDetailedInfo(path storage.DxPath, table storage.HostHealthInfoTable) (storage.FileInfo, error) {
@@ -578,6 +601,10 @@ func (fs *fileSystem) fileDetailedInfo(path storage.DxPath, table storage.HostHe
 // fileBriefInfo returns the brief info about a file specified by the path
 // If the input table is empty, the code the query the contractManager for health info
 func (fs *fileSystem) fileBriefInfo(path storage.DxPath, table storage.HostHealthInfoTable) (storage.FileBriefInfo, error) {
+	if err := fs.openFiles.acquire(nil, fs.tm.StopChan()); err != nil {
+		return storage.FileBriefInfo{}, err
+	}
+	defer fs.openFiles.release()
 	file, err := fs.fileSet.Open(path)
 	if err != nil {
 		return storage.FileBriefInfo{}, err
@@ -0,0 +1,90 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxfile"
+)
+
+// FsckReport is the result of checking the integrity of all DxFiles. Recovered and Lost
+// also include the corrupted files found when opened since the last check
+type FsckReport struct {
+	Checked   int                     `json:"checked"`
+	Healthy   int                     `json:"healthy"`
+	Corrupted int                     `json:"corrupted"`
+	Recovered []dxfile.RecoveryRecord `json:"recovered"`
+	Lost      []dxfile.RecoveryRecord `json:"lost"`
+}
+
+// Fsck walks through the file root directory and checks all DxFiles. The corrupted files
+// are recovered from the recent wal transactions and the shadow copies
+func (fs *fileSystem) Fsck() (report FsckReport, err error) {
+	if err = fs.tm.Add(); err != nil {
+		return
+	}
+	defer fs.tm.Done()
+
+	err = filepath.Walk(string(fs.fileRootDir), func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != storage.DxFileExt {
+			return nil
+		}
+		select {
+		case <-fs.tm.StopChan():
+			return errStopped
+		default:
+		}
+		dxPath, err := fs.dxPathFromSysPath(path, storage.DxFileExt)
+		if err != nil {
+			return err
+		}
+		if err = fs.openFiles.acquire(nil, fs.tm.StopChan()); err != nil {
+			return err
+		}
+		healthy, err := fs.fileSet.Check(dxPath)
+		fs.openFiles.release()
+		if err == dxfile.ErrUnknownFile {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		report.Checked++
+		if healthy {
+			report.Healthy++
+		} else {
+			report.Corrupted++
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	records := fs.fileSet.TakeRecoveries()
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].DxPath < records[j].DxPath
+	})
+	for _, record := range records {
+		if record.Recovered() {
+			report.Recovered = append(report.Recovered, record)
+		} else {
+			report.Lost = append(report.Lost, record)
+		}
+	}
+	fs.logger.Info("file system check finished", "checked", report.Checked, "healthy", report.Healthy,
+		"corrupted", report.Corrupted, "recovered", len(report.Recovered), "lost", len(report.Lost))
+	return
+}
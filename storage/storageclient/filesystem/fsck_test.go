@@ -0,0 +1,73 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package filesystem
+
+import (
+	"os"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxfile"
+)
+
+func TestFileSystem_Fsck(t *testing.T) {
+	fs := newEmptyTestFileSystem(t, "", &AlwaysSuccessContractManager{}, newStandardDisrupter())
+	defer fs.Close()
+	ck, err := crypto.GenerateCipherKey(crypto.GCMCipherCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []*dxfile.FileSetEntryWithID
+	for i := 0; i != 3; i++ {
+		file, err := fs.fileSet.NewRandomDxFile(randomDxPath(t, 2), 10, 30, erasurecode.ECTypeStandard, ck, 1<<22*10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = file.Close(); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	// the first file is recovered from the shadow copy, and the second file is lost
+	for i, file := range files[:2] {
+		if i == 1 {
+			if err = os.Remove(file.FilePath() + ".shadow"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f, err := os.OpenFile(file.FilePath(), os.O_RDWR, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.WriteAt([]byte("!@#*&^%$#"), 0); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	// recreate the file set without the recent transactions
+	fs.fileSet = dxfile.NewFileSet(fs.fileRootDir, fs.fileWal)
+
+	report, err := fs.Fsck()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked != 3 || report.Healthy != 1 || report.Corrupted != 2 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if len(report.Recovered) != 1 || report.Recovered[0].DxPath != files[0].DxPath().Path || report.Recovered[0].Source != dxfile.RecoverySourceShadow {
+		t.Errorf("unexpected recovered files: %+v", report.Recovered)
+	}
+	if len(report.Lost) != 1 || report.Lost[0].DxPath != files[1].DxPath().Path {
+		t.Errorf("unexpected lost files: %+v", report.Lost)
+	}
+	// the recovered file is healthy in the next check
+	if report, err = fs.Fsck(); err != nil {
+		t.Fatal(err)
+	}
+	if report.Healthy != 2 || len(report.Recovered) != 0 || len(report.Lost) != 1 {
+		t.Errorf("unexpected report of the second check: %+v", report)
+	}
+}
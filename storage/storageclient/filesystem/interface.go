@@ -47,6 +47,20 @@ type FileSystem interface {
 	RepairNeededChan() chan struct{}
 	StuckFoundChan() chan struct{}
 
+	// Backup and restore of the DxDirs and DxFiles
+	Backup() (Backup, error)
+	Restore(backup Backup) error
+	RemoveRestored(backup Backup) error
+
+	// HealthDistribution returns the number of files in each health status
+	HealthDistribution() (map[string]uint64, error)
+
+	// Fsck checks the integrity of all DxFiles, and recovers the corrupted ones
+	Fsck() (FsckReport, error)
+
+	// OpenFileStats returns the metrics of the DxFiles opened by the directory-wide operations
+	OpenFileStats() OpenFileStats
+
 	// private function fields used for APIs
 	getLogger() log.Logger
 	fileDetailedInfo(path storage.DxPath, table storage.HostHealthInfoTable) (storage.FileInfo, error)
@@ -0,0 +1,75 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package filesystem
+
+import "sync"
+
+// OpenFileStats is the metrics of the DxFiles opened by the directory-wide operations
+type OpenFileStats struct {
+	Limit int    `json:"limit"`
+	Open  int    `json:"open"`
+	Peak  int    `json:"peak"`
+	Waits uint64 `json:"waits"`
+}
+
+// openFileLimiter limits the number of DxFiles opened at the same time by the directory-wide
+// operations, such as the health scans, the file list and fsck. The metadata updates of the
+// directories run concurrently, and without the limit the file descriptors could be exhausted
+// on the systems with low ulimits
+type openFileLimiter struct {
+	slots chan struct{}
+	stats OpenFileStats
+	lock  sync.Mutex
+}
+
+// newOpenFileLimiter creates an openFileLimiter allowing at most limit DxFiles opened
+func newOpenFileLimiter(limit int) *openFileLimiter {
+	return &openFileLimiter{
+		slots: make(chan struct{}, limit),
+		stats: OpenFileStats{Limit: limit},
+	}
+}
+
+// acquire blocks until a DxFile could be opened. errInterrupted is returned if interrupt is
+// closed, and errStopped is returned if stop is closed before that
+func (l *openFileLimiter) acquire(interrupt, stop <-chan struct{}) error {
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		l.lock.Lock()
+		l.stats.Waits++
+		l.lock.Unlock()
+
+		select {
+		case l.slots <- struct{}{}:
+		case <-interrupt:
+			return errInterrupted
+		case <-stop:
+			return errStopped
+		}
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.stats.Open++
+	if l.stats.Open > l.stats.Peak {
+		l.stats.Peak = l.stats.Open
+	}
+	return nil
+}
+
+// release releases the slot acquired after the DxFile is closed
+func (l *openFileLimiter) release() {
+	l.lock.Lock()
+	l.stats.Open--
+	l.lock.Unlock()
+	<-l.slots
+}
+
+// OpenFileStats returns the metrics of the DxFiles opened by the directory-wide operations
+func (fs *fileSystem) OpenFileStats() OpenFileStats {
+	fs.openFiles.lock.Lock()
+	defer fs.openFiles.lock.Unlock()
+	return fs.openFiles.stats
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package filesystem
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpenFileLimiter(t *testing.T) {
+	l := newOpenFileLimiter(2)
+	for i := 0; i != 2; i++ {
+		if err := l.acquire(nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the third acquire blocks until a slot is released
+	acquired := make(chan error)
+	go func() {
+		acquired <- l.acquire(nil, nil)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired more than the limit")
+	case <-time.After(100 * time.Millisecond):
+	}
+	l.release()
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+
+	// the blocked acquire returns on interrupt and stop
+	interrupt, stop := make(chan struct{}, 1), make(chan struct{})
+	interrupt <- struct{}{}
+	if err := l.acquire(interrupt, stop); err != errInterrupted {
+		t.Errorf("expect %v, got %v", errInterrupted, err)
+	}
+	close(stop)
+	if err := l.acquire(interrupt, stop); err != errStopped {
+		t.Errorf("expect %v, got %v", errStopped, err)
+	}
+
+	l.release()
+	expected := OpenFileStats{Limit: 2, Open: 1, Peak: 2, Waits: 3}
+	if l.stats != expected {
+		t.Errorf("expect stats %+v, got %+v", expected, l.stats)
+	}
+}
+
+func TestFileSystem_OpenFilesReleased(t *testing.T) {
+	fs := newEmptyTestFileSystem(t, "", &AlwaysSuccessContractManager{}, newStandardDisrupter())
+	defer fs.Close()
+	if err := fs.createRandomFiles(10, 0.7, 0.5, 3, 0.1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.fileList(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Fsck(); err != nil {
+		t.Fatal(err)
+	}
+	stats := fs.OpenFileStats()
+	if stats.Open != 0 || stats.Peak == 0 || stats.Limit != defaultMaxOpenDxFiles {
+		t.Errorf("unexpected open file stats %+v", stats)
+	}
+}
@@ -84,6 +84,7 @@ func formatClientSetting(setting storage.ClientSetting) (formatted storage.Clien
 	formatted.EnableIPViolation = formatIPViolation(setting.EnableIPViolation)
 	formatted.MaxUploadSpeed = unit.FormatSpeed(setting.MaxUploadSpeed)
 	formatted.MaxDownloadSpeed = unit.FormatSpeed(setting.MaxDownloadSpeed)
+	formatted.DownloadOverdrive = fmt.Sprintf("%v extra sector fetches per segment", setting.DownloadOverdrive)
 	formatted.RentPayment = formatRentPayment(setting.RentPayment)
 	return
 }
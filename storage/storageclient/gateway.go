@@ -0,0 +1,490 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
+)
+
+// GatewayHealth is the overall health of the storage client reported by the gateway
+type GatewayHealth struct {
+	Online          bool `json:"online"`
+	Syncing         bool `json:"syncing"`
+	ActiveContracts int  `json:"activecontracts"`
+	Files           int  `json:"files"`
+	Uploading       int  `json:"uploading"`
+
+	// health of the root directory, which is the health of the least healthy file
+	Health        uint32 `json:"health"`
+	MinRedundancy uint32 `json:"minredundancy"`
+	StuckSegments uint32 `json:"stucksegments"`
+}
+
+// gatewayError is the json body responded when the request failed
+type gatewayError struct {
+	Error string `json:"error"`
+}
+
+var (
+	errGatewayFileExists   = errors.New("the file already exists, delete it before uploading again")
+	errGatewayBodyTooLarge = errors.New("the request body is too large")
+)
+
+// gateway is the JSON/HTTP gateway of the storage client, which allows the applications not
+// speaking the JSON-RPC conventions to upload, download, list, and check the files. The file
+// content is streamed in the request and response body. The requests are authenticated with
+// the bearer token saved in the persist directory of the storage client. Routes:
+//
+//	GET    /health          the health of the storage client
+//	GET    /files           list all files
+//	GET    /files/{dxpath}  download the file, range requests are supported
+//	PUT    /files/{dxpath}  upload the request body to dxpath, the existing file is not replaced
+//	DELETE /files/{dxpath}  delete the file
+//	GET    /info/{dxpath}   detailed information of the file
+type gateway struct {
+	client *StorageClient
+	files  *filesystem.PublicFileSystemAPI
+
+	// uploaded content is staged in the directory, the staged files are used as the
+	// local source of the uploaded files
+	stagingDir string
+
+	// stageLock serializes checking whether the file exists and placing the staged file, so
+	// that the staged file of a file already uploaded is never replaced
+	stageLock sync.Mutex
+
+	// maxUploadSize is the maximum size in bytes of the content uploaded in a request
+	maxUploadSize int64
+
+	// token is the bearer token required in the requests
+	token string
+
+	server   *http.Server
+	listener net.Listener
+}
+
+// StartGateway starts the JSON/HTTP gateway listening on the address. The bearer token is
+// loaded from the token file in the persist directory, and generated if not exists
+func (client *StorageClient) StartGateway(addr string) error {
+	tokenPath := filepath.Join(client.persistDir, gatewayTokenFile)
+	token, err := loadGatewayToken(tokenPath)
+	if err != nil {
+		return fmt.Errorf("failed to load the gateway token: %s", err.Error())
+	}
+	gw := &gateway{
+		client:        client,
+		files:         filesystem.NewPublicFileSystemAPI(client.fileSystem),
+		stagingDir:    filepath.Join(client.persistDir, gatewayStagingDir),
+		maxUploadSize: gatewayMaxUploadSize,
+		token:         token,
+	}
+	if err := os.MkdirAll(gw.stagingDir, 0700); err != nil {
+		return fmt.Errorf("failed to create the gateway staging directory: %s", err.Error())
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %s", addr, err.Error())
+	}
+	gw.listener = listener
+	gw.server = &http.Server{Handler: gw.handler()}
+
+	go func() {
+		if err := gw.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			client.log.Warn("storage client gateway stopped", "err", err)
+		}
+	}()
+	client.tm.OnStop(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), gatewayShutdownTimeout)
+		defer cancel()
+		return gw.server.Shutdown(ctx)
+	})
+
+	client.log.Info("Storage client gateway started", "addr", listener.Addr(), "token", tokenPath)
+	return nil
+}
+
+// loadGatewayToken loads the bearer token of the gateway from the file. A random token is
+// generated and saved if the file does not exist
+func loadGatewayToken(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err = ioutil.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// handler returns the http handler of the gateway
+func (gw *gateway) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", gw.handleHealth)
+	mux.HandleFunc("/files", gw.handleList)
+	mux.HandleFunc("/files/", gw.handleFile)
+	mux.HandleFunc("/info/", gw.handleInfo)
+	return gw.authenticate(mux)
+}
+
+// authenticate rejects the requests without the bearer token of the gateway
+func (gw *gateway) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || gw.token == "" ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(gw.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gdx"`)
+			writeGatewayError(w, http.StatusUnauthorized, errors.New("invalid or missing bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleHealth responds the overall health of the storage client
+func (gw *gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeGatewayError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	health := GatewayHealth{
+		Online:          gw.client.Online(),
+		Syncing:         gw.client.Syncing(),
+		ActiveContracts: len(gw.client.contractManager.RetrieveActiveContracts()),
+	}
+	if dir, err := gw.client.fileSystem.OpenDxDir(storage.RootDxPath()); err == nil {
+		metadata := dir.Metadata()
+		health.Health, health.MinRedundancy, health.StuckSegments = metadata.Health, metadata.MinRedundancy, metadata.NumStuckSegments
+		dir.Close()
+	}
+	for _, file := range gw.files.FileList() {
+		health.Files++
+		if file.UploadProgress < 100 {
+			health.Uploading++
+		}
+	}
+	writeGatewayJSON(w, http.StatusOK, health)
+}
+
+// handleList responds the brief information of all files
+func (gw *gateway) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeGatewayError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	writeGatewayJSON(w, http.StatusOK, gw.files.FileList())
+}
+
+// handleInfo responds the detailed information of the file
+func (gw *gateway) handleInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeGatewayError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	dxPath, err := storage.NewDxPath(strings.TrimPrefix(r.URL.Path, "/info/"))
+	if err != nil {
+		writeGatewayError(w, http.StatusBadRequest, err)
+		return
+	}
+	info := gw.files.DetailedFileInfo(dxPath.Path)
+	if info.DxPath == "" {
+		writeGatewayError(w, http.StatusNotFound, fmt.Errorf("file %s not found", dxPath.Path))
+		return
+	}
+	writeGatewayJSON(w, http.StatusOK, info)
+}
+
+// handleFile handles the upload, download, and delete of the file
+func (gw *gateway) handleFile(w http.ResponseWriter, r *http.Request) {
+	dxPath, err := storage.NewDxPath(strings.TrimPrefix(r.URL.Path, "/files/"))
+	if err != nil {
+		writeGatewayError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		gw.download(w, r, dxPath)
+	case http.MethodPut:
+		gw.upload(w, r, dxPath)
+	case http.MethodDelete:
+		gw.delete(w, dxPath)
+	default:
+		writeGatewayError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+// upload streams the request body into the staging file, and uploads the staged file. The
+// existing file is not replaced
+func (gw *gateway) upload(w http.ResponseWriter, r *http.Request, dxPath storage.DxPath) {
+	if r.ContentLength > gw.maxUploadSize {
+		writeGatewayError(w, http.StatusRequestEntityTooLarge, errGatewayBodyTooLarge)
+		return
+	}
+	if gw.client.dxFileExists(dxPath) {
+		writeGatewayError(w, http.StatusConflict, errGatewayFileExists)
+		return
+	}
+	tmp, size, err := gw.receive(http.MaxBytesReader(w, r.Body, gw.maxUploadSize))
+	if isBodyTooLarge(err) {
+		writeGatewayError(w, http.StatusRequestEntityTooLarge, errGatewayBodyTooLarge)
+		return
+	}
+	if err != nil {
+		writeGatewayError(w, http.StatusInternalServerError, fmt.Errorf("failed to receive the file: %s", err.Error()))
+		return
+	}
+	if err = gw.uploadReceived(tmp, dxPath); err == errGatewayFileExists {
+		writeGatewayError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		writeGatewayError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeGatewayJSON(w, http.StatusCreated, storage.FileBriefInfo{Path: dxPath.Path, Status: fmt.Sprintf("%d bytes received", size)})
+}
+
+// download streams the file downloaded back. Range requests are served with the range of
+// the file downloaded only
+func (gw *gateway) download(w http.ResponseWriter, r *http.Request, dxPath storage.DxPath) {
+	entry, err := gw.client.fileSystem.OpenDxFile(dxPath)
+	if err != nil {
+		writeGatewayError(w, http.StatusNotFound, fmt.Errorf("file %s not found", dxPath.Path))
+		return
+	}
+	size, modTime := entry.FileSize(), entry.TimeModify()
+	entry.Close()
+
+	if code, err := gw.streamFile(w, r, dxPath, size, modTime); err != nil {
+		writeGatewayError(w, code, err)
+	}
+}
+
+// streamFile streams the file, or the range of the file requested, in the response body.
+// If the download failed before anything was responded, the status code and the error are
+// returned to be responded by the caller
+func (gw *gateway) streamFile(w http.ResponseWriter, r *http.Request, dxPath storage.DxPath, size uint64, modTime time.Time) (int, error) {
+	offset, length, partial, err := parseByteRange(r.Header.Get("Range"), size)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		return http.StatusRequestedRangeNotSatisfiable, err
+	}
+
+	sw := &streamResponse{ResponseWriter: w, code: http.StatusOK}
+	sw.header = func(h http.Header) {
+		h.Set("Content-Type", "application/octet-stream")
+		h.Set("Accept-Ranges", "bytes")
+		h.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+		h.Set("Content-Length", strconv.FormatUint(length, 10))
+		if partial {
+			h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, size))
+			sw.code = http.StatusPartialContent
+		}
+	}
+	if err = gw.client.DownloadStream(dxPath, sw, offset, length); err != nil {
+		if !sw.written {
+			return http.StatusInternalServerError, fmt.Errorf("failed to download the file: %s", err.Error())
+		}
+		// the response is truncated, and the connection is closed by the server
+		gw.client.log.Warn("failed to stream the file", "path", dxPath.Path, "err", err)
+		return 0, nil
+	}
+	sw.writeHeader()
+	return 0, nil
+}
+
+// streamResponse writes the response header on the first write of the file content, so that
+// an error could still be responded if the download failed before any content is downloaded
+type streamResponse struct {
+	http.ResponseWriter
+	header  func(h http.Header)
+	code    int
+	written bool
+}
+
+// Write writes the response header if not written, and the data to the response body
+func (sw *streamResponse) Write(data []byte) (int, error) {
+	sw.writeHeader()
+	return sw.ResponseWriter.Write(data)
+}
+
+// writeHeader writes the response header once
+func (sw *streamResponse) writeHeader() {
+	if sw.written {
+		return
+	}
+	sw.written = true
+	sw.header(sw.ResponseWriter.Header())
+	sw.ResponseWriter.WriteHeader(sw.code)
+}
+
+// parseByteRange parses the single byte range in the Range header for the file of the size.
+// The whole file is served if the header is empty or has multiple ranges
+func parseByteRange(header string, size uint64) (offset, length uint64, partial bool, err error) {
+	if header == "" || !strings.HasPrefix(header, "bytes=") || strings.Contains(header, ",") {
+		return 0, size, false, nil
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(header, "bytes="))
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return 0, 0, false, fmt.Errorf("invalid range %s", header)
+	}
+	start, end := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+
+	// the suffix range requests the last bytes of the file
+	if start == "" {
+		n, err := strconv.ParseUint(end, 10, 64)
+		if err != nil || n == 0 || size == 0 {
+			return 0, 0, false, fmt.Errorf("invalid range %s", header)
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true, nil
+	}
+	offset, err = strconv.ParseUint(start, 10, 64)
+	if err != nil || offset >= size {
+		return 0, 0, false, fmt.Errorf("invalid range %s", header)
+	}
+	last := size - 1
+	if end != "" {
+		if last, err = strconv.ParseUint(end, 10, 64); err != nil || last < offset {
+			return 0, 0, false, fmt.Errorf("invalid range %s", header)
+		}
+		if last >= size {
+			last = size - 1
+		}
+	}
+	return offset, last - offset + 1, true, nil
+}
+
+// receive streams the content read from r into a temporary file in the staging directory,
+// and returns the path of the temporary file and the number of bytes written. The temporary
+// file is removed if failed
+func (gw *gateway) receive(r io.Reader) (string, int64, error) {
+	if err := os.MkdirAll(gw.stagingDir, 0700); err != nil {
+		return "", 0, err
+	}
+	tmp, err := ioutil.TempFile(gw.stagingDir, ".upload-")
+	if err != nil {
+		return "", 0, err
+	}
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, err
+	}
+	return tmp.Name(), size, nil
+}
+
+// isBodyTooLarge checks whether the error is returned by http.MaxBytesReader for the request
+// body exceeding the limit
+func isBodyTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "request body too large")
+}
+
+// uploadReceived places the received temporary file as the staged file of dxPath, and uploads
+// the staged file. errGatewayFileExists is returned if dxPath is already uploaded. The staged
+// file is removed if the upload failed
+func (gw *gateway) uploadReceived(tmp string, dxPath storage.DxPath) error {
+	gw.stageLock.Lock()
+	defer gw.stageLock.Unlock()
+
+	if gw.client.dxFileExists(dxPath) {
+		os.Remove(tmp)
+		return errGatewayFileExists
+	}
+	staged := gw.stagedPath(dxPath)
+	if err := os.MkdirAll(filepath.Dir(staged), 0700); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, staged); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	params := storage.FileUploadParams{
+		Source: staged,
+		DxPath: dxPath,
+		Mode:   storage.Normal,
+	}
+	if err := gw.client.Upload(params); err != nil {
+		os.Remove(staged)
+		return err
+	}
+	return nil
+}
+
+// delete deletes the file along with the staged file
+func (gw *gateway) delete(w http.ResponseWriter, dxPath storage.DxPath) {
+	if err := gw.client.DeleteFile(dxPath); err != nil {
+		writeGatewayError(w, http.StatusInternalServerError, err)
+		return
+	}
+	os.Remove(gw.stagedPath(dxPath))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeStaged removes the local source of the replaced or deleted file, if the source is
+// staged by the gateway. Other local files are never removed
+func (gw *gateway) removeStaged(source string) {
+	if source == "" || !strings.HasPrefix(source, gw.stagingDir+string(filepath.Separator)) {
+		return
+	}
+	if err := os.Remove(source); err != nil && !os.IsNotExist(err) {
+		gw.client.log.Warn("failed to remove the staged file", "path", source, "err", err)
+	}
+}
+
+// stagedPath returns the path of the staged file for the dxPath
+func (gw *gateway) stagedPath(dxPath storage.DxPath) string {
+	return string(dxPath.SysPath(storage.SysPath(gw.stagingDir)))
+}
+
+// writeGatewayJSON writes the value as json with the status code to the response
+func writeGatewayJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeGatewayError writes the error with the status code to the response
+func writeGatewayError(w http.ResponseWriter, code int, err error) {
+	writeGatewayJSON(w, code, gatewayError{Error: err.Error()})
+}
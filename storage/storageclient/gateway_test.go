@@ -0,0 +1,194 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
+)
+
+func TestGateway_Files(t *testing.T) {
+	storage.ENV = storage.EnvTest
+
+	sct := newStorageClientTester(t)
+	sc := sct.Client
+	defer sc.Close()
+
+	stagingDir, err := ioutil.TempDir("", "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	gw := &gateway{
+		client:        sc,
+		files:         filesystem.NewPublicFileSystemAPI(sc.fileSystem),
+		stagingDir:    stagingDir,
+		maxUploadSize: 8 << 20,
+		token:         testGatewayToken,
+	}
+	server := httptest.NewServer(gw.handler())
+	defer server.Close()
+
+	// the requests without the bearer token are rejected
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/files", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer wrong")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("request with the wrong token should be unauthorized: %v", err)
+	}
+
+	// without enough contracts, the upload fails and the staged file is removed
+	dxPath := randomDxPath()
+	content := generateRandomBytes(8)
+	resp := gatewayRequest(t, http.MethodPut, server.URL+"/files/"+dxPath.Path, content)
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("upload without contracts status expect %v, got %v", http.StatusInternalServerError, resp.StatusCode)
+	}
+	if _, err = os.Stat(gw.stagedPath(dxPath)); !os.IsNotExist(err) {
+		t.Fatalf("the staged file should be removed after the upload failed")
+	}
+
+	contractSet := sc.contractManager.GetStorageContractSet()
+	prevIDs := make(map[storage.ContractID]struct{})
+	for _, id := range contractSet.IDs() {
+		prevIDs[id] = struct{}{}
+	}
+	if err = sc.contractManager.InsertRandomActiveContracts(2); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, id := range contractSet.IDs() {
+			if _, exists := prevIDs[id]; exists {
+				continue
+			}
+			if c, exists := contractSet.Acquire(id); exists {
+				contractSet.Delete(c)
+			}
+		}
+	}()
+
+	// upload the content streamed in the request body
+	resp = gatewayRequest(t, http.MethodPut, server.URL+"/files/"+dxPath.Path, content)
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := ioutil.ReadAll(resp.Body)
+		t.Fatalf("upload status expect %v, got %v: %s", http.StatusCreated, resp.StatusCode, body)
+	}
+	staged, err := ioutil.ReadFile(gw.stagedPath(dxPath))
+	if err != nil || !bytes.Equal(staged, content) {
+		t.Fatalf("the staged file does not match the uploaded content: %v", err)
+	}
+
+	// the uploaded file is not replaced, and the staged file is kept
+	if resp = gatewayRequest(t, http.MethodPut, server.URL+"/files/"+dxPath.Path, generateRandomBytes(8)); resp.StatusCode != http.StatusConflict {
+		t.Errorf("upload to the existing file status expect %v, got %v", http.StatusConflict, resp.StatusCode)
+	}
+	if staged, err = ioutil.ReadFile(gw.stagedPath(dxPath)); err != nil || !bytes.Equal(staged, content) {
+		t.Fatalf("the staged file of the uploaded file should not be changed: %v", err)
+	}
+
+	// the content exceeding the size limit is rejected
+	if resp = gatewayRequest(t, http.MethodPut, server.URL+"/files/"+randomDxPath().Path, generateRandomBytes(9)); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("upload exceeding the size limit status expect %v, got %v", http.StatusRequestEntityTooLarge, resp.StatusCode)
+	}
+
+	// the uploaded file should be listed
+	resp = gatewayRequest(t, http.MethodGet, server.URL+"/files", nil)
+	var files []storage.FileBriefInfo
+	if err = json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+	var listed bool
+	for _, file := range files {
+		listed = listed || file.Path == dxPath.Path
+	}
+	if !listed {
+		t.Fatalf("the uploaded file %v is not listed", dxPath.Path)
+	}
+
+	resp = gatewayRequest(t, http.MethodGet, server.URL+"/info/"+dxPath.Path, nil)
+	var info storage.FileInfo
+	if err = json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.FileSize != uint64(len(content)) {
+		t.Errorf("file size expect %v, got %v", len(content), info.FileSize)
+	}
+
+	// delete the file, the staged file should be removed as well
+	if resp = gatewayRequest(t, http.MethodDelete, server.URL+"/files/"+dxPath.Path, nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete status expect %v, got %v", http.StatusNoContent, resp.StatusCode)
+	}
+	if _, err = os.Stat(gw.stagedPath(dxPath)); !os.IsNotExist(err) {
+		t.Errorf("the staged file should be removed after the file is deleted")
+	}
+
+	// bad requests
+	if resp = gatewayRequest(t, http.MethodGet, server.URL+"/info/"+dxPath.Path, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("info of deleted file status expect %v, got %v", http.StatusNotFound, resp.StatusCode)
+	}
+	if resp = gatewayRequest(t, http.MethodPut, server.URL+"/files/", content); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("upload with empty path status expect %v, got %v", http.StatusBadRequest, resp.StatusCode)
+	}
+	if resp = gatewayRequest(t, http.MethodPost, server.URL+"/files/"+dxPath.Path, content); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("post status expect %v, got %v", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
+
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		header          string
+		offset, length  uint64
+		partial, failed bool
+	}{
+		{"", 0, 100, false, false},
+		{"bytes=0-9", 0, 10, true, false},
+		{"bytes=90-", 90, 10, true, false},
+		{"bytes=90-200", 90, 10, true, false},
+		{"bytes=-10", 90, 10, true, false},
+		{"bytes=-200", 0, 100, true, false},
+		{"bytes=0-9,20-29", 0, 100, false, false},
+		{"bytes=100-", 0, 0, false, true},
+		{"bytes=9-0", 0, 0, false, true},
+		{"bytes=a-b", 0, 0, false, true},
+	}
+	for _, test := range tests {
+		offset, length, partial, err := parseByteRange(test.header, 100)
+		if (err != nil) != test.failed {
+			t.Errorf("%q: expect failed %v, got %v", test.header, test.failed, err)
+			continue
+		}
+		if !test.failed && (offset != test.offset || length != test.length || partial != test.partial) {
+			t.Errorf("%q: expect %v/%v/%v, got %v/%v/%v", test.header, test.offset, test.length, test.partial, offset, length, partial)
+		}
+	}
+}
+
+// testGatewayToken is the bearer token of the gateway in tests
+const testGatewayToken = "token"
+
+// gatewayRequest sends the request with the bearer token to the gateway and returns the response
+func gatewayRequest(t *testing.T, method, url string, body []byte) *http.Response {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testGatewayToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
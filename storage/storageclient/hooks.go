@@ -0,0 +1,161 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"fmt"
+	"sync"
+)
+
+// the operations the hooks could be registered for
+const (
+	HookUpload   = "upload"
+	HookDownload = "download"
+	HookDelete   = "delete"
+	HookRepair   = "repair"
+)
+
+type (
+	// HookInfo is the information of the operation passed to the hooks
+	HookInfo struct {
+		Operation string
+		DxPath    string
+
+		// LocalPath is the source file of the upload, or the destination of the download
+		LocalPath string
+		FileSize  uint64
+	}
+
+	// PreHook is called before the operation starts. The operation is vetoed if an error
+	// is returned
+	PreHook func(info HookInfo) error
+
+	// PostHook is called after the operation finishes with the error of the operation.
+	// The uploads and repairs are regarded as finished when the segments are scheduled,
+	// and the downloads are finished when all data are written to the destination. The
+	// post hooks are called synchronously by the operation, and the repair hooks by the
+	// upload loop, so the post hooks must not call back into the storage client
+	PostHook func(info HookInfo, err error)
+
+	// HookID is the id of the registered hook, used to unregister the hook
+	HookID uint64
+
+	// hookRegistry is the hooks registered by the applications embedding the storage client,
+	// keyed by the operation
+	hookRegistry struct {
+		pre    map[string][]preHookEntry
+		post   map[string][]postHookEntry
+		nextID HookID
+		lock   sync.RWMutex
+	}
+
+	preHookEntry struct {
+		id   HookID
+		hook PreHook
+	}
+
+	postHookEntry struct {
+		id   HookID
+		hook PostHook
+	}
+)
+
+// newHookRegistry creates an empty hookRegistry
+func newHookRegistry() *hookRegistry {
+	return &hookRegistry{
+		pre:  make(map[string][]preHookEntry),
+		post: make(map[string][]postHookEntry),
+	}
+}
+
+// RegisterPreHook registers the hook called before the operation. The hooks are called in the
+// order registered, and the operation is vetoed by the first hook returning an error
+func (client *StorageClient) RegisterPreHook(operation string, hook PreHook) (HookID, error) {
+	if err := validHookOperation(operation); err != nil {
+		return 0, err
+	}
+	h := client.hooks
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.nextID++
+	h.pre[operation] = append(h.pre[operation], preHookEntry{id: h.nextID, hook: hook})
+	return h.nextID, nil
+}
+
+// RegisterPostHook registers the hook called after the operation finishes
+func (client *StorageClient) RegisterPostHook(operation string, hook PostHook) (HookID, error) {
+	if err := validHookOperation(operation); err != nil {
+		return 0, err
+	}
+	h := client.hooks
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.nextID++
+	h.post[operation] = append(h.post[operation], postHookEntry{id: h.nextID, hook: hook})
+	return h.nextID, nil
+}
+
+// UnregisterHook removes the registered hook. False is returned if the hook does not exist
+func (client *StorageClient) UnregisterHook(id HookID) bool {
+	h := client.hooks
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for operation, entries := range h.pre {
+		for i, entry := range entries {
+			if entry.id == id {
+				h.pre[operation] = append(entries[:i:i], entries[i+1:]...)
+				return true
+			}
+		}
+	}
+	for operation, entries := range h.post {
+		for i, entry := range entries {
+			if entry.id == id {
+				h.post[operation] = append(entries[:i:i], entries[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runPreHooks calls the pre hooks of the operation, and returns the error of the hook vetoing
+// the operation
+func (h *hookRegistry) runPreHooks(info HookInfo) error {
+	h.lock.RLock()
+	entries := h.pre[info.Operation]
+	h.lock.RUnlock()
+
+	for _, entry := range entries {
+		if err := entry.hook(info); err != nil {
+			return fmt.Errorf("%s of %s vetoed by hook: %v", info.Operation, info.DxPath, err)
+		}
+	}
+	return nil
+}
+
+// runPostHooks calls the post hooks of the operation with the error of the operation
+func (h *hookRegistry) runPostHooks(info HookInfo, err error) {
+	h.lock.RLock()
+	entries := h.post[info.Operation]
+	h.lock.RUnlock()
+
+	for _, entry := range entries {
+		entry.hook(info, err)
+	}
+}
+
+// validHookOperation checks if the hooks could be registered for the operation
+func validHookOperation(operation string) error {
+	switch operation {
+	case HookUpload, HookDownload, HookDelete, HookRepair:
+		return nil
+	default:
+		return fmt.Errorf("hooks are not supported for operation %s", operation)
+	}
+}
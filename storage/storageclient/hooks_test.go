@@ -0,0 +1,71 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestStorageClient_Hooks(t *testing.T) {
+	sct := newStorageClientTester(t)
+	defer sct.Client.Close()
+	client := sct.Client
+
+	entry := newFileEntry(t, client)
+	dxPath := entry.DxPath()
+	defer os.Remove(string(entry.LocalPath()))
+	if err := entry.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.RegisterPreHook("rename", func(HookInfo) error { return nil }); err == nil {
+		t.Errorf("registering the hook for an unsupported operation should fail")
+	}
+
+	// the pre hook vetoes the deletion
+	var preCalled []HookInfo
+	vetoID, err := client.RegisterPreHook(HookDelete, func(info HookInfo) error {
+		preCalled = append(preCalled, info)
+		return errors.New("file is protected")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var postCalled []error
+	if _, err = client.RegisterPostHook(HookDelete, func(info HookInfo, err error) {
+		postCalled = append(postCalled, err)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = client.DeleteFile(dxPath); err == nil {
+		t.Fatal("the deletion should be vetoed by the pre hook")
+	}
+	if len(preCalled) != 1 || preCalled[0].DxPath != dxPath.Path || preCalled[0].Operation != HookDelete {
+		t.Errorf("unexpected pre hook calls %+v", preCalled)
+	}
+	if len(postCalled) != 0 {
+		t.Errorf("the post hook should not be called for the vetoed deletion")
+	}
+	if _, err = os.Stat(string(entry.FilePath())); err != nil {
+		t.Errorf("the vetoed file should not be deleted: %v", err)
+	}
+
+	// the deletion succeeds once the pre hook is unregistered
+	if !client.UnregisterHook(vetoID) {
+		t.Fatal("failed to unregister the hook")
+	}
+	if client.UnregisterHook(vetoID) {
+		t.Error("the hook should not be unregistered twice")
+	}
+	if err = client.DeleteFile(dxPath); err != nil {
+		t.Fatal(err)
+	}
+	if len(preCalled) != 1 || len(postCalled) != 1 || postCalled[0] != nil {
+		t.Errorf("unexpected hook calls, pre %+v, post %+v", preCalled, postCalled)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package memorymanager
+
+import "sync"
+
+// BufferPool reuses the large byte buffers, such as the sectors read for erasure encoding
+// and the segments recovered by erasure decoding. The buffers are kept by their capacity,
+// and the buffers returned are dropped once the idle buffers kept exceed the limit in bytes
+type BufferPool struct {
+	free  map[int][][]byte
+	idle  uint64
+	limit uint64
+	stats BufferPoolStats
+	lock  sync.Mutex
+}
+
+// BufferPoolStats is the metrics of the buffer pool
+type BufferPoolStats struct {
+	Allocated uint64 `json:"allocated"`
+	Reused    uint64 `json:"reused"`
+	Returned  uint64 `json:"returned"`
+	Dropped   uint64 `json:"dropped"`
+	IdleBytes uint64 `json:"idlebytes"`
+	Limit     uint64 `json:"limit"`
+}
+
+// NewBufferPool creates a buffer pool keeping at most limit bytes of idle buffers
+func NewBufferPool(limit uint64) *BufferPool {
+	return &BufferPool{
+		free:  make(map[int][][]byte),
+		limit: limit,
+	}
+}
+
+// Get returns a zeroed buffer with the length of size, which is reused from the idle
+// buffers if possible
+func (bp *BufferPool) Get(size int) []byte {
+	bp.lock.Lock()
+	buffers := bp.free[size]
+	if len(buffers) == 0 {
+		bp.stats.Allocated++
+		bp.lock.Unlock()
+		return make([]byte, size)
+	}
+	buf := buffers[len(buffers)-1]
+	buffers[len(buffers)-1] = nil
+	bp.free[size] = buffers[:len(buffers)-1]
+	bp.idle -= uint64(size)
+	bp.stats.Reused++
+	bp.lock.Unlock()
+
+	for i := range buf {
+		buf[i] = 0
+	}
+	return buf
+}
+
+// Put returns the buffer to the pool. The buffer must not be used after returned
+func (bp *BufferPool) Put(buf []byte) {
+	size := cap(buf)
+	if size == 0 {
+		return
+	}
+	bp.lock.Lock()
+	defer bp.lock.Unlock()
+
+	bp.stats.Returned++
+	if bp.idle+uint64(size) > bp.limit {
+		bp.stats.Dropped++
+		return
+	}
+	bp.free[size] = append(bp.free[size], buf[:size])
+	bp.idle += uint64(size)
+}
+
+// SetLimit changes the limit of the idle buffers kept. The idle buffers exceeding the new
+// limit are dropped
+func (bp *BufferPool) SetLimit(limit uint64) {
+	bp.lock.Lock()
+	defer bp.lock.Unlock()
+
+	bp.limit = limit
+	for size, buffers := range bp.free {
+		for len(buffers) > 0 && bp.idle > bp.limit {
+			buffers[len(buffers)-1] = nil
+			buffers = buffers[:len(buffers)-1]
+			bp.idle -= uint64(size)
+			bp.stats.Dropped++
+		}
+		bp.free[size] = buffers
+	}
+}
+
+// Stats returns the metrics of the buffer pool
+func (bp *BufferPool) Stats() BufferPoolStats {
+	bp.lock.Lock()
+	defer bp.lock.Unlock()
+
+	stats := bp.stats
+	stats.IdleBytes = bp.idle
+	stats.Limit = bp.limit
+	return stats
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package memorymanager
+
+import "testing"
+
+func TestBufferPool_GetPut(t *testing.T) {
+	bp := NewBufferPool(25)
+
+	buf := bp.Get(10)
+	if len(buf) != 10 {
+		t.Fatalf("expected buffer length 10, got %d", len(buf))
+	}
+	for i := range buf {
+		buf[i] = 1
+	}
+	bp.Put(buf)
+
+	// the returned buffer is reused and zeroed
+	reused := bp.Get(10)
+	if &reused[0] != &buf[0] {
+		t.Errorf("expected the returned buffer to be reused")
+	}
+	for _, b := range reused {
+		if b != 0 {
+			t.Fatalf("expected the reused buffer to be zeroed")
+		}
+	}
+
+	// the buffers exceeding the limit are dropped
+	bp.Put(reused)
+	bp.Put(bp.Get(10))
+	bp.Put(make([]byte, 10))
+	bp.Put(make([]byte, 10))
+
+	stats := bp.Stats()
+	expected := BufferPoolStats{Allocated: 1, Reused: 2, Returned: 5, Dropped: 1, IdleBytes: 20, Limit: 25}
+	if stats != expected {
+		t.Errorf("expected stats %+v, got %+v", expected, stats)
+	}
+
+	// shrinking the limit drops the idle buffers
+	bp.SetLimit(10)
+	if stats = bp.Stats(); stats.IdleBytes != 10 || stats.Dropped != 2 {
+		t.Errorf("unexpected stats after shrinking the limit: %+v", stats)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/timeseries"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// clientMetrics are the metrics aggregated hourly by the storage client
+var clientMetrics = []string{
+	storage.MetricSpend,
+	storage.MetricUploadBytes,
+	storage.MetricDownloadBytes,
+	storage.MetricContracts,
+}
+
+// openMetrics opens the ring file of the client metrics, which is shared with the
+// contract manager to record the contracts formed
+func (client *StorageClient) openMetrics() (err error) {
+	path := filepath.Join(client.persistDir, storage.MetricsFileName)
+	if client.metrics, err = timeseries.Open(path, clientMetrics, storage.MetricsRetentionHours); err != nil {
+		return
+	}
+	client.contractManager.SetMetrics(client.metrics)
+	return
+}
+
+// recordTransfer records the bytes transferred and the amount paid in a revision
+func (client *StorageClient) recordTransfer(metric string, bytes uint64, cost common.BigInt) {
+	if err := client.metrics.Add(metric, common.NewBigIntUint64(bytes)); err != nil {
+		client.log.Warn("failed to record the client metric", "metric", metric, "err", err)
+	}
+	if err := client.metrics.Add(storage.MetricSpend, cost); err != nil {
+		client.log.Warn("failed to record the client metric", "metric", storage.MetricSpend, "err", err)
+	}
+}
+
+// MetricsHistory returns the hourly aggregates of the client metrics in the last days
+func (client *StorageClient) MetricsHistory(days int) ([]timeseries.Point, error) {
+	if days <= 0 || days > storage.MetricsRetentionDays {
+		return nil, fmt.Errorf("days must be within [1, %v]", storage.MetricsRetentionDays)
+	}
+	return client.metrics.Query(time.Now().Add(-time.Duration(days) * 24 * time.Hour))
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/dxfuse"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
+)
+
+// Mount mounts the dxfile namespace at the mount point as a local filesystem. The opened
+// files are cached under the persist directory, and the written files are uploaded when closed
+func (client *StorageClient) Mount(mountPoint string) error {
+	if err := client.tm.Add(); err != nil {
+		return err
+	}
+	defer client.tm.Done()
+
+	mountPoint, err := filepath.Abs(mountPoint)
+	if err != nil {
+		return err
+	}
+
+	// the mount point is reserved with a nil entry, and the filesystem is mounted without
+	// the lock, which could block till the kernel responds
+	client.lock.Lock()
+	if _, exists := client.mounts[mountPoint]; exists {
+		client.lock.Unlock()
+		return fmt.Errorf("%v is already mounted", mountPoint)
+	}
+	client.mounts[mountPoint] = nil
+	client.lock.Unlock()
+
+	mounted, err := client.mount(mountPoint)
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	if err != nil {
+		delete(client.mounts, mountPoint)
+		return err
+	}
+	client.mounts[mountPoint] = mounted
+
+	client.log.Info("Dxfiles mounted", "mountpoint", mountPoint)
+	return nil
+}
+
+// mount creates the filesystem with the cache directory of the mount point, and mounts it
+func (client *StorageClient) mount(mountPoint string) (*dxfuse.MountedFS, error) {
+	// each mount point has its own cache directory
+	hash := sha256.Sum256([]byte(mountPoint))
+	cacheDir := filepath.Join(client.persistDir, mountCacheDir, hex.EncodeToString(hash[:8]))
+	dfs, err := dxfuse.New(&fuseBackend{client: client, snapshotDir: dxfuse.SnapshotDir(cacheDir)}, cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	mounted, err := dxfuse.Mount(dfs, mountPoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mount at %v: %s", mountPoint, err.Error())
+	}
+	return mounted, nil
+}
+
+// Unmount unmounts the filesystem mounted at the mount point
+func (client *StorageClient) Unmount(mountPoint string) error {
+	mountPoint, err := filepath.Abs(mountPoint)
+	if err != nil {
+		return err
+	}
+
+	client.lock.Lock()
+	mounted, exists := client.mounts[mountPoint]
+	if exists && mounted == nil {
+		client.lock.Unlock()
+		return fmt.Errorf("%v is being mounted", mountPoint)
+	}
+	delete(client.mounts, mountPoint)
+	client.lock.Unlock()
+	if !exists {
+		return fmt.Errorf("%v is not mounted", mountPoint)
+	}
+	return mounted.Unmount()
+}
+
+// Mounts returns the mount points of the mounted filesystems
+func (client *StorageClient) Mounts() []string {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	mountPoints := make([]string, 0, len(client.mounts))
+	for mountPoint, mounted := range client.mounts {
+		// the mount point being mounted is not listed
+		if mounted == nil {
+			continue
+		}
+		mountPoints = append(mountPoints, mountPoint)
+	}
+	sort.Strings(mountPoints)
+	return mountPoints
+}
+
+// unmountAll unmounts all filesystems, which is called when the storage client stops
+func (client *StorageClient) unmountAll() error {
+	var errs []error
+	for _, mountPoint := range client.Mounts() {
+		if err := client.Unmount(mountPoint); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unmount %v: %s", mountPoint, err.Error()))
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// fuseBackend implements dxfuse.Backend with the storage client
+type fuseBackend struct {
+	client      *StorageClient
+	snapshotDir string
+}
+
+// FileList returns the brief information of all dxfiles
+func (fb *fuseBackend) FileList() []storage.FileBriefInfo {
+	return filesystem.NewPublicFileSystemAPI(fb.client.fileSystem).FileList()
+}
+
+// FileSize returns the size of the dxfile
+func (fb *fuseBackend) FileSize(dxPath storage.DxPath) (uint64, error) {
+	entry, err := fb.client.fileSystem.OpenDxFile(dxPath)
+	if err != nil {
+		return 0, err
+	}
+	defer entry.Close()
+	return entry.FileSize(), nil
+}
+
+// Download downloads the dxfile to the local path
+func (fb *fuseBackend) Download(dxPath storage.DxPath, localPath string) error {
+	return fb.client.DownloadSync(storage.DownloadParameters{
+		RemoteFilePath:   dxPath.Path,
+		WriteToLocalPath: localPath,
+	})
+}
+
+// DownloadRange downloads the range of the dxfile to w
+func (fb *fuseBackend) DownloadRange(dxPath storage.DxPath, w io.Writer, offset, length uint64) error {
+	return fb.client.DownloadStream(dxPath, w, offset, length)
+}
+
+// Upload uploads the local file to dxPath. The existing dxfile is replaced only after the
+// new content is tracked, and the snapshot used as its source is removed
+func (fb *fuseBackend) Upload(dxPath storage.DxPath, localPath string) error {
+	replaced, err := fb.client.replaceFile(storage.FileUploadParams{
+		Source: localPath,
+		DxPath: dxPath,
+		Mode:   storage.Override,
+	})
+	if err != nil {
+		return err
+	}
+	fb.removeSnapshot(replaced)
+	return nil
+}
+
+// Delete deletes the dxfile, and the snapshot used as its source
+func (fb *fuseBackend) Delete(dxPath storage.DxPath) error {
+	var source string
+	if entry, err := fb.client.fileSystem.OpenDxFile(dxPath); err == nil {
+		source = string(entry.LocalPath())
+		entry.Close()
+	}
+	if err := fb.client.DeleteFile(dxPath); err != nil {
+		return err
+	}
+	fb.removeSnapshot(source)
+	return nil
+}
+
+// Rename renames the dxfile
+func (fb *fuseBackend) Rename(prevPath, newPath storage.DxPath) error {
+	return fb.client.fileSystem.RenameDxFile(prevPath, newPath)
+}
+
+// removeSnapshot removes the local source of the replaced or deleted dxfile, if the source
+// is a snapshot made by the filesystem. Other local files are never removed
+func (fb *fuseBackend) removeSnapshot(source string) {
+	if source == "" || !strings.HasPrefix(source, fb.snapshotDir+string(filepath.Separator)) {
+		return
+	}
+	if err := os.Remove(source); err != nil && !os.IsNotExist(err) {
+		fb.client.log.Warn("failed to remove the snapshot", "path", source, "err", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/ethdb"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// the operations recorded in the audit trail
+const (
+	OperationUpload   = "upload"
+	OperationDownload = "download"
+	OperationDelete   = "delete"
+)
+
+// OperationAudit links a successful upload or download negotiation to the contract revision
+// it resulted in, so that the file operations could be reconciled with the contract spend
+type OperationAudit struct {
+	ContractID     storage.ContractID `json:"contractID"`
+	HostID         enode.ID           `json:"hostID"`
+	Operation      string             `json:"operation"`
+	RevisionNumber uint64             `json:"revisionNumber"`
+
+	// Charged is the amount transferred from the client to the storage host by the revision
+	Charged common.BigInt `json:"charged"`
+
+	// Bytes is the size of the data uploaded, downloaded or deleted
+	Bytes uint64 `json:"bytes"`
+
+	// MerkleRoot is the merkle root of the contract after the upload or delete, or the
+	// merkle root of the sector downloaded
+	MerkleRoot common.Hash `json:"merkleRoot"`
+
+	Time time.Time `json:"time"`
+}
+
+// openOperationAudits opens the database of the operation audit trail
+func (client *StorageClient) openOperationAudits() (err error) {
+	client.operationAudits, err = ethdb.NewLDBDatabase(filepath.Join(client.persistDir, operationAuditDBName), 0, 0)
+	return
+}
+
+// operationAuditKey makes the key of the audit entry. The entries of a contract share the
+// contract id as the prefix, and are ordered by the revision number
+func operationAuditKey(id storage.ContractID, revisionNumber uint64) []byte {
+	key := make([]byte, len(id)+8)
+	copy(key, id[:])
+	binary.BigEndian.PutUint64(key[len(id):], revisionNumber)
+	return key
+}
+
+// recordOperation records the audit entry of the operation, which resulted in the signed revision
+// from the last revision
+func (client *StorageClient) recordOperation(operation string, hostID enode.ID, last, signed types.StorageContractRevision, bytes uint64, root common.Hash) {
+	audit := OperationAudit{
+		ContractID:     storage.ContractID(signed.ParentID),
+		HostID:         hostID,
+		Operation:      operation,
+		RevisionNumber: signed.NewRevisionNumber,
+		Charged:        common.PtrBigInt(last.NewValidProofOutputs[0].Value).Sub(common.PtrBigInt(signed.NewValidProofOutputs[0].Value)),
+		Bytes:          bytes,
+		MerkleRoot:     root,
+		Time:           time.Now(),
+	}
+	blob, err := json.Marshal(audit)
+	if err == nil {
+		err = client.operationAudits.Put(operationAuditKey(audit.ContractID, audit.RevisionNumber), blob)
+	}
+	if err != nil {
+		client.log.Warn("failed to record the operation audit", "contract", audit.ContractID, "operation", operation, "err", err)
+	}
+}
+
+// OperationAudits returns the audit entries of the upload and download operations of the
+// contract, ordered by the revision number
+func (client *StorageClient) OperationAudits(id storage.ContractID) ([]OperationAudit, error) {
+	iter := client.operationAudits.NewIteratorWithPrefix(id[:])
+	defer iter.Release()
+
+	audits := make([]OperationAudit, 0)
+	for iter.Next() {
+		var audit OperationAudit
+		if err := json.Unmarshal(iter.Value(), &audit); err != nil {
+			return nil, err
+		}
+		audits = append(audits, audit)
+	}
+	return audits, iter.Error()
+}
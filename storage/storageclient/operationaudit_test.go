@@ -0,0 +1,85 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/log"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestStorageClient_OperationAudits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "operationaudit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	client := &StorageClient{persistDir: dir, log: log.New()}
+	if err = client.openOperationAudits(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.operationAudits.Close()
+
+	ids := []common.Hash{{1}, {2}}
+	hostID := enode.ID{3}
+	revisions := make(map[common.Hash]types.StorageContractRevision)
+	for _, id := range ids {
+		revisions[id] = types.StorageContractRevision{
+			ParentID:              id,
+			NewValidProofOutputs:  []types.DxcoinCharge{{Value: big.NewInt(1000)}, {Value: big.NewInt(0)}},
+			NewMissedProofOutputs: []types.DxcoinCharge{{Value: big.NewInt(1000)}, {Value: big.NewInt(0)}},
+		}
+	}
+
+	// the revision numbers beyond 255 are ordered by the big endian keys
+	for _, number := range []uint64{1, 256, 2} {
+		for _, id := range ids {
+			last := revisions[id]
+			signed := NewRevision(last, big.NewInt(int64(number)))
+			signed.NewRevisionNumber = number
+			client.recordOperation(OperationUpload, hostID, last, signed, storage.SectorSize, common.Hash{byte(number)})
+			revisions[id] = signed
+		}
+	}
+	last := revisions[ids[0]]
+	signed := NewRevision(last, big.NewInt(10))
+	signed.NewRevisionNumber = 300
+	client.recordOperation(OperationDownload, hostID, last, signed, 1<<16, common.Hash{4})
+
+	audits, err := client.OperationAudits(storage.ContractID(ids[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expects := []struct {
+		operation string
+		number    uint64
+		charged   int64
+	}{
+		{OperationUpload, 1, 1}, {OperationUpload, 2, 2}, {OperationUpload, 256, 256}, {OperationDownload, 300, 10},
+	}
+	if len(audits) != len(expects) {
+		t.Fatalf("expect %v audits, got %v", len(expects), len(audits))
+	}
+	for i, expect := range expects {
+		audit := audits[i]
+		if audit.Operation != expect.operation || audit.RevisionNumber != expect.number || audit.Charged.Cmp(common.NewBigInt(expect.charged)) != 0 {
+			t.Errorf("audit %v not expected: %+v", i, audit)
+		}
+		if audit.ContractID != storage.ContractID(ids[0]) || audit.HostID != hostID {
+			t.Errorf("audit %v recorded with wrong contract or host: %+v", i, audit)
+		}
+	}
+
+	if audits, err = client.OperationAudits(storage.ContractID{5}); err != nil || len(audits) != 0 {
+		t.Errorf("the contract without operations should have no audits: %v, %v", len(audits), err)
+	}
+}
@@ -18,8 +18,10 @@ var settingsMetadata = common.Metadata{
 }
 
 type persistence struct {
-	MaxDownloadSpeed int64
-	MaxUploadSpeed   int64
+	MaxDownloadSpeed  int64
+	MaxUploadSpeed    int64
+	DownloadOverdrive int
+	Transitions       []StorageClassTransition
 }
 
 func (client *StorageClient) loadPersist() error {
@@ -42,7 +44,7 @@ func (client *StorageClient) saveSettings() error {
 
 // load prior StorageClient settings
 func (client *StorageClient) loadSettings() error {
-	client.persist = persistence{}
+	client.persist = persistence{DownloadOverdrive: DefaultDownloadOverdrive}
 	err := common.LoadDxJSON(settingsMetadata, filepath.Join(client.persistDir, PersistFilename), &client.persist)
 	if os.IsNotExist(err) {
 		client.persist.MaxDownloadSpeed = DefaultMaxDownloadSpeed
@@ -0,0 +1,76 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// uploadCost calculates the bandwidth price, the storage price and the deposit of the upload actions
+// based on the host config, and the file size after the actions are applied
+func uploadCost(config storage.HostExtConfig, actions []storage.UploadAction, fileSize uint64, blockBytes uint64) (bandwidthPrice, storagePrice, deposit common.BigInt, newFileSize uint64) {
+	// calculate price per sector
+	sectorBandwidthPrice := config.UploadBandwidthPrice.MultUint64(storage.SectorSize)
+	sectorStoragePrice := config.StoragePrice.MultUint64(blockBytes)
+	sectorDeposit := config.Deposit.MultUint64(blockBytes)
+
+	newFileSize = fileSize
+	for _, action := range actions {
+		switch action.Type {
+		case storage.UploadActionAppend:
+			bandwidthPrice = bandwidthPrice.Add(sectorBandwidthPrice)
+			newFileSize += storage.SectorSize
+		case storage.UploadActionTrim:
+			if trimmed := storage.SectorSize * action.A; trimmed <= newFileSize {
+				newFileSize -= trimmed
+			}
+		}
+	}
+	if newFileSize > fileSize {
+		addedSectors := (newFileSize - fileSize) / storage.SectorSize
+		storagePrice = sectorStoragePrice.MultUint64(addedSectors)
+		deposit = sectorDeposit.MultUint64(addedSectors)
+	}
+
+	// estimate cost of Merkle proof
+	proofSize := storage.HashSize * (128 + len(actions))
+	bandwidthPrice = bandwidthPrice.Add(config.DownloadBandwidthPrice.MultUint64(uint64(proofSize)))
+	return
+}
+
+// releasedDeposit calculates the deposit risked by the host for the sectors trimmed by the
+// upload actions, which is no longer at risk once the sectors are removed from the contract
+func releasedDeposit(config storage.HostExtConfig, actions []storage.UploadAction, blockBytes uint64) (deposit common.BigInt) {
+	sectorDeposit := config.Deposit.MultUint64(blockBytes)
+	for _, action := range actions {
+		if action.Type == storage.UploadActionTrim {
+			deposit = deposit.Add(sectorDeposit.MultUint64(action.A))
+		}
+	}
+	return
+}
+
+// downloadCost calculates the price of downloading the estimated bandwidth based on the host config
+func downloadCost(config storage.HostExtConfig, estBandwidth uint64) common.BigInt {
+	bandwidthPrice := config.DownloadBandwidthPrice.MultUint64(estBandwidth)
+	return config.BaseRPCPrice.Add(bandwidthPrice).Add(config.SectorAccessPrice)
+}
+
+// auditRevisionCharge compares the amount transferred from the client to the storage host by the
+// signed revision with the cost calculated from the prices the host advertised when the contract
+// was negotiated. The price fluctuation allowed by the client is tolerated
+func (client *StorageClient) auditRevisionCharge(hostID enode.ID, last, signed types.StorageContractRevision, cost func(config storage.HostExtConfig) common.BigInt) {
+	advertised, exists := client.storageHostManager.AdvertisedPrices(hostID)
+	if !exists {
+		return
+	}
+	prev := common.PtrBigInt(last.NewValidProofOutputs[0].Value)
+	charged := prev.Sub(common.PtrBigInt(signed.NewValidProofOutputs[0].Value))
+	expected := cost(advertised).MultFloat64(1 + extraRatio)
+	client.storageHostManager.AuditRevisionCharge(hostID, charged, expected)
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractmanager"
+	"github.com/DxChainNetwork/godx/storage/storageclient/storagehostmanager"
+)
+
+// ClientProfile contains the complete storage client configuration, which can be
+// exported from one node and imported on another node
+type ClientProfile struct {
+	Version       string                `json:"version"`
+	Setting       storage.ClientSetting `json:"setting"`
+	FilterMode    string                `json:"filtermode"`
+	FilteredHosts []enode.ID            `json:"filteredhosts"`
+}
+
+// ExportProfile will collect the current client setting, and host filter settings
+// into a ClientProfile
+func (client *StorageClient) ExportProfile() (profile ClientProfile) {
+	return ClientProfile{
+		Version:       ProfileVersion,
+		Setting:       client.RetrieveClientSetting(),
+		FilterMode:    client.storageHostManager.RetrieveFilterMode(),
+		FilteredHosts: client.storageHostManager.RetrieveFilteredHosts(),
+	}
+}
+
+// ImportProfile will apply the profile to the storage client. The whole profile will
+// be validated before any of the setting is applied. If the client setting failed to be
+// applied, the filter mode will be reverted back to the original one
+func (client *StorageClient) ImportProfile(profile ClientProfile) (err error) {
+	filterMode, err := profileValidation(profile)
+	if err != nil {
+		return
+	}
+
+	// record the previous filter settings
+	prevMode, err := storagehostmanager.ToFilterMode(client.storageHostManager.RetrieveFilterMode())
+	if err != nil {
+		return
+	}
+	prevHosts := client.storageHostManager.RetrieveFilteredHosts()
+
+	// apply the filter mode
+	if err = client.storageHostManager.SetFilterMode(filterMode, profile.FilteredHosts); err != nil {
+		return fmt.Errorf("failed to set the filter mode: %s", err.Error())
+	}
+
+	// apply the client setting, revert the filter mode if failed
+	if err = client.SetClientSetting(profile.Setting); err != nil {
+		if errRevert := client.storageHostManager.SetFilterMode(prevMode, prevHosts); errRevert != nil {
+			client.log.Error("failed to revert the filter mode", "err", errRevert.Error())
+		}
+		return fmt.Errorf("failed to set the client setting: %s", err.Error())
+	}
+
+	return
+}
+
+// profileValidation will validate every field contained in the profile, and return
+// the parsed filter mode
+func profileValidation(profile ClientProfile) (filterMode storagehostmanager.FilterMode, err error) {
+	if profile.Version != ProfileVersion {
+		err = fmt.Errorf("profile version %s is not supported, expected %s", profile.Version, ProfileVersion)
+		return
+	}
+
+	// validate client setting
+	if err = contractmanager.RentPaymentValidation(profile.Setting.RentPayment); err != nil {
+		err = fmt.Errorf("invalid rent payment: %s", err.Error())
+		return
+	}
+	if profile.Setting.MaxUploadSpeed < 0 || profile.Setting.MaxDownloadSpeed < 0 {
+		err = errors.New("upload/download speed limit cannot be negative")
+		return
+	}
+	if profile.Setting.DownloadOverdrive < 0 {
+		err = errors.New("download overdrive cannot be negative")
+		return
+	}
+
+	// validate the filter settings
+	if filterMode, err = storagehostmanager.ToFilterMode(profile.FilterMode); err != nil {
+		return
+	}
+	if filterMode != storagehostmanager.DisableFilter && len(profile.FilteredHosts) == 0 {
+		err = fmt.Errorf("filter mode %s requires at least one filtered host", profile.FilterMode)
+		return
+	}
+
+	return
+}
+
+// encodeProfile will encode the profile into JSON document
+func encodeProfile(profile ClientProfile) (string, error) {
+	blob, err := json.MarshalIndent(profile, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	return string(blob), nil
+}
+
+// decodeProfile will decode the JSON document into profile. The settings missing in the
+// document, such as the ones added after the profile was exported, are set to the defaults
+func decodeProfile(doc string) (profile ClientProfile, err error) {
+	profile.Setting = storage.ClientSetting{
+		RentPayment:       storage.DefaultRentPayment,
+		MaxUploadSpeed:    DefaultMaxUploadSpeed,
+		MaxDownloadSpeed:  DefaultMaxDownloadSpeed,
+		DownloadOverdrive: DefaultDownloadOverdrive,
+	}
+	err = json.Unmarshal([]byte(doc), &profile)
+	return
+}
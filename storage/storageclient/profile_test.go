@@ -0,0 +1,83 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestProfileEncodeDecode(t *testing.T) {
+	profile := ClientProfile{
+		Version:       ProfileVersion,
+		Setting:       storage.ClientSetting{RentPayment: storage.DefaultRentPayment, EnableIPViolation: true},
+		FilterMode:    "Whitelist",
+		FilteredHosts: []enode.ID{{1}, {2}},
+	}
+
+	doc, err := encodeProfile(profile)
+	if err != nil {
+		t.Fatalf("failed to encode the profile: %s", err.Error())
+	}
+
+	decoded, err := decodeProfile(doc)
+	if err != nil {
+		t.Fatalf("failed to decode the profile: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(profile, decoded) {
+		t.Errorf("profile does not match after decoding: expected %+v, got %+v", profile, decoded)
+	}
+}
+
+func TestProfileDecodeDefaults(t *testing.T) {
+	// the profile exported before the download overdrive is configurable
+	doc := `{"version": "` + ProfileVersion + `", "setting": {"rentpayment": {"storagehosts": 5}, "maxuploadspeed": 100}, "filtermode": "Disabled"}`
+	profile, err := decodeProfile(doc)
+	if err != nil {
+		t.Fatalf("failed to decode the profile: %s", err.Error())
+	}
+	if profile.Setting.DownloadOverdrive != DefaultDownloadOverdrive {
+		t.Errorf("download overdrive expect the default %v, got %v", DefaultDownloadOverdrive, profile.Setting.DownloadOverdrive)
+	}
+	if profile.Setting.MaxUploadSpeed != 100 || profile.Setting.MaxDownloadSpeed != DefaultMaxDownloadSpeed {
+		t.Errorf("speed limits not expected: %+v", profile.Setting)
+	}
+	if profile.Setting.RentPayment.StorageHosts != 5 || profile.Setting.RentPayment.Period != storage.DefaultRentPayment.Period {
+		t.Errorf("rent payment not expected: %+v", profile.Setting.RentPayment)
+	}
+}
+
+func TestProfileValidation(t *testing.T) {
+	valid := ClientProfile{
+		Version:    ProfileVersion,
+		Setting:    storage.ClientSetting{RentPayment: storage.DefaultRentPayment},
+		FilterMode: "Disabled",
+	}
+
+	tables := []struct {
+		modify func(p *ClientProfile)
+		valid  bool
+	}{
+		{func(p *ClientProfile) {}, true},
+		{func(p *ClientProfile) { p.Version = "0.0" }, false},
+		{func(p *ClientProfile) { p.Setting.RentPayment.StorageHosts = 0 }, false},
+		{func(p *ClientProfile) { p.Setting.MaxUploadSpeed = -1 }, false},
+		{func(p *ClientProfile) { p.FilterMode = "unknown" }, false},
+		{func(p *ClientProfile) { p.FilterMode = "Blacklist" }, false},
+		{func(p *ClientProfile) { p.FilterMode = "Blacklist"; p.FilteredHosts = []enode.ID{{1}} }, true},
+	}
+
+	for i, table := range tables {
+		profile := valid
+		table.modify(&profile)
+		if _, err := profileValidation(profile); (err == nil) != table.valid {
+			t.Errorf("test %d: expected valid %v, got error %v", i, table.valid, err)
+		}
+	}
+}
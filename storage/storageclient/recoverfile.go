@@ -0,0 +1,250 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
+)
+
+type (
+	// FileDescriptor is the information needed to rebuild a DxFile from the sectors stored
+	// under the active contracts. The sector roots are indexed by the segment index and
+	// the sector index, and the empty hash stands for the sector not uploaded
+	FileDescriptor struct {
+		DxPath          string          `json:"dxpath"`
+		FileSize        uint64          `json:"filesize"`
+		FileMode        os.FileMode     `json:"filemode"`
+		ErasureCodeType uint8           `json:"erasurecodetype"`
+		MinSectors      uint32          `json:"minsectors"`
+		NumSectors      uint32          `json:"numsectors"`
+		ShardSize       int             `json:"shardsize,omitempty"`
+		CipherKeyCode   uint8           `json:"cipherkeycode"`
+		CipherKey       []byte          `json:"cipherkey"`
+		SectorRoots     [][]common.Hash `json:"sectorroots"`
+	}
+
+	// FileRecoveryReport is the result of rebuilding a DxFile from the file descriptor
+	FileRecoveryReport struct {
+		DxPath                string `json:"dxpath"`
+		Segments              int    `json:"segments"`
+		RecoverableSegments   int    `json:"recoverablesegments"`
+		Sectors               int    `json:"sectors"`
+		SectorsFound          int    `json:"sectorsfound"`
+		ContractsSearched     int    `json:"contractssearched"`
+		UnrecoverableSegments []int  `json:"unrecoverablesegments,omitempty"`
+	}
+)
+
+// errNoSectorsFound is returned when none of the segments in the descriptor could be
+// recovered from the sectors under the active contracts
+var errNoSectorsFound = errors.New("not enough sectors found under the active contracts to recover any segment")
+
+// ExportFileDescriptor writes the descriptor of the DxFile to the path. The descriptor
+// contains the cipher key of the file, and shall be kept as safe as the file itself
+func (client *StorageClient) ExportFileDescriptor(dxPath storage.DxPath, path string) error {
+	if err := client.tm.Add(); err != nil {
+		return err
+	}
+	defer client.tm.Done()
+
+	desc, err := client.fileDescriptor(dxPath)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(desc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// RecoverFile rebuilds the DxFile deleted locally with the descriptor at the path, from the
+// sectors which are still stored under the active contracts. The recovered file is handed to
+// the repair loop as other files, so that the missing sectors are uploaded again
+func (client *StorageClient) RecoverFile(path string) (report FileRecoveryReport, err error) {
+	if err = client.tm.Add(); err != nil {
+		return
+	}
+	defer client.tm.Done()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return report, fmt.Errorf("failed to read the file descriptor: %s", err.Error())
+	}
+	var desc FileDescriptor
+	if err = json.Unmarshal(b, &desc); err != nil {
+		return report, fmt.Errorf("failed to decode the file descriptor: %s", err.Error())
+	}
+	rootHosts, numContracts, err := client.contractRootHosts()
+	if err != nil {
+		return
+	}
+	if report, err = client.recoverFile(desc, rootHosts); err != nil {
+		return
+	}
+	report.ContractsSearched = numContracts
+	return
+}
+
+// fileDescriptor returns the descriptor of the DxFile
+func (client *StorageClient) fileDescriptor(dxPath storage.DxPath) (desc FileDescriptor, err error) {
+	entry, err := client.fileSystem.OpenDxFile(dxPath)
+	if err != nil {
+		return
+	}
+	defer entry.Close()
+
+	ec, err := entry.ErasureCode()
+	if err != nil {
+		return
+	}
+	ck, err := entry.CipherKey()
+	if err != nil {
+		return
+	}
+	desc = FileDescriptor{
+		DxPath:          dxPath.Path,
+		FileSize:        entry.FileSize(),
+		FileMode:        entry.FileMode(),
+		ErasureCodeType: ec.Type(),
+		MinSectors:      ec.MinSectors(),
+		NumSectors:      ec.NumSectors(),
+		CipherKeyCode:   crypto.CipherCodeByName(ck.CodeName()),
+		CipherKey:       ck.Key(),
+		SectorRoots:     make([][]common.Hash, entry.NumSegments()),
+	}
+	if extra := ec.Extra(); len(extra) != 0 {
+		desc.ShardSize, _ = extra[0].(int)
+	}
+	for i := range desc.SectorRoots {
+		sectors, err := entry.Sectors(i)
+		if err != nil {
+			return desc, err
+		}
+		desc.SectorRoots[i] = make([]common.Hash, len(sectors))
+		for j, sectorSet := range sectors {
+			if len(sectorSet) != 0 {
+				desc.SectorRoots[i][j] = sectorSet[0].MerkleRoot
+			}
+		}
+	}
+	return
+}
+
+// contractRootHosts maps the merkle roots of the sectors stored under the active contracts
+// to the hosts of the contracts
+func (client *StorageClient) contractRootHosts() (rootHosts map[common.Hash]enode.ID, numContracts int, err error) {
+	contractSet := client.contractManager.GetStorageContractSet()
+	rootHosts = make(map[common.Hash]enode.ID)
+	for _, id := range contractSet.IDs() {
+		c, exists := contractSet.Acquire(id)
+		if !exists {
+			continue
+		}
+		roots, err := c.MerkleRoots()
+		hostID := c.Header().EnodeID
+		if returnErr := contractSet.Return(c); returnErr != nil {
+			client.log.Warn("failed to return the contract", "id", id, "err", returnErr)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get the merkle roots of contract %v: %s", id, err.Error())
+		}
+		for _, root := range roots {
+			rootHosts[root] = hostID
+		}
+		numContracts++
+	}
+	return
+}
+
+// recoverFile creates the DxFile with the descriptor, and adds the sectors found in rootHosts
+// to the file. The file is not created if no segment could be recovered
+func (client *StorageClient) recoverFile(desc FileDescriptor, rootHosts map[common.Hash]enode.ID) (report FileRecoveryReport, err error) {
+	dxPath, err := storage.NewDxPath(desc.DxPath)
+	if err != nil {
+		return
+	}
+	if entry, err := client.fileSystem.OpenDxFile(dxPath); err == nil {
+		entry.Close()
+		return report, fmt.Errorf("file %v already exists", desc.DxPath)
+	}
+	var extra []interface{}
+	if desc.ShardSize != 0 {
+		extra = append(extra, desc.ShardSize)
+	}
+	ec, err := erasurecode.New(desc.ErasureCodeType, desc.MinSectors, desc.NumSectors, extra...)
+	if err != nil {
+		return report, fmt.Errorf("invalid erasure code in the descriptor: %s", err.Error())
+	}
+	ck, err := crypto.NewCipherKey(desc.CipherKeyCode, desc.CipherKey)
+	if err != nil {
+		return report, fmt.Errorf("invalid cipher key in the descriptor: %s", err.Error())
+	}
+
+	entry, err := client.fileSystem.NewDxFile(dxPath, "", false, ec, ck, desc.FileSize, desc.FileMode)
+	if err != nil {
+		return report, fmt.Errorf("could not create the dx file: %s", err.Error())
+	}
+	recovered := false
+	defer func() {
+		entry.Close()
+		if !recovered {
+			client.fileSystem.DeleteDxFile(dxPath)
+		}
+	}()
+	if entry.NumSegments() != len(desc.SectorRoots) {
+		return report, fmt.Errorf("descriptor has %v segments, expect %v", len(desc.SectorRoots), entry.NumSegments())
+	}
+
+	report = FileRecoveryReport{DxPath: desc.DxPath, Segments: len(desc.SectorRoots)}
+	for i, roots := range desc.SectorRoots {
+		if len(roots) != int(desc.NumSectors) {
+			return report, fmt.Errorf("segment %v has %v sectors, expect %v", i, len(roots), desc.NumSectors)
+		}
+		var found uint32
+		for j, root := range roots {
+			if root == (common.Hash{}) {
+				continue
+			}
+			report.Sectors++
+			hostID, exists := rootHosts[root]
+			if !exists {
+				continue
+			}
+			if err = entry.AddSector(hostID, root, i, j); err != nil {
+				return report, err
+			}
+			found++
+		}
+		report.SectorsFound += int(found)
+		if found >= desc.MinSectors {
+			report.RecoverableSegments++
+		} else {
+			report.UnrecoverableSegments = append(report.UnrecoverableSegments, i)
+		}
+	}
+	if report.RecoverableSegments == 0 {
+		return report, errNoSectorsFound
+	}
+	recovered = true
+
+	// update the health of the directories, and hand the file to the repair loop
+	go client.fileSystem.InitAndUpdateDirMetadata(dxPath)
+	select {
+	case client.fileSystem.RepairNeededChan() <- struct{}{}:
+	default:
+	}
+	return report, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestStorageClient_RecoverFile(t *testing.T) {
+	storage.ENV = storage.EnvTest
+
+	sct := newStorageClientTester(t)
+	sc := sct.Client
+	defer sc.Close()
+
+	entry := newFileEntry(t, sc)
+	localPath := string(entry.LocalPath())
+	dxPath := entry.DxPath()
+	defer os.Remove(localPath)
+
+	// the first sector of each segment is stored on host1, and the second on host2
+	host1, host2 := enode.RandomID(enode.ID{}, 1), enode.RandomID(enode.ID{}, 2)
+	host1Roots := make(map[common.Hash]enode.ID)
+	for i := 0; i < entry.NumSegments(); i++ {
+		for j, hostID := range []enode.ID{host1, host2} {
+			root := common.BytesToHash([]byte{1, byte(i), byte(j)})
+			if err := entry.AddSector(hostID, root, i, j); err != nil {
+				t.Fatal(err)
+			}
+			if hostID == host1 {
+				host1Roots[root] = hostID
+			}
+		}
+	}
+	numSegments := entry.NumSegments()
+	if err := entry.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	descPath := filepath.Join(homeDir(), "descriptor.json")
+	defer os.Remove(descPath)
+	if err := sc.ExportFileDescriptor(dxPath, descPath); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(descPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var desc FileDescriptor
+	if err = json.Unmarshal(b, &desc); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file still exists
+	if _, err = sc.recoverFile(desc, host1Roots); err == nil {
+		t.Fatalf("recovering the existing file should fail")
+	}
+
+	if err = sc.fileSystem.DeleteDxFile(dxPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// no sector found under the contracts, and the file is not created
+	if _, err = sc.recoverFile(desc, make(map[common.Hash]enode.ID)); err != errNoSectorsFound {
+		t.Fatalf("expect error %v, got %v", errNoSectorsFound, err)
+	}
+	if _, err = sc.fileSystem.OpenDxFile(dxPath); err == nil {
+		t.Fatalf("the file not recovered should not be created")
+	}
+
+	// only the sectors on host1 are found, which are enough for the erasure code 1/2
+	report, err := sc.recoverFile(desc, host1Roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Segments != numSegments || report.RecoverableSegments != numSegments {
+		t.Errorf("expect %v segments recovered, got %v/%v", numSegments, report.RecoverableSegments, report.Segments)
+	}
+	if report.Sectors != 2*numSegments || report.SectorsFound != numSegments {
+		t.Errorf("expect %v/%v sectors found, got %v/%v", numSegments, 2*numSegments, report.SectorsFound, report.Sectors)
+	}
+
+	recovered, err := sc.fileSystem.OpenDxFile(dxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recovered.Close()
+	if recovered.FileSize() != desc.FileSize {
+		t.Errorf("expect file size %v, got %v", desc.FileSize, recovered.FileSize())
+	}
+	for i := 0; i < numSegments; i++ {
+		sectors, err := recovered.Sectors(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sectors[0]) != 1 || sectors[0][0].HostID != host1 || sectors[0][0].MerkleRoot != desc.SectorRoots[i][0] {
+			t.Errorf("segment %v: unexpected sector %+v", i, sectors[0])
+		}
+		if len(sectors[1]) != 0 {
+			t.Errorf("segment %v: the sector not found should not be added", i)
+		}
+	}
+}
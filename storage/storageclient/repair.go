@@ -6,12 +6,14 @@ package storageclient
 
 import (
 	"fmt"
+	"github.com/DxChainNetwork/godx/common/alert"
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
 	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxdir"
 	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxfile"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -137,7 +139,7 @@ func (client *StorageClient) stuckLoop() {
 
 		// Call bubble once all segments have been popped off heap
 		if err := client.fileSystem.InitAndUpdateDirMetadata(dir.DxPath()); err != nil {
-			client.log.Error("[stuck loop]update dir meta data failed", "error", err)
+			alert.Raise(client.log, alert.SeverityError, alertModule, "failed to update the directory metadata in the stuck loop", "error", err)
 		}
 
 		// Sleep until it is time to try and repair another stuck Segment
@@ -197,8 +199,47 @@ func (client *StorageClient) healthCheckLoop() {
 			return
 		case <-healthCheckSignal:
 			if err := client.fileSystem.InitAndUpdateDirMetadata(dxPath); err != nil {
-				client.log.Error("[health check loop]update dir meta data failed", "error", err)
+				alert.Raise(client.log, alert.SeverityError, alertModule, "failed to update the directory metadata in the health check loop", "error", err)
+			}
+		case <-client.contractManager.StaleContractFoundChan():
+			// the stale contracts are canceled, check the health of all files at once so
+			// that the data stored with them is migrated without waiting for the health check
+			if err := client.updateAllDirMetadata(); err != nil {
+				alert.Raise(client.log, alert.SeverityError, alertModule, "failed to update the directory metadata for the stale contracts", "error", err)
 			}
 		}
 	}
 }
+
+// updateAllDirMetadata updates the metadata of all directories, from the deepest ones to the
+// root directory, which recalculates the health of all files
+func (client *StorageClient) updateAllDirMetadata() error {
+	root := string(client.fileSystem.RootDir())
+	var dxPaths []storage.DxPath
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		dxPath := storage.RootDxPath()
+		if rel != "." {
+			if dxPath, err = storage.NewDxPath(filepath.ToSlash(rel)); err != nil {
+				return err
+			}
+		}
+		dxPaths = append(dxPaths, dxPath)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dxPaths) - 1; i >= 0; i-- {
+		if err = client.fileSystem.InitAndUpdateDirMetadata(dxPaths[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
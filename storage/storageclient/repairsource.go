@@ -0,0 +1,124 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"crypto/sha256"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxfile"
+)
+
+// sourceVerification is the cached result of verifying a local source file against
+// the checksum recorded in the dxfile
+type sourceVerification struct {
+	size    int64
+	modTime time.Time
+	valid   bool
+}
+
+// sourceVerifier verifies whether the local source files are still the same as the
+// uploaded ones, so that they can be used for repairing. Since computing the checksum
+// requires reading the whole file, the result is cached until the file is modified
+type sourceVerifier struct {
+	verified map[dxfile.FileID]sourceVerification
+	lock     sync.Mutex
+}
+
+// newSourceVerifier initializes the sourceVerifier object
+func newSourceVerifier() *sourceVerifier {
+	return &sourceVerifier{
+		verified: make(map[dxfile.FileID]sourceVerification),
+	}
+}
+
+// verify checks whether the local file of the dxfile still has the same content as it
+// was uploaded. If no checksum was recorded for the dxfile, the local file is trusted
+func (sv *sourceVerifier) verify(df *dxfile.DxFile) bool {
+	checksum := df.SourceChecksum()
+	if checksum == (common.Hash{}) {
+		return true
+	}
+
+	info, err := os.Stat(string(df.LocalPath()))
+	if err != nil || uint64(info.Size()) != df.FileSize() {
+		return false
+	}
+
+	// check the cached result first
+	id := df.UID()
+	sv.lock.Lock()
+	cached, exists := sv.verified[id]
+	sv.lock.Unlock()
+	if exists && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.valid
+	}
+
+	localChecksum, err := sourceChecksum(string(df.LocalPath()))
+	if err != nil {
+		return false
+	}
+
+	sv.lock.Lock()
+	sv.verified[id] = sourceVerification{
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		valid:   localChecksum == checksum,
+	}
+	sv.lock.Unlock()
+	return localChecksum == checksum
+}
+
+// recordSourceChecksum calculates the checksum of the source file of the uploaded dxfile in
+// background, since the whole file is read. The checksum is not recorded if the source file
+// is modified during the calculation. The entry is closed when done
+func (client *StorageClient) recordSourceChecksum(entry *dxfile.FileSetEntryWithID, source string) {
+	go func() {
+		defer entry.Close()
+		if err := client.tm.Add(); err != nil {
+			return
+		}
+		defer client.tm.Done()
+
+		before, err := os.Stat(source)
+		if err != nil {
+			client.log.Warn("failed to stat the source file", "path", source, "err", err)
+			return
+		}
+		checksum, err := sourceChecksum(source)
+		if err != nil {
+			client.log.Warn("failed to calculate the checksum of the source file", "path", source, "err", err)
+			return
+		}
+		after, err := os.Stat(source)
+		if err != nil || after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) || uint64(after.Size()) != entry.FileSize() {
+			client.log.Warn("the source file is modified since uploaded, checksum not recorded", "path", source)
+			return
+		}
+		if err = entry.SetSourceChecksum(checksum); err != nil {
+			client.log.Warn("failed to record the source checksum", "path", source, "err", err)
+		}
+	}()
+}
+
+// sourceChecksum calculates the checksum of the file content
+func sourceChecksum(path string) (checksum common.Hash, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return
+	}
+	copy(checksum[:], h.Sum(nil))
+	return
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestSourceVerifier_Verify(t *testing.T) {
+	storage.ENV = storage.EnvTest
+
+	sct := newStorageClientTester(t)
+	sc := sct.Client
+	defer sc.Close()
+
+	entry := newFileEntry(t, sc)
+	localPath := string(entry.LocalPath())
+	defer func() {
+		_ = os.Remove(localPath)
+		_ = os.Remove(entry.FilePath())
+		_ = entry.Close()
+	}()
+
+	// without the checksum recorded, the local file is trusted
+	if !sc.sourceVerifier.verify(entry.DxFile) {
+		t.Fatalf("local file without checksum should be trusted")
+	}
+
+	checksum, err := sourceChecksum(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entry.SetSourceChecksum(checksum); err != nil {
+		t.Fatal(err)
+	}
+	if !sc.sourceVerifier.verify(entry.DxFile) {
+		t.Fatalf("unchanged local file should pass the verification")
+	}
+
+	// modify the local file content with the same size
+	f, err := os.OpenFile(localPath, os.O_RDWR, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1)
+	if _, err = f.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	b[0]++
+	if _, err = f.WriteAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	future := time.Now().Add(time.Hour)
+	if err = os.Chtimes(localPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if sc.sourceVerifier.verify(entry.DxFile) {
+		t.Fatalf("modified local file should not pass the verification")
+	}
+
+	// removed local file
+	if err = os.Remove(localPath); err != nil {
+		t.Fatal(err)
+	}
+	if sc.sourceVerifier.verify(entry.DxFile) {
+		t.Fatalf("removed local file should not pass the verification")
+	}
+}
+
+func TestRecordSourceChecksum(t *testing.T) {
+	storage.ENV = storage.EnvTest
+
+	sct := newStorageClientTester(t)
+	sc := sct.Client
+	defer sc.Close()
+
+	entry := newFileEntry(t, sc)
+	localPath := string(entry.LocalPath())
+	defer func() {
+		_ = os.Remove(localPath)
+		_ = os.Remove(entry.FilePath())
+		_ = entry.Close()
+	}()
+
+	expect, err := sourceChecksum(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.recordSourceChecksum(entry.CopyEntry(), localPath)
+
+	// the checksum is recorded in background
+	deadline := time.Now().Add(5 * time.Second)
+	for entry.SourceChecksum() != expect {
+		if time.Now().After(deadline) {
+			t.Fatalf("the source checksum not recorded: %x != %x", entry.SourceChecksum(), expect)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3SignAlgorithm     = "AWS4-HMAC-SHA256"
+	s3TimeFormat        = "20060102T150405Z"
+	s3UnsignedPayload   = "UNSIGNED-PAYLOAD"
+	s3StreamingPayload  = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	s3AccessKeyIDLength = 20
+)
+
+var errS3ContentSHA256Mismatch = errors.New("the sha256 of the content does not match x-amz-content-sha256")
+
+type (
+	// S3Credentials is the access key of the S3 gateway, which is used to sign the requests
+	// with the AWS signature version 4
+	S3Credentials struct {
+		AccessKeyID     string `json:"accessKeyId"`
+		SecretAccessKey string `json:"secretAccessKey"`
+	}
+
+	// s3AuthError is the S3 error responded when the request is not authenticated
+	s3AuthError struct {
+		code    int
+		errCode string
+		message string
+	}
+
+	// s3Signature is the signature of the request parsed from either the authorization
+	// header or the query of the presigned URL
+	s3Signature struct {
+		accessKeyID   string
+		scope         string
+		date          time.Time
+		expires       time.Duration
+		signedHeaders []string
+		signature     string
+		payloadHash   string
+		presigned     bool
+	}
+
+	// s3PayloadVerifier verifies the sha256 of the request body signed in the request
+	// once the body is read to the end
+	s3PayloadVerifier struct {
+		io.ReadCloser
+		hash     hash.Hash
+		expected []byte
+	}
+)
+
+func (e *s3AuthError) Error() string {
+	return e.message
+}
+
+// loadS3Credentials loads the access key of the S3 gateway from the file. A random access
+// key is generated and saved if the file does not exist
+func loadS3Credentials(path string) (S3Credentials, error) {
+	var creds S3Credentials
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		if err = json.Unmarshal(data, &creds); err != nil {
+			return S3Credentials{}, err
+		}
+		if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+			return S3Credentials{}, errors.New("the access key is empty")
+		}
+		return creds, nil
+	}
+	if !os.IsNotExist(err) {
+		return S3Credentials{}, err
+	}
+
+	b := make([]byte, 40)
+	if _, err = rand.Read(b); err != nil {
+		return S3Credentials{}, err
+	}
+	creds = S3Credentials{
+		AccessKeyID:     strings.ToUpper(hex.EncodeToString(b[:s3AccessKeyIDLength/2])),
+		SecretAccessKey: hex.EncodeToString(b[s3AccessKeyIDLength/2:]),
+	}
+	if data, err = json.MarshalIndent(creds, "", "  "); err != nil {
+		return S3Credentials{}, err
+	}
+	if err = ioutil.WriteFile(path, data, 0600); err != nil {
+		return S3Credentials{}, err
+	}
+	return creds, nil
+}
+
+// verifyS3Signature verifies the AWS signature version 4 of the request, signed either in the
+// authorization header or in the query of the presigned URL. When the sha256 of the request
+// body is signed, the body is replaced by the reader verifying the sha256 at the end
+func verifyS3Signature(r *http.Request, creds S3Credentials, now time.Time) error {
+	sig, err := parseS3Signature(r)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(sig.accessKeyID), []byte(creds.AccessKeyID)) != 1 {
+		return &s3AuthError{http.StatusForbidden, "InvalidAccessKeyId", "the access key id does not exist"}
+	}
+
+	// the scope is date/region/service/aws4_request, the region is not checked
+	scope := strings.Split(sig.scope, "/")
+	if len(scope) != 4 || scope[0] != sig.date.Format("20060102") || scope[2] != "s3" || scope[3] != "aws4_request" {
+		return &s3AuthError{http.StatusBadRequest, "AuthorizationQueryParametersError", "the credential scope is not valid"}
+	}
+	if sig.presigned {
+		if now.Before(sig.date.Add(-s3MaxTimeSkew)) || now.After(sig.date.Add(sig.expires)) {
+			return &s3AuthError{http.StatusForbidden, "AccessDenied", "the presigned URL has expired"}
+		}
+	} else if now.Sub(sig.date) > s3MaxTimeSkew || sig.date.Sub(now) > s3MaxTimeSkew {
+		return &s3AuthError{http.StatusForbidden, "RequestTimeTooSkewed", "the difference between the request time and the local time is too large"}
+	}
+	if sig.payloadHash == s3StreamingPayload {
+		return &s3AuthError{http.StatusNotImplemented, "NotImplemented", "the chunked payload signing is not supported"}
+	}
+
+	stringToSign := strings.Join([]string{
+		s3SignAlgorithm,
+		sig.date.Format(s3TimeFormat),
+		sig.scope,
+		hexSHA256([]byte(s3CanonicalRequest(r, sig))),
+	}, "\n")
+	key := s3HMAC([]byte("AWS4"+creds.SecretAccessKey), []byte(scope[0]))
+	for _, s := range scope[1:] {
+		key = s3HMAC(key, []byte(s))
+	}
+	expected := hex.EncodeToString(s3HMAC(key, []byte(stringToSign)))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(sig.signature)) != 1 {
+		return &s3AuthError{http.StatusForbidden, "SignatureDoesNotMatch", "the request signature does not match"}
+	}
+
+	if sig.payloadHash != s3UnsignedPayload {
+		expected, err := hex.DecodeString(sig.payloadHash)
+		if err != nil || len(expected) != sha256.Size {
+			return &s3AuthError{http.StatusBadRequest, "InvalidArgument", "x-amz-content-sha256 is not valid"}
+		}
+		r.Body = &s3PayloadVerifier{ReadCloser: r.Body, hash: sha256.New(), expected: expected}
+	}
+	return nil
+}
+
+// parseS3Signature parses the signature from the authorization header, or from the query
+// if the request is presigned
+func parseS3Signature(r *http.Request) (s3Signature, error) {
+	var sig s3Signature
+	var credential, signedHeaders, date string
+	query := r.URL.Query()
+
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if !strings.HasPrefix(auth, s3SignAlgorithm+" ") {
+			return sig, &s3AuthError{http.StatusBadRequest, "InvalidArgument", "only " + s3SignAlgorithm + " is supported"}
+		}
+		for _, field := range strings.Split(strings.TrimPrefix(auth, s3SignAlgorithm+" "), ",") {
+			kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "Credential":
+				credential = kv[1]
+			case "SignedHeaders":
+				signedHeaders = kv[1]
+			case "Signature":
+				sig.signature = kv[1]
+			}
+		}
+		date = r.Header.Get("X-Amz-Date")
+		sig.payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+		if sig.payloadHash == "" {
+			return sig, &s3AuthError{http.StatusBadRequest, "InvalidRequest", "x-amz-content-sha256 is missing"}
+		}
+	} else if query.Get("X-Amz-Algorithm") != "" {
+		if query.Get("X-Amz-Algorithm") != s3SignAlgorithm {
+			return sig, &s3AuthError{http.StatusBadRequest, "InvalidArgument", "only " + s3SignAlgorithm + " is supported"}
+		}
+		credential, signedHeaders, date = query.Get("X-Amz-Credential"), query.Get("X-Amz-SignedHeaders"), query.Get("X-Amz-Date")
+		sig.signature, sig.payloadHash, sig.presigned = query.Get("X-Amz-Signature"), s3UnsignedPayload, true
+		expires, err := strconv.ParseInt(query.Get("X-Amz-Expires"), 10, 64)
+		if err != nil || expires <= 0 || time.Duration(expires)*time.Second > s3MaxPresignExpiry {
+			return sig, &s3AuthError{http.StatusBadRequest, "AuthorizationQueryParametersError", "X-Amz-Expires is not valid"}
+		}
+		sig.expires = time.Duration(expires) * time.Second
+	} else {
+		return sig, &s3AuthError{http.StatusForbidden, "AccessDenied", "the request is not signed"}
+	}
+
+	i := strings.Index(credential, "/")
+	if i <= 0 || signedHeaders == "" || sig.signature == "" {
+		return sig, &s3AuthError{http.StatusBadRequest, "AuthorizationHeaderMalformed", "the credential, the signed headers, or the signature is missing"}
+	}
+	sig.accessKeyID, sig.scope = credential[:i], credential[i+1:]
+	sig.signedHeaders = strings.Split(signedHeaders, ";")
+	var err error
+	if sig.date, err = time.Parse(s3TimeFormat, date); err != nil {
+		return sig, &s3AuthError{http.StatusBadRequest, "AuthorizationHeaderMalformed", "the request date is not valid"}
+	}
+	var signsHost bool
+	for _, h := range sig.signedHeaders {
+		signsHost = signsHost || h == "host"
+	}
+	if !signsHost {
+		return sig, &s3AuthError{http.StatusBadRequest, "AuthorizationHeaderMalformed", "the host header is not signed"}
+	}
+	return sig, nil
+}
+
+// s3CanonicalRequest returns the canonical form of the request signed
+func s3CanonicalRequest(r *http.Request, sig s3Signature) string {
+	// the query parameters are sorted by the key then the value, the signature is excluded
+	query := r.URL.Query()
+	var params []string
+	for key, values := range query {
+		if sig.presigned && key == "X-Amz-Signature" {
+			continue
+		}
+		for _, value := range values {
+			params = append(params, s3URIEncode(key, true)+"="+s3URIEncode(value, true))
+		}
+	}
+	sort.Strings(params)
+
+	var headers bytes.Buffer
+	for _, name := range sig.signedHeaders {
+		raw := r.Header[http.CanonicalHeaderKey(name)]
+		if name == "host" {
+			raw = []string{r.Host}
+		}
+		values := make([]string, 0, len(raw))
+		for _, value := range raw {
+			values = append(values, strings.Join(strings.Fields(value), " "))
+		}
+		headers.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		s3URIEncode(r.URL.Path, false),
+		strings.Join(params, "&"),
+		headers.String(),
+		strings.Join(sig.signedHeaders, ";"),
+		sig.payloadHash,
+	}, "\n")
+}
+
+// s3URIEncode encodes the string as required by the AWS signature, all characters except
+// the unreserved ones are percent encoded. The slashes are kept when encoding the path
+func s3URIEncode(s string, encodeSlash bool) string {
+	var buf bytes.Buffer
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			buf.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			buf.WriteByte(b)
+		default:
+			buf.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{b})))
+		}
+	}
+	return buf.String()
+}
+
+// Read reads the request body, and verifies the sha256 at the end of the body
+func (pv *s3PayloadVerifier) Read(p []byte) (int, error) {
+	n, err := pv.ReadCloser.Read(p)
+	pv.hash.Write(p[:n])
+	if err == io.EOF && !hmac.Equal(pv.hash.Sum(nil), pv.expected) {
+		return n, errS3ContentSHA256Mismatch
+	}
+	return n, err
+}
+
+// s3HMAC returns the HMAC-SHA256 of the data
+func s3HMAC(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// hexSHA256 returns the hex encoded sha256 of the data
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
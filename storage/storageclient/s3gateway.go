@@ -0,0 +1,729 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
+)
+
+// s3Namespace is the xml namespace of the S3 responses
+const s3Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// bucketNameRegexp matches the valid bucket names, following the S3 naming rules
+var bucketNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+type (
+	s3Error struct {
+		XMLName  xml.Name `xml:"Error"`
+		Code     string   `xml:"Code"`
+		Message  string   `xml:"Message"`
+		Resource string   `xml:"Resource"`
+	}
+
+	s3Owner struct {
+		ID          string `xml:"ID"`
+		DisplayName string `xml:"DisplayName"`
+	}
+
+	s3Bucket struct {
+		Name         string `xml:"Name"`
+		CreationDate string `xml:"CreationDate"`
+	}
+
+	s3ListBucketsResult struct {
+		XMLName xml.Name   `xml:"ListAllMyBucketsResult"`
+		Xmlns   string     `xml:"xmlns,attr"`
+		Owner   s3Owner    `xml:"Owner"`
+		Buckets []s3Bucket `xml:"Buckets>Bucket"`
+	}
+
+	s3Object struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		Size         uint64 `xml:"Size"`
+		StorageClass string `xml:"StorageClass"`
+	}
+
+	s3CommonPrefix struct {
+		Prefix string `xml:"Prefix"`
+	}
+
+	s3ListObjectsResult struct {
+		XMLName        xml.Name         `xml:"ListBucketResult"`
+		Xmlns          string           `xml:"xmlns,attr"`
+		Name           string           `xml:"Name"`
+		Prefix         string           `xml:"Prefix"`
+		Delimiter      string           `xml:"Delimiter,omitempty"`
+		MaxKeys        int              `xml:"MaxKeys"`
+		IsTruncated    bool             `xml:"IsTruncated"`
+		Contents       []s3Object       `xml:"Contents"`
+		CommonPrefixes []s3CommonPrefix `xml:"CommonPrefixes"`
+
+		// list objects v1
+		Marker     string `xml:"Marker,omitempty"`
+		NextMarker string `xml:"NextMarker,omitempty"`
+
+		// list objects v2
+		KeyCount              int    `xml:"KeyCount,omitempty"`
+		StartAfter            string `xml:"StartAfter,omitempty"`
+		ContinuationToken     string `xml:"ContinuationToken,omitempty"`
+		NextContinuationToken string `xml:"NextContinuationToken,omitempty"`
+	}
+
+	s3InitiateMultipartUploadResult struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Xmlns    string   `xml:"xmlns,attr"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		UploadID string   `xml:"UploadId"`
+	}
+
+	s3CompletedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+
+	s3CompleteMultipartUpload struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}
+
+	s3CompleteMultipartUploadResult struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		Bucket  string   `xml:"Bucket"`
+		Key     string   `xml:"Key"`
+		ETag    string   `xml:"ETag"`
+	}
+)
+
+// multipartUpload is a multipart upload in progress, the parts are saved in dir
+type multipartUpload struct {
+	bucket string
+	key    string
+	dir    string
+
+	// md5 of the parts uploaded, keyed by the part number
+	parts map[int][]byte
+}
+
+// s3Gateway is the S3 compatible object gateway of the storage client. Buckets are the
+// dxdirs directly under the root, and the object keys are the dxpaths within the bucket.
+// Objects are streamed through the staging and download of the JSON/HTTP gateway. The
+// requests must be signed with the access key saved in the persist directory, using the
+// AWS signature version 4 either in the authorization header or in the presigned URL
+type s3Gateway struct {
+	*gateway
+
+	creds        S3Credentials
+	multipartDir string
+	lock         sync.Mutex
+	uploads      map[string]*multipartUpload
+}
+
+// StartS3Gateway starts the S3 compatible object gateway listening on the address. The access
+// key is loaded from the credentials file in the persist directory, and generated if not exists
+func (client *StorageClient) StartS3Gateway(addr string) error {
+	credsPath := filepath.Join(client.persistDir, s3CredentialsFile)
+	creds, err := loadS3Credentials(credsPath)
+	if err != nil {
+		return fmt.Errorf("failed to load the S3 gateway credentials: %s", err.Error())
+	}
+	gw := newS3Gateway(client, creds, filepath.Join(client.persistDir, gatewayStagingDir), filepath.Join(client.persistDir, s3MultipartDir))
+	for _, dir := range []string{gw.stagingDir, gw.multipartDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create the S3 gateway directory: %s", err.Error())
+		}
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %s", addr, err.Error())
+	}
+	gw.listener = listener
+	gw.server = &http.Server{Handler: gw}
+
+	go func() {
+		if err := gw.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			client.log.Warn("storage client S3 gateway stopped", "err", err)
+		}
+	}()
+	client.tm.OnStop(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), gatewayShutdownTimeout)
+		defer cancel()
+		return gw.server.Shutdown(ctx)
+	})
+
+	client.log.Info("Storage client S3 gateway started", "addr", listener.Addr(), "credentials", credsPath)
+	return nil
+}
+
+// newS3Gateway creates the S3 gateway verifying the requests signed with the credentials,
+// staging the objects in stagingDir, and the parts of the multipart uploads in multipartDir
+func newS3Gateway(client *StorageClient, creds S3Credentials, stagingDir, multipartDir string) *s3Gateway {
+	return &s3Gateway{
+		gateway: &gateway{
+			client:     client,
+			files:      filesystem.NewPublicFileSystemAPI(client.fileSystem),
+			stagingDir: stagingDir,
+		},
+		creds:        creds,
+		multipartDir: multipartDir,
+		uploads:      make(map[string]*multipartUpload),
+	}
+}
+
+// ServeHTTP verifies the signature of the request, and routes the request by the bucket,
+// the key, and the query
+func (gw *s3Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifyS3Signature(r, gw.creds, time.Now()); err != nil {
+		authErr, ok := err.(*s3AuthError)
+		if !ok {
+			authErr = &s3AuthError{http.StatusForbidden, "AccessDenied", err.Error()}
+		}
+		writeS3Error(w, r, authErr.code, authErr.errCode, authErr.message)
+		return
+	}
+
+	bucket, key := r.URL.Path, ""
+	bucket = strings.TrimPrefix(bucket, "/")
+	if i := strings.Index(bucket, "/"); i >= 0 {
+		bucket, key = bucket[:i], bucket[i+1:]
+	}
+	query := r.URL.Query()
+
+	switch {
+	case bucket == "" && r.Method == http.MethodGet:
+		gw.listBuckets(w, r)
+	case bucket == "":
+		writeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+	case !bucketNameRegexp.MatchString(bucket):
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidBucketName", "the bucket name is not valid")
+	case key == "":
+		gw.serveBucket(w, r, bucket)
+	case r.Method == http.MethodPost && hasQuery(query, "uploads"):
+		gw.initiateMultipartUpload(w, r, bucket, key)
+	case r.Method == http.MethodPost && hasQuery(query, "uploadId"):
+		gw.completeMultipartUpload(w, r, bucket, key, query.Get("uploadId"))
+	case r.Method == http.MethodPut && hasQuery(query, "uploadId"):
+		gw.uploadPart(w, r, query.Get("uploadId"), query.Get("partNumber"))
+	case r.Method == http.MethodDelete && hasQuery(query, "uploadId"):
+		gw.abortMultipartUpload(w, r, query.Get("uploadId"))
+	default:
+		gw.serveObject(w, r, bucket, key)
+	}
+}
+
+// serveBucket handles the bucket operations
+func (gw *s3Gateway) serveBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	switch r.Method {
+	case http.MethodPut:
+		dxPath, err := storage.NewDxPath(bucket)
+		if err != nil {
+			writeS3Error(w, r, http.StatusBadRequest, "InvalidBucketName", err.Error())
+			return
+		}
+		if gw.bucketExists(bucket) {
+			writeS3Error(w, r, http.StatusConflict, "BucketAlreadyOwnedByYou", "the bucket already exists")
+			return
+		}
+		dir, err := gw.client.fileSystem.NewDxDir(dxPath)
+		if err != nil {
+			writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		dir.Close()
+		w.Header().Set("Location", "/"+bucket)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodHead:
+		if !gw.bucketExists(bucket) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		if !gw.bucketExists(bucket) {
+			writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket", "the bucket does not exist")
+			return
+		}
+		gw.listObjects(w, r, bucket)
+	case http.MethodDelete:
+		writeS3Error(w, r, http.StatusNotImplemented, "NotImplemented", "deleting buckets is not supported")
+	default:
+		writeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+	}
+}
+
+// serveObject handles the object operations
+func (gw *s3Gateway) serveObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if !gw.bucketExists(bucket) {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket", "the bucket does not exist")
+		return
+	}
+	dxPath, err := objectDxPath(bucket, key)
+	if err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		gw.putObject(w, r, dxPath)
+	case http.MethodGet, http.MethodHead:
+		gw.getObject(w, r, dxPath)
+	case http.MethodDelete:
+		// deleting an object not exist is not an error in S3
+		var source string
+		if entry, err := gw.client.fileSystem.OpenDxFile(dxPath); err == nil {
+			source = string(entry.LocalPath())
+			entry.Close()
+		}
+		if err = gw.client.DeleteFile(dxPath); err != nil {
+			writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		gw.removeStaged(source)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+	}
+}
+
+// listBuckets responds the dxdirs directly under the root as buckets
+func (gw *s3Gateway) listBuckets(w http.ResponseWriter, r *http.Request) {
+	infos, err := ioutil.ReadDir(string(gw.client.fileSystem.RootDir()))
+	if err != nil && !os.IsNotExist(err) {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	result := s3ListBucketsResult{Xmlns: s3Namespace, Owner: s3Owner{ID: "dxchain", DisplayName: "dxchain"}}
+	for _, info := range infos {
+		if !info.IsDir() || !bucketNameRegexp.MatchString(info.Name()) {
+			continue
+		}
+		result.Buckets = append(result.Buckets, s3Bucket{Name: info.Name(), CreationDate: s3Time(info.ModTime())})
+	}
+	writeS3XML(w, http.StatusOK, result)
+}
+
+// listObjects responds the objects in the bucket, both list objects v1 and v2 are supported
+func (gw *s3Gateway) listObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	result := s3ListObjectsResult{
+		Xmlns:     s3Namespace,
+		Name:      bucket,
+		Prefix:    query.Get("prefix"),
+		Delimiter: query.Get("delimiter"),
+		MaxKeys:   s3MaxKeys,
+	}
+	if maxKeys := query.Get("max-keys"); maxKeys != "" {
+		n, err := strconv.Atoi(maxKeys)
+		if err != nil || n < 0 {
+			writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", "max-keys is not valid")
+			return
+		}
+		if n < result.MaxKeys {
+			result.MaxKeys = n
+		}
+	}
+	v2 := query.Get("list-type") == "2"
+	after := query.Get("marker")
+	if v2 {
+		result.StartAfter, result.ContinuationToken = query.Get("start-after"), query.Get("continuation-token")
+		after = result.StartAfter
+		if result.ContinuationToken != "" {
+			after = result.ContinuationToken
+		}
+	} else {
+		result.Marker = after
+	}
+
+	var keys []string
+	for _, file := range gw.files.FileList() {
+		if key := strings.TrimPrefix(file.Path, bucket+"/"); key != file.Path {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	prefixes := make(map[string]struct{})
+	var last string
+	for _, key := range keys {
+		if !strings.HasPrefix(key, result.Prefix) || key <= after {
+			continue
+		}
+		// the keys rolled up in the common prefix returned in the previous page
+		if result.Delimiter != "" && strings.HasSuffix(after, result.Delimiter) && strings.HasPrefix(key, after) {
+			continue
+		}
+		commonPrefix := ""
+		if result.Delimiter != "" {
+			if i := strings.Index(key[len(result.Prefix):], result.Delimiter); i >= 0 {
+				commonPrefix = key[:len(result.Prefix)+i+len(result.Delimiter)]
+			}
+		}
+		if _, exists := prefixes[commonPrefix]; exists && commonPrefix != "" {
+			continue
+		}
+		if len(result.Contents)+len(result.CommonPrefixes) >= result.MaxKeys {
+			result.IsTruncated = true
+			break
+		}
+
+		if commonPrefix != "" {
+			prefixes[commonPrefix] = struct{}{}
+			result.CommonPrefixes = append(result.CommonPrefixes, s3CommonPrefix{Prefix: commonPrefix})
+			last = commonPrefix
+			continue
+		}
+		object := s3Object{Key: key, StorageClass: "STANDARD"}
+		if size, modTime, err := gw.objectInfo(bucket, key); err == nil {
+			object.Size, object.LastModified = size, s3Time(modTime)
+		}
+		result.Contents = append(result.Contents, object)
+		last = key
+	}
+
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+	if result.IsTruncated {
+		if v2 {
+			result.NextContinuationToken = last
+		} else {
+			result.NextMarker = last
+		}
+	}
+	writeS3XML(w, http.StatusOK, result)
+}
+
+// putObject streams the request body into the staged file and uploads it. The existing
+// object is replaced
+func (gw *s3Gateway) putObject(w http.ResponseWriter, r *http.Request, dxPath storage.DxPath) {
+	hash := md5.New()
+	tmp, _, err := gw.receive(io.TeeReader(r.Body, hash))
+	if err == errS3ContentSHA256Mismatch {
+		writeS3Error(w, r, http.StatusBadRequest, "XAmzContentSHA256Mismatch", err.Error())
+		return
+	}
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", fmt.Sprintf("failed to receive the object: %s", err.Error()))
+		return
+	}
+	if err := gw.replace(tmp, dxPath); err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, hash.Sum(nil)))
+	w.WriteHeader(http.StatusOK)
+}
+
+// getObject downloads the object and streams it back. Only the metadata is responded for
+// the HEAD requests
+func (gw *s3Gateway) getObject(w http.ResponseWriter, r *http.Request, dxPath storage.DxPath) {
+	entry, err := gw.client.fileSystem.OpenDxFile(dxPath)
+	if err != nil {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchKey", "the object does not exist")
+		return
+	}
+	size, modTime := entry.FileSize(), entry.TimeModify()
+	entry.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Length", strconv.FormatUint(size, 10))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if code, err := gw.streamFile(w, r, dxPath, size, modTime); err != nil {
+		errCode := "InternalError"
+		if code == http.StatusRequestedRangeNotSatisfiable {
+			errCode = "InvalidRange"
+		}
+		writeS3Error(w, r, code, errCode, err.Error())
+	}
+}
+
+// initiateMultipartUpload starts a multipart upload of the object
+func (gw *s3Gateway) initiateMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if !gw.bucketExists(bucket) {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket", "the bucket does not exist")
+		return
+	}
+	if _, err := objectDxPath(bucket, key); err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	upload := &multipartUpload{
+		bucket: bucket,
+		key:    key,
+		dir:    filepath.Join(gw.multipartDir, hex.EncodeToString(id)),
+		parts:  make(map[int][]byte),
+	}
+	if err := os.MkdirAll(upload.dir, 0700); err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	gw.lock.Lock()
+	gw.uploads[hex.EncodeToString(id)] = upload
+	gw.lock.Unlock()
+
+	writeS3XML(w, http.StatusOK, s3InitiateMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Bucket:   bucket,
+		Key:      key,
+		UploadID: hex.EncodeToString(id),
+	})
+}
+
+// uploadPart saves the request body as a part of the multipart upload
+func (gw *s3Gateway) uploadPart(w http.ResponseWriter, r *http.Request, uploadID, partNumber string) {
+	number, err := strconv.Atoi(partNumber)
+	if err != nil || number < 1 || number > s3MaxParts {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", fmt.Sprintf("part number must be within 1 and %v", s3MaxParts))
+		return
+	}
+	upload, exists := gw.multipartUpload(uploadID)
+	if !exists {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchUpload", "the multipart upload does not exist")
+		return
+	}
+
+	tmp, err := ioutil.TempFile(upload.dir, ".part-")
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), r.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), partPath(upload, number))
+	}
+	if err == errS3ContentSHA256Mismatch {
+		os.Remove(tmp.Name())
+		writeS3Error(w, r, http.StatusBadRequest, "XAmzContentSHA256Mismatch", err.Error())
+		return
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", fmt.Sprintf("failed to receive the part: %s", err.Error()))
+		return
+	}
+
+	gw.lock.Lock()
+	upload.parts[number] = hash.Sum(nil)
+	gw.lock.Unlock()
+
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, hash.Sum(nil)))
+	w.WriteHeader(http.StatusOK)
+}
+
+// completeMultipartUpload concatenates the parts listed in the request and uploads the object
+func (gw *s3Gateway) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) {
+	upload, exists := gw.multipartUpload(uploadID)
+	if !exists || upload.bucket != bucket || upload.key != key {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchUpload", "the multipart upload does not exist")
+		return
+	}
+	var complete s3CompleteMultipartUpload
+	if err := xml.NewDecoder(r.Body).Decode(&complete); err != nil || len(complete.Parts) == 0 {
+		writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", "the parts to be completed are not valid")
+		return
+	}
+
+	// the parts must be in ascending order and match the parts uploaded
+	gw.lock.Lock()
+	var readers []io.Reader
+	var sums []byte
+	var partErr error
+	for i, part := range complete.Parts {
+		sum, uploaded := upload.parts[part.PartNumber]
+		if i > 0 && part.PartNumber <= complete.Parts[i-1].PartNumber {
+			partErr = fmt.Errorf("the parts are not in ascending order")
+			break
+		}
+		if !uploaded || strings.Trim(part.ETag, `"`) != hex.EncodeToString(sum) {
+			partErr = fmt.Errorf("part %v is not uploaded", part.PartNumber)
+			break
+		}
+		file, err := os.Open(partPath(upload, part.PartNumber))
+		if err != nil {
+			partErr = err
+			break
+		}
+		defer file.Close()
+		readers = append(readers, file)
+		sums = append(sums, sum...)
+	}
+	gw.lock.Unlock()
+	if partErr != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidPart", partErr.Error())
+		return
+	}
+
+	dxPath, err := objectDxPath(bucket, key)
+	if err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+	tmp, _, err := gw.receive(io.MultiReader(readers...))
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", fmt.Sprintf("failed to assemble the parts: %s", err.Error()))
+		return
+	}
+	if err = gw.replace(tmp, dxPath); err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	gw.removeMultipartUpload(uploadID)
+
+	// the etag of the multipart object is the md5 of the part md5s with the number of parts
+	sum := md5.Sum(sums)
+	writeS3XML(w, http.StatusOK, s3CompleteMultipartUploadResult{
+		Xmlns:  s3Namespace,
+		Bucket: bucket,
+		Key:    key,
+		ETag:   fmt.Sprintf(`"%x-%d"`, sum, len(complete.Parts)),
+	})
+}
+
+// abortMultipartUpload discards the multipart upload along with the parts uploaded
+func (gw *s3Gateway) abortMultipartUpload(w http.ResponseWriter, r *http.Request, uploadID string) {
+	if !gw.removeMultipartUpload(uploadID) {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchUpload", "the multipart upload does not exist")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// replace uploads the received temporary file as the object of dxPath. The temporary file
+// is kept in the staging directory as the local source of the object, and the existing
+// object is replaced only after the upload is tracked, so it is kept if the upload failed
+func (gw *s3Gateway) replace(tmp string, dxPath storage.DxPath) error {
+	replaced, err := gw.client.replaceFile(storage.FileUploadParams{
+		Source: tmp,
+		DxPath: dxPath,
+		Mode:   storage.Override,
+	})
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to upload the object: %s", err.Error())
+	}
+	gw.removeStaged(replaced)
+	return nil
+}
+
+// multipartUpload returns the multipart upload of the id
+func (gw *s3Gateway) multipartUpload(uploadID string) (*multipartUpload, bool) {
+	gw.lock.Lock()
+	defer gw.lock.Unlock()
+	upload, exists := gw.uploads[uploadID]
+	return upload, exists
+}
+
+// removeMultipartUpload removes the multipart upload and the parts uploaded, returns
+// false if the upload does not exist
+func (gw *s3Gateway) removeMultipartUpload(uploadID string) bool {
+	gw.lock.Lock()
+	upload, exists := gw.uploads[uploadID]
+	delete(gw.uploads, uploadID)
+	gw.lock.Unlock()
+	if exists {
+		os.RemoveAll(upload.dir)
+	}
+	return exists
+}
+
+// bucketExists checks whether the dxdir of the bucket exists
+func (gw *s3Gateway) bucketExists(bucket string) bool {
+	info, err := os.Stat(filepath.Join(string(gw.client.fileSystem.RootDir()), bucket))
+	return err == nil && info.IsDir()
+}
+
+// objectInfo returns the size and the last modified time of the object
+func (gw *s3Gateway) objectInfo(bucket, key string) (uint64, time.Time, error) {
+	dxPath, err := objectDxPath(bucket, key)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	entry, err := gw.client.fileSystem.OpenDxFile(dxPath)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer entry.Close()
+	return entry.FileSize(), entry.TimeModify(), nil
+}
+
+// objectDxPath maps the object key in the bucket to the dxpath. Keys which are not the
+// canonical form of the dxpath are rejected, so that the keys listed are the same as put
+func objectDxPath(bucket, key string) (storage.DxPath, error) {
+	dxPath, err := storage.NewDxPath(bucket + "/" + key)
+	if err != nil {
+		return storage.DxPath{}, err
+	}
+	if dxPath.Path != bucket+"/"+key {
+		return storage.DxPath{}, fmt.Errorf("object key %v is not supported", key)
+	}
+	return dxPath, nil
+}
+
+// partPath returns the path of the part file
+func partPath(upload *multipartUpload, number int) string {
+	return filepath.Join(upload.dir, strconv.Itoa(number))
+}
+
+// hasQuery checks whether the query parameter is present, the value might be empty
+func hasQuery(query map[string][]string, key string) bool {
+	_, exists := query[key]
+	return exists
+}
+
+// s3Time formats the time in the format used in the S3 responses
+func s3Time(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// writeS3XML writes the value as xml with the status code to the response
+func writeS3XML(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(v)
+}
+
+// writeS3Error writes the S3 error with the status code to the response
+func writeS3Error(w http.ResponseWriter, r *http.Request, code int, errCode, message string) {
+	writeS3XML(w, code, s3Error{Code: errCode, Message: message, Resource: r.URL.Path})
+}
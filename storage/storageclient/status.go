@@ -0,0 +1,197 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"sort"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
+	"github.com/DxChainNetwork/godx/storage/storageclient/memorymanager"
+)
+
+type (
+	// ClientStatus is the consolidated status of the storage client, which could be used to
+	// render the status page with a single call
+	ClientStatus struct {
+		Allowance    AllowanceSummary  `json:"allowance"`
+		Contracts    ContractCounts    `json:"contracts"`
+		FileHealth   map[string]uint64 `json:"filehealth"`
+		Repair       RepairBacklog     `json:"repair"`
+		Workers      WorkerUtilization `json:"workers"`
+		Resources    ResourceUsage     `json:"resources"`
+		RecentErrors []alert.Alert     `json:"recenterrors"`
+	}
+
+	// ResourceUsage is the usage of the pooled buffers for erasure coding, and the DxFiles
+	// opened by the directory-wide operations
+	ResourceUsage struct {
+		Buffers   memorymanager.BufferPoolStats `json:"buffers"`
+		OpenFiles filesystem.OpenFileStats      `json:"openfiles"`
+	}
+
+	// AllowanceSummary is the rent payment and the fund spent in the current period
+	AllowanceSummary struct {
+		Fund         common.BigInt `json:"fund"`
+		StorageHosts uint64        `json:"storagehosts"`
+		Period       uint64        `json:"period"`
+		RenewWindow  uint64        `json:"renewwindow"`
+		Spent        common.BigInt `json:"spent"`
+		Unspent      common.BigInt `json:"unspent"`
+		Withheld     common.BigInt `json:"withheld"`
+	}
+
+	// ContractCounts is the number of contracts in each state. The active contracts are
+	// counted by their abilities, so that a contract could be counted more than once
+	ContractCounts struct {
+		Active     int `json:"active"`
+		Uploadable int `json:"uploadable"`
+		Renewable  int `json:"renewable"`
+		Canceled   int `json:"canceled"`
+		Expired    int `json:"expired"`
+	}
+
+	// RepairBacklog is the segments waiting to be uploaded or repaired
+	RepairBacklog struct {
+		PendingSegments  int    `json:"pendingsegments"`
+		StuckSegments    int    `json:"stucksegments"`
+		ActiveSegments   int    `json:"activesegments"`
+		NumStuckSegments uint32 `json:"numstucksegments"`
+	}
+
+	// WorkerUtilization is the number of workers busy with or cooling down from the uploads
+	// and downloads
+	WorkerUtilization struct {
+		Total            int `json:"total"`
+		Uploading        int `json:"uploading"`
+		Downloading      int `json:"downloading"`
+		UploadCooldown   int `json:"uploadcooldown"`
+		DownloadCooldown int `json:"downloadcooldown"`
+	}
+)
+
+// Status returns the consolidated status of the storage client
+func (client *StorageClient) Status() (status ClientStatus, err error) {
+	if err = client.tm.Add(); err != nil {
+		return
+	}
+	defer client.tm.Done()
+
+	status.Allowance = client.allowanceSummary()
+	status.Contracts = client.contractCounts()
+	if status.FileHealth, err = client.fileSystem.HealthDistribution(); err != nil {
+		return
+	}
+	if status.Repair, err = client.repairBacklog(); err != nil {
+		return
+	}
+	status.Workers = client.workerUtilization()
+	status.Resources = ResourceUsage{
+		Buffers:   sectorBuffers.Stats(),
+		OpenFiles: client.fileSystem.OpenFileStats(),
+	}
+	status.RecentErrors = recentErrors()
+	return
+}
+
+// allowanceSummary returns the rent payment and the spending of the current period
+func (client *StorageClient) allowanceSummary() AllowanceSummary {
+	rent := client.contractManager.AcquireRentPayment()
+	cost := client.contractManager.RetrievePeriodCost()
+	return AllowanceSummary{
+		Fund:         rent.Fund,
+		StorageHosts: rent.StorageHosts,
+		Period:       rent.Period,
+		RenewWindow:  rent.RenewWindow,
+		Spent:        cost.ContractFees.Add(cost.UploadCost).Add(cost.DownloadCost).Add(cost.StorageCost),
+		Unspent:      cost.UnspentFund,
+		Withheld:     cost.WithheldFund,
+	}
+}
+
+// contractCounts counts the active contracts by their status, and the expired contracts
+func (client *StorageClient) contractCounts() (counts ContractCounts) {
+	for _, contract := range client.contractManager.RetrieveActiveContracts() {
+		counts.Active++
+		if contract.Status.UploadAbility {
+			counts.Uploadable++
+		}
+		if contract.Status.RenewAbility {
+			counts.Renewable++
+		}
+		if contract.Status.Canceled {
+			counts.Canceled++
+		}
+	}
+	counts.Expired = len(client.contractManager.RetrieveExpiredContracts())
+	return
+}
+
+// repairBacklog returns the segments in the upload heap, and the stuck segments of all files
+func (client *StorageClient) repairBacklog() (backlog RepairBacklog, err error) {
+	backlog.PendingSegments, backlog.StuckSegments, backlog.ActiveSegments = client.uploadHeap.backlog()
+
+	root, err := client.fileSystem.OpenDxDir(storage.RootDxPath())
+	if err != nil {
+		return
+	}
+	defer root.Close()
+	backlog.NumStuckSegments = root.Metadata().NumStuckSegments
+	return
+}
+
+// workerUtilization returns the utilization of the workers
+func (client *StorageClient) workerUtilization() (utilization WorkerUtilization) {
+	client.lock.Lock()
+	workers := make([]*worker, 0, len(client.workerPool))
+	for _, w := range client.workerPool {
+		workers = append(workers, w)
+	}
+	client.lock.Unlock()
+
+	for _, w := range workers {
+		utilization.Total++
+		w.mu.Lock()
+		if len(w.pendingSegments) != 0 {
+			utilization.Uploading++
+		}
+		if w.onUploadCoolDown() {
+			utilization.UploadCooldown++
+		}
+		w.mu.Unlock()
+
+		w.downloadMu.Lock()
+		if len(w.downloadSegments) != 0 {
+			utilization.Downloading++
+		}
+		if w.onDownloadCooldown() {
+			utilization.DownloadCooldown++
+		}
+		w.downloadMu.Unlock()
+	}
+	return
+}
+
+// recentErrors returns the latest warnings and errors raised by the storage client
+// and the contract manager
+func recentErrors() []alert.Alert {
+	var errs []alert.Alert
+	for _, a := range alert.Alerts() {
+		if a.Severity < alert.SeverityWarning {
+			continue
+		}
+		if a.Module != alertModule && a.Module != "contractmanager" {
+			continue
+		}
+		errs = append(errs, a)
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].LastSeen.After(errs[j].LastSeen) })
+	if len(errs) > maxStatusErrors {
+		errs = errs[:maxStatusErrors]
+	}
+	return errs
+}
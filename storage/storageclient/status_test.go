@@ -0,0 +1,71 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common/alert"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestStorageClient_Status(t *testing.T) {
+	storage.ENV = storage.EnvTest
+
+	sct := newStorageClientTester(t)
+	defer sct.Client.Close()
+
+	entry := newFileEntry(t, sct.Client)
+	defer func() {
+		os.Remove(string(entry.LocalPath()))
+		os.Remove(string(entry.FilePath()))
+		entry.Close()
+	}()
+
+	// one worker is uploading, and the other is cooling down from the upload failure
+	mockAddWorkers(2, sct.Client)
+	var workers []*worker
+	for _, w := range sct.Client.workerPool {
+		workers = append(workers, w)
+	}
+	workers[0].pendingSegments = append(workers[0].pendingSegments, &unfinishedUploadSegment{})
+	workers[1].uploadConsecutiveFailures = 1
+	workers[1].uploadRecentFailure = time.Now()
+
+	alert.Raise(sct.Client.log, alert.SeverityWarning, alertModule, "status test warning")
+	alert.Raise(sct.Client.log, alert.SeverityInfo, alertModule, "status test info")
+	alert.Raise(sct.Client.log, alert.SeverityError, "storagehost", "status test host error")
+
+	status, err := sct.Client.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Workers.Total != 2 || status.Workers.Uploading != 1 || status.Workers.UploadCooldown != 1 {
+		t.Errorf("unexpected worker utilization %+v", status.Workers)
+	}
+	if status.Resources.OpenFiles.Limit == 0 || status.Resources.Buffers.Limit != DefaultBufferPoolLimit {
+		t.Errorf("unexpected resource usage %+v", status.Resources)
+	}
+	if status.Contracts.Active != 0 {
+		t.Errorf("unexpected contract counts %+v", status.Contracts)
+	}
+	var numFiles uint64
+	for _, n := range status.FileHealth {
+		numFiles += n
+	}
+	if numFiles == 0 {
+		t.Errorf("the file created is not counted in the health distribution")
+	}
+
+	messages := make(map[string]bool)
+	for _, a := range status.RecentErrors {
+		messages[a.Message] = true
+	}
+	if !messages["status test warning"] || messages["status test info"] || messages["status test host error"] {
+		t.Errorf("unexpected recent errors %+v", status.RecentErrors)
+	}
+}
@@ -0,0 +1,416 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxfile"
+)
+
+// host preferences of the storage classes
+const (
+	hostPreferenceAny   = "any"
+	hostPreferenceFast  = "fast"
+	hostPreferenceCheap = "cheap"
+)
+
+// StorageClass defines the redundancy of the file stored on the network, and which
+// hosts are preferred to store the sectors
+type StorageClass struct {
+	Name           string `json:"name"`
+	MinSectors     uint32 `json:"minsectors"`
+	NumSectors     uint32 `json:"numsectors"`
+	HostPreference string `json:"hostpreference"`
+}
+
+// storageClasses are the storage classes a file can be transitioned to
+var storageClasses = []StorageClass{
+	{Name: "standard", MinSectors: storage.DefaultMinSectors, NumSectors: storage.DefaultNumSectors, HostPreference: hostPreferenceAny},
+	{Name: "performance", MinSectors: 2, NumSectors: 6, HostPreference: hostPreferenceFast},
+	{Name: "archive", MinSectors: 4, NumSectors: 6, HostPreference: hostPreferenceCheap},
+}
+
+// StorageClassTransition is a transition of a file to another storage class. The sectors
+// are placed by the repair loop into a new file at the target path, which replaces the
+// original file once fully uploaded
+type StorageClassTransition struct {
+	DxPath     storage.DxPath `json:"dxpath"`
+	TargetPath storage.DxPath `json:"targetpath"`
+	Class      string         `json:"class"`
+	StartTime  time.Time      `json:"starttime"`
+
+	// Progress is the upload progress of the target file in percentage
+	Progress float64 `json:"progress"`
+}
+
+// StorageClasses returns the storage classes supported
+func (client *StorageClient) StorageClasses() []StorageClass {
+	return append([]StorageClass{}, storageClasses...)
+}
+
+// storageClassByName returns the storage class with the name
+func storageClassByName(name string) (StorageClass, error) {
+	for _, class := range storageClasses {
+		if class.Name == name {
+			return class, nil
+		}
+	}
+	return StorageClass{}, fmt.Errorf("unknown storage class %s", name)
+}
+
+// TransitionStorageClass starts the transition of the file to the storage class. The data is
+// read from the local source file if unchanged, otherwise downloaded from the current sectors
+func (client *StorageClient) TransitionStorageClass(dxPath storage.DxPath, className string) error {
+	if err := client.tm.Add(); err != nil {
+		return err
+	}
+	defer client.tm.Done()
+
+	class, err := storageClassByName(className)
+	if err != nil {
+		return err
+	}
+	ec, err := erasurecode.New(erasurecode.ECTypeStandard, class.MinSectors, class.NumSectors)
+	if err != nil {
+		return err
+	}
+	numContracts := uint64(len(client.contractManager.GetStorageContractSet().Contracts()))
+	requiredContracts := uint64(math.Ceil(float64(class.NumSectors+class.MinSectors) / 2))
+	if numContracts < requiredContracts {
+		return fmt.Errorf("not enough contracts for storage class %s: got %v, needed %v", class.Name, numContracts, requiredContracts)
+	}
+
+	source, err := client.fileSystem.OpenDxFile(dxPath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	current, err := source.ErasureCode()
+	if err != nil {
+		return err
+	}
+	if current.MinSectors() == class.MinSectors && current.NumSectors() == class.NumSectors && class.HostPreference == hostPreferenceAny {
+		return fmt.Errorf("file %v is already stored as storage class %s", dxPath, class.Name)
+	}
+	targetPath, err := storage.NewDxPath(dxPath.Path + storage.TransitionFileSuffix)
+	if err != nil {
+		return err
+	}
+
+	client.transitionLock.Lock()
+	if _, exists := client.transitions[dxPath.Path]; exists {
+		client.transitionLock.Unlock()
+		return fmt.Errorf("file %v is already in transition", dxPath)
+	}
+	cipherKey, err := crypto.GenerateCipherKey(crypto.GCMCipherCode)
+	if err != nil {
+		client.transitionLock.Unlock()
+		return fmt.Errorf("generate cipher key error: %v", err)
+	}
+	target, err := client.fileSystem.NewDxFile(targetPath, source.LocalPath(), false, ec, cipherKey, source.FileSize(), source.FileMode())
+	if err != nil {
+		client.transitionLock.Unlock()
+		return fmt.Errorf("could not create the transition target file, error: %v", err)
+	}
+	defer target.Close()
+	if err = target.SetSourceChecksum(source.SourceChecksum()); err != nil {
+		client.transitionLock.Unlock()
+		return err
+	}
+	client.transitions[dxPath.Path] = &StorageClassTransition{
+		DxPath:     dxPath,
+		TargetPath: targetPath,
+		Class:      class.Name,
+		StartTime:  time.Now(),
+	}
+	client.transitionLock.Unlock()
+
+	if err = client.saveTransitions(); err != nil {
+		return err
+	}
+
+	// hand the target file to the repair loop to place the sectors
+	hosts := client.refreshHostsAndWorkers()
+	if err = client.createAndPushSegments([]*dxfile.FileSetEntryWithID{target}, hosts, targetUnstuckSegments, make(storage.HostHealthInfoTable)); err != nil {
+		return err
+	}
+	select {
+	case client.uploadHeap.segmentComing <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// StorageClassTransitions returns the storage class transitions in progress
+func (client *StorageClient) StorageClassTransitions() []StorageClassTransition {
+	client.transitionLock.Lock()
+	transitions := make([]StorageClassTransition, 0, len(client.transitions))
+	for _, t := range client.transitions {
+		transitions = append(transitions, *t)
+	}
+	client.transitionLock.Unlock()
+
+	for i, t := range transitions {
+		target, err := client.fileSystem.OpenDxFile(t.TargetPath)
+		if err != nil {
+			continue
+		}
+		transitions[i].Progress = target.UploadProgress()
+		target.Close()
+	}
+	sort.Slice(transitions, func(i, j int) bool {
+		return transitions[i].StartTime.Before(transitions[j].StartTime)
+	})
+	return transitions
+}
+
+// transitionSource returns the source file of the transition whose target is the dxPath.
+// nil is returned if the dxPath is not a transition target
+func (client *StorageClient) transitionSource(dxPath storage.DxPath) *StorageClassTransition {
+	client.transitionLock.Lock()
+	defer client.transitionLock.Unlock()
+
+	for _, t := range client.transitions {
+		if t.TargetPath.Equals(dxPath) {
+			transition := *t
+			return &transition
+		}
+	}
+	return nil
+}
+
+// storageClassHosts filters the hosts for uploading the file with the host preference of
+// the storage class, if the file is a transition target. If not enough hosts satisfy the
+// preference, all hosts are returned so that the transition is not stuck
+func (client *StorageClient) storageClassHosts(dxPath storage.DxPath, hosts map[string]struct{}, needed int) map[string]struct{} {
+	t := client.transitionSource(dxPath)
+	if t == nil {
+		return hosts
+	}
+	class, err := storageClassByName(t.Class)
+	if err != nil || class.HostPreference == hostPreferenceAny {
+		return hosts
+	}
+
+	type rankedHost struct {
+		id    string
+		score float64
+	}
+	var ranked []rankedHost
+	for _, contract := range client.contractManager.GetStorageContractSet().Contracts() {
+		id := contract.Header().EnodeID
+		if _, exists := hosts[id.String()]; !exists {
+			continue
+		}
+		switch class.HostPreference {
+		case hostPreferenceFast:
+			if expected, known := client.hostLatency.expectedDuration(id, storage.SectorSize); known {
+				ranked = append(ranked, rankedHost{id.String(), float64(expected)})
+			}
+		case hostPreferenceCheap:
+			if info, exists := client.storageHostManager.RetrieveHostInfo(id); exists {
+				ranked = append(ranked, rankedHost{id.String(), info.StoragePrice.Float64()})
+			}
+		}
+	}
+	if len(ranked) < needed {
+		return hosts
+	}
+
+	// keep the better half of the ranked hosts, but no fewer than needed
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].score < ranked[j].score })
+	keep := (len(ranked) + 1) / 2
+	if keep < needed {
+		keep = needed
+	}
+	filtered := make(map[string]struct{})
+	for _, host := range ranked[:keep] {
+		filtered[host.id] = struct{}{}
+	}
+	return filtered
+}
+
+// storageClassTransitionLoop periodically checks the transitions in progress, and completes
+// the transitions whose target files are fully uploaded
+func (client *StorageClient) storageClassTransitionLoop() {
+	if err := client.tm.Add(); err != nil {
+		return
+	}
+	defer client.tm.Done()
+
+	for {
+		select {
+		case <-client.tm.StopChan():
+			return
+		case <-time.After(transitionCheckInterval):
+		}
+
+		client.transitionLock.Lock()
+		transitions := make([]StorageClassTransition, 0, len(client.transitions))
+		for _, t := range client.transitions {
+			transitions = append(transitions, *t)
+		}
+		client.transitionLock.Unlock()
+
+		for _, t := range transitions {
+			if err := client.checkTransition(t); err != nil {
+				client.log.Warn("storage class transition failed", "path", t.DxPath, "class", t.Class, "err", err)
+			}
+		}
+	}
+}
+
+// checkTransition completes the transition if the target file is fully uploaded. The transition
+// is aborted if the source file no longer exists
+func (client *StorageClient) checkTransition(t StorageClassTransition) error {
+	retiredPath, err := storage.NewDxPath(t.DxPath.Path + storage.RetiredFileSuffix)
+	if err != nil {
+		return err
+	}
+	// the source file has been renamed aside, but the transition was interrupted before the
+	// target file replaced it
+	if client.dxFileExists(retiredPath) {
+		return client.completeTransition(t, retiredPath)
+	}
+
+	source, err := client.fileSystem.OpenDxFile(t.DxPath)
+	if err != nil {
+		client.removeTransition(t)
+		if err := client.fileSystem.DeleteDxFile(t.TargetPath); err != nil {
+			return err
+		}
+		return fmt.Errorf("transition aborted, source file not available: %v", err)
+	}
+	source.Close()
+
+	target, err := client.fileSystem.OpenDxFile(t.TargetPath)
+	if err != nil {
+		client.removeTransition(t)
+		return fmt.Errorf("transition aborted, target file not available: %v", err)
+	}
+	// the empty file has no sectors to be uploaded
+	fileSize := target.FileSize()
+	health, stuckHealth, _ := target.Health(client.contractManager.HostHealthMap())
+	target.Close()
+	if fileSize != 0 && (health < dxfile.CompleteHealthThreshold || stuckHealth < dxfile.CompleteHealthThreshold) {
+		return nil
+	}
+	return client.completeTransition(t, retiredPath)
+}
+
+// completeTransition replaces the source file with the target file. The source file is renamed
+// aside before the target file is renamed in place, so that the file is never lost if the
+// transition is interrupted, and the transition is resumed from the renamed source file.
+// The sectors of the source file are removed from the contracts afterwards
+func (client *StorageClient) completeTransition(t StorageClassTransition, retiredPath storage.DxPath) error {
+	if !client.dxFileExists(retiredPath) {
+		if err := client.fileSystem.RenameDxFile(t.DxPath, retiredPath); err != nil {
+			return err
+		}
+	}
+	if client.dxFileExists(t.TargetPath) {
+		if err := client.fileSystem.RenameDxFile(t.TargetPath, t.DxPath); err != nil {
+			if restoreErr := client.fileSystem.RenameDxFile(retiredPath, t.DxPath); restoreErr != nil {
+				client.log.Warn("failed to restore the source file of the transition", "path", t.DxPath, "err", restoreErr)
+			}
+			return err
+		}
+	} else if !client.dxFileExists(t.DxPath) {
+		// the target file is lost, keep the source file
+		client.removeTransition(t)
+		if err := client.fileSystem.RenameDxFile(retiredPath, t.DxPath); err != nil {
+			return err
+		}
+		return errors.New("transition aborted, target file not available")
+	}
+
+	sectors, err := client.fileSectors(retiredPath)
+	if err != nil {
+		return err
+	}
+	if err = client.fileSystem.DeleteDxFile(retiredPath); err != nil {
+		return err
+	}
+	client.removeTransition(t)
+	if parent, err := t.DxPath.Parent(); err == nil {
+		go client.fileSystem.InitAndUpdateDirMetadata(parent)
+	}
+	client.removeFileSectorsInBackground(t.DxPath, sectors)
+	client.log.Info("storage class transition completed", "path", t.DxPath, "class", t.Class)
+	return nil
+}
+
+// dxFileExists checks whether the DxFile exists in the file system
+func (client *StorageClient) dxFileExists(path storage.DxPath) bool {
+	entry, err := client.fileSystem.OpenDxFile(path)
+	if err != nil {
+		return false
+	}
+	entry.Close()
+	return true
+}
+
+// removeTransition removes the transition from the transitions in progress
+func (client *StorageClient) removeTransition(t StorageClassTransition) {
+	client.transitionLock.Lock()
+	delete(client.transitions, t.DxPath.Path)
+	client.transitionLock.Unlock()
+
+	if err := client.saveTransitions(); err != nil {
+		client.log.Warn("failed to save the storage class transitions", "err", err)
+	}
+}
+
+// saveTransitions saves the transitions in progress to the persist file
+func (client *StorageClient) saveTransitions() error {
+	client.transitionLock.Lock()
+	transitions := make([]StorageClassTransition, 0, len(client.transitions))
+	for _, t := range client.transitions {
+		transitions = append(transitions, *t)
+	}
+	client.transitionLock.Unlock()
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	client.persist.Transitions = transitions
+	if err := client.saveSettings(); err != nil {
+		return errors.New("failed to save the storage class transitions: " + err.Error())
+	}
+	return nil
+}
+
+// loadTransitions restores the transitions in progress from the persisted settings
+func (client *StorageClient) loadTransitions() {
+	client.transitionLock.Lock()
+	defer client.transitionLock.Unlock()
+
+	for i := range client.persist.Transitions {
+		t := client.persist.Transitions[i]
+		client.transitions[t.DxPath.Path] = &t
+	}
+}
+
+// repairDownloadSnapshot returns the snapshot of the file to download the data from for
+// repairing the entry. For a transition target, it is the snapshot of the source file
+func (client *StorageClient) repairDownloadSnapshot(entry *dxfile.FileSetEntryWithID) (*dxfile.Snapshot, error) {
+	t := client.transitionSource(entry.DxPath())
+	if t == nil {
+		return entry.Snapshot()
+	}
+	source, err := client.fileSystem.OpenDxFile(t.DxPath)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+	return source.Snapshot()
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"testing"
+
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
+)
+
+func TestStorageClassByName(t *testing.T) {
+	for _, class := range storageClasses {
+		found, err := storageClassByName(class.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != class {
+			t.Errorf("expect class %+v, got %+v", class, found)
+		}
+		if _, err = erasurecode.New(erasurecode.ECTypeStandard, class.MinSectors, class.NumSectors); err != nil {
+			t.Errorf("class %s: invalid erasure code params: %v", class.Name, err)
+		}
+	}
+	if _, err := storageClassByName("glacier"); err == nil {
+		t.Error("unknown storage class should return error")
+	}
+}
+
+func TestTransitionSource(t *testing.T) {
+	client := &StorageClient{transitions: make(map[string]*StorageClassTransition)}
+	source, target := storage.DxPath{Path: "a/b"}, storage.DxPath{Path: "a/b" + storage.TransitionFileSuffix}
+	client.transitions[source.Path] = &StorageClassTransition{DxPath: source, TargetPath: target, Class: "standard"}
+
+	if tr := client.transitionSource(target); tr == nil || !tr.DxPath.Equals(source) {
+		t.Errorf("expect transition source %v, got %v", source, tr)
+	}
+	if tr := client.transitionSource(source); tr != nil {
+		t.Errorf("source file should not be a transition target")
+	}
+
+	// hosts not filtered for the standard class which accepts any host
+	hosts := map[string]struct{}{"h1": {}, "h2": {}}
+	if filtered := client.storageClassHosts(target, hosts, 2); len(filtered) != len(hosts) {
+		t.Errorf("expect %v hosts, got %v", len(hosts), len(filtered))
+	}
+}
+
+func TestCheckTransition(t *testing.T) {
+	storage.ENV = storage.EnvTest
+
+	sct := newStorageClientTester(t)
+	sc := sct.Client
+	defer sc.Close()
+
+	// newTransition creates the empty source file and the target file of the transition,
+	// the target file is distinguished by the number of sectors
+	newTransition := func() StorageClassTransition {
+		source := randomDxPath()
+		tr := StorageClassTransition{DxPath: source, TargetPath: storage.DxPath{Path: source.Path + storage.TransitionFileSuffix}, Class: "archive"}
+		for i, path := range []storage.DxPath{tr.DxPath, tr.TargetPath} {
+			ec, err := erasurecode.New(erasurecode.ECTypeStandard, 1, uint32(2+i))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ck, err := crypto.GenerateCipherKey(crypto.GCMCipherCode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry, err := sc.fileSystem.NewDxFile(path, "", false, ec, ck, 0, 0777)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry.Close()
+		}
+		sc.transitionLock.Lock()
+		sc.transitions[source.Path] = &tr
+		sc.transitionLock.Unlock()
+		return tr
+	}
+	checkCompleted := func(tr StorageClassTransition) {
+		entry, err := sc.fileSystem.OpenDxFile(tr.DxPath)
+		if err != nil {
+			t.Fatalf("the file should exist after the transition: %v", err)
+		}
+		defer entry.Close()
+		if ec, err := entry.ErasureCode(); err != nil || ec.NumSectors() != 3 {
+			t.Errorf("the file should be replaced by the target file")
+		}
+		retiredPath := storage.DxPath{Path: tr.DxPath.Path + storage.RetiredFileSuffix}
+		if sc.dxFileExists(tr.TargetPath) || sc.dxFileExists(retiredPath) {
+			t.Errorf("the target and the retired files should not exist after the transition")
+		}
+		if sc.transitionSource(tr.TargetPath) != nil {
+			t.Errorf("the transition should be removed after completed")
+		}
+	}
+
+	// the transition of the empty file completes without any sectors uploaded
+	tr := newTransition()
+	if err := sc.checkTransition(tr); err != nil {
+		t.Fatal(err)
+	}
+	checkCompleted(tr)
+
+	// the transition interrupted after the source file is renamed aside is resumed
+	tr = newTransition()
+	if err := sc.fileSystem.RenameDxFile(tr.DxPath, storage.DxPath{Path: tr.DxPath.Path + storage.RetiredFileSuffix}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.checkTransition(tr); err != nil {
+		t.Fatal(err)
+	}
+	checkCompleted(tr)
+}
@@ -21,15 +21,19 @@ import (
 	"github.com/DxChainNetwork/godx/accounts"
 	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/common/threadmanager"
+	"github.com/DxChainNetwork/godx/common/timeseries"
 	"github.com/DxChainNetwork/godx/core/types"
 	"github.com/DxChainNetwork/godx/core/vm"
 	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/ethdb"
 	"github.com/DxChainNetwork/godx/internal/ethapi"
 	"github.com/DxChainNetwork/godx/log"
 	"github.com/DxChainNetwork/godx/p2p/enode"
 	"github.com/DxChainNetwork/godx/rlp"
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/storageclient/contractmanager"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+	"github.com/DxChainNetwork/godx/storage/storageclient/dxfuse"
 	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
 	"github.com/DxChainNetwork/godx/storage/storageclient/memorymanager"
 	"github.com/DxChainNetwork/godx/storage/storageclient/storagehostmanager"
@@ -51,17 +55,43 @@ type StorageClient struct {
 	downloadHeap   *downloadSegmentHeap
 	newDownloads   chan struct{}
 
+	// observed download performance of storage hosts, used for download scheduling
+	hostLatency *hostLatencyTracker
+
+	// statistics of the extra sector fetches issued for the downloads
+	overdrive overdriveTracker
+
 	// Upload management
 	uploadHeap uploadHeap
 
+	// verifies local files before they are used for repairing
+	sourceVerifier *sourceVerifier
+
 	// List of workers that can be used for uploading and/or downloading.
 	workerPool map[storage.ContractID]*worker
 
+	// filesystems mounted, keyed by the mount point. The mount point being mounted is
+	// reserved with nil
+	mounts map[string]*dxfuse.MountedFS
+
+	// storage class transitions in progress, keyed by the dxpath of the file
+	transitions    map[string]*StorageClassTransition
+	transitionLock sync.Mutex
+
 	// Directories and File related
 	persist        persistence
 	persistDir     string
 	staticFilesDir string
 
+	// hourly aggregates of the spend, bytes transferred and contracts
+	metrics *timeseries.Store
+
+	// audit trail of the upload and download operations, keyed by the contract id and revision number
+	operationAudits *ethdb.LDBDatabase
+
+	// callbacks registered by the embedding applications around the file operations
+	hooks *hookRegistry
+
 	//storage client is used as the address to sign the storage contract and pays for the money
 	PaymentAddress common.Address
 
@@ -76,8 +106,16 @@ type StorageClient struct {
 	apiBackend ethapi.Backend
 }
 
-// New initializes StorageClient object
-func New(persistDir string) (*StorageClient, error) {
+// New initializes StorageClient object. If the passphrase is not empty, the
+// storage contracts will be encrypted at rest with the key derived from it
+func New(persistDir string, passphrase string) (*StorageClient, error) {
+	return NewWithHostSelector(persistDir, passphrase, nil)
+}
+
+// NewWithHostSelector initializes StorageClient object with the strategy used to select
+// the storage hosts to form contracts with. If the selector is nil, the storage hosts are
+// selected randomly weighted by the evaluation
+func NewWithHostSelector(persistDir string, passphrase string, selector storagehostmanager.HostSelector) (*StorageClient, error) {
 	var err error
 
 	sc := &StorageClient{
@@ -86,25 +124,42 @@ func New(persistDir string) (*StorageClient, error) {
 		log:            log.New(),
 		newDownloads:   make(chan struct{}, 1),
 		downloadHeap:   new(downloadSegmentHeap),
+		hostLatency:    newHostLatencyTracker(),
 		uploadHeap: uploadHeap{
-			pendingSegments:     make(map[uploadSegmentID]struct{}),
+			pendingSegments:     make(map[uploadSegmentID]*unfinishedUploadSegment),
+			activeSegments:      make(map[uploadSegmentID]*unfinishedUploadSegment),
 			segmentComing:       make(chan struct{}, 1),
 			stuckSegmentSuccess: make(chan storage.DxPath, 1),
 		},
-		workerPool: make(map[storage.ContractID]*worker),
+		workerPool:     make(map[storage.ContractID]*worker),
+		sourceVerifier: newSourceVerifier(),
+		mounts:         make(map[string]*dxfuse.MountedFS),
+		transitions:    make(map[string]*StorageClassTransition),
+		hooks:          newHookRegistry(),
 	}
 
 	sc.memoryManager = memorymanager.New(DefaultMaxMemory, sc.tm.StopChan())
 
 	// initialize storageHostManager
 	sc.storageHostManager = storagehostmanager.New(sc.persistDir)
+	sc.storageHostManager.SetHostSelector(selector)
 
 	// initialize storage contract manager
-	if sc.contractManager, err = contractmanager.New(sc.persistDir, sc.storageHostManager); err != nil {
+	if sc.contractManager, err = contractmanager.New(sc.persistDir, sc.storageHostManager, passphrase); err != nil {
 		err = fmt.Errorf("error initializing contract manager: %s", err.Error())
 		return nil, err
 	}
 
+	// open the hourly metrics
+	if err = sc.openMetrics(); err != nil {
+		return nil, fmt.Errorf("error opening the metrics: %s", err.Error())
+	}
+
+	// open the audit trail of the upload and download operations
+	if err = sc.openOperationAudits(); err != nil {
+		return nil, fmt.Errorf("error opening the operation audits: %s", err.Error())
+	}
+
 	// initialize fileSystem
 	sc.fileSystem = filesystem.New(persistDir, sc.contractManager)
 
@@ -136,6 +191,7 @@ func (client *StorageClient) Start(b storage.EthBackend, apiBackend ethapi.Backe
 	if err := client.loadPersist(); err != nil {
 		return err
 	}
+	client.loadTransitions()
 
 	if err = client.fileSystem.Start(); err != nil {
 		return err
@@ -150,6 +206,7 @@ func (client *StorageClient) Start(b storage.EthBackend, apiBackend ethapi.Backe
 	go client.stuckLoop()
 	go client.uploadOrRepair()
 	go client.healthCheckLoop()
+	go client.storageClassTransitionLoop()
 
 	// kill workers on shutdown.
 	client.tm.OnStop(func() error {
@@ -168,11 +225,13 @@ func (client *StorageClient) Start(b storage.EthBackend, apiBackend ethapi.Backe
 
 // Close method will be used to send storage
 func (client *StorageClient) Close() error {
+	// unmount first, so that the files written can still be uploaded
+	client.log.Info("Unmounting the mounted filesystems")
+	fullErr := client.unmountAll()
+
 	client.log.Info("Closing The Contract Manager")
 	client.contractManager.Stop()
 
-	var fullErr error
-
 	// Closing the host manager
 	client.log.Info("Closing the storage client host manager")
 	err := client.storageHostManager.Close()
@@ -187,17 +246,12 @@ func (client *StorageClient) Close() error {
 	client.log.Info("Closing The Storage Client Manager")
 	err = client.tm.Stop()
 	fullErr = common.ErrCompose(fullErr, err)
-	return fullErr
-}
 
-// DeleteFile will delete from the file system file set. The file
-// wil also be deleted from the disk
-func (client *StorageClient) DeleteFile(path storage.DxPath) error {
-	if err := client.tm.Add(); err != nil {
-		return err
-	}
-	defer client.tm.Done()
-	return client.fileSystem.DeleteDxFile(path)
+	err = client.metrics.Close()
+	fullErr = common.ErrCompose(fullErr, err)
+
+	client.operationAudits.Close()
+	return fullErr
 }
 
 // ContractDetail will return the detailed contract information
@@ -241,6 +295,10 @@ func (client *StorageClient) SetClientSetting(setting storage.ClientSetting) (er
 			setting.MaxUploadSpeed, setting.MaxDownloadSpeed)
 		return
 	}
+	if setting.DownloadOverdrive < 0 {
+		err = fmt.Errorf("download overdrive %v cannot be smaller than 0", setting.DownloadOverdrive)
+		return
+	}
 
 	// set the rent payment
 	if err = client.contractManager.SetRentPayment(setting.RentPayment); err != nil {
@@ -259,6 +317,7 @@ func (client *StorageClient) SetClientSetting(setting storage.ClientSetting) (er
 	client.lock.Lock()
 	client.persist.MaxDownloadSpeed = setting.MaxDownloadSpeed
 	client.persist.MaxUploadSpeed = setting.MaxUploadSpeed
+	client.persist.DownloadOverdrive = setting.DownloadOverdrive
 	if err = client.saveSettings(); err != nil {
 		err = fmt.Errorf("failed to save the storage client settings: %s", err.Error())
 		client.lock.Unlock()
@@ -275,11 +334,16 @@ func (client *StorageClient) SetClientSetting(setting storage.ClientSetting) (er
 // RetrieveClientSetting will return the current storage client setting
 func (client *StorageClient) RetrieveClientSetting() (setting storage.ClientSetting) {
 	maxDownloadSpeed, maxUploadSpeed, _ := client.contractManager.RetrieveRateLimit()
+	client.lock.Lock()
+	overdrive := client.persist.DownloadOverdrive
+	client.lock.Unlock()
+
 	setting = storage.ClientSetting{
 		RentPayment:       client.contractManager.AcquireRentPayment(),
 		EnableIPViolation: client.storageHostManager.RetrieveIPViolationCheckSetting(),
 		MaxUploadSpeed:    maxUploadSpeed,
 		MaxDownloadSpeed:  maxDownloadSpeed,
+		DownloadOverdrive: overdrive,
 	}
 	return
 }
@@ -307,7 +371,24 @@ func (client *StorageClient) Append(sp storage.Peer, data []byte, hostInfo *stor
 	return merkle.Sha256MerkleTreeRoot(data), err
 }
 
-func (client *StorageClient) Write(sp storage.Peer, actions []storage.UploadAction, hostInfo *storage.HostInfo) (err error) {
+// Write sends the upload actions to the host, and commits the contract revision signed. The
+// actions exceeding the batch limits of the host are sent in multiple requests in order
+func (client *StorageClient) Write(sp storage.Peer, actions []storage.UploadAction, hostInfo *storage.HostInfo) error {
+	for _, batch := range splitUploadBatches(hostInfo.HostExtConfig, actions) {
+		batch := batch
+		if _, err := client.write(sp, hostInfo, func(*contractset.Contract) ([]storage.UploadAction, error) {
+			return batch, nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write negotiates the upload actions with the host, and returns the contract revision signed.
+// The actions are created by makeActions after the contract is acquired, so that the actions
+// depending on the merkle roots of the contract are not changed by other negotiations
+func (client *StorageClient) write(sp storage.Peer, hostInfo *storage.HostInfo, makeActions func(*contractset.Contract) ([]storage.UploadAction, error)) (rev types.StorageContractRevision, err error) {
 	// Retrieve the last contract revision
 	scs := client.contractManager.GetStorageContractSet()
 
@@ -315,55 +396,52 @@ func (client *StorageClient) Write(sp storage.Peer, actions []storage.UploadActi
 	contractID := scs.GetContractIDByHostID(hostInfo.EnodeID)
 	contract, exist := scs.Acquire(contractID)
 	if !exist {
-		return fmt.Errorf("contract does not exist: %s", contractID.String())
+		return rev, fmt.Errorf("contract does not exist: %s", contractID.String())
 	}
 
 	defer scs.Return(contract)
 
+	actions, err := makeActions(contract)
+	if err != nil {
+		return rev, err
+	}
+	if err = checkUploadBatch(hostInfo.HostExtConfig, actions); err != nil {
+		return rev, err
+	}
+
 	// old contract header and revision
 	contractHeader := contract.Header()
 	contractRevision := contractHeader.LatestContractRevision
 
-	// calculate price per sector
+	// calculate the new file size and total cost/collateral
 	blockBytes := storage.SectorSize * uint64(contractRevision.NewWindowEnd-client.ethBackend.GetCurrentBlockHeight())
-	sectorBandwidthPrice := hostInfo.UploadBandwidthPrice.MultUint64(storage.SectorSize)
-	sectorStoragePrice := hostInfo.StoragePrice.MultUint64(blockBytes)
-	sectorDeposit := hostInfo.Deposit.MultUint64(blockBytes)
-
-	// calculate the new Merkle root set and total cost/collateral
-	var bandwidthPrice, storagePrice, deposit common.BigInt
-	newFileSize := contractRevision.NewFileSize
-	for _, action := range actions {
-		switch action.Type {
-		case storage.UploadActionAppend:
-			bandwidthPrice = bandwidthPrice.Add(sectorBandwidthPrice)
-			newFileSize += storage.SectorSize
-		}
-	}
-	if newFileSize > contractRevision.NewFileSize {
-		addedSectors := (newFileSize - contractRevision.NewFileSize) / storage.SectorSize
-		storagePrice = sectorStoragePrice.MultUint64(addedSectors)
-		deposit = sectorDeposit.MultUint64(addedSectors)
-	}
-
-	// estimate cost of Merkle proof
-	proofSize := storage.HashSize * (128 + len(actions))
-	bandwidthPrice = bandwidthPrice.Add(hostInfo.DownloadBandwidthPrice.MultUint64(uint64(proofSize)))
+	bandwidthPrice, storagePrice, deposit, newFileSize := uploadCost(hostInfo.HostExtConfig, actions, contractRevision.NewFileSize, blockBytes)
 	cost := bandwidthPrice.Add(storagePrice).Add(hostInfo.BaseRPCPrice)
 
 	// check that enough funds are available
 	if contractRevision.NewValidProofOutputs[0].Value.Cmp(cost.BigIntPtr()) < 0 {
-		return errors.New("contract has insufficient funds to support upload")
+		return rev, errors.New("contract has insufficient funds to support upload")
 	}
 	if contractRevision.NewMissedProofOutputs[1].Value.Cmp(deposit.BigIntPtr()) < 0 {
-		return errors.New("contract has insufficient collateral to support upload")
+		return rev, errors.New("contract has insufficient collateral to support upload")
 	}
 
 	// create the revision; we will update the Merkle root later
-	rev := NewRevision(contractRevision, cost.BigIntPtr())
+	rev = NewRevision(contractRevision, cost.BigIntPtr())
 	rev.NewMissedProofOutputs[1].Value = rev.NewMissedProofOutputs[1].Value.Sub(rev.NewMissedProofOutputs[1].Value, deposit.BigIntPtr())
 	rev.NewFileSize = newFileSize
 
+	// the deposit risked for the sectors removed is returned to the host, as long as the host
+	// has no incentive to fail the storage proof
+	released := releasedDeposit(hostInfo.HostExtConfig, actions, blockBytes)
+	hostValid, hostMissed := common.PtrBigInt(rev.NewValidProofOutputs[1].Value), common.PtrBigInt(rev.NewMissedProofOutputs[1].Value)
+	if maxReleased := hostValid.Sub(hostMissed); released.Cmp(maxReleased) > 0 {
+		released = maxReleased
+	}
+	if released.Sign() > 0 {
+		rev.NewMissedProofOutputs[1].Value = rev.NewMissedProofOutputs[1].Value.Add(rev.NewMissedProofOutputs[1].Value, released.BigIntPtr())
+	}
+
 	// create the request
 	req := storage.UploadRequest{
 		StorageContractID: contractRevision.ParentID,
@@ -397,38 +475,40 @@ func (client *StorageClient) Write(sp storage.Peer, actions []storage.UploadActi
 		if err == nil {
 			client.storageHostManager.IncrementSuccessfulInteractions(hostInfo.EnodeID)
 		}
+		client.storageHostManager.RecordHostQoS(hostInfo.EnodeID, sp.TakeQoSSample())
 	}()
 
 	// send contract upload request
 	if err := sp.RequestContractUpload(req); err != nil {
-		return err
+		return rev, err
 	}
 
 	// 2. read merkle proof response from host
 	var merkleResp storage.UploadMerkleProof
 	msg, err := sp.ClientWaitContractResp()
 	if err != nil {
-		return fmt.Errorf("read upload merkle proof response msg failed, err: %v", err)
+		return rev, fmt.Errorf("read upload merkle proof response msg failed, err: %v", err)
 	}
 
 	// meaning request was sent too frequently, the host's evaluation
 	// will not be degraded
 	if msg.Code == storage.HostBusyHandleReqMsg {
-		return storage.ErrHostBusyHandleReq
+		return rev, storage.ErrHostBusyHandleReq
 	}
 
 	if msg.Code == storage.HostNegotiateErrorMsg {
 		hostNegotiateErr = storage.ErrHostNegotiate
-		return hostNegotiateErr
+		return rev, hostNegotiateErr
 	}
 
 	if err := msg.Decode(&merkleResp); err != nil {
 		hostNegotiateErr = err
-		return err
+		return rev, err
 	}
 
 	// verify merkle proof
 	numSectors := contractRevision.NewFileSize / storage.SectorSize
+	newNumSectors := newFileSize / storage.SectorSize
 	proofRanges := CalculateProofRanges(actions, numSectors)
 	proofHashes := merkleResp.OldSubtreeHashes
 	leafHashes := merkleResp.OldLeafHashes
@@ -436,15 +516,15 @@ func (client *StorageClient) Write(sp storage.Peer, actions []storage.UploadActi
 
 	if err := merkle.Sha256VerifyDiffProof(proofRanges, numSectors, proofHashes, leafHashes, oldRoot); err != nil {
 		hostNegotiateErr = err
-		return fmt.Errorf("invalid merkle proof for old root, err: %v", err)
+		return rev, fmt.Errorf("invalid merkle proof for old root, err: %v", err)
 	}
 
 	// and then modify the leaves and verify the new Merkle root
 	leafHashes = ModifyLeaves(leafHashes, actions, numSectors)
 	proofRanges = ModifyProofRanges(proofRanges, actions, numSectors)
-	if err := merkle.Sha256VerifyDiffProof(proofRanges, numSectors, proofHashes, leafHashes, newRoot); err != nil {
+	if err := merkle.Sha256VerifyDiffProof(proofRanges, newNumSectors, proofHashes, leafHashes, newRoot); err != nil {
 		hostNegotiateErr = err
-		return fmt.Errorf("invalid merkle proof for new root, err: %v", err)
+		return rev, fmt.Errorf("invalid merkle proof for new root, err: %v", err)
 	}
 
 	// update the revision, sign it, and send it
@@ -457,36 +537,36 @@ func (client *StorageClient) Write(sp storage.Peer, actions []storage.UploadActi
 	clientWallet, err := am.Find(clientAccount)
 	if err != nil {
 		clientNegotiateErr = err
-		return err
+		return rev, err
 	}
 	// client sign the new revision
 	clientRevisionSign, err := clientWallet.SignHash(clientAccount, rev.RLPHash().Bytes())
 	if err != nil {
 		clientNegotiateErr = err
-		return err
+		return rev, err
 	}
 
 	// send client sig to host
 	if err := sp.SendContractUploadClientRevisionSign(clientRevisionSign); err != nil {
 		clientNegotiateErr = err
-		return fmt.Errorf("send storage contract upload client revision sign msg failed, err: %v", err)
+		return rev, fmt.Errorf("send storage contract upload client revision sign msg failed, err: %v", err)
 	}
 
 	// read the host's signature
 	var hostRevisionSig []byte
 	msg, err = sp.ClientWaitContractResp()
 	if err != nil {
-		return err
+		return rev, err
 	}
 
 	if msg.Code == storage.HostNegotiateErrorMsg {
 		hostNegotiateErr = storage.ErrHostNegotiate
-		return hostNegotiateErr
+		return rev, hostNegotiateErr
 	}
 
 	if err := msg.Decode(&hostRevisionSig); err != nil {
 		hostNegotiateErr = err
-		return err
+		return rev, err
 	}
 
 	rev.Signatures = [][]byte{clientRevisionSign, hostRevisionSig}
@@ -499,9 +579,9 @@ func (client *StorageClient) Write(sp storage.Peer, actions []storage.UploadActi
 		// wait for host ack msg
 		msg, err = sp.ClientWaitContractResp()
 		if err == nil && msg.Code == storage.HostAckMsg {
-			return fmt.Errorf("commitUpload update contract header failed, err: %v", err)
+			return rev, fmt.Errorf("commitUpload update contract header failed, err: %v", err)
 		}
-		return fmt.Errorf("commitUpload failed, but don't wait for host ack msg, err: %v", err)
+		return rev, fmt.Errorf("commitUpload failed, but don't wait for host ack msg, err: %v", err)
 	}
 
 	_ = sp.SendClientCommitSuccessMsg()
@@ -513,11 +593,23 @@ func (client *StorageClient) Write(sp storage.Peer, actions []storage.UploadActi
 
 		_ = contract.RollbackUndoMem(contractHeader)
 		err = fmt.Errorf("failed to read host ACK message, error: %s", err.Error())
-		return err
+		return rev, err
 	}
 
 	switch msg.Code {
 	case storage.HostAckMsg:
+		client.updateContractRoots(contract, actions, numSectors)
+		client.auditRevisionCharge(hostInfo.EnodeID, contractRevision, rev, func(config storage.HostExtConfig) common.BigInt {
+			bandwidth, storageCost, _, _ := uploadCost(config, actions, contractRevision.NewFileSize, blockBytes)
+			return bandwidth.Add(storageCost).Add(config.BaseRPCPrice)
+		})
+		if newFileSize < contractRevision.NewFileSize {
+			client.recordTransfer(storage.MetricUploadBytes, 0, cost)
+			client.recordOperation(OperationDelete, hostInfo.EnodeID, contractRevision, rev, contractRevision.NewFileSize-newFileSize, newRoot)
+			return
+		}
+		client.recordTransfer(storage.MetricUploadBytes, newFileSize-contractRevision.NewFileSize, cost)
+		client.recordOperation(OperationUpload, hostInfo.EnodeID, contractRevision, rev, newFileSize-contractRevision.NewFileSize, newRoot)
 		return
 	default:
 		hostCommitErr = storage.ErrHostCommit
@@ -525,7 +617,32 @@ func (client *StorageClient) Write(sp storage.Peer, actions []storage.UploadActi
 
 		_ = sp.SendClientAckMsg()
 		_, _ = sp.ClientWaitContractResp()
-		return hostCommitErr
+		return rev, hostCommitErr
+	}
+}
+
+// rotateSessionKey rotates the session keys with the host after the host sent invalid sector
+// data, so that the traffic afterwards is not exposed if the keys leaked
+func (client *StorageClient) rotateSessionKey(sp storage.Peer) {
+	if err := sp.RotateSessionKey(); err != nil {
+		client.log.Debug("failed to rotate the session key", "err", err)
+	}
+}
+
+// updateContractRoots applies the upload actions committed to the merkle roots stored for
+// the contract. The merkle roots not in sync with the revision before the upload are left
+// untouched, which are reported by the contract verification
+func (client *StorageClient) updateContractRoots(contract *contractset.Contract, actions []storage.UploadAction, numSectors uint64) {
+	roots, err := contract.MerkleRoots()
+	if err != nil || uint64(len(roots)) != numSectors {
+		client.log.Debug("the merkle roots of the contract are not in sync with the revision", "numRoots", len(roots), "numSectors", numSectors, "err", err)
+		return
+	}
+	if roots, err = applyUploadActions(roots, actions); err == nil {
+		err = contract.UpdateMerkleRoots(roots)
+	}
+	if err != nil {
+		client.log.Warn("failed to update the merkle roots of the contract", "err", err)
 	}
 }
 
@@ -572,10 +689,7 @@ func (client *StorageClient) Read(sp storage.Peer, w io.Writer, req storage.Down
 	lastRevision := contractHeader.LatestContractRevision
 
 	// calculate price
-	bandwidthPrice := hostInfo.DownloadBandwidthPrice.MultUint64(estBandwidth)
-	sectorAccessPrice := hostInfo.SectorAccessPrice
-
-	price := hostInfo.BaseRPCPrice.Add(bandwidthPrice).Add(sectorAccessPrice)
+	price := downloadCost(hostInfo.HostExtConfig, estBandwidth)
 	if lastRevision.NewValidProofOutputs[0].Value.Cmp(price.BigIntPtr()) < 0 {
 		return errors.New("client funds not enough to support download")
 	}
@@ -633,9 +747,11 @@ func (client *StorageClient) Read(sp storage.Peer, w io.Writer, req storage.Down
 		if err == nil {
 			client.storageHostManager.IncrementSuccessfulInteractions(hostInfo.EnodeID)
 		}
+		client.storageHostManager.RecordHostQoS(hostInfo.EnodeID, sp.TakeQoSSample())
 	}()
 
 	// send download request
+	requestStart := time.Now()
 	err = sp.RequestContractDownload(req)
 	if err != nil {
 		return err
@@ -649,6 +765,7 @@ func (client *StorageClient) Read(sp storage.Peer, w io.Writer, req storage.Down
 	if err != nil {
 		return err
 	}
+	ttfb := time.Since(requestStart)
 
 	// meaning request was sent too frequently, the host's evaluation
 	// will not be degraded
@@ -662,7 +779,8 @@ func (client *StorageClient) Read(sp storage.Peer, w io.Writer, req storage.Down
 		return hostNegotiateErr
 	}
 
-	err = msg.Decode(&resp)
+	// the data larger than the requested length is rejected before read
+	resp, err = storage.DecodeDownloadResponse(msg.Payload, uint64(msg.Size), uint64(sector.Length))
 	if err != nil {
 		hostNegotiateErr = err
 		return err
@@ -683,6 +801,7 @@ func (client *StorageClient) Read(sp storage.Peer, w io.Writer, req storage.Down
 			if !verified || err != nil {
 				err = errors.New("host provided incorrect sector data or Merkle proof")
 				hostNegotiateErr = err
+				client.rotateSessionKey(sp)
 				return err
 			}
 		}
@@ -701,6 +820,9 @@ func (client *StorageClient) Read(sp storage.Peer, w io.Writer, req storage.Down
 			clientNegotiateErr = err
 			return err
 		}
+
+		// record the host download performance
+		client.hostLatency.record(hostInfo.EnodeID, uint64(sector.Length), ttfb, time.Since(requestStart))
 	}
 
 	newRevision.Signatures = [][]byte{clientSig, hostSig}
@@ -734,6 +856,11 @@ func (client *StorageClient) Read(sp storage.Peer, w io.Writer, req storage.Down
 
 	switch msg.Code {
 	case storage.HostAckMsg:
+		client.auditRevisionCharge(hostInfo.EnodeID, lastRevision, newRevision, func(config storage.HostExtConfig) common.BigInt {
+			return downloadCost(config, estBandwidth)
+		})
+		client.recordTransfer(storage.MetricDownloadBytes, uint64(sector.Length), price)
+		client.recordOperation(OperationDownload, hostInfo.EnodeID, lastRevision, newRevision, uint64(len(resp.Data)), sector.MerkleRoot)
 		return
 	default:
 		hostCommitErr = storage.ErrHostCommit
@@ -745,8 +872,9 @@ func (client *StorageClient) Read(sp storage.Peer, w io.Writer, req storage.Down
 	}
 }
 
-// Download requests for a single section and returns the requested data. A Merkle proof is always requested.
-func (client *StorageClient) Download(sp storage.Peer, root common.Hash, offset, length uint32, hostInfo *storage.HostInfo) ([]byte, error) {
+// Download requests for a single section and returns the requested data. The data is verified
+// with the Merkle proof if merkleProof is true.
+func (client *StorageClient) Download(sp storage.Peer, root common.Hash, offset, length uint32, merkleProof bool, hostInfo *storage.HostInfo) ([]byte, error) {
 	client.lock.Lock()
 	defer client.lock.Unlock()
 
@@ -756,7 +884,7 @@ func (client *StorageClient) Download(sp storage.Peer, root common.Hash, offset,
 			Offset:     offset,
 			Length:     length,
 		},
-		MerkleProof: true,
+		MerkleProof: merkleProof,
 	}
 	var buf bytes.Buffer
 	err := client.Read(sp, &buf, req, nil, hostInfo)
@@ -781,6 +909,9 @@ func (client *StorageClient) newDownload(params downloadParams) (*download, erro
 	if params.offset+params.length > params.file.FileSize() {
 		return nil, errors.New("download data out the boundary of the remote file")
 	}
+	if params.verifyRate < 0 || params.verifyRate > 1 {
+		return nil, fmt.Errorf("download verify rate %v out of range [0, 1]", params.verifyRate)
+	}
 
 	// instantiate the download object.
 	d := &download{
@@ -795,6 +926,7 @@ func (client *StorageClient) newDownload(params downloadParams) (*download, erro
 		overdrive:         params.overdrive,
 		dxFile:            params.file,
 		priority:          params.priority,
+		verifyRate:        params.verifyRate,
 		log:               client.log,
 		memoryManager:     client.memoryManager,
 	}
@@ -802,6 +934,10 @@ func (client *StorageClient) newDownload(params downloadParams) (*download, erro
 	// record the end time when it's done.
 	d.onComplete(func(_ error) error {
 		d.endTime = time.Now()
+		if d.sectorsSkipped != 0 {
+			d.log.Info("Download completed with sampled verification", "file", d.destinationString,
+				"verified", d.sectorsVerified, "skipped", d.sectorsSkipped)
+		}
 		return nil
 	})
 
@@ -901,7 +1037,7 @@ func (client *StorageClient) newDownload(params downloadParams) (*download, erro
 }
 
 // createDownload performs a file download and returns the download object
-func (client *StorageClient) createDownload(p storage.DownloadParameters) (*download, error) {
+func (client *StorageClient) createDownload(p storage.DownloadParameters) (d *download, err error) {
 	dxPath, err := storage.NewDxPath(p.RemoteFilePath)
 	if err != nil {
 		return nil, err
@@ -936,6 +1072,18 @@ func (client *StorageClient) createDownload(p storage.DownloadParameters) (*down
 		p.WriteToLocalPath = filepath.Join(usr.HomeDir, p.WriteToLocalPath)
 	}
 
+	// run the hooks registered for the download before the destination is truncated. The
+	// post hooks are called once the download is completed, or failed to be created
+	info := HookInfo{Operation: HookDownload, DxPath: dxPath.Path, LocalPath: p.WriteToLocalPath, FileSize: entry.FileSize()}
+	if err = client.hooks.runPreHooks(info); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			client.hooks.runPostHooks(info, err)
+		}
+	}()
+
 	// instantiate the file to write the downloaded data
 	var dw writeDestination
 	var destinationType string
@@ -946,12 +1094,17 @@ func (client *StorageClient) createDownload(p storage.DownloadParameters) (*down
 	dw = osFile
 	destinationType = "file"
 
+	overdrive, err := client.downloadOverdrive(p.Overdrive)
+	if err != nil {
+		return nil, err
+	}
+
 	// create the download object.
 	snap, err := entry.Snapshot()
 	if err != nil {
 		return nil, fmt.Errorf("cannot create snapshot: %v", err)
 	}
-	d, err := client.newDownload(downloadParams{
+	d, err = client.newDownload(downloadParams{
 		destination:       dw,
 		destinationType:   destinationType,
 		destinationString: p.WriteToLocalPath,
@@ -963,9 +1116,10 @@ func (client *StorageClient) createDownload(p storage.DownloadParameters) (*down
 		needsMemory: true,
 
 		// always download from 0
-		offset:    0,
-		overdrive: 3,
-		priority:  5,
+		offset:     0,
+		overdrive:  overdrive,
+		priority:   5,
+		verifyRate: p.VerifyRate,
 	})
 	if closer, ok := dw.(io.Closer); err != nil && ok {
 		closeErr := closer.Close()
@@ -985,6 +1139,10 @@ func (client *StorageClient) createDownload(p storage.DownloadParameters) (*down
 		}
 		return nil
 	})
+	d.onComplete(func(downloadErr error) error {
+		client.hooks.runPostHooks(info, downloadErr)
+		return nil
+	})
 
 	return d, nil
 }
@@ -61,7 +61,7 @@ func newFileEntry(t *testing.T, client *StorageClient) *dxfile.FileSetEntryWithI
 }
 
 func newStorageClientTester(t *testing.T) *StorageClientTester {
-	client, err := New(filepath.Join(homeDir(), "storageclient"))
+	client, err := New(filepath.Join(homeDir(), "storageclient"), "")
 	if err != nil {
 		return nil
 	}
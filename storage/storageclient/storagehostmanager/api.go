@@ -57,21 +57,23 @@ func (api *PublicStorageHostManagerAPI) StorageHost(id string) storage.HostInfo
 	return info
 }
 
+// Hosts will return the storage hosts information with the enode IDs provided. It is used
+// to retrieve many storage hosts at a time instead of calling StorageHost one by one
+func (api *PublicStorageHostManagerAPI) Hosts(ids []enode.ID) ([]storage.HostInfo, error) {
+	return api.shm.Hosts(ids)
+}
+
+// AllHosts will return a page of the storage hosts sorted by the evaluation. The filter
+// could be all, active, or filtered. If the limit is not positive, 100 storage hosts are
+// returned at most
+func (api *PublicStorageHostManagerAPI) AllHosts(offset, limit int, filter string) (HostsPage, error) {
+	return api.shm.HostsPage(offset, limit, filter)
+}
+
 // StorageHostRanks will return the storage host rankings based on their evaluations. The
 // higher the evaluation is, the higher order it will be placed
 func (api *PublicStorageHostManagerAPI) StorageHostRanks() (rankings []StorageHostRank) {
-	allHosts := api.shm.storageHostTree.All()
-	// based on the host information, calculate the evaluation
-	for _, host := range allHosts {
-		eval := api.shm.evalFunc(host)
-
-		rankings = append(rankings, StorageHostRank{
-			EvaluationDetail: eval.EvaluationDetail(eval.Evaluation(), false, false),
-			EnodeID:          host.EnodeID.String(),
-		})
-	}
-
-	return
+	return api.shm.StorageHostRanks()
 }
 
 // FilterMode will return the current storage host manager filter mode setting
@@ -0,0 +1,81 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file
+
+package storagehostmanager
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DxChainNetwork/godx/p2p/enode"
+)
+
+// Backup returns the JSON encoded state of the storage host manager, which contains the
+// same information as the persisted settings
+func (shm *StorageHostManager) Backup() ([]byte, error) {
+	shm.lock.RLock()
+	defer shm.lock.RUnlock()
+	return json.Marshal(shm.persistUpdate())
+}
+
+// ValidateBackup checks whether the storage host manager state encoded by Backup could be
+// decoded, without applying it
+func (shm *StorageHostManager) ValidateBackup(data []byte) error {
+	_, err := shm.decodeBackup(data)
+	return err
+}
+
+// Restore applies the storage host manager state encoded by Backup. The storage host
+// information and the price audits are merged into the current state. The block height
+// of the current node is kept, and the filter settings are expected to be applied through
+// the client profile
+func (shm *StorageHostManager) Restore(data []byte) error {
+	persist, err := shm.decodeBackup(data)
+	if err != nil {
+		return err
+	}
+
+	shm.lock.Lock()
+	shm.ipViolationCheck = persist.IPViolationCheck
+	shm.ipChangeGracePeriod = persist.IPChangeGracePeriod
+	shm.priceAuditBlacklist = persist.PriceAuditBlacklist
+	if shm.priceAudits == nil {
+		shm.priceAudits = make(map[enode.ID]*HostPriceAudit)
+	}
+	for id, audit := range persist.PriceAudits {
+		shm.priceAudits[id] = audit
+	}
+	shm.lock.Unlock()
+
+	for _, info := range persist.StorageHostsInfo {
+		if _, exists := shm.storageHostTree.RetrieveHostInfo(info.EnodeID); exists {
+			shm.lock.Lock()
+			err := shm.modify(info)
+			shm.lock.Unlock()
+			if err != nil {
+				shm.log.Error("failed to update storage host information from the backup", "id", info.EnodeID, "err", err.Error())
+			}
+			continue
+		}
+		if err := shm.insert(info); err != nil {
+			shm.log.Error("failed to insert storage host information from the backup", "id", info.EnodeID, "err", err.Error())
+			continue
+		}
+		shm.scanValidation(info)
+	}
+
+	shm.lock.Lock()
+	defer shm.lock.Unlock()
+	return shm.saveSettings()
+}
+
+// decodeBackup decodes the storage host manager state encoded by Backup. The ip change grace
+// period of the current state is kept if it is not in the backup
+func (shm *StorageHostManager) decodeBackup(data []byte) (persistence, error) {
+	persist := persistence{IPChangeGracePeriod: shm.RetrieveIPChangeGracePeriod()}
+	if err := json.Unmarshal(data, &persist); err != nil {
+		return persistence{}, fmt.Errorf("failed to decode the storage host manager backup: %s", err.Error())
+	}
+	return persist, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/DxChainNetwork/godx/accounts"
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+type (
+	// BootstrapSnapshot is the signed list of the well known storage hosts. A brand new storage
+	// client loads the snapshot to form its first contracts before the initial scan of the host
+	// pool finished, which requires the block chain to be fully synced
+	BootstrapSnapshot struct {
+		Signer    common.Address  `json:"signer"`
+		Timestamp int64           `json:"timestamp"`
+		Hosts     []BootstrapHost `json:"hosts"`
+		Signature []byte          `json:"signature"`
+	}
+
+	// BootstrapHost is the storage host included in the bootstrap snapshot, along with the
+	// settings of the host when the snapshot was made
+	BootstrapHost struct {
+		EnodeURL string                `json:"enodeurl"`
+		Config   storage.HostExtConfig `json:"config"`
+	}
+)
+
+// hash returns the hash of the bootstrap snapshot signed by the signer
+func (s BootstrapSnapshot) hash() (common.Hash, error) {
+	data, err := json.Marshal(BootstrapSnapshot{
+		Signer:    s.Signer,
+		Timestamp: s.Timestamp,
+		Hosts:     s.Hosts,
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(data), nil
+}
+
+// verify checks if the bootstrap snapshot is signed by the trusted signer, and is not
+// older than bootstrapSnapshotMaxAge
+func (s BootstrapSnapshot) verify(trusted common.Address, now time.Time) error {
+	if s.Signer != trusted {
+		return fmt.Errorf("the snapshot is signed by %v, expected %v", s.Signer.String(), trusted.String())
+	}
+	hash, err := s.hash()
+	if err != nil {
+		return err
+	}
+	pub, err := crypto.SigToPub(hash.Bytes(), s.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot signature: %s", err.Error())
+	}
+	if crypto.PubkeyToAddress(*pub) != s.Signer {
+		return errors.New("the snapshot signature does not match the signer")
+	}
+	if now.Sub(time.Unix(s.Timestamp, 0)) > bootstrapSnapshotMaxAge {
+		return fmt.Errorf("the snapshot made at %v is outdated", time.Unix(s.Timestamp, 0))
+	}
+	return nil
+}
+
+// LoadBootstrapSnapshot verifies the JSON encoded bootstrap snapshot against the trusted
+// signer, and inserts the storage hosts unknown to the storage host manager. The storage
+// hosts are scanned at once without waiting for the block chain to be synced, and the
+// host selection is allowed before the initial scan finished. The settings of the storage
+// hosts are overridden by the scans, and the bootstrap flag is cleared once the host
+// announcements are found on the block chain. It returns the number of hosts inserted
+func (shm *StorageHostManager) LoadBootstrapSnapshot(data []byte, trusted common.Address) (int, error) {
+	var snapshot BootstrapSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return 0, fmt.Errorf("failed to decode the bootstrap snapshot: %s", err.Error())
+	}
+	if err := snapshot.verify(trusted, time.Now()); err != nil {
+		return 0, fmt.Errorf("failed to verify the bootstrap snapshot: %s", err.Error())
+	}
+
+	inserted := shm.insertBootstrapHosts(snapshot.Hosts)
+	for _, info := range inserted {
+		shm.scanValidation(info)
+	}
+
+	shm.lock.Lock()
+	defer shm.lock.Unlock()
+	return len(inserted), shm.saveSettings()
+}
+
+// insertBootstrapHosts inserts the bootstrap hosts not known by the storage host manager,
+// and returns the storage host information inserted. The storage hosts already known are
+// not touched, the information of which is more reliable than the snapshot
+func (shm *StorageHostManager) insertBootstrapHosts(hosts []BootstrapHost) (inserted []storage.HostInfo) {
+	shm.lock.RLock()
+	height := shm.blockHeight
+	shm.lock.RUnlock()
+
+	for _, host := range hosts {
+		info, err := parseHostAnnouncement(types.HostAnnouncement{NetAddress: host.EnodeURL})
+		if err != nil {
+			shm.log.Warn("failed to parse the bootstrap host", "url", host.EnodeURL, "err", err.Error())
+			continue
+		}
+		if info.EnodeURL == shm.b.SelfEnodeURL() {
+			continue
+		}
+		if _, exists := shm.storageHostTree.RetrieveHostInfo(info.EnodeID); exists {
+			continue
+		}
+		info.HostExtConfig = host.Config
+		info.FirstSeen = height
+		info.Bootstrap = true
+		if err := shm.insert(info); err != nil {
+			shm.log.Warn("failed to insert the bootstrap host", "id", info.EnodeID, "err", err.Error())
+			continue
+		}
+		inserted = append(inserted, info)
+	}
+	return
+}
+
+// ExportBootstrapSnapshot makes the bootstrap snapshot from the active storage hosts with the
+// highest evaluations, signed by the account of the signer
+func (shm *StorageHostManager) ExportBootstrapSnapshot(signer common.Address, num int) ([]byte, error) {
+	if num <= 0 {
+		return nil, errors.New("the number of storage hosts must be positive")
+	}
+	hosts := shm.ActiveStorageHosts()
+	sort.Slice(hosts, func(i, j int) bool {
+		return shm.evalFunc(hosts[i]).Evaluation().Cmp(shm.evalFunc(hosts[j]).Evaluation()) > 0
+	})
+	if len(hosts) > num {
+		hosts = hosts[:num]
+	}
+
+	snapshot := BootstrapSnapshot{
+		Signer:    signer,
+		Timestamp: time.Now().Unix(),
+	}
+	for _, info := range hosts {
+		snapshot.Hosts = append(snapshot.Hosts, BootstrapHost{EnodeURL: info.EnodeURL, Config: info.HostExtConfig})
+	}
+	hash, err := snapshot.hash()
+	if err != nil {
+		return nil, err
+	}
+
+	account := accounts.Account{Address: signer}
+	wallet, err := shm.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the signer account: %s", err.Error())
+	}
+	if snapshot.Signature, err = wallet.SignHash(account, hash.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to sign the bootstrap snapshot: %s", err.Error())
+	}
+	return json.MarshalIndent(snapshot, "", "  ")
+}
+
+// bootstrapped checks if there are storage hosts loaded from the bootstrap snapshot, with
+// which the contracts could be formed before the initial scan finished
+func (shm *StorageHostManager) bootstrapped() bool {
+	for _, info := range shm.storageHostTree.All() {
+		if info.Bootstrap {
+			return true
+		}
+	}
+	return false
+}
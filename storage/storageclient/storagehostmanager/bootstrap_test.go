@@ -0,0 +1,131 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// newBootstrapHost creates the bootstrap host with a random enode URL
+func newBootstrapHost(t *testing.T) BootstrapHost {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := enode.NewV4(&key.PublicKey, net.IP{10, 0, 0, 1}, 36000, 36000)
+	return BootstrapHost{
+		EnodeURL: node.String(),
+		Config: storage.HostExtConfig{
+			AcceptingContracts: true,
+			Deposit:            common.NewBigInt(100),
+			RemainingStorage:   100,
+		},
+	}
+}
+
+func TestBootstrapSnapshot_Verify(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	now := time.Now()
+	snapshot := BootstrapSnapshot{
+		Signer:    signer,
+		Timestamp: now.Unix(),
+		Hosts:     []BootstrapHost{newBootstrapHost(t), newBootstrapHost(t)},
+	}
+	hash, err := snapshot.hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Signature, err = crypto.Sign(hash.Bytes(), key); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = snapshot.verify(signer, now); err != nil {
+		t.Fatalf("the snapshot should be verified: %v", err)
+	}
+	if err = snapshot.verify(common.Address{1}, now); err == nil {
+		t.Errorf("the snapshot signed by an untrusted signer should not be verified")
+	}
+	if err = snapshot.verify(signer, now.Add(bootstrapSnapshotMaxAge+time.Hour)); err == nil {
+		t.Errorf("the outdated snapshot should not be verified")
+	}
+
+	tampered := snapshot
+	tampered.Hosts = snapshot.Hosts[:1]
+	if err = tampered.verify(signer, now); err == nil {
+		t.Errorf("the tampered snapshot should not be verified")
+	}
+}
+
+func TestStorageHostManager_InsertBootstrapHosts(t *testing.T) {
+	shm := newHostManagerTestData()
+	hosts := []BootstrapHost{newBootstrapHost(t), newBootstrapHost(t), {EnodeURL: "invalid"}}
+
+	// the known storage host is not overridden by the snapshot
+	known, err := parseHostAnnouncement(types.HostAnnouncement{NetAddress: hosts[0].EnodeURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = shm.insert(known); err != nil {
+		t.Fatal(err)
+	}
+
+	inserted := shm.insertBootstrapHosts(hosts)
+	if len(inserted) != 1 {
+		t.Fatalf("expect 1 storage host inserted, got %v", len(inserted))
+	}
+	info, exists := shm.storageHostTree.RetrieveHostInfo(inserted[0].EnodeID)
+	if !exists || !info.Bootstrap || !info.AcceptingContracts {
+		t.Fatalf("the bootstrap host is not inserted with the snapshot settings: %+v", info)
+	}
+	if factor := shm.presenceFactorCalc(info); factor != bootstrapPresenceFactor {
+		t.Errorf("expect the presence factor %v, got %v", bootstrapPresenceFactor, factor)
+	}
+	if info, _ = shm.storageHostTree.RetrieveHostInfo(known.EnodeID); info.Bootstrap {
+		t.Errorf("the known storage host should not be marked as bootstrap host")
+	}
+
+	// the announcement found on the block chain clears the bootstrap flag
+	shm.insertStorageHostInformation(inserted[0])
+	if info, _ = shm.storageHostTree.RetrieveHostInfo(inserted[0].EnodeID); info.Bootstrap {
+		t.Errorf("the bootstrap flag should be cleared once the host is announced")
+	}
+}
+
+func TestStorageHostManager_RetrieveRandomHostsBootstrapped(t *testing.T) {
+	shm := newHostManagerTestData()
+	if _, err := shm.RetrieveRandomHosts(1, nil, nil); err == nil {
+		t.Fatalf("the storage hosts should not be selected before the initial scan")
+	}
+
+	inserted := shm.insertBootstrapHosts([]BootstrapHost{newBootstrapHost(t)})
+	if len(inserted) != 1 {
+		t.Fatalf("expect 1 storage host inserted, got %v", len(inserted))
+	}
+	info := inserted[0]
+	info.ScanRecords = storage.HostPoolScans{{Timestamp: time.Now(), Success: true}}
+	if err := shm.modify(info); err != nil {
+		t.Fatal(err)
+	}
+
+	selected, err := shm.RetrieveRandomHosts(1, nil, nil)
+	if err != nil {
+		t.Fatalf("the bootstrap host should be selected before the initial scan: %v", err)
+	}
+	if len(selected) != 1 || selected[0].EnodeID != info.EnodeID {
+		t.Errorf("expect the bootstrap host to be selected, got %v", selected)
+	}
+}
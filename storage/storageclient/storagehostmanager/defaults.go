@@ -18,6 +18,21 @@ const (
 	minStorage                = uint64(20e9)
 )
 
+// Those values are used to calculate the storage host evaluation from the negotiation quality
+const (
+	qosFailureSmoothing         = float64(5)
+	qosFailureExponentiation    = 4
+	qosRTTCutoff                = 2 * time.Second
+	qosRTTExponentiation        = 0.5
+	qosThroughputFloor          = float64(1 << 20)
+	qosThroughputExponentiation = 0.5
+
+	// qosDecay is the decay applied to the aggregated counts before a new sample is merged,
+	// and qosAverageWeight is the weight of the new sample in the averaged rtt and throughput
+	qosDecay         = 0.95
+	qosAverageWeight = 0.2
+)
+
 // StorageHostManager related constant
 const (
 	saveFrequency                    = 2 * time.Minute
@@ -38,6 +53,9 @@ const (
 	minScans          = 12
 
 	maxDowntime = 10 * 24 * time.Hour
+
+	// maxExplainScanRecords is the number of latest scan records included in the host explanation
+	maxExplainScanRecords = 10
 )
 
 // historical interaction with host related constants
@@ -46,3 +64,27 @@ const (
 	historicInteractionDecayLimit = 500
 	recentInteractionWeightLimit  = 0.01
 )
+
+// ip network change related constants
+const (
+	// defaultIPChangeGracePeriod is the default period after a known storage host changed its
+	// ip network, within which the host is not checked for ip violation
+	defaultIPChangeGracePeriod = 24 * time.Hour
+
+	// maxIPNetworkChanges is the number of latest ip network changes kept in the host info
+	maxIPNetworkChanges = 10
+)
+
+// bootstrap snapshot related constants
+const (
+	// bootstrapSnapshotMaxAge is the max age of the bootstrap snapshot to be loaded
+	bootstrapSnapshotMaxAge = 30 * 24 * time.Hour
+
+	// bootstrapPresenceFactor is the conservative presence factor of the bootstrap hosts,
+	// whose announcements are not found on the block chain yet
+	bootstrapPresenceFactor = float64(1) / 36
+)
+
+// priceDiscrepancyBlacklistLimit is the number of revisions charging more than advertised
+// before the storage host is blacklisted, if the price audit blacklist is enabled
+const priceDiscrepancyBlacklistLimit = 3
@@ -23,6 +23,9 @@ func (shm *StorageHostManager) calculateEvaluationFunc(rent storage.RentPayment)
 			ContractPriceFactor:    shm.contractPriceFactorCalc(info, rent),
 			StorageRemainingFactor: shm.storageRemainingFactorCalc(info),
 			UptimeFactor:           shm.uptimeFactorCalc(info),
+			QoSFactor:              shm.qosFactorCalc(info),
+			DepositReference:       depositFactorReference(rent),
+			ContractPriceReference: contractPriceFactorReference(rent),
 		}
 	}
 }
@@ -32,6 +35,11 @@ func (shm *StorageHostManager) calculateEvaluationFunc(rent storage.RentPayment)
 func (shm *StorageHostManager) presenceFactorCalc(info storage.HostInfo) float64 {
 	var base float64 = 1
 
+	// the first seen height of the bootstrap host is not reliable
+	if info.Bootstrap {
+		return bootstrapPresenceFactor
+	}
+
 	switch presence := shm.blockHeight - info.FirstSeen; {
 	case presence < 0:
 		return base
@@ -96,6 +104,16 @@ func (shm *StorageHostManager) depositFactorCalc(info storage.HostInfo, rent sto
 	return smallWeight * largeWeight
 }
 
+// depositFactorReference returns the deposit factor of the storage host providing exactly
+// the deposit floor of the expected contract fund, which is normalized to the score 0.5
+func depositFactorReference(rent storage.RentPayment) float64 {
+	cutoff := rent.Fund.DivUint64(rent.StorageHosts).MultFloat64(depositFloor).Float64()
+	if cutoff < 1 {
+		cutoff = 1
+	}
+	return math.Pow(cutoff, depositExponentialSmall)
+}
+
 // interactionFactorCalc calculates the factor value based on the historical success interactions
 // and failed interactions. More success interactions will cause higher evaluation
 func (shm *StorageHostManager) interactionFactorCalc(info storage.HostInfo) float64 {
@@ -150,6 +168,16 @@ func (shm *StorageHostManager) contractPriceFactorCalc(info storage.HostInfo, re
 	return 1 / (smallWeight * largeWeight)
 }
 
+// contractPriceFactorReference returns the contract price factor of the storage host charging
+// exactly the price floor of the expected contract fund, which is normalized to the score 0.5
+func contractPriceFactorReference(rent storage.RentPayment) float64 {
+	cutoff := rent.Fund.DivUint64(rent.StorageHosts).MultFloat64(priceFloor).Float64()
+	if cutoff < 1 {
+		cutoff = 1
+	}
+	return 1 / math.Pow(cutoff, priceExponentiationSmall)
+}
+
 // storageRemainingFactorCalc calculates the factor value based on the storage remaining, the more storage
 // space the storage host remained, higher evaluation it will got
 func (shm *StorageHostManager) storageRemainingFactorCalc(info storage.HostInfo) float64 {
@@ -250,6 +278,29 @@ func (shm *StorageHostManager) uptimeEvaluation(info storage.HostInfo) float64 {
 	return math.Pow(uptimeRatio, exp)
 }
 
+// qosFactorCalc calculates the factor value based on the quality of the negotiations experienced
+// with the storage host. The host timed out or failed to receive the messages frequently, responding
+// slowly, or transferring the data slowly gets lower evaluation. The host never negotiated with is
+// not punished, its evaluation is left to the other factors
+func (shm *StorageHostManager) qosFactorCalc(info storage.HostInfo) float64 {
+	qos := info.QoS
+	failures := qos.Timeouts + qos.SendFailures
+	if qos.Responses+failures == 0 {
+		return 1
+	}
+
+	ratio := (qos.Responses + qosFailureSmoothing) / (qos.Responses + failures + qosFailureSmoothing)
+	factor := math.Pow(ratio, qosFailureExponentiation)
+
+	if qos.RTT > qosRTTCutoff {
+		factor *= math.Pow(float64(qosRTTCutoff)/float64(qos.RTT), qosRTTExponentiation)
+	}
+	if qos.Throughput > 0 && qos.Throughput < qosThroughputFloor {
+		factor *= math.Pow(qos.Throughput/qosThroughputFloor, qosThroughputExponentiation)
+	}
+	return factor
+}
+
 // rentPaymentValidation will validate the rent payment provided by the storage client
 // eliminate any zero values by changing them to one
 func rentPaymentValidation(rent storage.RentPayment) {
@@ -0,0 +1,181 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/storagehosttree"
+)
+
+// HostExplanation explains why a storage host is or is not chosen by the storage client
+// when forming contracts
+type HostExplanation struct {
+	EnodeID    string                           `json:"enodeid"`
+	Evaluation storagehosttree.EvaluationDetail `json:"evaluation"`
+
+	// filter status
+	FilterMode     string `json:"filtermode"`
+	InFilteredList bool   `json:"infilteredlist"`
+	Selectable     bool   `json:"selectable"`
+
+	// ip violation status
+	IPViolationCheck bool   `json:"ipviolationcheck"`
+	IPViolation      bool   `json:"ipviolation"`
+	IPViolationWith  string `json:"ipviolationwith,omitempty"`
+
+	// PriceBlacklisted is whether the host is blacklisted for charging more than advertised
+	PriceBlacklisted bool `json:"priceblacklisted"`
+
+	// SelectorFiltered is whether the host is filtered out by the host selector, and
+	// SelectorScore is the score given by the host selector otherwise
+	SelectorFiltered bool          `json:"selectorfiltered"`
+	SelectorScore    common.BigInt `json:"selectorscore"`
+
+	AcceptingContracts bool                  `json:"acceptingcontracts"`
+	RecentScans        storage.HostPoolScans `json:"recentscans"`
+
+	// the probability of the host being picked in a single draw of the latest selection
+	SelectionWeight   float64   `json:"selectionweight"`
+	LastSelectionTime time.Time `json:"lastselectiontime"`
+
+	Reasons []string `json:"reasons"`
+}
+
+// ExplainHost collects the evaluation breakdown, filter status, ip violation status, and
+// the recent scan records of the storage host, which helps to figure out why the host is
+// (not) chosen to form contracts with. The host is checked with the same predicates as
+// RetrieveRandomHosts, including the price blacklist and the registered host selector
+func (shm *StorageHostManager) ExplainHost(id enode.ID) (exp HostExplanation, err error) {
+	host, exists := shm.storageHostTree.RetrieveHostInfo(id)
+	if !exists {
+		err = fmt.Errorf("the storage host %v does not exist in the storage host pool", id)
+		return
+	}
+
+	shm.lock.RLock()
+	filterMode := shm.filterMode
+	_, inFilteredList := shm.filteredHosts[id]
+	ipCheck := shm.ipViolationCheck
+	ipChangeGrace := shm.ipChangeGracePeriod
+	initialScan := shm.initialScan
+	selectionEval := shm.lastSelectionEval
+	selectionTime := shm.lastSelectionTime
+	filteredTree := shm.filteredTree
+	selector, evalFunc := shm.selector, shm.evalFunc
+	eval := shm.evalFunc(host)
+	shm.lock.RUnlock()
+
+	_, selectable := filteredTree.RetrieveHostInfo(id)
+	score, candidate := candidateScore(selector, evalFunc, host)
+	selectionOpen := initialScan || shm.bootstrapped()
+
+	exp = HostExplanation{
+		EnodeID:            id.String(),
+		FilterMode:         filterMode.String(),
+		InFilteredList:     inFilteredList,
+		Selectable:         selectable,
+		IPViolationCheck:   ipCheck,
+		PriceBlacklisted:   shm.PriceBlacklisted(id),
+		SelectorFiltered:   !selector.Filter(host),
+		SelectorScore:      score,
+		AcceptingContracts: host.AcceptingContracts,
+		RecentScans:        recentScans(host.ScanRecords),
+		LastSelectionTime:  selectionTime,
+	}
+
+	// if no selection has been made yet, the current evaluation base will be used
+	if selectionTime.IsZero() {
+		selectionEval = filteredTree.EvaluationTotal()
+	}
+	exp.Evaluation = eval.EvaluationDetail(selectionEval, false, false)
+	if selectable && candidate && !exp.PriceBlacklisted {
+		exp.SelectionWeight = exp.Evaluation.Evaluation.DivWithFloatResult(selectionEval)
+	}
+
+	// ip violation check
+	if ipCheck {
+		if violated, ok := shm.ipViolatedBy(host, ipChangeGrace); ok {
+			exp.IPViolation = true
+			exp.IPViolationWith = violated.String()
+		}
+	}
+
+	exp.Reasons = explainReasons(exp, host, selectionOpen)
+	return
+}
+
+// ipViolatedBy checks if there is another storage host located under the same ip network,
+// whose ip network changed earlier than the host. If so, the host will be treated as bad
+// host once the ip violation check is enabled. The storage hosts in the ip change grace
+// period are not taken into account
+func (shm *StorageHostManager) ipViolatedBy(host storage.HostInfo, grace time.Duration) (id enode.ID, violated bool) {
+	now := time.Now()
+	if inIPChangeGrace(host, grace, now) {
+		return
+	}
+	for _, other := range shm.storageHostTree.All() {
+		if other.EnodeID == host.EnodeID || !other.LastIPNetWorkChange.Before(host.LastIPNetWorkChange) {
+			continue
+		}
+		if inIPChangeGrace(other, grace, now) {
+			continue
+		}
+		ipFilter := storagehosttree.NewFilter()
+		ipFilter.Add(other.IP)
+		if ipFilter.Filtered(host.IP) {
+			return other.EnodeID, true
+		}
+	}
+	return
+}
+
+// recentScans returns at most maxExplainScanRecords latest scan records
+func recentScans(scans storage.HostPoolScans) storage.HostPoolScans {
+	if len(scans) > maxExplainScanRecords {
+		scans = scans[len(scans)-maxExplainScanRecords:]
+	}
+	return append(storage.HostPoolScans{}, scans...)
+}
+
+// explainReasons lists the human readable reasons that stop the storage host from being
+// chosen. Empty list means the host could be chosen. The selection is open once the initial
+// scan finished, or the bootstrap snapshot is loaded
+func explainReasons(exp HostExplanation, host storage.HostInfo, selectionOpen bool) (reasons []string) {
+	if !selectionOpen {
+		reasons = append(reasons, "the initial scan of the storage host pool is not finished")
+	}
+	if !exp.Selectable {
+		if exp.InFilteredList {
+			reasons = append(reasons, "the host is blacklisted")
+		} else {
+			reasons = append(reasons, "the host is not whitelisted")
+		}
+	}
+	if exp.PriceBlacklisted {
+		reasons = append(reasons, "the host is blacklisted for charging more than advertised")
+	}
+	if exp.SelectorFiltered {
+		reasons = append(reasons, "the host is filtered out by the host selector")
+		if !host.AcceptingContracts {
+			reasons = append(reasons, "the host is not accepting contracts")
+		}
+		if len(host.ScanRecords) == 0 {
+			reasons = append(reasons, "the host has not been scanned yet")
+		} else if !host.ScanRecords[len(host.ScanRecords)-1].Success {
+			reasons = append(reasons, "the latest scan of the host failed")
+		}
+	} else if exp.SelectorScore.Sign() <= 0 {
+		reasons = append(reasons, "the host is scored non-positive by the host selector")
+	}
+	if exp.IPViolation {
+		reasons = append(reasons, fmt.Sprintf("the host is under the same ip network as host %s", exp.IPViolationWith))
+	}
+	return
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+)
+
+func TestStorageHostManager_ExplainHost(t *testing.T) {
+	shm := New("test")
+	shm.initialScan = true
+
+	host := activeHostInfoGenerator()
+	if err := shm.insert(host); err != nil {
+		t.Fatalf("insert failed: %s", err.Error())
+	}
+	other := activeHostInfoGenerator()
+	if err := shm.insert(other); err != nil {
+		t.Fatalf("insert failed: %s", err.Error())
+	}
+
+	// eligible host
+	exp, err := shm.ExplainHost(host.EnodeID)
+	if err != nil {
+		t.Fatalf("failed to explain the host: %s", err.Error())
+	}
+	if !exp.Selectable || len(exp.Reasons) != 0 {
+		t.Fatalf("the host should be eligible, got reasons %v", exp.Reasons)
+	}
+	if exp.SelectionWeight <= 0 || exp.SelectionWeight > 1 {
+		t.Errorf("selection weight should be within (0, 1], got %v", exp.SelectionWeight)
+	}
+	if len(exp.RecentScans) != len(host.ScanRecords) {
+		t.Errorf("recent scans not match. Got %v, expect %v", len(exp.RecentScans), len(host.ScanRecords))
+	}
+
+	// blacklisted host
+	if err := shm.SetFilterMode(BlacklistFilter, []enode.ID{host.EnodeID}); err != nil {
+		t.Fatalf("failed to set the filter mode: %s", err.Error())
+	}
+	if exp, err = shm.ExplainHost(host.EnodeID); err != nil {
+		t.Fatalf("failed to explain the host: %s", err.Error())
+	}
+	if exp.Selectable || !exp.InFilteredList || exp.SelectionWeight != 0 || len(exp.Reasons) != 1 {
+		t.Errorf("the blacklisted host should not be selectable, got %+v", exp)
+	}
+
+	// the host filtered out by the host selector
+	shm.SetHostSelector(&partnerSelector{partners: map[enode.ID]struct{}{}})
+	if exp, err = shm.ExplainHost(other.EnodeID); err != nil {
+		t.Fatalf("failed to explain the host: %s", err.Error())
+	}
+	if !exp.Selectable || !exp.SelectorFiltered || exp.SelectionWeight != 0 || len(exp.Reasons) != 1 {
+		t.Errorf("the host filtered out by the selector should not be chosen, got %+v", exp)
+	}
+	shm.SetHostSelector(nil)
+
+	// the host blacklisted for overcharging
+	shm.RecordAdvertisedPrices(other.EnodeID, other.HostExtConfig)
+	shm.SetPriceAuditBlacklist(true)
+	for i := 0; i < priceDiscrepancyBlacklistLimit; i++ {
+		shm.AuditRevisionCharge(other.EnodeID, common.NewBigInt(110), common.NewBigInt(100))
+	}
+	if exp, err = shm.ExplainHost(other.EnodeID); err != nil {
+		t.Fatalf("failed to explain the host: %s", err.Error())
+	}
+	if !exp.Selectable || !exp.PriceBlacklisted || exp.SelectionWeight != 0 || len(exp.Reasons) != 1 {
+		t.Errorf("the host blacklisted for overcharging should not be chosen, got %+v", exp)
+	}
+	shm.SetPriceAuditBlacklist(false)
+
+	// the selection is closed before the initial scan finished, unless bootstrapped
+	shm.initialScan = false
+	if exp, err = shm.ExplainHost(other.EnodeID); err != nil {
+		t.Fatalf("failed to explain the host: %s", err.Error())
+	}
+	if len(exp.Reasons) != 1 {
+		t.Errorf("the host should not be chosen before the initial scan finished, got reasons %v", exp.Reasons)
+	}
+	other.Bootstrap = true
+	if err := shm.modify(other); err != nil {
+		t.Fatalf("modify failed: %s", err.Error())
+	}
+	if exp, err = shm.ExplainHost(other.EnodeID); err != nil {
+		t.Fatalf("failed to explain the host: %s", err.Error())
+	}
+	if len(exp.Reasons) != 0 {
+		t.Errorf("the host should be chosen once bootstrapped, got reasons %v", exp.Reasons)
+	}
+	shm.initialScan = true
+
+	// ip violation
+	violated := hostInfoGeneratorIPID("104.238.46.129", enodeIDGenerator(), time.Now())
+	earlier := hostInfoGeneratorIPID("104.238.46.130", enodeIDGenerator(), time.Now().Add(-time.Hour))
+	if err := shm.insert(violated); err != nil {
+		t.Fatalf("insert failed: %s", err.Error())
+	}
+	if err := shm.insert(earlier); err != nil {
+		t.Fatalf("insert failed: %s", err.Error())
+	}
+	shm.SetIPViolationCheck(true)
+	if exp, err = shm.ExplainHost(violated.EnodeID); err != nil {
+		t.Fatalf("failed to explain the host: %s", err.Error())
+	}
+	if !exp.IPViolation || exp.IPViolationWith != earlier.EnodeID.String() {
+		t.Errorf("the host should violate the ip check with %v, got %+v", earlier.EnodeID, exp)
+	}
+
+	// non-existing host
+	if _, err = shm.ExplainHost(enodeIDGenerator()); err == nil {
+		t.Errorf("explaining a non-existing host should return an error")
+	}
+}
@@ -33,6 +33,17 @@ func (shm *StorageHostManager) RetrieveFilterMode() (fm string) {
 	return shm.filterMode.String()
 }
 
+// RetrieveFilteredHosts will return the list of storage host ids that are used by the
+// current filter mode, either as whitelist or blacklist
+func (shm *StorageHostManager) RetrieveFilteredHosts() (ids []enode.ID) {
+	shm.lock.RLock()
+	defer shm.lock.RUnlock()
+	for id := range shm.filteredHosts {
+		ids = append(ids, id)
+	}
+	return
+}
+
 // SetFilterMode will be used to set the host ip filter mode. Actions are required only
 // when the mode is set to be whitelist, meaning that only the storage host in both whitelist
 // and hostPool can be inserted into the filteredTree
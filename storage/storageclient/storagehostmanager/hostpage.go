@@ -0,0 +1,109 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"fmt"
+
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// the filters of the storage hosts retrieved by pages
+const (
+	HostsFilterAll      = "all"
+	HostsFilterActive   = "active"
+	HostsFilterFiltered = "filtered"
+)
+
+const (
+	// defaultHostsPageLimit is the number of storage hosts in a page if the limit is not specified
+	defaultHostsPageLimit = 100
+
+	// maxHostsPageLimit is the max number of storage hosts in a page
+	maxHostsPageLimit = 1000
+
+	// maxHostsBatch is the max number of storage hosts retrieved by enode IDs at a time
+	maxHostsBatch = 1000
+)
+
+// HostsPage is a page of the storage hosts sorted by the evaluation
+type HostsPage struct {
+	Total  int                `json:"total"`
+	Offset int                `json:"offset"`
+	Limit  int                `json:"limit"`
+	Hosts  []storage.HostInfo `json:"hosts"`
+}
+
+// isActiveHost checks if the storage host is accepting contracts and online in the latest scan
+func isActiveHost(info storage.HostInfo) bool {
+	numScanRecords := len(info.ScanRecords)
+	return numScanRecords != 0 && info.ScanRecords[numScanRecords-1].Success && info.AcceptingContracts
+}
+
+// Hosts will return the storage hosts with the enode IDs. The enode IDs not found in the
+// storage host pool are ignored
+func (shm *StorageHostManager) Hosts(ids []enode.ID) ([]storage.HostInfo, error) {
+	if len(ids) > maxHostsBatch {
+		return nil, fmt.Errorf("at most %v storage hosts could be retrieved at a time, got %v", maxHostsBatch, len(ids))
+	}
+	infos := make([]storage.HostInfo, 0, len(ids))
+	for _, id := range ids {
+		if info, exists := shm.storageHostTree.RetrieveHostInfo(id); exists {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+// HostsPage will return the storage hosts matching the filter from the offset. The storage hosts
+// are sorted by the evaluation, and at most limit storage hosts are returned
+func (shm *StorageHostManager) HostsPage(offset, limit int, filter string) (page HostsPage, err error) {
+	if offset < 0 {
+		return HostsPage{}, fmt.Errorf("the offset cannot be negative: %v", offset)
+	}
+	if limit <= 0 {
+		limit = defaultHostsPageLimit
+	}
+	if limit > maxHostsPageLimit {
+		return HostsPage{}, fmt.Errorf("at most %v storage hosts could be retrieved in a page, got %v", maxHostsPageLimit, limit)
+	}
+
+	var hosts []storage.HostInfo
+	switch filter {
+	case "", HostsFilterAll:
+		hosts = shm.storageHostTree.All()
+	case HostsFilterActive:
+		for _, info := range shm.storageHostTree.All() {
+			if isActiveHost(info) {
+				hosts = append(hosts, info)
+			}
+		}
+	case HostsFilterFiltered:
+		shm.lock.RLock()
+		tree := shm.filteredTree
+		shm.lock.RUnlock()
+		hosts = tree.All()
+	default:
+		return HostsPage{}, fmt.Errorf("unknown filter %s, expect %s, %s, or %s", filter,
+			HostsFilterAll, HostsFilterActive, HostsFilterFiltered)
+	}
+
+	page = HostsPage{
+		Total:  len(hosts),
+		Offset: offset,
+		Limit:  limit,
+		Hosts:  []storage.HostInfo{},
+	}
+	if offset >= len(hosts) {
+		return
+	}
+	end := offset + limit
+	if end > len(hosts) {
+		end = len(hosts)
+	}
+	page.Hosts = hosts[offset:end]
+	return
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"testing"
+
+	"github.com/DxChainNetwork/godx/p2p/enode"
+)
+
+func TestStorageHostManager_HostsPage(t *testing.T) {
+	shm := New("test")
+	for i := 0; i < 25; i++ {
+		info := activeHostInfoGenerator()
+		if i%5 == 0 {
+			info.AcceptingContracts = false
+		}
+		if err := shm.insert(info); err != nil {
+			t.Fatalf("failed to insert the host information into the tree: %s", err.Error())
+		}
+	}
+	all := shm.AllHosts()
+
+	// the pages of all storage hosts follow the order of the evaluation
+	var paged []enode.ID
+	for offset := 0; offset < 30; offset += 10 {
+		page, err := shm.HostsPage(offset, 10, HostsFilterAll)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 25 {
+			t.Fatalf("expect total 25, got %v", page.Total)
+		}
+		for _, info := range page.Hosts {
+			paged = append(paged, info.EnodeID)
+		}
+	}
+	if len(paged) != len(all) {
+		t.Fatalf("expect %v storage hosts paged, got %v", len(all), len(paged))
+	}
+	for i, id := range paged {
+		if all[i].EnodeID != id {
+			t.Fatalf("the storage host at %v not expected", i)
+		}
+	}
+
+	page, err := shm.HostsPage(0, 0, HostsFilterActive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 20 || len(page.Hosts) != 20 || page.Limit != defaultHostsPageLimit {
+		t.Errorf("the page of active hosts not expected: total %v, hosts %v, limit %v", page.Total, len(page.Hosts), page.Limit)
+	}
+	if page, err = shm.HostsPage(100, 10, ""); err != nil || len(page.Hosts) != 0 {
+		t.Errorf("the page out of range should be empty: %v, %v", len(page.Hosts), err)
+	}
+
+	// invalid arguments
+	if _, err = shm.HostsPage(-1, 10, HostsFilterAll); err == nil {
+		t.Errorf("the negative offset should be rejected")
+	}
+	if _, err = shm.HostsPage(0, maxHostsPageLimit+1, HostsFilterAll); err == nil {
+		t.Errorf("the limit exceeding %v should be rejected", maxHostsPageLimit)
+	}
+	if _, err = shm.HostsPage(0, 10, "unknown"); err == nil {
+		t.Errorf("the unknown filter should be rejected")
+	}
+}
+
+func TestStorageHostManager_Hosts(t *testing.T) {
+	shm := New("test")
+	var ids []enode.ID
+	for i := 0; i < 5; i++ {
+		info := activeHostInfoGenerator()
+		if err := shm.insert(info); err != nil {
+			t.Fatalf("failed to insert the host information into the tree: %s", err.Error())
+		}
+		ids = append(ids, info.EnodeID)
+	}
+
+	infos, err := shm.Hosts(append(ids[:3:3], enodeIDGenerator()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("expect 3 storage hosts, got %v", len(infos))
+	}
+	for i, info := range infos {
+		if info.EnodeID != ids[i] {
+			t.Errorf("the storage host at %v not expected", i)
+		}
+	}
+
+	if _, err = shm.Hosts(make([]enode.ID, maxHostsBatch+1)); err == nil {
+		t.Errorf("the batch exceeding %v should be rejected", maxHostsBatch)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/storagehosttree"
+)
+
+type (
+	// HostSelector is the strategy used to select the storage hosts to form contracts with.
+	// The storage hosts are first filtered as candidates, then scored, and at last picked.
+	// The blacklisted storage hosts are excluded by the storage host manager before the
+	// selection, and the picked storage hosts sharing the ip network are skipped after.
+	HostSelector interface {
+		// Filter returns whether the storage host could be a candidate
+		Filter(info storage.HostInfo) bool
+
+		// Score returns the score of the candidate, given its evaluation by the storage
+		// host manager. The candidates with non-positive scores are dropped
+		Score(info storage.HostInfo, evaluation common.BigInt) common.BigInt
+
+		// Pick orders the scored candidates by preference. The storage host manager takes
+		// the needed number of storage hosts in the order
+		Pick(candidates []HostCandidate) []HostCandidate
+	}
+
+	// HostCandidate is a storage host candidate with its score
+	HostCandidate struct {
+		Info  storage.HostInfo
+		Score common.BigInt
+	}
+
+	// randomWeightedSelector is the default HostSelector. The candidates are the storage hosts
+	// accepting contracts and online in the latest scan, scored by the evaluation, and picked
+	// randomly with the probability proportional to the score.
+	randomWeightedSelector struct {
+		rand *rand.Rand
+	}
+)
+
+// DefaultHostSelector returns the HostSelector used when no selector is registered
+func DefaultHostSelector() HostSelector {
+	return &randomWeightedSelector{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Filter returns whether the storage host is accepting contracts and online in the latest scan
+func (s *randomWeightedSelector) Filter(info storage.HostInfo) bool {
+	return isActiveHost(info)
+}
+
+// Score returns the evaluation as the score
+func (s *randomWeightedSelector) Score(info storage.HostInfo, evaluation common.BigInt) common.BigInt {
+	return evaluation
+}
+
+// Pick orders the candidates as drawn randomly without replacement, with the probability
+// proportional to the score. Each candidate is given the key log(u)/score with u uniformly
+// random in (0, 1), and the candidates are sorted by the key in descending order.
+func (s *randomWeightedSelector) Pick(candidates []HostCandidate) []HostCandidate {
+	keys := make([]float64, len(candidates))
+	picked := make([]int, len(candidates))
+	for i, candidate := range candidates {
+		u := s.rand.Float64()
+		for u == 0 {
+			u = s.rand.Float64()
+		}
+		keys[i] = math.Log(u) / candidate.Score.Float64()
+		picked[i] = i
+	}
+	sort.Slice(picked, func(i, j int) bool {
+		return keys[picked[i]] > keys[picked[j]]
+	})
+	ordered := make([]HostCandidate, len(candidates))
+	for i, index := range picked {
+		ordered[i] = candidates[index]
+	}
+	return ordered
+}
+
+// SetHostSelector registers the strategy used to select the storage hosts. If nil, the
+// default random weighted selection is used
+func (shm *StorageHostManager) SetHostSelector(selector HostSelector) {
+	if selector == nil {
+		selector = DefaultHostSelector()
+	}
+	shm.lock.Lock()
+	defer shm.lock.Unlock()
+	shm.selector = selector
+}
+
+// selectHosts selects at most num storage hosts from the filtered tree with the selector.
+// The storage hosts in the blacklist are excluded, and the storage hosts sharing the ip
+// network with the ones in addrBlacklist or already selected are skipped.
+func (shm *StorageHostManager) selectHosts(num int, blacklist, addrBlacklist []enode.ID) []storage.HostInfo {
+	shm.lock.RLock()
+	selector, evalFunc, tree := shm.selector, shm.evalFunc, shm.filteredTree
+	shm.lock.RUnlock()
+
+	excluded := make(map[enode.ID]struct{})
+	for _, id := range blacklist {
+		excluded[id] = struct{}{}
+	}
+	filter := storagehosttree.NewFilter()
+	for _, id := range addrBlacklist {
+		if info, exists := tree.RetrieveHostInfo(id); exists {
+			filter.Add(info.IP)
+		}
+	}
+
+	var candidates []HostCandidate
+	for _, info := range tree.All() {
+		if _, exists := excluded[info.EnodeID]; exists {
+			continue
+		}
+		if score, ok := candidateScore(selector, evalFunc, info); ok {
+			candidates = append(candidates, HostCandidate{Info: info, Score: score})
+		}
+	}
+
+	var infos []storage.HostInfo
+	for _, candidate := range selector.Pick(candidates) {
+		if len(infos) >= num {
+			break
+		}
+		if filter.Filtered(candidate.Info.IP) {
+			continue
+		}
+		infos = append(infos, candidate.Info)
+		filter.Add(candidate.Info.IP)
+	}
+	return infos
+}
+
+// candidateScore filters and scores the storage host with the selector. The storage host
+// filtered out or with a non-positive score is not a candidate
+func candidateScore(selector HostSelector, evalFunc storagehosttree.EvaluationFunc, info storage.HostInfo) (score common.BigInt, ok bool) {
+	if !selector.Filter(info) {
+		return
+	}
+	score = selector.Score(info, evalFunc(info).Evaluation())
+	return score, score.Sign() > 0
+}
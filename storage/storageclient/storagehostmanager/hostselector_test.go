@@ -0,0 +1,133 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// partnerSelector selects the partner storage hosts only, in the order of the enode ID
+type partnerSelector struct {
+	partners map[enode.ID]struct{}
+}
+
+func (s *partnerSelector) Filter(info storage.HostInfo) bool {
+	_, exists := s.partners[info.EnodeID]
+	return exists
+}
+
+func (s *partnerSelector) Score(info storage.HostInfo, evaluation common.BigInt) common.BigInt {
+	return common.NewBigInt(1)
+}
+
+func (s *partnerSelector) Pick(candidates []HostCandidate) []HostCandidate {
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Info.EnodeID.String() < candidates[j].Info.EnodeID.String()
+	})
+	return candidates
+}
+
+func newSelectorTestHostManager(t *testing.T, num int) (*StorageHostManager, []storage.HostInfo) {
+	shm := New("test")
+	shm.initialScan = true
+	var infos []storage.HostInfo
+	for i := 0; i < num; i++ {
+		info := activeHostInfoGenerator()
+		if err := shm.insert(info); err != nil {
+			t.Fatalf("failed to insert the host information into the tree: %s", err.Error())
+		}
+		infos = append(infos, info)
+	}
+	return shm, infos
+}
+
+func TestStorageHostManager_DefaultHostSelector(t *testing.T) {
+	shm, infos := newSelectorTestHostManager(t, 10)
+	offline := activeHostInfoGenerator()
+	offline.ScanRecords[0].Success = false
+	if err := shm.insert(offline); err != nil {
+		t.Fatalf("failed to insert the host information into the tree: %s", err.Error())
+	}
+
+	for i := 0; i < 20; i++ {
+		selected, err := shm.RetrieveRandomHosts(5, []enode.ID{infos[0].EnodeID}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(selected) != 5 {
+			t.Fatalf("expect 5 storage hosts selected, got %v", len(selected))
+		}
+		seen := make(map[enode.ID]struct{})
+		for _, info := range selected {
+			if info.EnodeID == infos[0].EnodeID || info.EnodeID == offline.EnodeID {
+				t.Fatalf("the blacklisted or offline storage host %v is selected", info.EnodeID)
+			}
+			if _, exists := seen[info.EnodeID]; exists {
+				t.Fatalf("the storage host %v is selected twice", info.EnodeID)
+			}
+			seen[info.EnodeID] = struct{}{}
+		}
+	}
+
+	// the storage hosts are all selected if the number needed exceeds the candidates
+	selected, err := shm.RetrieveRandomHosts(20, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 10 {
+		t.Errorf("expect 10 storage hosts selected, got %v", len(selected))
+	}
+}
+
+func TestStorageHostManager_SetHostSelector(t *testing.T) {
+	shm, infos := newSelectorTestHostManager(t, 10)
+	selector := &partnerSelector{partners: make(map[enode.ID]struct{})}
+	var partners []string
+	for _, info := range infos[:4] {
+		selector.partners[info.EnodeID] = struct{}{}
+		partners = append(partners, info.EnodeID.String())
+	}
+	sort.Strings(partners)
+	shm.SetHostSelector(selector)
+
+	for i := 0; i < 3; i++ {
+		selected, err := shm.RetrieveRandomHosts(3, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(selected) != 3 {
+			t.Fatalf("expect 3 storage hosts selected, got %v", len(selected))
+		}
+		for j, info := range selected {
+			if info.EnodeID.String() != partners[j] {
+				t.Fatalf("the selection is not deterministic: expect %v, got %v", partners[j], info.EnodeID.String())
+			}
+		}
+	}
+
+	// the storage hosts sharing the ip network are skipped after picked
+	shm.ipViolationCheck = true
+	selected, err := shm.RetrieveRandomHosts(4, nil, []enode.ID{infos[0].EnodeID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range selected {
+		if info.EnodeID == infos[0].EnodeID {
+			t.Errorf("the storage host in the address blacklist is selected")
+		}
+	}
+
+	// the default selector is restored with nil
+	shm.SetHostSelector(nil)
+	shm.ipViolationCheck = false
+	if selected, err = shm.RetrieveRandomHosts(10, nil, nil); err != nil || len(selected) != 10 {
+		t.Errorf("the default selector is not restored: %v, %v", len(selected), err)
+	}
+}
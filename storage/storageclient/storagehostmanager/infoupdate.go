@@ -26,6 +26,7 @@ func (shm *StorageHostManager) hostInfoUpdate(hi storage.HostInfo, err error) {
 		storedInfo.HostExtConfig = hi.HostExtConfig
 		storedInfo.IPNetwork = hi.IPNetwork
 		storedInfo.LastIPNetWorkChange = hi.LastIPNetWorkChange
+		storedInfo.IPNetworkChanges = hi.IPNetworkChanges
 	} else {
 		storedInfo = hi
 	}
@@ -195,3 +196,44 @@ func (shm *StorageHostManager) IncrementFailedInteractions(id enode.ID) {
 		shm.log.Error("failed to increment the failed interactions", "err", err.Error())
 	}
 }
+
+// RecordHostQoS merges the negotiation quality sample taken from the peer into the storage host
+// information, and recalculates the storage host evaluation
+func (shm *StorageHostManager) RecordHostQoS(id enode.ID, sample storage.PeerQoSSample) {
+	if sample.Empty() {
+		return
+	}
+	shm.lock.Lock()
+	defer shm.lock.Unlock()
+
+	host, exists := shm.storageHostTree.RetrieveHostInfo(id)
+	if !exists {
+		return
+	}
+	hostQoSUpdate(&host.QoS, sample)
+	if err := shm.storageHostTree.HostInfoUpdate(host); err != nil {
+		shm.log.Error("failed to update the host qos", "err", err.Error())
+	}
+}
+
+// hostQoSUpdate decays the aggregated negotiation quality, and merges the sample into it
+func hostQoSUpdate(qos *storage.HostQoS, sample storage.PeerQoSSample) {
+	qos.Responses = qos.Responses*qosDecay + float64(sample.Responses)
+	qos.Timeouts = qos.Timeouts*qosDecay + float64(sample.Timeouts)
+	qos.SendFailures = qos.SendFailures*qosDecay + float64(sample.SendFailures)
+
+	if rtt := sample.AverageRTT(); rtt > 0 {
+		if qos.RTT == 0 {
+			qos.RTT = rtt
+		} else {
+			qos.RTT = time.Duration(float64(qos.RTT)*(1-qosAverageWeight) + float64(rtt)*qosAverageWeight)
+		}
+	}
+	if throughput := sample.Throughput(); throughput > 0 {
+		if qos.Throughput == 0 {
+			qos.Throughput = throughput
+		} else {
+			qos.Throughput = qos.Throughput*(1-qosAverageWeight) + throughput*qosAverageWeight
+		}
+	}
+}
@@ -4,7 +4,12 @@
 
 package storagehostmanager
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/storage"
+)
 
 func TestStorageHostManager_IncrementSuccessfulInteractions(t *testing.T) {
 	shm := newHostManagerTestData()
@@ -46,3 +51,53 @@ func TestStorageHostManager_IncrementFailedInteractions(t *testing.T) {
 			hiUpdated.RecentFailedInteractions, hi.RecentFailedInteractions+1)
 	}
 }
+
+func TestStorageHostManager_RecordHostQoS(t *testing.T) {
+	shm := newHostManagerTestData()
+	hi := hostInfoGenerator()
+
+	if err := shm.insert(hi); err != nil {
+		t.Fatalf("failed to insert data into the storage host tree")
+	}
+	if factor := shm.qosFactorCalc(hi); factor != 1 {
+		t.Fatalf("the host never negotiated with should not be punished, got factor %v", factor)
+	}
+
+	// a host responding fast
+	shm.RecordHostQoS(hi.EnodeID, storage.PeerQoSSample{
+		Responses:        10,
+		TotalRTT:         10 * 100 * time.Millisecond,
+		TransferredBytes: 8 << 20,
+		TransferTime:     time.Second,
+	})
+	good, exists := shm.storageHostTree.RetrieveHostInfo(hi.EnodeID)
+	if !exists {
+		t.Fatalf("failed to retrieve the storage host information with id %s", hi.EnodeID)
+	}
+	if good.QoS.Responses != 10 || good.QoS.RTT != 100*time.Millisecond || good.QoS.Throughput != 8<<20 {
+		t.Fatalf("unexpected host qos %+v", good.QoS)
+	}
+	if factor := shm.qosFactorCalc(good); factor != 1 {
+		t.Errorf("the host responding fast should not be punished, got factor %v", factor)
+	}
+
+	// the host starts to time out and respond slowly
+	shm.RecordHostQoS(hi.EnodeID, storage.PeerQoSSample{
+		Responses: 2,
+		Timeouts:  5,
+		TotalRTT:  2 * 30 * time.Second,
+	})
+	bad, _ := shm.storageHostTree.RetrieveHostInfo(hi.EnodeID)
+	if bad.QoS.RTT <= good.QoS.RTT || bad.QoS.Timeouts != 5 {
+		t.Fatalf("unexpected host qos %+v", bad.QoS)
+	}
+	if shm.qosFactorCalc(bad) >= shm.qosFactorCalc(good) {
+		t.Errorf("the host timed out should get lower qos factor")
+	}
+
+	// empty sample is ignored
+	shm.RecordHostQoS(hi.EnodeID, storage.PeerQoSSample{})
+	if ignored, _ := shm.storageHostTree.RetrieveHostInfo(hi.EnodeID); ignored.QoS != bad.QoS {
+		t.Errorf("the empty sample should not change the host qos")
+	}
+}
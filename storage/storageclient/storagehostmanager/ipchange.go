@@ -0,0 +1,64 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"time"
+
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// recordIPNetworkChange sets the new ip network of the storage host, and appends the change
+// to the history, which keeps at most maxIPNetworkChanges records
+func recordIPNetworkChange(hi *storage.HostInfo, ipNetwork string, now time.Time) {
+	hi.IPNetworkChanges = append(hi.IPNetworkChanges, storage.IPNetworkChange{
+		From: hi.IPNetwork,
+		To:   ipNetwork,
+		Time: now,
+	})
+	if len(hi.IPNetworkChanges) > maxIPNetworkChanges {
+		hi.IPNetworkChanges = hi.IPNetworkChanges[len(hi.IPNetworkChanges)-maxIPNetworkChanges:]
+	}
+	hi.IPNetwork = ipNetwork
+	hi.LastIPNetWorkChange = now
+}
+
+// inIPChangeGrace checks if the known storage host changed its ip network within the grace
+// period. The storage hosts with dynamic ip addresses could be moved to the ip network of
+// another host, and they are not treated as ip violation until the grace period is over.
+// The grace is granted at most once in maxIPNetworkChanges grace periods, so that the host
+// changing its ip network repeatedly could not stay in the grace period
+func inIPChangeGrace(hi storage.HostInfo, grace time.Duration, now time.Time) bool {
+	if len(hi.IPNetworkChanges) == 0 {
+		return false
+	}
+	latest := hi.IPNetworkChanges[len(hi.IPNetworkChanges)-1]
+	if latest.From == "" || now.Sub(latest.Time) >= grace {
+		return false
+	}
+	window := grace * maxIPNetworkChanges
+	for _, change := range hi.IPNetworkChanges[:len(hi.IPNetworkChanges)-1] {
+		if change.From != "" && latest.Time.Sub(change.Time) < window {
+			return false
+		}
+	}
+	return true
+}
+
+// SetIPChangeGracePeriod sets the grace period given to the storage hosts changed their ip
+// networks before the ip violation check applies to them. Zero disables the grace period
+func (shm *StorageHostManager) SetIPChangeGracePeriod(grace time.Duration) {
+	shm.lock.Lock()
+	defer shm.lock.Unlock()
+	shm.ipChangeGracePeriod = grace
+}
+
+// RetrieveIPChangeGracePeriod returns the grace period given to the storage hosts changed
+// their ip networks
+func (shm *StorageHostManager) RetrieveIPChangeGracePeriod() time.Duration {
+	shm.lock.RLock()
+	defer shm.lock.RUnlock()
+	return shm.ipChangeGracePeriod
+}
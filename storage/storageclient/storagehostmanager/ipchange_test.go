@@ -0,0 +1,84 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestRecordIPNetworkChange(t *testing.T) {
+	hi := hostInfoGeneratorForIPViolation("196.5.4.3", time.Time{})
+	now := time.Now()
+	recordIPNetworkChange(&hi, "196.5.4.0/24", now)
+	if len(hi.IPNetworkChanges) != 1 || hi.IPNetworkChanges[0].From != "" || hi.IPNetwork != "196.5.4.0/24" || !hi.LastIPNetWorkChange.Equal(now) {
+		t.Fatalf("unexpected host info after the first ip network change: %+v", hi)
+	}
+	if inIPChangeGrace(hi, time.Hour, now) {
+		t.Errorf("the ip network found for the first time should not be in grace period")
+	}
+
+	recordIPNetworkChange(&hi, "10.0.0.0/24", now)
+	if !inIPChangeGrace(hi, time.Hour, now) {
+		t.Errorf("the known host changed ip network should be in grace period")
+	}
+	if inIPChangeGrace(hi, time.Hour, now.Add(time.Hour)) {
+		t.Errorf("the grace period should be over")
+	}
+
+	// the grace is not granted again to the host changing the ip network repeatedly
+	later := now.Add(2 * time.Hour)
+	recordIPNetworkChange(&hi, "10.0.2.0/24", later)
+	if inIPChangeGrace(hi, time.Hour, later) {
+		t.Errorf("the grace should be granted only once in %v grace periods", maxIPNetworkChanges)
+	}
+	later = later.Add(maxIPNetworkChanges * time.Hour)
+	recordIPNetworkChange(&hi, "10.0.3.0/24", later)
+	if !inIPChangeGrace(hi, time.Hour, later) {
+		t.Errorf("the host with the ip network stable for %v grace periods should be in grace period", maxIPNetworkChanges)
+	}
+
+	for i := 0; i != maxIPNetworkChanges; i++ {
+		recordIPNetworkChange(&hi, "10.0.1.0/24", now)
+	}
+	if len(hi.IPNetworkChanges) != maxIPNetworkChanges || hi.IPNetworkChanges[0].From != "10.0.3.0/24" {
+		t.Errorf("expected %v latest ip network changes kept, got %+v", maxIPNetworkChanges, hi.IPNetworkChanges)
+	}
+}
+
+func TestStorageHostManager_FilterIPViolationHostsGrace(t *testing.T) {
+	shm := newHostManagerTestData()
+	shm.ipViolationCheck = true
+	shm.ipChangeGracePeriod = time.Hour
+
+	// the moved host joined the ip network of the existing host recently
+	existing := hostInfoGeneratorForIPViolation("196.5.4.3", time.Now().Add(-48*time.Hour))
+	moved := hostInfoGeneratorForIPViolation("196.5.4.4", time.Time{})
+	recordIPNetworkChange(&moved, "10.0.0.0/24", time.Now().Add(-72*time.Hour))
+	recordIPNetworkChange(&moved, "196.5.4.0/24", time.Now())
+	for _, info := range []storage.HostInfo{existing, moved} {
+		if err := shm.insert(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := []enode.ID{existing.EnodeID, moved.EnodeID}
+
+	if bad := shm.FilterIPViolationHosts(ids); len(bad) != 0 {
+		t.Errorf("the host in the grace period should not be filtered, got %v", bad)
+	}
+	if _, violated := shm.ipViolatedBy(moved, shm.ipChangeGracePeriod); violated {
+		t.Errorf("the host in the grace period should not be explained as ip violation")
+	}
+
+	// after the grace period the moved host is filtered
+	shm.SetIPChangeGracePeriod(0)
+	bad := shm.FilterIPViolationHosts(ids)
+	if len(bad) != 1 || bad[0] != moved.EnodeID {
+		t.Errorf("expected the moved host to be filtered, got %v", bad)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"sort"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// MarketStats is the aggregation of the prices offered by the active storage hosts.
+// Median is used so that a few hosts with extreme prices will not affect the result
+type MarketStats struct {
+	Hosts int `json:"hosts"`
+
+	ContractPrice          common.BigInt `json:"contractprice"`
+	DownloadBandwidthPrice common.BigInt `json:"downloadbandwidthprice"`
+	StoragePrice           common.BigInt `json:"storageprice"`
+	UploadBandwidthPrice   common.BigInt `json:"uploadbandwidthprice"`
+	Deposit                common.BigInt `json:"deposit"`
+}
+
+// MarketStats aggregates the prices of the active storage hosts. The price increases
+// scheduled by the hosts before or at the block height are taken into account
+func (shm *StorageHostManager) MarketStats(height uint64) (stats MarketStats) {
+	var configs []storage.HostExtConfig
+	for _, host := range shm.ActiveStorageHosts() {
+		configs = append(configs, host.UpcomingPrices(height))
+	}
+	if len(configs) == 0 {
+		return
+	}
+
+	stats.Hosts = len(configs)
+	stats.ContractPrice = medianPrice(configs, func(c storage.HostExtConfig) common.BigInt { return c.ContractPrice })
+	stats.DownloadBandwidthPrice = medianPrice(configs, func(c storage.HostExtConfig) common.BigInt { return c.DownloadBandwidthPrice })
+	stats.StoragePrice = medianPrice(configs, func(c storage.HostExtConfig) common.BigInt { return c.StoragePrice })
+	stats.UploadBandwidthPrice = medianPrice(configs, func(c storage.HostExtConfig) common.BigInt { return c.UploadBandwidthPrice })
+	stats.Deposit = medianPrice(configs, func(c storage.HostExtConfig) common.BigInt { return c.Deposit })
+	return
+}
+
+// medianPrice returns the median of the price specified by the price function
+func medianPrice(configs []storage.HostExtConfig, price func(storage.HostExtConfig) common.BigInt) common.BigInt {
+	prices := make([]common.BigInt, 0, len(configs))
+	for _, config := range configs {
+		prices = append(prices, price(config))
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].Cmp(prices[j]) < 0
+	})
+
+	mid := len(prices) / 2
+	if len(prices)%2 == 1 {
+		return prices[mid]
+	}
+	return prices[mid-1].Add(prices[mid]).DivUint64(2)
+}
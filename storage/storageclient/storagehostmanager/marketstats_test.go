@@ -0,0 +1,44 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestStorageHostManager_MarketStats(t *testing.T) {
+	shm := New("test")
+	if stats := shm.MarketStats(0); stats.Hosts != 0 {
+		t.Fatalf("expect no host in the market stats, got %v", stats.Hosts)
+	}
+
+	for _, price := range []uint64{10, 30, 20, 1000} {
+		host := activeHostInfoGenerator()
+		host.StoragePrice = common.NewBigIntUint64(price)
+		host.ScheduledPrices = []storage.HostScheduledPrice{
+			{Name: "storagePrice", Price: common.NewBigIntUint64(price * 2), EffectiveHeight: 100},
+		}
+		if err := shm.insert(host); err != nil {
+			t.Fatalf("insert failed: %s", err.Error())
+		}
+	}
+	// inactive host should not be counted
+	inactive := hostInfoGenerator()
+	inactive.StoragePrice = common.NewBigIntUint64(1)
+	if err := shm.insert(inactive); err != nil {
+		t.Fatalf("insert failed: %s", err.Error())
+	}
+
+	stats := shm.MarketStats(99)
+	if stats.Hosts != 4 || stats.StoragePrice.CmpUint64(25) != 0 {
+		t.Errorf("market stats not expected. Got %v hosts with storage price %v", stats.Hosts, stats.StoragePrice)
+	}
+	if stats = shm.MarketStats(100); stats.StoragePrice.CmpUint64(50) != 0 {
+		t.Errorf("scheduled prices should be included. Got storage price %v, expect 50", stats.StoragePrice)
+	}
+}
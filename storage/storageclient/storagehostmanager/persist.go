@@ -30,6 +30,11 @@ type persistence struct {
 	IPViolationCheck bool
 	FilteredHosts    map[enode.ID]struct{}
 	FilterMode       FilterMode
+
+	IPChangeGracePeriod time.Duration
+
+	PriceAudits         map[enode.ID]*HostPriceAudit
+	PriceAuditBlacklist bool
 }
 
 // saveSettings will save the storage host configurations into the JSON file
@@ -47,6 +52,11 @@ func (shm *StorageHostManager) persistUpdate() (persist persistence) {
 		IPViolationCheck: shm.ipViolationCheck,
 		FilteredHosts:    shm.filteredHosts,
 		FilterMode:       shm.filterMode,
+
+		IPChangeGracePeriod: shm.ipChangeGracePeriod,
+
+		PriceAudits:         shm.priceAudits,
+		PriceAuditBlacklist: shm.priceAuditBlacklist,
 	}
 }
 
@@ -85,6 +95,8 @@ func (shm *StorageHostManager) loadSettings() error {
 
 	var persist persistence
 	persist.FilteredHosts = make(map[enode.ID]struct{})
+	persist.PriceAudits = make(map[enode.ID]*HostPriceAudit)
+	persist.IPChangeGracePeriod = shm.ipChangeGracePeriod
 
 	err = common.LoadDxJSON(settingsMetadata, filepath.Join(shm.persistDir, PersistFilename), &persist)
 	if err != nil {
@@ -96,6 +108,9 @@ func (shm *StorageHostManager) loadSettings() error {
 	shm.ipViolationCheck = persist.IPViolationCheck
 	shm.filteredHosts = persist.FilteredHosts
 	shm.filterMode = persist.FilterMode
+	shm.ipChangeGracePeriod = persist.IPChangeGracePeriod
+	shm.priceAudits = persist.PriceAudits
+	shm.priceAuditBlacklist = persist.PriceAuditBlacklist
 
 	// update the storage host tree
 	for _, info := range persist.StorageHostsInfo {
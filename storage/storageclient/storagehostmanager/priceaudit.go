@@ -0,0 +1,123 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// HostPriceAudit records the prices a storage host advertised when the contract was negotiated,
+// and the discrepancies found by comparing the charges of the signed revisions to them
+type HostPriceAudit struct {
+	Advertised      storage.HostExtConfig `json:"advertised"`
+	Revisions       uint64                `json:"revisions"`
+	Discrepancies   uint64                `json:"discrepancies"`
+	Overcharged     common.BigInt         `json:"overcharged"`
+	LastDiscrepancy time.Time             `json:"lastdiscrepancy"`
+	Blacklisted     bool                  `json:"blacklisted"`
+}
+
+// RecordAdvertisedPrices records the host config used to negotiate the contract with the storage
+// host. The charges of the later revisions are audited against it
+func (shm *StorageHostManager) RecordAdvertisedPrices(id enode.ID, config storage.HostExtConfig) {
+	shm.lock.Lock()
+	defer shm.lock.Unlock()
+
+	audit, exists := shm.priceAudits[id]
+	if !exists {
+		audit = &HostPriceAudit{}
+		shm.priceAudits[id] = audit
+	}
+	audit.Advertised = config
+}
+
+// AdvertisedPrices returns the host config recorded when the contract was negotiated
+func (shm *StorageHostManager) AdvertisedPrices(id enode.ID) (config storage.HostExtConfig, exists bool) {
+	shm.lock.RLock()
+	defer shm.lock.RUnlock()
+
+	audit, exists := shm.priceAudits[id]
+	if !exists {
+		return
+	}
+	return audit.Advertised, true
+}
+
+// AuditRevisionCharge compares the charge implied by a signed revision with the cost expected
+// from the advertised prices. If the storage host charged more, the discrepancy is recorded,
+// and the storage host is blacklisted once the discrepancies reach the limit if enabled
+func (shm *StorageHostManager) AuditRevisionCharge(id enode.ID, charged, expected common.BigInt) {
+	shm.lock.Lock()
+	defer shm.lock.Unlock()
+
+	audit, exists := shm.priceAudits[id]
+	if !exists {
+		return
+	}
+	audit.Revisions++
+	if charged.Cmp(expected) <= 0 {
+		return
+	}
+
+	audit.Discrepancies++
+	audit.Overcharged = audit.Overcharged.Add(charged.Sub(expected))
+	audit.LastDiscrepancy = time.Now()
+	shm.log.Warn("storage host charged more than advertised", "hostID", id, "charged", charged, "expected", expected)
+
+	if shm.priceAuditBlacklist && !audit.Blacklisted && audit.Discrepancies >= priceDiscrepancyBlacklistLimit {
+		audit.Blacklisted = true
+		shm.log.Warn("storage host blacklisted for overcharging", "hostID", id, "discrepancies", audit.Discrepancies)
+	}
+}
+
+// SetPriceAuditBlacklist enables or disables blacklisting the storage hosts charging more than
+// advertised. Disabling it will lift the existing blacklists
+func (shm *StorageHostManager) SetPriceAuditBlacklist(enable bool) {
+	shm.lock.Lock()
+	defer shm.lock.Unlock()
+
+	shm.priceAuditBlacklist = enable
+	for _, audit := range shm.priceAudits {
+		audit.Blacklisted = enable && audit.Discrepancies >= priceDiscrepancyBlacklistLimit
+	}
+}
+
+// PriceBlacklisted checks if the storage host is blacklisted for charging more than advertised
+func (shm *StorageHostManager) PriceBlacklisted(id enode.ID) bool {
+	shm.lock.RLock()
+	defer shm.lock.RUnlock()
+
+	audit, exists := shm.priceAudits[id]
+	return exists && audit.Blacklisted
+}
+
+// PriceAudits returns the price audits of all storage hosts the client negotiated with
+func (shm *StorageHostManager) PriceAudits() map[string]HostPriceAudit {
+	shm.lock.RLock()
+	defer shm.lock.RUnlock()
+
+	audits := make(map[string]HostPriceAudit)
+	for id, audit := range shm.priceAudits {
+		audits[id.String()] = *audit
+	}
+	return audits
+}
+
+// priceBlacklistedHosts returns the ids of the storage hosts blacklisted for overcharging
+func (shm *StorageHostManager) priceBlacklistedHosts() (ids []enode.ID) {
+	shm.lock.RLock()
+	defer shm.lock.RUnlock()
+
+	for id, audit := range shm.priceAudits {
+		if audit.Blacklisted {
+			ids = append(ids, id)
+		}
+	}
+	return
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehostmanager
+
+import (
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+)
+
+func TestStorageHostManager_AuditRevisionCharge(t *testing.T) {
+	shm := New("test")
+	host := hostInfoGenerator()
+	if err := shm.insert(host); err != nil {
+		t.Fatalf("failed to insert the host information into the tree: %s", err.Error())
+	}
+
+	// the charges of the host without advertised prices recorded are not audited
+	shm.AuditRevisionCharge(host.EnodeID, common.NewBigInt(200), common.NewBigInt(100))
+	if _, exists := shm.PriceAudits()[host.EnodeID.String()]; exists {
+		t.Fatalf("the host without advertised prices should not be audited")
+	}
+
+	shm.RecordAdvertisedPrices(host.EnodeID, host.HostExtConfig)
+	if advertised, exists := shm.AdvertisedPrices(host.EnodeID); !exists || advertised.StoragePrice.Cmp(host.StoragePrice) != 0 {
+		t.Fatalf("the advertised prices not expected: %+v, %v", advertised, exists)
+	}
+	shm.SetPriceAuditBlacklist(true)
+
+	shm.AuditRevisionCharge(host.EnodeID, common.NewBigInt(100), common.NewBigInt(100))
+	for i := 0; i < priceDiscrepancyBlacklistLimit; i++ {
+		if shm.PriceBlacklisted(host.EnodeID) {
+			t.Fatalf("the host should not be blacklisted after %v discrepancies", i)
+		}
+		shm.AuditRevisionCharge(host.EnodeID, common.NewBigInt(110), common.NewBigInt(100))
+	}
+
+	audit := shm.PriceAudits()[host.EnodeID.String()]
+	if audit.Revisions != priceDiscrepancyBlacklistLimit+1 || audit.Discrepancies != priceDiscrepancyBlacklistLimit {
+		t.Errorf("the audit not expected: %+v", audit)
+	}
+	if audit.Overcharged.Cmp(common.NewBigInt(10*priceDiscrepancyBlacklistLimit)) != 0 {
+		t.Errorf("the overcharged amount expect %v, got %v", 10*priceDiscrepancyBlacklistLimit, audit.Overcharged)
+	}
+	if !shm.PriceBlacklisted(host.EnodeID) {
+		t.Fatalf("the host should be blacklisted")
+	}
+
+	// the discrepancies are flagged in the rankings
+	ranks := shm.StorageHostRanks()
+	if len(ranks) != 1 || ranks[0].PriceDiscrepancies != priceDiscrepancyBlacklistLimit || !ranks[0].PriceBlacklisted {
+		t.Errorf("the rankings not expected: %+v", ranks)
+	}
+
+	// disabling the blacklist lifts the existing blacklists
+	shm.SetPriceAuditBlacklist(false)
+	if shm.PriceBlacklisted(host.EnodeID) {
+		t.Errorf("the host should not be blacklisted after the blacklist is disabled")
+	}
+}
@@ -207,8 +207,7 @@ func (shm *StorageHostManager) updateHostConfig(hi storage.HostInfo) {
 	ipnet, err := storagehosttree.IPNetwork(hi.IP)
 
 	if err == nil && ipnet.String() != hi.IPNetwork {
-		hi.IPNetwork = ipnet.String()
-		hi.LastIPNetWorkChange = time.Now()
+		recordIPNetworkChange(&hi, ipnet.String(), time.Now())
 	} else if err != nil {
 		shm.log.Error("failed to get the IP network information", "err", err.Error())
 	}
@@ -106,6 +106,7 @@ func newHostManagerTestData() *StorageHostManager {
 		rent:          storage.DefaultRentPayment,
 		scanLookup:    make(map[enode.ID]struct{}),
 		filteredHosts: make(map[enode.ID]struct{}),
+		selector:      DefaultHostSelector(),
 	}
 
 	shm.evalFunc = shm.calculateEvaluationFunc(shm.rent)
@@ -196,6 +197,10 @@ func (st *storageClientBackendTestData) GetPaymentAddress() (common.Address, err
 
 func (st *storageClientBackendTestData) RevisionOrRenewingDone(hostID enode.ID) {}
 
+func (st *storageClientBackendTestData) GetStorageContractRevisionNumber(contractID common.Hash) (uint64, bool, error) {
+	return 0, false, nil
+}
+
 func (st *storageClientBackendTestData) CheckAndUpdateConnection(peerNode *enode.Node) {}
 
 func (st *storageClientBackendTestData) SelfEnodeURL() string {
@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/common/threadmanager"
@@ -30,8 +31,10 @@ type StorageHostManager struct {
 	evalFunc        storagehosttree.EvaluationFunc
 	storageHostTree *storagehosttree.StorageHostTree
 
-	// ip violation check
-	ipViolationCheck bool
+	// ip violation check, and the grace period before the check applies to the storage
+	// hosts changed their ip networks
+	ipViolationCheck    bool
+	ipChangeGracePeriod time.Duration
 
 	// maintenance related
 	initialScan     bool
@@ -53,6 +56,17 @@ type StorageHostManager struct {
 	filteredHosts map[enode.ID]struct{}
 	filteredTree  *storagehosttree.StorageHostTree
 
+	// total evaluation of the filtered tree when the latest random selection was made
+	lastSelectionEval common.BigInt
+	lastSelectionTime time.Time
+
+	// strategy used to select the storage hosts to form contracts with
+	selector HostSelector
+
+	// prices advertised by the storage hosts when negotiating, and the audit of the charges
+	priceAudits         map[enode.ID]*HostPriceAudit
+	priceAuditBlacklist bool
+
 	blockHeight uint64
 }
 
@@ -65,6 +79,10 @@ func New(persistDir string) *StorageHostManager {
 		scanLookup:    make(map[enode.ID]struct{}),
 		filterMode:    DisableFilter,
 		filteredHosts: make(map[enode.ID]struct{}),
+		priceAudits:   make(map[enode.ID]*HostPriceAudit),
+		selector:      DefaultHostSelector(),
+
+		ipChangeGracePeriod: defaultIPChangeGracePeriod,
 	}
 
 	shm.evalFunc = shm.calculateEvaluationFunc(shm.rent)
@@ -121,17 +139,9 @@ func (shm *StorageHostManager) ActiveStorageHosts() (activeStorageHosts []storag
 	allHosts := shm.storageHostTree.All()
 	// based on the host information, filter out active hosts
 	for _, host := range allHosts {
-		numScanRecords := len(host.ScanRecords)
-		if numScanRecords == 0 {
-			continue
+		if isActiveHost(host) {
+			activeStorageHosts = append(activeStorageHosts, host)
 		}
-		if !host.ScanRecords[numScanRecords-1].Success {
-			continue
-		}
-		if !host.AcceptingContracts {
-			continue
-		}
-		activeStorageHosts = append(activeStorageHosts, host)
 	}
 	return
 }
@@ -241,9 +251,14 @@ func (shm *StorageHostManager) FilterIPViolationHosts(hostIDs []enode.ID) (badHo
 		return hostsInfo[i].LastIPNetWorkChange.Before(hostsInfo[j].LastIPNetWorkChange)
 	})
 
-	// start the filter
+	// start the filter. The storage hosts in the ip change grace period are neither filtered
+	// nor filtering the others
 	ipFilter := storagehosttree.NewFilter()
+	now := time.Now()
 	for _, hi := range hostsInfo {
+		if inIPChangeGrace(hi, shm.ipChangeGracePeriod, now) {
+			continue
+		}
 		if ipFilter.Filtered(hi.IP) {
 			badHostIDs = append(badHostIDs, hi.EnodeID)
 			continue
@@ -254,7 +269,8 @@ func (shm *StorageHostManager) FilterIPViolationHosts(hostIDs []enode.ID) (badHo
 	return
 }
 
-// RetrieveRandomHosts will randomly select storage hosts from the storage host pool
+// RetrieveRandomHosts will select storage hosts from the storage host pool with the host selector,
+// which is the random weighted selection by default
 //  1. blacklist represents the storage host that are prohibited to be selected
 //  2. addrBlacklist represents for any storage host whose network address is caontine
 func (shm *StorageHostManager) RetrieveRandomHosts(num int, blacklist, addrBlacklist []enode.ID) (infos []storage.HostInfo, err error) {
@@ -263,17 +279,27 @@ func (shm *StorageHostManager) RetrieveRandomHosts(num int, blacklist, addrBlack
 	ipCheck := shm.ipViolationCheck
 	shm.lock.RUnlock()
 
-	// if the initialize scan is not complete
-	if !initScan {
+	// if the initialize scan is not complete, the storage hosts could only be selected
+	// if the bootstrap snapshot is loaded
+	if !initScan && !shm.bootstrapped() {
 		err = errors.New("storage host pool initial scan is not finished")
 		return
 	}
 
-	// select random
+	// record the evaluation base of the selection
+	shm.lock.Lock()
+	shm.lastSelectionEval = shm.filteredTree.EvaluationTotal()
+	shm.lastSelectionTime = time.Now()
+	shm.lock.Unlock()
+
+	// the storage hosts charging more than advertised are not selected
+	blacklist = append(blacklist, shm.priceBlacklistedHosts()...)
+
+	// select with the registered host selector
 	if ipCheck {
-		infos = shm.filteredTree.SelectRandom(num, blacklist, addrBlacklist)
+		infos = shm.selectHosts(num, blacklist, addrBlacklist)
 	} else {
-		infos = shm.filteredTree.SelectRandom(num, blacklist, nil)
+		infos = shm.selectHosts(num, blacklist, nil)
 	}
 
 	return
@@ -321,11 +347,18 @@ func (shm *StorageHostManager) StorageHostRanks() (rankings []StorageHostRank) {
 	// based on the host information, calculate the evaluation
 	for _, host := range allHosts {
 		eval := shm.evalFunc(host)
-
-		rankings = append(rankings, StorageHostRank{
+		rank := StorageHostRank{
 			EvaluationDetail: eval.EvaluationDetail(eval.Evaluation(), false, false),
 			EnodeID:          host.EnodeID.String(),
-		})
+		}
+
+		// flag the storage host charging more than advertised
+		if audit, exists := shm.priceAudits[host.EnodeID]; exists {
+			rank.PriceDiscrepancies = audit.Discrepancies
+			rank.Overcharged = audit.Overcharged
+			rank.PriceBlacklisted = audit.Blacklisted
+		}
+		rankings = append(rankings, rank)
 	}
 
 	return
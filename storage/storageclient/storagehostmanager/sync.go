@@ -116,6 +116,12 @@ func (shm *StorageHostManager) insertStorageHostInformation(info storage.HostInf
 	oldInfo.EnodeURL = info.EnodeURL
 	oldInfo.IP = info.IP
 
+	// the announcement of the bootstrap host is found, the first seen height is reliable now
+	if oldInfo.Bootstrap {
+		oldInfo.Bootstrap = false
+		oldInfo.FirstSeen = shm.blockHeight
+	}
+
 	// check if the ip address has been changed, if so, update the IP network field
 	// and update the LastIPNetWorkChange time
 	networkAddr, err := storagehosttree.IPNetwork(oldInfo.IP)
@@ -22,6 +22,11 @@ import (
 type StorageHostRank struct {
 	storagehosttree.EvaluationDetail
 	EnodeID string
+
+	// price audit of the storage host
+	PriceDiscrepancies uint64
+	Overcharged        common.BigInt
+	PriceBlacklisted   bool
 }
 
 // hostInfoGenerator will randomly generate storage host information
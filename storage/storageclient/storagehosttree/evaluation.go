@@ -5,6 +5,8 @@
 package storagehosttree
 
 import (
+	"math"
+
 	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/storage"
 )
@@ -19,7 +21,15 @@ type HostEvaluation interface {
 // EvaluationFunc is used to calculate storage host evaluation
 type EvaluationFunc func(storage.HostInfo) HostEvaluation
 
-// EvaluationDetail contains the detailed storage host evaluation factors
+// EvaluationScale is the evaluation of the storage host scoring 1 in every factor. The
+// evaluation is the product of the normalized factor scores multiplied by the scale, thus
+// ranges in [1, EvaluationScale] no matter how the factors are calculated internally
+const EvaluationScale = 1e15
+
+// EvaluationDetail contains the detailed storage host evaluation factors. The factor fields
+// are the raw factor values, whose scale depends on the internal weights of the evaluation
+// and could change across the client versions. Scores contains the factors normalized to
+// [0, 1], and Score is the product of them, which shall be used to compare the storage hosts
 type EvaluationDetail struct {
 	Evaluation     common.BigInt `json:"evaluation"`
 	ConversionRate float64       `json:"conversionrate"`
@@ -30,9 +40,30 @@ type EvaluationDetail struct {
 	ContractPriceFactor    float64 `json:"contractpriceFactor"`
 	StorageRemainingFactor float64 `json:"storageremainingfactor"`
 	UptimeFactor           float64 `json:"uptimefactor"`
+	QoSFactor              float64 `json:"qosfactor"`
+
+	Score  float64          `json:"score"`
+	Scores EvaluationScores `json:"scores"`
 }
 
-// EvaluationCriteria contains statistics that used to calculate the storage host evaluation
+// EvaluationScores contains the evaluation factors normalized to [0, 1], where 1 is the best.
+// The factors with raw values bounded in [0, 1] are used as is. The deposit and contract
+// price factors are unbounded, and are normalized to 0.5 for the storage host meeting exactly
+// the expectation of the storage client, approaching 1 for the better hosts and 0 for the worse
+type EvaluationScores struct {
+	Presence         float64 `json:"presence"`
+	Deposit          float64 `json:"deposit"`
+	Interaction      float64 `json:"interaction"`
+	ContractPrice    float64 `json:"contractprice"`
+	StorageRemaining float64 `json:"storageremaining"`
+	Uptime           float64 `json:"uptime"`
+	QoS              float64 `json:"qos"`
+}
+
+// EvaluationCriteria contains statistics that used to calculate the storage host evaluation.
+// DepositReference and ContractPriceReference are the raw deposit and contract price factors
+// of the storage host meeting exactly the expectation of the storage client, which are used
+// to normalize the factors. Zero reference means the factor is taken as normalized already
 type EvaluationCriteria struct {
 	PresenceFactor         float64
 	DepositFactor          float64
@@ -40,12 +71,33 @@ type EvaluationCriteria struct {
 	ContractPriceFactor    float64
 	StorageRemainingFactor float64
 	UptimeFactor           float64
+	QoSFactor              float64
+
+	DepositReference       float64
+	ContractPriceReference float64
+}
+
+// Scores returns the evaluation factors normalized to [0, 1]
+func (ec EvaluationCriteria) Scores() EvaluationScores {
+	return EvaluationScores{
+		Presence:         normalizeFactor(ec.PresenceFactor, 0),
+		Deposit:          normalizeFactor(ec.DepositFactor, ec.DepositReference),
+		Interaction:      normalizeFactor(ec.InteractionFactor, 0),
+		ContractPrice:    normalizeFactor(ec.ContractPriceFactor, ec.ContractPriceReference),
+		StorageRemaining: normalizeFactor(ec.StorageRemainingFactor, 0),
+		Uptime:           normalizeFactor(ec.UptimeFactor, 0),
+		QoS:              normalizeFactor(ec.QoSFactor, 0),
+	}
+}
+
+// Score returns the product of the normalized factor scores, which is in [0, 1]
+func (es EvaluationScores) Score() float64 {
+	return es.Presence * es.Deposit * es.Interaction * es.ContractPrice * es.StorageRemaining * es.Uptime * es.QoS
 }
 
 // Evaluation will be used to calculate the storage host evaluation
 func (ec EvaluationCriteria) Evaluation() common.BigInt {
-	total := ec.PresenceFactor * ec.DepositFactor * ec.InteractionFactor *
-		ec.ContractPriceFactor * ec.StorageRemainingFactor * ec.UptimeFactor
+	total := ec.Scores().Score() * EvaluationScale
 
 	// making sure the total is at least 1
 	if total < 1 {
@@ -65,6 +117,7 @@ func (ec EvaluationCriteria) EvaluationDetail(evalAll common.BigInt, ignoreAge,
 	}
 
 	eval := ec.Evaluation()
+	scores := ec.Scores()
 
 	ratio := conversionRate(eval, evalAll)
 
@@ -77,10 +130,30 @@ func (ec EvaluationCriteria) EvaluationDetail(evalAll common.BigInt, ignoreAge,
 		ContractPriceFactor:    ec.ContractPriceFactor,
 		StorageRemainingFactor: ec.StorageRemainingFactor,
 		UptimeFactor:           ec.UptimeFactor,
+		QoSFactor:              ec.QoSFactor,
+		Score:                  scores.Score(),
+		Scores:                 scores,
 	}
 
 }
 
+// normalizeFactor maps the raw factor value to [0, 1]. With a positive reference, the raw value
+// is mapped with raw / (raw + reference), so that the reference is mapped to 0.5. Otherwise the
+// raw value is expected in [0, 1] already, and is only clamped
+func normalizeFactor(raw, reference float64) float64 {
+	switch {
+	case math.IsNaN(raw) || raw <= 0:
+		return 0
+	case math.IsInf(raw, 1):
+		return 1
+	case reference > 0:
+		return 1 / (1 + reference/raw)
+	case raw > 1:
+		return 1
+	}
+	return raw
+}
+
 // conversionRate calculate the rate of evalAll / (eval * 50)
 func conversionRate(eval, evalAll common.BigInt) float64 {
 	// eliminate 0 for denominator
@@ -51,6 +51,59 @@ func TestEvaluationCriteria_EvaluationDetail(t *testing.T) {
 	}
 }
 
+func TestNormalizeFactor(t *testing.T) {
+	tables := []struct {
+		raw       float64
+		reference float64
+		score     float64
+	}{
+		{0.25, 0, 0.25},
+		{5, 0, 1},
+		{-1, 0, 0},
+		{math.NaN(), 0, 0},
+		{100, 100, 0.5},
+		{100, 300, 0.25},
+		{math.Inf(1), 100, 1},
+		{1e-300, 1e100, 0},
+	}
+
+	for _, table := range tables {
+		if score := normalizeFactor(table.raw, table.reference); score != table.score {
+			t.Errorf("normalizing %v with reference %v: expect %v, got %v", table.raw, table.reference, table.score, score)
+		}
+	}
+}
+
+func TestEvaluationCriteria_Scores(t *testing.T) {
+	ec := EvaluationCriteria{
+		PresenceFactor:         1,
+		DepositFactor:          1e72,
+		InteractionFactor:      0.5,
+		ContractPriceFactor:    0.25,
+		StorageRemainingFactor: 1,
+		UptimeFactor:           1,
+		QoSFactor:              1,
+		DepositReference:       1e72,
+		ContractPriceReference: 0.75,
+	}
+	scores := ec.Scores()
+	if scores.Deposit != 0.5 || scores.ContractPrice != 0.25 {
+		t.Fatalf("unexpected scores %+v", scores)
+	}
+	if score := scores.Score(); score != 0.0625 {
+		t.Errorf("score expect 0.0625, got %v", score)
+	}
+	if eval := ec.Evaluation(); eval.Cmp(common.NewBigInt(0.0625*EvaluationScale)) != 0 {
+		t.Errorf("evaluation expect %v, got %v", 0.0625*EvaluationScale, eval)
+	}
+
+	// the evaluation is not affected by the scale of the raw values
+	ec.DepositFactor, ec.DepositReference = 1, 1
+	if detail := ec.EvaluationDetail(common.NewBigInt(1), false, false); detail.Score != 0.0625 || detail.DepositFactor != 1 {
+		t.Errorf("unexpected evaluation detail %+v", detail)
+	}
+}
+
 func randomCriteria() EvaluationCriteria {
 	return EvaluationCriteria{
 		PresenceFactor:         randFloat64(),
@@ -59,6 +112,7 @@ func randomCriteria() EvaluationCriteria {
 		ContractPriceFactor:    randFloat64(),
 		StorageRemainingFactor: randFloat64(),
 		UptimeFactor:           randFloat64(),
+		QoSFactor:              1,
 	}
 }
 
@@ -70,6 +124,7 @@ func partialRandomCriteria() EvaluationCriteria {
 		ContractPriceFactor:    100,
 		StorageRemainingFactor: randFloat64(),
 		UptimeFactor:           randFloat64(),
+		QoSFactor:              1,
 	}
 }
 
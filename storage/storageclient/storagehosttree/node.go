@@ -5,6 +5,8 @@
 package storagehosttree
 
 import (
+	"bytes"
+
 	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/storage"
 )
@@ -39,9 +41,17 @@ type nodeEntry struct {
 type nodeEntries []nodeEntry
 
 // the storage host with higher weight will placed in the front of the list
-func (ne nodeEntries) Len() int           { return len(ne) }
-func (ne nodeEntries) Less(i, j int) bool { return ne[i].eval.Cmp(ne[j].eval) > 0 }
-func (ne nodeEntries) Swap(i, j int)      { ne[i], ne[j] = ne[j], ne[i] }
+func (ne nodeEntries) Len() int      { return len(ne) }
+func (ne nodeEntries) Swap(i, j int) { ne[i], ne[j] = ne[j], ne[i] }
+
+// Less compares the evaluation first. The entries with the same evaluation are ordered
+// by the enode ID, so that the order is stable across the calls
+func (ne nodeEntries) Less(i, j int) bool {
+	if cmp := ne[i].eval.Cmp(ne[j].eval); cmp != 0 {
+		return cmp > 0
+	}
+	return bytes.Compare(ne[i].EnodeID[:], ne[j].EnodeID[:]) < 0
+}
 
 // newNode will create and initialize a new node object, which will be inserted into
 // the StorageHostTree
@@ -134,6 +134,15 @@ func (t *StorageHostTree) all() (his []storage.HostInfo) {
 	return
 }
 
+// EvaluationTotal returns the sum of the evaluations of all storage hosts stored
+// in the tree, which is the base used for the weighted random selection
+func (t *StorageHostTree) EvaluationTotal() common.BigInt {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.root.evalTotal
+}
+
 // RetrieveHostInfo will get storage host information from the tree based on the
 // enode ID
 func (t *StorageHostTree) RetrieveHostInfo(enodeID enode.ID) (storage.HostInfo, bool) {
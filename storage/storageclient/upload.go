@@ -18,7 +18,7 @@ import (
 
 // Upload instructs the storage client to start tracking a file. The storage client will
 // automatically upload and repair tracked files using a background loop.
-func (client *StorageClient) Upload(up storage.FileUploadParams) error {
+func (client *StorageClient) Upload(up storage.FileUploadParams) (err error) {
 	if err := client.tm.Add(); err != nil {
 		return err
 	}
@@ -33,6 +33,15 @@ func (client *StorageClient) Upload(up storage.FileUploadParams) error {
 		return dxdir.ErrUploadDirectory
 	}
 
+	// Run the hooks registered for the upload
+	info := HookInfo{Operation: HookUpload, DxPath: up.DxPath.Path, LocalPath: up.Source, FileSize: uint64(sourceInfo.Size())}
+	if err = client.hooks.runPreHooks(info); err != nil {
+		return err
+	}
+	defer func() {
+		client.hooks.runPostHooks(info, err)
+	}()
+
 	file, err := os.Open(up.Source)
 	if err != nil {
 		return fmt.Errorf("unable to open the source file, error: %v", err)
@@ -90,6 +99,10 @@ func (client *StorageClient) Upload(up storage.FileUploadParams) error {
 		return fmt.Errorf("source file size is 0, fileName: %s", sourceInfo.Name())
 	}
 
+	// Record the checksum of the source file, which is used to check whether the local
+	// file can still be used as the repair source
+	client.recordSourceChecksum(entry.CopyEntry(), up.Source)
+
 	// Update the health of the DxFile directory recursively to ensure the health is updated with the new file
 	go client.fileSystem.InitAndUpdateDirMetadata(dirDxPath)
 
@@ -108,3 +121,59 @@ func (client *StorageClient) Upload(up storage.FileUploadParams) error {
 	}
 	return nil
 }
+
+// replaceFile uploads the source to the dxpath, replacing the existing file. The new content
+// is uploaded to a separate file first, and the existing file is replaced only after the new
+// content is tracked, so the existing file is kept when the upload fails. The local path of
+// the replaced file is returned, which is empty if no file is replaced
+func (client *StorageClient) replaceFile(up storage.FileUploadParams) (string, error) {
+	if !client.dxFileExists(up.DxPath) {
+		return "", client.Upload(up)
+	}
+
+	dxPath := up.DxPath
+	replacing, err := storage.NewDxPath(dxPath.Path + storage.ReplacingFileSuffix)
+	if err != nil {
+		return "", err
+	}
+	replaced, err := storage.NewDxPath(dxPath.Path + storage.ReplacedFileSuffix)
+	if err != nil {
+		return "", err
+	}
+
+	// the files left by an interrupted replacement are deleted first
+	if err = client.DeleteFile(replacing); err != nil {
+		return "", err
+	}
+	if err = client.DeleteFile(replaced); err != nil {
+		return "", err
+	}
+	up.DxPath = replacing
+	if err = client.Upload(up); err != nil {
+		if deleteErr := client.DeleteFile(replacing); deleteErr != nil {
+			client.log.Warn("failed to delete the file of the failed replacement", "path", replacing.Path, "err", deleteErr)
+		}
+		return "", err
+	}
+
+	// the existing file is renamed aside, and restored if the new content cannot be renamed in
+	var source string
+	if entry, err := client.fileSystem.OpenDxFile(dxPath); err == nil {
+		source = string(entry.LocalPath())
+		entry.Close()
+	}
+	err = client.fileSystem.RenameDxFile(dxPath, replaced)
+	if err != nil && err != dxfile.ErrUnknownFile {
+		return "", fmt.Errorf("cannot rename the existing file: %v", err)
+	}
+	if err = client.fileSystem.RenameDxFile(replacing, dxPath); err != nil {
+		if restoreErr := client.fileSystem.RenameDxFile(replaced, dxPath); restoreErr != nil {
+			client.log.Warn("failed to restore the replaced file", "path", dxPath.Path, "err", restoreErr)
+		}
+		return "", fmt.Errorf("cannot replace the existing file: %v", err)
+	}
+	if err = client.DeleteFile(replaced); err != nil {
+		client.log.Warn("failed to delete the replaced file", "path", replaced.Path, "err", err)
+	}
+	return source, nil
+}
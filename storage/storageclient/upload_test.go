@@ -12,6 +12,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -175,6 +176,42 @@ func TestPushFileToSegmentHeap(t *testing.T) {
 	}
 }
 
+func TestUploadHeapCollapseDuplicates(t *testing.T) {
+	uh := uploadHeap{
+		pendingSegments: make(map[uploadSegmentID]*unfinishedUploadSegment),
+		activeSegments:  make(map[uploadSegmentID]*unfinishedUploadSegment),
+	}
+	id := uploadSegmentID{fid: dxfile.FileID{1}, index: 2}
+	segment := &unfinishedUploadSegment{id: id}
+
+	if !uh.push(segment) {
+		t.Fatal("the first segment should be pushed")
+	}
+	if uh.push(&unfinishedUploadSegment{id: id}) {
+		t.Fatal("the duplicate pending segment should be collapsed")
+	}
+
+	// the segment being uploaded collapses the duplicate and shares the stuck repair result
+	if popped := uh.pop(); popped != segment {
+		t.Fatal("unexpected segment popped")
+	}
+	if uh.push(&unfinishedUploadSegment{id: id, stuckRepair: true}) {
+		t.Fatal("the duplicate active segment should be collapsed")
+	}
+	if uh.len() != 0 {
+		t.Fatalf("expect empty heap, got %v", uh.len())
+	}
+	if !segment.stuckRepair {
+		t.Error("the stuck repair flag of the duplicate not merged")
+	}
+
+	// once released, the segment could be pushed again
+	uh.release(segment)
+	if !uh.push(&unfinishedUploadSegment{id: id}) {
+		t.Error("the segment should be pushed after released")
+	}
+}
+
 func TestRequiredContract(t *testing.T) {
 	a := 9
 	b := 10
@@ -185,6 +222,50 @@ func TestRequiredContract(t *testing.T) {
 
 }
 
+func TestClaimSectorSlot(t *testing.T) {
+	hosts := map[string]struct{}{"a": {}, "b": {}, "c": {}, "d": {}}
+	positions := hostSectorPositions(hosts)
+
+	// the host storing the first sector rotates across the consecutive segments
+	leading := make(map[string]bool)
+	for index := uint64(0); index < 4; index++ {
+		uc := &unfinishedUploadSegment{
+			index:             index,
+			sectorSlotsStatus: make([]bool, 4),
+			hostPositions:     positions,
+		}
+		for host := range hosts {
+			if uc.claimSectorSlot(host) == 0 {
+				leading[host] = true
+			}
+		}
+		for i, taken := range uc.sectorSlotsStatus {
+			if !taken {
+				t.Fatalf("segment %v: sector %v not claimed", index, i)
+			}
+		}
+	}
+	if len(leading) != len(hosts) {
+		t.Fatalf("the first sectors are stored by %v hosts, expect %v", len(leading), len(hosts))
+	}
+
+	// the next free sector is claimed if the preferred one is taken, and -1 if all are taken
+	uc := &unfinishedUploadSegment{
+		index:             1,
+		sectorSlotsStatus: []bool{false, true, false},
+		hostPositions:     positions,
+	}
+	if index := uc.claimSectorSlot("a"); index != 2 {
+		t.Fatalf("expect sector 2 claimed, got %v", index)
+	}
+	if index := uc.claimSectorSlot("e"); index != 0 {
+		t.Fatalf("expect sector 0 claimed by unknown host, got %v", index)
+	}
+	if index := uc.claimSectorSlot("b"); index != -1 {
+		t.Fatalf("expect no sector claimed, got %v", index)
+	}
+}
+
 func TestCreatAndAssignToWorkers(t *testing.T) {
 	storage.ENV = storage.EnvTest
 
@@ -423,3 +504,70 @@ func getDxPathFromPath(root string, path string) storage.DxPath {
 	}
 	return storage.RootDxPath()
 }
+
+func TestReplaceFile(t *testing.T) {
+	storage.ENV = storage.EnvTest
+
+	sct := newStorageClientTester(t)
+	sc := sct.Client
+	defer sc.Close()
+
+	contractSet := sc.contractManager.GetStorageContractSet()
+	prevIDs := make(map[storage.ContractID]struct{})
+	for _, id := range contractSet.IDs() {
+		prevIDs[id] = struct{}{}
+	}
+	if err := sc.contractManager.InsertRandomActiveContracts(2); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, id := range contractSet.IDs() {
+			if _, exists := prevIDs[id]; exists {
+				continue
+			}
+			if c, exists := contractSet.Acquire(id); exists {
+				contractSet.Delete(c)
+			}
+		}
+	}()
+
+	dir, err := ioutil.TempDir("", "replace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sources := make([]string, 2)
+	for i := range sources {
+		sources[i] = filepath.Join(dir, strconv.Itoa(i))
+		if err = ioutil.WriteFile(sources[i], generateRandomBytes(8), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dxPath := randomDxPath()
+	if replaced, err := sc.replaceFile(storage.FileUploadParams{Source: sources[0], DxPath: dxPath}); err != nil || replaced != "" {
+		t.Fatalf("upload of the new file: %v, %v", replaced, err)
+	}
+	replaced, err := sc.replaceFile(storage.FileUploadParams{Source: sources[1], DxPath: dxPath})
+	if err != nil || replaced != sources[0] {
+		t.Fatalf("replace expect the replaced source %v, got %v, %v", sources[0], replaced, err)
+	}
+
+	// the failed replacement keeps the existing file
+	if _, err = sc.replaceFile(storage.FileUploadParams{Source: filepath.Join(dir, "missing"), DxPath: dxPath}); err == nil {
+		t.Fatalf("replace with the missing source should fail")
+	}
+	entry, err := sc.fileSystem.OpenDxFile(dxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source := string(entry.LocalPath()); source != sources[1] {
+		t.Errorf("the source of the file expect %v, got %v", sources[1], source)
+	}
+	entry.Close()
+	for _, suffix := range []string{storage.ReplacingFileSuffix, storage.ReplacedFileSuffix} {
+		if sc.dxFileExists(storage.DxPath{Path: dxPath.Path + suffix}) {
+			t.Errorf("the file with suffix %v should be deleted", suffix)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/DxChainNetwork/godx/common/alert"
 	"github.com/DxChainNetwork/godx/storage"
 	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
 	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxfile"
@@ -64,8 +65,12 @@ type uploadHeap struct {
 	heap uploadSegmentHeap
 
 	// pendingSegments is a map containing all the segments are that currently
-	// assigned to workers and are being repaired or uploaded
-	pendingSegments map[uploadSegmentID]struct{}
+	// in the heap waiting to be uploaded or repaired
+	pendingSegments map[uploadSegmentID]*unfinishedUploadSegment
+
+	// activeSegments is a map containing all the segments popped from the heap, which
+	// are being prepared or assigned to workers, until the segment is released
+	activeSegments map[uploadSegmentID]*unfinishedUploadSegment
 
 	// Control channels
 	segmentComing       chan struct{}
@@ -81,40 +86,73 @@ func (uh *uploadHeap) len() int {
 	return uhLen
 }
 
+// backlog returns the number of segments pending in the heap, the number of them which are
+// stuck, and the number of segments popped but not released yet
+func (uh *uploadHeap) backlog() (pending, stuck, active int) {
+	uh.mu.Lock()
+	defer uh.mu.Unlock()
+	for _, uc := range uh.heap {
+		if uc.stuck {
+			stuck++
+		}
+	}
+	return uh.heap.Len(), stuck, len(uh.activeSegments)
+}
+
+// push adds the segment to the heap. If the same segment is already pending in the heap or
+// being uploaded, the segment is collapsed into the existing one, so that the segment is not
+// uploaded twice, and false is returned
 func (uh *uploadHeap) push(uuc *unfinishedUploadSegment) bool {
-	var added bool
 	uh.mu.Lock()
-	_, exists := uh.pendingSegments[uuc.id]
+	defer uh.mu.Unlock()
+
+	existing, exists := uh.pendingSegments[uuc.id]
 	if !exists {
-		uh.pendingSegments[uuc.id] = struct{}{}
-		heap.Push(&uh.heap, uuc)
-		added = true
+		existing, exists = uh.activeSegments[uuc.id]
 	}
-	uh.mu.Unlock()
-	return added
+	if exists {
+		existing.collapse(uuc)
+		return false
+	}
+	uh.pendingSegments[uuc.id] = uuc
+	heap.Push(&uh.heap, uuc)
+	return true
 }
 
+// pop pops the segment from the heap, and marks it as active until released
 func (uh *uploadHeap) pop() (uc *unfinishedUploadSegment) {
 	uh.mu.Lock()
 	if len(uh.heap) > 0 {
 		uc = heap.Pop(&uh.heap).(*unfinishedUploadSegment)
 		delete(uh.pendingSegments, uc.id)
+		uh.activeSegments[uc.id] = uc
 	}
 	uh.mu.Unlock()
 	return uc
 }
 
+// release removes the segment from the active segments, after which the same
+// segment could be pushed to the heap again
+func (uh *uploadHeap) release(uc *unfinishedUploadSegment) {
+	uh.mu.Lock()
+	if uh.activeSegments[uc.id] == uc {
+		delete(uh.activeSegments, uc.id)
+	}
+	uh.mu.Unlock()
+}
+
 func (client *StorageClient) createUnfinishedSegments(entry *dxfile.FileSetEntryWithID, hosts map[string]struct{}, target uploadTarget, hostHealthInfoTable storage.HostHealthInfoTable) ([]*unfinishedUploadSegment, error) {
 	ec, err := entry.ErasureCode()
 	if err != nil {
 		return nil, err
 	}
+	hosts = client.storageClassHosts(entry.DxPath(), hosts, int(ec.NumSectors()))
 	if len(client.workerPool) < int(ec.MinSectors()) {
 		client.log.Info("cannot create any segment from file because there are not enough workers, so marked all unhealthy segments as stuck")
 
 		var err error
 		if err = entry.MarkAllUnhealthySegmentsAsStuck(hostHealthInfoTable); err != nil {
-			client.log.Error("unable to mark all segments as stuck", "err", err)
+			alert.Raise(client.log, alert.SeverityError, alertModule, "unable to mark all segments as stuck", "err", err)
 		} else {
 			err = errors.New("not enough storage contracts meets the minimum sectors")
 		}
@@ -138,6 +176,7 @@ func (client *StorageClient) createUnfinishedSegments(entry *dxfile.FileSetEntry
 
 	// Assemble the set of segments
 	newUnfinishedSegments := make([]*unfinishedUploadSegment, len(segmentIndexes))
+	hostPositions := hostSectorPositions(hosts)
 	for i, index := range segmentIndexes {
 		// Sanity check: fileUID should not be the empty value.
 		fid := entry.UID()
@@ -175,6 +214,7 @@ func (client *StorageClient) createUnfinishedSegments(entry *dxfile.FileSetEntry
 
 			sectorSlotsStatus: make([]bool, ec.NumSectors()),
 			unusedHosts:       make(map[string]struct{}),
+			hostPositions:     hostPositions,
 		}
 
 		// Every Segment can have a different set of unused hosts.
@@ -275,6 +315,14 @@ func (client *StorageClient) createAndPushRandomSegment(files []*dxfile.FileSetE
 	randFileIndex := rand.Intn(len(files))
 	file := files[randFileIndex]
 
+	// Run the hooks registered for the repair. The file vetoed is left stuck
+	info := HookInfo{Operation: HookRepair, DxPath: file.DxPath().Path, LocalPath: string(file.LocalPath()), FileSize: file.FileSize()}
+	if err := client.hooks.runPreHooks(info); err != nil {
+		client.log.Info("stuck repair vetoed", "dxpath", info.DxPath, "err", err)
+		return
+	}
+	defer client.hooks.runPostHooks(info, nil)
+
 	client.lock.Lock()
 	// Build the unfinished stuck segments from the file
 	unfinishedUploadSegments, _ := client.createUnfinishedSegments(file, hosts, target, hostHealthInfoTable)
@@ -470,6 +518,7 @@ func (client *StorageClient) uploadOrRepair() {
 		client.lock.Unlock()
 		if availableWorkers < nextSegment.sectorsMinNeedNum {
 			client.log.Info("Setting segment as stuck because there are not enough good workers", "segmentID", nextSegment.id)
+			client.uploadHeap.release(nextSegment)
 			err := client.setStuckAndClose(nextSegment, true)
 			if err != nil {
 				client.log.Error("Unable to mark segment as stuck and close", "err", err)
@@ -481,6 +530,7 @@ func (client *StorageClient) uploadOrRepair() {
 		err := client.doProcessNextSegment(nextSegment)
 		if err != nil {
 			client.log.Error("Unable to prepare next segment without issues", "segmentID", nextSegment.id, "err", err)
+			client.uploadHeap.release(nextSegment)
 			err = client.setStuckAndClose(nextSegment, true)
 			if err != nil {
 				client.log.Error("Unable to mark segment as stuck and close", "err", err)
@@ -493,7 +543,9 @@ func (client *StorageClient) uploadOrRepair() {
 		if consecutiveSegmentUploads >= MaxConsecutiveSegmentUploads {
 			var stuckSegments []*unfinishedUploadSegment
 			for client.uploadHeap.len() > 0 {
-				if c := client.uploadHeap.pop(); c.stuck {
+				c := client.uploadHeap.pop()
+				client.uploadHeap.release(c)
+				if c.stuck {
 					stuckSegments = append(stuckSegments, c)
 				}
 			}
@@ -530,6 +582,23 @@ func (client *StorageClient) doUpload() error {
 		return nil
 	}
 
+	defer dxFile.Close()
+
+	// Run the hooks registered for the repair. The segments of the file vetoed are marked
+	// as stuck, so that the file is not selected again, and is retried by the stuck loop
+	info := HookInfo{Operation: HookRepair, DxPath: dxFile.DxPath().Path, LocalPath: string(dxFile.LocalPath()), FileSize: dxFile.FileSize()}
+	if err = client.hooks.runPreHooks(info); err != nil {
+		client.log.Info("repair vetoed, marking the unhealthy segments as stuck", "dxpath", info.DxPath, "err", err)
+		if err = dxFile.MarkAllUnhealthySegmentsAsStuck(client.contractManager.HostHealthMap()); err != nil {
+			return err
+		}
+		dirPath, err := dxFile.DxPath().Parent()
+		if err != nil {
+			return err
+		}
+		return client.fileSystem.InitAndUpdateDirMetadata(dirPath)
+	}
+
 	// Refresh the worker pool and get the set of hosts that are currently
 	// useful for uploading
 	hosts := client.refreshHostsAndWorkers()
@@ -537,6 +606,7 @@ func (client *StorageClient) doUpload() error {
 	// Push a min-heap of segments organized by upload progress
 	// we don't worry about the dxfile nil problem. we have done it above
 	client.pushDirOrFileToSegmentHeap(dxFile.DxPath(), false, hosts, targetUnstuckSegments)
+	client.hooks.runPostHooks(info, nil)
 	client.uploadHeap.mu.Lock()
 	heapLen := client.uploadHeap.heap.Len()
 	client.uploadHeap.mu.Unlock()
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -63,10 +64,55 @@ type unfinishedUploadSegment struct {
 	sectorsUploadingNum int                 // number of sectors that are being uploaded, but aren't finished yet (may fail)
 	released            bool                // whether this segment has been released from the active segments set
 	unusedHosts         map[string]struct{} // hosts that aren't yet storing any sectors or performing any work
+	hostPositions       map[string]int      // position of the hosts in the sector layout, shared by the segments of the file
 	workersRemain       int                 // number of inactive workers still able to upload a sector
 	workerBackups       []*worker           // workers that can be used if other workers fail
 }
 
+// hostSectorPositions returns the positions of the hosts in the sector layout of a file.
+// The hosts are positioned in the order of their ids, so that the positions are consistent
+// across the segments of the file
+func hostSectorPositions(hosts map[string]struct{}) map[string]int {
+	ids := make([]string, 0, len(hosts))
+	for id := range hosts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	positions := make(map[string]int, len(ids))
+	for i, id := range ids {
+		positions[id] = i
+	}
+	return positions
+}
+
+// preferredSectorIndex returns the sector index the host is expected to store in the segment.
+// The layout is rotated by the segment index, so that a host storing the leading sectors
+// of a segment does not store the leading sectors of the next segment. With the critical
+// sectors spread across the hosts, a sequential read does not pile up on the same hosts.
+func (uc *unfinishedUploadSegment) preferredSectorIndex(hostID string) int {
+	position, exists := uc.hostPositions[hostID]
+	if !exists || len(uc.sectorSlotsStatus) == 0 {
+		return 0
+	}
+	return int((uint64(position) + uc.index) % uint64(len(uc.sectorSlotsStatus)))
+}
+
+// claimSectorSlot finds a sector not yet uploaded for the host to upload, starting from the
+// preferred sector index of the host, and marks it as uploading. -1 is returned if all sectors
+// are taken. Note uc.mu must be held
+func (uc *unfinishedUploadSegment) claimSectorSlot(hostID string) int {
+	numSectors := len(uc.sectorSlotsStatus)
+	start := uc.preferredSectorIndex(hostID)
+	for i := 0; i < numSectors; i++ {
+		index := (start + i) % numSectors
+		if !uc.sectorSlotsStatus[index] {
+			uc.sectorSlotsStatus[index] = true
+			return index
+		}
+	}
+	return -1
+}
+
 // notifyBackupWorkers is called when a worker fails to upload a sector, meaning
 // that the backup workers may now be needed to help the sector finish uploading
 func (uc *unfinishedUploadSegment) notifyBackupWorkers() {
@@ -85,6 +131,14 @@ func (uc *unfinishedUploadSegment) notifyBackupWorkers() {
 	}
 }
 
+// collapse merges the duplicate of the segment into the segment. The duplicate is not uploaded,
+// and the stuck repair flag is kept so that the result of the upload is reported for both
+func (uc *unfinishedUploadSegment) collapse(duplicate *unfinishedUploadSegment) {
+	uc.mu.Lock()
+	uc.stuckRepair = uc.stuckRepair || duplicate.stuckRepair
+	uc.mu.Unlock()
+}
+
 // IsSegmentUploadComplete checks some fields of the segment to determine if the segment is completed
 // 1）no remain workers and no uploading task
 // 2) completely upload and no uploading task
@@ -104,14 +158,6 @@ func (uc *unfinishedUploadSegment) IsSegmentUploadComplete() bool {
 // Now it may be that one sector will not be assigned to worker, and this doesn't have a big impact on the upload process
 // But we will optimize this features and schedule strategy is more balanced and fair
 func (client *StorageClient) dispatchSegment(uc *unfinishedUploadSegment) {
-	// Add segment to pendingSegments map
-	client.uploadHeap.mu.Lock()
-	_, exists := client.uploadHeap.pendingSegments[uc.id]
-	if !exists {
-		client.uploadHeap.pendingSegments[uc.id] = struct{}{}
-	}
-	client.uploadHeap.mu.Unlock()
-
 	// Distribute the segment to each worker in the work pool, marking the number of workers that have received the segment
 	client.lock.Lock()
 	uc.workersRemain += len(client.workerPool)
@@ -127,8 +173,7 @@ func (client *StorageClient) dispatchSegment(uc *unfinishedUploadSegment) {
 // assignSectorTaskToWorker will assign non uploaded sector to worker
 func (client *StorageClient) assignSectorTaskToWorker(workers []*worker, uc *unfinishedUploadSegment) {
 	for _, w := range workers {
-		if w.isReady(uc) {
-			w.pendingSegments = append(w.pendingSegments, uc)
+		if w.isReady(uc) && w.queueUploadSegment(uc) {
 			select {
 			case w.uploadChan <- struct{}{}:
 			default:
@@ -145,9 +190,10 @@ func (client *StorageClient) downloadLogicalSegmentData(segment *unfinishedUploa
 		downloadLength = segment.fileEntry.FileSize() % segment.length
 	}
 
-	// Create the download
+	// Create the download. The segment of the storage class transition target is recovered
+	// from the source file, as the target has no data on the network yet
 	buf := newDownloadBuffer(segment.length, segment.fileEntry.SectorSize())
-	snap, err := segment.fileEntry.Snapshot()
+	snap, err := client.repairDownloadSnapshot(segment.fileEntry)
 	if err != nil {
 		return fmt.Errorf("cannot create the snapshot: %v", err)
 	}
@@ -211,6 +257,7 @@ func (client *StorageClient) retrieveDataAndDispatchSegment(segment *unfinishedU
 
 	ec, err := segment.fileEntry.ErasureCode()
 	if err != nil {
+		client.uploadHeap.release(segment)
 		return
 	}
 
@@ -241,6 +288,8 @@ func (client *StorageClient) retrieveDataAndDispatchSegment(segment *unfinishedU
 	for _, b := range segment.logicalSegmentData {
 		segmentBytes = append(segmentBytes, b...)
 	}
+	downloadBuffer{buf: segment.logicalSegmentData}.release()
+	segment.logicalSegmentData = nil
 	segment.physicalSegmentData, err = ec.Encode(segmentBytes)
 	if err != nil {
 		segment.workersRemain = 0
@@ -253,7 +302,6 @@ func (client *StorageClient) retrieveDataAndDispatchSegment(segment *unfinishedU
 		return
 	}
 
-	segment.logicalSegmentData = nil
 	client.memoryManager.Return(erasureCodingMemory)
 	segment.memoryReleased += erasureCodingMemory
 
@@ -302,6 +350,16 @@ func (client *StorageClient) retrieveLogicalSegmentData(segment *unfinishedUploa
 		return errors.New("file not available locally")
 	}
 
+	// Check the local file has not been changed since uploaded. A changed local file
+	// cannot be used to repair the segment, recover the data from network instead
+	if !client.sourceVerifier.verify(segment.fileEntry.DxFile) {
+		client.log.Warn("local file changed since uploaded", "path", segment.fileEntry.LocalPath())
+		if needDownload {
+			return client.downloadLogicalSegmentData(segment)
+		}
+		return errors.New("local file changed since uploaded")
+	}
+
 	// Try to read the file content from disk. If failed, go through needDownload
 	osFile, err := os.Open(string(segment.fileEntry.LocalPath()))
 	if err != nil && needDownload {
@@ -316,9 +374,11 @@ func (client *StorageClient) retrieveLogicalSegmentData(segment *unfinishedUploa
 	_, err = buf.ReadFrom(sr)
 	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF && needDownload {
 		client.log.Error("failed to read file, downloading instead", "err", err)
+		buf.release()
 		return client.downloadLogicalSegmentData(segment)
 	} else if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		client.log.Error("failed to read file locally", "err", err)
+		buf.release()
 		return errors.New("failed to read file locally")
 	}
 	segment.logicalSegmentData = buf.buf
@@ -358,9 +418,7 @@ func (client *StorageClient) cleanupUploadSegment(uc *unfinishedUploadSegment) {
 	if segmentComplete && !released {
 		uc.released = true
 		client.updateUploadSegmentStuckStatus(uc)
-		client.uploadHeap.mu.Lock()
-		delete(client.uploadHeap.pendingSegments, uc.id)
-		client.uploadHeap.mu.Unlock()
+		client.uploadHeap.release(uc)
 	}
 
 	uc.memoryReleased += uint64(memoryReleased)
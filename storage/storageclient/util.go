@@ -6,6 +6,7 @@ package storageclient
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"sort"
 	"time"
@@ -22,6 +23,7 @@ import (
 	"github.com/DxChainNetwork/godx/p2p/enode"
 	"github.com/DxChainNetwork/godx/params"
 	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/coinchargemaintenance"
 	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem"
 	"github.com/DxChainNetwork/godx/storage/storageclient/storagehostmanager"
 )
@@ -62,6 +64,21 @@ func (client *StorageClient) GetTxByBlockHash(blockHash common.Hash) (types.Tran
 	return block.Transactions(), nil
 }
 
+// GetStorageContractRevisionNumber returns the revision number of the storage contract stored
+// on chain. If the contract cannot be found on chain, exists is false
+func (client *StorageClient) GetStorageContractRevisionNumber(contractID common.Hash) (revisionNumber uint64, exists bool, err error) {
+	stateDB, err := client.ethBackend.GetBlockChain().State()
+	if err != nil {
+		return
+	}
+	contractAddr := common.BytesToAddress(contractID[12:])
+	if !stateDB.Exist(contractAddr) {
+		return
+	}
+	revisionNumHash := stateDB.GetState(contractAddr, coinchargemaintenance.KeyRevisionNumber)
+	return new(big.Int).SetBytes(revisionNumHash.Bytes()).Uint64(), true, nil
+}
+
 // GetStorageHostSetting will be used to get the storage host's external setting based on the
 // peerID provided
 func (client *StorageClient) GetStorageHostSetting(hostEnodeID enode.ID, hostEnodeURL string, config *storage.HostExtConfig) error {
@@ -199,6 +216,55 @@ func (client *StorageClient) SelfEnodeURL() string {
 	return client.ethBackend.SelfEnodeURL()
 }
 
+// checkUploadBatch checks the upload actions against the batch limits advertised by the
+// host, so that the request rejected by the host is not sent
+func checkUploadBatch(config storage.HostExtConfig, actions []storage.UploadAction) error {
+	var size, sectors uint64
+	for _, action := range actions {
+		size += uint64(len(action.Data))
+		if action.Type == storage.UploadActionAppend {
+			sectors++
+		}
+	}
+	if config.MaxReviseBatchSize != 0 && size > config.MaxReviseBatchSize {
+		return fmt.Errorf("upload data size %v exceeds the max batch size %v of the host", size, config.MaxReviseBatchSize)
+	}
+	if config.MaxUploadBatchSectors != 0 && sectors > config.MaxUploadBatchSectors {
+		return fmt.Errorf("upload of %v sectors exceeds the max batch of %v sectors of the host", sectors, config.MaxUploadBatchSectors)
+	}
+	return nil
+}
+
+// splitUploadBatches splits the upload actions into the batches within the limits advertised
+// by the host, keeping the order of the actions. The sectors appended and removed are split
+// into separate batches, since the host does not accept both in the same request
+func splitUploadBatches(config storage.HostExtConfig, actions []storage.UploadAction) [][]storage.UploadAction {
+	var batches [][]storage.UploadAction
+	var batch []storage.UploadAction
+	var size, sectors uint64
+	for _, action := range actions {
+		isAppend := action.Type == storage.UploadActionAppend
+		if len(batch) != 0 {
+			mixed := isAppend != (batch[0].Type == storage.UploadActionAppend)
+			oversize := config.MaxReviseBatchSize != 0 && size+uint64(len(action.Data)) > config.MaxReviseBatchSize
+			tooMany := isAppend && config.MaxUploadBatchSectors != 0 && sectors >= config.MaxUploadBatchSectors
+			if mixed || oversize || tooMany {
+				batches = append(batches, batch)
+				batch, size, sectors = nil, 0, 0
+			}
+		}
+		batch = append(batch, action)
+		size += uint64(len(action.Data))
+		if isAppend {
+			sectors++
+		}
+	}
+	if len(batch) != 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
 // CalculateProofRanges will calculate the proof ranges which is used to verify a
 // pre-modification Merkle diff proof for the specified actions.
 func CalculateProofRanges(actions []storage.UploadAction, oldNumSectors uint64) []merkle.SubTreeLimit {
@@ -209,6 +275,14 @@ func CalculateProofRanges(actions []storage.UploadAction, oldNumSectors uint64)
 		case storage.UploadActionAppend:
 			sectorsChanged[newNumSectors] = struct{}{}
 			newNumSectors++
+		case storage.UploadActionSwap:
+			sectorsChanged[action.A] = struct{}{}
+			sectorsChanged[action.B] = struct{}{}
+		case storage.UploadActionTrim:
+			for i := uint64(0); i < action.A && newNumSectors > 0; i++ {
+				newNumSectors--
+				sectorsChanged[newNumSectors] = struct{}{}
+			}
 		}
 	}
 
@@ -239,19 +313,82 @@ func ModifyProofRanges(proofRanges []merkle.SubTreeLimit, actions []storage.Uplo
 				Right: numSectors + 1,
 			})
 			numSectors++
+		case storage.UploadActionTrim:
+			proofRanges = proofRanges[:len(proofRanges)-int(action.A)]
+			numSectors -= action.A
 		}
 	}
 	return proofRanges
 }
 
 // ModifyLeaves will modify the leaf hashes of a Merkle diff proof to verify a
-// post-modification Merkle diff proof for the specified actions.
+// post-modification Merkle diff proof for the specified actions. The leaf hashes
+// are ordered by the sector index as the proof ranges
 func ModifyLeaves(leafHashes []common.Hash, actions []storage.UploadAction, numSectors uint64) []common.Hash {
+	// map the sector index to the position of its leaf hash
+	positions := make(map[uint64]int, len(leafHashes))
+	for i, r := range CalculateProofRanges(actions, numSectors) {
+		positions[r.Left] = i
+	}
+
 	for _, action := range actions {
 		switch action.Type {
 		case storage.UploadActionAppend:
 			leafHashes = append(leafHashes, merkle.Sha256MerkleTreeRoot(action.Data))
+		case storage.UploadActionSwap:
+			i, j := positions[action.A], positions[action.B]
+			leafHashes[i], leafHashes[j] = leafHashes[j], leafHashes[i]
+		case storage.UploadActionTrim:
+			leafHashes = leafHashes[:len(leafHashes)-int(action.A)]
 		}
 	}
 	return leafHashes
 }
+
+// removeSectorActions creates the upload actions removing the sectors from the contract
+// with the merkle roots. Each sector removed is swapped to the end of the contract,
+// and then trimmed. The merkle roots after the actions are applied are returned,
+// along with the number of sectors removed
+func removeSectorActions(roots []common.Hash, remove map[common.Hash]struct{}) (actions []storage.UploadAction, newRoots []common.Hash, removed uint64) {
+	newRoots = append([]common.Hash(nil), roots...)
+	for i := len(newRoots) - 1; i >= 0; i-- {
+		if _, exists := remove[newRoots[i]]; !exists {
+			continue
+		}
+		last := len(newRoots) - 1
+		if i != last {
+			actions = append(actions, storage.UploadAction{Type: storage.UploadActionSwap, A: uint64(i), B: uint64(last)})
+			newRoots[i], newRoots[last] = newRoots[last], newRoots[i]
+		}
+		newRoots = newRoots[:last]
+		removed++
+	}
+	if removed != 0 {
+		actions = append(actions, storage.UploadAction{Type: storage.UploadActionTrim, A: removed})
+	}
+	return
+}
+
+// applyUploadActions applies the upload actions to the merkle roots of the contract, and
+// returns the merkle roots after the actions are applied
+func applyUploadActions(roots []common.Hash, actions []storage.UploadAction) ([]common.Hash, error) {
+	newRoots := append([]common.Hash(nil), roots...)
+	for _, action := range actions {
+		numSectors := uint64(len(newRoots))
+		switch action.Type {
+		case storage.UploadActionAppend:
+			newRoots = append(newRoots, merkle.Sha256MerkleTreeRoot(action.Data))
+		case storage.UploadActionSwap:
+			if action.A >= numSectors || action.B >= numSectors {
+				return nil, fmt.Errorf("swap sector %v and %v out of %v sectors", action.A, action.B, numSectors)
+			}
+			newRoots[action.A], newRoots[action.B] = newRoots[action.B], newRoots[action.A]
+		case storage.UploadActionTrim:
+			if action.A > numSectors {
+				return nil, fmt.Errorf("trim %v sectors out of %v sectors", action.A, numSectors)
+			}
+			newRoots = newRoots[:numSectors-action.A]
+		}
+	}
+	return newRoots, nil
+}
@@ -109,3 +109,108 @@ func TestNewVision(t *testing.T) {
 	}
 
 }
+
+func TestCheckUploadBatch(t *testing.T) {
+	config := storage.HostExtConfig{
+		MaxReviseBatchSize:    14,
+		MaxUploadBatchSectors: 2,
+	}
+	if err := checkUploadBatch(config, actions); err != nil {
+		t.Fatalf("the batch within the limits is rejected: %v", err)
+	}
+
+	oversize := append(actions, storage.UploadAction{Type: storage.UploadActionAppend, Data: []byte("x")}, storage.UploadAction{Type: storage.UploadActionAppend, Data: []byte("x")})
+	config.MaxUploadBatchSectors = 3
+	if err := checkUploadBatch(config, oversize); err == nil {
+		t.Fatalf("the batch exceeding the max batch size is not rejected")
+	}
+
+	config.MaxReviseBatchSize = 32
+	config.MaxUploadBatchSectors = 1
+	if err := checkUploadBatch(config, oversize); err == nil {
+		t.Fatalf("the batch exceeding the max batch sectors is not rejected")
+	}
+	config.MaxUploadBatchSectors = 0
+	if err := checkUploadBatch(config, oversize); err != nil {
+		t.Fatalf("the batch is rejected when the host does not limit the sectors: %v", err)
+	}
+}
+
+func TestRemoveSectorActions(t *testing.T) {
+	var roots []common.Hash
+	for i := 0; i < 10; i++ {
+		roots = append(roots, common.Hash{byte(i + 1)})
+	}
+	remove := map[common.Hash]struct{}{roots[2]: {}, roots[7]: {}, roots[9]: {}, {0xff}: {}}
+
+	actions, newRoots, removed := removeSectorActions(roots, remove)
+	if removed != 3 || len(newRoots) != 7 {
+		t.Fatalf("expect 3 sectors removed, got %v removed and %v left", removed, len(newRoots))
+	}
+	for _, root := range newRoots {
+		if _, exists := remove[root]; exists {
+			t.Fatalf("the sector %v is not removed", root)
+		}
+	}
+
+	// the merkle diff proof constructed from the old roots verifies the new merkle root
+	numSectors := uint64(len(roots))
+	proofRanges := CalculateProofRanges(actions, numSectors)
+	proofHashes, err := merkle.Sha256DiffProof(roots, proofRanges, numSectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var leaves []common.Hash
+	for _, r := range proofRanges {
+		leaves = append(leaves, roots[r.Left])
+	}
+	if err = merkle.Sha256VerifyDiffProof(proofRanges, numSectors, proofHashes, leaves, merkle.Sha256CachedTreeRoot2(roots)); err != nil {
+		t.Fatalf("failed to verify the old merkle root: %v", err)
+	}
+	leaves = ModifyLeaves(leaves, actions, numSectors)
+	proofRanges = ModifyProofRanges(proofRanges, actions, numSectors)
+	if err = merkle.Sha256VerifyDiffProof(proofRanges, numSectors-removed, proofHashes, leaves, merkle.Sha256CachedTreeRoot2(newRoots)); err != nil {
+		t.Fatalf("failed to verify the new merkle root: %v", err)
+	}
+
+	if actions, _, removed = removeSectorActions(roots, nil); len(actions) != 0 || removed != 0 {
+		t.Errorf("no actions expected if no sector is removed, got %v", actions)
+	}
+}
+
+func TestSplitUploadBatches(t *testing.T) {
+	appendAction := func(data string) storage.UploadAction {
+		return storage.UploadAction{Type: storage.UploadActionAppend, Data: []byte(data)}
+	}
+	swap := storage.UploadAction{Type: storage.UploadActionSwap, A: 0, B: 3}
+	trim := storage.UploadAction{Type: storage.UploadActionTrim, A: 1}
+	actions := []storage.UploadAction{appendAction("aa"), appendAction("bb"), appendAction("cc"), swap, trim, appendAction("dddd")}
+
+	tests := []struct {
+		config storage.HostExtConfig
+		expect []int
+	}{
+		{storage.HostExtConfig{}, []int{3, 2, 1}},
+		{storage.HostExtConfig{MaxUploadBatchSectors: 2}, []int{2, 1, 2, 1}},
+		{storage.HostExtConfig{MaxReviseBatchSize: 4}, []int{2, 1, 2, 1}},
+		{storage.HostExtConfig{MaxReviseBatchSize: 6, MaxUploadBatchSectors: 1}, []int{1, 1, 1, 2, 1}},
+	}
+	for i, test := range tests {
+		batches := splitUploadBatches(test.config, actions)
+		var sizes []int
+		var joined []storage.UploadAction
+		for _, batch := range batches {
+			sizes = append(sizes, len(batch))
+			joined = append(joined, batch...)
+			if err := checkUploadBatch(test.config, batch); err != nil {
+				t.Errorf("test %d: the batch exceeds the limits: %v", i, err)
+			}
+		}
+		if !reflect.DeepEqual(sizes, test.expect) {
+			t.Errorf("test %d: batch sizes expect %v, got %v", i, test.expect, sizes)
+		}
+		if !reflect.DeepEqual(joined, actions) {
+			t.Errorf("test %d: the order of the actions is changed", i)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/vm"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+)
+
+// the remediations suggested for the inconsistent contracts
+const (
+	remediationNone         = "none"
+	remediationResync       = "resync with host"
+	remediationMarkUnusable = "mark unusable"
+)
+
+type (
+	// ContractSetReport is the result of verifying the consistency of the contracts in the
+	// contract set
+	ContractSetReport struct {
+		Contracts    []ContractConsistency `json:"contracts"`
+		Consistent   int                   `json:"consistent"`
+		Inconsistent int                   `json:"inconsistent"`
+	}
+
+	// ContractConsistency is the result of verifying the merkle roots stored for a contract
+	// against the latest revision, and the signatures of the revision. The remediation is
+	// suggested for the inconsistent contract. The contract recently created and not yet
+	// found on chain is pending
+	ContractConsistency struct {
+		ContractID          string   `json:"contractid"`
+		HostID              string   `json:"hostid"`
+		StartHeight         uint64   `json:"startheight"`
+		RevisionNumber      uint64   `json:"revisionnumber"`
+		ChainRevisionNumber uint64   `json:"chainrevisionnumber"`
+		OnChain             bool     `json:"onchain"`
+		Pending             bool     `json:"pending"`
+		NumRoots            int      `json:"numroots"`
+		ExpectedRoots       uint64   `json:"expectedroots"`
+		Inconsistencies     []string `json:"inconsistencies,omitempty"`
+		Remediation         string   `json:"remediation"`
+		Consistent          bool     `json:"consistent"`
+	}
+)
+
+// VerifyContracts verifies each contract in the contract set. The number of merkle roots
+// stored is checked against the file size of the latest revision, and the revision is
+// checked to be signed by the client and the host of the contract, and not be outdated by
+// the revision on chain. Only the latest revision is checked, the earlier revisions are
+// not kept by the contract set. The contract with the missing or extra roots is suggested
+// to be resynced with the host, and the contract with an invalid revision is suggested to
+// be marked as unusable
+func (client *StorageClient) VerifyContracts() (report ContractSetReport, err error) {
+	if err = client.tm.Add(); err != nil {
+		return
+	}
+	defer client.tm.Done()
+
+	blockHeight := client.ethBackend.GetCurrentBlockHeight()
+	contractSet := client.contractManager.GetStorageContractSet()
+	for _, id := range contractSet.IDs() {
+		c, exists := contractSet.Acquire(id)
+		if !exists {
+			continue
+		}
+		roots, rootsErr := c.MerkleRoots()
+		header := c.Header()
+		if returnErr := contractSet.Return(c); returnErr != nil {
+			client.log.Warn("failed to return the contract", "id", id, "err", returnErr)
+		}
+
+		cc := checkContractConsistency(header, roots, rootsErr)
+		cc.ChainRevisionNumber, cc.OnChain, err = client.GetStorageContractRevisionNumber(common.Hash(id))
+		if err != nil {
+			return report, fmt.Errorf("failed to get the revision number of contract %v on chain: %s", id, err.Error())
+		}
+		checkChainRevision(&cc, blockHeight)
+
+		if cc.Consistent {
+			report.Consistent++
+		} else {
+			report.Inconsistent++
+		}
+		report.Contracts = append(report.Contracts, cc)
+	}
+
+	// the inconsistent contracts are listed first
+	sort.Slice(report.Contracts, func(i, j int) bool {
+		if report.Contracts[i].Consistent != report.Contracts[j].Consistent {
+			return !report.Contracts[i].Consistent
+		}
+		return report.Contracts[i].ContractID < report.Contracts[j].ContractID
+	})
+	return
+}
+
+// checkContractConsistency checks the merkle roots stored for the contract against the latest
+// revision, and whether the revision is signed by the client and the host of the contract
+func checkContractConsistency(header contractset.ContractHeader, roots []common.Hash, rootsErr error) ContractConsistency {
+	rev := header.LatestContractRevision
+	cc := ContractConsistency{
+		ContractID:     header.ID.String(),
+		HostID:         header.EnodeID.String(),
+		StartHeight:    header.StartHeight,
+		RevisionNumber: rev.NewRevisionNumber,
+		NumRoots:       len(roots),
+		ExpectedRoots:  rev.NewFileSize / storage.SectorSize,
+	}
+	var resync, unusable bool
+
+	if rootsErr != nil {
+		cc.Inconsistencies = append(cc.Inconsistencies, fmt.Sprintf("failed to read the merkle roots: %s", rootsErr.Error()))
+		resync = true
+	} else {
+		integrity := checkContractIntegrity(header, roots)
+		if rev.NewFileSize%storage.SectorSize != 0 {
+			cc.Inconsistencies = append(cc.Inconsistencies, fmt.Sprintf("the file size %v is not a multiple of the sector size", rev.NewFileSize))
+			unusable = true
+		} else if !integrity.FileSizeMatch {
+			cc.Inconsistencies = append(cc.Inconsistencies, fmt.Sprintf("%v merkle roots stored, the file size of the revision expects %v", len(roots), cc.ExpectedRoots))
+			resync = true
+		}
+		if integrity.FileSizeMatch && !integrity.RootsMatch {
+			cc.Inconsistencies = append(cc.Inconsistencies, "the merkle roots stored do not match the file merkle root of the revision")
+			resync = true
+		}
+	}
+
+	if problems := checkRevisionSignatures(header); len(problems) != 0 {
+		cc.Inconsistencies = append(cc.Inconsistencies, problems...)
+		unusable = true
+	}
+
+	switch {
+	case unusable:
+		cc.Remediation = remediationMarkUnusable
+	case resync:
+		cc.Remediation = remediationResync
+	default:
+		cc.Remediation = remediationNone
+		cc.Consistent = true
+	}
+	return cc
+}
+
+// checkRevisionSignatures checks that the latest revision belongs to the contract, and is signed
+// by the client and the host receiving the proof outputs of the revision
+func checkRevisionSignatures(header contractset.ContractHeader) (problems []string) {
+	rev := header.LatestContractRevision
+	if rev.ParentID != common.Hash(header.ID) {
+		problems = append(problems, fmt.Sprintf("the revision belongs to contract %v", rev.ParentID.String()))
+	}
+	if len(rev.Signatures) != 2 {
+		return append(problems, fmt.Sprintf("the revision is signed by %v parties, expect 2", len(rev.Signatures)))
+	}
+	if len(rev.NewValidProofOutputs) < 2 {
+		return append(problems, "the revision has no proof outputs for both the client and the host")
+	}
+
+	hash := rev.RLPHash().Bytes()
+	parties := []string{"client", "host"}
+	for i, sig := range rev.Signatures {
+		pub, err := crypto.SigToPub(hash, sig)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid %s signature: %s", parties[i], err.Error()))
+			continue
+		}
+		if addr := crypto.PubkeyToAddress(*pub); addr != rev.NewValidProofOutputs[i].Address {
+			problems = append(problems, fmt.Sprintf("the revision is signed by %v instead of the %s %v", addr.String(), parties[i], rev.NewValidProofOutputs[i].Address.String()))
+		}
+	}
+	if err := vm.CheckMultiSignatures(rev, rev.Signatures); err != nil {
+		problems = append(problems, fmt.Sprintf("the signatures do not match the unlock hash of the revision: %s", err.Error()))
+	}
+	return
+}
+
+// checkChainRevision checks the latest revision against the revision of the contract on chain.
+// The contract missing on chain, or with a newer revision on chain cannot be revised anymore.
+// The contract created within contractCreationWindow blocks might not be on chain yet, and
+// is pending instead
+func checkChainRevision(cc *ContractConsistency, blockHeight uint64) {
+	var problem string
+	switch {
+	case !cc.OnChain && blockHeight < cc.StartHeight+contractCreationWindow:
+		cc.Pending = true
+		return
+	case !cc.OnChain:
+		problem = "the contract is not found on chain"
+	case cc.ChainRevisionNumber > cc.RevisionNumber:
+		problem = fmt.Sprintf("the revision %v is outdated by the revision %v on chain", cc.RevisionNumber, cc.ChainRevisionNumber)
+	default:
+		return
+	}
+	cc.Inconsistencies = append(cc.Inconsistencies, problem)
+	cc.Remediation = remediationMarkUnusable
+	cc.Consistent = false
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+)
+
+// newConsistentContractHeader creates the contract header with the latest revision of the
+// contract committing the roots, signed by the client and the host receiving the proof outputs
+func newConsistentContractHeader(t *testing.T, roots []common.Hash, keys ...*ecdsa.PrivateKey) contractset.ContractHeader {
+	if len(keys) == 0 {
+		clientKey, _ := crypto.GenerateKey()
+		hostKey, _ := crypto.GenerateKey()
+		keys = []*ecdsa.PrivateKey{clientKey, hostKey}
+	}
+	id := storage.ContractID{1}
+	rev := types.StorageContractRevision{
+		ParentID:          common.Hash(id),
+		NewRevisionNumber: 3,
+		NewFileSize:       uint64(len(roots)) * storage.SectorSize,
+		NewFileMerkleRoot: merkle.Sha256CachedTreeRoot2(roots),
+	}
+	var addrs []common.Address
+	for _, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		addrs = append(addrs, addr)
+		rev.NewValidProofOutputs = append(rev.NewValidProofOutputs, types.DxcoinCharge{Address: addr, Value: big.NewInt(1)})
+	}
+	rev.NewUnlockHash = types.UnlockConditions{PaymentAddresses: addrs, SignaturesRequired: 2}.UnlockHash()
+	for _, key := range keys {
+		sig, err := crypto.Sign(rev.RLPHash().Bytes(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rev.Signatures = append(rev.Signatures, sig)
+	}
+	return contractset.ContractHeader{
+		ID:                     id,
+		EnodeID:                enode.ID{2},
+		LatestContractRevision: rev,
+	}
+}
+
+func TestCheckContractConsistency(t *testing.T) {
+	roots := []common.Hash{{1}, {2}, {3}}
+	header := newConsistentContractHeader(t, roots)
+
+	cc := checkContractConsistency(header, roots, nil)
+	if !cc.Consistent || cc.Remediation != remediationNone || cc.ExpectedRoots != 3 {
+		t.Fatalf("the contract should be consistent, got %+v", cc)
+	}
+
+	tests := []struct {
+		name        string
+		roots       []common.Hash
+		rootsErr    error
+		remediation string
+	}{
+		{"missing root", roots[:2], nil, remediationResync},
+		{"extra root", append(append([]common.Hash{}, roots...), common.Hash{4}), nil, remediationResync},
+		{"changed root", []common.Hash{{1}, {2}, {4}}, nil, remediationResync},
+		{"unreadable roots", nil, errors.New("corrupted"), remediationResync},
+	}
+	for _, test := range tests {
+		cc := checkContractConsistency(header, test.roots, test.rootsErr)
+		if cc.Consistent || cc.Remediation != test.remediation || len(cc.Inconsistencies) == 0 {
+			t.Errorf("%s: expect remediation %v, got %+v", test.name, test.remediation, cc)
+		}
+	}
+}
+
+func TestCheckRevisionSignatures(t *testing.T) {
+	roots := []common.Hash{{1}}
+
+	// the revision signed by the client only
+	header := newConsistentContractHeader(t, roots)
+	header.LatestContractRevision.Signatures = header.LatestContractRevision.Signatures[:1]
+	if cc := checkContractConsistency(header, roots, nil); cc.Consistent || cc.Remediation != remediationMarkUnusable {
+		t.Errorf("the revision signed by the client only should be marked unusable, got %+v", cc)
+	}
+
+	// the revision of another contract
+	header = newConsistentContractHeader(t, roots)
+	header.ID = storage.ContractID{3}
+	if problems := checkRevisionSignatures(header); len(problems) == 0 {
+		t.Errorf("the revision of another contract should be reported")
+	}
+
+	// the signatures in the wrong order do not match the proof outputs
+	header = newConsistentContractHeader(t, roots)
+	sigs := header.LatestContractRevision.Signatures
+	sigs[0], sigs[1] = sigs[1], sigs[0]
+	if problems := checkRevisionSignatures(header); len(problems) == 0 {
+		t.Errorf("the swapped signatures should be reported")
+	}
+
+	// the revision signed by a key not receiving the proof outputs
+	header = newConsistentContractHeader(t, roots)
+	otherKey, _ := crypto.GenerateKey()
+	sig, err := crypto.Sign(header.LatestContractRevision.RLPHash().Bytes(), otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.LatestContractRevision.Signatures[1] = sig
+	if problems := checkRevisionSignatures(header); len(problems) == 0 {
+		t.Errorf("the revision signed by another host should be reported")
+	}
+}
+
+func TestCheckChainRevision(t *testing.T) {
+	roots := []common.Hash{{1}}
+	header := newConsistentContractHeader(t, roots)
+	header.StartHeight = 100
+	cc := checkContractConsistency(header, roots, nil)
+	cc.OnChain, cc.ChainRevisionNumber = true, cc.RevisionNumber
+	if checkChainRevision(&cc, 200); !cc.Consistent {
+		t.Fatalf("the contract up to date with the chain should be consistent, got %+v", cc)
+	}
+
+	outdated := cc
+	outdated.ChainRevisionNumber++
+	if checkChainRevision(&outdated, 200); outdated.Consistent || outdated.Remediation != remediationMarkUnusable {
+		t.Errorf("the contract outdated by the chain should be marked unusable, got %+v", outdated)
+	}
+
+	pending := cc
+	pending.OnChain = false
+	if checkChainRevision(&pending, 100+contractCreationWindow-1); !pending.Consistent || !pending.Pending {
+		t.Errorf("the contract missing on chain within the creation window should be pending, got %+v", pending)
+	}
+
+	missing := cc
+	missing.OnChain = false
+	if checkChainRevision(&missing, 100+contractCreationWindow); missing.Consistent || missing.Pending || missing.Remediation != remediationMarkUnusable {
+		t.Errorf("the contract missing on chain after the creation window should be marked unusable, got %+v", missing)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/vm"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxfile"
+)
+
+type (
+	// FileIntegrityReport is the result of verifying a downloaded file against the sectors
+	// stored by the hosts, and the sector roots covered by the latest signed revisions of
+	// the contracts storing the file
+	FileIntegrityReport struct {
+		DxPath           string                    `json:"dxpath"`
+		LocalPath        string                    `json:"localpath"`
+		FileSize         uint64                    `json:"filesize"`
+		Segments         int                       `json:"segments"`
+		SegmentsVerified int                       `json:"segmentsverified"`
+		SectorsFetched   int                       `json:"sectorsfetched"`
+		SectorsCovered   int                       `json:"sectorscovered"`
+		SectorsUncovered int                       `json:"sectorsuncovered"`
+		Contracts        []ContractIntegrity       `json:"contracts"`
+		Failures         []SegmentIntegrityFailure `json:"failures,omitempty"`
+		Verified         bool                      `json:"verified"`
+	}
+
+	// ContractIntegrity is the result of checking the merkle roots of a contract against the
+	// latest signed revision of the contract
+	ContractIntegrity struct {
+		ContractID          string      `json:"contractid"`
+		HostID              string      `json:"hostid"`
+		RevisionNumber      uint64      `json:"revisionnumber"`
+		ChainRevisionNumber uint64      `json:"chainrevisionnumber"`
+		OnChain             bool        `json:"onchain"`
+		FileMerkleRoot      common.Hash `json:"filemerkleroot"`
+		NumRoots            int         `json:"numroots"`
+		RootsMatch          bool        `json:"rootsmatch"`
+		FileSizeMatch       bool        `json:"filesizematch"`
+		SignaturesValid     bool        `json:"signaturesvalid"`
+		SectorsCovered      int         `json:"sectorscovered"`
+		Valid               bool        `json:"valid"`
+	}
+
+	// SegmentIntegrityFailure is the reason why a segment of the file failed the verification
+	SegmentIntegrityFailure struct {
+		Index  int    `json:"index"`
+		Reason string `json:"reason"`
+	}
+
+	// contractRoots is the integrity of a contract along with the merkle roots it covers
+	contractRoots struct {
+		integrity ContractIntegrity
+		roots     map[common.Hash]struct{}
+	}
+)
+
+// VerifyFile verifies the downloaded file at the local path against the file uploaded. For
+// each segment, enough sectors are fetched from the hosts to recover the segment, the merkle
+// roots of the sectors are recomputed and compared with the roots recorded in the file, and
+// the recovered segment is compared with the local file. Each sector root is also checked to
+// be covered by the latest signed revision of the contract with the host. Note that the
+// sectors are paid with the download bandwidth of the contracts
+func (client *StorageClient) VerifyFile(dxPath storage.DxPath, localPath string) (report FileIntegrityReport, err error) {
+	if err = client.tm.Add(); err != nil {
+		return
+	}
+	defer client.tm.Done()
+
+	entry, err := client.fileSystem.OpenDxFile(dxPath)
+	if err != nil {
+		return
+	}
+	defer entry.Close()
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return report, fmt.Errorf("failed to open the downloaded file: %s", err.Error())
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	if uint64(info.Size()) != entry.FileSize() {
+		return report, fmt.Errorf("the size of the downloaded file %v does not match the file size %v", info.Size(), entry.FileSize())
+	}
+
+	ec, err := entry.ErasureCode()
+	if err != nil {
+		return
+	}
+	ck, err := entry.CipherKey()
+	if err != nil {
+		return
+	}
+	contracts, err := client.contractRootSets()
+	if err != nil {
+		return
+	}
+
+	report = FileIntegrityReport{
+		DxPath:    dxPath.Path,
+		LocalPath: localPath,
+		FileSize:  entry.FileSize(),
+		Segments:  entry.NumSegments(),
+	}
+	segmentSize := entry.SegmentSize()
+	local := make([]byte, segmentSize)
+	for i := 0; i < report.Segments; i++ {
+		sectors, err := entry.Sectors(i)
+		if err != nil {
+			return report, err
+		}
+		checkSectorCoverage(sectors, contracts, &report)
+
+		n, err := f.ReadAt(local, int64(uint64(i)*segmentSize))
+		if err != nil && err != io.EOF {
+			return report, fmt.Errorf("failed to read the downloaded file: %s", err.Error())
+		}
+		if err = client.verifySegment(sectors, ec, ck, segmentSize, local[:n], &report); err != nil {
+			report.Failures = append(report.Failures, SegmentIntegrityFailure{Index: i, Reason: err.Error()})
+			continue
+		}
+		report.SegmentsVerified++
+	}
+
+	report.Verified = report.SegmentsVerified == report.Segments && report.SectorsUncovered == 0
+	for _, c := range contracts {
+		if c.integrity.SectorsCovered == 0 {
+			continue
+		}
+		report.Contracts = append(report.Contracts, c.integrity)
+		if !c.integrity.Valid {
+			report.Verified = false
+		}
+	}
+	sort.Slice(report.Contracts, func(i, j int) bool {
+		return report.Contracts[i].ContractID < report.Contracts[j].ContractID
+	})
+	return
+}
+
+// contractRootSets checks the integrity of each contract in the contract set, and maps the
+// hosts of the contracts to the merkle roots covered by the contracts
+func (client *StorageClient) contractRootSets() (map[enode.ID]*contractRoots, error) {
+	contractSet := client.contractManager.GetStorageContractSet()
+	sets := make(map[enode.ID]*contractRoots)
+	for _, id := range contractSet.IDs() {
+		c, exists := contractSet.Acquire(id)
+		if !exists {
+			continue
+		}
+		roots, err := c.MerkleRoots()
+		header := c.Header()
+		if returnErr := contractSet.Return(c); returnErr != nil {
+			client.log.Warn("failed to return the contract", "id", id, "err", returnErr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the merkle roots of contract %v: %s", id, err.Error())
+		}
+
+		integrity := checkContractIntegrity(header, roots)
+		integrity.ChainRevisionNumber, integrity.OnChain, err = client.GetStorageContractRevisionNumber(common.Hash(id))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the revision number of contract %v on chain: %s", id, err.Error())
+		}
+		// the contract is stale if the revision on chain is newer than the local revision
+		integrity.Valid = integrity.Valid && integrity.OnChain && integrity.ChainRevisionNumber <= integrity.RevisionNumber
+
+		set := &contractRoots{integrity: integrity, roots: make(map[common.Hash]struct{}, len(roots))}
+		for _, root := range roots {
+			set.roots[root] = struct{}{}
+		}
+		sets[header.EnodeID] = set
+	}
+	return sets, nil
+}
+
+// checkContractIntegrity checks whether the merkle roots of the contract are committed by the
+// latest revision, and whether the revision is signed by both the client and the host
+func checkContractIntegrity(header contractset.ContractHeader, roots []common.Hash) ContractIntegrity {
+	rev := header.LatestContractRevision
+	ci := ContractIntegrity{
+		ContractID:     header.ID.String(),
+		HostID:         header.EnodeID.String(),
+		RevisionNumber: rev.NewRevisionNumber,
+		FileMerkleRoot: rev.NewFileMerkleRoot,
+		NumRoots:       len(roots),
+	}
+
+	var root common.Hash
+	if len(roots) != 0 {
+		root = merkle.Sha256CachedTreeRoot2(roots)
+	}
+	ci.RootsMatch = root == rev.NewFileMerkleRoot
+	ci.FileSizeMatch = rev.NewFileSize == uint64(len(roots))*storage.SectorSize
+	ci.SignaturesValid = len(rev.Signatures) == 2 && vm.CheckMultiSignatures(rev, rev.Signatures) == nil
+	ci.Valid = ci.RootsMatch && ci.FileSizeMatch && ci.SignaturesValid
+	return ci
+}
+
+// checkSectorCoverage counts the sectors of the segment covered by the contracts with the hosts
+// storing the sectors
+func checkSectorCoverage(sectors [][]*dxfile.Sector, contracts map[enode.ID]*contractRoots, report *FileIntegrityReport) {
+	for _, sectorSet := range sectors {
+		for _, sector := range sectorSet {
+			c, exists := contracts[sector.HostID]
+			if !exists {
+				report.SectorsUncovered++
+				continue
+			}
+			if _, covered := c.roots[sector.MerkleRoot]; !covered {
+				report.SectorsUncovered++
+				continue
+			}
+			c.integrity.SectorsCovered++
+			report.SectorsCovered++
+		}
+	}
+}
+
+// verifySegment fetches enough sectors of the segment to recover it, and compares the
+// recovered segment with the local data. The merkle root of each fetched sector is
+// recomputed and checked against the root recorded in the file
+func (client *StorageClient) verifySegment(sectors [][]*dxfile.Sector, ec erasurecode.ErasureCoder, ck crypto.CipherKey, segmentSize uint64, local []byte, report *FileIntegrityReport) error {
+	physical := make([][]byte, len(sectors))
+	var fetched uint32
+	for i, sectorSet := range sectors {
+		if fetched == ec.MinSectors() {
+			break
+		}
+		for _, sector := range sectorSet {
+			data, err := client.fetchSector(sector.HostID, sector.MerkleRoot)
+			if err != nil {
+				client.log.Debug("failed to fetch the sector for verification", "host", sector.HostID, "err", err)
+				continue
+			}
+			report.SectorsFetched++
+			if merkle.Sha256MerkleTreeRoot(data) != sector.MerkleRoot {
+				return fmt.Errorf("sector %v stored by host %v does not match the merkle root %v", i, sector.HostID, sector.MerkleRoot.String())
+			}
+			if physical[i], err = ck.DecryptInPlace(data); err != nil {
+				return fmt.Errorf("failed to decrypt sector %v: %s", i, err.Error())
+			}
+			fetched++
+			break
+		}
+	}
+	if fetched < ec.MinSectors() {
+		return fmt.Errorf("only %v of %v sectors needed could be fetched", fetched, ec.MinSectors())
+	}
+	return compareRecoveredSegment(ec, physical, segmentSize, local)
+}
+
+// compareRecoveredSegment recovers the segment from the physical sectors, and compares it with
+// the local data. The local data is shorter than the segment for the last segment of the file
+func compareRecoveredSegment(ec erasurecode.ErasureCoder, physical [][]byte, segmentSize uint64, local []byte) error {
+	var buf bytes.Buffer
+	if err := ec.Recover(physical, int(segmentSize), &buf); err != nil {
+		return fmt.Errorf("unable to recover the segment: %s", err.Error())
+	}
+	recovered := buf.Bytes()
+	if len(local) > len(recovered) || !bytes.Equal(recovered[:len(local)], local) {
+		return errors.New("the recovered segment does not match the downloaded file")
+	}
+	return nil
+}
+
+// fetchSector downloads the whole sector from the host
+func (client *StorageClient) fetchSector(hostID enode.ID, root common.Hash) ([]byte, error) {
+	hostInfo, ok := client.storageHostManager.RetrieveHostInfo(hostID)
+	if !ok {
+		return nil, ErrUnableRetrieveHostInfo
+	}
+	sp, err := client.SetupConnection(hostInfo.EnodeURL)
+	if err != nil {
+		return nil, err
+	}
+	if !sp.TryToRenewOrRevise() {
+		return nil, ErrContractRenewing
+	}
+	defer sp.RevisionOrRenewingDone()
+
+	return client.Download(sp, root, 0, uint32(storage.SectorSize), false, &hostInfo)
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storageclient
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+	"github.com/DxChainNetwork/godx/storage/storageclient/contractset"
+	"github.com/DxChainNetwork/godx/storage/storageclient/erasurecode"
+	"github.com/DxChainNetwork/godx/storage/storageclient/filesystem/dxfile"
+)
+
+// newSignedContractHeader creates the contract header with the latest revision committing
+// the roots, signed by both the client and the host
+func newSignedContractHeader(t *testing.T, roots []common.Hash) contractset.ContractHeader {
+	clientKey, _ := crypto.GenerateKey()
+	hostKey, _ := crypto.GenerateKey()
+	rev := types.StorageContractRevision{
+		NewRevisionNumber: 3,
+		NewFileSize:       uint64(len(roots)) * storage.SectorSize,
+		NewFileMerkleRoot: merkle.Sha256CachedTreeRoot2(roots),
+	}
+	uc := types.UnlockConditions{
+		PaymentAddresses:   []common.Address{crypto.PubkeyToAddress(clientKey.PublicKey), crypto.PubkeyToAddress(hostKey.PublicKey)},
+		SignaturesRequired: 2,
+	}
+	rev.NewUnlockHash = uc.UnlockHash()
+	for _, key := range []*ecdsa.PrivateKey{clientKey, hostKey} {
+		sig, err := crypto.Sign(rev.RLPHash().Bytes(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rev.Signatures = append(rev.Signatures, sig)
+	}
+	return contractset.ContractHeader{
+		ID:                     storage.ContractID{1},
+		EnodeID:                enode.ID{2},
+		LatestContractRevision: rev,
+	}
+}
+
+func TestCheckContractIntegrity(t *testing.T) {
+	roots := []common.Hash{{1}, {2}, {3}}
+	header := newSignedContractHeader(t, roots)
+
+	if ci := checkContractIntegrity(header, roots); !ci.Valid || ci.NumRoots != len(roots) {
+		t.Fatalf("the contract should be valid, got %+v", ci)
+	}
+	if ci := checkContractIntegrity(header, roots[:2]); ci.Valid || ci.RootsMatch || ci.FileSizeMatch {
+		t.Errorf("the contract missing a root should be invalid, got %+v", ci)
+	}
+	if ci := checkContractIntegrity(header, []common.Hash{{1}, {2}, {4}}); ci.Valid || ci.RootsMatch {
+		t.Errorf("the contract with a changed root should be invalid, got %+v", ci)
+	}
+
+	header.LatestContractRevision.Signatures = header.LatestContractRevision.Signatures[:1]
+	if ci := checkContractIntegrity(header, roots); ci.Valid || ci.SignaturesValid {
+		t.Errorf("the revision signed only by the client should be invalid, got %+v", ci)
+	}
+}
+
+func TestCheckSectorCoverage(t *testing.T) {
+	host1, host2, host3 := enode.ID{1}, enode.ID{2}, enode.ID{3}
+	contracts := map[enode.ID]*contractRoots{
+		host1: {roots: map[common.Hash]struct{}{{1}: {}}},
+		host2: {roots: map[common.Hash]struct{}{{2}: {}}},
+	}
+	sectors := [][]*dxfile.Sector{
+		{{MerkleRoot: common.Hash{1}, HostID: host1}, {MerkleRoot: common.Hash{1}, HostID: host2}},
+		{{MerkleRoot: common.Hash{2}, HostID: host2}, {MerkleRoot: common.Hash{2}, HostID: host3}},
+	}
+
+	var report FileIntegrityReport
+	checkSectorCoverage(sectors, contracts, &report)
+	if report.SectorsCovered != 2 || report.SectorsUncovered != 2 {
+		t.Errorf("expect 2 sectors covered and 2 uncovered, got %v and %v", report.SectorsCovered, report.SectorsUncovered)
+	}
+	if contracts[host1].integrity.SectorsCovered != 1 || contracts[host2].integrity.SectorsCovered != 1 {
+		t.Errorf("each contract should cover a sector")
+	}
+}
+
+func TestCompareRecoveredSegment(t *testing.T) {
+	ec, err := erasurecode.New(erasurecode.ECTypeStandard, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segmentSize := uint64(2 * 64)
+	data := bytes.Repeat([]byte{1, 2, 3, 4}, int(segmentSize)/4)
+	sectors, err := ec.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the parity sectors are available
+	sectors[0], sectors[1] = nil, nil
+
+	if err = compareRecoveredSegment(ec, sectors, segmentSize, data[:100]); err != nil {
+		t.Errorf("the last segment of the file should match: %v", err)
+	}
+	local := append([]byte{}, data...)
+	local[10]++
+	if err = compareRecoveredSegment(ec, sectors, segmentSize, local); err == nil {
+		t.Errorf("the changed data should not match")
+	}
+}
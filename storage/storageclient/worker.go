@@ -178,6 +178,7 @@ func (w *worker) killDownloading() {
 
 	// close connection after downloading
 	for i := 0; i < len(removedSegments); i++ {
+		removedSegments[i].releasePreferred(w.hostID.String())
 		removedSegments[i].removeWorker()
 	}
 }
@@ -199,6 +200,7 @@ func (w *worker) queueDownloadSegment(uds *unfinishedDownloadSegment) {
 
 	// if the worker has terminated, remove it from the uds
 	if terminated {
+		uds.releasePreferred(w.hostID.String())
 		uds.removeWorker()
 	}
 }
@@ -262,7 +264,8 @@ func (w *worker) download(uds *unfinishedDownloadSegment) error {
 	root := uds.segmentMap[w.hostID.String()].root
 
 	// call rpc request the data from host, if get error, unregister the worker.
-	sectorData, err := w.client.Download(sp, root, uint32(fetchOffset), uint32(fetchLength), hostInfo)
+	merkleProof := uds.download.sampleVerification()
+	sectorData, err := w.client.Download(sp, root, uint32(fetchOffset), uint32(fetchLength), merkleProof, hostInfo)
 	if err != nil {
 		w.client.log.Error("worker failed to download sector", "error", err)
 		uds.unregisterWorker(w)
@@ -288,11 +291,13 @@ func (w *worker) download(uds *unfinishedDownloadSegment) error {
 	// go on keeping the decrypted sector.
 	if uds.sectorsCompleted <= uds.erasureCode.MinSectors() {
 		uds.physicalSegmentData[sectorIndex] = decryptedSector
+		uds.markFetchUsed(w.hostID.String())
 		w.client.log.Debug("received a sector,but not enough to recover", "sectors_completed", uds.sectorsCompleted)
 	}
 
 	// recover the logical data
 	if uds.sectorsCompleted == uds.erasureCode.MinSectors() {
+		w.client.overdrive.record(uds.erasureCode.MinSectors(), uds.fetchesIssued, uds.overdriveUsed)
 		go uds.recoverLogicalData()
 		w.client.log.Debug("received enough sectors to recover", "sectors_completed", uds.sectorsCompleted)
 	}
@@ -311,6 +316,10 @@ func (w *worker) processDownloadSegment(uds *unfinishedDownloadSegment) *unfinis
 
 	sectorCompleted := uds.completedSectors[sectorData.index]
 
+	// the worker has picked up the segment, it is no longer pending
+	_, preferred := uds.preferredPending[w.hostID.String()]
+	delete(uds.preferredPending, w.hostID.String())
+
 	// if the given segment downloading complete/fail, or no sector associated with host for downloading,
 	// or the sector has completed, the worker should be removed.
 	if segmentComplete || segmentFailed || w.onDownloadCooldown() || !workerHasSector || sectorCompleted {
@@ -326,9 +335,16 @@ func (w *worker) processDownloadSegment(uds *unfinishedDownloadSegment) *unfinis
 	sectorsInProgress := uds.sectorsRegistered + uds.sectorsCompleted
 	desiredSectorsInProgress := uds.erasureCode.MinSectors() + uds.overdrive
 	workersDesired := sectorsInProgress < desiredSectorsInProgress && !sectorTaken
+
+	// if the worker is not the preferred one, and the preferred workers are able to fill up
+	// the desired sectors in progress, leave the fetch to the faster preferred workers
+	if !preferred && sectorsInProgress+uint32(len(uds.preferredPending)) >= desiredSectorsInProgress {
+		workersDesired = false
+	}
 	if workersDesired {
 		uds.sectorsRegistered++
 		uds.sectorUsage[sectorData.index] = true
+		uds.registerFetch(w.hostID.String())
 		return uds
 	}
 
@@ -96,6 +96,22 @@ func (w *worker) isReady(uc *unfinishedUploadSegment) bool {
 	return true
 }
 
+// queueUploadSegment appends the segment to the worker's pending segments. If the same
+// segment is already queued, the duplicate is dropped and false is returned
+func (w *worker) queueUploadSegment(uc *unfinishedUploadSegment) bool {
+	w.mu.Lock()
+	for _, pending := range w.pendingSegments {
+		if pending.id == uc.id {
+			w.mu.Unlock()
+			w.dropSegment(uc)
+			return false
+		}
+	}
+	w.pendingSegments = append(w.pendingSegments, uc)
+	w.mu.Unlock()
+	return true
+}
+
 // Signal worker by sending uploadChan and then worker will retrieve sector index to upload sector
 func (w *worker) signalUploadChan(uc *unfinishedUploadSegment) {
 	select {
@@ -194,15 +210,7 @@ func (w *worker) preProcessUploadSegment(uc *unfinishedUploadSegment) (*unfinish
 
 	// If the segment needs upload by this worker, find a sector to upload and return the index for that sector
 	// and then mark the sector as true
-	index := -1
-	for i := 0; i < len(uc.sectorSlotsStatus); i++ {
-		if !uc.sectorSlotsStatus[i] {
-			index = i
-			uc.sectorSlotsStatus[i] = true
-			break
-		}
-	}
-
+	index := uc.claimSectorSlot(w.contract.EnodeID.String())
 	if index == -1 {
 		uc.mu.Unlock()
 		w.dropSegment(uc)
@@ -7,10 +7,14 @@ package storagehost
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/DxChainNetwork/godx/accounts"
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/timeseries"
 	"github.com/DxChainNetwork/godx/common/unit"
+	"github.com/DxChainNetwork/godx/p2p/enode"
 	"github.com/DxChainNetwork/godx/storage"
 )
 
@@ -58,6 +62,34 @@ func (h *HostPrivateAPI) Announce() string {
 	return fmt.Sprintf("Announcement transaction: %v", hash.Hex())
 }
 
+// Retire puts the host into the retirement mode. The host stops accepting new contracts
+// and uploads, and keeps serving downloads and submitting storage proofs until all
+// storage responsibilities are resolved
+func (h *HostPrivateAPI) Retire() (string, error) {
+	if err := h.storageHost.retire(); err != nil {
+		return "", err
+	}
+	status, err := h.storageHost.retirementStatus()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Host retiring, estimated to complete at block %v", status.EstimatedCompleteAt), nil
+}
+
+// CancelRetirement brings the retiring host back to accept new contracts and uploads
+func (h *HostPrivateAPI) CancelRetirement() (string, error) {
+	if err := h.storageHost.cancelRetirement(); err != nil {
+		return "", err
+	}
+	return "Successfully cancelled the host retirement", nil
+}
+
+// RetirementStatus returns the retirement status of the host, including the estimated
+// block height when all storage responsibilities are resolved
+func (h *HostPrivateAPI) RetirementStatus() (HostRetirement, error) {
+	return h.storageHost.retirementStatus()
+}
+
 // Folders return all the folders
 func (h *HostPrivateAPI) Folders() []storage.HostFolder {
 	return h.storageHost.StorageManager.Folders()
@@ -70,15 +102,19 @@ func (h *HostPrivateAPI) AvailableSpace() storage.HostSpace {
 
 // GetHostConfig return the internal settings of the storage host
 func (h *HostPrivateAPI) GetHostConfig() storage.HostIntConfigForDisplay {
-	// Get the internal setting
-	config := h.storageHost.getInternalConfig()
-	// parse the numbers to human readable string
+	return hostConfigForDisplay(h.storageHost.getInternalConfig())
+}
+
+// hostConfigForDisplay parses the numbers in the config to human readable string
+func hostConfigForDisplay(config storage.HostIntConfig) storage.HostIntConfigForDisplay {
 	display := storage.HostIntConfigForDisplay{
 		AcceptingContracts:     unit.FormatBool(config.AcceptingContracts),
 		MaxDownloadBatchSize:   unit.FormatStorage(config.MaxDownloadBatchSize, false),
 		MaxDuration:            unit.FormatTime(config.MaxDuration),
 		MaxReviseBatchSize:     unit.FormatStorage(config.MaxReviseBatchSize, false),
+		MaxUploadBatchSectors:  strconv.FormatUint(config.MaxUploadBatchSectors, 10),
 		WindowSize:             unit.FormatTime(config.WindowSize),
+		DeletionRetention:      unit.FormatTime(config.DeletionRetention),
 		PaymentAddress:         config.PaymentAddress.String(),
 		Deposit:                unit.FormatCurrency(config.Deposit, "/byte/block"),
 		DepositBudget:          unit.FormatCurrency(config.DepositBudget, "/contract"),
@@ -90,6 +126,10 @@ func (h *HostPrivateAPI) GetHostConfig() storage.HostIntConfigForDisplay {
 		StoragePrice:           unit.FormatCurrency(config.StoragePrice, "/byte/block"),
 		UploadBandwidthPrice:   unit.FormatCurrency(config.UploadBandwidthPrice, "/byte"),
 	}
+	for _, sp := range config.ScheduledPrices {
+		display.ScheduledPrices = append(display.ScheduledPrices,
+			fmt.Sprintf("%v: %v at block %v", sp.Name, unit.FormatCurrency(sp.Price), sp.EffectiveHeight))
+	}
 
 	return display
 }
@@ -108,6 +148,8 @@ func (h *HostPrivateAPI) GetFinancialMetrics() HostFinancialMetricsForDisplay {
 		RiskedStorageDeposit:              unit.FormatCurrency(fm.RiskedStorageDeposit),
 		StorageRevenue:                    unit.FormatCurrency(fm.StorageRevenue),
 		TransactionFeeExpenses:            unit.FormatCurrency(fm.TransactionFeeExpenses),
+		AnnouncementFeeExpenses:           unit.FormatCurrency(fm.AnnouncementFeeExpenses),
+		NetRevenue:                        unit.FormatCurrency(fm.netRevenue()),
 		DownloadBandwidthRevenue:          unit.FormatCurrency(fm.DownloadBandwidthRevenue),
 		PotentialDownloadBandwidthRevenue: unit.FormatCurrency(fm.PotentialDownloadBandwidthRevenue),
 		PotentialUploadBandwidthRevenue:   unit.FormatCurrency(fm.PotentialUploadBandwidthRevenue),
@@ -116,7 +158,20 @@ func (h *HostPrivateAPI) GetFinancialMetrics() HostFinancialMetricsForDisplay {
 	return display
 }
 
-//GetPaymentAddress get the account address used to sign the storage contract. If not configured, the first address in the local wallet will be used as the paymentAddress by default.
+// ContractMargins returns the net margin of each storage contract after the gas spent on the
+// storage proof, along with the deposit at risk. The contracts with the lowest margins are
+// listed first
+func (h *HostPrivateAPI) ContractMargins() ([]ContractMargin, error) {
+	return h.storageHost.contractMargins()
+}
+
+// MetricsHistory returns the hourly aggregates of the revenue, the bytes transferred, the
+// contracts and the storage proofs of the host in the last days
+func (h *HostPrivateAPI) MetricsHistory(days int) ([]timeseries.Point, error) {
+	return h.storageHost.metricsHistory(days)
+}
+
+// GetPaymentAddress get the account address used to sign the storage contract. If not configured, the first address in the local wallet will be used as the paymentAddress by default.
 func (h *HostPrivateAPI) GetPaymentAddress() string {
 	addr, err := h.storageHost.getPaymentAddress()
 	if err != nil {
@@ -160,13 +215,131 @@ func (h *HostPrivateAPI) DeleteFolder(folderPath string) (string, error) {
 	return "successfully delete the storage folder", nil
 }
 
+// RemapFolder update the path of the folder whose data file has been moved to newPath
+func (h *HostPrivateAPI) RemapFolder(oldPath string, newPath string) (string, error) {
+	err := h.storageHost.StorageManager.RemapFolder(oldPath, newPath)
+	if err != nil {
+		return "", err
+	}
+	return "successfully remap the storage folder", nil
+}
+
+// DelayedDeletions return the sectors removed by the clients that are waiting to be deleted
+func (h *HostPrivateAPI) DelayedDeletions() ([]DelayedDeletion, error) {
+	return h.storageHost.delayedDeletions()
+}
+
+// CancelDelayedDeletion restore the sectors queued for deletion at the height, so that they will
+// not be deleted. If no roots are specified, all sectors queued at the height are restored
+func (h *HostPrivateAPI) CancelDelayedDeletion(height uint64, roots []common.Hash) (string, error) {
+	if err := h.storageHost.cancelDelayedDeletion(height, roots); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("successfully restored the sectors queued for deletion at block %v", height), nil
+}
+
+// SchedulePrice schedule the price specified by name to be changed at the effective block height
+func (h *HostPrivateAPI) SchedulePrice(name string, priceStr string, height uint64) (string, error) {
+	price, err := unit.ParseCurrency(priceStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid currency expression: %v", err)
+	}
+	sp := storage.HostScheduledPrice{
+		Name:            name,
+		Price:           price,
+		EffectiveHeight: height,
+	}
+	if err = h.storageHost.schedulePrice(sp); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("successfully scheduled %v to be %v at block %v", name, priceStr, height), nil
+}
+
+// CancelScheduledPrice cancel the price change scheduled at the effective block height
+func (h *HostPrivateAPI) CancelScheduledPrice(name string, height uint64) (string, error) {
+	if err := h.storageHost.cancelScheduledPrice(name, height); err != nil {
+		return "", err
+	}
+	return "successfully canceled the scheduled price", nil
+}
+
+// ClientReputation returns the abuse reports of the storage clients sorted by the score
+func (h *HostPrivateAPI) ClientReputation() []ClientReputation {
+	return h.storageHost.reputation.report(time.Now())
+}
+
+// NegotiationUsage returns the memory buffered and the bandwidth consumed by the negotiations
+// in progress, along with the limits enforced
+func (h *HostPrivateAPI) NegotiationUsage() NegotiationUsage {
+	return h.storageHost.negotiations.usage()
+}
+
+// ResetClientReputation removes the abuse record of the storage client, which also lifts the ban
+func (h *HostPrivateAPI) ResetClientReputation(idStr string) (string, error) {
+	var id enode.ID
+	if err := id.UnmarshalText([]byte(idStr)); err != nil {
+		return "", fmt.Errorf("invalid client id: %v", err)
+	}
+	if !h.storageHost.reputation.reset(id) {
+		return "", fmt.Errorf("client %v has no abuse record", idStr)
+	}
+	return "successfully reset the client reputation", nil
+}
+
+// ResubmitProof constructs and sends the storage proof of the contract again, which is the
+// manual recovery when the previous submission of the storage proof failed
+func (h *HostPrivateAPI) ResubmitProof(idStr string) (string, error) {
+	var id common.Hash
+	if err := id.UnmarshalText([]byte(idStr)); err != nil {
+		return "", fmt.Errorf("invalid contract id: %v", err)
+	}
+	txHash, err := h.storageHost.resubmitStorageProof(id)
+	if err != nil {
+		return "", fmt.Errorf("cannot resubmit the storage proof of %v: %v", id.String(), err)
+	}
+	return fmt.Sprintf("Storage proof transaction: %v", txHash.Hex()), nil
+}
+
+// ValidateConfig validates the config changes specified by the key value pairs along with
+// the folders to be added or resized, which is a mapping from the folder path to the size.
+// The errors and warnings found are reported, and nothing is applied to the host
+func (h *HostPrivateAPI) ValidateConfig(config map[string]string, folders map[string]string) HostConfigValidation {
+	var report HostConfigValidation
+
+	// apply the changes with the setters, and revert to the current config afterwards
+	h.storageHost.lock.Lock()
+	prevConfig := h.storageHost.config
+	for key, value := range config {
+		callback, exist := hostSetterCallbacks[key]
+		if !exist {
+			report.Errors = append(report.Errors, fmt.Sprintf("unknown config variable %v", key))
+			continue
+		}
+		if err := callback(h, value); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%v: %v", key, err))
+		}
+	}
+	prospective := h.storageHost.config
+	h.storageHost.config = prevConfig
+	h.storageHost.lock.Unlock()
+
+	errs, warnings := h.storageHost.checkConfig(prospective, folders)
+	report.Errors = append(report.Errors, errs...)
+	report.Warnings = warnings
+	report.Valid = len(report.Errors) == 0
+	report.Config = hostConfigForDisplay(prospective)
+	return report
+}
+
 // hostSetterCallbacks is the mapping from the field name to the setter function
 var hostSetterCallbacks = map[string]func(*HostPrivateAPI, string) error{
 	"acceptingContracts":     (*HostPrivateAPI).setAcceptingContracts,
 	"maxDownloadBatchSize":   (*HostPrivateAPI).setMaxDownloadBatchSize,
 	"maxDuration":            (*HostPrivateAPI).setMaxDuration,
 	"maxReviseBatchSize":     (*HostPrivateAPI).setMaxReviseBatchSize,
+	"maxUploadBatchSectors":  (*HostPrivateAPI).setMaxUploadBatchSectors,
 	"windowSize":             (*HostPrivateAPI).setWindowSize,
+	"deletionRetention":      (*HostPrivateAPI).setDeletionRetention,
 	"paymentAddress":         (*HostPrivateAPI).setPaymentAddress,
 	"deposit":                (*HostPrivateAPI).setDeposit,
 	"depositBudget":          (*HostPrivateAPI).setDepositBudget,
@@ -251,6 +424,16 @@ func (h *HostPrivateAPI) setMaxReviseBatchSize(str string) error {
 	return nil
 }
 
+// setMaxUploadBatchSectors set host MaxUploadBatchSectors to value
+func (h *HostPrivateAPI) setMaxUploadBatchSectors(str string) error {
+	val, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number of sectors: %v", err)
+	}
+	h.storageHost.config.MaxUploadBatchSectors = val
+	return nil
+}
+
 // setWindowSize set host WindowSize to value
 func (h *HostPrivateAPI) setWindowSize(str string) error {
 	val, err := unit.ParseTime(str)
@@ -261,6 +444,16 @@ func (h *HostPrivateAPI) setWindowSize(str string) error {
 	return nil
 }
 
+// setDeletionRetention set host DeletionRetention to value
+func (h *HostPrivateAPI) setDeletionRetention(str string) error {
+	val, err := unit.ParseTime(str)
+	if err != nil {
+		return fmt.Errorf("invalid time duration string: %v", err)
+	}
+	h.storageHost.config.DeletionRetention = val
+	return nil
+}
+
 // setPaymentAddress configure the account address used to sign the storage contract,
 // which has and can only be the address of the local wallet.
 func (h *HostPrivateAPI) setPaymentAddress(addrStr string) error {
@@ -42,11 +42,21 @@ func TestHostPrivateAPI_SetConfig(t *testing.T) {
 			storage.HostIntConfig{MaxReviseBatchSize: uint64(mustParseStorage("1kb"))},
 			nil,
 		},
+		"maxUploadBatchSectors": {
+			map[string]string{"maxUploadBatchSectors": "8"},
+			storage.HostIntConfig{MaxUploadBatchSectors: 8},
+			nil,
+		},
 		"windowSize": {
 			map[string]string{"windowSize": "1b"},
 			storage.HostIntConfig{WindowSize: uint64(mustParseTime("1b"))},
 			nil,
 		},
+		"deletionRetention": {
+			map[string]string{"deletionRetention": "1d"},
+			storage.HostIntConfig{DeletionRetention: uint64(mustParseTime("1d"))},
+			nil,
+		},
 		"paymentAddress": {
 			map[string]string{"paymentAddress": "0x1"},
 			storage.HostIntConfig{},
@@ -0,0 +1,141 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DxChainNetwork/godx/accounts"
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/unit"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// HostConfigValidation is the report of validating a prospective host config. The config
+// is valid if no error is found, while the warnings do not prevent the config from being set
+type HostConfigValidation struct {
+	Valid    bool                            `json:"valid"`
+	Errors   []string                        `json:"errors"`
+	Warnings []string                        `json:"warnings"`
+	Config   storage.HostIntConfigForDisplay `json:"config"`
+}
+
+// checkConfig checks the prospective config and the folders to be added or resized against
+// the protocol limits, the wallet balance of the payment address, and the disk space
+func (h *StorageHost) checkConfig(config storage.HostIntConfig, folders map[string]string) (errs, warnings []string) {
+	errorf := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+	warnf := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	// protocol limits
+	if config.MaxDownloadBatchSize < storage.SectorSize {
+		errorf("maxDownloadBatchSize %v is smaller than the sector size %v", config.MaxDownloadBatchSize, storage.SectorSize)
+	}
+	if config.MaxReviseBatchSize < storage.SectorSize {
+		errorf("maxReviseBatchSize %v is smaller than the sector size %v", config.MaxReviseBatchSize, storage.SectorSize)
+	}
+	if config.MaxUploadBatchSectors != 0 && config.MaxUploadBatchSectors*storage.SectorSize > config.MaxReviseBatchSize {
+		warnf("maxUploadBatchSectors %v exceeds maxReviseBatchSize %v, the upload requests are limited by maxReviseBatchSize",
+			config.MaxUploadBatchSectors, unit.FormatStorage(config.MaxReviseBatchSize, false))
+	}
+	if config.WindowSize == 0 {
+		errorf("windowSize must be positive")
+	} else if config.WindowSize < storage.BlockPerHour {
+		warnf("windowSize %v is less than an hour, the storage proofs might be missed", unit.FormatTime(config.WindowSize))
+	}
+	if config.MaxDuration == 0 {
+		errorf("maxDuration must be positive")
+	} else if config.MaxDuration < storage.DefaultRentPayment.Period {
+		warnf("maxDuration %v is shorter than the default client period %v, such clients will not sign contracts",
+			unit.FormatTime(config.MaxDuration), unit.FormatTime(storage.DefaultRentPayment.Period))
+	}
+
+	// pricing and deposit
+	prices := []struct {
+		name  string
+		price common.BigInt
+	}{
+		{"storagePrice", config.StoragePrice},
+		{"uploadBandwidthPrice", config.UploadBandwidthPrice},
+		{"downloadBandwidthPrice", config.DownloadBandwidthPrice},
+	}
+	for _, p := range prices {
+		if p.price.Sign() == 0 {
+			warnf("%v is zero, the service is provided for free", p.name)
+		}
+	}
+	if config.DepositBudget.Cmp(config.MaxDeposit) < 0 {
+		warnf("depositBudget %v is less than maxDeposit %v, a single contract could use up the budget",
+			unit.FormatCurrency(config.DepositBudget), unit.FormatCurrency(config.MaxDeposit))
+	}
+
+	// payment address and the balance to pay the deposit
+	if config.AcceptingContracts {
+		h.checkPaymentAddress(config, errorf, warnf)
+	}
+
+	// folders to be added or resized, and the total space after the changes
+	var totalSectors uint64
+	existing := make(map[string]uint64)
+	for _, folder := range h.StorageManager.Folders() {
+		existing[folder.Path] = folder.TotalSectors
+		totalSectors += folder.TotalSectors
+	}
+	paths := make([]string, 0, len(folders))
+	for path := range folders {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		size, err := unit.ParseStorage(folders[path])
+		if err != nil {
+			errorf("folder %v: invalid storage string: %v", path, err)
+			continue
+		}
+		if err = h.StorageManager.ValidateFolder(path, size); err != nil {
+			errorf("folder %v: %v", path, err)
+			continue
+		}
+		totalSectors = totalSectors - existing[path] + size/storage.SectorSize
+	}
+	if config.AcceptingContracts && totalSectors == 0 {
+		errorf("accepting contracts without any storage folder")
+	}
+	return
+}
+
+// checkPaymentAddress checks whether the payment address is in the local wallet, and has
+// enough balance to pay the deposit of the contracts
+func (h *StorageHost) checkPaymentAddress(config storage.HostIntConfig, errorf, warnf func(string, ...interface{})) {
+	if config.PaymentAddress == (common.Address{}) {
+		errorf("accepting contracts without a payment address")
+		return
+	}
+	if h.am != nil {
+		if _, err := h.am.Find(accounts.Account{Address: config.PaymentAddress}); err != nil {
+			errorf("payment address %v is not in the local wallet", config.PaymentAddress.String())
+		}
+	}
+	if h.ethBackend == nil {
+		warnf("the balance of the payment address is not checked, the host is not started")
+		return
+	}
+	stateDB, err := h.ethBackend.GetBlockChain().State()
+	if err != nil {
+		warnf("the balance of the payment address is not checked: %v", err)
+		return
+	}
+	balance := common.PtrBigInt(stateDB.GetBalance(config.PaymentAddress))
+	if balance.Sign() == 0 {
+		errorf("payment address %v has no balance to pay the deposit", config.PaymentAddress.String())
+	} else if balance.Cmp(config.DepositBudget) < 0 {
+		warnf("the balance %v of the payment address is less than depositBudget %v",
+			unit.FormatCurrency(balance), unit.FormatCurrency(config.DepositBudget))
+	}
+}
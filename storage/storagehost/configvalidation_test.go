@@ -0,0 +1,64 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHostPrivateAPI_ValidateConfig(t *testing.T) {
+	h := newTestStorageHost(t)
+	if err := h.StorageManager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	api := NewHostPrivateAPI(h)
+	prevConfig := h.getInternalConfig()
+
+	tests := []struct {
+		config  map[string]string
+		folders map[string]string
+		errs    []string
+	}{
+		{
+			config: map[string]string{"storagePrice": "1camel"},
+		},
+		{
+			config: map[string]string{"unknown": "1", "windowSize": "xyz"},
+			errs:   []string{"unknown config variable", "windowSize"},
+		},
+		{
+			config: map[string]string{"acceptingContracts": "true"},
+			errs:   []string{"payment address", "without any storage folder"},
+		},
+		{
+			config:  map[string]string{"maxReviseBatchSize": "1kb"},
+			folders: map[string]string{filepath.Join(h.persistDir, "folder"): "1kb"},
+			errs:    []string{"maxReviseBatchSize", "folder"},
+		},
+	}
+	for i, test := range tests {
+		report := api.ValidateConfig(test.config, test.folders)
+		if report.Valid != (len(test.errs) == 0) || len(report.Errors) != len(test.errs) {
+			t.Fatalf("test %v: errors not expected: %v", i, report.Errors)
+		}
+		for _, expect := range test.errs {
+			var found bool
+			for _, err := range report.Errors {
+				found = found || strings.Contains(err, expect)
+			}
+			if !found {
+				t.Errorf("test %v: expect error containing %q, got %v", i, expect, report.Errors)
+			}
+		}
+		// the config should not be applied
+		if !reflect.DeepEqual(h.getInternalConfig(), prevConfig) {
+			t.Fatalf("test %v: the config is changed by validation", i)
+		}
+	}
+}
@@ -31,15 +31,34 @@ func ContractCreateHandler(h *StorageHost, sp storage.Peer, contractCreateReqMsg
 		}
 	}()
 
+	if h.isRetiring() {
+		hostNegotiateErr = errHostRetiring
+		return
+	}
 	if !h.externalConfig().AcceptingContracts {
 		hostNegotiateErr = errors.New("host is not accepting new contracts")
 		return
 	}
 
+	budget := h.beginNegotiation(negotiationCreate, sp)
+	defer budget.done()
+	if err := budget.admitReceived(uint64(contractCreateReqMsg.Size)); err != nil {
+		hostNegotiateErr = err
+		return
+	}
+
 	// 1. Read ContractCreateRequest msg
 	var req storage.ContractCreateRequest
 	if err := contractCreateReqMsg.Decode(&req); err != nil {
 		clientNegotiateErr = fmt.Errorf("failed to decode the contract create request message: %s", err.Error())
+		h.recordClientAbuse(sp, abuseMalformedMsg)
+		return
+	}
+
+	// Validate the proof outputs and collaterals before they are accessed
+	if err := validateContractCreateRequest(req); err != nil {
+		hostNegotiateErr = fmt.Errorf("contract create request validation failed: %s", err.Error())
+		h.recordClientAbuse(sp, abuseMalformedMsg)
 		return
 	}
 
@@ -115,6 +134,7 @@ func ContractCreateHandler(h *StorageHost, sp storage.Peer, contractCreateReqMsg
 	msg, err := sp.HostWaitContractResp()
 	if err != nil {
 		log.Error("storage host failed to get client revision sign", "err", err)
+		h.recordClientAbuse(sp, abuseTimeout)
 		return
 	}
 
@@ -125,6 +145,7 @@ func ContractCreateHandler(h *StorageHost, sp storage.Peer, contractCreateReqMsg
 
 	if err = msg.Decode(&clientRevisionSign); err != nil {
 		clientNegotiateErr = fmt.Errorf("storage host failed to decode client revision sign: %s", err.Error())
+		h.recordClientAbuse(sp, abuseMalformedMsg)
 		return
 	}
 
@@ -179,6 +200,7 @@ func ContractCreateHandler(h *StorageHost, sp storage.Peer, contractCreateReqMsg
 	msg, err = sp.HostWaitContractResp()
 	if err != nil {
 		log.Error("storage host failed to get client commit success msg", "err", err)
+		h.recordClientAbuse(sp, abuseTimeout)
 		return
 	}
 
@@ -199,7 +221,13 @@ func ContractCreateHandler(h *StorageHost, sp storage.Peer, contractCreateReqMsg
 			so.RiskedStorageDeposit = renewBaseDeposit(so, h.externalConfig(), req.StorageContract)
 		}
 
-		if err := finalizeStorageResponsibility(h, so); err != nil {
+		session := newCreateSession(so, req.Renew, req.OldContractID)
+		err := h.beginSession(session)
+		if err == nil {
+			err = finalizeStorageResponsibility(h, so)
+			h.endSession(session.ContractID)
+		}
+		if err != nil {
 			_ = sp.SendHostCommitFailedMsg()
 
 			// wait for client ack msg
@@ -215,6 +243,7 @@ func ContractCreateHandler(h *StorageHost, sp storage.Peer, contractCreateReqMsg
 		}
 	} else if msg.Code == storage.ClientCommitFailedMsg {
 		clientCommitErr = storage.ErrClientCommit
+		h.recordClientAbuse(sp, abuseCommitFailure)
 		return
 	} else if msg.Code == storage.ClientNegotiateErrorMsg {
 		clientNegotiateErr = storage.ErrClientNegotiate
@@ -5,9 +5,13 @@
 package storagehost
 
 import (
+	"bytes"
+
 	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/ethdb"
 	"github.com/DxChainNetwork/godx/rlp"
+
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // openDB opens the db specified by path. If the db file not exist, create a new one
@@ -77,6 +81,13 @@ func storeHeight(db ethdb.Database, storageContractID common.Hash, height uint64
 		existingItems = make([]byte, 0)
 	}
 
+	// the task of the storage contract is queued at most once at the height
+	for i := 0; i+common.HashLength <= len(existingItems); i += common.HashLength {
+		if bytes.Equal(existingItems[i:i+common.HashLength], storageContractID[:]) {
+			return nil
+		}
+	}
+
 	existingItems = append(existingItems, storageContractID[:]...)
 
 	return scdb.StoreWithPrefix(height, existingItems, prefixHeight)
@@ -98,3 +109,59 @@ func getHeight(db ethdb.Database, height uint64) ([]byte, error) {
 
 	return valueBytes, nil
 }
+
+//storeDelayedDeletion add the sector roots to the delayed deletion queue, which will be deleted at the height
+func storeDelayedDeletion(db ethdb.Database, height uint64, roots []common.Hash) error {
+	scdb := ethdb.StorageContractDB{db}
+
+	// only the missing entry means the queue at the height is empty, the other errors must
+	// not drop the roots already queued
+	existingRoots, err := scdb.GetWithPrefix(height, prefixDelayedDeletion)
+	if err == leveldb.ErrNotFound {
+		existingRoots = make([]byte, 0)
+	} else if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		existingRoots = append(existingRoots, root[:]...)
+	}
+
+	return scdb.StoreWithPrefix(height, existingRoots, prefixDelayedDeletion)
+}
+
+//replaceDelayedDeletion overwrite the sector roots in the delayed deletion queue at the height
+func replaceDelayedDeletion(db ethdb.Database, height uint64, roots []common.Hash) error {
+	scdb := ethdb.StorageContractDB{db}
+	data := make([]byte, 0, len(roots)*common.HashLength)
+	for _, root := range roots {
+		data = append(data, root[:]...)
+	}
+	return scdb.StoreWithPrefix(height, data, prefixDelayedDeletion)
+}
+
+//deleteDelayedDeletion remove the delayed deletion entry at the height
+func deleteDelayedDeletion(db ethdb.Database, height uint64) error {
+	scdb := ethdb.StorageContractDB{db}
+	return scdb.DeleteWithPrefix(height, prefixDelayedDeletion)
+}
+
+//getDelayedDeletions get all entries in the delayed deletion queue, mapping from the height to the sector roots
+func getDelayedDeletions(db *ethdb.LDBDatabase) (map[uint64][]common.Hash, error) {
+	deletions := make(map[uint64][]common.Hash)
+
+	iter := db.NewIteratorWithPrefix([]byte(prefixDelayedDeletion))
+	defer iter.Release()
+	for iter.Next() {
+		var height uint64
+		if err := rlp.DecodeBytes(iter.Key()[len(prefixDelayedDeletion):], &height); err != nil {
+			return nil, err
+		}
+		value := iter.Value()
+		roots := make([]common.Hash, len(value)/common.HashLength)
+		for i := range roots {
+			copy(roots[i][:], value[i*common.HashLength:(i+1)*common.HashLength])
+		}
+		deletions[height] = roots
+	}
+	return deletions, iter.Error()
+}
@@ -7,6 +7,7 @@ package storagehost
 import (
 	"math/big"
 	"strconv"
+	"time"
 
 	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/common/math"
@@ -26,6 +27,8 @@ const (
 	databaseFile = "hostdb"
 	// StorageManager is a dir for storagemanager related topic
 	StorageManager = "storagemanager"
+	// alertModule is the module name of the alerts raised by the storage host
+	alertModule = "storagehost"
 )
 
 const (
@@ -33,10 +36,70 @@ const (
 	postponedExecution    = 3  //Total length of time to start a test task
 	confirmedBufferHeight = 40 //signing transaction not confirmed maximum time
 
+	// proofResubmitInterval is the number of blocks a storage proof transaction is given to
+	// be confirmed before the proof could be manually resubmitted
+	proofResubmitInterval = 2 * postponedExecution
+
+	// proofResubmitDeadlineBuffer is the minimum number of blocks before the proof deadline
+	// a storage proof could be manually resubmitted, so that the transaction has a chance to
+	// be packed in time
+	proofResubmitDeadlineBuffer = postponedExecution
+
 	//prefixStorageResponsibility db prefix for StorageResponsibility
 	prefixStorageResponsibility = "StorageResponsibility-"
 	//prefixHeight db prefix for task
 	prefixHeight = "height-"
+	//prefixDelayedDeletion db prefix for the sectors waiting to be deleted
+	prefixDelayedDeletion = "delayedDeletion-"
+	//prefixNegotiationSession db prefix for the snapshots of the negotiation sessions being finalized
+	prefixNegotiationSession = "negotiationSession-"
+
+	// maxScheduledPrices is the maximum number of price changes the host could schedule,
+	// which limits the size of the external config broadcast to the clients
+	maxScheduledPrices = 16
+
+	// the sector roots of a storage responsibility are cached in sub trees of height 7,
+	// each of which is the root of 128 sector roots
+	subTreeHeight   = 7
+	rootsPerSubTree = 1 << subTreeHeight
+
+	// numSectorVerificationSamples is the number of sectors of a storage responsibility read
+	// and verified before the proof window
+	numSectorVerificationSamples = 4
+
+	// ioConcurrency is the maximum number of concurrent sector disk operations
+	ioConcurrency = 8
+
+	// proofIOUrgentBlocks is the number of blocks before the proof deadline, within which the
+	// sector reads for the storage proof are granted before the other disk operations
+	proofIOUrgentBlocks = 2 * postponedExecution
+
+	// the memory buffered and the bandwidth consumed by a negotiation are limited, and the
+	// negotiations exceeding the global limits wait for at most negotiationBudgetWait, except
+	// the requests read from the clients which wait till the memory is released. The
+	// memory buffered by a negotiation is limited by the max revise batch size of the host
+	// plus negotiationMessageOverhead for the revision and the encoding of the message
+	negotiationMessageOverhead     = 1 << 20
+	globalNegotiationMemoryLimit   = 1 << 30
+	negotiationBandwidthLimit      = 64 << 20
+	globalNegotiationBandwidthRate = 256 << 20 // bytes per second
+	negotiationBudgetWait          = 30 * time.Second
+)
+
+const (
+	// the half life of the client abuse score
+	reputationHalfLife = time.Hour
+
+	// the client abuse score above which the requests are delayed or the client is banned
+	reputationDeprioritizeScore = 10
+	reputationBanScore          = 30
+
+	// reputationDeprioritizeDelay is the delay before handling the requests of a deprioritized client
+	reputationDeprioritizeDelay = 5 * time.Second
+
+	// the duration of the first ban, which is doubled for each ban after till the max ban duration
+	reputationBaseBanDuration = 10 * time.Minute
+	reputationMaxBanDuration  = 24 * time.Hour
 )
 
 var (
@@ -49,10 +112,12 @@ var (
 	}
 
 	// persistence default value
-	defaultMaxDuration          = storage.BlocksPerDay * 30 // 30 days
-	defaultMaxDownloadBatchSize = 17 * (1 << 20)            // 17 MB
-	defaultMaxReviseBatchSize   = 17 * (1 << 20)            // 17 MB
-	defaultWindowSize           = 5 * storage.BlockPerHour  // 5 hours
+	defaultMaxDuration           = storage.BlocksPerDay * 30 // 30 days
+	defaultMaxDownloadBatchSize  = 17 * (1 << 20)            // 17 MB
+	defaultMaxReviseBatchSize    = 17 * (1 << 20)            // 17 MB
+	defaultMaxUploadBatchSectors = 4
+	defaultWindowSize            = 5 * storage.BlockPerHour // 5 hours
+	defaultDeletionRetention     = storage.BlocksPerDay     // 1 day
 
 	// deposit defaults value
 	defaultDeposit       = common.PtrBigInt(math.BigPow(10, 3))  // 173 dx per TB per month
@@ -72,6 +137,11 @@ var (
 
 	//Total time to sign the contract
 	postponedExecutionBuffer = storage.BlocksPerDay
+
+	// sectorVerificationLead is the number of blocks before the proof window the sectors
+	// of a storage responsibility are verified, which leaves the operator time to respond
+	// to the disk errors
+	sectorVerificationLead = 6 * storage.BlockPerHour
 )
 
 // init set the initial value for sector height
@@ -92,10 +162,12 @@ func calculateSectorHeight() uint64 {
 // it is the first time use the host service, or cannot find the setting file
 func defaultConfig() storage.HostIntConfig {
 	return storage.HostIntConfig{
-		MaxDownloadBatchSize: uint64(defaultMaxDownloadBatchSize),
-		MaxDuration:          uint64(defaultMaxDuration),
-		MaxReviseBatchSize:   uint64(defaultMaxReviseBatchSize),
-		WindowSize:           uint64(defaultWindowSize),
+		MaxDownloadBatchSize:  uint64(defaultMaxDownloadBatchSize),
+		MaxDuration:           uint64(defaultMaxDuration),
+		MaxReviseBatchSize:    uint64(defaultMaxReviseBatchSize),
+		MaxUploadBatchSectors: uint64(defaultMaxUploadBatchSectors),
+		WindowSize:            uint64(defaultWindowSize),
+		DeletionRetention:     uint64(defaultDeletionRetention),
 
 		Deposit:       defaultDeposit,
 		DepositBudget: defaultDepositBudget,
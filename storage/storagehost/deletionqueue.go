@@ -0,0 +1,137 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
+)
+
+// DelayedDeletion is the list of sectors removed by the storage clients, which
+// will be physically deleted from the storage manager at the height
+type DelayedDeletion struct {
+	Height uint64        `json:"height"`
+	Roots  []common.Hash `json:"roots"`
+}
+
+// queueSectorDeletion places the sectors removed by the client into the delayed deletion
+// queue. Before the deletion retention passed, the sectors are still kept in the storage
+// manager, so that the same sectors uploaded again do not need to be written again. If
+// the deletion retention is not set, the sectors will be deleted immediately.
+// Require: lock the storageHost by caller
+func (h *StorageHost) queueSectorDeletion(roots []common.Hash) error {
+	if len(roots) == 0 {
+		return nil
+	}
+	if h.config.DeletionRetention == 0 {
+		return h.DeleteSectorBatch(roots)
+	}
+	return storeDelayedDeletion(h.db, h.blockHeight+h.config.DeletionRetention, roots)
+}
+
+// processDelayedDeletions physically deletes the sectors in the delayed deletion queue
+// whose deletion height has been reached. The due deletions are taken under the lock,
+// and deleted from the storage manager without holding the lock
+func (h *StorageHost) processDelayedDeletions() {
+	h.lock.Lock()
+	deletions, err := getDelayedDeletions(h.db)
+	if err != nil {
+		h.lock.Unlock()
+		h.log.Error("failed to get the delayed deletions", "err", err)
+		return
+	}
+	due := make(map[uint64][]common.Hash)
+	for height, roots := range deletions {
+		if _, deleting := h.deletingHeights[height]; height > h.blockHeight || deleting {
+			continue
+		}
+		h.deletingHeights[height] = struct{}{}
+		due[height] = roots
+	}
+	h.lock.Unlock()
+
+	for height, roots := range due {
+		err := h.DeleteSectorBatch(roots)
+		h.lock.Lock()
+		delete(h.deletingHeights, height)
+		if err != nil {
+			alert.Raise(h.log, alert.SeverityWarning, alertModule, "failed to delete the sectors in delayed deletion queue", "height", height, "err", err)
+		} else if err = deleteDelayedDeletion(h.db, height); err != nil {
+			h.log.Warn("failed to remove the delayed deletion", "height", height, "err", err)
+		}
+		h.lock.Unlock()
+	}
+}
+
+// cancelDelayedDeletion removes the sectors from the delayed deletion queue at the height,
+// so that the sectors are kept in the storage manager. If no roots are specified, all
+// sectors queued at the height are restored. The deletion already in progress can not
+// be cancelled
+func (h *StorageHost) cancelDelayedDeletion(height uint64, roots []common.Hash) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if _, deleting := h.deletingHeights[height]; deleting {
+		return fmt.Errorf("the sectors queued at block %v are being deleted", height)
+	}
+	deletions, err := getDelayedDeletions(h.db)
+	if err != nil {
+		return err
+	}
+	queued, exist := deletions[height]
+	if !exist {
+		return fmt.Errorf("no sector is queued for deletion at block %v", height)
+	}
+	if len(roots) == 0 {
+		return deleteDelayedDeletion(h.db, height)
+	}
+
+	// the same sector could be queued several times, each cancelled root restores one
+	remain := queued
+	for _, root := range roots {
+		i := indexOfRoot(remain, root)
+		if i < 0 {
+			return fmt.Errorf("sector %x is not queued for deletion at block %v", root, height)
+		}
+		remain = append(remain[:i:i], remain[i+1:]...)
+	}
+	if len(remain) == 0 {
+		return deleteDelayedDeletion(h.db, height)
+	}
+	return replaceDelayedDeletion(h.db, height, remain)
+}
+
+// indexOfRoot returns the index of the root in the roots, -1 if not found
+func indexOfRoot(roots []common.Hash, root common.Hash) int {
+	for i := range roots {
+		if roots[i] == root {
+			return i
+		}
+	}
+	return -1
+}
+
+// delayedDeletions returns the sectors in the delayed deletion queue sorted by the
+// deletion height
+func (h *StorageHost) delayedDeletions() ([]DelayedDeletion, error) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	deletions, err := getDelayedDeletions(h.db)
+	if err != nil {
+		return nil, err
+	}
+	var queue []DelayedDeletion
+	for height, roots := range deletions {
+		queue = append(queue, DelayedDeletion{Height: height, Roots: roots})
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].Height < queue[j].Height
+	})
+	return queue, nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestStorageHost_DelayedDeletion(t *testing.T) {
+	h := newTestStorageHost(t)
+	if err := h.StorageManager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		h.StorageManager.Close()
+		h.db.Close()
+	}()
+	if err := h.AddStorageFolder(filepath.Join(h.persistDir, "folder"), 1<<25); err != nil {
+		t.Fatal(err)
+	}
+
+	var roots []common.Hash
+	for i := 0; i != 2; i++ {
+		data := make([]byte, storage.SectorSize)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatal(err)
+		}
+		root := merkle.Sha256MerkleTreeRoot(data)
+		if err := h.AddSector(root, data); err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, root)
+	}
+
+	h.blockHeight = 10
+	h.config.DeletionRetention = 5
+	if err := h.queueSectorDeletion(roots); err != nil {
+		t.Fatal(err)
+	}
+	queue, err := h.delayedDeletions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].Height != 15 || len(queue[0].Roots) != len(roots) {
+		t.Fatalf("delayed deletion queue not expected: %+v", queue)
+	}
+
+	// before the retention passed, the sectors should still exist
+	h.blockHeight = 14
+	h.processDelayedDeletions()
+	for _, root := range roots {
+		if _, err := h.ReadSector(root); err != nil {
+			t.Fatalf("sector %x should not be deleted before the retention passed: %v", root, err)
+		}
+	}
+
+	// after the retention passed, the sectors should be deleted
+	h.blockHeight = 15
+	h.processDelayedDeletions()
+	for _, root := range roots {
+		if _, err := h.ReadSector(root); err == nil {
+			t.Fatalf("sector %x should be deleted after the retention passed", root)
+		}
+	}
+	if queue, err = h.delayedDeletions(); err != nil || len(queue) != 0 {
+		t.Fatalf("delayed deletion queue should be empty: %v, %v", queue, err)
+	}
+}
+
+func TestStoreDelayedDeletion(t *testing.T) {
+	db, err := openDB(filepath.Join(tempDir(t.Name()), databaseFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := []common.Hash{{1}, {2}}
+	if err = storeDelayedDeletion(db, 10, roots[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err = storeDelayedDeletion(db, 10, roots[1:]); err != nil {
+		t.Fatal(err)
+	}
+	queue, err := getDelayedDeletions(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue[10]) != 2 || queue[10][0] != roots[0] || queue[10][1] != roots[1] {
+		t.Fatalf("the roots queued at the same height should be appended: %v", queue)
+	}
+
+	// the failure to read the queue should not overwrite the roots already queued
+	db.Close()
+	if err = storeDelayedDeletion(db, 10, roots); err == nil {
+		t.Fatalf("the failure to read the queue should be returned")
+	}
+}
+
+func TestStorageHost_CancelDelayedDeletion(t *testing.T) {
+	h := newTestStorageHost(t)
+	defer h.db.Close()
+
+	roots := []common.Hash{{1}, {2}, {1}, {3}}
+	if err := storeDelayedDeletion(h.db, 10, roots); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeDelayedDeletion(h.db, 20, roots); err != nil {
+		t.Fatal(err)
+	}
+
+	// each cancelled root restores one of the sectors queued
+	if err := h.cancelDelayedDeletion(10, []common.Hash{{1}, {3}}); err != nil {
+		t.Fatal(err)
+	}
+	queue, err := getDelayedDeletions(h.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue[10]) != 2 || queue[10][0] != roots[1] || queue[10][1] != roots[2] {
+		t.Fatalf("the roots remained not expected: %v", queue[10])
+	}
+	if len(queue[20]) != len(roots) {
+		t.Fatalf("the roots queued at the other height should not be changed: %v", queue[20])
+	}
+	if err = h.cancelDelayedDeletion(10, []common.Hash{{3}}); err == nil {
+		t.Fatalf("the root not queued should not be cancelled")
+	}
+	if err = h.cancelDelayedDeletion(30, nil); err == nil {
+		t.Fatalf("the height not queued should not be cancelled")
+	}
+
+	// the deletion in progress can not be cancelled
+	h.deletingHeights[20] = struct{}{}
+	if err = h.cancelDelayedDeletion(20, nil); err == nil {
+		t.Fatalf("the deletion in progress should not be cancelled")
+	}
+	delete(h.deletingHeights, 20)
+
+	// all sectors at the height are restored without the roots specified
+	if err = h.cancelDelayedDeletion(20, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.cancelDelayedDeletion(10, []common.Hash{{2}, {1}}); err != nil {
+		t.Fatal(err)
+	}
+	if queue, err = getDelayedDeletions(h.db); err != nil || len(queue) != 0 {
+		t.Fatalf("delayed deletion queue should be empty: %v, %v", queue, err)
+	}
+}
@@ -33,11 +33,15 @@ func DownloadHandler(h *StorageHost, sp storage.Peer, downloadReqMsg p2p.Msg) {
 		}
 	}()
 
+	budget := h.beginNegotiation(negotiationDownload, sp)
+	defer budget.done()
+
 	// read the download request.
 	var req storage.DownloadRequest
 	err := downloadReqMsg.Decode(&req)
 	if err != nil {
 		clientNegotiateErr = fmt.Errorf("error decoding the download request message: %s", err.Error())
+		h.recordClientAbuse(sp, abuseMalformedMsg)
 		return
 	}
 
@@ -71,10 +75,8 @@ func DownloadHandler(h *StorageHost, sp storage.Peer, downloadReqMsg p2p.Msg) {
 		err = errors.New("length cannot be 0")
 	case req.MerkleProof && (sec.Offset%storage.SegmentSize != 0 || sec.Length%storage.SegmentSize != 0):
 		err = errors.New("offset and length must be multiples of SegmentSize when requesting a Merkle proof")
-	case len(req.NewValidProofValues) != len(currentRevision.NewValidProofOutputs):
-		err = errors.New("the number of valid proof values not match the old")
-	case len(req.NewMissedProofValues) != len(currentRevision.NewMissedProofOutputs):
-		err = errors.New("the number of missed proof values not match the old")
+	default:
+		err = validateProofValues(currentRevision, req.NewValidProofValues, req.NewMissedProofValues)
 	}
 	if err != nil {
 		hostNegotiateErr = fmt.Errorf("download request validation failed: %s", err.Error())
@@ -82,30 +84,19 @@ func DownloadHandler(h *StorageHost, sp storage.Peer, downloadReqMsg p2p.Msg) {
 	}
 
 	// construct the new revision
-	newRevision := currentRevision
+	newRevision := revisionWithProofValues(currentRevision, req.NewValidProofValues, req.NewMissedProofValues)
 	newRevision.NewRevisionNumber = req.NewRevisionNumber
-	newRevision.NewValidProofOutputs = make([]types.DxcoinCharge, len(currentRevision.NewValidProofOutputs))
-	for i := range newRevision.NewValidProofOutputs {
-		newRevision.NewValidProofOutputs[i] = types.DxcoinCharge{
-			Value:   req.NewValidProofValues[i],
-			Address: currentRevision.NewValidProofOutputs[i].Address,
-		}
-	}
-	newRevision.NewMissedProofOutputs = make([]types.DxcoinCharge, len(currentRevision.NewMissedProofOutputs))
-	for i := range newRevision.NewMissedProofOutputs {
-		newRevision.NewMissedProofOutputs[i] = types.DxcoinCharge{
-			Value:   req.NewMissedProofValues[i],
-			Address: currentRevision.NewMissedProofOutputs[i].Address,
-		}
-	}
 
 	// calculate expected cost and verify against client's revision
 	var estBandwidth uint64
 	sectorAccesses := make(map[common.Hash]struct{})
-	// use the worst-case proof size of 2*tree depth (this occurs when
-	// proving across the two leaves in the center of the tree)
-	estHashesPerProof := 2 * bits.Len64(storage.SectorSize/merkle.LeafSize)
-	estBandwidth += uint64(sec.Length) + uint64(estHashesPerProof*storage.HashSize)
+	estBandwidth += uint64(sec.Length)
+	if req.MerkleProof {
+		// use the worst-case proof size of 2*tree depth (this occurs when
+		// proving across the two leaves in the center of the tree)
+		estHashesPerProof := 2 * bits.Len64(storage.SectorSize/merkle.LeafSize)
+		estBandwidth += uint64(estHashesPerProof * storage.HashSize)
+	}
 	sectorAccesses[sec.MerkleRoot] = struct{}{}
 
 	// calculate total cost
@@ -118,6 +109,13 @@ func DownloadHandler(h *StorageHost, sp storage.Peer, downloadReqMsg p2p.Msg) {
 		return
 	}
 
+	// account the sector read into memory and the data sent, which waits if the host is
+	// busy with the other negotiations
+	if err = budget.admit(storage.SectorSize, estBandwidth); err != nil {
+		hostNegotiateErr = err
+		return
+	}
+
 	// Sign the new revision.
 	account := accounts.Account{Address: newRevision.NewValidProofOutputs[1].Address}
 	wallet, err := h.am.Find(account)
@@ -140,7 +138,7 @@ func DownloadHandler(h *StorageHost, sp storage.Peer, downloadReqMsg p2p.Msg) {
 	so.StorageContractRevisions = append(so.StorageContractRevisions, newRevision)
 
 	// fetch the requested data from host local storage
-	sectorData, err := h.ReadSector(sec.MerkleRoot)
+	sectorData, err := h.readSector(sec.MerkleRoot, ioDownloadServe, false)
 	if err != nil {
 		hostNegotiateErr = fmt.Errorf("host failed read sector: %s", err.Error())
 		return
@@ -176,6 +174,7 @@ func DownloadHandler(h *StorageHost, sp storage.Peer, downloadReqMsg p2p.Msg) {
 	msg, err := sp.HostWaitContractResp()
 	if err != nil {
 		log.Error("storage host failed to get client commit success msg", "err", err)
+		h.recordClientAbuse(sp, abuseTimeout)
 		return
 	}
 
@@ -195,8 +194,10 @@ func DownloadHandler(h *StorageHost, sp storage.Peer, downloadReqMsg p2p.Msg) {
 			_ = sp.SendHostAckMsg()
 			return
 		}
+		h.recordMetric(storage.MetricDownloadBytes, common.NewBigIntUint64(uint64(len(data))))
 	} else if msg.Code == storage.ClientCommitFailedMsg {
 		clientCommitErr = storage.ErrClientCommit
+		h.recordClientAbuse(sp, abuseCommitFailure)
 		return
 	} else if msg.Code == storage.ClientNegotiateErrorMsg {
 		clientNegotiateErr = storage.ErrClientNegotiate
@@ -6,6 +6,7 @@ package storagehost
 
 import (
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
 	"github.com/DxChainNetwork/godx/core"
 	"github.com/DxChainNetwork/godx/core/types"
 	"github.com/DxChainNetwork/godx/core/vm"
@@ -45,13 +46,19 @@ func (h *StorageHost) hostBlockHeightChange(cce core.ChainChangeEvent) {
 		h.handleTaskItem(taskItems[i])
 	}
 
+	// physically delete the sectors whose deletion retention has passed
+	h.processDelayedDeletions()
+
+	// change the prices whose effective height has been reached
+	h.applyScheduledPrices()
+
 	// update the contractToClientID
 	h.UpdateContractToClientNodeMappingAndConnection()
 
 	// sync the configuration
 	err := h.syncConfig()
 	if err != nil {
-		h.log.Error("could not save during ProcessConsensusChange", "err", err)
+		alert.Raise(h.log, alert.SeverityError, alertModule, "failed to save the host persistence on block change", "err", err)
 	}
 }
 
@@ -68,6 +75,11 @@ func (h *StorageHost) applyBlockHashesStorageResponsibility(blocks []common.Hash
 		if errGetBlock != nil {
 			continue
 		}
+		fees, errFee := h.getStorageTxFeesWithBlockHash(blockApply)
+		if errFee != nil {
+			h.log.Error("Failed to get the storage transaction fees of the block", "err", errFee)
+		}
+		h.applyAnnouncementFee(fees.announcement, false)
 
 		//Traverse all contract transactions and modify storage responsibility status
 		for _, id := range ContractCreateIDsApply {
@@ -114,6 +126,7 @@ func (h *StorageHost) applyBlockHashesStorageResponsibility(blocks []common.Hash
 				continue
 			}
 			so.StorageProofConfirmed = true
+			h.applyStorageProofFee(&so, fees.proofs[id], false)
 			errPut := putStorageResponsibility(h.db, so.id(), so)
 			if errPut != nil {
 				h.log.Error("Failed to put storage responsibility", "err", errPut)
@@ -160,6 +173,11 @@ func (h *StorageHost) revertedBlockHashesStorageResponsibility(blocks []common.H
 			h.log.Error("Failed to get the data from the block as expected ", "err", errGetBlock)
 			continue
 		}
+		fees, errFee := h.getStorageTxFeesWithBlockHash(blockReverted)
+		if errFee != nil {
+			h.log.Error("Failed to get the storage transaction fees of the block", "err", errFee)
+		}
+		h.applyAnnouncementFee(fees.announcement, true)
 
 		//Traverse all ContractCreate transactions and modify storage responsibility status
 		for _, id := range ContractCreateIDs {
@@ -199,6 +217,7 @@ func (h *StorageHost) revertedBlockHashesStorageResponsibility(blocks []common.H
 				continue
 			}
 			so.StorageProofConfirmed = false
+			h.applyStorageProofFee(&so, fees.proofs[id], true)
 			errPut := putStorageResponsibility(h.db, so.id(), so)
 			if errPut != nil {
 				h.log.Error("Failed to put storage responsibility", "err", errPut)
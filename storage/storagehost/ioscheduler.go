@@ -0,0 +1,111 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"sync"
+
+	"github.com/DxChainNetwork/godx/common"
+)
+
+// ioPurpose is the purpose of a disk operation of the storage manager
+type ioPurpose int
+
+const (
+	// ioProof is the sector read for the storage proof construction and verification
+	ioProof ioPurpose = iota
+	// ioUploadIngest is the sector write of the data uploaded by the clients
+	ioUploadIngest
+	// ioDownloadServe is the sector read serving the downloads of the clients
+	ioDownloadServe
+)
+
+type (
+	// ioScheduler limits the number of concurrent disk operations. Once all slots are taken,
+	// the waiting operations are granted in the order they arrived, except that the urgent
+	// storage proof reads are granted first, since a missed storage proof costs the deposit
+	ioScheduler struct {
+		lock    sync.Mutex
+		free    int
+		waiting []*ioRequest
+	}
+
+	// ioRequest is a disk operation waiting for a slot
+	ioRequest struct {
+		purpose ioPurpose
+		urgent  bool
+		granted chan struct{}
+	}
+)
+
+// newIOScheduler creates the io scheduler allowing the number of concurrent disk operations
+func newIOScheduler(concurrency int) *ioScheduler {
+	return &ioScheduler{free: concurrency}
+}
+
+// acquire blocks until a slot is granted to the disk operation. Only the storage proof reads
+// could be urgent. The slot must be released after the operation finished
+func (s *ioScheduler) acquire(purpose ioPurpose, urgent bool) {
+	s.lock.Lock()
+	if s.free > 0 {
+		s.free--
+		s.lock.Unlock()
+		return
+	}
+	req := &ioRequest{
+		purpose: purpose,
+		urgent:  urgent && purpose == ioProof,
+		granted: make(chan struct{}),
+	}
+	s.waiting = append(s.waiting, req)
+	s.lock.Unlock()
+
+	<-req.granted
+}
+
+// release hands the slot to the next waiting disk operation, or frees it if there is none
+func (s *ioScheduler) release() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.waiting) == 0 {
+		s.free++
+		return
+	}
+	i := s.next()
+	req := s.waiting[i]
+	s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+	close(req.granted)
+}
+
+// next returns the index of the waiting disk operation to be granted next
+func (s *ioScheduler) next() int {
+	for i, req := range s.waiting {
+		if req.urgent {
+			return i
+		}
+	}
+	return 0
+}
+
+// proofReadUrgent checks if the storage proof of the responsibility is close to the deadline,
+// in which case the sector reads for the proof are prioritized
+func (h *StorageHost) proofReadUrgent(so StorageResponsibility) bool {
+	return h.blockHeight+proofIOUrgentBlocks >= so.proofDeadline()
+}
+
+// readSector reads the sector through the io scheduler
+func (h *StorageHost) readSector(root common.Hash, purpose ioPurpose, urgent bool) ([]byte, error) {
+	h.io.acquire(purpose, urgent)
+	defer h.io.release()
+	return h.ReadSector(root)
+}
+
+// addSector writes the sector uploaded through the io scheduler
+func (h *StorageHost) addSector(root common.Hash, data []byte) error {
+	h.io.acquire(ioUploadIngest, false)
+	defer h.io.release()
+	return h.AddSector(root, data)
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIOScheduler_UrgentProofFirst(t *testing.T) {
+	s := newIOScheduler(1)
+	s.acquire(ioUploadIngest, false)
+
+	// queue the operations one by one, so that the arrival order is fixed
+	order := make(chan string, 4)
+	var queued int
+	queue := func(name string, purpose ioPurpose, urgent bool) {
+		go func() {
+			s.acquire(purpose, urgent)
+			order <- name
+			s.release()
+		}()
+		for {
+			s.lock.Lock()
+			n := len(s.waiting)
+			s.lock.Unlock()
+			if n == queued+1 {
+				queued++
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	queue("upload", ioUploadIngest, false)
+	queue("download", ioDownloadServe, false)
+	queue("proof", ioProof, false)
+	queue("urgentProof", ioProof, true)
+
+	s.release()
+	expected := []string{"urgentProof", "upload", "download", "proof"}
+	for _, name := range expected {
+		select {
+		case got := <-order:
+			if got != name {
+				t.Fatalf("expect %v to be granted, got %v", name, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%v is not granted", name)
+		}
+	}
+
+	if s.free != 1 || len(s.waiting) != 0 {
+		t.Errorf("all slots should be freed, free %v, waiting %v", s.free, len(s.waiting))
+	}
+}
+
+func TestIOScheduler_UrgentOnlyForProof(t *testing.T) {
+	s := newIOScheduler(1)
+	s.acquire(ioProof, true)
+
+	done := make(chan struct{})
+	go func() {
+		s.acquire(ioDownloadServe, true)
+		close(done)
+	}()
+	for {
+		s.lock.Lock()
+		var urgent bool
+		n := len(s.waiting)
+		if n == 1 {
+			urgent = s.waiting[0].urgent
+		}
+		s.lock.Unlock()
+		if n == 1 {
+			if urgent {
+				t.Errorf("the download should not be urgent")
+			}
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.release()
+	<-done
+}
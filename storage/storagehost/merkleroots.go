@@ -0,0 +1,124 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"context"
+	"sync"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+)
+
+type (
+	// sectorRootsCache keeps the cached sub trees of the committed sector roots of the storage
+	// responsibilities, so that the merkle root of an append-only revision is calculated from
+	// the sub trees and the roots not cached, instead of all sector roots
+	sectorRootsCache struct {
+		trees map[common.Hash]*cachedRoots
+		lock  sync.Mutex
+	}
+
+	// cachedRoots is the sub trees of a storage responsibility. Each sub tree is the root
+	// of rootsPerSubTree sector roots
+	cachedRoots struct {
+		subTrees []cachedSubTree
+	}
+
+	// cachedSubTree is the merkle root of the sector roots, and the last sector root
+	// of them, which is used to detect that the sector roots are changed
+	cachedSubTree struct {
+		sum  common.Hash
+		last common.Hash
+	}
+)
+
+// newSectorRootsCache creates an empty sectorRootsCache
+func newSectorRootsCache() *sectorRootsCache {
+	return &sectorRootsCache{
+		trees: make(map[common.Hash]*cachedRoots),
+	}
+}
+
+// root calculates the merkle root of the sector roots of the storage responsibility. The
+// cached sub trees are used for the prefix of the roots, and the rest of the roots are
+// pushed to the tree. The cache is not updated, since the roots might not be committed.
+// A nil cache calculates the root from all sector roots
+func (rc *sectorRootsCache) root(ctx context.Context, id common.Hash, roots []common.Hash) (common.Hash, error) {
+	subTrees := rc.subTrees(id, roots)
+
+	ct := merkle.NewSha256CachedTree(sectorHeight)
+	for _, st := range subTrees {
+		if err := ct.PushSubTree(subTreeHeight, st.sum); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	if err := ct.PushRoots(ctx, roots[len(subTrees)*rootsPerSubTree:]); err != nil {
+		return common.Hash{}, err
+	}
+	return ct.Root(), nil
+}
+
+// update caches the sub trees of the committed sector roots of the storage responsibility
+func (rc *sectorRootsCache) update(id common.Hash, roots []common.Hash) {
+	if rc == nil {
+		return
+	}
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	cr, exist := rc.trees[id]
+	if !exist {
+		cr = &cachedRoots{}
+		rc.trees[id] = cr
+	}
+	cr.subTrees = validSubTrees(cr.subTrees, roots)
+	for start := len(cr.subTrees) * rootsPerSubTree; start+rootsPerSubTree <= len(roots); start += rootsPerSubTree {
+		group := roots[start : start+rootsPerSubTree]
+		cr.subTrees = append(cr.subTrees, cachedSubTree{
+			sum:  merkle.Sha256CachedTreeRoot(group, sectorHeight),
+			last: group[len(group)-1],
+		})
+	}
+}
+
+// remove removes the cached sub trees of the storage responsibility
+func (rc *sectorRootsCache) remove(id common.Hash) {
+	if rc == nil {
+		return
+	}
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	delete(rc.trees, id)
+}
+
+// subTrees returns the cached sub trees still valid for the roots
+func (rc *sectorRootsCache) subTrees(id common.Hash, roots []common.Hash) []cachedSubTree {
+	if rc == nil {
+		return nil
+	}
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	cr, exist := rc.trees[id]
+	if !exist {
+		return nil
+	}
+	cr.subTrees = validSubTrees(cr.subTrees, roots)
+	return append([]cachedSubTree(nil), cr.subTrees...)
+}
+
+// validSubTrees drops the sub trees not covered by the roots, which happens when the roots
+// are rolled back. If the last sector root of the last sub tree does not match, the roots
+// are replaced and all sub trees are dropped
+func validSubTrees(subTrees []cachedSubTree, roots []common.Hash) []cachedSubTree {
+	if n := len(roots) / rootsPerSubTree; len(subTrees) > n {
+		subTrees = subTrees[:n]
+	}
+	if n := len(subTrees); n != 0 && roots[n*rootsPerSubTree-1] != subTrees[n-1].last {
+		return nil
+	}
+	return subTrees
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+)
+
+func TestSectorRootsCache(t *testing.T) {
+	rc := newSectorRootsCache()
+	id := common.Hash{1}
+	ctx := context.Background()
+
+	checkRoot := func(roots []common.Hash) {
+		t.Helper()
+		got, err := rc.root(ctx, id, roots)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect := merkle.Sha256CachedTreeRoot(roots, sectorHeight); got != expect {
+			t.Fatalf("%v roots: expect merkle root %x, got %x", len(roots), expect, got)
+		}
+	}
+
+	// append the roots in batches, and commit them
+	var roots []common.Hash
+	for _, n := range []int{1, rootsPerSubTree - 2, 3, 2*rootsPerSubTree + 5, 10} {
+		roots = append(roots, randomRoots(n)...)
+		checkRoot(roots)
+		rc.update(id, roots)
+		checkRoot(roots)
+	}
+	if len(rc.trees[id].subTrees) != len(roots)/rootsPerSubTree {
+		t.Fatalf("expect %v sub trees, got %v", len(roots)/rootsPerSubTree, len(rc.trees[id].subTrees))
+	}
+
+	// uncommitted roots are not cached
+	checkRoot(append(append([]common.Hash{}, roots...), randomRoots(rootsPerSubTree)...))
+	if len(rc.trees[id].subTrees) != len(roots)/rootsPerSubTree {
+		t.Fatalf("the sub trees of the uncommitted roots are cached")
+	}
+
+	// rolled back to fewer roots
+	roots = roots[:rootsPerSubTree+3]
+	checkRoot(roots)
+
+	// the roots are replaced
+	roots = randomRoots(len(roots))
+	checkRoot(roots)
+
+	// the nil cache calculates from all roots
+	rc.remove(id)
+	if _, exist := rc.trees[id]; exist {
+		t.Fatalf("the sub trees are not removed")
+	}
+	rc = nil
+	checkRoot(roots)
+}
+
+// randomRoots returns n random sector roots
+func randomRoots(n int) []common.Hash {
+	roots := make([]common.Hash, n)
+	for i := range roots {
+		rand.Read(roots[i][:])
+	}
+	return roots
+}
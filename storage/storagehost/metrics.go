@@ -0,0 +1,47 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/timeseries"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// hostMetrics are the metrics aggregated hourly by the storage host
+var hostMetrics = []string{
+	storage.MetricRevenue,
+	storage.MetricUploadBytes,
+	storage.MetricDownloadBytes,
+	storage.MetricContracts,
+	storage.MetricStorageProofs,
+}
+
+// openMetrics opens the ring file of the host metrics in the persist directory
+func (h *StorageHost) openMetrics() (err error) {
+	path := filepath.Join(h.persistDir, storage.MetricsFileName)
+	h.metrics, err = timeseries.Open(path, hostMetrics, storage.MetricsRetentionHours)
+	return
+}
+
+// recordMetric adds the value to the aggregate of the metric in the current hour.
+// The metrics are only for display, so the failure is logged and not returned
+func (h *StorageHost) recordMetric(metric string, value common.BigInt) {
+	if err := h.metrics.Add(metric, value); err != nil {
+		h.log.Warn("failed to record the host metric", "metric", metric, "err", err)
+	}
+}
+
+// metricsHistory returns the hourly aggregates of the host metrics in the last days
+func (h *StorageHost) metricsHistory(days int) ([]timeseries.Point, error) {
+	if days <= 0 || days > storage.MetricsRetentionDays {
+		return nil, fmt.Errorf("days must be within [1, %v]", storage.MetricsRetentionDays)
+	}
+	return h.metrics.Query(time.Now().Add(-time.Duration(days) * 24 * time.Hour))
+}
@@ -0,0 +1,395 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// the kinds of the negotiations accounted by the negotiation budget
+const (
+	negotiationCreate   = "create"
+	negotiationUpload   = "upload"
+	negotiationDownload = "download"
+)
+
+var (
+	// errNegotiationBusy is returned if the negotiation waited too long for the memory or the
+	// bandwidth used by the other negotiations
+	errNegotiationBusy = errors.New("host is busy with the other negotiations")
+
+	// errNegotiationClosed is returned if the host is closed while the negotiation waits
+	errNegotiationClosed = errors.New("host is closed while the negotiation waits")
+)
+
+type (
+	// NegotiationUsage is the memory buffered and the bandwidth consumed by the negotiations
+	// in progress, along with the limits
+	NegotiationUsage struct {
+		Active                    int                `json:"active"`
+		Buffered                  uint64             `json:"buffered"`
+		MemoryLimit               uint64             `json:"memoryLimit"`
+		NegotiationMemoryLimit    uint64             `json:"negotiationMemoryLimit"`
+		Bandwidth                 uint64             `json:"bandwidth"`
+		BandwidthRate             uint64             `json:"bandwidthRate"`
+		NegotiationBandwidthLimit uint64             `json:"negotiationBandwidthLimit"`
+		Waiting                   int                `json:"waiting"`
+		Throttled                 uint64             `json:"throttled"`
+		Rejected                  uint64             `json:"rejected"`
+		Negotiations              []NegotiationStats `json:"negotiations"`
+	}
+
+	// NegotiationStats is the memory buffered and the bandwidth consumed by a negotiation
+	NegotiationStats struct {
+		Kind      string    `json:"kind"`
+		ClientID  string    `json:"clientID"`
+		Started   time.Time `json:"started"`
+		Buffered  uint64    `json:"buffered"`
+		Bandwidth uint64    `json:"bandwidth"`
+	}
+
+	// negotiationBudget accounts the bytes buffered and the bandwidth consumed by each
+	// negotiation. A negotiation exceeding the per negotiation limits is rejected, and a
+	// negotiation exceeding the global limits waits for the others. The requests of the
+	// storage clients are not read from the connections till the global memory has room
+	// for them, which keeps the clients waiting instead of disconnecting them
+	negotiationBudget struct {
+		lock sync.Mutex
+
+		memoryLimit               uint64
+		negotiationMemoryLimit    uint64
+		bandwidthRate             uint64
+		negotiationBandwidthLimit uint64
+		wait                      time.Duration
+
+		buffered  uint64
+		bandwidth uint64
+		waiting   int
+		throttled uint64
+		rejected  uint64
+
+		// the bandwidth consumed in the current second
+		windowStart time.Time
+		windowBytes uint64
+
+		// released is closed and replaced whenever the buffered memory is released
+		released chan struct{}
+
+		// quit is closed when the host is closed, which stops the waiting negotiations
+		quit      chan struct{}
+		closeOnce sync.Once
+
+		nextID uint64
+		active map[uint64]*negotiationTracker
+	}
+
+	// negotiationTracker is the memory buffered and the bandwidth consumed by a negotiation
+	negotiationTracker struct {
+		budget    *negotiationBudget
+		id        uint64
+		kind      string
+		clientID  string
+		started   time.Time
+		buffered  uint64
+		bandwidth uint64
+	}
+)
+
+// newNegotiationBudget creates the negotiation budget with the default limits
+func newNegotiationBudget() *negotiationBudget {
+	return &negotiationBudget{
+		memoryLimit:               globalNegotiationMemoryLimit,
+		negotiationMemoryLimit:    negotiationMemoryLimit(uint64(defaultMaxReviseBatchSize)),
+		bandwidthRate:             globalNegotiationBandwidthRate,
+		negotiationBandwidthLimit: negotiationBandwidthLimit,
+		wait:                      negotiationBudgetWait,
+		released:                  make(chan struct{}),
+		quit:                      make(chan struct{}),
+		active:                    make(map[uint64]*negotiationTracker),
+	}
+}
+
+// negotiationMemoryLimit returns the memory a negotiation could buffer, which is the max
+// revise batch size of the host plus the overhead of the revision and the message encoding
+func negotiationMemoryLimit(maxReviseBatchSize uint64) uint64 {
+	return maxReviseBatchSize + negotiationMessageOverhead
+}
+
+// setNegotiationMemoryLimit sets the memory the negotiations begun afterwards could buffer
+func (b *negotiationBudget) setNegotiationMemoryLimit(limit uint64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.negotiationMemoryLimit = limit
+}
+
+// close stops the negotiations waiting for the memory or the bandwidth
+func (b *negotiationBudget) close() {
+	b.closeOnce.Do(func() {
+		close(b.quit)
+	})
+}
+
+// waitRead waits till the global memory has room for a message of the negotiation memory
+// limit, and returns false if the budget or the quit channel is closed. The caller holds
+// reading the next request from the connection while waiting, so that the request is not
+// buffered by the host before the memory is available
+func (b *negotiationBudget) waitRead(quit <-chan struct{}) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var throttled bool
+	// the read is always allowed if nothing is buffered, so that the message larger than
+	// the global limit is not blocked forever
+	for b.buffered != 0 && b.buffered+b.negotiationMemoryLimit > b.memoryLimit {
+		if !throttled {
+			throttled = true
+			b.throttled++
+		}
+		b.waitLocked(b.released, time.Time{}, quit)
+		if b.isClosed(quit) {
+			return false
+		}
+	}
+	return true
+}
+
+// begin starts accounting a negotiation with the client
+func (b *negotiationBudget) begin(kind string, clientID string) *negotiationTracker {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.nextID++
+	t := &negotiationTracker{
+		budget:   b,
+		id:       b.nextID,
+		kind:     kind,
+		clientID: clientID,
+		started:  time.Now(),
+	}
+	b.active[t.id] = t
+	return t
+}
+
+// admit reserves the memory to be buffered and consumes the bandwidth of the negotiation.
+// It waits if the global limits are reached, and returns an error if the negotiation
+// exceeds its own limits or has waited too long
+func (t *negotiationTracker) admit(memory, bandwidth uint64) error {
+	deadline := time.Now().Add(t.budget.wait)
+	if err := t.reserve(memory, deadline); err != nil {
+		return err
+	}
+	return t.consume(bandwidth, deadline)
+}
+
+// admitReceived reserves the memory and consumes the bandwidth of the message read from the
+// connection. The read of the message has waited for the room in the global memory, and the
+// negotiation keeps waiting for the other negotiations instead of being rejected if the room
+// is taken by the messages read concurrently. It returns an error only if the negotiation
+// exceeds its own limits or the host is closed
+func (t *negotiationTracker) admitReceived(size uint64) error {
+	if err := t.reserve(size, time.Time{}); err != nil {
+		return err
+	}
+	return t.consume(size, time.Time{})
+}
+
+// reserve reserves the memory buffered by the negotiation, waiting for the memory released
+// by the other negotiations till the deadline. The zero deadline waits till the budget is
+// closed
+func (t *negotiationTracker) reserve(size uint64, deadline time.Time) error {
+	b := t.budget
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if t.buffered+size > b.negotiationMemoryLimit {
+		b.rejected++
+		return fmt.Errorf("the negotiation buffers %v bytes, exceeding the limit of %v bytes", t.buffered+size, b.negotiationMemoryLimit)
+	}
+	if b.buffered+size > b.memoryLimit {
+		b.throttled++
+	}
+	for b.buffered+size > b.memoryLimit {
+		if !b.waitLocked(b.released, deadline, nil) {
+			if b.isClosed(nil) {
+				return errNegotiationClosed
+			}
+			b.rejected++
+			return errNegotiationBusy
+		}
+	}
+	b.buffered += size
+	t.buffered += size
+	return nil
+}
+
+// consume consumes the bandwidth of the negotiation. The global bandwidth is limited per
+// second, and the negotiation exceeding the rate waits for the next second till the deadline.
+// The zero deadline waits till the budget is closed
+func (t *negotiationTracker) consume(size uint64, deadline time.Time) error {
+	b := t.budget
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if t.bandwidth+size > b.negotiationBandwidthLimit {
+		b.rejected++
+		return fmt.Errorf("the negotiation consumes %v bytes, exceeding the limit of %v bytes", t.bandwidth+size, b.negotiationBandwidthLimit)
+	}
+	var throttled bool
+	for {
+		now := time.Now()
+		if now.Sub(b.windowStart) >= time.Second {
+			b.windowStart, b.windowBytes = now, 0
+		}
+		// the first negotiation in the second is always admitted, so that the negotiation
+		// larger than the rate is not blocked forever
+		if b.windowBytes == 0 || b.windowBytes+size <= b.bandwidthRate {
+			break
+		}
+		if !throttled {
+			throttled = true
+			b.throttled++
+		}
+		next := b.windowStart.Add(time.Second)
+		if !deadline.IsZero() && next.After(deadline) {
+			b.rejected++
+			return errNegotiationBusy
+		}
+		b.waitLocked(nil, next, nil)
+		if b.isClosed(nil) {
+			return errNegotiationClosed
+		}
+	}
+	b.windowBytes += size
+	b.bandwidth += size
+	t.bandwidth += size
+	return nil
+}
+
+// waitLocked releases the lock and waits for the channel closed, the deadline, the quit
+// channel closed or the budget closed, and returns whether the channel is closed. The
+// channel could be nil to wait for the deadline only, and the zero deadline never expires.
+// Require: lock the budget by caller
+func (b *negotiationBudget) waitLocked(ch chan struct{}, deadline time.Time, quit <-chan struct{}) bool {
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return false
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	b.waiting++
+	b.lock.Unlock()
+	var closed bool
+	select {
+	case <-ch:
+		closed = true
+	case <-expired:
+	case <-quit:
+	case <-b.quit:
+	}
+	b.lock.Lock()
+	b.waiting--
+	return closed
+}
+
+// isClosed returns whether the budget or the quit channel is closed
+func (b *negotiationBudget) isClosed(quit <-chan struct{}) bool {
+	select {
+	case <-b.quit:
+		return true
+	case <-quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// done releases the memory buffered by the negotiation, and stops accounting it
+func (t *negotiationTracker) done() {
+	b := t.budget
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.active, t.id)
+	if t.buffered == 0 {
+		return
+	}
+	b.buffered -= t.buffered
+	t.buffered = 0
+	close(b.released)
+	b.released = make(chan struct{})
+}
+
+// usage returns the current usage of the negotiation budget. The negotiations buffering the
+// most memory are listed first
+func (b *negotiationBudget) usage() NegotiationUsage {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	usage := NegotiationUsage{
+		Active:                    len(b.active),
+		Buffered:                  b.buffered,
+		MemoryLimit:               b.memoryLimit,
+		NegotiationMemoryLimit:    b.negotiationMemoryLimit,
+		Bandwidth:                 b.bandwidth,
+		BandwidthRate:             b.bandwidthRate,
+		NegotiationBandwidthLimit: b.negotiationBandwidthLimit,
+		Waiting:                   b.waiting,
+		Throttled:                 b.throttled,
+		Rejected:                  b.rejected,
+	}
+	for _, t := range b.active {
+		usage.Negotiations = append(usage.Negotiations, NegotiationStats{
+			Kind:      t.kind,
+			ClientID:  t.clientID,
+			Started:   t.started,
+			Buffered:  t.buffered,
+			Bandwidth: t.bandwidth,
+		})
+	}
+	sort.Slice(usage.Negotiations, func(i, j int) bool {
+		if usage.Negotiations[i].Buffered != usage.Negotiations[j].Buffered {
+			return usage.Negotiations[i].Buffered > usage.Negotiations[j].Buffered
+		}
+		return usage.Negotiations[i].Started.Before(usage.Negotiations[j].Started)
+	})
+	return usage
+}
+
+// beginNegotiation starts accounting the negotiation with the client connected with the peer
+func (h *StorageHost) beginNegotiation(kind string, sp storage.Peer) *negotiationTracker {
+	var clientID string
+	if node := sp.PeerNode(); node != nil {
+		clientID = node.ID().String()
+	}
+	h.lock.RLock()
+	maxReviseBatchSize := h.config.MaxReviseBatchSize
+	h.lock.RUnlock()
+
+	h.negotiations.setNegotiationMemoryLimit(negotiationMemoryLimit(maxReviseBatchSize))
+	return h.negotiations.begin(kind, clientID)
+}
+
+// WaitNegotiationRead holds reading the next request of the storage client till the host has
+// the memory to buffer it, which pushes the backpressure to the client connection instead
+// of disconnecting it. It returns false if the host or the quit channel is closed
+func (h *StorageHost) WaitNegotiationRead(quit <-chan struct{}) bool {
+	h.lock.RLock()
+	maxReviseBatchSize := h.config.MaxReviseBatchSize
+	h.lock.RUnlock()
+
+	h.negotiations.setNegotiationMemoryLimit(negotiationMemoryLimit(maxReviseBatchSize))
+	return h.negotiations.waitRead(quit)
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestNegotiationBudget creates the negotiation budget with small limits
+func newTestNegotiationBudget() *negotiationBudget {
+	b := newNegotiationBudget()
+	b.memoryLimit = 100
+	b.negotiationMemoryLimit = 60
+	b.bandwidthRate = 100
+	b.negotiationBandwidthLimit = 150
+	b.wait = time.Second
+	return b
+}
+
+func TestNegotiationBudget_NegotiationLimits(t *testing.T) {
+	b := newTestNegotiationBudget()
+	tracker := b.begin(negotiationUpload, "client")
+	defer tracker.done()
+
+	if err := tracker.admit(61, 0); err == nil {
+		t.Errorf("the negotiation buffering more than the limit should be rejected")
+	}
+	if err := tracker.admit(0, 151); err == nil {
+		t.Errorf("the negotiation consuming more than the limit should be rejected")
+	}
+	if usage := b.usage(); usage.Rejected != 2 || usage.Buffered != 0 || usage.Bandwidth != 0 {
+		t.Errorf("unexpected usage after the rejections: %+v", usage)
+	}
+}
+
+func TestNegotiationBudget_MemoryBackpressure(t *testing.T) {
+	b := newTestNegotiationBudget()
+	first := b.begin(negotiationUpload, "client1")
+	if err := first.admit(60, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	second := b.begin(negotiationDownload, "client2")
+	admitted := make(chan error)
+	go func() {
+		admitted <- second.admit(60, 0)
+	}()
+
+	// the second negotiation waits for the memory buffered by the first one
+	for b.usage().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	usage := b.usage()
+	if usage.Active != 2 || usage.Buffered != 60 || usage.Throttled != 1 {
+		t.Fatalf("unexpected usage while waiting: %+v", usage)
+	}
+	if usage.Negotiations[0].ClientID != "client1" || usage.Negotiations[0].Buffered != 60 {
+		t.Errorf("the negotiation buffering the most should be listed first: %+v", usage.Negotiations)
+	}
+
+	first.done()
+	if err := <-admitted; err != nil {
+		t.Fatalf("the waiting negotiation should be admitted: %v", err)
+	}
+	second.done()
+	if usage = b.usage(); usage.Active != 0 || usage.Buffered != 0 {
+		t.Errorf("all memory should be released: %+v", usage)
+	}
+}
+
+func TestNegotiationBudget_Busy(t *testing.T) {
+	b := newTestNegotiationBudget()
+	b.wait = 10 * time.Millisecond
+	first := b.begin(negotiationUpload, "client1")
+	defer first.done()
+	if err := first.admit(60, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	second := b.begin(negotiationUpload, "client2")
+	defer second.done()
+	if err := second.admit(60, 0); err != errNegotiationBusy {
+		t.Errorf("expect the negotiation to be busy, got %v", err)
+	}
+}
+
+func TestNegotiationBudget_BandwidthRate(t *testing.T) {
+	b := newTestNegotiationBudget()
+	tracker := b.begin(negotiationDownload, "client")
+	defer tracker.done()
+
+	start := time.Now()
+	if err := tracker.admit(0, 80); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.admit(0, 40); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("the bandwidth exceeding the rate should wait for the next second, waited %v", elapsed)
+	}
+	if usage := b.usage(); usage.Bandwidth != 120 || usage.Throttled != 1 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestNegotiationBudget_AdmitReceived(t *testing.T) {
+	b := newTestNegotiationBudget()
+	b.wait = 10 * time.Millisecond
+	first := b.begin(negotiationUpload, "client1")
+	if err := first.admitReceived(60); err != nil {
+		t.Fatal(err)
+	}
+
+	// the message already read waits for the other negotiations instead of being rejected
+	second := b.begin(negotiationUpload, "client2")
+	defer second.done()
+	admitted := make(chan error)
+	go func() {
+		admitted <- second.admitReceived(60)
+	}()
+	for b.usage().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(2 * b.wait)
+	select {
+	case err := <-admitted:
+		t.Fatalf("the received message should keep waiting, got %v", err)
+	default:
+	}
+
+	first.done()
+	if err := <-admitted; err != nil {
+		t.Fatalf("the waiting message should be admitted: %v", err)
+	}
+	if usage := b.usage(); usage.Waiting != 0 || usage.Buffered != 60 || usage.Rejected != 0 {
+		t.Errorf("unexpected usage after the admission: %+v", usage)
+	}
+}
+
+func TestNegotiationBudget_WaitRead(t *testing.T) {
+	b := newTestNegotiationBudget()
+	if !b.waitRead(nil) {
+		t.Fatal("the read should not wait with the memory available")
+	}
+	tracker := b.begin(negotiationUpload, "client1")
+	if err := tracker.admitReceived(50); err != nil {
+		t.Fatal(err)
+	}
+
+	// the room left is less than the negotiation memory limit, the read waits
+	read := make(chan bool)
+	go func() {
+		read <- b.waitRead(nil)
+	}()
+	for b.usage().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	tracker.done()
+	if !<-read {
+		t.Fatal("the read should continue after the memory is released")
+	}
+
+	// the waiting read is stopped when the budget is closed
+	tracker = b.begin(negotiationUpload, "client2")
+	defer tracker.done()
+	if err := tracker.admitReceived(50); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		read <- b.waitRead(nil)
+	}()
+	for b.usage().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	b.close()
+	if <-read {
+		t.Error("the read should be stopped by the closed budget")
+	}
+	waiting := b.begin(negotiationUpload, "client3")
+	defer waiting.done()
+	if err := waiting.admitReceived(60); err != errNegotiationClosed {
+		t.Errorf("expect the negotiation to be closed, got %v", err)
+	}
+}
+
+func TestNegotiationBudget_MemoryLimitFromConfig(t *testing.T) {
+	b := newTestNegotiationBudget()
+	b.memoryLimit = 1 << 30
+	b.setNegotiationMemoryLimit(negotiationMemoryLimit(16 << 20))
+	tracker := b.begin(negotiationUpload, "client")
+	defer tracker.done()
+
+	if err := tracker.admitReceived(16<<20 + negotiationMessageOverhead + 1); err == nil {
+		t.Errorf("the message exceeding the max revise batch size should be rejected")
+	}
+	if err := tracker.admit(16<<20, 0); err != nil {
+		t.Errorf("the message within the max revise batch size should be admitted: %v", err)
+	}
+	if usage := b.usage(); usage.NegotiationMemoryLimit != 16<<20+negotiationMessageOverhead {
+		t.Errorf("unexpected negotiation memory limit: %v", usage.NegotiationMemoryLimit)
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"fmt"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/ethdb"
+	"github.com/DxChainNetwork/godx/rlp"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// the kinds of the negotiation sessions
+const (
+	sessionUpload = "upload"
+	sessionCreate = "create"
+)
+
+// negotiationSession is the snapshot of a negotiation persisted when the storage client has
+// committed, and before the storage host finalizes the session. If the host crashes before
+// the session is finalized, the snapshot is used to complete or roll back the session when
+// the host is started again.
+//
+// SectorsAdded is updated after the sectors are written to the storage manager. If the host
+// crashed right after a sector is written but before the progress is persisted, the sector
+// is kept in the storage manager, which leaks the space instead of deleting a sector that
+// might be shared with other contracts.
+type negotiationSession struct {
+	Kind         string
+	ContractID   common.Hash
+	SectorsAdded uint64
+
+	// upload sessions. The sector data is not included, so an upload session could only be
+	// completed if all gained sectors have been written. The swap and trim actions are
+	// applied to the sector roots again when the session is completed
+	OldRevisionNumber uint64
+	Revision          types.StorageContractRevision
+	SectorsGained     []common.Hash
+	RemoveActions     []storage.UploadAction
+	SectorsRemoved    []common.Hash
+	StorageRevenue    common.BigInt
+	Deposit           common.BigInt
+	ReleasedDeposit   common.BigInt
+	BandwidthRevenue  common.BigInt
+
+	// create sessions. The sector roots of the renewed contract are loaded from the old
+	// storage responsibility instead of being included
+	Responsibility StorageResponsibility
+	Renew          bool
+	OldContractID  common.Hash
+}
+
+// newUploadSession creates the snapshot of the upload session, which appends the gained sectors,
+// removes the sectors with the remove actions, and adds the revenues to the old storage
+// responsibility with the revision
+func newUploadSession(old StorageResponsibility, revision types.StorageContractRevision, sectorsGained []common.Hash, removeActions []storage.UploadAction, sectorsRemoved []common.Hash,
+	storageRevenue, deposit, releasedDeposit, bandwidthRevenue common.BigInt) *negotiationSession {
+	return &negotiationSession{
+		Kind:              sessionUpload,
+		ContractID:        old.id(),
+		OldRevisionNumber: old.StorageContractRevisions[len(old.StorageContractRevisions)-1].NewRevisionNumber,
+		Revision:          revision,
+		SectorsGained:     sectorsGained,
+		RemoveActions:     removeActions,
+		SectorsRemoved:    sectorsRemoved,
+		StorageRevenue:    storageRevenue,
+		Deposit:           deposit,
+		ReleasedDeposit:   releasedDeposit,
+		BandwidthRevenue:  bandwidthRevenue,
+	}
+}
+
+// newCreateSession creates the snapshot of the contract create session
+func newCreateSession(so StorageResponsibility, renew bool, oldContractID common.Hash) *negotiationSession {
+	so.SectorRoots = nil
+	return &negotiationSession{
+		Kind:           sessionCreate,
+		ContractID:     so.id(),
+		Responsibility: so,
+		Renew:          renew,
+		OldContractID:  oldContractID,
+	}
+}
+
+// beginSession persists the snapshot of the session before it is finalized
+func (h *StorageHost) beginSession(session *negotiationSession) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if err := putNegotiationSession(h.db, session); err != nil {
+		return err
+	}
+	h.sessions[session.ContractID] = session
+	return nil
+}
+
+// endSession removes the snapshot of the session after it is finalized or rolled back
+func (h *StorageHost) endSession(id common.Hash) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.sessions, id)
+	if err := deleteNegotiationSession(h.db, id); err != nil {
+		h.log.Warn("failed to delete the negotiation session", "id", id, "err", err)
+	}
+}
+
+// recordSessionProgress persists the number of sectors written for the session of the
+// storage responsibility, if there is any.
+// Require: lock the storageHost by caller
+func (h *StorageHost) recordSessionProgress(id common.Hash, sectorsAdded uint64) {
+	session, exists := h.sessions[id]
+	if !exists {
+		return
+	}
+	session.SectorsAdded = sectorsAdded
+	if err := putNegotiationSession(h.db, session); err != nil {
+		h.log.Warn("failed to record the progress of the negotiation session", "id", id, "err", err)
+	}
+}
+
+// recoverSessions completes or rolls back the sessions left unfinished when the host was
+// stopped
+func (h *StorageHost) recoverSessions() error {
+	sessions, err := getNegotiationSessions(h.db)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		var completed bool
+		switch session.Kind {
+		case sessionUpload:
+			completed, err = h.recoverUploadSession(session)
+		case sessionCreate:
+			completed, err = h.recoverCreateSession(session)
+		default:
+			err = fmt.Errorf("unknown session kind %s", session.Kind)
+		}
+		if err != nil {
+			alert.Raise(h.log, alert.SeverityWarning, alertModule, "the unfinished negotiation session is rolled back", "id", session.ContractID, "kind", session.Kind, "err", err)
+		} else {
+			h.log.Info("recovered the unfinished negotiation session", "id", session.ContractID, "kind", session.Kind, "completed", completed)
+		}
+		if err = deleteNegotiationSession(h.db, session.ContractID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recoverUploadSession completes the upload session if all gained sectors have been written,
+// otherwise rolls back the sectors written. If the storage responsibility has already been
+// updated, the session is regarded as completed. The sectors removed by the completed session
+// are queued for deletion, which is done after the session is finalized otherwise
+func (h *StorageHost) recoverUploadSession(session *negotiationSession) (bool, error) {
+	written := session.SectorsGained[:session.SectorsAdded]
+	so, err := getStorageResponsibility(h.db, session.ContractID)
+	if err != nil {
+		return false, common.ErrCompose(err, h.deleteSessionSectors(written))
+	}
+	latest := so.StorageContractRevisions[len(so.StorageContractRevisions)-1].NewRevisionNumber
+	if latest >= session.Revision.NewRevisionNumber {
+		// the storage responsibility is updated, but the removed sectors are not queued
+		return true, h.queueRemovedSectors(so.id(), session.SectorsRemoved)
+	}
+	if latest != session.OldRevisionNumber {
+		return false, common.ErrCompose(fmt.Errorf("revision number %v not expected, expect %v", latest, session.OldRevisionNumber), h.deleteSessionSectors(written))
+	}
+	if len(written) != len(session.SectorsGained) {
+		return false, common.ErrCompose(fmt.Errorf("%v of %v sectors written", len(written), len(session.SectorsGained)), h.deleteSessionSectors(written))
+	}
+
+	roots := append(append([]common.Hash(nil), so.SectorRoots...), session.SectorsGained...)
+	roots, _, err = removeSectors(roots, session.RemoveActions, make(map[uint64]struct{}))
+	if err != nil {
+		return false, common.ErrCompose(err, h.deleteSessionSectors(written))
+	}
+	if uint64(len(roots))*storage.SectorSize != session.Revision.NewFileSize {
+		return false, common.ErrCompose(fmt.Errorf("%v sector roots do not match the file size %v", len(roots), session.Revision.NewFileSize), h.deleteSessionSectors(written))
+	}
+	so.SectorRoots = roots
+	so.PotentialStorageRevenue = so.PotentialStorageRevenue.Add(session.StorageRevenue)
+	so.RiskedStorageDeposit = so.RiskedStorageDeposit.Add(session.Deposit).Sub(session.ReleasedDeposit)
+	so.PotentialUploadRevenue = so.PotentialUploadRevenue.Add(session.BandwidthRevenue)
+	so.StorageContractRevisions = append(so.StorageContractRevisions, session.Revision)
+	if err = h.modifyStorageResponsibility(so, nil, nil, nil); err != nil {
+		return false, common.ErrCompose(err, h.deleteSessionSectors(written))
+	}
+	h.sectorRoots.remove(so.id())
+	return true, h.queueRemovedSectors(so.id(), session.SectorsRemoved)
+}
+
+// queueRemovedSectors queues the sectors removed by the recovered upload session for deletion
+func (h *StorageHost) queueRemovedSectors(id common.Hash, removed []common.Hash) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if err := h.queueSectorDeletion(removed); err != nil {
+		return fmt.Errorf("failed to queue the removed sectors of %v for deletion: %s", id, err.Error())
+	}
+	return nil
+}
+
+// recoverCreateSession inserts the storage responsibility of the contract create session if
+// it has not been inserted. The sectors of the renewed contract added before the crash are
+// released first, since they are added again by the insertion.
+func (h *StorageHost) recoverCreateSession(session *negotiationSession) (bool, error) {
+	so := session.Responsibility
+	if session.Renew {
+		if oldSo, err := getStorageResponsibility(h.db, session.OldContractID); err == nil {
+			so.SectorRoots = oldSo.SectorRoots
+		}
+	}
+
+	if _, err := getStorageResponsibility(h.db, session.ContractID); err == nil {
+		// the storage responsibility is stored, but the tasks might not be queued
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		return true, h.queueStorageResponsibilityTasks(so)
+	}
+
+	if session.SectorsAdded != 0 {
+		if err := h.DeleteSectorBatch(so.SectorRoots); err != nil {
+			return false, err
+		}
+	}
+	if err := h.insertStorageResponsibility(so); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// deleteSessionSectors deletes the sectors written by the unfinished session
+func (h *StorageHost) deleteSessionSectors(roots []common.Hash) error {
+	if len(roots) == 0 {
+		return nil
+	}
+	return h.DeleteSectorBatch(roots)
+}
+
+// putNegotiationSession store the negotiation session snapshot to DB
+func putNegotiationSession(db ethdb.Database, session *negotiationSession) error {
+	scdb := ethdb.StorageContractDB{db}
+	data, err := rlp.EncodeToBytes(session)
+	if err != nil {
+		return err
+	}
+	return scdb.StoreWithPrefix(session.ContractID, data, prefixNegotiationSession)
+}
+
+// deleteNegotiationSession delete the negotiation session snapshot from DB
+func deleteNegotiationSession(db ethdb.Database, id common.Hash) error {
+	scdb := ethdb.StorageContractDB{db}
+	return scdb.DeleteWithPrefix(id, prefixNegotiationSession)
+}
+
+// getNegotiationSessions get all negotiation session snapshots from DB
+func getNegotiationSessions(db *ethdb.LDBDatabase) ([]*negotiationSession, error) {
+	var sessions []*negotiationSession
+
+	iter := db.NewIteratorWithPrefix([]byte(prefixNegotiationSession))
+	defer iter.Release()
+	for iter.Next() {
+		session := new(negotiationSession)
+		if err := rlp.DecodeBytes(iter.Value(), session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, iter.Error()
+}
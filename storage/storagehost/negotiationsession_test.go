@@ -0,0 +1,194 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"crypto/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// newTestSessionResponsibility creates a storage responsibility with the first revision
+func newTestSessionResponsibility(windowStart uint64) StorageResponsibility {
+	sc := types.StorageContract{
+		WindowStart:    windowStart,
+		RevisionNumber: 1,
+		WindowEnd:      windowStart + 1000,
+	}
+	return StorageResponsibility{
+		ContractCost:             common.BigInt0,
+		LockedStorageDeposit:     common.BigInt0,
+		PotentialDownloadRevenue: common.BigInt0,
+		PotentialStorageRevenue:  common.BigInt0,
+		PotentialUploadRevenue:   common.BigInt0,
+		RiskedStorageDeposit:     common.BigInt0,
+		TransactionFeeExpenses:   common.BigInt0,
+		OriginStorageContract:    sc,
+		StorageContractRevisions: []types.StorageContractRevision{{
+			ParentID:          sc.RLPHash(),
+			NewRevisionNumber: 1,
+			NewWindowStart:    sc.WindowStart,
+			NewWindowEnd:      sc.WindowEnd,
+		}},
+	}
+}
+
+// newTestUploadSession stores the storage responsibility, and writes the first sectorsAdded of
+// the sectors gained in the upload session
+func newTestUploadSession(t *testing.T, h *StorageHost, so StorageResponsibility, sectorsGained, sectorsAdded int) *negotiationSession {
+	if err := putStorageResponsibility(h.db, so.id(), so); err != nil {
+		t.Fatal(err)
+	}
+	var roots []common.Hash
+	for i := 0; i != sectorsGained; i++ {
+		data := make([]byte, storage.SectorSize)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatal(err)
+		}
+		root := merkle.Sha256MerkleTreeRoot(data)
+		if i < sectorsAdded {
+			if err := h.AddSector(root, data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		roots = append(roots, root)
+	}
+	revision := so.StorageContractRevisions[0]
+	revision.NewRevisionNumber++
+	revision.NewFileSize = uint64(sectorsGained) * storage.SectorSize
+	session := newUploadSession(so, revision, roots, nil, nil, common.NewBigInt(10), common.NewBigInt(20), common.NewBigInt(0), common.NewBigInt(30))
+	session.SectorsAdded = uint64(sectorsAdded)
+	if err := putNegotiationSession(h.db, session); err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func TestStorageHost_RecoverSessions(t *testing.T) {
+	h := newTestStorageHost(t)
+	if err := h.StorageManager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		h.StorageManager.Close()
+		h.db.Close()
+	}()
+	if err := h.AddStorageFolder(filepath.Join(h.persistDir, "folder"), 1<<25); err != nil {
+		t.Fatal(err)
+	}
+	h.blockHeight = 10
+
+	// all sectors are written, the upload session is completed
+	completedSo := newTestSessionResponsibility(10000)
+	completed := newTestUploadSession(t, h, completedSo, 2, 2)
+
+	// one of the sectors is not written, the upload session is rolled back
+	rolledBackSo := newTestSessionResponsibility(20000)
+	rolledBack := newTestUploadSession(t, h, rolledBackSo, 2, 1)
+
+	// the storage responsibility of the contract create session is not inserted
+	createdSo := newTestSessionResponsibility(30000)
+	if err := putNegotiationSession(h.db, newCreateSession(createdSo, false, common.Hash{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.recoverSessions(); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, err := getNegotiationSessions(h.db); err != nil || len(sessions) != 0 {
+		t.Fatalf("the sessions should be removed after the recovery: %v, %v", len(sessions), err)
+	}
+
+	so, err := getStorageResponsibility(h.db, completedSo.id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(so.SectorRoots) != 2 || len(so.StorageContractRevisions) != 2 || so.StorageContractRevisions[1].NewRevisionNumber != completed.Revision.NewRevisionNumber {
+		t.Errorf("the upload session is not completed: %v roots, %v revisions", len(so.SectorRoots), len(so.StorageContractRevisions))
+	}
+	if so.PotentialStorageRevenue.Cmp(common.NewBigInt(10)) != 0 || so.RiskedStorageDeposit.Cmp(common.NewBigInt(20)) != 0 || so.PotentialUploadRevenue.Cmp(common.NewBigInt(30)) != 0 {
+		t.Errorf("the revenues of the upload session are not applied: %+v", so)
+	}
+
+	if so, err = getStorageResponsibility(h.db, rolledBackSo.id()); err != nil {
+		t.Fatal(err)
+	}
+	if len(so.SectorRoots) != 0 || len(so.StorageContractRevisions) != 1 {
+		t.Errorf("the upload session is not rolled back: %v roots, %v revisions", len(so.SectorRoots), len(so.StorageContractRevisions))
+	}
+	if _, err = h.ReadSector(rolledBack.SectorsGained[0]); err == nil {
+		t.Errorf("the sector written by the rolled back session should be deleted")
+	}
+
+	if _, err = getStorageResponsibility(h.db, createdSo.id()); err != nil {
+		t.Fatalf("the contract create session is not completed: %v", err)
+	}
+	items, err := getHeight(h.db, createdSo.expiration()+postponedExecution)
+	if err != nil || len(items) != common.HashLength || common.BytesToHash(items) != createdSo.id() {
+		t.Errorf("the proof task of the created storage responsibility is not queued: %v", err)
+	}
+
+	// the completed session is not applied twice
+	if err = putNegotiationSession(h.db, completed); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.recoverSessions(); err != nil {
+		t.Fatal(err)
+	}
+	if so, err = getStorageResponsibility(h.db, completedSo.id()); err != nil || len(so.SectorRoots) != 2 {
+		t.Errorf("the completed session should not be applied again: %v", err)
+	}
+}
+
+func TestStorageHost_RecoverRemoveSession(t *testing.T) {
+	h := newTestStorageHost(t)
+	defer h.db.Close()
+	h.config.DeletionRetention = 10
+	h.blockHeight = 10
+
+	so := newTestSessionResponsibility(10000)
+	so.SectorRoots = randomRoots(3)
+	if err := putStorageResponsibility(h.db, so.id(), so); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first sector is swapped to the end and trimmed
+	actions := []storage.UploadAction{
+		{Type: storage.UploadActionSwap, A: 0, B: 2},
+		{Type: storage.UploadActionTrim, A: 1},
+	}
+	expect := []common.Hash{so.SectorRoots[2], so.SectorRoots[1]}
+	revision := so.StorageContractRevisions[0]
+	revision.NewRevisionNumber++
+	revision.NewFileSize = uint64(len(expect)) * storage.SectorSize
+	session := newUploadSession(so, revision, nil, actions, so.SectorRoots[:1], common.NewBigInt(0), common.NewBigInt(0), common.NewBigInt(0), common.NewBigInt(10))
+	if err := putNegotiationSession(h.db, session); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.recoverSessions(); err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := getStorageResponsibility(h.db, so.id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(recovered.SectorRoots, expect) || len(recovered.StorageContractRevisions) != 2 {
+		t.Errorf("the removal is not applied by the recovered session: %v roots, %v revisions", len(recovered.SectorRoots), len(recovered.StorageContractRevisions))
+	}
+	deletions, err := getDelayedDeletions(h.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roots := deletions[h.blockHeight+h.config.DeletionRetention]; !reflect.DeepEqual(roots, so.SectorRoots[:1]) {
+		t.Errorf("the removed sector is not queued for deletion: %v", roots)
+	}
+}
@@ -16,6 +16,8 @@ type persistence struct {
 	FinancialMetrics HostFinancialMetrics   `json:"financialmetrics"`
 	Config           storage.HostIntConfig  `json:"config"`
 	Contracts        map[string]common.Hash `json:"contracts"`
+	Retiring         bool                   `json:"retiring"`
+	RetireHeight     uint64                 `json:"retireHeight"`
 }
 
 // save the host config: the filed as persistence shown, to the json file
@@ -49,6 +51,8 @@ func (h *StorageHost) extractPersistence() *persistence {
 		FinancialMetrics: h.financialMetrics,
 		Config:           h.config,
 		Contracts:        h.clientToContract,
+		Retiring:         h.retiring,
+		RetireHeight:     h.retireHeight,
 	}
 }
 
@@ -59,4 +63,6 @@ func (h *StorageHost) loadPersistence(persist *persistence) {
 	h.financialMetrics = persist.FinancialMetrics
 	h.config = persist.Config
 	h.clientToContract = persist.Contracts
+	h.retiring = persist.Retiring
+	h.retireHeight = persist.RetireHeight
 }
@@ -0,0 +1,79 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"fmt"
+
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// schedulePrice schedules the price change, which will be published along with the external
+// config so that the clients could plan the renewals ahead. The scheduled price with the
+// same name and effective height will be replaced
+func (h *StorageHost) schedulePrice(sp storage.HostScheduledPrice) error {
+	if err := storage.ValidateScheduledPrice(sp); err != nil {
+		return err
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if sp.EffectiveHeight <= h.blockHeight {
+		return fmt.Errorf("the effective height %v must be larger than the current block height %v", sp.EffectiveHeight, h.blockHeight)
+	}
+
+	var replaced bool
+	prices := make([]storage.HostScheduledPrice, 0, len(h.config.ScheduledPrices)+1)
+	for _, existing := range h.config.ScheduledPrices {
+		if existing.Name == sp.Name && existing.EffectiveHeight == sp.EffectiveHeight {
+			existing, replaced = sp, true
+		}
+		prices = append(prices, existing)
+	}
+	if !replaced {
+		if len(prices) >= maxScheduledPrices {
+			return fmt.Errorf("cannot schedule more than %v price changes", maxScheduledPrices)
+		}
+		prices = append(prices, sp)
+	}
+	storage.SortScheduledPrices(prices)
+
+	h.config.ScheduledPrices = prices
+	return h.syncConfig()
+}
+
+// cancelScheduledPrice removes the scheduled price with the name and effective height
+func (h *StorageHost) cancelScheduledPrice(name string, height uint64) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var found bool
+	var prices []storage.HostScheduledPrice
+	for _, existing := range h.config.ScheduledPrices {
+		if existing.Name == name && existing.EffectiveHeight == height {
+			found = true
+			continue
+		}
+		prices = append(prices, existing)
+	}
+	if !found {
+		return fmt.Errorf("no %v change is scheduled at block %v", name, height)
+	}
+
+	h.config.ScheduledPrices = prices
+	return h.syncConfig()
+}
+
+// applyScheduledPrices changes the prices whose effective height has been reached.
+// The config is saved by the caller
+func (h *StorageHost) applyScheduledPrices() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, sp := range h.config.ApplyScheduledPrices(h.blockHeight) {
+		h.log.Info("Scheduled price applied", "name", sp.Name, "price", sp.Price, "height", sp.EffectiveHeight)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestStorageHost_SchedulePrice(t *testing.T) {
+	h := newTestStorageHost(t)
+	defer h.db.Close()
+
+	h.blockHeight = 10
+	h.config.StoragePrice = common.NewBigIntUint64(1)
+	h.config.ContractPrice = common.NewBigIntUint64(100)
+
+	tests := []struct {
+		sp  storage.HostScheduledPrice
+		err bool
+	}{
+		{storage.HostScheduledPrice{Name: "storagePrice", Price: common.NewBigIntUint64(3), EffectiveHeight: 30}, false},
+		{storage.HostScheduledPrice{Name: "storagePrice", Price: common.NewBigIntUint64(2), EffectiveHeight: 20}, false},
+		{storage.HostScheduledPrice{Name: "contractPrice", Price: common.NewBigIntUint64(200), EffectiveHeight: 20}, false},
+		// replace the existing scheduled price
+		{storage.HostScheduledPrice{Name: "contractPrice", Price: common.NewBigIntUint64(300), EffectiveHeight: 20}, false},
+		{storage.HostScheduledPrice{Name: "maxDeposit", Price: common.NewBigIntUint64(1), EffectiveHeight: 20}, true},
+		{storage.HostScheduledPrice{Name: "storagePrice", Price: common.NewBigIntUint64(1), EffectiveHeight: 10}, true},
+	}
+	for i, test := range tests {
+		err := h.schedulePrice(test.sp)
+		if (err != nil) != test.err {
+			t.Fatalf("test %v: expect error %v, got %v", i, test.err, err)
+		}
+	}
+
+	// the scheduled prices should be published in the external config sorted by the height
+	scheduled := h.config.ScheduledPrices
+	if len(scheduled) != 3 {
+		t.Fatalf("expect 3 scheduled prices, got %v", len(scheduled))
+	}
+	for i := 1; i < len(scheduled); i++ {
+		if scheduled[i-1].EffectiveHeight > scheduled[i].EffectiveHeight {
+			t.Fatalf("scheduled prices not sorted: %v", scheduled)
+		}
+	}
+
+	if err := h.cancelScheduledPrice("storagePrice", 30); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.cancelScheduledPrice("storagePrice", 30); err == nil {
+		t.Fatalf("canceling a non-existing scheduled price should return an error")
+	}
+
+	// before the effective height, the prices should not change
+	h.blockHeight = 19
+	h.applyScheduledPrices()
+	if h.config.StoragePrice.Cmp(common.NewBigIntUint64(1)) != 0 || len(h.config.ScheduledPrices) != 2 {
+		t.Fatalf("prices should not change before the effective height")
+	}
+
+	// once the effective height reached, the prices should be changed
+	h.blockHeight = 20
+	h.applyScheduledPrices()
+	if h.config.StoragePrice.Cmp(common.NewBigIntUint64(2)) != 0 {
+		t.Errorf("storage price not changed. Got %v, expect %v", h.config.StoragePrice, 2)
+	}
+	if h.config.ContractPrice.Cmp(common.NewBigIntUint64(300)) != 0 {
+		t.Errorf("contract price not changed. Got %v, expect %v", h.config.ContractPrice, 300)
+	}
+	if len(h.config.ScheduledPrices) != 0 {
+		t.Errorf("applied prices should be removed from the schedule, got %v", h.config.ScheduledPrices)
+	}
+}
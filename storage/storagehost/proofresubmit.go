@@ -0,0 +1,69 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"fmt"
+
+	"github.com/DxChainNetwork/godx/common"
+)
+
+// resubmitStorageProof constructs and sends the storage proof of the storage responsibility
+// again. It is the manual recovery for the proof whose previous submission failed, e.g. the
+// sector could not be read or the transaction was dropped.
+func (h *StorageHost) resubmitStorageProof(id common.Hash) (common.Hash, error) {
+	h.checkAndLockStorageResponsibility(id)
+	defer h.checkAndUnlockStorageResponsibility(id)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	so, err := getStorageResponsibility(h.db, id)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("cannot get the storage responsibility: %v", err)
+	}
+	if err = h.checkProofResubmission(so); err != nil {
+		return common.Hash{}, err
+	}
+	txHash, err := h.submitStorageProof(so)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	h.log.Info("storage proof resubmitted", "id", id.String(), "tx", txHash.String(), "height", h.blockHeight)
+	// check the proof again at the deadline
+	if err = h.queueTaskItem(so.proofDeadline(), id); err != nil {
+		h.log.Warn("Error queuing task item", "err", err)
+	}
+	return txHash, nil
+}
+
+// checkProofResubmission checks whether the storage proof of the storage responsibility could
+// be resubmitted at the current block height. The proof window must have been open long enough
+// that the automatic submission is done, and must not be too close to the deadline. The proof
+// transaction sent recently is given some blocks to be confirmed.
+func (h *StorageHost) checkProofResubmission(so StorageResponsibility) error {
+	if so.ResponsibilityStatus != responsibilityUnresolved {
+		return fmt.Errorf("storage responsibility already resolved")
+	}
+	if !so.CreateContractConfirmed {
+		return fmt.Errorf("storage contract not confirmed yet")
+	}
+	if so.StorageProofConfirmed {
+		return fmt.Errorf("storage proof already confirmed")
+	}
+	if len(so.SectorRoots) == 0 || len(so.StorageContractRevisions) == 0 {
+		return fmt.Errorf("no data stored, storage proof not needed")
+	}
+	if submitAt := so.expiration() + postponedExecution; h.blockHeight < submitAt {
+		return fmt.Errorf("proof window not open, the storage proof is submitted automatically at block %v", submitAt)
+	}
+	if h.blockHeight+proofResubmitDeadlineBuffer > so.proofDeadline() {
+		return fmt.Errorf("too close to the proof deadline %v at block %v", so.proofDeadline(), h.blockHeight)
+	}
+	if last, exist := h.proofSubmissions[so.id()]; exist && h.blockHeight < last+proofResubmitInterval {
+		return fmt.Errorf("storage proof sent at block %v might be pending, retry after block %v", last, last+proofResubmitInterval)
+	}
+	return nil
+}
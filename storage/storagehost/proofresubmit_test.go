@@ -0,0 +1,72 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+)
+
+func TestStorageHost_CheckProofResubmission(t *testing.T) {
+	h := newTestStorageHost(t)
+	defer h.db.Close()
+
+	newSo := func() StorageResponsibility {
+		return StorageResponsibility{
+			SectorRoots:             []common.Hash{{1}},
+			CreateContractConfirmed: true,
+			StorageContractRevisions: []types.StorageContractRevision{{
+				NewWindowStart: 100,
+				NewWindowEnd:   200,
+			}},
+		}
+	}
+	tests := []struct {
+		name      string
+		height    uint64
+		modify    func(so *StorageResponsibility)
+		submitted bool
+		ok        bool
+	}{
+		{"in window", 150, nil, false, true},
+		{"resolved", 150, func(so *StorageResponsibility) { so.ResponsibilityStatus = responsibilityFailed }, false, false},
+		{"contract not confirmed", 150, func(so *StorageResponsibility) { so.CreateContractConfirmed = false }, false, false},
+		{"proof confirmed", 150, func(so *StorageResponsibility) { so.StorageProofConfirmed = true }, false, false},
+		{"no data", 150, func(so *StorageResponsibility) { so.SectorRoots = nil }, false, false},
+		{"before automatic submission", 100 + postponedExecution - 1, nil, false, false},
+		{"automatic submission", 100 + postponedExecution, nil, false, true},
+		{"close to deadline", 200 - proofResubmitDeadlineBuffer + 1, nil, false, false},
+		{"recently submitted", 150, nil, true, false},
+	}
+	for _, test := range tests {
+		so := newSo()
+		if test.modify != nil {
+			test.modify(&so)
+		}
+		h.blockHeight = test.height
+		delete(h.proofSubmissions, so.id())
+		if test.submitted {
+			h.proofSubmissions[so.id()] = test.height - 1
+		}
+		if err := h.checkProofResubmission(so); (err == nil) != test.ok {
+			t.Errorf("%v: expect ok %v, got error %v", test.name, test.ok, err)
+		}
+	}
+
+	// the submission long ago is not pending
+	so := newSo()
+	h.blockHeight = 150
+	h.proofSubmissions[so.id()] = 150 - proofResubmitInterval
+	if err := h.checkProofResubmission(so); err != nil {
+		t.Errorf("the proof submitted long ago should be resubmitted: %v", err)
+	}
+
+	// unknown storage responsibility
+	if _, err := h.resubmitStorageProof(common.Hash{2}); err == nil {
+		t.Errorf("resubmitting the proof of an unknown contract should fail")
+	}
+}
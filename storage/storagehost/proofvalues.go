@@ -0,0 +1,89 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"math/big"
+
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// validateContractCreateRequest validates the proof outputs and the collaterals of the
+// storage contract in the request, which are accessed by index and dereferenced during
+// the contract create negotiation
+func validateContractCreateRequest(req storage.ContractCreateRequest) error {
+	sc := req.StorageContract
+	if err := validateProofOutputs(sc.ValidProofOutputs, sc.MissedProofOutputs); err != nil {
+		return err
+	}
+	if !validValue(sc.ClientCollateral.Value) || !validValue(sc.HostCollateral.Value) {
+		return errInvalidCollateral
+	}
+	return nil
+}
+
+// validateProofOutputs checks that there are two valid and missed proof outputs (client + host),
+// and each output has a non-negative value
+func validateProofOutputs(validOutputs, missedOutputs []types.DxcoinCharge) error {
+	if len(validOutputs) != 2 || len(missedOutputs) != 2 {
+		return errBadContractOutputCounts
+	}
+	for _, outputs := range [][]types.DxcoinCharge{validOutputs, missedOutputs} {
+		for _, output := range outputs {
+			if !validValue(output.Value) {
+				return errInvalidProofValue
+			}
+		}
+	}
+	return nil
+}
+
+// validateProofValues checks the new proof values in the upload or download request against
+// the current revision. There must be exactly one value for each proof output of the current
+// revision, and each value must be non-negative
+func validateProofValues(current types.StorageContractRevision, validValues, missedValues []*big.Int) error {
+	if err := validateProofOutputs(current.NewValidProofOutputs, current.NewMissedProofOutputs); err != nil {
+		return err
+	}
+	if len(validValues) != len(current.NewValidProofOutputs) || len(missedValues) != len(current.NewMissedProofOutputs) {
+		return errBadProofValueCounts
+	}
+	for _, values := range [][]*big.Int{validValues, missedValues} {
+		for _, value := range values {
+			if !validValue(value) {
+				return errInvalidProofValue
+			}
+		}
+	}
+	return nil
+}
+
+// revisionWithProofValues returns a copy of the current revision paying the new proof values
+// to the addresses of the current proof outputs. The values must have been validated with
+// validateProofValues
+func revisionWithProofValues(current types.StorageContractRevision, validValues, missedValues []*big.Int) types.StorageContractRevision {
+	revision := current
+	revision.NewValidProofOutputs = make([]types.DxcoinCharge, len(current.NewValidProofOutputs))
+	for i := range revision.NewValidProofOutputs {
+		revision.NewValidProofOutputs[i] = types.DxcoinCharge{
+			Value:   validValues[i],
+			Address: current.NewValidProofOutputs[i].Address,
+		}
+	}
+	revision.NewMissedProofOutputs = make([]types.DxcoinCharge, len(current.NewMissedProofOutputs))
+	for i := range revision.NewMissedProofOutputs {
+		revision.NewMissedProofOutputs[i] = types.DxcoinCharge{
+			Value:   missedValues[i],
+			Address: current.NewMissedProofOutputs[i].Address,
+		}
+	}
+	return revision
+}
+
+// validValue checks that the value is present and non-negative
+func validValue(value *big.Int) bool {
+	return value != nil && value.Sign() >= 0
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func testProofOutputs(values ...*big.Int) []types.DxcoinCharge {
+	outputs := make([]types.DxcoinCharge, len(values))
+	for i, value := range values {
+		outputs[i] = types.DxcoinCharge{Address: common.Address{byte(i + 1)}, Value: value}
+	}
+	return outputs
+}
+
+func TestValidateProofValues(t *testing.T) {
+	current := types.StorageContractRevision{
+		NewValidProofOutputs:  testProofOutputs(big.NewInt(100), big.NewInt(200)),
+		NewMissedProofOutputs: testProofOutputs(big.NewInt(100), big.NewInt(200)),
+	}
+	tests := []struct {
+		current       types.StorageContractRevision
+		valid, missed []*big.Int
+		err           error
+	}{
+		{current, []*big.Int{big.NewInt(90), big.NewInt(210)}, []*big.Int{big.NewInt(90), big.NewInt(200)}, nil},
+		{current, []*big.Int{big.NewInt(90)}, []*big.Int{big.NewInt(90), big.NewInt(200)}, errBadProofValueCounts},
+		{current, []*big.Int{big.NewInt(90), big.NewInt(210)}, nil, errBadProofValueCounts},
+		{current, []*big.Int{big.NewInt(90), big.NewInt(210), big.NewInt(1)}, []*big.Int{big.NewInt(90), big.NewInt(200)}, errBadProofValueCounts},
+		{current, []*big.Int{nil, big.NewInt(210)}, []*big.Int{big.NewInt(90), big.NewInt(200)}, errInvalidProofValue},
+		{current, []*big.Int{big.NewInt(90), big.NewInt(210)}, []*big.Int{big.NewInt(-1), big.NewInt(200)}, errInvalidProofValue},
+		{types.StorageContractRevision{}, []*big.Int{big.NewInt(90), big.NewInt(210)}, []*big.Int{big.NewInt(90), big.NewInt(200)}, errBadContractOutputCounts},
+	}
+	for i, test := range tests {
+		if err := validateProofValues(test.current, test.valid, test.missed); err != test.err {
+			t.Errorf("test %d: expect error %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestRevisionWithProofValues(t *testing.T) {
+	current := types.StorageContractRevision{
+		NewRevisionNumber:     1,
+		NewValidProofOutputs:  testProofOutputs(big.NewInt(100), big.NewInt(200)),
+		NewMissedProofOutputs: testProofOutputs(big.NewInt(100), big.NewInt(200)),
+	}
+	valid := []*big.Int{big.NewInt(90), big.NewInt(210)}
+	missed := []*big.Int{big.NewInt(80), big.NewInt(200)}
+	revision := revisionWithProofValues(current, valid, missed)
+
+	for i := range valid {
+		if revision.NewValidProofOutputs[i].Value.Cmp(valid[i]) != 0 || revision.NewValidProofOutputs[i].Address != current.NewValidProofOutputs[i].Address {
+			t.Errorf("unexpected valid proof output %d: %+v", i, revision.NewValidProofOutputs[i])
+		}
+		if revision.NewMissedProofOutputs[i].Value.Cmp(missed[i]) != 0 || revision.NewMissedProofOutputs[i].Address != current.NewMissedProofOutputs[i].Address {
+			t.Errorf("unexpected missed proof output %d: %+v", i, revision.NewMissedProofOutputs[i])
+		}
+	}
+	// the current revision should not be modified
+	if current.NewValidProofOutputs[0].Value.Cmp(big.NewInt(100)) != 0 {
+		t.Error("current revision modified")
+	}
+}
+
+func TestValidateContractCreateRequest(t *testing.T) {
+	newRequest := func() storage.ContractCreateRequest {
+		sc := types.StorageContract{
+			ValidProofOutputs:  testProofOutputs(big.NewInt(100), big.NewInt(200)),
+			MissedProofOutputs: testProofOutputs(big.NewInt(100), big.NewInt(200)),
+		}
+		sc.ClientCollateral.Value = big.NewInt(100)
+		sc.HostCollateral.Value = big.NewInt(200)
+		return storage.ContractCreateRequest{StorageContract: sc}
+	}
+
+	if err := validateContractCreateRequest(newRequest()); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+
+	req := newRequest()
+	req.StorageContract.ValidProofOutputs = req.StorageContract.ValidProofOutputs[:1]
+	if err := validateContractCreateRequest(req); err != errBadContractOutputCounts {
+		t.Errorf("expect error %v, got %v", errBadContractOutputCounts, err)
+	}
+
+	req = newRequest()
+	req.StorageContract.MissedProofOutputs[1].Value = nil
+	if err := validateContractCreateRequest(req); err != errInvalidProofValue {
+		t.Errorf("expect error %v, got %v", errInvalidProofValue, err)
+	}
+
+	req = newRequest()
+	req.StorageContract.HostCollateral.Value = nil
+	if err := validateContractCreateRequest(req); err != errInvalidCollateral {
+		t.Errorf("expect error %v, got %v", errInvalidCollateral, err)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// abuseKind is the kind of the negotiation abuse from the storage client
+type abuseKind int
+
+const (
+	// abuseMalformedMsg is the message from the client that cannot be decoded
+	abuseMalformedMsg abuseKind = iota
+	// abuseTimeout is the client not responding after the host transferred the data
+	abuseTimeout
+	// abuseCommitFailure is the client failed to commit after the negotiation
+	abuseCommitFailure
+)
+
+// abuseWeights is the score added to the client for each kind of abuse
+var abuseWeights = map[abuseKind]float64{
+	abuseMalformedMsg:  5,
+	abuseTimeout:       3,
+	abuseCommitFailure: 2,
+}
+
+// ClientReputation is the abuse report of a storage client
+type ClientReputation struct {
+	ClientID       string    `json:"clientID"`
+	Score          float64   `json:"score"`
+	MalformedMsgs  uint64    `json:"malformedMsgs"`
+	Timeouts       uint64    `json:"timeouts"`
+	CommitFailures uint64    `json:"commitFailures"`
+	Bans           int       `json:"bans"`
+	Deprioritized  bool      `json:"deprioritized"`
+	BannedUntil    time.Time `json:"bannedUntil,omitempty"`
+}
+
+// clientRecord is the abuse record of a storage client. The score decays exponentially
+// over time, so that the clients behaving well afterwards are forgiven
+type clientRecord struct {
+	score          float64
+	updated        time.Time
+	malformedMsgs  uint64
+	timeouts       uint64
+	commitFailures uint64
+	bans           int
+	bannedUntil    time.Time
+}
+
+// clientReputation keeps the abuse records of the storage clients. The client with the
+// score above reputationDeprioritizeScore has its requests handled after a delay, and
+// the client with the score above reputationBanScore is banned for a period, which is
+// doubled for each ban
+type clientReputation struct {
+	lock    sync.Mutex
+	records map[enode.ID]*clientRecord
+}
+
+// newClientReputation creates an empty clientReputation
+func newClientReputation() *clientReputation {
+	return &clientReputation{
+		records: make(map[enode.ID]*clientRecord),
+	}
+}
+
+// decay decays the score of the record to the time now
+func (r *clientRecord) decay(now time.Time) {
+	if elapsed := now.Sub(r.updated); elapsed > 0 {
+		r.score *= math.Pow(0.5, float64(elapsed)/float64(reputationHalfLife))
+	}
+	r.updated = now
+}
+
+// record records the abuse of the client, and bans the client if the score reaches the
+// ban score. Return whether the client is banned by the abuse
+func (cr *clientReputation) record(id enode.ID, kind abuseKind, now time.Time) bool {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	r, exists := cr.records[id]
+	if !exists {
+		r = &clientRecord{updated: now}
+		cr.records[id] = r
+	}
+	r.decay(now)
+	r.score += abuseWeights[kind]
+	switch kind {
+	case abuseMalformedMsg:
+		r.malformedMsgs++
+	case abuseTimeout:
+		r.timeouts++
+	case abuseCommitFailure:
+		r.commitFailures++
+	}
+
+	if r.score < reputationBanScore || now.Before(r.bannedUntil) {
+		return false
+	}
+	banDuration := reputationMaxBanDuration
+	if r.bans < 16 && reputationBaseBanDuration<<uint(r.bans) < reputationMaxBanDuration {
+		banDuration = reputationBaseBanDuration << uint(r.bans)
+	}
+	r.bans++
+	r.bannedUntil = now.Add(banDuration)
+	r.score = 0
+	return true
+}
+
+// admit checks whether the request from the client could be handled. An error is returned
+// if the client is banned, and the delay is returned if the client is deprioritized
+func (cr *clientReputation) admit(id enode.ID, now time.Time) (time.Duration, error) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	r, exists := cr.records[id]
+	if !exists {
+		return 0, nil
+	}
+	if now.Before(r.bannedUntil) {
+		return 0, fmt.Errorf("client %v is banned until %v", id.TerminalString(), r.bannedUntil.Format(time.RFC3339))
+	}
+	r.decay(now)
+	if r.score >= reputationDeprioritizeScore {
+		return reputationDeprioritizeDelay, nil
+	}
+	// the records forgiven are removed, the ban count is kept with the banned clients
+	if r.score < 1 && r.bans == 0 {
+		delete(cr.records, id)
+	}
+	return 0, nil
+}
+
+// report returns the reputation of the clients with abuse records, sorted by the score
+func (cr *clientReputation) report(now time.Time) []ClientReputation {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	reports := make([]ClientReputation, 0, len(cr.records))
+	for id, r := range cr.records {
+		r.decay(now)
+		report := ClientReputation{
+			ClientID:       id.String(),
+			Score:          r.score,
+			MalformedMsgs:  r.malformedMsgs,
+			Timeouts:       r.timeouts,
+			CommitFailures: r.commitFailures,
+			Bans:           r.bans,
+			Deprioritized:  r.score >= reputationDeprioritizeScore,
+		}
+		if now.Before(r.bannedUntil) {
+			report.BannedUntil = r.bannedUntil
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Score != reports[j].Score {
+			return reports[i].Score > reports[j].Score
+		}
+		return reports[i].ClientID < reports[j].ClientID
+	})
+	return reports
+}
+
+// reset removes the abuse record of the client, including the ban
+func (cr *clientReputation) reset(id enode.ID) bool {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	_, exists := cr.records[id]
+	delete(cr.records, id)
+	return exists
+}
+
+// AdmitClient checks the reputation of the client before handling the negotiation request.
+// An error is returned if the client is temporarily banned, and the request of the
+// deprioritized client should be handled after the delay returned
+func (h *StorageHost) AdmitClient(id enode.ID) (time.Duration, error) {
+	return h.reputation.admit(id, time.Now())
+}
+
+// recordClientAbuse records the negotiation abuse of the client connected with the peer
+func (h *StorageHost) recordClientAbuse(sp storage.Peer, kind abuseKind) {
+	node := sp.PeerNode()
+	if node == nil {
+		return
+	}
+	if h.reputation.record(node.ID(), kind, time.Now()) {
+		h.log.Warn("Storage client banned for negotiation abuse", "client", node.ID().TerminalString())
+	}
+}
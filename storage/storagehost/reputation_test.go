@@ -0,0 +1,69 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/p2p/enode"
+)
+
+func TestClientReputation_Penalties(t *testing.T) {
+	cr := newClientReputation()
+	id := enode.ID{1}
+	now := time.Now()
+
+	// the client without abuse record is admitted right away
+	if delay, err := cr.admit(id, now); delay != 0 || err != nil {
+		t.Fatalf("clean client not admitted: %v, %v", delay, err)
+	}
+
+	// malformed messages push the client over the deprioritize score
+	cr.record(id, abuseMalformedMsg, now)
+	cr.record(id, abuseMalformedMsg, now)
+	if delay, err := cr.admit(id, now); delay != reputationDeprioritizeDelay || err != nil {
+		t.Fatalf("abusive client should be deprioritized: %v, %v", delay, err)
+	}
+
+	// the score decays by half after the half life
+	later := now.Add(reputationHalfLife)
+	if delay, err := cr.admit(id, later); delay != 0 || err != nil {
+		t.Fatalf("the score should decay: %v, %v", delay, err)
+	}
+
+	// the client reaching the ban score is banned, and the ban is doubled for each time
+	var banned bool
+	for i := 0; !banned; i++ {
+		if i > 100 {
+			t.Fatal("the client is never banned")
+		}
+		banned = cr.record(id, abuseTimeout, later)
+	}
+	if _, err := cr.admit(id, later.Add(reputationBaseBanDuration-time.Second)); err == nil {
+		t.Fatal("banned client should not be admitted")
+	}
+	later = later.Add(reputationBaseBanDuration)
+	if _, err := cr.admit(id, later); err != nil {
+		t.Fatalf("the ban should be lifted: %v", err)
+	}
+	for banned = false; !banned; {
+		banned = cr.record(id, abuseCommitFailure, later)
+	}
+	if _, err := cr.admit(id, later.Add(2*reputationBaseBanDuration-time.Second)); err == nil {
+		t.Fatal("the second ban should be doubled")
+	}
+
+	reports := cr.report(later)
+	if len(reports) != 1 || reports[0].Bans != 2 || reports[0].MalformedMsgs != 2 || reports[0].BannedUntil.IsZero() {
+		t.Fatalf("report not expected: %+v", reports)
+	}
+	if !cr.reset(id) || len(cr.report(later)) != 0 {
+		t.Fatal("the record should be removed after reset")
+	}
+	if _, err := cr.admit(id, later); err != nil {
+		t.Fatalf("the ban should be lifted after reset: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"errors"
+
+	"github.com/DxChainNetwork/godx/ethdb"
+	"github.com/DxChainNetwork/godx/rlp"
+)
+
+// errHostRetiring is returned when the retiring host is asked for new contracts or uploads
+var errHostRetiring = errors.New("host is retiring, new contracts and uploads are not accepted")
+
+// HostRetirement is the retirement status of the storage host. During the retirement, the
+// host stops accepting new contracts and uploads, and keeps serving downloads and submitting
+// storage proofs until all storage responsibilities are resolved
+type HostRetirement struct {
+	Retiring            bool   `json:"retiring"`
+	StartHeight         uint64 `json:"startheight"`
+	BlockHeight         uint64 `json:"blockheight"`
+	UnresolvedContracts int    `json:"unresolvedcontracts"`
+	EstimatedCompleteAt uint64 `json:"estimatedcompleteat"`
+	RemainingBlocks     uint64 `json:"remainingblocks"`
+	Completed           bool   `json:"completed"`
+}
+
+// retire puts the storage host into the retirement mode
+func (h *StorageHost) retire() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.retiring {
+		return errors.New("host is already retiring")
+	}
+	h.retiring = true
+	h.retireHeight = h.blockHeight
+	return h.syncConfig()
+}
+
+// cancelRetirement brings the retiring storage host back to accept new contracts and uploads
+func (h *StorageHost) cancelRetirement() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !h.retiring {
+		return errors.New("host is not retiring")
+	}
+	h.retiring = false
+	h.retireHeight = 0
+	return h.syncConfig()
+}
+
+// isRetiring checks whether the storage host is in the retirement mode
+func (h *StorageHost) isRetiring() bool {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.retiring
+}
+
+// retirementStatus returns the retirement status of the storage host. The retirement is
+// estimated to complete at the latest proof deadline of the unresolved storage responsibilities
+func (h *StorageHost) retirementStatus() (HostRetirement, error) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	status := HostRetirement{
+		Retiring:            h.retiring,
+		StartHeight:         h.retireHeight,
+		BlockHeight:         h.blockHeight,
+		EstimatedCompleteAt: h.blockHeight,
+	}
+	sos, err := getStorageResponsibilities(h.db)
+	if err != nil {
+		return HostRetirement{}, err
+	}
+	for _, so := range sos {
+		if so.ResponsibilityStatus != responsibilityUnresolved {
+			continue
+		}
+		status.UnresolvedContracts++
+		if deadline := so.proofDeadline(); deadline > status.EstimatedCompleteAt {
+			status.EstimatedCompleteAt = deadline
+		}
+	}
+	status.RemainingBlocks = status.EstimatedCompleteAt - h.blockHeight
+	status.Completed = h.retiring && status.UnresolvedContracts == 0
+	return status, nil
+}
+
+// getStorageResponsibilities get all storage responsibilities stored in the db
+func getStorageResponsibilities(db *ethdb.LDBDatabase) ([]StorageResponsibility, error) {
+	var sos []StorageResponsibility
+
+	iter := db.NewIteratorWithPrefix([]byte(prefixStorageResponsibility))
+	defer iter.Release()
+	for iter.Next() {
+		var so StorageResponsibility
+		if err := rlp.DecodeBytes(iter.Value(), &so); err != nil {
+			return nil, err
+		}
+		sos = append(sos, so)
+	}
+	return sos, iter.Error()
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"testing"
+
+	"github.com/DxChainNetwork/godx/core/types"
+)
+
+func TestStorageHost_Retirement(t *testing.T) {
+	h := newTestStorageHost(t)
+	defer h.db.Close()
+	h.blockHeight = 100
+
+	// two unresolved responsibilities, and a resolved one with a later deadline
+	for i, windowEnd := range []uint64{300, 500, 800} {
+		so := StorageResponsibility{
+			OriginStorageContract: types.StorageContract{
+				RevisionNumber: uint64(i),
+				WindowEnd:      windowEnd,
+			},
+		}
+		if windowEnd == 800 {
+			so.ResponsibilityStatus = responsibilitySucceeded
+		}
+		if err := putStorageResponsibility(h.db, so.id(), so); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := h.retire(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.retire(); err == nil {
+		t.Errorf("retiring twice should fail")
+	}
+	if !h.isRetiring() {
+		t.Fatalf("host should be retiring")
+	}
+	status, err := h.retirementStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := HostRetirement{
+		Retiring:            true,
+		StartHeight:         100,
+		BlockHeight:         100,
+		UnresolvedContracts: 2,
+		EstimatedCompleteAt: 500,
+		RemainingBlocks:     400,
+	}
+	if status != expected {
+		t.Errorf("retirement status expect %+v, got %+v", expected, status)
+	}
+
+	// the retirement survives the restart
+	h2 := &StorageHost{}
+	h2.loadPersistence(h.extractPersistence())
+	if !h2.retiring || h2.retireHeight != 100 {
+		t.Errorf("retirement not persisted: %v at %v", h2.retiring, h2.retireHeight)
+	}
+
+	if err = h.cancelRetirement(); err != nil {
+		t.Fatal(err)
+	}
+	if status, err = h.retirementStatus(); err != nil || status.Retiring || status.Completed {
+		t.Errorf("retirement status after cancelled not expected: %+v, %v", status, err)
+	}
+}
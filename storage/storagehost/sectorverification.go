@@ -0,0 +1,82 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+)
+
+// sectorVerificationHeight returns the block height at which the sectors of the storage
+// responsibility are verified before the proof window
+func (so *StorageResponsibility) sectorVerificationHeight() uint64 {
+	if so.expiration() < sectorVerificationLead {
+		return 0
+	}
+	return so.expiration() - sectorVerificationLead
+}
+
+// inSectorVerificationPeriod checks whether the block height is between the sector
+// verification height and the start of the proof window
+func (so *StorageResponsibility) inSectorVerificationPeriod(height uint64) bool {
+	return height >= so.sectorVerificationHeight() && height < so.expiration()
+}
+
+// verifyResponsibilitySectors reads a random sample of the sectors of the storage responsibility,
+// and verifies the data read against the sector roots. The sector to be proved is decided by
+// the block right before the proof window, so a sample is verified in advance to discover
+// the disk errors while there is still time for the operator to fix them. The roots of the
+// sectors failed the verification are returned
+func (h *StorageHost) verifyResponsibilitySectors(so StorageResponsibility) (failed []common.Hash) {
+	for _, index := range sampleSectorIndexes(len(so.SectorRoots), numSectorVerificationSamples) {
+		root := so.SectorRoots[index]
+		if err := h.verifySector(root); err != nil {
+			alert.Raise(h.log, alert.SeverityCritical, alertModule, "sector verification failed before the proof window", "id", so.id(), "root", root, "err", err)
+			failed = append(failed, root)
+		}
+	}
+	if len(failed) == 0 {
+		h.log.Debug("sectors verified before the proof window", "id", so.id(), "sectors", len(so.SectorRoots))
+	}
+	return
+}
+
+// verifySector reads the sector and checks the merkle root of the data
+func (h *StorageHost) verifySector(root common.Hash) error {
+	data, err := h.readSector(root, ioProof, false)
+	if err != nil {
+		return err
+	}
+	if merkle.Sha256MerkleTreeRoot(data) != root {
+		return fmt.Errorf("merkle root of the sector data does not match")
+	}
+	return nil
+}
+
+// sampleSectorIndexes randomly picks at most n distinct indexes from [0, numSectors)
+func sampleSectorIndexes(numSectors, n int) []int {
+	if numSectors <= n {
+		indexes := make([]int, numSectors)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes
+	}
+	picked := make(map[int]struct{}, n)
+	indexes := make([]int, 0, n)
+	for len(indexes) < n {
+		index := rand.Intn(numSectors)
+		if _, exists := picked[index]; exists {
+			continue
+		}
+		picked[index] = struct{}{}
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
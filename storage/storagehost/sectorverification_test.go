@@ -0,0 +1,90 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestStorageHost_VerifyResponsibilitySectors(t *testing.T) {
+	h := newTestStorageHost(t)
+	if err := h.StorageManager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		h.StorageManager.Close()
+		h.db.Close()
+	}()
+	if err := h.AddStorageFolder(filepath.Join(h.persistDir, "folder"), 1<<25); err != nil {
+		t.Fatal(err)
+	}
+
+	var so StorageResponsibility
+	for i := 0; i != 2; i++ {
+		data := make([]byte, storage.SectorSize)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatal(err)
+		}
+		root := merkle.Sha256MerkleTreeRoot(data)
+		if err := h.AddSector(root, data); err != nil {
+			t.Fatal(err)
+		}
+		so.SectorRoots = append(so.SectorRoots, root)
+	}
+	if failed := h.verifyResponsibilitySectors(so); len(failed) != 0 {
+		t.Fatalf("the stored sectors failed the verification: %v", failed)
+	}
+
+	// the sector not stored fails the verification
+	missing := common.Hash{1}
+	so.SectorRoots = append(so.SectorRoots, missing)
+	if failed := h.verifyResponsibilitySectors(so); len(failed) != 1 || failed[0] != missing {
+		t.Fatalf("expect the missing sector failed, got %v", failed)
+	}
+}
+
+func TestStorageResponsibility_InSectorVerificationPeriod(t *testing.T) {
+	so := StorageResponsibility{
+		StorageContractRevisions: []types.StorageContractRevision{{NewWindowStart: 10000}},
+	}
+	tests := []struct {
+		height uint64
+		expect bool
+	}{
+		{10000 - sectorVerificationLead - 1, false},
+		{10000 - sectorVerificationLead, true},
+		{9999, true},
+		{10000, false},
+	}
+	for _, test := range tests {
+		if res := so.inSectorVerificationPeriod(test.height); res != test.expect {
+			t.Errorf("height %v: expect %v, got %v", test.height, test.expect, res)
+		}
+	}
+}
+
+func TestSampleSectorIndexes(t *testing.T) {
+	if indexes := sampleSectorIndexes(3, 4); len(indexes) != 3 {
+		t.Fatalf("expect all 3 sectors sampled, got %v", indexes)
+	}
+	indexes := sampleSectorIndexes(100, 4)
+	picked := make(map[int]struct{})
+	for _, index := range indexes {
+		if index < 0 || index >= 100 {
+			t.Fatalf("index %v out of range", index)
+		}
+		picked[index] = struct{}{}
+	}
+	if len(picked) != 4 {
+		t.Fatalf("expect 4 distinct indexes, got %v", indexes)
+	}
+}
@@ -5,6 +5,7 @@
 package storagehost
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -13,7 +14,9 @@ import (
 
 	"github.com/DxChainNetwork/godx/accounts"
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
 	tm "github.com/DxChainNetwork/godx/common/threadmanager"
+	"github.com/DxChainNetwork/godx/common/timeseries"
 	"github.com/DxChainNetwork/godx/ethdb"
 	"github.com/DxChainNetwork/godx/log"
 	"github.com/DxChainNetwork/godx/p2p/enode"
@@ -41,14 +44,47 @@ type StorageHost struct {
 	lockedStorageResponsibility map[common.Hash]*TryMutex
 	clientToContract            map[string]common.Hash
 
+	// proofSubmissions is the block height the storage proof transaction of each storage
+	// responsibility was last sent at
+	proofSubmissions map[common.Hash]uint64
+
+	// sessions are the snapshots of the negotiation sessions being finalized
+	sessions map[common.Hash]*negotiationSession
+
+	// deletingHeights are the heights of the delayed deletions being physically deleted
+	deletingHeights map[uint64]struct{}
+
+	// abuse records of the storage clients
+	reputation *clientReputation
+
+	// cached sub trees of the sector roots, used to calculate the merkle roots of the revisions
+	sectorRoots *sectorRootsCache
+
+	// io schedules the disk operations of the sectors, prioritizing the storage proofs
+	io *ioScheduler
+
+	// negotiations accounts the memory and the bandwidth used by the negotiations
+	negotiations *negotiationBudget
+
+	// in the retirement mode, the host stops accepting new contracts and uploads
+	// since the retireHeight
+	retiring     bool
+	retireHeight uint64
+
 	// things for log and persistence
 	db         *ethdb.LDBDatabase
+	metrics    *timeseries.Store
 	persistDir string
 	log        log.Logger
 
 	// things for thread safety
 	lock sync.RWMutex
 	tm   tm.ThreadManager
+
+	// ctx is cancelled when the host is closed, which interrupts the in-flight
+	// Merkle computations of the negotiations
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // IsContractSignedWithClient check whether this host signed a contract with the given client
@@ -109,7 +145,15 @@ func New(persistDir string) (*StorageHost, error) {
 		persistDir:                  persistDir,
 		lockedStorageResponsibility: make(map[common.Hash]*TryMutex),
 		clientToContract:            make(map[string]common.Hash),
+		proofSubmissions:            make(map[common.Hash]uint64),
+		sessions:                    make(map[common.Hash]*negotiationSession),
+		deletingHeights:             make(map[uint64]struct{}),
+		reputation:                  newClientReputation(),
+		sectorRoots:                 newSectorRootsCache(),
+		io:                          newIOScheduler(ioConcurrency),
+		negotiations:                newNegotiationBudget(),
 	}
+	h.ctx, h.cancel = context.WithCancel(context.Background())
 
 	var err error
 	// Create the data path
@@ -124,6 +168,10 @@ func New(persistDir string) (*StorageHost, error) {
 	if h.db, err = openDB(filepath.Join(persistDir, databaseFile)); err != nil {
 		return nil, err
 	}
+	// open the hourly metrics
+	if err = h.openMetrics(); err != nil {
+		return nil, err
+	}
 
 	return &h, nil
 }
@@ -139,7 +187,9 @@ func (h *StorageHost) Start(eth storage.HostBackend) (err error) {
 	if err = h.load(); err != nil {
 		return err
 	}
-	// start the storage manager
+	// start the storage manager. The interrupted sector additions are redone only if the
+	// sectors are referenced by the storage responsibilities
+	h.StorageManager.SetSectorReferenced(h.sectorReferenced)
 	if err = h.StorageManager.Start(); err != nil {
 		return err
 	}
@@ -149,6 +199,10 @@ func (h *StorageHost) Start(eth storage.HostBackend) (err error) {
 		h.log.Error("responsibilityFailed to parse storage contract tx API for host", "error", err)
 		return
 	}
+	// complete or roll back the negotiation sessions unfinished before the host stopped
+	if err = h.recoverSessions(); err != nil {
+		return err
+	}
 	//Delete residual storage responsibility
 	if err = h.pruneStaleStorageResponsibilities(); err != nil {
 		return err
@@ -160,6 +214,8 @@ func (h *StorageHost) Start(eth storage.HostBackend) (err error) {
 
 // Close the storage host and persist the data
 func (h *StorageHost) Close() error {
+	h.cancel()
+	h.negotiations.close()
 	err := h.tm.Stop()
 
 	newErr := h.StorageManager.Close()
@@ -167,6 +223,9 @@ func (h *StorageHost) Close() error {
 
 	h.db.Close()
 
+	newErr = h.metrics.Close()
+	err = common.ErrCompose(err, newErr)
+
 	newErr = h.syncConfig()
 	err = common.ErrCompose(err, newErr)
 	return err
@@ -210,7 +269,7 @@ func (h *StorageHost) load() error {
 
 	// and get synchronization
 	if syncErr := h.syncConfig(); syncErr != nil {
-		h.log.Warn("Tempt to synchronize config to file responsibilityFailed: " + syncErr.Error())
+		alert.Raise(h.log, alert.SeverityWarning, alertModule, "failed to synchronize the host config to file", "err", syncErr)
 	}
 	return nil
 }
@@ -424,7 +483,7 @@ func (h *StorageHost) externalConfig() storage.HostExtConfig {
 	totalStorageSpace = storage.SectorSize * hs.TotalSectors
 	remainingStorageSpace = storage.SectorSize * hs.FreeSectors
 
-	acceptingContracts := h.config.AcceptingContracts
+	acceptingContracts := h.config.AcceptingContracts && !h.retiring
 	MaxDeposit := h.config.MaxDeposit
 	paymentAddress := h.config.PaymentAddress
 
@@ -462,6 +521,7 @@ func (h *StorageHost) externalConfig() storage.HostExtConfig {
 		MaxDownloadBatchSize:   h.config.MaxDownloadBatchSize,
 		MaxDuration:            h.config.MaxDuration,
 		MaxReviseBatchSize:     h.config.MaxReviseBatchSize,
+		MaxUploadBatchSectors:  h.config.MaxUploadBatchSectors,
 		SectorSize:             storage.SectorSize,
 		WindowSize:             h.config.WindowSize,
 		PaymentAddress:         paymentAddress,
@@ -475,6 +535,7 @@ func (h *StorageHost) externalConfig() storage.HostExtConfig {
 		SectorAccessPrice:      h.config.SectorAccessPrice,
 		StoragePrice:           h.config.StoragePrice,
 		UploadBandwidthPrice:   h.config.UploadBandwidthPrice,
+		ScheduledPrices:        h.config.ScheduledPrices,
 		Version:                storage.ConfigVersion,
 	}
 }
@@ -7,9 +7,12 @@ package storagemanager
 import (
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
 	"github.com/DxChainNetwork/godx/common/writeaheadlog"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
 	"github.com/DxChainNetwork/godx/rlp"
 	"github.com/DxChainNetwork/godx/storage"
 
@@ -21,6 +24,7 @@ type (
 	addSectorUpdate struct {
 		// user input fields
 		id   sectorID
+		root common.Hash
 		data []byte
 
 		// The folder to add sector
@@ -43,6 +47,11 @@ type (
 	addSectorInitPersist struct {
 		ID   sectorID
 		Data []byte
+
+		// Root is the merkle root of the data used to verify the sector written in
+		// recovery. It is decoded as the tail so that the transactions logged without
+		// the root could still be decoded, and has at most one element
+		Root []common.Hash `rlp:"tail"`
 	}
 
 	// addPhysicalSectorAppendPersist is the append part for add physical sector update
@@ -110,6 +119,7 @@ func (sm *storageManager) createAddSectorUpdate(root common.Hash, data []byte) (
 	// create an update with copied data
 	update = &addSectorUpdate{
 		id:   sectorID,
+		root: root,
 		data: dataCpy,
 	}
 	return
@@ -133,6 +143,7 @@ func (update *addSectorUpdate) recordIntent(manager *storageManager) (err error)
 	pUpdate := addSectorInitPersist{
 		ID:   update.id,
 		Data: update.data,
+		Root: []common.Hash{update.root},
 	}
 	b, err := rlp.EncodeToBytes(pUpdate)
 	if err != nil {
@@ -424,6 +435,9 @@ func decodeAddSectorUpdate(txn *writeaheadlog.Transaction) (update *addSectorUpd
 		data: initPersist.Data,
 		txn:  txn,
 	}
+	if len(initPersist.Root) != 0 {
+		update.root = initPersist.Root[0]
+	}
 	// Not appended
 	if len(txn.Operations) == 1 {
 		return
@@ -484,12 +498,79 @@ func (update *addSectorUpdate) lockResource(manager *storageManager) (err error)
 	return
 }
 
-// prepareCommitted prepare for committed transaction
+// prepareCommitted prepare for committed transaction. If the transaction is to add a physical
+// sector referenced by the storage responsibilities with the merkle root recorded, the sector
+// is to be redone in processCommitted, and the database batch is prepared here. Otherwise the
+// update is reverted.
 func (update *addSectorUpdate) prepareCommitted(manager *storageManager) (err error) {
+	if !update.redoable(manager) {
+		return
+	}
+	update.batch, err = manager.db.saveSectorToBatch(update.batch, update.sector, true)
+	if err != nil {
+		return
+	}
+	// The slot might have been marked as used in database before the interruption
+	if update.folder.usage[update.sector.index/bitVectorGranularity].isFree(update.sector.index % bitVectorGranularity) {
+		if err = update.folder.setUsedSectorSlot(update.sector.index); err != nil {
+			return
+		}
+	}
+	update.batch, err = manager.db.saveStorageFolderToBatch(update.batch, update.folder)
 	return
 }
 
-// processCommitted process for committed transaction
+// processCommitted process for committed transaction. A physical sector is only redone after
+// the data on the disk is verified against the recorded merkle root. If the data written
+// before the interruption is torn, the sector is written again with the data in wal. If the
+// data still could not be verified, the update is reverted with errSectorChecksum, so that a
+// corrupted sector is never committed.
 func (update *addSectorUpdate) processCommitted(manager *storageManager) (err error) {
-	return errRevert
+	if !update.redoable(manager) {
+		return errRevert
+	}
+	if err = update.verifySectorData(); err == nil {
+		return manager.db.writeBatch(update.batch)
+	}
+	manager.log.Warn("sector data not match the merkle root, rewrite from wal", "id", update.id, "err", err)
+	offset := int64(update.sector.index * storage.SectorSize)
+	if _, err = update.folder.dataFile.WriteAt(update.data, offset); err != nil {
+		return
+	}
+	if err = update.verifySectorData(); err != nil {
+		alert.Raise(manager.log, alert.SeverityError, alertModule, "sector checksum failed during recovery",
+			"id", update.id, "folder", update.folder.path, "err", err)
+		return err
+	}
+	return manager.db.writeBatch(update.batch)
+}
+
+// redoable returns whether the recovered update could be redone. Only the physical sector
+// updates referenced by the storage responsibilities, with the merkle root matching the sector
+// id in wal could be redone
+func (update *addSectorUpdate) redoable(manager *storageManager) bool {
+	if !update.physical || update.sector == nil || update.folder == nil {
+		return false
+	}
+	if update.root == (common.Hash{}) || manager.calculateSectorID(update.root) != update.id {
+		return false
+	}
+	if manager.sectorReferenced == nil || !manager.sectorReferenced(update.root) {
+		return false
+	}
+	return merkle.Sha256MerkleTreeRoot(update.data) == update.root
+}
+
+// verifySectorData read the sector data from the folder and verify the data against the
+// merkle root of the update
+func (update *addSectorUpdate) verifySectorData() (err error) {
+	b := make([]byte, storage.SectorSize)
+	n, err := update.folder.dataFile.ReadAt(b, int64(update.sector.index*storage.SectorSize))
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if uint64(n) != storage.SectorSize || merkle.Sha256MerkleTreeRoot(b) != update.root {
+		return errSectorChecksum
+	}
+	return nil
 }
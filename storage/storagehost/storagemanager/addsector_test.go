@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -187,6 +188,103 @@ func TestAddSectorStopRecoverPhysical(t *testing.T) {
 	}
 }
 
+// TestAddSectorStopRecoverReferenced test the scenario of stop and recover when the sector is
+// referenced by the storage responsibilities. The sector written before stopped shall be
+// verified and kept after recovery
+func TestAddSectorStopRecoverReferenced(t *testing.T) {
+	keyWord := "physical process normal stop"
+	d := newDisruptor().register(keyWord, func() bool { return true })
+	sm := newTestStorageManager(t, "referenced", d)
+	path := randomFolderPath(t, "referenced")
+	size := uint64(1 << 25)
+	if err := sm.AddStorageFolder(path, size); err != nil {
+		t.Fatal(err)
+	}
+	data := randomBytes(storage.SectorSize)
+	root := merkle.Sha256MerkleTreeRoot(data)
+	if err := sm.AddSector(root, data); err != nil {
+		t.Fatalf("errStop should not give error: %v", err)
+	}
+	sm.shutdown(t, 100*time.Millisecond)
+	newsm, err := New(sm.persistDir)
+	if err != nil {
+		t.Fatalf("cannot create a new sm: %v", err)
+	}
+	newSM := newsm.(*storageManager)
+	newSM.SetSectorReferenced(func(sectorRoot common.Hash) bool { return sectorRoot == root })
+	if err = newSM.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// wait for the update to complete
+	<-time.After(500 * time.Millisecond)
+	if err = checkSectorExist(root, newSM, data, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkFoldersHasExpectedSectors(newSM, 1); err != nil {
+		t.Fatal(err)
+	}
+	newSM.shutdown(t, 100*time.Millisecond)
+	if err = checkWalTxnNum(filepath.Join(sm.persistDir, walFileName), 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestAddSectorStopRecoverTornWrite test the scenario that the data of the referenced sector is
+// corrupted when stopped. The sector shall be rewritten with the data in wal during recovery
+func TestAddSectorStopRecoverTornWrite(t *testing.T) {
+	keyWord := "physical process normal stop"
+	d := newDisruptor().register(keyWord, func() bool { return true })
+	sm := newTestStorageManager(t, "torn write", d)
+	path := randomFolderPath(t, "torn write")
+	size := uint64(1 << 25)
+	if err := sm.AddStorageFolder(path, size); err != nil {
+		t.Fatal(err)
+	}
+	data := randomBytes(storage.SectorSize)
+	root := merkle.Sha256MerkleTreeRoot(data)
+	if err := sm.AddSector(root, data); err != nil {
+		t.Fatalf("errStop should not give error: %v", err)
+	}
+	s, err := sm.db.getSector(sm.calculateSectorID(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.shutdown(t, 100*time.Millisecond)
+	// Tear the second half of the sector
+	f, err := os.OpenFile(filepath.Join(path, dataFileName), os.O_RDWR, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torn := make([]byte, storage.SectorSize/2)
+	if _, err = f.WriteAt(torn, int64(s.index*storage.SectorSize+storage.SectorSize/2)); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	newsm, err := New(sm.persistDir)
+	if err != nil {
+		t.Fatalf("cannot create a new sm: %v", err)
+	}
+	newSM := newsm.(*storageManager)
+	newSM.SetSectorReferenced(func(sectorRoot common.Hash) bool { return sectorRoot == root })
+	if err = newSM.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// wait for the update to complete. The sector data is verified twice
+	<-time.After(500 * time.Millisecond)
+	if err = checkSectorExist(root, newSM, data, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkFoldersHasExpectedSectors(newSM, 1); err != nil {
+		t.Fatal(err)
+	}
+	newSM.shutdown(t, 100*time.Millisecond)
+	if err = checkWalTxnNum(filepath.Join(sm.persistDir, walFileName), 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestAddSectorsStopRecoverVirtual test stopped during adding virtual sectors
 func TestAddSectorsStopRecoverVirtual(t *testing.T) {
 	tests := []struct {
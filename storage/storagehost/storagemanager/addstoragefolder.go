@@ -239,7 +239,7 @@ func (update *addStorageFolderUpdate) release(manager *storageManager, upErr *up
 	}
 	// delete folder in memory
 	// The folders is locked before prepare. So it shall be safe to delete the entry
-	delete(manager.folders.sfs, update.path)
+	manager.folders.delete(update.path)
 	// If update failed at update stage, revert the memory and commit release the transaction
 	if upErr.prepareErr != nil {
 		if <-update.txn.InitComplete; update.txn.InitErr != nil {
@@ -99,6 +99,11 @@ func TestAddStorageFolderRecover(t *testing.T) {
 	if sm.folders.exist(filepath.Join(path, dataFileName)) {
 		t.Fatalf("folders exist path %v", path)
 	}
+	for _, entry := range newSM.folders.folderEntries() {
+		if entry.path == path {
+			t.Fatalf("folder entries still have the reverted folder %v", path)
+		}
+	}
 	exist, err := newSM.db.hasStorageFolder(path)
 	if err != nil {
 		t.Fatalf("database check folder exist: %v", err)
@@ -0,0 +1,23 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// +build linux
+
+package storagemanager
+
+import (
+	"os"
+	"syscall"
+)
+
+// allocateDiskSpace reserves the disk space of the file from the offset with the length, and
+// grows the file size accordingly. errAllocateUnsupported is returned if the file system
+// does not support the allocation
+func allocateDiskSpace(file *os.File, offset, length int64) error {
+	err := syscall.Fallocate(int(file.Fd()), 0, offset, length)
+	if err == syscall.EOPNOTSUPP || err == syscall.ENOSYS {
+		return errAllocateUnsupported
+	}
+	return err
+}
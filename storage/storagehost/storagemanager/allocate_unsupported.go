@@ -0,0 +1,14 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// +build !linux
+
+package storagemanager
+
+import "os"
+
+// allocateDiskSpace is not supported on the platform, the data file is grown by writing zeros
+func allocateDiskSpace(file *os.File, offset, length int64) error {
+	return errAllocateUnsupported
+}
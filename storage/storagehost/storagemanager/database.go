@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/rlp"
@@ -21,9 +22,23 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
-type database struct {
-	lvl *leveldb.DB
-}
+type (
+	database struct {
+		lvl *leveldb.DB
+
+		// pendingUsage is the usage of the folders written to the batches but not yet to
+		// the database. The usage is marked as persisted after the batch is written
+		pendingLock  sync.Mutex
+		pendingUsage map[*storageFolder]pendingUsage
+	}
+
+	// pendingUsage is the usage of a folder written in the batch
+	pendingUsage struct {
+		batch      *leveldb.Batch
+		usage      []bitVector
+		numSectors uint64
+	}
+)
 
 // openDB will create a new level db. If the db already existed,
 // it will open the db instead
@@ -48,7 +63,10 @@ func newPersistentDB(path string) (db *database, err error) {
 	}
 
 	// initialize DB object
-	db = &database{lvl}
+	db = &database{
+		lvl:          lvl,
+		pendingUsage: make(map[*storageFolder]pendingUsage),
+	}
 	return
 }
 
@@ -66,9 +84,44 @@ func (db *database) newBatch() *leveldb.Batch {
 // writeBatch write the batch to the database
 func (db *database) writeBatch(batch *leveldb.Batch) (err error) {
 	err = db.lvl.Write(batch, nil)
+	db.settlePendingUsage(batch, err == nil)
 	return
 }
 
+// addPendingUsage records the usage of the folder written in the batch, which is marked as
+// persisted after the batch is written. Until then the folder entry in the database is
+// unknown, and the full usage is written in the following saves.
+// Note the storage folder must be locked to use this function
+func (db *database) addPendingUsage(batch *leveldb.Batch, sf *storageFolder) {
+	usage := make([]bitVector, len(sf.usage))
+	copy(usage, sf.usage)
+	sf.persistedUsage = nil
+
+	db.pendingLock.Lock()
+	defer db.pendingLock.Unlock()
+	db.pendingUsage[sf] = pendingUsage{
+		batch:      batch,
+		usage:      usage,
+		numSectors: sf.numSectors,
+	}
+}
+
+// settlePendingUsage marks the usage written in the batch as persisted if the batch is
+// written successfully, and drops the usage pending on the batch otherwise
+func (db *database) settlePendingUsage(batch *leveldb.Batch, written bool) {
+	db.pendingLock.Lock()
+	defer db.pendingLock.Unlock()
+	for sf, pending := range db.pendingUsage {
+		if pending.batch != batch {
+			continue
+		}
+		if written {
+			sf.setUsagePersisted(pending.usage, pending.numSectors)
+		}
+		delete(db.pendingUsage, sf)
+	}
+}
+
 // getOrCreateSectorSalt return the sector salt and return.
 // If previously the sector salt is not stored, create a new one and return
 func (db *database) getOrCreateSectorSalt() (salt sectorSalt, err error) {
@@ -154,9 +207,16 @@ func (db *database) saveStorageFolder(sf *storageFolder) (err error) {
 	return
 }
 
-// saveStorageFolderToBatch append the save storage folder operations to the batch
+// saveStorageFolderToBatch append the save storage folder operations to the batch.
+// If only a few usage bit vectors are changed since the folder entry was written, the
+// changed bit vectors are written as deltas instead of rewriting the full usage. Otherwise
+// the folder entry is rewritten, and the deltas are compacted into it.
 // The storage folder should be locked before calling this function
 func (db *database) saveStorageFolderToBatch(batch *leveldb.Batch, sf *storageFolder) (newBatch *leveldb.Batch, err error) {
+	if indexes, ok := sf.usageDeltaIndexes(); ok {
+		db.saveUsageDeltasToBatch(batch, sf, indexes)
+		return batch, nil
+	}
 	// write folder data update to batch
 	folderKey := makeFolderKey(sf.path)
 	folderData, err := rlp.EncodeToBytes(sf)
@@ -167,10 +227,73 @@ func (db *database) saveStorageFolderToBatch(batch *leveldb.Batch, sf *storageFo
 	// write id to path mapping to batch
 	folderIDToPathKey := makeFolderIDToPathKey(sf.id)
 	batch.Put(folderIDToPathKey, []byte(sf.path))
+	// the deltas are compacted into the folder entry, which is marked as persisted after
+	// the batch is written
+	db.deleteUsageDeltasToBatch(batch, sf)
+	db.addPendingUsage(batch, sf)
 
 	return batch, nil
 }
 
+// saveUsageDeltasToBatch append the usage bit vectors of the indexes to the batch. The
+// deltas reverted to the value in the folder entry are deleted
+func (db *database) saveUsageDeltasToBatch(batch *leveldb.Batch, sf *storageFolder, indexes []uint64) {
+	for _, index := range indexes {
+		key := makeFolderUsageKey(sf.id, index)
+		if sf.usage[index] == sf.persistedUsage[index] {
+			batch.Delete(key)
+			continue
+		}
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, uint64(sf.usage[index]))
+		batch.Put(key, b)
+	}
+}
+
+// deleteUsageDeltasToBatch append the deletion of all usage deltas of the folder to the
+// batch, including the ones written in the batch but not yet in the database
+func (db *database) deleteUsageDeltasToBatch(batch *leveldb.Batch, sf *storageFolder) {
+	for index := range sf.usageDeltas {
+		batch.Delete(makeFolderUsageKey(sf.id, index))
+	}
+	iter := db.lvl.NewIterator(util.BytesPrefix(makeFolderUsagePrefix(sf.id)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		batch.Delete(common.CopyBytes(iter.Key()))
+	}
+}
+
+// loadUsageDeltas applies the usage deltas in the database to the storage folder decoded
+// from the folder entry
+func (db *database) loadUsageDeltas(sf *storageFolder) (err error) {
+	sf.setUsagePersisted(sf.usage, sf.numSectors)
+	prefix := makeFolderUsagePrefix(sf.id)
+	iter := db.lvl.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+	for iter.Next() {
+		index, err := strconv.ParseUint(strings.TrimPrefix(string(iter.Key()), string(prefix)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid usage delta key %s: %v", iter.Key(), err)
+		}
+		if len(iter.Value()) != 8 {
+			return fmt.Errorf("invalid usage delta %s", iter.Key())
+		}
+		sf.usageDeltas[index] = struct{}{}
+		if index >= uint64(len(sf.usage)) {
+			// the delta out of range is left by the shrink not fully written, thus
+			// the full usage shall be rewritten in the next save
+			sf.persistedUsage = nil
+			continue
+		}
+		sf.usage[index] = bitVector(binary.LittleEndian.Uint64(iter.Value()))
+	}
+	// the stored sectors in the folder entry is outdated by the deltas
+	if len(sf.usageDeltas) != 0 {
+		sf.storedSectors = sf.countStoredSectors()
+	}
+	return iter.Error()
+}
+
 // deleteStorageFolderToBatch delete the folder id to sector id
 func (db *database) deleteFolderSectorToBatch(batch *leveldb.Batch, folderID folderID, sectorID sectorID) (newBatch *leveldb.Batch) {
 	batch.Delete(makeFolderSectorKey(folderID, sectorID))
@@ -189,6 +312,10 @@ func (db *database) loadStorageFolder(path string) (sf *storageFolder, err error
 		sf = nil
 		return
 	}
+	if err = db.loadUsageDeltas(sf); err != nil {
+		sf = nil
+		return
+	}
 	return
 }
 
@@ -213,6 +340,7 @@ func (db *database) deleteStorageFolder(sf *storageFolder) (err error) {
 	for iter.Next() {
 		batch.Delete(iter.Key())
 	}
+	db.deleteUsageDeltasToBatch(batch, sf)
 	if err = db.writeBatch(batch); err != nil {
 		return
 	}
@@ -236,6 +364,10 @@ func (db *database) loadAllStorageFolders() (folders map[string]*storageFolder,
 			fullErr = common.ErrCompose(fullErr, fmt.Errorf("cannot load folder %s: %v", key, err))
 			continue
 		}
+		if err := db.loadUsageDeltas(sf); err != nil {
+			fullErr = common.ErrCompose(fullErr, fmt.Errorf("cannot load usage of folder %s: %v", key, err))
+			continue
+		}
 		// Add the folder to map
 		folders[path] = sf
 	}
@@ -359,6 +491,18 @@ func makeFolderSectorKey(folderID folderID, sectorID sectorID) (key []byte) {
 	return
 }
 
+// makeFolderUsageKey makes the key of the usage delta of the bit vector at the index
+func makeFolderUsageKey(id folderID, index uint64) (key []byte) {
+	key = makeKey(prefixFolderUsage, strconv.FormatUint(uint64(id), 10), strconv.FormatUint(index, 10))
+	return
+}
+
+// makeFolderUsagePrefix makes the prefix of the usage deltas of the folder
+func makeFolderUsagePrefix(id folderID) (prefix []byte) {
+	prefix = []byte(prefixFolderUsage + "_" + strconv.FormatUint(uint64(id), 10) + "_")
+	return
+}
+
 // makeSectorKey make the key off sector
 func makeSectorKey(sectorID sectorID) (key []byte) {
 	key = makeKey(prefixSector, common.Bytes2Hex(sectorID[:]))
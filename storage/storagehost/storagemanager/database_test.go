@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // TestDatabase_getSectorSalt test database.getOrCreateSectorSalt
@@ -100,6 +101,126 @@ func TestDatabase_PutLoadAllStorageFolder(t *testing.T) {
 
 // checkStorageFolderEqual checks equality of two storageFolder. Only persist fields are checked.
 // If error happened, directory error the testing.T
+// TestDatabase_StorageFolderUsageDeltas test the usage changes are written as deltas, and
+// compacted into the folder entry when too many bit vectors are changed
+func TestDatabase_StorageFolderUsageDeltas(t *testing.T) {
+	db := newTestDatabase(t, "")
+	sf := randomStorageFolder(t, "", db)
+	numSectors := uint64(minUsageDeltaCompaction * 2 * bitVectorGranularity)
+	sf.numSectors, sf.storedSectors = numSectors, 0
+	sf.usage = make([]bitVector, numSectors/bitVectorGranularity)
+	if err := db.saveStorageFolder(sf); err != nil {
+		t.Fatal(err)
+	}
+	folderBytes, err := db.lvl.Get(makeFolderKey(sf.path), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a few sectors are added and one of them is deleted
+	for _, index := range []uint64{0, 1, 65, 130} {
+		if err = sf.setUsedSectorSlot(index); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sf.setFreeSectorSlot(130); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.saveStorageFolder(sf); err != nil {
+		t.Fatal(err)
+	}
+	newFolderBytes, err := db.lvl.Get(makeFolderKey(sf.path), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(folderBytes, newFolderBytes) {
+		t.Fatalf("the folder entry should not be rewritten for a few usage changes")
+	}
+	if numDeltas := countUsageDeltas(db, sf.id); numDeltas != 2 {
+		t.Fatalf("expect 2 usage deltas, got %v", numDeltas)
+	}
+	recovered, err := db.loadStorageFolder(sf.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStorageFolderEqual(t, "delta", recovered, sf)
+
+	// the reverted delta is deleted
+	if err = sf.setFreeSectorSlot(65); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.saveStorageFolder(sf); err != nil {
+		t.Fatal(err)
+	}
+	if numDeltas := countUsageDeltas(db, sf.id); numDeltas != 1 {
+		t.Fatalf("expect 1 usage delta, got %v", numDeltas)
+	}
+
+	// too many bit vectors changed, and the deltas are compacted
+	for i := uint64(0); i < minUsageDeltaCompaction+1; i++ {
+		if err = sf.setUsedSectorSlot(i*bitVectorGranularity + 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = db.saveStorageFolder(sf); err != nil {
+		t.Fatal(err)
+	}
+	if numDeltas := countUsageDeltas(db, sf.id); numDeltas != 0 {
+		t.Fatalf("expect the deltas compacted, got %v", numDeltas)
+	}
+	recovered, err = db.loadStorageFolder(sf.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStorageFolderEqual(t, "compacted", recovered, sf)
+}
+
+// TestDatabase_StorageFolderUsageNotWritten test the usage is not marked as persisted if the
+// batch with the folder entry is not written to the database
+func TestDatabase_StorageFolderUsageNotWritten(t *testing.T) {
+	db := newTestDatabase(t, "")
+	sf := randomStorageFolder(t, "", db)
+	numSectors := uint64(minUsageDeltaCompaction * 2 * bitVectorGranularity)
+	sf.numSectors, sf.storedSectors = numSectors, 0
+	sf.usage = make([]bitVector, numSectors/bitVectorGranularity)
+	if err := db.saveStorageFolder(sf); err != nil {
+		t.Fatal(err)
+	}
+
+	// too many bit vectors changed, and the folder entry rewritten in the batch is dropped
+	for i := uint64(0); i < minUsageDeltaCompaction+1; i++ {
+		if err := sf.setUsedSectorSlot(i * bitVectorGranularity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.saveStorageFolderToBatch(db.newBatch(), sf); err != nil {
+		t.Fatal(err)
+	}
+
+	// the following save shall still write all the changes
+	if err := sf.setUsedSectorSlot(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.saveStorageFolder(sf); err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := db.loadStorageFolder(sf.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStorageFolderEqual(t, "not written", recovered, sf)
+}
+
+// countUsageDeltas returns the number of usage deltas of the folder in the database
+func countUsageDeltas(db *database, id folderID) (count int) {
+	iter := db.lvl.NewIterator(util.BytesPrefix(makeFolderUsagePrefix(id)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		count++
+	}
+	return
+}
+
 func checkStorageFolderEqual(t *testing.T, testName string, got, want *storageFolder) {
 	if got.id != want.id {
 		t.Errorf("Test %v %v: expect id %v, got %v", t.Name(), testName, want.id, got.id)
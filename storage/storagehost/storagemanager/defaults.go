@@ -9,6 +9,7 @@ const (
 	prefixFolder         = "storageFolder"
 	prefixFolderSector   = "folderToSector"
 	prefixFolderIDToPath = "folderIDToPath"
+	prefixFolderUsage    = "folderUsage"
 	sectorSaltKey        = "sectorSalt"
 	prefixSector         = "sector"
 )
@@ -27,11 +28,15 @@ const (
 	opNameDeleteVirtualSector  = "delete virtual sector"
 	opNameDeletePhysicalSector = "delete physical sector"
 
-	opNameExpandFolder   = "expand folder"
-	opNameShrinkFolder   = "shrink folder"
-	opNameRelocateSector = "relocate sector"
+	opNameExpandFolder         = "expand folder"
+	opNameExpandFolderProgress = "expand folder progress"
+	opNameShrinkFolder         = "shrink folder"
+	opNameRelocateSector       = "relocate sector"
 )
 
+// alertModule is the module name of the alerts raised by the storage manager
+const alertModule = "storagemanager"
+
 const (
 	databaseFileName = "storagemanager.db"
 	walFileName      = "storagemanager.wal"
@@ -78,3 +83,30 @@ const (
 	// sector
 	maxFolderSelectionRetries = 3
 )
+
+const (
+	// numRemapVerifySectors is the number of stored sectors in the new data file to be
+	// verified against their merkle roots during folder remapping
+	numRemapVerifySectors = 8
+)
+
+const (
+	// numExpandCheckpointSectors is the number of sectors preallocated by writing zeros in
+	// the data file between two progress checkpoints recorded in the wal during folder
+	// expansion, when the disk space could not be allocated directly
+	numExpandCheckpointSectors = 64
+)
+
+const (
+	// minUsageDeltaCompaction is the minimum number of usage bit vectors written as deltas
+	// before the full usage of the folder is rewritten
+	minUsageDeltaCompaction = 16
+
+	// usageDeltaCompactionRatio defines the max portion of the usage bit vectors written as
+	// deltas. When exceeded, the full usage is rewritten
+	usageDeltaCompactionRatio = 32
+
+	// usageCompactionInterval is the number of delta saves of a folder after which the full
+	// usage is rewritten regardless of the number of deltas
+	usageCompactionInterval = 1024
+)
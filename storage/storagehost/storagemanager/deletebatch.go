@@ -219,6 +219,8 @@ func (update *deleteSectorBatchUpdate) loadSectorsAndFolders(manager *storageMan
 	// lock all sectors
 	manager.sectorLocks.lockSectors(update.ids)
 	folderPaths := make([]string, 0)
+	// folders already included in folderPaths, which should only be locked once
+	includedFolders := make(map[folderID]struct{})
 	// Get all sectors and get related folder paths
 	for _, id := range update.ids {
 		s, err := manager.db.getSector(id)
@@ -230,12 +232,13 @@ func (update *deleteSectorBatchUpdate) loadSectorsAndFolders(manager *storageMan
 		} else {
 			// Need to delete the sector. The folder is effected
 			update.sectors = append(update.sectors, s)
-			if _, exist := update.folders[s.folderID]; !exist {
+			if _, exist := includedFolders[s.folderID]; !exist {
 				path, err := manager.db.getFolderPath(s.folderID)
 				if err != nil {
 					return err
 				}
 				folderPaths = append(folderPaths, path)
+				includedFolders[s.folderID] = struct{}{}
 			}
 		}
 	}
@@ -448,6 +451,7 @@ func (update *deleteSectorBatchUpdate) lockResource(manager *storageManager) (er
 	}()
 	// folderPaths are the path to lock together
 	var folderPaths []string
+	includedFolders := make(map[folderID]struct{})
 	for _, op := range update.txn.Operations[1:] {
 		switch op.Name {
 		case opNameDeletePhysicalSector:
@@ -464,12 +468,13 @@ func (update *deleteSectorBatchUpdate) lockResource(manager *storageManager) (er
 			}
 			update.sectors = append(update.sectors, s)
 			// Find the folder path
-			if _, exist := update.folders[s.folderID]; !exist {
+			if _, exist := includedFolders[s.folderID]; !exist {
 				path, err := manager.db.getFolderPath(s.folderID)
 				if err != nil {
 					return err
 				}
 				folderPaths = append(folderPaths, path)
+				includedFolders[s.folderID] = struct{}{}
 			}
 		case opNameDeleteVirtualSector:
 			var persist deleteVirtualSectorPersist
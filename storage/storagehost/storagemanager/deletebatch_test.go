@@ -59,6 +59,45 @@ func TestDeleteSectorBatchNormal(t *testing.T) {
 	}
 }
 
+// TestDeleteSectorBatchSameFolder test deleting a batch of the sectors stored in the same
+// folder, which should lock the folder only once
+func TestDeleteSectorBatchSameFolder(t *testing.T) {
+	sm := newTestStorageManager(t, "", newDisruptor())
+	path := randomFolderPath(t, "")
+	size := uint64(1 << 25)
+	if err := sm.AddStorageFolder(path, size); err != nil {
+		t.Fatal(err)
+	}
+	var roots []common.Hash
+	for i := 0; i != 3; i++ {
+		data := randomBytes(storage.SectorSize)
+		root := merkle.Sha256MerkleTreeRoot(data)
+		if err := sm.AddSector(root, data); err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, root)
+	}
+	if err := checkFuncTimeout(5*time.Second, func() {
+		if err := sm.DeleteSectorBatch(roots); err != nil {
+			t.Error(err)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, root := range roots {
+		if err := checkSectorNotExist(sm.calculateSectorID(root), sm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := checkFoldersHasExpectedSectors(sm, 0); err != nil {
+		t.Fatal(err)
+	}
+	sm.shutdown(t, 10*time.Second)
+	if err := checkWalTxnNum(filepath.Join(sm.persistDir, walFileName), 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestDeleteSectorBatchStop test
 func TestDeleteSectorBatchStop(t *testing.T) {
 	tests := []struct {
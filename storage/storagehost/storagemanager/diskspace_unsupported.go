@@ -0,0 +1,12 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// +build windows plan9
+
+package storagemanager
+
+// freeDiskSpace is not supported on the platform, the free space check is skipped
+func freeDiskSpace(path string) (uint64, error) {
+	return 0, errDiskSpaceUnknown
+}
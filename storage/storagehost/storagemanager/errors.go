@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
 )
 
 var (
@@ -24,12 +25,22 @@ var (
 	// errRevert is the error type to signal the update has to be reverted
 	errRevert = errors.New("update to be reverted")
 
+	// errDiskSpaceUnknown is the error that the free disk space is not available on the platform
+	errDiskSpaceUnknown = errors.New("free disk space unknown")
+
+	// errAllocateUnsupported is the error that the disk space could not be allocated without
+	// writing the data on the platform or the file system
+	errAllocateUnsupported = errors.New("disk space allocation not supported")
+
 	// errFolderAlreadyFull is the error trying to add a sector to an already full folder
 	errFolderAlreadyFull = errors.New("folder already full")
 
 	// errAllFoldersFullOrUsed is the error happened when all folders are full or in use
 	errAllFoldersFullOrUsed = errors.New("all folders are full or in use")
 
+	// errSectorChecksum is the error that the sector data does not match the merkle root
+	errSectorChecksum = errors.New("sector data not match the merkle root")
+
 	// errDisrupted is the error that is disrupted during test
 	errDisrupted = errors.New("disrupted")
 )
@@ -93,7 +104,8 @@ func (upErr *updateError) hasErrStopped() (has bool) {
 	return upErr.prepareErr == errStopped || upErr.processErr == errStopped || upErr.releaseErr == errStopped
 }
 
-// logError determine the behavior of logging the updateErr for the update
+// logError determine the behavior of logging the updateErr for the update, which is also
+// raised as an alert
 func (sm *storageManager) logError(up update, err *updateError) {
 	// If there is errReverted, set it to nil
 	if err.prepareErr == errRevert {
@@ -106,11 +118,9 @@ func (sm *storageManager) logError(up update, err *updateError) {
 	if err == nil || err.isNil() || err.hasErrStopped() {
 		return
 	}
-	var msg string
+	desc := "error during update"
 	if up != nil {
-		msg = fmt.Sprintf("cannot %v", up.str())
-	} else {
-		msg = "error during update"
+		desc = fmt.Sprintf("cannot %v", up.str())
 	}
 	// compose the arguments for log
 	var args []interface{}
@@ -127,5 +137,11 @@ func (sm *storageManager) logError(up update, err *updateError) {
 	if len(args) == 0 {
 		return
 	}
-	sm.log.Warn(msg, args...)
+	// the failed release leaves the storage manager in an inconsistent state
+	severity := alert.SeverityWarning
+	if err.releaseErr != nil {
+		severity = alert.SeverityError
+	}
+	args = append([]interface{}{"update", desc}, args...)
+	alert.Raise(sm.log, severity, alertModule, "storage manager update failed", args...)
 }
@@ -25,6 +25,10 @@ type (
 
 		targetNumSectors uint64
 
+		// preallocatedSectors is the number of sectors the data file has been grown to.
+		// It is recorded in the wal as the progress checkpoint
+		preallocatedSectors uint64
+
 		folder *storageFolder
 
 		txn *writeaheadlog.Transaction
@@ -41,6 +45,10 @@ type (
 
 		TargetNumSectors uint64
 	}
+
+	expandFolderProgressPersist struct {
+		PreallocatedSectors uint64
+	}
 )
 
 // expandFolder expand the folder to target Num Sectors size
@@ -92,7 +100,20 @@ func (update *expandFolderUpdate) recordIntent(manager *storageManager) (err err
 	// If recordIntent return error, folder lock will not be released in release function. Release
 	// it right now
 	update.prevNumSectors = update.folder.numSectors
+	update.preallocatedSectors = update.prevNumSectors
 	// Record the intent to the wal
+	op, err := update.intentOperation()
+	if err != nil {
+		return
+	}
+	if update.txn, err = manager.wal.NewTransaction([]writeaheadlog.Operation{op}); err != nil {
+		return err
+	}
+	return
+}
+
+// intentOperation returns the wal operation of the expand folder intent
+func (update *expandFolderUpdate) intentOperation() (op writeaheadlog.Operation, err error) {
 	persist := expandFolderUpdatePersist{
 		FolderPath:       update.folderPath,
 		TargetNumSectors: update.targetNumSectors,
@@ -102,16 +123,115 @@ func (update *expandFolderUpdate) recordIntent(manager *storageManager) (err err
 	if err != nil {
 		return
 	}
-	op := writeaheadlog.Operation{
+	op = writeaheadlog.Operation{
 		Name: opNameExpandFolder,
 		Data: b,
 	}
-	if update.txn, err = manager.wal.NewTransaction([]writeaheadlog.Operation{op}); err != nil {
-		return err
+	return
+}
+
+// checkpoint records the preallocation progress in the wal. Since operations could not be
+// appended to a committed transaction, the intent together with the progress is committed
+// as a new transaction, and the previous transaction is released afterwards. If the host
+// crashes in between, both transactions are recovered, and the later recovered one finds
+// the folder already expanded.
+func (update *expandFolderUpdate) checkpoint(manager *storageManager) (err error) {
+	intentOp, err := update.intentOperation()
+	if err != nil {
+		return
+	}
+	b, err := rlp.EncodeToBytes(expandFolderProgressPersist{PreallocatedSectors: update.preallocatedSectors})
+	if err != nil {
+		return
+	}
+	progressOp := writeaheadlog.Operation{
+		Name: opNameExpandFolderProgress,
+		Data: b,
+	}
+	txn, err := manager.wal.NewTransaction([]writeaheadlog.Operation{intentOp, progressOp})
+	if err != nil {
+		return
+	}
+	if <-txn.InitComplete; txn.InitErr != nil {
+		return txn.InitErr
+	}
+	if err = <-txn.Commit(); err != nil {
+		return
+	}
+	prevTxn := update.txn
+	update.txn = txn
+	return prevTxn.Release()
+}
+
+// preallocate grows the data file from the preallocated sectors to the target number of
+// sectors, so that the disk space is actually reserved for the folder. The disk space is
+// allocated directly where the file system supports it. Otherwise zeros are written, and the
+// progress is recorded in the wal every numExpandCheckpointSectors sectors, from where the
+// preallocation is resumed if interrupted.
+func (update *expandFolderUpdate) preallocate(manager *storageManager) (err error) {
+	if update.preallocatedSectors >= update.targetNumSectors {
+		return
+	}
+	if !manager.disruptor.disrupt("expand folder allocate unsupported") {
+		offset := int64(numSectorsToSize(update.preallocatedSectors))
+		length := int64(numSectorsToSize(update.targetNumSectors)) - offset
+		err = allocateDiskSpace(update.folder.dataFile, offset, length)
+		if err == nil {
+			if err = update.folder.dataFile.Sync(); err != nil {
+				return
+			}
+			update.preallocatedSectors = update.targetNumSectors
+			return
+		}
+		if err != errAllocateUnsupported {
+			return
+		}
+	}
+	return update.writeZeros(manager)
+}
+
+// writeZeros grows the data file by writing zeros to the sectors from the preallocated
+// sectors to the target number of sectors, and records the progress in the wal
+func (update *expandFolderUpdate) writeZeros(manager *storageManager) (err error) {
+	zeros := make([]byte, storage.SectorSize)
+	for update.preallocatedSectors < update.targetNumSectors {
+		end := update.preallocatedSectors + numExpandCheckpointSectors
+		if end > update.targetNumSectors {
+			end = update.targetNumSectors
+		}
+		for index := update.preallocatedSectors; index < end; index++ {
+			if _, err = update.folder.dataFile.WriteAt(zeros, int64(numSectorsToSize(index))); err != nil {
+				return
+			}
+		}
+		if err = update.folder.dataFile.Sync(); err != nil {
+			return
+		}
+		update.preallocatedSectors = end
+		if end == update.targetNumSectors {
+			break
+		}
+		if err = update.checkpoint(manager); err != nil {
+			return
+		}
+		if manager.stopped() || manager.disruptor.disrupt("expand folder preallocate stop") {
+			return errStopped
+		}
 	}
 	return
 }
 
+// applyExpand applies the target number of sectors to the memory folder and the database
+// after the data file has been preallocated
+func (update *expandFolderUpdate) applyExpand(manager *storageManager) (err error) {
+	update.folder.setNumSectors(update.targetNumSectors)
+	update.folder.usage = expandUsage(update.folder.usage, update.targetNumSectors)
+	if update.batch, err = manager.db.saveStorageFolderToBatch(update.batch, update.folder); err != nil {
+		return
+	}
+	return manager.db.writeBatch(update.batch)
+}
+
 // prepare is the function to be called during prepare stage
 func (update *expandFolderUpdate) prepare(manager *storageManager, target uint8) (err error) {
 	update.batch = manager.db.newBatch()
@@ -132,15 +252,10 @@ func (update *expandFolderUpdate) prepare(manager *storageManager, target uint8)
 	return
 }
 
-// prepareNormal prepares for the update as normal update
+// prepareNormal prepares for the update as normal update. The memory and database are not
+// updated until the data file is preallocated, so that the sectors are never placed beyond
+// the data file
 func (update *expandFolderUpdate) prepareNormal(manager *storageManager) (err error) {
-	// Update the memory folder
-	update.folder.numSectors = update.targetNumSectors
-	update.folder.usage = expandUsage(update.folder.usage, update.targetNumSectors)
-	// update the db batch
-	if update.batch, err = manager.db.saveStorageFolderToBatch(update.batch, update.folder); err != nil {
-		return err
-	}
 	// Finished initialization of the transaction
 	if <-update.txn.InitComplete; update.txn.InitErr != nil {
 		return update.txn.InitErr
@@ -175,19 +290,38 @@ func (update *expandFolderUpdate) processNormal(manager *storageManager) (err er
 	if err = <-update.txn.Commit(); err != nil {
 		return err
 	}
-	// truncate the related file
-	if err = update.folder.dataFile.Truncate(int64(numSectorsToSize(update.targetNumSectors))); err != nil {
-		return err
-	}
-	// apply the batch
-	if err = manager.db.writeBatch(update.batch); err != nil {
+	// grow the data file
+	if err = update.preallocate(manager); err != nil {
 		return err
 	}
-	return
+	// apply the memory and database
+	return update.applyExpand(manager)
 }
 
+// processCommitted resumes the interrupted preallocation from the recorded progress, or
+// from the data file size if the data file is somehow smaller, and applies the expansion.
+// If the folder is found already expanded, there is nothing left to do
 func (update *expandFolderUpdate) processCommitted(manager *storageManager) (err error) {
-	return errRevert
+	if update.folder.numSectors >= update.targetNumSectors {
+		return
+	}
+	if update.preallocatedSectors < update.folder.numSectors {
+		update.preallocatedSectors = update.folder.numSectors
+	}
+	fileInfo, err := update.folder.dataFile.Stat()
+	if err != nil {
+		return err
+	}
+	if fileSectors := sizeToNumSectors(uint64(fileInfo.Size())); fileSectors < update.preallocatedSectors {
+		update.preallocatedSectors = fileSectors
+	}
+	if update.preallocatedSectors > update.targetNumSectors {
+		update.preallocatedSectors = update.targetNumSectors
+	}
+	if err = update.preallocate(manager); err != nil {
+		return err
+	}
+	return update.applyExpand(manager)
 }
 
 func (update *expandFolderUpdate) release(manager *storageManager, upErr *updateError) (err error) {
@@ -209,7 +343,7 @@ func (update *expandFolderUpdate) release(manager *storageManager, upErr *update
 	}
 	if upErr.prepareErr != nil {
 		// If error happened at prepare stage, revert the memory
-		update.folder.numSectors = update.prevNumSectors
+		update.folder.setNumSectors(update.prevNumSectors)
 		update.folder.usage = shrinkUsage(update.folder.usage, update.prevNumSectors)
 		// commit and release the transaction
 		if <-update.txn.InitComplete; update.txn.InitErr != nil {
@@ -226,7 +360,7 @@ func (update *expandFolderUpdate) release(manager *storageManager, upErr *update
 	}
 	// If process error
 	// revert the memory
-	update.folder.numSectors = update.prevNumSectors
+	update.folder.setNumSectors(update.prevNumSectors)
 	update.folder.usage = shrinkUsage(update.folder.usage, update.prevNumSectors)
 	// revert the database
 	batch := manager.db.newBatch()
@@ -250,10 +384,22 @@ func decodeExpandFolderUpdate(txn *writeaheadlog.Transaction) (update *expandFol
 		return nil, fmt.Errorf("cannot decode init persist: %v", err)
 	}
 	update = &expandFolderUpdate{
-		folderPath:       persist.FolderPath,
-		prevNumSectors:   persist.PrevNumSectors,
-		targetNumSectors: persist.TargetNumSectors,
-		txn:              txn,
+		folderPath:          persist.FolderPath,
+		prevNumSectors:      persist.PrevNumSectors,
+		targetNumSectors:    persist.TargetNumSectors,
+		preallocatedSectors: persist.PrevNumSectors,
+		txn:                 txn,
+	}
+	// decode the progress checkpoint if recorded
+	for _, op := range txn.Operations[1:] {
+		if op.Name != opNameExpandFolderProgress {
+			continue
+		}
+		var progress expandFolderProgressPersist
+		if err = rlp.DecodeBytes(op.Data, &progress); err != nil {
+			return nil, fmt.Errorf("cannot decode progress persist: %v", err)
+		}
+		update.preallocatedSectors = progress.PreallocatedSectors
 	}
 	return
 }
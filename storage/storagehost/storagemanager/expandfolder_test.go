@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/writeaheadlog"
 	"github.com/DxChainNetwork/godx/crypto/merkle"
 	"github.com/DxChainNetwork/godx/storage"
 )
@@ -141,11 +142,14 @@ func TestExpandFolderDisrupt(t *testing.T) {
 }
 
 func TestExpandFolderStop(t *testing.T) {
+	// The uncommitted expansion is discarded, and the committed one is resumed
 	tests := []struct {
-		keyWord string
+		keyWord  string
+		expanded bool
 	}{
-		{"expand folder prepare normal stop"},
-		{"expand folder process normal stop"},
+		{"expand folder prepare normal stop", false},
+		{"expand folder process normal stop", true},
+		{"expand folder preallocate stop", true},
 	}
 	for _, test := range tests {
 		d := newDisruptor().register(test.keyWord, func() bool { return true })
@@ -196,12 +200,19 @@ func TestExpandFolderStop(t *testing.T) {
 			t.Fatal(err)
 		}
 		<-time.After(300 * time.Millisecond)
+		if err = waitFolderUnlocked(newSM, path, 10*time.Second); err != nil {
+			t.Fatal(err)
+		}
 		for _, expect := range expects {
 			if err := checkSectorExist(expect.root, newSM, expect.data, uint64(expect.count)); err != nil {
 				t.Fatal(err)
 			}
 		}
-		if err := checkFolderSize(newSM, path, size); err != nil {
+		expectSize := size
+		if test.expanded {
+			expectSize = expandSize
+		}
+		if err := checkFolderSize(newSM, path, expectSize); err != nil {
 			t.Fatal(err)
 		}
 		// check locks
@@ -221,6 +232,72 @@ func TestExpandFolderStop(t *testing.T) {
 	}
 }
 
+// TestExpandFolderResume test the preallocation interrupted after the first progress
+// checkpoint is resumed from the checkpoint
+func TestExpandFolderResume(t *testing.T) {
+	d := newDisruptor().register("expand folder preallocate stop", func() bool { return true })
+	d.register("expand folder allocate unsupported", func() bool { return true })
+	sm := newTestStorageManager(t, "", d)
+	path := randomFolderPath(t, "")
+	size := uint64(1 << 25)
+	if err := sm.AddStorageFolder(path, size); err != nil {
+		t.Fatal(err)
+	}
+	expandSize := size + (numExpandCheckpointSectors+1)*storage.SectorSize
+	if err := sm.expandFolder(path, expandSize); err != nil {
+		t.Fatal(err)
+	}
+	sm.shutdown(t, time.Second)
+	// the data file is grown to the first checkpoint, while the folder size is not changed
+	checkpointSize := size + numExpandCheckpointSectors*storage.SectorSize
+	fileInfo, err := os.Stat(filepath.Join(path, dataFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileInfo.Size() != int64(checkpointSize) {
+		t.Fatalf("file size not expected. expect %v, got %v", checkpointSize, fileInfo.Size())
+	}
+	walPath := filepath.Join(sm.persistDir, walFileName)
+	wal, txns, err := writeaheadlog.New(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 1 {
+		t.Fatalf("expect 1 transaction in wal, got %v", len(txns))
+	}
+	up, err := decodeExpandFolderUpdate(txns[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.preallocatedSectors != sizeToNumSectors(checkpointSize) {
+		t.Fatalf("progress not expected. expect %v, got %v", sizeToNumSectors(checkpointSize), up.preallocatedSectors)
+	}
+	if _, err = wal.CloseIncomplete(); err != nil {
+		t.Fatal(err)
+	}
+	// restart and the expansion is resumed
+	newsm, err := New(sm.persistDir)
+	if err != nil {
+		t.Fatalf("cannot create a new sm: %v", err)
+	}
+	newSM := newsm.(*storageManager)
+	if err = newSM.Start(); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(300 * time.Millisecond)
+	if err = waitFolderUnlocked(newSM, path, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFolderSize(newSM, path, expandSize); err != nil {
+		t.Fatal(err)
+	}
+	newSM.shutdown(t, 100*time.Millisecond)
+	if err := checkWalTxnNum(walPath, 0); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Remove(filepath.Join(path, dataFileName))
+}
+
 func checkFolderSize(sm *storageManager, folderPath string, size uint64) (err error) {
 	// check the memory folder size
 	sf, err := sm.folders.getWithoutLock(folderPath)
@@ -262,6 +339,16 @@ func checkFolderSize(sm *storageManager, folderPath string, size uint64) (err er
 	return nil
 }
 
+// waitFolderUnlocked waits for the folder to be released by the recovered update, which
+// might take a while to preallocate the data file
+func waitFolderUnlocked(sm *storageManager, folderPath string, timeout time.Duration) (err error) {
+	sf, err := sm.folders.getWithoutLock(folderPath)
+	if err != nil {
+		return err
+	}
+	return checkFuncTimeout(timeout, func() { sf.lock.Lock(); sf.lock.Unlock() })
+}
+
 func checkFuncTimeout(timeout time.Duration, f func()) (err error) {
 	waitChan := make(chan struct{})
 	go func() {
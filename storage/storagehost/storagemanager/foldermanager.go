@@ -7,7 +7,9 @@ package storagemanager
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/DxChainNetwork/godx/common"
@@ -17,6 +19,16 @@ import (
 type folderManager struct {
 	sfs  map[string]*storageFolder
 	lock sync.RWMutex
+
+	// entries is the snapshot of sfs which is replaced whenever sfs is changed. With
+	// the snapshot the folder usage could be ranged over without the locks
+	entries atomic.Value
+}
+
+// folderEntry is an entry of the folder manager snapshot
+type folderEntry struct {
+	path string
+	sf   *storageFolder
 }
 
 // loadFolderManager creates a new storage folders from database and open the data files
@@ -36,9 +48,30 @@ func loadFolderManager(db *database) (fm *folderManager, err error) {
 	fm = &folderManager{
 		sfs: folders,
 	}
+	fm.refreshEntries()
 	return
 }
 
+// refreshEntries replaces the snapshot with the current folders.
+// Note the function is not thread safe, and should be called every time sfs is changed
+func (fm *folderManager) refreshEntries() {
+	entries := make([]folderEntry, 0, len(fm.sfs))
+	for path, sf := range fm.sfs {
+		entries = append(entries, folderEntry{path: path, sf: sf})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].path < entries[j].path
+	})
+	fm.entries.Store(entries)
+}
+
+// folderEntries return the latest snapshot of the folders. The function does not
+// need any lock, and the returned entries should not be modified
+func (fm *folderManager) folderEntries() []folderEntry {
+	entries, _ := fm.entries.Load().([]folderEntry)
+	return entries
+}
+
 // close close all files in the storage folders
 func (fm *folderManager) close() (err error) {
 	fm.lock.Lock()
@@ -104,6 +137,7 @@ func (fm *folderManager) getFolders(folderPaths []string) (folders map[folderID]
 // Note this function is not thread safe
 func (fm *folderManager) delete(path string) {
 	delete(fm.sfs, path)
+	fm.refreshEntries()
 }
 
 // size return the size in the folder manager
@@ -120,6 +154,7 @@ func (fm *folderManager) addFolder(sf *storageFolder) (err error) {
 		err = errors.New("path already exist")
 	}
 	fm.sfs[sf.path] = sf
+	fm.refreshEntries()
 	return nil
 }
 
@@ -177,10 +212,13 @@ func (fm *folderManager) validateShrink(folderPath string, targetNumSector uint6
 		if path == folderPath {
 			continue
 		}
-		freeSectors += sf.numSectors - sf.storedSectors
+		numSectors, storedSectors := sf.sectorUsage()
+		if numSectors > storedSectors {
+			freeSectors += numSectors - storedSectors
+		}
 	}
 	freeSectors += targetNumSector
-	if freeSectors < fm.sfs[folderPath].storedSectors {
+	if _, storedSectors := fm.sfs[folderPath].sectorUsage(); freeSectors < storedSectors {
 		return fmt.Errorf("not enough storage space for shrink")
 	}
 	return
@@ -0,0 +1,144 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagemanager
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// RemapFolder updates the path of a storage folder whose data file has been moved
+// by the operator from oldPath to newPath. The content of the data file must be
+// unchanged, so all the sectors stored in the folder are kept.
+func (sm *storageManager) RemapFolder(oldPath string, newPath string) (err error) {
+	// Change both paths to absolute path
+	if oldPath, err = absolutePath(oldPath); err != nil {
+		return
+	}
+	if newPath, err = absolutePath(newPath); err != nil {
+		return
+	}
+	if oldPath == newPath {
+		return errors.New("the new path is the same as the old path")
+	}
+
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+
+	sm.folders.lock.Lock()
+	defer sm.folders.lock.Unlock()
+
+	if sm.folders.exist(newPath) {
+		return fmt.Errorf("folder %v already exist", newPath)
+	}
+	sf, err := sm.folders.get(oldPath)
+	if err != nil {
+		return err
+	}
+	defer sf.lock.Unlock()
+
+	// Open the data file at the new location and verify its content
+	newDataFile, err := openRemappedDataFile(newPath, sf.numSectors)
+	if err != nil {
+		return err
+	}
+	if err = sm.verifyRemappedDataFile(sf, newDataFile); err != nil {
+		newDataFile.Close()
+		return err
+	}
+
+	// Update the database. The folder to sector entries are keyed with the folder id,
+	// thus only the folder entry and the folder id to path entry need to be updated.
+	batch := sm.db.newBatch()
+	batch.Delete(makeFolderKey(oldPath))
+	newSf := &storageFolder{
+		id:            sf.id,
+		path:          newPath,
+		usage:         sf.usage,
+		numSectors:    sf.numSectors,
+		storedSectors: sf.storedSectors,
+	}
+	if batch, err = sm.db.saveStorageFolderToBatch(batch, newSf); err != nil {
+		newDataFile.Close()
+		return err
+	}
+	if err = sm.db.writeBatch(batch); err != nil {
+		newDataFile.Close()
+		return err
+	}
+
+	// Update the memory
+	if sf.dataFile != nil {
+		if err = sf.dataFile.Close(); err != nil {
+			sm.log.Warn("cannot close the previous data file", "path", oldPath, "err", err)
+		}
+	}
+	sf.path = newPath
+	sf.dataFile = newDataFile
+	sf.setUsagePersisted(sf.usage, sf.numSectors)
+	sf.status = folderAvailable
+	sm.folders.delete(oldPath)
+	sm.folders.sfs[newPath] = sf
+	sm.folders.refreshEntries()
+	return nil
+}
+
+// openRemappedDataFile open the data file under the new path and check whether the
+// file size is large enough for numSectors
+func openRemappedDataFile(path string, numSectors uint64) (f *os.File, err error) {
+	dataFilePath := filepath.Join(path, dataFileName)
+	fileInfo, err := os.Stat(dataFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the data file: %v", err)
+	}
+	if fileInfo.Size() < int64(numSectorsToSize(numSectors)) {
+		return nil, fmt.Errorf("data file size too small. Got %v, expect %v", fileInfo.Size(), numSectorsToSize(numSectors))
+	}
+	return os.OpenFile(dataFilePath, os.O_RDWR, 0600)
+}
+
+// verifyRemappedDataFile verifies a random sample of the sectors stored in the folder
+// against the new data file. The merkle root of the sector data read from the new data
+// file must derive the id of a sector stored at the same location in the database.
+// The remap is refused if the folder has stored sectors but none could be verified.
+// Note the storage folder must be locked to use this function
+func (sm *storageManager) verifyRemappedDataFile(sf *storageFolder, newDataFile *os.File) (err error) {
+	ids := sm.db.getAllSectorsIDsFromFolder(sf.id)
+	if len(ids) == 0 {
+		if sf.storedSectors != 0 {
+			return fmt.Errorf("no sector of the folder is found in database to verify the data file")
+		}
+		return nil
+	}
+	rand.Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+	if len(ids) > numRemapVerifySectors {
+		ids = ids[:numRemapVerifySectors]
+	}
+
+	data := make([]byte, storage.SectorSize)
+	for _, id := range ids {
+		var s *sector
+		if s, err = sm.db.getSector(id); err != nil {
+			return fmt.Errorf("cannot get sector %x from database: %v", id, err)
+		}
+		if _, err = newDataFile.ReadAt(data, int64(numSectorsToSize(s.index))); err != nil {
+			return fmt.Errorf("cannot read sector %d from the new data file: %v", s.index, err)
+		}
+		// the sector id is derived from the merkle root of the data
+		root := merkle.Sha256MerkleTreeRoot(data)
+		if sm.calculateSectorID(root) != id {
+			return fmt.Errorf("sector %d in the new data file does not match its merkle root", s.index)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagemanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// TestRemapFolderNormal test the process of remapping a folder whose data file
+// has been moved to a new location
+func TestRemapFolderNormal(t *testing.T) {
+	sm := newTestStorageManager(t, "", newDisruptor())
+	oldPath := randomFolderPath(t, "")
+	size := uint64(1 << 25)
+	if err := sm.AddStorageFolder(oldPath, size); err != nil {
+		t.Fatal(err)
+	}
+	// insert some sectors
+	datas := make(map[common.Hash][]byte)
+	for i := 0; i != 4; i++ {
+		data := randomBytes(storage.SectorSize)
+		root := merkle.Sha256MerkleTreeRoot(data)
+		if err := sm.AddSector(root, data); err != nil {
+			t.Fatal(err)
+		}
+		datas[root] = data
+	}
+	// move the data file to the new path
+	newPath := randomFolderPath(t, "")
+	if err := os.MkdirAll(newPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(oldPath, dataFileName), filepath.Join(newPath, dataFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.RemapFolder(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if sm.folders.exist(oldPath) {
+		t.Fatalf("old path still exist in folder manager")
+	}
+	if exist, err := sm.db.hasStorageFolder(oldPath); err != nil || exist {
+		t.Fatalf("old path still exist in database")
+	}
+	for root, data := range datas {
+		if err := checkSectorExist(root, sm, data, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// reopen the storage manager, the sectors should still exist
+	sm.shutdown(t, 100*time.Millisecond)
+	newSM, err := newStorageManager(sm.persistDir, newDisruptor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = newSM.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for root, data := range datas {
+		if err := checkSectorExist(root, newSM, data, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newSM.shutdown(t, 100*time.Millisecond)
+	_ = os.Remove(filepath.Join(newPath, dataFileName))
+}
+
+// TestRemapFolderError test the error cases of remapping a folder
+func TestRemapFolderError(t *testing.T) {
+	sm := newTestStorageManager(t, "", newDisruptor())
+	defer sm.shutdown(t, 100*time.Millisecond)
+	path := randomFolderPath(t, "")
+	size := uint64(1 << 25)
+	if err := sm.AddStorageFolder(path, size); err != nil {
+		t.Fatal(err)
+	}
+	data := randomBytes(storage.SectorSize)
+	if err := sm.AddSector(merkle.Sha256MerkleTreeRoot(data), data); err != nil {
+		t.Fatal(err)
+	}
+	// folder not exist
+	if err := sm.RemapFolder(randomFolderPath(t, ""), randomFolderPath(t, "")); err == nil {
+		t.Errorf("remapping a non-existing folder should return an error")
+	}
+	// data file not exist under the new path
+	newPath := randomFolderPath(t, "")
+	if err := sm.RemapFolder(path, newPath); err == nil {
+		t.Errorf("remapping to a path without data file should return an error")
+	}
+	// data file with different content
+	if err := os.MkdirAll(newPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(newPath, dataFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Truncate(int64(size)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := sm.RemapFolder(path, newPath); err == nil {
+		t.Errorf("remapping to a data file with different content should return an error")
+	}
+	if !sm.folders.exist(path) {
+		t.Errorf("folder shall not be remapped after failure")
+	}
+	_ = os.Remove(filepath.Join(newPath, dataFileName))
+	_ = os.Remove(filepath.Join(path, dataFileName))
+}
+
+// TestRemapFolderVerifyRoots test the new data file is verified against the merkle roots
+// of the sectors, even if the previous data file is not available any more
+func TestRemapFolderVerifyRoots(t *testing.T) {
+	sm := newTestStorageManager(t, "", newDisruptor())
+	defer sm.shutdown(t, 100*time.Millisecond)
+	path := randomFolderPath(t, "")
+	size := uint64(1 << 25)
+	if err := sm.AddStorageFolder(path, size); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i != 4; i++ {
+		data := randomBytes(storage.SectorSize)
+		if err := sm.AddSector(merkle.Sha256MerkleTreeRoot(data), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content, err := ioutil.ReadFile(filepath.Join(path, dataFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the previous data file is removed with the previous mount point
+	if err = os.Remove(filepath.Join(path, dataFileName)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a single sector corrupted in the new data file is detected
+	newPath := randomFolderPath(t, "")
+	if err = os.MkdirAll(newPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	sf, err := sm.db.loadStorageFolder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := sm.db.getSector(sm.db.getAllSectorsIDsFromFolder(sf.id)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := append([]byte(nil), content...)
+	corrupted[numSectorsToSize(s.index)] ^= 0xff
+	if err = ioutil.WriteFile(filepath.Join(newPath, dataFileName), corrupted, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = sm.RemapFolder(path, newPath); err == nil {
+		t.Fatalf("remapping to a data file with a corrupted sector should return an error")
+	}
+	if !sm.folders.exist(path) {
+		t.Fatalf("folder shall not be remapped after failure")
+	}
+
+	// the data file with the same content is remapped
+	if err = ioutil.WriteFile(filepath.Join(newPath, dataFileName), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = sm.RemapFolder(path, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if !sm.folders.exist(newPath) {
+		t.Errorf("folder is not remapped to the new path")
+	}
+	_ = os.Remove(filepath.Join(newPath, dataFileName))
+}
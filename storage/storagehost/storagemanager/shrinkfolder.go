@@ -174,7 +174,7 @@ func (update *shrinkFolderUpdate) process(manager *storageManager, target uint8)
 func (update *shrinkFolderUpdate) prepareNormal(manager *storageManager) (err error) {
 	var once sync.Once
 	update.targetFolder.status = folderUnavailable
-	update.targetFolder.numSectors = update.targetNumSectors
+	update.targetFolder.setNumSectors(update.targetNumSectors)
 	update.folders[update.targetFolder.id] = update.targetFolder
 
 	// get all related sectors
@@ -403,7 +403,7 @@ func (update *shrinkFolderUpdate) release(manager *storageManager, upErr *update
 	if newErr == nil && info.Size() != int64(numSectorsToSize(update.prevNumSectors)) {
 		// the folder has been truncated. Only truncate the file to previous size, and
 		// revert the folder db info. The sectors can reside in new locations
-		update.targetFolder.numSectors = update.prevNumSectors
+		update.targetFolder.setNumSectors(update.prevNumSectors)
 		update.targetFolder.usage = expandUsage(update.targetFolder.usage, update.targetNumSectors)
 		newErr = update.targetFolder.dataFile.Truncate(int64(numSectorsToSize(update.targetNumSectors)))
 		err = common.ErrCompose(err, newErr)
@@ -430,7 +430,7 @@ func (update *shrinkFolderUpdate) revert(manager *storageManager, memoryOnly boo
 	var newErr error
 	// first grow the folder to prevSize
 	if update.targetFolder.numSectors != update.prevNumSectors {
-		update.targetFolder.numSectors = update.prevNumSectors
+		update.targetFolder.setNumSectors(update.prevNumSectors)
 		update.targetFolder.usage = expandUsage(update.targetFolder.usage, update.prevNumSectors)
 	}
 	// Then update relocates
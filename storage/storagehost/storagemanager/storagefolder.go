@@ -10,8 +10,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/bits"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync/atomic"
 
 	"github.com/DxChainNetwork/godx/common"
 	"github.com/DxChainNetwork/godx/common/math"
@@ -21,6 +24,14 @@ import (
 
 type (
 	storageFolder struct {
+		// numSectors and storedSectors are written atomically so that the usage could be
+		// read without locks. They are placed first to be 64-bit aligned.
+		// sector is the total number of sector in this folder
+		numSectors uint64
+
+		// StoredSectors is the number of sectors stored in the folder
+		storedSectors uint64
+
 		// id is a uint32 associated with a folder. It is randomly generated
 		// unique key of a folder.
 		id folderID
@@ -36,17 +47,22 @@ type (
 		// represent in decimal, but use as binary
 		usage []bitVector
 
-		// sector is the total number of sector in this folder
-		numSectors uint64
-
-		// StoredSectors is the number of sectors stored in the folder
-		storedSectors uint64
-
 		// folderLock locked the storage folder to prevent racing
 		lock common.TryLock
 
 		// dataFile is the file where all the data sectors locates
 		dataFile *os.File
+
+		// persistedUsage and persistedNumSectors are the usage and the number of sectors
+		// in the folder entry of the database. The usage bit vectors changed since are
+		// written as deltas, which are recorded in usageDeltas by their indexes
+		persistedUsage      []bitVector
+		persistedNumSectors uint64
+		usageDeltas         map[uint64]struct{}
+
+		// usageDeltaSaves is the number of times the usage deltas are written since the
+		// full usage is written
+		usageDeltaSaves int
 	}
 
 	// storageFolderPersist defines the persist data to be stored in database
@@ -154,7 +170,7 @@ func (sf *storageFolder) setFreeSectorSlot(index uint64) (err error) {
 		return
 	}
 	sf.usage[usageIndex].clearUsage(bitIndex)
-	sf.storedSectors--
+	atomic.AddUint64(&sf.storedSectors, ^uint64(0))
 	return
 }
 
@@ -169,10 +185,80 @@ func (sf *storageFolder) setUsedSectorSlot(index uint64) (err error) {
 		return
 	}
 	sf.usage[usageIndex].setUsage(bitIndex)
-	sf.storedSectors++
+	atomic.AddUint64(&sf.storedSectors, 1)
+	return
+}
+
+// usageDeltaIndexes returns the indexes of the usage bit vectors to be written as deltas,
+// which includes the indexes of the deltas already written, since a delta written before
+// might be reverted to the persisted value. If the folder entry in the database shall be
+// rewritten with the full usage instead, ok is false.
+// Note the storage folder must be locked to use this function
+func (sf *storageFolder) usageDeltaIndexes() (indexes []uint64, ok bool) {
+	if sf.persistedUsage == nil || sf.numSectors != sf.persistedNumSectors || len(sf.usage) != len(sf.persistedUsage) {
+		return nil, false
+	}
+	if sf.usageDeltaSaves >= usageCompactionInterval {
+		return nil, false
+	}
+	var changed []uint64
+	for i := range sf.usage {
+		if _, exist := sf.usageDeltas[uint64(i)]; !exist && sf.usage[i] != sf.persistedUsage[i] {
+			changed = append(changed, uint64(i))
+		}
+	}
+	maxDeltas := len(sf.usage) / usageDeltaCompactionRatio
+	if maxDeltas < minUsageDeltaCompaction {
+		maxDeltas = minUsageDeltaCompaction
+	}
+	if len(sf.usageDeltas)+len(changed) > maxDeltas {
+		return nil, false
+	}
+	if sf.usageDeltas == nil {
+		sf.usageDeltas = make(map[uint64]struct{})
+	}
+	for _, index := range changed {
+		sf.usageDeltas[index] = struct{}{}
+	}
+	for index := range sf.usageDeltas {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	sf.usageDeltaSaves++
+	return indexes, true
+}
+
+// setUsagePersisted marks the usage and the number of sectors as written in the folder entry
+// of the database
+// Note the storage folder must be locked to use this function
+func (sf *storageFolder) setUsagePersisted(usage []bitVector, numSectors uint64) {
+	sf.persistedUsage = make([]bitVector, len(usage))
+	copy(sf.persistedUsage, usage)
+	sf.persistedNumSectors = numSectors
+	sf.usageDeltas = make(map[uint64]struct{})
+	sf.usageDeltaSaves = 0
+}
+
+// countStoredSectors returns the number of sectors marked as used in the usage
+func (sf *storageFolder) countStoredSectors() (count uint64) {
+	for _, vec := range sf.usage {
+		count += uint64(bits.OnesCount64(uint64(vec)))
+	}
 	return
 }
 
+// setNumSectors set the total number of sectors of the folder.
+// Note the storage folder must be locked to use this function
+func (sf *storageFolder) setNumSectors(numSectors uint64) {
+	atomic.StoreUint64(&sf.numSectors, numSectors)
+}
+
+// sectorUsage return the total and stored number of sectors in the folder. The numbers
+// are written atomically, thus the function could be called without the folder lock
+func (sf *storageFolder) sectorUsage() (numSectors, storedSectors uint64) {
+	return atomic.LoadUint64(&sf.numSectors), atomic.LoadUint64(&sf.storedSectors)
+}
+
 // sizeToNumSectors convert the size to number of sectors
 func sizeToNumSectors(size uint64) (numSectors uint64) {
 	numSectors = size / storage.SectorSize
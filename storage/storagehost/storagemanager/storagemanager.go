@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
 	"github.com/DxChainNetwork/godx/common/threadmanager"
 	"github.com/DxChainNetwork/godx/common/writeaheadlog"
 	"github.com/DxChainNetwork/godx/log"
@@ -24,6 +25,7 @@ type (
 	StorageManager interface {
 		Start() error
 		Close() error
+		SetSectorReferenced(referenced func(sectorRoot common.Hash) bool)
 		// Functions for download and storage responsibilities
 		AddSectorBatch(sectorRoots []common.Hash) error
 		AddSector(sectorRoot common.Hash, sectorData []byte) error
@@ -34,6 +36,8 @@ type (
 		AddStorageFolder(path string, size uint64) error
 		DeleteFolder(folderPath string) error
 		ResizeFolder(folderPath string, size uint64) error
+		RemapFolder(oldPath string, newPath string) error
+		ValidateFolder(path string, size uint64) error
 		// Status check
 		Folders() []storage.HostFolder
 		AvailableSpace() storage.HostSpace
@@ -64,6 +68,11 @@ type (
 		// sector and then folder
 		lock common.WPLock
 
+		// sectorReferenced checks whether the sector is referenced by the persisted storage
+		// responsibilities. It is used in recovery to decide whether an interrupted physical
+		// sector addition is redone or reverted
+		sectorReferenced func(sectorRoot common.Hash) bool
+
 		// disruptor is used only for test
 		disruptor *disruptor
 	}
@@ -92,6 +101,13 @@ func newStorageManager(persistDir string, d *disruptor) (sm *storageManager, err
 	return
 }
 
+// SetSectorReferenced sets the function checking whether a sector is referenced by the persisted
+// storage responsibilities. The interrupted physical sector additions referenced are redone in
+// recovery, and the others are reverted. Shall be called before Start
+func (sm *storageManager) SetSectorReferenced(referenced func(sectorRoot common.Hash) bool) {
+	sm.sectorReferenced = referenced
+}
+
 // Start start the storage manager
 func (sm *storageManager) Start() (err error) {
 	// generate or get the sector salt. The sector salt is constant across host's lifetime
@@ -111,7 +127,9 @@ func (sm *storageManager) Start() (err error) {
 		return fmt.Errorf("cannot open the wal: %v", err)
 	}
 	// Create goroutines to process unfinished transactions
-	// The txn should be processed in reverse order (all recovered transactions are to be reverted)
+	// The txn should be processed in reverse order (recovered transactions are to be reverted,
+	// except the physical sectors referenced by the storage responsibilities, which are verified
+	// against the merkle root and redone)
 	for i := len(txns) - 1; i >= 0; i-- {
 		// If the module is stopped, return
 		if sm.stopped() {
@@ -123,11 +141,11 @@ func (sm *storageManager) Start() (err error) {
 		// decode the update
 		up, err := decodeFromTransaction(txn)
 		if err != nil {
+			var ctx []interface{}
 			if len(txn.Operations) > 0 {
-				sm.log.Warn("Cannot decode transaction", "update", txn.Operations[0].Name)
-			} else {
-				sm.log.Warn("Cannot decode transaction. wal might be corrupted")
+				ctx = append(ctx, "update", txn.Operations[0].Name)
 			}
+			alert.Raise(sm.log, alert.SeverityCritical, alertModule, "cannot decode the transaction, wal might be corrupted", append(ctx, "err", err)...)
 			continue
 		}
 		// start a thread to process
@@ -248,15 +266,13 @@ func (sm *storageManager) DeleteFolder(folderPath string) (err error) {
 
 // Folders return all used folders
 func (sm *storageManager) Folders() []storage.HostFolder {
-	sm.lock.Lock()
-	defer sm.lock.Unlock()
-
 	var folders []storage.HostFolder
-	for _, sf := range sm.folders.sfs {
+	for _, entry := range sm.folders.folderEntries() {
+		numSectors, storedSectors := entry.sf.sectorUsage()
 		folders = append(folders, storage.HostFolder{
-			Path:         sf.path,
-			TotalSectors: sf.numSectors,
-			UsedSectors:  sf.storedSectors,
+			Path:         entry.path,
+			TotalSectors: numSectors,
+			UsedSectors:  storedSectors,
 		})
 	}
 	return folders
@@ -264,14 +280,15 @@ func (sm *storageManager) Folders() []storage.HostFolder {
 
 // AvailableSpace return the host storage space infos
 func (sm *storageManager) AvailableSpace() storage.HostSpace {
-	sm.lock.Lock()
-	defer sm.lock.Unlock()
-
 	var totalSectors, usedSectors, freeSectors uint64
-	for _, sf := range sm.folders.sfs {
-		totalSectors += sf.numSectors
-		usedSectors += sf.storedSectors
-		freeSectors += sf.numSectors - sf.storedSectors
+	for _, entry := range sm.folders.folderEntries() {
+		numSectors, storedSectors := entry.sf.sectorUsage()
+		totalSectors += numSectors
+		usedSectors += storedSectors
+		// the numbers are loaded separately, thus could be inconsistent during a shrink
+		if numSectors > storedSectors {
+			freeSectors += numSectors - storedSectors
+		}
 	}
 	return storage.HostSpace{
 		TotalSectors: totalSectors,
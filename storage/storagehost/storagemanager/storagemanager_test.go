@@ -14,6 +14,8 @@ import (
 	"time"
 
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/storage"
 )
 
 func init() {
@@ -116,6 +118,52 @@ func TestEmptyStorageManager(t *testing.T) {
 	}
 }
 
+// TestStorageManager_FoldersWithoutLock test that the folder status could be queried
+// while the storage manager and the folders are locked by other operations
+func TestStorageManager_FoldersWithoutLock(t *testing.T) {
+	sm := newTestStorageManager(t, "", newDisruptor())
+	defer sm.shutdown(t, time.Second)
+
+	size := uint64(1 << 25)
+	if err := sm.AddStorageFolder(randomFolderPath(t, ""), size); err != nil {
+		t.Fatal(err)
+	}
+	data := randomBytes(storage.SectorSize)
+	if err := sm.AddSector(merkle.Sha256MerkleTreeRoot(data), data); err != nil {
+		t.Fatal(err)
+	}
+
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	sm.folders.lock.Lock()
+	defer sm.folders.lock.Unlock()
+	for _, entry := range sm.folders.folderEntries() {
+		entry.sf.lock.Lock()
+		defer entry.sf.lock.Unlock()
+	}
+
+	done := make(chan struct{})
+	var folders []storage.HostFolder
+	var space storage.HostSpace
+	go func() {
+		folders, space = sm.Folders(), sm.AvailableSpace()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("the status queries are blocked by the locks")
+	}
+
+	numSectors := sizeToNumSectors(size)
+	if len(folders) != 1 || folders[0].TotalSectors != numSectors || folders[0].UsedSectors != 1 {
+		t.Errorf("folders not expected: %+v", folders)
+	}
+	if space.TotalSectors != numSectors || space.UsedSectors != 1 || space.FreeSectors != numSectors-1 {
+		t.Errorf("available space not expected: %+v", space)
+	}
+}
+
 // randomFolderPath create a random folder path under the testing directory
 func randomFolderPath(t *testing.T, extra string) (path string) {
 	path = filepath.Join(os.TempDir(), "storagemanager", filepath.Join(t.Name()), extra)
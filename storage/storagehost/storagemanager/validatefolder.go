@@ -0,0 +1,75 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagemanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// ValidateFolder checks whether the folder could be added, or resized if the folder already
+// exists, with the size. Nothing is changed by the function
+func (sm *storageManager) ValidateFolder(path string, size uint64) (err error) {
+	if path, err = absolutePath(path); err != nil {
+		return
+	}
+	numSectors := sizeToNumSectors(size)
+	if numSectors < minSectorsPerFolder {
+		return fmt.Errorf("size too small")
+	}
+	if numSectors > maxSectorsPerFolder {
+		return fmt.Errorf("size too large")
+	}
+
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+
+	// the folder to be resized
+	if sf, exist := sm.folders.sfs[path]; exist {
+		if numSectors < sf.numSectors {
+			return sm.folders.validateShrink(path, numSectors)
+		}
+		return checkFreeSpace(path, (numSectors-sf.numSectors)*storage.SectorSize)
+	}
+
+	// the folder to be added
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		return fmt.Errorf("folder already exists: %v", path)
+	}
+	if len(sm.folders.sfs) >= maxNumFolders {
+		return fmt.Errorf("too many folders to manager")
+	}
+	return checkFreeSpace(path, numSectors*storage.SectorSize)
+}
+
+// checkFreeSpace checks whether the disk where the path is located has enough free space.
+// The nearest existing ancestor of the path is used to find the disk
+func checkFreeSpace(path string, size uint64) error {
+	dir := path
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("no existing directory found for %v", path)
+		}
+		dir = parent
+	}
+	free, err := freeDiskSpace(dir)
+	if err == errDiskSpaceUnknown {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot get the free disk space of %v: %v", dir, err)
+	}
+	if free < size {
+		return fmt.Errorf("not enough disk space for %v: %v bytes needed, %v bytes available", path, size, free)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagemanager
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+// TestStorageManager_ValidateFolder test validating the folders to be added or resized
+func TestStorageManager_ValidateFolder(t *testing.T) {
+	sm := newTestStorageManager(t, "", newDisruptor())
+	defer sm.shutdown(t, time.Second)
+
+	path := randomFolderPath(t, "")
+	size := uint64(1 << 26)
+	if err := sm.ValidateFolder(path, size); err != nil {
+		t.Fatalf("validate a new folder: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("the folder should not be created by validation")
+	}
+	if err := sm.AddStorageFolder(path, size); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		size  uint64
+		valid bool
+	}{
+		{path, size * 2, true},
+		{path, size / 2, true},
+		{path, storage.SectorSize, false},
+		{randomFolderPath(t, ""), maxSectorsPerFolder * storage.SectorSize * 2, false},
+		// within the folder size limit, but far more than the disk could offer
+		{randomFolderPath(t, ""), maxSectorsPerFolder * storage.SectorSize, false},
+	}
+	for i, test := range tests {
+		err := sm.ValidateFolder(test.path, test.size)
+		if test.valid != (err == nil) {
+			t.Errorf("test %v: expect valid %v, got error %v", i, test.valid, err)
+		}
+	}
+	if folders := sm.Folders(); len(folders) != 1 || folders[0].TotalSectors != sizeToNumSectors(size) {
+		t.Errorf("the folders should not be changed by validation: %+v", folders)
+	}
+}
@@ -6,11 +6,13 @@ package storagehost
 
 import (
 	"bytes"
+	"fmt"
 	"math/big"
 	"reflect"
 
 	"github.com/DxChainNetwork/godx/accounts"
 	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/alert"
 	"github.com/DxChainNetwork/godx/core/types"
 	"github.com/DxChainNetwork/godx/crypto"
 	"github.com/DxChainNetwork/godx/crypto/merkle"
@@ -167,7 +169,7 @@ func (h *StorageHost) insertStorageResponsibility(so StorageResponsibility) erro
 
 		//Not enough time to submit proof of storage, no need to put in the task force
 		if so.expiration()+postponedExecution >= so.proofDeadline() {
-			h.log.Warn("Not enough time to submit proof of storage")
+			alert.Raise(h.log, alert.SeverityWarning, alertModule, "not enough time to submit the storage proof", "id", so.id())
 			return errNotAllowed
 		}
 
@@ -178,6 +180,7 @@ func (h *StorageHost) insertStorageResponsibility(so StorageResponsibility) erro
 				if err != nil {
 					return err
 				}
+				h.recordSessionProgress(so.id(), uint64(len(so.SectorRoots)))
 			}
 			errPut := putStorageResponsibility(h.db, so.id(), so)
 			if errPut != nil {
@@ -199,6 +202,7 @@ func (h *StorageHost) insertStorageResponsibility(so StorageResponsibility) erro
 		h.financialMetrics.PotentialUploadBandwidthRevenue = h.financialMetrics.PotentialUploadBandwidthRevenue.Add(so.PotentialUploadRevenue)
 		h.financialMetrics.RiskedStorageDeposit = h.financialMetrics.RiskedStorageDeposit.Add(so.RiskedStorageDeposit)
 		h.financialMetrics.TransactionFeeExpenses = h.financialMetrics.TransactionFeeExpenses.Add(so.TransactionFeeExpenses)
+		h.recordMetric(storage.MetricContracts, common.BigInt1)
 
 		return nil
 	}()
@@ -207,6 +211,17 @@ func (h *StorageHost) insertStorageResponsibility(so StorageResponsibility) erro
 	if err != nil {
 		return err
 	}
+	if err = h.queueStorageResponsibilityTasks(so); err != nil {
+		h.log.Warn("Error with task item, redacting responsibility", "id", so.id())
+		return common.ErrCompose(err, h.removeStorageResponsibility(so, responsibilityRejected))
+	}
+
+	return nil
+}
+
+// queueStorageResponsibilityTasks inserts the tasks of the storage responsibility into the task queue
+// Require: lock the storageHost by caller
+func (h *StorageHost) queueStorageResponsibilityTasks(so StorageResponsibility) error {
 	//insert the check  contract create task in the task queue.
 	errContractCreate := h.queueTaskItem(h.blockHeight+postponedExecution, so.id())
 	errContractCreateDoubleTime := h.queueTaskItem(h.blockHeight+postponedExecution*2, so.id())
@@ -215,16 +230,16 @@ func (h *StorageHost) insertStorageResponsibility(so StorageResponsibility) erro
 	errRevision := h.queueTaskItem(so.expiration()-postponedExecutionBuffer, so.id())
 	errRevisionDoubleTime := h.queueTaskItem(so.expiration()-postponedExecutionBuffer+postponedExecution, so.id())
 
+	//insert the sector verification task in the task queue.
+	var errVerification error
+	if so.sectorVerificationHeight() > h.blockHeight {
+		errVerification = h.queueTaskItem(so.sectorVerificationHeight(), so.id())
+	}
+
 	//insert the check proof task in the task queue.
 	errProof := h.queueTaskItem(so.expiration()+postponedExecution, so.id())
 	errProofDoubleTime := h.queueTaskItem(so.expiration()+postponedExecution*2, so.id())
-	err = common.ErrCompose(errContractCreate, errContractCreateDoubleTime, errRevision, errRevisionDoubleTime, errProof, errProofDoubleTime)
-	if err != nil {
-		h.log.Warn("Error with task item, redacting responsibility", "id", so.id())
-		return common.ErrCompose(err, h.removeStorageResponsibility(so, responsibilityRejected))
-	}
-
-	return nil
+	return common.ErrCompose(errContractCreate, errContractCreateDoubleTime, errRevision, errRevisionDoubleTime, errVerification, errProof, errProofDoubleTime)
 }
 
 //the virtual sector will need to appear in 'sectorsRemoved' multiple times. Same with 'sectorsGained'。
@@ -233,11 +248,11 @@ func (h *StorageHost) modifyStorageResponsibility(so StorageResponsibility, sect
 	h.checkAndLockStorageResponsibility(so.id())
 	defer h.checkAndUnlockStorageResponsibility(so.id())
 
-	h.lock.Lock()
-	defer h.lock.Unlock()
-
 	//Need enough time to submit revision
-	if so.expiration()-postponedExecutionBuffer <= h.blockHeight {
+	h.lock.RLock()
+	blockHeight := h.blockHeight
+	h.lock.RUnlock()
+	if so.expiration()-postponedExecutionBuffer <= blockHeight {
 		return errNotAllowed
 	}
 
@@ -255,16 +270,22 @@ func (h *StorageHost) modifyStorageResponsibility(so StorageResponsibility, sect
 		}
 	}
 
+	//The sectors are written without holding h.lock, so that the disk I/O does not block the
+	//other operations of the host such as the storage proof. The storage responsibility is
+	//still locked.
 	var i int
 	var err error
 	for i = range sectorsGained {
-		err = h.AddSector(sectorsGained[i], gainedSectorData[i])
+		err = h.addSector(sectorsGained[i], gainedSectorData[i])
 		//If the adding or update fails,the added sectors should be
 		//removed and the StorageResponsibility should be considered invalid.
 		if err != nil {
 			h.log.Warn("Error writing data to the sector", "err", err)
 			break
 		}
+		h.lock.Lock()
+		h.recordSessionProgress(so.id(), uint64(i+1))
+		h.lock.Unlock()
 	}
 	//This operation is wrong, you need to restore the sector
 	if err != nil {
@@ -275,6 +296,18 @@ func (h *StorageHost) modifyStorageResponsibility(so StorageResponsibility, sect
 		return err
 	}
 
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	//The block height might have changed while the sectors are written
+	if so.expiration()-postponedExecutionBuffer <= h.blockHeight {
+		for i := range sectorsGained {
+			//The error of restoring a sector doesn't make any sense to us.
+			h.DeleteSector(sectorsGained[i])
+		}
+		return errNotAllowed
+	}
+
 	var oldso StorageResponsibility
 	var errOld error
 	errDBso := func() error {
@@ -295,10 +328,9 @@ func (h *StorageHost) modifyStorageResponsibility(so StorageResponsibility, sect
 		}
 		return errDBso
 	}
-	//Delete the deleted sector
-	for k := range sectorsRemoved {
-		//The error of restoring a sector doesn't make any sense to us.
-		h.DeleteSector(sectorsRemoved[k])
+	//The deleted sectors are placed in the delayed deletion queue instead of being removed immediately
+	if err := h.queueSectorDeletion(sectorsRemoved); err != nil {
+		h.log.Warn("failed to queue the sectors for deletion", "err", err)
 	}
 
 	// Update the financial information for the storage responsibility - apply the cost
@@ -328,13 +360,11 @@ func (h *StorageHost) rollbackStorageResponsibility(oldSo StorageResponsibility,
 	h.checkAndLockStorageResponsibility(oldSo.id())
 	defer h.checkAndUnlockStorageResponsibility(oldSo.id())
 
-	h.lock.Lock()
-	defer h.lock.Unlock()
-
+	//The sectors are restored without holding h.lock, the same as modifyStorageResponsibility
 	var i int
 	var err error
 	for i = range sectorsRemoved {
-		err = h.AddSector(sectorsRemoved[i], removedSectorData[i])
+		err = h.addSector(sectorsRemoved[i], removedSectorData[i])
 		//If the adding or update fails,the added sectors should be
 		//removed and the StorageResponsibility should be considered invalid.
 		if err != nil {
@@ -356,6 +386,9 @@ func (h *StorageHost) rollbackStorageResponsibility(oldSo StorageResponsibility,
 		h.DeleteSector(sectorsGained[i])
 	}
 
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
 	var newSo StorageResponsibility
 	var errNew error
 	errDB := func() error {
@@ -398,6 +431,25 @@ func (h *StorageHost) rollbackStorageResponsibility(oldSo StorageResponsibility,
 	return nil
 }
 
+// sectorReferenced checks whether the sector is referenced by any persisted storage
+// responsibility. It is called by the storage manager when recovering the interrupted sector
+// additions, which are few, so the storage responsibilities are scanned for each call
+func (h *StorageHost) sectorReferenced(root common.Hash) bool {
+	sos, err := getStorageResponsibilities(h.db)
+	if err != nil {
+		h.log.Warn("failed to load the storage responsibilities", "err", err)
+		return false
+	}
+	for _, so := range sos {
+		for _, sectorRoot := range so.SectorRoots {
+			if sectorRoot == root {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 //pruneStaleStorageResponsibilities remove stale storage responsibilities because these storage responsibilities will affect the financial metrics of the host
 func (h *StorageHost) pruneStaleStorageResponsibilities() error {
 	h.lock.RLock()
@@ -467,6 +519,8 @@ func (h *StorageHost) removeStorageResponsibility(so StorageResponsibility, sos
 		h.financialMetrics.StorageRevenue = h.financialMetrics.StorageRevenue.Add(so.PotentialStorageRevenue)
 		h.financialMetrics.DownloadBandwidthRevenue = h.financialMetrics.DownloadBandwidthRevenue.Add(so.PotentialDownloadRevenue)
 		h.financialMetrics.UploadBandwidthRevenue = h.financialMetrics.UploadBandwidthRevenue.Add(so.PotentialUploadRevenue)
+		// The revenue is recorded net of the gas spent on the storage proof
+		h.recordMetric(storage.MetricRevenue, revenue.Sub(so.TransactionFeeExpenses))
 
 	case responsibilityFailed:
 		// Remove the responsibility statistics as potential risk and income.
@@ -488,6 +542,8 @@ func (h *StorageHost) removeStorageResponsibility(so StorageResponsibility, sos
 	h.financialMetrics.ContractCount--
 	so.ResponsibilityStatus = sos
 	so.SectorRoots = []common.Hash{}
+	h.sectorRoots.remove(so.id())
+	delete(h.proofSubmissions, so.id())
 	return putStorageResponsibility(h.db, so.id(), so)
 }
 
@@ -525,6 +581,8 @@ func (h *StorageHost) resetFinancialMetrics() error {
 		}
 	}
 
+	// The announcement fees are not related to any storage responsibility
+	fm.AnnouncementFeeExpenses = h.financialMetrics.AnnouncementFeeExpenses
 	h.financialMetrics = fm
 	return nil
 }
@@ -594,11 +652,16 @@ func (h *StorageHost) handleTaskItem(soid common.Hash) {
 
 		//The host sends a revision transaction to the transaction pool.
 		if _, err := h.sendStorageContractRevisionTx(scrv.NewValidProofOutputs[1].Address, scBytes); err != nil {
-			h.log.Warn("Error sending a revision transaction", "err", err)
+			alert.Raise(h.log, alert.SeverityError, alertModule, "failed to send the storage contract revision transaction", "id", so.id(), "err", err)
 			return
 		}
 	}
 
+	//Verify the sectors shortly before the proof window, so that the disk errors are found in advance.
+	if !so.StorageProofConfirmed && len(so.SectorRoots) != 0 && so.inSectorVerificationPeriod(h.blockHeight) {
+		h.verifyResponsibilitySectors(so)
+	}
+
 	//If revision meets the condition, a proof transaction will be submitted.
 	if !so.StorageProofConfirmed && h.blockHeight >= so.expiration()+postponedExecution {
 		if len(so.SectorRoots) == 0 {
@@ -619,70 +682,8 @@ func (h *StorageHost) handleTaskItem(soid common.Hash) {
 			return
 		}
 
-		//The storage host side gets the index of the data containing the segment
-		scrv := so.StorageContractRevisions[len(so.StorageContractRevisions)-1]
-		segmentIndex, err := h.storageProofSegment(scrv)
-		if err != nil {
-			h.log.Warn("An error occurred while getting the storage certificate from the storage host", "err", err)
-			return
-		}
-
-		sectorIndex := segmentIndex / (storage.SectorSize / merkle.LeafSize)
-		sectorRoot := so.SectorRoots[sectorIndex]
-		sectorBytes, err := h.ReadSector(sectorRoot)
-		//No content can be read from the memory, indicating that the storage host is not storing.
-		if err != nil {
-			h.log.Warn("the storage host is not storing", "err", err)
-			return
-		}
-
-		//Build a storage certificate for this storage contract
-		sectorSegment := segmentIndex % (storage.SectorSize / merkle.LeafSize)
-		base, cachedHashSet := merkleProof(sectorBytes, sectorSegment)
-		// Using the sector, build a cached root.
-		log2SectorSize := uint64(0)
-		for 1<<log2SectorSize < (storage.SectorSize / merkle.LeafSize) {
-			log2SectorSize++
-		}
-		ct := merkle.NewSha256CachedTree(log2SectorSize)
-		err = ct.SetStorageProofIndex(segmentIndex)
-		if err != nil {
-			h.log.Warn("cannot call SetIndex on Tree ", "err", err)
-		}
-		for _, root := range so.SectorRoots {
-			ct.Push(root)
-		}
-		hashSet := ct.Prove(base, cachedHashSet)
-		sp := types.StorageProof{
-			ParentID: so.id(),
-			HashSet:  hashSet,
-		}
-		copy(sp.Segment[:], base)
-
-		//Here take the address of the storage host in the storage contract book
-		fromAddress := so.OriginStorageContract.ValidProofOutputs[1].Address
-		account := accounts.Account{Address: fromAddress}
-		wallet, err := h.am.Find(account)
-		if err != nil {
-			h.log.Warn("There was an error opening the wallet", "err", err)
-			return
-		}
-		spSign, err := wallet.SignHash(account, sp.RLPHash().Bytes())
-		if err != nil {
-			h.log.Warn("Error when sign data", "err", err)
-			return
-		}
-		sp.Signature = spSign
-
-		spBytes, err := rlp.EncodeToBytes(sp)
-		if err != nil {
-			h.log.Warn("Error when serializing proof", "err", err)
-			return
-		}
-
-		//The host sends a storage proof transaction to the transaction pool.
-		if _, err := h.sendStorageProofTx(fromAddress, spBytes); err != nil {
-			h.log.Warn("Error sending a storage proof transaction", "err", err)
+		if _, err := h.submitStorageProof(so); err != nil {
+			h.log.Warn("cannot submit the storage proof", "id", so.id().String(), "err", err)
 			return
 		}
 
@@ -709,6 +710,76 @@ func (h *StorageHost) handleTaskItem(soid common.Hash) {
 
 }
 
+// submitStorageProof constructs the storage proof of the storage responsibility, and sends the
+// storage proof transaction. The caller must hold the lock of the storage responsibility and h.lock.
+func (h *StorageHost) submitStorageProof(so StorageResponsibility) (common.Hash, error) {
+	//The storage host side gets the index of the data containing the segment
+	scrv := so.StorageContractRevisions[len(so.StorageContractRevisions)-1]
+	segmentIndex, err := h.storageProofSegment(scrv)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("cannot get the segment index to prove: %v", err)
+	}
+
+	sectorIndex := segmentIndex / (storage.SectorSize / merkle.LeafSize)
+	sectorRoot := so.SectorRoots[sectorIndex]
+	sectorBytes, err := h.readSector(sectorRoot, ioProof, h.proofReadUrgent(so))
+	//No content can be read from the memory, indicating that the storage host is not storing.
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("the storage host is not storing the sector %x: %v", sectorRoot, err)
+	}
+
+	//Build a storage certificate for this storage contract
+	sectorSegment := segmentIndex % (storage.SectorSize / merkle.LeafSize)
+	base, cachedHashSet := merkleProof(sectorBytes, sectorSegment)
+	// Using the sector, build a cached root.
+	log2SectorSize := uint64(0)
+	for 1<<log2SectorSize < (storage.SectorSize / merkle.LeafSize) {
+		log2SectorSize++
+	}
+	ct := merkle.NewSha256CachedTree(log2SectorSize)
+	err = ct.SetStorageProofIndex(segmentIndex)
+	if err != nil {
+		h.log.Warn("cannot call SetIndex on Tree ", "err", err)
+	}
+	if err = ct.PushRoots(h.ctx, so.SectorRoots); err != nil {
+		return common.Hash{}, fmt.Errorf("storage proof construction interrupted: %v", err)
+	}
+	hashSet := ct.Prove(base, cachedHashSet)
+	sp := types.StorageProof{
+		ParentID: so.id(),
+		HashSet:  hashSet,
+	}
+	copy(sp.Segment[:], base)
+
+	//Here take the address of the storage host in the storage contract book
+	fromAddress := so.OriginStorageContract.ValidProofOutputs[1].Address
+	account := accounts.Account{Address: fromAddress}
+	wallet, err := h.am.Find(account)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("there was an error opening the wallet: %v", err)
+	}
+	spSign, err := wallet.SignHash(account, sp.RLPHash().Bytes())
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error when sign data: %v", err)
+	}
+	sp.Signature = spSign
+
+	spBytes, err := rlp.EncodeToBytes(sp)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error when serializing proof: %v", err)
+	}
+
+	//The host sends a storage proof transaction to the transaction pool.
+	txHash, err := h.sendStorageProofTx(fromAddress, spBytes)
+	if err != nil {
+		alert.Raise(h.log, alert.SeverityCritical, alertModule, "failed to send the storage proof transaction", "id", so.id(), "err", err)
+		return common.Hash{}, err
+	}
+	h.recordMetric(storage.MetricStorageProofs, common.BigInt1)
+	h.proofSubmissions[so.id()] = h.blockHeight
+	return txHash, nil
+}
+
 //merkleProof get the storage proof
 func merkleProof(b []byte, proofIndex uint64) (base []byte, hashSet []common.Hash) {
 	t := merkle.NewSha256MerkleTree()
@@ -5,12 +5,18 @@
 package storagehost
 
 import (
-	"github.com/DxChainNetwork/godx/common"
-	"github.com/DxChainNetwork/godx/core/types"
-	"github.com/DxChainNetwork/godx/ethdb"
+	"crypto/rand"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/ethdb"
+	"github.com/DxChainNetwork/godx/storage"
 )
 
 func TestStoreStorageResponsibility(t *testing.T) {
@@ -82,6 +88,87 @@ func TestFinalizeAndRollbackStorageResponsibility(t *testing.T) {
 	}
 }
 
+// TestModifyStorageResponsibility_Concurrent test that the host lock is not held while the
+// sectors are written, so that the other operations such as the storage proof are not blocked
+func TestModifyStorageResponsibility_Concurrent(t *testing.T) {
+	h := newTestStorageHost(t)
+	if err := h.StorageManager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		h.StorageManager.Close()
+		h.db.Close()
+	}()
+	if err := h.AddStorageFolder(filepath.Join(h.persistDir, "folder"), 1<<25); err != nil {
+		t.Fatal(err)
+	}
+
+	so := StorageResponsibility{
+		OriginStorageContract: types.StorageContract{
+			WindowStart:    1000000,
+			RevisionNumber: 1,
+			WindowEnd:      1440000,
+		},
+	}
+	if err := putStorageResponsibility(h.db, so.id(), so); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, storage.SectorSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	root := merkle.Sha256MerkleTreeRoot(data)
+	so.SectorRoots = []common.Hash{root}
+
+	// take all io slots, so that the sector write blocks
+	for i := 0; i != ioConcurrency; i++ {
+		h.io.acquire(ioProof, false)
+	}
+	modified := make(chan error)
+	go func() {
+		modified <- h.modifyStorageResponsibility(so, nil, []common.Hash{root}, [][]byte{data})
+	}()
+	for {
+		h.io.lock.Lock()
+		waiting := len(h.io.waiting)
+		h.io.lock.Unlock()
+		if waiting == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// the host lock can be acquired while the sector is being written
+	locked := make(chan struct{})
+	go func() {
+		h.lock.Lock()
+		h.lock.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the host lock is held while the sector is written")
+	}
+
+	for i := 0; i != ioConcurrency; i++ {
+		h.io.release()
+	}
+	if err := <-modified; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.ReadSector(root); err != nil {
+		t.Errorf("the sector gained is not stored: %v", err)
+	}
+	persisted, err := getStorageResponsibility(h.db, so.id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(persisted.SectorRoots) != 1 || persisted.SectorRoots[0] != root {
+		t.Errorf("the storage responsibility is not updated: %v", persisted.SectorRoots)
+	}
+}
+
 func TestStoreHeight(t *testing.T) {
 	db := ethdb.NewMemDatabase()
 	var height uint64
@@ -157,4 +244,31 @@ func TestStoreHeight(t *testing.T) {
 			t.Error("DB persistence error")
 		}
 	}
+
+	// the task of the same storage contract is not queued twice
+	if err = storeHeight(db, sc1.RLPHash(), height); err != nil {
+		t.Error(err)
+	}
+	if data, err = getHeight(db, height); err != nil || len(data) != 96 {
+		t.Errorf("duplicate task is queued: %v, %v", len(data), err)
+	}
+}
+
+func TestStorageHost_SectorReferenced(t *testing.T) {
+	h := newTestStorageHost(t)
+	defer h.db.Close()
+
+	so := StorageResponsibility{
+		OriginStorageContract: types.StorageContract{WindowStart: 1000000, WindowEnd: 1440000},
+		SectorRoots:           []common.Hash{{1}, {2}},
+	}
+	if err := putStorageResponsibility(h.db, so.id(), so); err != nil {
+		t.Fatal(err)
+	}
+	if !h.sectorReferenced(common.Hash{2}) {
+		t.Errorf("the sector of the storage responsibility should be referenced")
+	}
+	if h.sectorReferenced(common.Hash{3}) {
+		t.Errorf("the sector not in any storage responsibility should not be referenced")
+	}
 }
@@ -0,0 +1,176 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/common/unit"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/core/vm"
+	"github.com/DxChainNetwork/godx/rlp"
+)
+
+type (
+	// storageTxFees is the gas spent on the storage proof and host announcement transactions
+	// in a block
+	storageTxFees struct {
+		// proofs is keyed by the id of the storage responsibility proved
+		proofs       map[common.Hash]common.BigInt
+		announcement common.BigInt
+	}
+
+	// ContractMargin is the net margin of the storage responsibility after the transaction fees
+	// spent on it, along with the storage deposit at risk
+	ContractMargin struct {
+		ContractID      string `json:"contractid"`
+		Status          string `json:"status"`
+		Expiration      uint64 `json:"expiration"`
+		Revenue         string `json:"revenue"`
+		TransactionFees string `json:"transactionfees"`
+		NetMargin       string `json:"netmargin"`
+		RiskedDeposit   string `json:"riskeddeposit"`
+	}
+)
+
+// getStorageTxFeesWithBlockHash calculates the gas spent in the block with the receipts of
+// the transactions. The announcements are regarded as sent by the host if they are signed
+// by the payment address
+func (h *StorageHost) getStorageTxFeesWithBlockHash(blockHash common.Hash) (storageTxFees, error) {
+	block, err := h.ethBackend.GetBlockByHash(blockHash)
+	if err != nil {
+		return storageTxFees{}, err
+	}
+	if len(block.Transactions()) == 0 {
+		return storageTxFees{}, nil
+	}
+	chain := h.ethBackend.GetBlockChain()
+	receipts := chain.GetReceiptsByHash(blockHash)
+	signer := types.MakeSigner(chain.Config(), block.Number())
+	return calculateStorageTxFees(block.Transactions(), receipts, signer, h.config.PaymentAddress)
+}
+
+// calculateStorageTxFees calculates the fees of the storage proof and host announcement
+// transactions, which is the gas used in the receipt multiplied by the gas price
+func calculateStorageTxFees(txs types.Transactions, receipts types.Receipts, signer types.Signer, paymentAddress common.Address) (storageTxFees, error) {
+	if len(txs) != len(receipts) {
+		return storageTxFees{}, fmt.Errorf("%v receipts found for %v transactions", len(receipts), len(txs))
+	}
+	fees := storageTxFees{proofs: make(map[common.Hash]common.BigInt)}
+	for i, tx := range txs {
+		if tx.To() == nil {
+			continue
+		}
+		fee := common.PtrBigInt(new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), tx.GasPrice()))
+		switch vm.PrecompiledEVMFileContracts[*tx.To()] {
+		case vm.StorageProofTransaction:
+			var sp types.StorageProof
+			if err := rlp.DecodeBytes(tx.Data(), &sp); err != nil {
+				continue
+			}
+			fees.proofs[sp.ParentID] = fees.proofs[sp.ParentID].Add(fee)
+		case vm.HostAnnounceTransaction:
+			if paymentAddress == (common.Address{}) {
+				continue
+			}
+			if from, err := types.Sender(signer, tx); err != nil || from != paymentAddress {
+				continue
+			}
+			fees.announcement = fees.announcement.Add(fee)
+		}
+	}
+	return fees, nil
+}
+
+// applyStorageProofFee adds the fee of the storage proof transaction to the transaction fee
+// expenses of the storage responsibility and the financial metrics. The fee is subtracted
+// when the block containing the storage proof is reverted
+func (h *StorageHost) applyStorageProofFee(so *StorageResponsibility, fee common.BigInt, reverted bool) {
+	if fee.IsEqual(common.BigInt0) {
+		return
+	}
+	if !reverted {
+		so.TransactionFeeExpenses = so.TransactionFeeExpenses.Add(fee)
+		h.financialMetrics.TransactionFeeExpenses = h.financialMetrics.TransactionFeeExpenses.Add(fee)
+		return
+	}
+	if so.TransactionFeeExpenses.Cmp(fee) < 0 {
+		fee = so.TransactionFeeExpenses
+	}
+	so.TransactionFeeExpenses = so.TransactionFeeExpenses.Sub(fee)
+	h.financialMetrics.TransactionFeeExpenses = h.financialMetrics.TransactionFeeExpenses.Sub(fee)
+}
+
+// applyAnnouncementFee adds the fee of the host announcements to the financial metrics, or
+// subtracts it when the block is reverted
+func (h *StorageHost) applyAnnouncementFee(fee common.BigInt, reverted bool) {
+	if !reverted {
+		h.financialMetrics.AnnouncementFeeExpenses = h.financialMetrics.AnnouncementFeeExpenses.Add(fee)
+		return
+	}
+	if h.financialMetrics.AnnouncementFeeExpenses.Cmp(fee) < 0 {
+		fee = h.financialMetrics.AnnouncementFeeExpenses
+	}
+	h.financialMetrics.AnnouncementFeeExpenses = h.financialMetrics.AnnouncementFeeExpenses.Sub(fee)
+}
+
+// revenue returns the revenue of the storage responsibility once the storage proof succeeds
+func (so StorageResponsibility) revenue() common.BigInt {
+	return so.ContractCost.Add(so.PotentialStorageRevenue).Add(so.PotentialDownloadRevenue).Add(so.PotentialUploadRevenue)
+}
+
+// netRevenue returns the revenue earned from the resolved storage responsibilities after the
+// gas spent on the storage proofs and host announcements
+func (fm HostFinancialMetrics) netRevenue() common.BigInt {
+	earned := fm.ContractCompensation.Add(fm.StorageRevenue).Add(fm.DownloadBandwidthRevenue).Add(fm.UploadBandwidthRevenue)
+	return earned.Sub(fm.TransactionFeeExpenses).Sub(fm.AnnouncementFeeExpenses)
+}
+
+// contractMargins returns the net margins of all storage responsibilities in the host, sorted
+// from the lowest margin, so that the unprofitable contracts are listed first
+func (h *StorageHost) contractMargins() ([]ContractMargin, error) {
+	h.lock.RLock()
+	sos, err := getStorageResponsibilities(h.db)
+	h.lock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	margins := make([]common.BigInt, len(sos))
+	for i, so := range sos {
+		margins[i] = so.revenue().Sub(so.TransactionFeeExpenses)
+	}
+	sort.Sort(byMargin{sos: sos, margins: margins})
+
+	res := make([]ContractMargin, 0, len(sos))
+	for i, so := range sos {
+		res = append(res, ContractMargin{
+			ContractID:      so.id().String(),
+			Status:          so.ResponsibilityStatus.String(),
+			Expiration:      so.expiration(),
+			Revenue:         unit.FormatCurrency(so.revenue()),
+			TransactionFees: unit.FormatCurrency(so.TransactionFeeExpenses),
+			NetMargin:       unit.FormatCurrency(margins[i]),
+			RiskedDeposit:   unit.FormatCurrency(so.RiskedStorageDeposit),
+		})
+	}
+	return res, nil
+}
+
+// byMargin sorts the storage responsibilities by the net margin
+type byMargin struct {
+	sos     []StorageResponsibility
+	margins []common.BigInt
+}
+
+func (b byMargin) Len() int           { return len(b.sos) }
+func (b byMargin) Less(i, j int) bool { return b.margins[i].Cmp(b.margins[j]) < 0 }
+func (b byMargin) Swap(i, j int) {
+	b.sos[i], b.sos[j] = b.sos[j], b.sos[i]
+	b.margins[i], b.margins[j] = b.margins[j], b.margins[i]
+}
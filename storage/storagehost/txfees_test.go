@@ -0,0 +1,104 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/rlp"
+)
+
+func TestCalculateStorageTxFees(t *testing.T) {
+	hostKey, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	paymentAddress := crypto.PubkeyToAddress(hostKey.PublicKey)
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	proofID := common.HexToHash("0x01")
+	proof, err := rlp.EncodeToBytes(types.StorageProof{ParentID: proofID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proofAddress := common.BytesToAddress([]byte{12})
+	announceAddress := common.BytesToAddress([]byte{9})
+
+	var txs types.Transactions
+	var receipts types.Receipts
+	addTx := func(to common.Address, data []byte, key *ecdsa.PrivateKey, gasUsed uint64) {
+		tx := types.NewTransaction(uint64(len(txs)), to, big.NewInt(0), 100000, big.NewInt(10), data)
+		signed, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, signed)
+		receipts = append(receipts, &types.Receipt{GasUsed: gasUsed})
+	}
+	addTx(proofAddress, proof, hostKey, 100)
+	addTx(proofAddress, proof, hostKey, 50)
+	addTx(announceAddress, nil, hostKey, 20)
+	addTx(announceAddress, nil, otherKey, 1000)
+	addTx(common.HexToAddress("0xabcd"), nil, hostKey, 1000)
+
+	fees, err := calculateStorageTxFees(txs, receipts, signer, paymentAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fees.proofs) != 1 || !fees.proofs[proofID].IsEqual(common.NewBigInt(1500)) {
+		t.Errorf("unexpected proof fees %v", fees.proofs)
+	}
+	if !fees.announcement.IsEqual(common.NewBigInt(200)) {
+		t.Errorf("announcement fee expect 200, got %v", fees.announcement)
+	}
+
+	if _, err = calculateStorageTxFees(txs, receipts[1:], signer, paymentAddress); err == nil {
+		t.Errorf("mismatched receipts should fail")
+	}
+}
+
+func TestStorageHost_ContractMargins(t *testing.T) {
+	h := newTestStorageHost(t)
+	defer h.db.Close()
+
+	// the proof fee is applied to both the responsibility and the financial metrics
+	costs := []int64{100, 500}
+	var sos []StorageResponsibility
+	for i, cost := range costs {
+		so := StorageResponsibility{
+			ContractCost:          common.NewBigInt(cost),
+			OriginStorageContract: types.StorageContract{RevisionNumber: uint64(i), WindowEnd: 300},
+		}
+		h.applyStorageProofFee(&so, common.NewBigInt(150), false)
+		if err := putStorageResponsibility(h.db, so.id(), so); err != nil {
+			t.Fatal(err)
+		}
+		sos = append(sos, so)
+	}
+	if !h.financialMetrics.TransactionFeeExpenses.IsEqual(common.NewBigInt(300)) {
+		t.Errorf("transaction fee expenses expect 300, got %v", h.financialMetrics.TransactionFeeExpenses)
+	}
+	h.applyStorageProofFee(&sos[1], common.NewBigInt(150), true)
+	if !sos[1].TransactionFeeExpenses.IsEqual(common.BigInt0) || !h.financialMetrics.TransactionFeeExpenses.IsEqual(common.NewBigInt(150)) {
+		t.Errorf("the reverted proof fee should be subtracted")
+	}
+
+	margins, err := h.contractMargins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(margins) != 2 || margins[0].ContractID != sos[0].id().String() || margins[0].NetMargin != "-50 Camel" {
+		t.Errorf("the contract with the negative margin should be listed first, got %+v", margins)
+	}
+
+	h.financialMetrics.ContractCompensation = common.NewBigInt(1000)
+	h.applyAnnouncementFee(common.NewBigInt(100), false)
+	if net := h.financialMetrics.netRevenue(); !net.IsEqual(common.NewBigInt(750)) {
+		t.Errorf("net revenue expect 750, got %v", net)
+	}
+}
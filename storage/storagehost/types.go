@@ -18,6 +18,18 @@ var (
 	// missed proof outputs.
 	errBadContractOutputCounts = ErrorRevision("responsibilityRejected for having an unexpected number of outputs")
 
+	// errBadProofValueCounts is returned if the number of the new valid or missed
+	// proof values in the request does not match the outputs of the current revision.
+	errBadProofValueCounts = ErrorRevision("responsibilityRejected for having an unexpected number of proof values")
+
+	// errInvalidProofValue is returned if a proof value or output presented by the
+	// client is missing or negative.
+	errInvalidProofValue = ErrorRevision("responsibilityRejected for missing or negative proof value")
+
+	// errInvalidCollateral is returned if the collateral of the proposed storage
+	// contract is missing or negative.
+	errInvalidCollateral = ErrorRevision("responsibilityRejected for missing or negative collateral")
+
 	// errBadContractParent is returned when a file contract revision is
 	// presented which has a parent id that doesn't match the file contract
 	// which is supposed to be getting revised.
@@ -146,6 +158,7 @@ type (
 		RiskedStorageDeposit              common.BigInt `json:"riskedstoragedeposit"`
 		StorageRevenue                    common.BigInt `json:"storagerevenue"`
 		TransactionFeeExpenses            common.BigInt `json:"transactionfeeexpenses"`
+		AnnouncementFeeExpenses           common.BigInt `json:"announcementfeeexpenses"`
 		DownloadBandwidthRevenue          common.BigInt `json:"downloadbandwidthrevenue"`
 		PotentialDownloadBandwidthRevenue common.BigInt `json:"potentialdownloadbandwidthrevenue"`
 		PotentialUploadBandwidthRevenue   common.BigInt `json:"potentialuploadbandwidthrevenue"`
@@ -170,6 +183,8 @@ type (
 		RiskedStorageDeposit              string `json:"riskedstoragedeposit"`
 		StorageRevenue                    string `json:"storagerevenue"`
 		TransactionFeeExpenses            string `json:"transactionfeeexpenses"`
+		AnnouncementFeeExpenses           string `json:"announcementfeeexpenses"`
+		NetRevenue                        string `json:"netrevenue"`
 		DownloadBandwidthRevenue          string `json:"downloadbandwidthrevenue"`
 		PotentialDownloadBandwidthRevenue string `json:"potentialdownloadbandwidthrevenue"`
 		PotentialUploadBandwidthRevenue   string `json:"potentialuploadbandwidthrevenue"`
@@ -5,6 +5,7 @@
 package storagehost
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -31,10 +32,61 @@ func UploadHandler(h *StorageHost, sp storage.Peer, uploadReqMsg p2p.Msg) {
 		}
 	}()
 
-	// Read upload request
-	var uploadRequest storage.UploadRequest
-	if err := uploadReqMsg.Decode(&uploadRequest); err != nil {
+	// the retiring host keeps the stored data for downloads, but accepts no more
+	if h.isRetiring() {
+		hostNegotiateErr = errHostRetiring
+		return
+	}
+
+	settings := h.externalConfig()
+
+	// account the request buffered and received, which waits if the host is busy with the
+	// other negotiations
+	budget := h.beginNegotiation(negotiationUpload, sp)
+	defer budget.done()
+	if err := budget.admitReceived(uint64(uploadReqMsg.Size)); err != nil {
+		hostNegotiateErr = err
+		return
+	}
+
+	// Read upload request. The actions are processed as they are decoded from the message,
+	// and the data is bounded by the max revise batch size
+	var sectorsGained []common.Hash
+	var gainedSectorData [][]byte
+	var removeActions []storage.UploadAction
+	var actionErr error
+	uploadRequest, err := storage.DecodeUploadRequest(uploadReqMsg.Payload, uint64(uploadReqMsg.Size), settings.MaxReviseBatchSize, func(action storage.UploadAction) error {
+		switch action.Type {
+		case storage.UploadActionAppend:
+		case storage.UploadActionSwap, storage.UploadActionTrim:
+			removeActions = append(removeActions, action)
+		default:
+			actionErr = fmt.Errorf("unknown upload action type: %s", action.Type)
+			return actionErr
+		}
+		if len(removeActions) != 0 && len(sectorsGained) != 0 {
+			actionErr = errors.New("sectors could not be appended and removed in the same upload request")
+			return actionErr
+		}
+		if action.Type != storage.UploadActionAppend {
+			return nil
+		}
+		// exceeding the batch limit is the host policy, not a malformed request
+		if settings.MaxUploadBatchSectors != 0 && uint64(len(sectorsGained)) >= settings.MaxUploadBatchSectors {
+			actionErr = fmt.Errorf("upload request exceeds the max batch of %v sectors", settings.MaxUploadBatchSectors)
+			return actionErr
+		}
+		sectorsGained = append(sectorsGained, merkle.Sha256MerkleTreeRoot(action.Data))
+		gainedSectorData = append(gainedSectorData, action.Data)
+		return nil
+	})
+	if actionErr != nil {
+		hostNegotiateErr = actionErr
+		return
+	}
+	if err != nil {
 		clientNegotiateErr = fmt.Errorf("failed to decode the upload request message: %s", err.Error())
+		h.recordClientAbuse(sp, abuseMalformedMsg)
 		return
 	}
 
@@ -50,78 +102,79 @@ func UploadHandler(h *StorageHost, sp storage.Peer, uploadReqMsg p2p.Msg) {
 		return
 	}
 
-	settings := h.externalConfig()
 	currentBlockHeight := h.blockHeight
 	currentRevision := so.StorageContractRevisions[len(so.StorageContractRevisions)-1]
 
-	// Process each action
+	// Validate the proof values before constructing the new revision from them
+	if err := validateProofValues(currentRevision, uploadRequest.NewValidProofValues, uploadRequest.NewMissedProofValues); err != nil {
+		hostNegotiateErr = fmt.Errorf("upload request validation failed: %s", err.Error())
+		h.recordClientAbuse(sp, abuseMalformedMsg)
+		return
+	}
+
+	// Append the sectors gained
 	newRoots := append([]common.Hash(nil), so.SectorRoots...)
 	sectorsChanged := make(map[uint64]struct{})
+	for _, root := range sectorsGained {
+		newRoots = append(newRoots, root)
+		sectorsChanged[uint64(len(newRoots))-1] = struct{}{}
+	}
 
-	var bandwidthRevenue common.BigInt
-	var sectorsGained []common.Hash
-	var gainedSectorData [][]byte
-	for _, action := range uploadRequest.Actions {
-		switch action.Type {
-		case storage.UploadActionAppend:
-			// Update sector roots.
-			newRoot := merkle.Sha256MerkleTreeRoot(action.Data)
-			newRoots = append(newRoots, newRoot)
-			sectorsGained = append(sectorsGained, newRoot)
-			gainedSectorData = append(gainedSectorData, action.Data)
-
-			sectorsChanged[uint64(len(newRoots))-1] = struct{}{}
-
-			// Update finances
-			bandwidthRevenue = bandwidthRevenue.Add(settings.UploadBandwidthPrice.MultUint64(storage.SectorSize))
-		default:
-			hostNegotiateErr = fmt.Errorf("unknown upload action type: %s", action.Type)
-		}
+	// Remove the sectors swapped to the end and trimmed
+	newRoots, sectorsRemoved, err := removeSectors(newRoots, removeActions, sectorsChanged)
+	if err != nil {
+		hostNegotiateErr = fmt.Errorf("upload request validation failed: %s", err.Error())
+		return
 	}
 
+	// Update finances
+	bandwidthRevenue := settings.UploadBandwidthPrice.MultUint64(storage.SectorSize * uint64(len(sectorsGained)))
+
 	//var storageRevenue, newDeposit *big.Int
-	var storageRevenue, newDeposit common.BigInt
+	var storageRevenue, newDeposit, releasedDeposit common.BigInt
 
+	blocksRemaining := so.proofDeadline() - currentBlockHeight
 	if len(newRoots) > len(so.SectorRoots) {
 		bytesAdded := storage.SectorSize * uint64(len(newRoots)-len(so.SectorRoots))
-		blocksRemaining := so.proofDeadline() - currentBlockHeight
 		blockBytesCurrency := common.NewBigIntUint64(blocksRemaining).Mult(common.NewBigIntUint64(bytesAdded))
 		storageRevenue = blockBytesCurrency.Mult(settings.StoragePrice)
 		newDeposit = newDeposit.Add(blockBytesCurrency.Mult(settings.Deposit))
+	} else if len(sectorsRemoved) != 0 {
+		// the deposit risked for the removed sectors is no longer at risk. The storage
+		// revenue already paid is kept by the host
+		bytesRemoved := storage.SectorSize * uint64(len(sectorsRemoved))
+		blockBytesCurrency := common.NewBigIntUint64(blocksRemaining).Mult(common.NewBigIntUint64(bytesRemoved))
+		releasedDeposit = blockBytesCurrency.Mult(settings.Deposit)
+		if releasedDeposit.Cmp(so.RiskedStorageDeposit) > 0 {
+			releasedDeposit = so.RiskedStorageDeposit
+		}
+	}
+
+	// The cached sub trees are only validated by their last sector roots, and the sectors
+	// swapped into them are not detected. Drop the cache, so that the merkle root is
+	// calculated from all sector roots
+	if len(removeActions) != 0 {
+		h.sectorRoots.remove(so.id())
 	}
 
 	// If a Merkle proof was requested, construct it
-	newMerkleRoot := merkle.Sha256CachedTreeRoot2(newRoots)
+	newMerkleRoot, err := h.sectorRoots.root(h.ctx, so.id(), newRoots)
+	if err != nil {
+		hostNegotiateErr = fmt.Errorf("failed to calculate the new merkle root: %s", err.Error())
+		return
+	}
 
 	// Construct the new revision
-	newRevision := currentRevision
+	newRevision := revisionWithProofValues(currentRevision, uploadRequest.NewValidProofValues, uploadRequest.NewMissedProofValues)
 	newRevision.NewRevisionNumber = uploadRequest.NewRevisionNumber
-	for _, action := range uploadRequest.Actions {
-		if action.Type == storage.UploadActionAppend {
-			newRevision.NewFileSize += storage.SectorSize
-		}
-	}
+	newRevision.NewFileSize = storage.SectorSize * uint64(len(newRoots))
 	newRevision.NewFileMerkleRoot = newMerkleRoot
-	newRevision.NewValidProofOutputs = make([]types.DxcoinCharge, len(currentRevision.NewValidProofOutputs))
-	for i := range newRevision.NewValidProofOutputs {
-		newRevision.NewValidProofOutputs[i] = types.DxcoinCharge{
-			Value:   uploadRequest.NewValidProofValues[i],
-			Address: currentRevision.NewValidProofOutputs[i].Address,
-		}
-	}
-	newRevision.NewMissedProofOutputs = make([]types.DxcoinCharge, len(currentRevision.NewMissedProofOutputs))
-	for i := range newRevision.NewMissedProofOutputs {
-		newRevision.NewMissedProofOutputs[i] = types.DxcoinCharge{
-			Value:   uploadRequest.NewMissedProofValues[i],
-			Address: currentRevision.NewMissedProofOutputs[i].Address,
-		}
-	}
 
 	// Verify the new revision
 	newRevenue := storageRevenue.Add(bandwidthRevenue).Add(settings.BaseRPCPrice)
 
 	so.SectorRoots, newRoots = newRoots, so.SectorRoots
-	if err := VerifyRevision(&so, &newRevision, currentBlockHeight, newRevenue, newDeposit); err != nil {
+	if err := VerifyRevision(h.ctx, h.sectorRoots, &so, &newRevision, currentBlockHeight, newRevenue, newDeposit); err != nil {
 		hostNegotiateErr = fmt.Errorf("revision verification failed. contractID: %s, err: %s", newRevision.ParentID.String(), err.Error())
 		return
 	}
@@ -149,7 +202,7 @@ func UploadHandler(h *StorageHost, sp storage.Peer, uploadReqMsg p2p.Msg) {
 	}
 
 	// Construct the merkle proof
-	oldHashSet, err := merkle.Sha256DiffProof(so.SectorRoots, proofRanges, oldNumSectors)
+	oldHashSet, err := merkle.Sha256DiffProofContext(h.ctx, so.SectorRoots, proofRanges, oldNumSectors)
 	if err != nil {
 		hostNegotiateErr = fmt.Errorf("error construct the merkle proof: %s", err.Error())
 		return
@@ -174,6 +227,7 @@ func UploadHandler(h *StorageHost, sp storage.Peer, uploadReqMsg p2p.Msg) {
 	msg, err := sp.HostWaitContractResp()
 	if err != nil {
 		log.Error("after the merkle proof was sent, failed to get the storage client's response", "err", err)
+		h.recordClientAbuse(sp, abuseTimeout)
 		return
 	}
 
@@ -184,6 +238,7 @@ func UploadHandler(h *StorageHost, sp storage.Peer, uploadReqMsg p2p.Msg) {
 
 	if err = msg.Decode(&clientRevisionSign); err != nil {
 		clientNegotiateErr = fmt.Errorf("failed to decode the client revision sign: %s", err.Error())
+		h.recordClientAbuse(sp, abuseMalformedMsg)
 		return
 	}
 
@@ -206,7 +261,7 @@ func UploadHandler(h *StorageHost, sp storage.Peer, uploadReqMsg p2p.Msg) {
 	// Update the storage responsibility
 	so.SectorRoots = newRoots
 	so.PotentialStorageRevenue = so.PotentialStorageRevenue.Add(storageRevenue)
-	so.RiskedStorageDeposit = so.RiskedStorageDeposit.Add(newDeposit)
+	so.RiskedStorageDeposit = so.RiskedStorageDeposit.Add(newDeposit).Sub(releasedDeposit)
 	so.PotentialUploadRevenue = so.PotentialUploadRevenue.Add(bandwidthRevenue)
 	so.StorageContractRevisions = append(so.StorageContractRevisions, newRevision)
 
@@ -220,11 +275,18 @@ func UploadHandler(h *StorageHost, sp storage.Peer, uploadReqMsg p2p.Msg) {
 	msg, err = sp.HostWaitContractResp()
 	if err != nil {
 		log.Error("storage host failed to get client commit success msg", "err", err)
+		h.recordClientAbuse(sp, abuseTimeout)
 		return
 	}
 
 	if msg.Code == storage.ClientCommitSuccessMsg {
-		err = h.modifyStorageResponsibility(so, nil, sectorsGained, gainedSectorData)
+		// the session is persisted, so that it could be completed or rolled back if the host
+		// crashed before it is finalized
+		session := newUploadSession(snapshotSo, newRevision, sectorsGained, removeActions, sectorsRemoved, storageRevenue, newDeposit, releasedDeposit, bandwidthRevenue)
+		if err = h.beginSession(session); err == nil {
+			err = h.modifyStorageResponsibility(so, nil, sectorsGained, gainedSectorData)
+			h.endSession(session.ContractID)
+		}
 		if err != nil {
 			_ = sp.SendHostCommitFailedMsg()
 
@@ -239,8 +301,11 @@ func UploadHandler(h *StorageHost, sp storage.Peer, uploadReqMsg p2p.Msg) {
 			_ = sp.SendHostAckMsg()
 			return
 		}
+		h.sectorRoots.update(so.id(), so.SectorRoots)
+		h.recordMetric(storage.MetricUploadBytes, common.NewBigIntUint64(storage.SectorSize*uint64(len(sectorsGained))))
 	} else if msg.Code == storage.ClientCommitFailedMsg {
 		clientCommitErr = storage.ErrClientCommit
+		h.recordClientAbuse(sp, abuseCommitFailure)
 		return
 	} else if msg.Code == storage.ClientNegotiateErrorMsg {
 		clientNegotiateErr = storage.ErrClientNegotiate
@@ -261,13 +326,57 @@ func UploadHandler(h *StorageHost, sp storage.Peer, uploadReqMsg p2p.Msg) {
 	if err := sp.SendHostAckMsg(); err != nil {
 		log.Error("storage host failed to send host ack msg", "err", err)
 		_ = h.rollbackStorageResponsibility(snapshotSo, sectorsGained, nil, nil)
+		if len(removeActions) != 0 {
+			h.sectorRoots.remove(so.id())
+		}
 		h.ethBackend.CheckAndUpdateConnection(sp.PeerNode())
+		return
+	}
+
+	// the sectors removed are queued for deletion only after the revision is acknowledged,
+	// so that the rolled back storage responsibility still has the sectors
+	h.lock.Lock()
+	err = h.queueSectorDeletion(sectorsRemoved)
+	h.lock.Unlock()
+	if err != nil {
+		log.Warn("failed to queue the removed sectors for deletion", "err", err)
+	}
+}
+
+// removeSectors applies the swap and trim actions to the sector roots, and returns the
+// sector roots left and the sector roots removed. The indexes of the sectors changed are
+// added to sectorsChanged, so that the merkle proof could be constructed
+func removeSectors(roots []common.Hash, actions []storage.UploadAction, sectorsChanged map[uint64]struct{}) ([]common.Hash, []common.Hash, error) {
+	var removed []common.Hash
+	for _, action := range actions {
+		numSectors := uint64(len(roots))
+		switch action.Type {
+		case storage.UploadActionSwap:
+			if action.A >= numSectors || action.B >= numSectors {
+				return nil, nil, fmt.Errorf("swap sector %v and %v out of %v sectors", action.A, action.B, numSectors)
+			}
+			roots[action.A], roots[action.B] = roots[action.B], roots[action.A]
+			sectorsChanged[action.A] = struct{}{}
+			sectorsChanged[action.B] = struct{}{}
+		case storage.UploadActionTrim:
+			if action.A > numSectors {
+				return nil, nil, fmt.Errorf("trim %v sectors out of %v sectors", action.A, numSectors)
+			}
+			for i := numSectors - action.A; i < numSectors; i++ {
+				sectorsChanged[i] = struct{}{}
+			}
+			removed = append(removed, roots[numSectors-action.A:]...)
+			roots = roots[:numSectors-action.A]
+		}
 	}
+	return roots, removed, nil
 }
 
 // VerifyRevision checks that the revision pays the host correctly, and that
-// the revision does not attempt any malicious or unexpected changes.
-func VerifyRevision(so *StorageResponsibility, revision *types.StorageContractRevision, blockHeight uint64, expectedExchange, expectedCollateral common.BigInt) error {
+// the revision does not attempt any malicious or unexpected changes. The merkle
+// root is calculated with the cached sub trees of the sector roots if the cache
+// is not nil.
+func VerifyRevision(ctx context.Context, cache *sectorRootsCache, so *StorageResponsibility, revision *types.StorageContractRevision, blockHeight uint64, expectedExchange, expectedCollateral common.BigInt) error {
 	// Check that the revision is well-formed.
 	if len(revision.NewValidProofOutputs) != 2 || len(revision.NewMissedProofOutputs) != 2 {
 		return errBadContractOutputCounts
@@ -359,7 +468,11 @@ func VerifyRevision(so *StorageResponsibility, revision *types.StorageContractRe
 	}
 
 	// The Merkle root is checked last because it is the most expensive check.
-	if revision.NewFileMerkleRoot != merkle.Sha256CachedTreeRoot(so.SectorRoots, sectorHeight) {
+	root, err := cache.root(ctx, so.id(), so.SectorRoots)
+	if err != nil {
+		return err
+	}
+	if revision.NewFileMerkleRoot != root {
 		return errBadFileMerkleRoot
 	}
 
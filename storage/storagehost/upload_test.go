@@ -0,0 +1,208 @@
+// Copyright 2019 DxChain, All rights reserved.
+// Use of this source code is governed by an Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package storagehost
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/godx/accounts"
+	"github.com/DxChainNetwork/godx/common"
+	"github.com/DxChainNetwork/godx/core/types"
+	"github.com/DxChainNetwork/godx/crypto"
+	"github.com/DxChainNetwork/godx/crypto/merkle"
+	"github.com/DxChainNetwork/godx/p2p"
+	"github.com/DxChainNetwork/godx/p2p/enode"
+	"github.com/DxChainNetwork/godx/rlp"
+	"github.com/DxChainNetwork/godx/storage"
+)
+
+func TestRemoveSectors(t *testing.T) {
+	roots := []common.Hash{{1}, {2}, {3}, {4}, {5}}
+	actions := []storage.UploadAction{
+		{Type: storage.UploadActionSwap, A: 1, B: 4},
+		{Type: storage.UploadActionSwap, A: 0, B: 3},
+		{Type: storage.UploadActionTrim, A: 2},
+	}
+	changed := make(map[uint64]struct{})
+	newRoots, removed, err := removeSectors(roots, actions, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []common.Hash{{4}, {5}, {3}}; !reflect.DeepEqual(newRoots, expect) {
+		t.Errorf("unexpected roots after the removal: %v", newRoots)
+	}
+	if expect := []common.Hash{{1}, {2}}; !reflect.DeepEqual(removed, expect) {
+		t.Errorf("unexpected roots removed: %v", removed)
+	}
+	for _, i := range []uint64{0, 1, 3, 4} {
+		if _, exist := changed[i]; !exist {
+			t.Errorf("sector %v should be marked as changed", i)
+		}
+	}
+
+	invalid := [][]storage.UploadAction{
+		{{Type: storage.UploadActionSwap, A: 0, B: 5}},
+		{{Type: storage.UploadActionTrim, A: 6}},
+	}
+	for _, actions := range invalid {
+		roots := []common.Hash{{1}, {2}, {3}, {4}, {5}}
+		if _, _, err := removeSectors(roots, actions, make(map[uint64]struct{})); err == nil {
+			t.Errorf("the actions %+v should be rejected", actions)
+		}
+	}
+}
+
+// uploadTestPeer is the storage client peer negotiating the upload with the storage host.
+// The client responses are queued in resps, and the host messages are recorded
+type uploadTestPeer struct {
+	storage.Peer
+	resps []p2p.Msg
+
+	merkleProof    storage.UploadMerkleProof
+	hostSign       []byte
+	negotiateError bool
+	commitFailed   bool
+	acked          bool
+}
+
+func (p *uploadTestPeer) PeerNode() *enode.Node { return nil }
+
+func (p *uploadTestPeer) IsStaticConn() bool { return true }
+
+func (p *uploadTestPeer) SendUploadMerkleProof(proof storage.UploadMerkleProof) error {
+	p.merkleProof = proof
+	return nil
+}
+
+func (p *uploadTestPeer) SendUploadHostRevisionSign(sign []byte) error {
+	p.hostSign = sign
+	return nil
+}
+
+func (p *uploadTestPeer) SendHostNegotiateErrorMsg() error {
+	p.negotiateError = true
+	return nil
+}
+
+func (p *uploadTestPeer) SendHostCommitFailedMsg() error {
+	p.commitFailed = true
+	return nil
+}
+
+func (p *uploadTestPeer) SendHostAckMsg() error {
+	p.acked = true
+	return nil
+}
+
+func (p *uploadTestPeer) HostWaitContractResp() (p2p.Msg, error) {
+	if len(p.resps) == 0 {
+		return p2p.Msg{}, errors.New("no more client responses")
+	}
+	msg := p.resps[0]
+	p.resps = p.resps[1:]
+	return msg, nil
+}
+
+// testAccountManager signs the revisions with the key of the storage host
+type testAccountManager struct {
+	accounts.Wallet
+	key *ecdsa.PrivateKey
+}
+
+func (am *testAccountManager) Find(accounts.Account) (accounts.Wallet, error) { return am, nil }
+
+func (am *testAccountManager) Wallets() []accounts.Wallet { return []accounts.Wallet{am} }
+
+func (am *testAccountManager) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, am.key)
+}
+
+// newTestMsg encodes the value into the message with the code
+func newTestMsg(t *testing.T, code uint64, val interface{}) p2p.Msg {
+	data, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p2p.Msg{Code: code, Size: uint32(len(data)), Payload: bytes.NewReader(data)}
+}
+
+func TestUploadHandler_RemoveSectors(t *testing.T) {
+	h := newTestStorageHost(t)
+	if err := h.StorageManager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		h.StorageManager.Close()
+		h.db.Close()
+	}()
+	hostKey, _ := crypto.GenerateKey()
+	h.am = &testAccountManager{key: hostKey}
+	h.config.DeletionRetention = 10
+	h.blockHeight = 10
+
+	// the storage responsibility with the sub trees of the sector roots cached
+	numSectors := 2*rootsPerSubTree + 44
+	so := newTestSessionResponsibility(10000)
+	so.SectorRoots = randomRoots(numSectors)
+	rev := &so.StorageContractRevisions[0]
+	rev.NewFileSize = uint64(numSectors) * storage.SectorSize
+	rev.NewFileMerkleRoot = merkle.Sha256CachedTreeRoot(so.SectorRoots, sectorHeight)
+	clientAddr, hostAddr := common.Address{1}, crypto.PubkeyToAddress(hostKey.PublicKey)
+	rev.NewValidProofOutputs = []types.DxcoinCharge{{Address: clientAddr, Value: big.NewInt(1e12)}, {Address: hostAddr, Value: big.NewInt(0)}}
+	rev.NewMissedProofOutputs = []types.DxcoinCharge{{Address: clientAddr, Value: big.NewInt(1e12)}, {Address: hostAddr, Value: big.NewInt(0)}}
+	if err := putStorageResponsibility(h.db, so.id(), so); err != nil {
+		t.Fatal(err)
+	}
+	h.sectorRoots.update(so.id(), so.SectorRoots)
+
+	// the sector in the first sub tree is swapped with the last sector, and trimmed
+	price := h.externalConfig().BaseRPCPrice.BigIntPtr()
+	clientValue := new(big.Int).Sub(rev.NewValidProofOutputs[0].Value, price)
+	req := storage.UploadRequest{
+		StorageContractID: so.id(),
+		Actions: []storage.UploadAction{
+			{Type: storage.UploadActionSwap, A: 5, B: uint64(numSectors - 1)},
+			{Type: storage.UploadActionTrim, A: 1},
+		},
+		NewRevisionNumber:    rev.NewRevisionNumber + 1,
+		NewValidProofValues:  []*big.Int{clientValue, price},
+		NewMissedProofValues: []*big.Int{clientValue, big.NewInt(0)},
+	}
+	sp := &uploadTestPeer{resps: []p2p.Msg{
+		newTestMsg(t, storage.ContractUploadClientRevisionSign, []byte{1}),
+		{Code: storage.ClientCommitSuccessMsg},
+	}}
+	UploadHandler(h, sp, newTestMsg(t, storage.ContractUploadReqMsg, req))
+	if sp.negotiateError || sp.commitFailed || !sp.acked || len(sp.hostSign) == 0 {
+		t.Fatalf("the upload negotiation failed: %+v", sp)
+	}
+
+	expect := append([]common.Hash{}, so.SectorRoots[:numSectors-1]...)
+	expect[5] = so.SectorRoots[numSectors-1]
+	expectRoot := merkle.Sha256CachedTreeRoot(expect, sectorHeight)
+	if sp.merkleProof.NewMerkleRoot != expectRoot {
+		t.Errorf("the new merkle root sent is not calculated from the swapped roots")
+	}
+	updated, err := getStorageResponsibility(h.db, so.id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := updated.StorageContractRevisions[len(updated.StorageContractRevisions)-1]
+	if !reflect.DeepEqual(updated.SectorRoots, expect) || latest.NewFileSize != uint64(len(expect))*storage.SectorSize || latest.NewFileMerkleRoot != expectRoot {
+		t.Errorf("the storage responsibility is not updated with the removal: %v roots, file size %v", len(updated.SectorRoots), latest.NewFileSize)
+	}
+	deletions, err := getDelayedDeletions(h.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roots := deletions[h.blockHeight+h.config.DeletionRetention]; !reflect.DeepEqual(roots, []common.Hash{so.SectorRoots[5]}) {
+		t.Errorf("the removed sector is not queued for deletion: %v", roots)
+	}
+}
@@ -47,6 +47,22 @@ const (
 	// DxFileExt is the extension of DxFile
 	DxFileExt = ".dxfile"
 
+	// TransitionFileSuffix is appended to the dxpath of the file in storage class transition
+	// to make the dxpath of the file placing the sectors of the new storage class
+	TransitionFileSuffix = ".transition"
+
+	// RetiredFileSuffix is appended to the dxpath of the file replaced by the storage class
+	// transition, until the file is deleted
+	RetiredFileSuffix = ".transition.retired"
+
+	// ReplacingFileSuffix is appended to the dxpath of the file uploading the new content of
+	// an existing file, until the new content replaces the existing file
+	ReplacingFileSuffix = ".replacing"
+
+	// ReplacedFileSuffix is appended to the dxpath of the existing file replaced by the new
+	// content, until the file is deleted
+	ReplacedFileSuffix = ".replaced"
+
 	// ConfigVersion is the version of host config
 	ConfigVersion = "1.0.1"
 )
@@ -54,12 +70,14 @@ const (
 type (
 	// HostIntConfig make group of host setting as object
 	HostIntConfig struct {
-		AcceptingContracts   bool           `json:"acceptingContracts"`
-		MaxDownloadBatchSize uint64         `json:"maxDownloadBatchSize"`
-		MaxDuration          uint64         `json:"maxDuration"`
-		MaxReviseBatchSize   uint64         `json:"maxReviseBatchSize"`
-		WindowSize           uint64         `json:"windowSize"`
-		PaymentAddress       common.Address `json:"paymentAddress"`
+		AcceptingContracts    bool           `json:"acceptingContracts"`
+		MaxDownloadBatchSize  uint64         `json:"maxDownloadBatchSize"`
+		MaxDuration           uint64         `json:"maxDuration"`
+		MaxReviseBatchSize    uint64         `json:"maxReviseBatchSize"`
+		MaxUploadBatchSectors uint64         `json:"maxUploadBatchSectors"`
+		WindowSize            uint64         `json:"windowSize"`
+		DeletionRetention     uint64         `json:"deletionRetention"`
+		PaymentAddress        common.Address `json:"paymentAddress"`
 
 		Deposit       common.BigInt `json:"deposit"`
 		DepositBudget common.BigInt `json:"depositBudget"`
@@ -71,16 +89,20 @@ type (
 		SectorAccessPrice      common.BigInt `json:"sectorAccessPrice"`
 		StoragePrice           common.BigInt `json:"storagePrice"`
 		UploadBandwidthPrice   common.BigInt `json:"uploadBandwidthPrice"`
+
+		ScheduledPrices []HostScheduledPrice `json:"scheduledPrices"`
 	}
 
 	// HostIntConfigForDisplay is the host internal config for displayed
 	HostIntConfigForDisplay struct {
-		AcceptingContracts   string `json:"acceptingContracts"`
-		MaxDownloadBatchSize string `json:"maxDownloadBatchSize"`
-		MaxDuration          string `json:"maxDuration"`
-		MaxReviseBatchSize   string `json:"maxReviseBatchSize"`
-		WindowSize           string `json:"windowSize"`
-		PaymentAddress       string `json:"paymentAddress"`
+		AcceptingContracts    string `json:"acceptingContracts"`
+		MaxDownloadBatchSize  string `json:"maxDownloadBatchSize"`
+		MaxDuration           string `json:"maxDuration"`
+		MaxReviseBatchSize    string `json:"maxReviseBatchSize"`
+		MaxUploadBatchSectors string `json:"maxUploadBatchSectors"`
+		WindowSize            string `json:"windowSize"`
+		DeletionRetention     string `json:"deletionRetention"`
+		PaymentAddress        string `json:"paymentAddress"`
 
 		Deposit       string `json:"deposit"`
 		DepositBudget string `json:"depositBudget"`
@@ -92,6 +114,8 @@ type (
 		SectorAccessPrice      string `json:"sectorAccessPrice"`
 		StoragePrice           string `json:"storagePrice"`
 		UploadBandwidthPrice   string `json:"uploadBandwidthPrice"`
+
+		ScheduledPrices []string `json:"scheduledPrices"`
 	}
 
 	// HostExtConfig make group of host setting to broadcast as object
@@ -118,6 +142,15 @@ type (
 		UploadBandwidthPrice   common.BigInt `json:"uploadBandwidthPrice"`
 
 		Version string `json:"version"`
+
+		// the fields below are encoded in the optional RLP tail, see hostExtConfigRLP
+
+		// MaxUploadBatchSectors is the maximum number of sectors accepted in an upload
+		// request, 0 means the upload request is only limited by MaxReviseBatchSize
+		MaxUploadBatchSectors uint64 `json:"maxUploadBatchSectors"`
+
+		// ScheduledPrices are the price changes pre-announced by the host
+		ScheduledPrices []HostScheduledPrice `json:"scheduledPrices"`
 	}
 
 	// HostInfo storage storage host information
@@ -143,11 +176,38 @@ type (
 		IPNetwork           string    `json:"ipnetwork"`
 		LastIPNetWorkChange time.Time `json:"lastipnetworkchange"`
 
+		// IPNetworkChanges is the recent history of the ip network changes of the host
+		IPNetworkChanges []IPNetworkChange `json:"ipnetworkchanges"`
+
 		EnodeID    enode.ID `json:"enodeid"`
 		EnodeURL   string   `json:"enodeurl"`
 		NodePubKey []byte   `json:"nodepubkey"`
 
 		Filtered bool `json:"filtered"`
+
+		// QoS is the quality of the storage negotiations experienced with the host
+		QoS HostQoS `json:"qos"`
+
+		// Bootstrap indicates the host is loaded from the bootstrap snapshot, and its
+		// announcement has not been found on the block chain yet
+		Bootstrap bool `json:"bootstrap"`
+	}
+
+	// IPNetworkChange is the ip network change of the host found in the scan. From is empty
+	// if the ip network is found for the first time
+	IPNetworkChange struct {
+		From string    `json:"from"`
+		To   string    `json:"to"`
+		Time time.Time `json:"time"`
+	}
+
+	// HostQoS is the decayed aggregation of the negotiation quality samples of the host
+	HostQoS struct {
+		Responses    float64       `json:"responses"`
+		Timeouts     float64       `json:"timeouts"`
+		SendFailures float64       `json:"sendfailures"`
+		RTT          time.Duration `json:"rtt"`
+		Throughput   float64       `json:"throughput"`
 	}
 
 	// HostPoolScans stores a list of host pool scan records
@@ -197,6 +257,10 @@ type ClientSetting struct {
 	EnableIPViolation bool        `json:"enableipviolation"`
 	MaxUploadSpeed    int64       `json:"maxuploadspeed"`
 	MaxDownloadSpeed  int64       `json:"maxdownloadspeed"`
+
+	// DownloadOverdrive is the number of extra sector fetches issued beyond the minimum
+	// sectors for every segment downloaded
+	DownloadOverdrive int `json:"downloadoverdrive"`
 }
 
 type (
@@ -223,6 +287,7 @@ type (
 		EnableIPViolation string                `json:"IP Violation Check Status"`
 		MaxUploadSpeed    string                `json:"Max Upload Speed"`
 		MaxDownloadSpeed  string                `json:"Max Download Speed"`
+		DownloadOverdrive string                `json:"Download Overdrive"`
 	}
 )
 